@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/report"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/thresholds"
+	"github.com/bitrise-io/steps-bundle-analyzer/pkg/bundleanalyzer"
+)
+
+// maxListedFiles caps the number of added, removed and changed files printed in the summary
+const maxListedFiles = 10
+
+// runAnalyze analyzes the artifact, copies the reports to the output directory and prints a summary
+func runAnalyze(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	formats := fs.String("format", "markdown,html", "Comma separated report formats: text, markdown, html, csv, sarif (json is always generated)")
+	baseline := fs.String("baseline", "", "JSON report or artifact of the baseline build to compare with")
+	outputDir := fs.String("output-dir", ".", "Directory the reports are written to")
+	pluginVersion := fs.String("plugin-version", "", "bundle-inspector plugin version to use, the installed version when empty")
+	timeout := fs.Duration("timeout", 0, "Maximum duration of the analysis, for example 10m (no limit when 0)")
+	duplicates := fs.Bool("duplicates", false, "List byte-identical files in the artifact")
+	secrets := fs.Bool("secrets", false, "Scan bundled assets, plists and string resources for API keys, private keys and tokens")
+	verbose := fs.Bool("verbose", false, "Print the progress of the analysis")
+	failOnLargeSize := fs.String("fail-on-large-size", "", "Fail when the artifact exceeds the size threshold, for example 150MB or +3% (relative terms need --baseline)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: bundle-analyzer analyze <artifact> [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return nil
+	} else if err != nil {
+		return errUsage
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	artifactPath := positional[0]
+
+	progress := io.Discard
+	if *verbose {
+		progress = stderr
+	}
+	logger := progressLogger{Logger: log.NewLogger(log.WithOutput(progress)), out: progress}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	workDir, err := os.MkdirTemp("", "bundle-analyzer-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	opts := bundleanalyzer.Options{
+		OutputFormats:  splitList(*formats),
+		PluginVersion:  *pluginVersion,
+		FindDuplicates: *duplicates,
+		ScanSecrets:    *secrets,
+		Logger:         logger,
+	}
+
+	fmt.Fprintf(stderr, "Analyzing %s...\n", artifactPath)
+	opts.WorkDir = filepath.Join(workDir, "current")
+	current, err := bundleanalyzer.Analyze(ctx, artifactPath, opts)
+	if err != nil {
+		return err
+	}
+
+	var baselineReport *bundleanalyzer.Report
+	if *baseline != "" {
+		if strings.EqualFold(filepath.Ext(*baseline), ".json") {
+			baselineReport, err = bundleanalyzer.LoadReport(*baseline)
+		} else {
+			fmt.Fprintf(stderr, "Analyzing baseline %s...\n", *baseline)
+			opts.WorkDir = filepath.Join(workDir, "baseline")
+			opts.OutputFormats = nil
+			baselineReport, err = bundleanalyzer.Analyze(ctx, *baseline, opts)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read baseline: %w", err)
+		}
+	}
+
+	// Every generated report is copied to the output directory, the working directory is removed on exit
+	deployAll := func(string) bool { return true }
+	deployed, err := report.NewDeployer(fileutil.NewFileManager(), logger).Deploy(analyze.ReportPaths(current.ReportFiles), *outputDir, "", deployAll)
+	if err != nil {
+		return err
+	}
+
+	printSummary(stdout, current, baselineReport, bundleanalyzer.ReportFiles(deployed))
+	if current.Canceled {
+		return fmt.Errorf("analysis canceled, the results are partial")
+	}
+	if current.TimedOut {
+		return fmt.Errorf("analysis timed out after %s, the results are partial", *timeout)
+	}
+	if *failOnLargeSize != "" {
+		thresholdLogger := progressLogger{Logger: log.NewLogger(log.WithOutput(stderr)), out: stderr}
+		return checkSizeThreshold(current, baselineReport, *failOnLargeSize, thresholdLogger)
+	}
+	return nil
+}
+
+// checkSizeThreshold checks the size of the report with the step's fail_on_large_size check,
+// relative terms of the threshold are compared with the baseline
+func checkSizeThreshold(current, baseline *bundleanalyzer.Report, threshold string, logger log.Logger) error {
+	result := analyze.ArtifactResult{
+		ArtifactPath: current.ArtifactPath,
+		Name:         current.Name,
+		Metrics:      analyze.BundleMetrics{SizeBytes: current.SizeBytes},
+	}
+	if baseline != nil {
+		// The messages of relative terms name the baseline build, that is the baseline file here
+		result.Comparison = &analyze.BaselineComparison{
+			Baseline:       analyze.HistoryRecord{Artifact: baseline.Name, BuildNumber: baseline.Name, SizeBytes: baseline.SizeBytes},
+			SizeDeltaBytes: current.SizeBytes - baseline.SizeBytes,
+		}
+	}
+
+	checker := thresholds.NewChecker(config.Config{FailOnLargeSize: threshold}, env.NewRepository(), logger)
+	if violations := checker.Check(result, nil); len(violations) > 0 {
+		return fmt.Errorf("size threshold failed: %w", violations[0].Err)
+	}
+	return nil
+}
+
+// progressLogger keeps the empty lines of the progress log on its output, log.Logger prints them to stdout
+type progressLogger struct {
+	log.Logger
+	out io.Writer
+}
+
+func (l progressLogger) Println() {
+	fmt.Fprintln(l.out)
+}
+
+// printSummary prints the metrics of the report and the differences from the baseline
+func printSummary(out io.Writer, current, baseline *bundleanalyzer.Report, reports bundleanalyzer.ReportFiles) {
+	fmt.Fprintf(out, "\n%s\n", current.Name)
+
+	var diff bundleanalyzer.Diff
+	filesBaseline := baseline
+	if baseline != nil {
+		diff = bundleanalyzer.Compare(baseline, current)
+		fmt.Fprintf(out, "  Baseline:              %s\n", baseline.Name)
+		if !bundleanalyzer.CanCompareFiles(baseline, current) {
+			filesBaseline = nil
+		}
+	}
+
+	fmt.Fprintf(out, "  Size:                  %s%s\n", formatMB(current.SizeBytes), deltaMB(baseline, diff.SizeDeltaBytes))
+	fmt.Fprintf(out, "  Potential savings:     %s%s\n", formatMB(current.PotentialSavingsBytes), deltaMB(baseline, diff.PotentialSavingsDeltaBytes))
+	fmt.Fprintf(out, "  Files:                 %d%s\n", current.FileCount, deltaCount(filesBaseline, diff.FileCountDelta))
+	if current.DexMethodCount > 0 {
+		fmt.Fprintf(out, "  DEX method references: %d%s\n", current.DexMethodCount, deltaCount(filesBaseline, diff.DexMethodCountDelta))
+	}
+
+	if len(current.Duplicates) > 0 {
+		var wasted int64
+		for _, set := range current.Duplicates {
+			wasted += set.WastedBytes
+		}
+		fmt.Fprintf(out, "  Duplicates:            %d set(s) wasting %s\n", len(current.Duplicates), formatMB(wasted))
+	}
+
+	if len(current.Secrets) > 0 {
+		fmt.Fprintf(out, "\n  Potential secrets (%d):\n", len(current.Secrets))
+		for _, secret := range current.Secrets {
+			fmt.Fprintf(out, "    %s  %s  %s\n", secret.Detector, secret.Path, secret.Masked)
+		}
+	}
+
+	printFiles(out, "Added files", diff.Added)
+	printFiles(out, "Removed files", diff.Removed)
+	if len(diff.Changed) > 0 {
+		changed := append([]bundleanalyzer.FileChange(nil), diff.Changed...)
+		sort.SliceStable(changed, func(i, j int) bool {
+			return abs(changed[i].CompressedDeltaBytes()) > abs(changed[j].CompressedDeltaBytes())
+		})
+		fmt.Fprintf(out, "\n  Changed files (%d):\n", len(changed))
+		for idx, change := range changed {
+			if idx == maxListedFiles {
+				fmt.Fprintf(out, "    ... and %d more\n", len(changed)-maxListedFiles)
+				break
+			}
+			fmt.Fprintf(out, "    %+.2f MB  %s\n", float64(change.CompressedDeltaBytes())/(1024*1024), change.Path)
+		}
+	}
+
+	fmt.Fprintf(out, "\n  Reports:\n")
+	for _, path := range []string{reports.Markdown, reports.HTML, reports.JSON} {
+		if path != "" {
+			fmt.Fprintf(out, "    %s\n", path)
+		}
+	}
+}
+
+// printFiles prints the largest files of the list
+func printFiles(out io.Writer, title string, files []bundleanalyzer.File) {
+	if len(files) == 0 {
+		return
+	}
+
+	sorted := append([]bundleanalyzer.File(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CompressedSize > sorted[j].CompressedSize })
+	fmt.Fprintf(out, "\n  %s (%d):\n", title, len(sorted))
+	for idx, file := range sorted {
+		if idx == maxListedFiles {
+			fmt.Fprintf(out, "    ... and %d more\n", len(sorted)-maxListedFiles)
+			break
+		}
+		fmt.Fprintf(out, "    %s  %s\n", formatMB(file.CompressedSize), file.Path)
+	}
+}
+
+// formatMB formats a size in megabytes like the step's log
+func formatMB(sizeBytes int64) string {
+	return fmt.Sprintf("%.2f MB", float64(sizeBytes)/(1024*1024))
+}
+
+// deltaMB formats a size difference from the baseline, empty without a baseline
+func deltaMB(baseline *bundleanalyzer.Report, deltaBytes int64) string {
+	if baseline == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%+.2f MB)", float64(deltaBytes)/(1024*1024))
+}
+
+// deltaCount formats a count difference from the baseline, empty without a baseline
+func deltaCount(baseline *bundleanalyzer.Report, delta int64) string {
+	if baseline == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%+d)", delta)
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
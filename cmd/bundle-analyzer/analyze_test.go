@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/pkg/bundleanalyzer"
+)
+
+const mb = 1024 * 1024
+
+func testReport(name string, sizeBytes int64) *bundleanalyzer.Report {
+	return &bundleanalyzer.Report{ArtifactPath: "/tmp/" + name, Name: name, SizeBytes: sizeBytes}
+}
+
+func testLogger() log.Logger {
+	return progressLogger{Logger: log.NewLogger(log.WithOutput(io.Discard)), out: io.Discard}
+}
+
+func TestCheckSizeThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   *bundleanalyzer.Report
+		baseline  *bundleanalyzer.Report
+		threshold string
+		wantErr   string
+	}{
+		{name: "within threshold", current: testReport("app.apk", 40*mb), threshold: "50MB"},
+		{name: "exceeds threshold", current: testReport("app.apk", 60*mb), threshold: "50MB",
+			wantErr: "size threshold failed: bundle size 60.00 MB exceeds threshold 50.00 MB"},
+		{name: "relative term without baseline", current: testReport("app.apk", 60*mb), threshold: "+3%"},
+		{name: "grew more than allowed", current: testReport("app.apk", 60*mb), baseline: testReport("old.apk", 50*mb), threshold: "100MB or +10%",
+			wantErr: "size threshold failed: bundle size 60.00 MB exceeds threshold +10% (55.00 MB) compared to baseline build #old.apk"},
+		{name: "grew within allowance", current: testReport("app.apk", 52*mb), baseline: testReport("old.apk", 50*mb), threshold: "100MB or +10%"},
+		{name: "invalid threshold", current: testReport("app.apk", 60*mb), threshold: "fifty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSizeThreshold(tt.current, tt.baseline, tt.threshold, testLogger())
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkSizeThreshold() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("checkSizeThreshold() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
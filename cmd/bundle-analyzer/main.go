@@ -0,0 +1,98 @@
+// Command bundle-analyzer runs the analysis of the Bundle Analyzer step on a developer machine:
+//
+//	bundle-analyzer analyze app.apk --format html --baseline old.json
+//
+// It only needs the Bitrise CLI (for the bundle-inspector plugin), envman and the BITRISE_* variables of a build are not used.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+const usage = `Usage: bundle-analyzer <command> [flags]
+
+Commands:
+  analyze <artifact>  Analyze an IPA, APK or AAB and write the reports
+  help                Show this help
+
+Run 'bundle-analyzer analyze --help' for the flags of the analyze command.
+`
+
+// errUsage marks invalid command line arguments, the usage is already printed
+var errUsage = errors.New("invalid arguments")
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	if err != nil && !errors.Is(err, errUsage) {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// exitCode returns the exit code of the command's error: 2 for invalid arguments, 1 for failures and failed thresholds
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// run executes the command of the arguments
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "analyze":
+		// Ctrl+C stops bundle-inspector and its child processes
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return runAnalyze(ctx, args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprintf(stderr, "Unknown command: %s\n\n%s", args[0], usage)
+		return errUsage
+	}
+}
+
+// parseInterspersed parses the flags of the arguments and returns the positional arguments,
+// flags are accepted after positional arguments too (analyze app.apk --format html)
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// splitList splits a comma separated flag value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		wantPositional []string
+		wantFormat     string
+		wantVerbose    bool
+		wantErr        string
+	}{
+		{name: "flags first", args: []string{"--format", "html", "app.apk"}, wantPositional: []string{"app.apk"}, wantFormat: "html"},
+		{name: "flags last", args: []string{"app.apk", "--format=html", "--verbose"}, wantPositional: []string{"app.apk"}, wantFormat: "html", wantVerbose: true},
+		{name: "flags between positional arguments", args: []string{"app.apk", "-verbose", "old.apk", "--format", "csv"},
+			wantPositional: []string{"app.apk", "old.apk"}, wantFormat: "csv", wantVerbose: true},
+		{name: "no flags", args: []string{"app.apk"}, wantPositional: []string{"app.apk"}, wantFormat: "markdown"},
+		{name: "unknown flag", args: []string{"app.apk", "--baseline", "old.json"}, wantErr: "flag provided but not defined: -baseline"},
+		{name: "missing flag value", args: []string{"app.apk", "--format"}, wantErr: "flag needs an argument: -format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			format := fs.String("format", "markdown", "")
+			verbose := fs.Bool("verbose", false, "")
+
+			positional, err := parseInterspersed(fs, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseInterspersed() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseInterspersed() error = %s", err)
+			}
+			if !reflect.DeepEqual(positional, tt.wantPositional) || *format != tt.wantFormat || *verbose != tt.wantVerbose {
+				t.Errorf("parseInterspersed() = %v, format %s, verbose %t", positional, *format, *verbose)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		wantExitCode int
+		wantStdout   string
+		wantStderr   string
+	}{
+		{name: "no command", wantExitCode: 2, wantStderr: "Usage: bundle-analyzer <command> [flags]"},
+		{name: "help", args: []string{"help"}, wantStdout: "Usage: bundle-analyzer <command> [flags]"},
+		{name: "unknown command", args: []string{"inspect", "app.apk"}, wantExitCode: 2, wantStderr: "Unknown command: inspect"},
+		{name: "analyze help", args: []string{"analyze", "--help"}, wantStderr: "Usage: bundle-analyzer analyze <artifact> [flags]"},
+		{name: "unknown flag", args: []string{"analyze", "app.apk", "--html"}, wantExitCode: 2, wantStderr: "flag provided but not defined: -html"},
+		{name: "missing artifact", args: []string{"analyze", "--format", "html"}, wantExitCode: 2, wantStderr: "Usage: bundle-analyzer analyze <artifact> [flags]"},
+		{name: "several artifacts", args: []string{"analyze", "app.apk", "--verbose", "old.apk"}, wantExitCode: 2, wantStderr: "Usage: bundle-analyzer analyze <artifact> [flags]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(tt.args, &stdout, &stderr)
+			if got := exitCode(err); got != tt.wantExitCode {
+				t.Errorf("run() exit code = %d (%v), want %d", got, err, tt.wantExitCode)
+			}
+			if !strings.Contains(stdout.String(), tt.wantStdout) || (tt.wantStdout == "" && stdout.Len() > 0) {
+				t.Errorf("run() stdout = %q, want %q", stdout.String(), tt.wantStdout)
+			}
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("run() stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", want: 0},
+		{name: "invalid arguments", err: errUsage, want: 2},
+		{name: "failed analysis", err: errors.New("failed to read baseline: unexpected end of JSON input"), want: 1},
+		{name: "failed threshold", err: checkSizeThreshold(testReport("app.apk", 60*mb), nil, "50MB", testLogger()), want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
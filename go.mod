@@ -4,6 +4,17 @@ go 1.21
 
 require github.com/bitrise-io/go-steputils v1.0.6
 
+require (
+	github.com/antlr4-go/antlr/v4 v4.13.0 // indirect
+	github.com/google/cel-go v0.20.1
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	golang.org/x/exp v0.0.0-20230515195305-f3d0a9c9a5cc // indirect
+	golang.org/x/text v0.9.0
+	google.golang.org/genproto/googleapis/api v0.0.0-20230803162519-f966b187b2e5 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20230803162519-f966b187b2e5 // indirect
+	google.golang.org/protobuf v1.31.0
+)
+
 require (
 	github.com/bitrise-io/go-utils v1.0.1 // indirect
 	github.com/bitrise-io/go-utils/v2 v2.0.0-alpha.31
@@ -0,0 +1,201 @@
+package analyze
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// bundledAdvisories is the advisory database shipped with the step, advisory_database_path adds newer advisories offline
+//
+//go:embed advisories.json
+var bundledAdvisories []byte
+
+// severityRanks orders the severities of advisories
+var severityRanks = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// AdvisoryDatabase is a list of known vulnerabilities of SDK versions
+type AdvisoryDatabase struct {
+	// Updated is the date the database was last updated (YYYY-MM-DD)
+	Updated    string     `json:"updated"`
+	Advisories []Advisory `json:"advisories"`
+}
+
+// Advisory is a known vulnerability of an SDK
+type Advisory struct {
+	ID string `json:"id"`
+	// SDK is the Maven coordinate (group:artifact) of Android libraries or the framework name of iOS frameworks
+	SDK      string          `json:"sdk"`
+	Platform string          `json:"platform"`
+	Severity string          `json:"severity"`
+	Summary  string          `json:"summary"`
+	Ranges   []AdvisoryRange `json:"ranges"`
+}
+
+// AdvisoryRange is a range of vulnerable versions: from Introduced (inclusive, every earlier version when empty)
+// up to Fixed (exclusive, every later version when empty)
+type AdvisoryRange struct {
+	Introduced string `json:"introduced"`
+	Fixed      string `json:"fixed"`
+}
+
+// VulnerableSDK is a bundled SDK version affected by an advisory
+type VulnerableSDK struct {
+	SDK      DetectedSDK
+	Advisory Advisory
+	// FixedVersion is the first fixed version after the detected one, empty if no fix is known
+	FixedVersion string
+}
+
+// SeverityAtLeast checks whether a severity is at least the minimum severity
+func SeverityAtLeast(severity, minimum string) bool {
+	return severityRanks[strings.ToLower(severity)] >= severityRanks[strings.ToLower(minimum)]
+}
+
+// IsValidSeverity checks whether the value is one of low, medium, high and critical
+func IsValidSeverity(severity string) bool {
+	_, ok := severityRanks[strings.ToLower(severity)]
+	return ok
+}
+
+// LoadAdvisoryDatabase returns the bundled advisory database, extended with the database file when a path is given.
+// Advisories of the file replace the bundled advisories with the same ID and SDK, so a newer file can correct them.
+func LoadAdvisoryDatabase(databasePath string) (AdvisoryDatabase, error) {
+	var db AdvisoryDatabase
+	if err := json.Unmarshal(bundledAdvisories, &db); err != nil {
+		return AdvisoryDatabase{}, fmt.Errorf("failed to parse bundled advisory database: %w", err)
+	}
+	if databasePath == "" {
+		return db, nil
+	}
+
+	data, err := os.ReadFile(databasePath)
+	if err != nil {
+		return AdvisoryDatabase{}, fmt.Errorf("failed to read advisory database: %w", err)
+	}
+	var update AdvisoryDatabase
+	if err := json.Unmarshal(data, &update); err != nil {
+		return AdvisoryDatabase{}, fmt.Errorf("failed to parse advisory database %s: %w", databasePath, err)
+	}
+	for _, advisory := range update.Advisories {
+		if advisory.ID == "" || advisory.SDK == "" || !IsValidSeverity(advisory.Severity) {
+			return AdvisoryDatabase{}, fmt.Errorf("invalid advisory in %s: id, sdk and a severity of low, medium, high or critical are required", databasePath)
+		}
+	}
+
+	replaced := map[string]bool{}
+	for _, advisory := range update.Advisories {
+		replaced[advisory.ID+" "+advisory.SDK] = true
+	}
+	merged := AdvisoryDatabase{Updated: db.Updated, Advisories: update.Advisories}
+	for _, advisory := range db.Advisories {
+		if !replaced[advisory.ID+" "+advisory.SDK] {
+			merged.Advisories = append(merged.Advisories, advisory)
+		}
+	}
+	if update.Updated > merged.Updated {
+		merged.Updated = update.Updated
+	}
+	return merged, nil
+}
+
+// Match returns the detected SDKs affected by an advisory of the database, the most severe first
+func (db AdvisoryDatabase) Match(sdks []DetectedSDK) []VulnerableSDK {
+	var matches []VulnerableSDK
+	for _, sdk := range sdks {
+		for _, advisory := range db.Advisories {
+			if advisory.SDK != sdk.Name || (advisory.Platform != "" && advisory.Platform != sdk.Platform) {
+				continue
+			}
+			for _, versionRange := range advisory.Ranges {
+				if versionRange.contains(sdk.Version) {
+					matches = append(matches, VulnerableSDK{SDK: sdk, Advisory: advisory, FixedVersion: versionRange.Fixed})
+					break
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return severityRanks[strings.ToLower(matches[i].Advisory.Severity)] > severityRanks[strings.ToLower(matches[j].Advisory.Severity)]
+	})
+	return matches
+}
+
+// contains checks whether the version is in the range
+func (r AdvisoryRange) contains(version string) bool {
+	if r.Introduced != "" && compareVersions(version, r.Introduced) < 0 {
+		return false
+	}
+	return r.Fixed == "" || compareVersions(version, r.Fixed) < 0
+}
+
+// compareVersions compares dotted versions numerically, a pre-release (1.2.0-beta01) precedes its release
+func compareVersions(a, b string) int {
+	aRelease, aPre, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	bRelease, bPre, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	aParts, bParts := strings.Split(aRelease, "."), strings.Split(bRelease, ".")
+	for idx := 0; idx < len(aParts) || idx < len(bParts); idx++ {
+		aPart, bPart := versionPart(aParts, idx), versionPart(bParts, idx)
+		if aPart != bPart {
+			if aPart < bPart {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return strings.Compare(aPre, bPre)
+}
+
+// versionPart returns the numeric value of a version segment, missing and non-numeric segments count as 0
+func versionPart(parts []string, idx int) int {
+	if idx >= len(parts) {
+		return 0
+	}
+	value, _ := strconv.Atoi(parts[idx])
+	return value
+}
+
+// scanVulnerableSDKsFromConfig detects the SDKs of the artifact and matches them against the advisory database,
+// false is returned when the artifact couldn't be checked
+func scanVulnerableSDKsFromConfig(cfg config.Config, artifactPath string, logger log.Logger) ([]VulnerableSDK, bool) {
+	db, err := LoadAdvisoryDatabase(cfg.AdvisoryDatabasePath)
+	if err != nil {
+		logger.Warnf("Failed to load advisory database: %s", err)
+		return nil, false
+	}
+
+	logger.Infof("Checking SDK versions against %d advisories (database updated %s)...", len(db.Advisories), db.Updated)
+	sdks, err := DetectSDKs(artifactPath)
+	if err != nil {
+		logger.Warnf("Failed to detect SDKs: %s", err)
+		return nil, false
+	}
+	logger.Printf("Found %d versioned SDK(s)", len(sdks))
+
+	vulnerable := db.Match(sdks)
+	for _, match := range vulnerable {
+		logger.Printf("- %s %s: %s (%s) %s", match.SDK.Name, match.SDK.Version, match.Advisory.ID, match.Advisory.Severity, match.Advisory.Summary)
+	}
+	if len(vulnerable) == 0 {
+		logger.Donef("No SDK with known vulnerabilities found")
+	}
+	return vulnerable, true
+}
@@ -0,0 +1,605 @@
+package analyze
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/logging"
+)
+
+// PluginOutputName is the file bundle-inspector's output is saved to in the working directory of the analysis
+const PluginOutputName = "bundle-inspector-output.log"
+
+// BundleMetrics holds the parsed bundle analysis metrics
+type BundleMetrics struct {
+	SizeBytes             int64
+	SizeMB                string
+	PotentialSavingsBytes int64
+	DexMethodCount        int64
+	FileCount             int64
+	// ObfuscationCoverage is the percentage of DEX classes with obfuscated names, set if ObfuscationMeasured
+	ObfuscationCoverage float64
+	ObfuscationMeasured bool
+}
+
+// ReportPaths holds the paths to generated reports
+type ReportPaths struct {
+	Markdown string
+	HTML     string
+	JSON     string
+	// CSV and SARIF are rendered by the step from the analysis result, empty unless listed in output_formats
+	CSV   string
+	SARIF string
+	// Licenses is the license inventory written by the step, empty unless license_inventory is enabled
+	Licenses string
+}
+
+// ThresholdViolation is a failed threshold check, Check is the name of the input configuring it
+type ThresholdViolation struct {
+	Check string
+	Err   error
+}
+
+// ArtifactResult holds the analysis results of a single artifact
+type ArtifactResult struct {
+	ArtifactPath string
+	Name         string
+	Metrics      BundleMetrics
+	// Inventory lists the entries within Scope, the entries left out by include_patterns and exclude_patterns are
+	// summed in Excluded
+	Inventory Inventory
+	Scope     EntryScope
+	Excluded  ExcludedEntries
+	// DownloadBytes is the estimated download size under DownloadCompression, zero without an inventory
+	DownloadBytes       int64
+	DownloadCompression string
+	DexMethodCounts     map[string]int64
+	GeneratedFiles      ReportPaths
+	ReportPaths         ReportPaths
+	// App fills the placeholders of report_title and the Datadog and New Relic attributes, it's only read with
+	// report_title, datadog_api_key or newrelic_license_key
+	App AppIdentity
+	// Title is the rendered report_title, empty without report_title
+	Title         string
+	Comparison    *BaselineComparison
+	RatchetRecord *HistoryRecord
+	ModuleResults []ModuleBudgetResult
+	Secrets       []SecretFinding
+	// SecretsScanned is set when the artifact was scanned for secrets, Secrets is empty if nothing was found
+	SecretsScanned bool
+	VulnerableSDKs []VulnerableSDK
+	// SDKsScanned is set when the SDKs of the artifact were matched against the advisory database
+	SDKsScanned bool
+	Licenses    []LicenseEntry
+	// LicensesRead is set when the licenses of the artifact were collected
+	LicensesRead bool
+	Signature    SignatureInfo
+	// SignatureVerified is set when the signature of the artifact was verified
+	SignatureVerified bool
+	Profile           ProvisioningProfile
+	// ProfileRead is set when the embedded provisioning profile of the IPA was decoded
+	ProfileRead bool
+	Privacy     PrivacyReport
+	// PrivacyChecked is set when the privacy manifests of the IPA were validated
+	PrivacyChecked bool
+	Permissions    []string
+	// PermissionsRead is set when the permissions of the artifact were read
+	PermissionsRead bool
+	AttackSurface   AttackSurface
+	// AttackSurfaceRead is set when the exported components and deep links of the artifact were read
+	AttackSurfaceRead bool
+	Libraries         []DetectedSDK
+	// LibrariesRead is set when the library inventory of the artifact was read for the SBOM diff
+	LibrariesRead bool
+	DebugFindings []DebugFinding
+	// DebugChecked is set when the artifact was checked for signs of a debug build
+	DebugChecked bool
+	// DebugBuildReasons are the signs that the artifact isn't a release build, checked on every artifact if DebugBuildChecked
+	DebugBuildReasons []string
+	DebugBuildChecked bool
+	Hardening         []BinaryHardening
+	// HardeningChecked is set when the native binaries of the artifact were checked for exploit mitigations
+	HardeningChecked bool
+	Reputation       []BinaryReputation
+	// ReputationChecked is set when the native binaries were screened against the hash lists or reputation API
+	ReputationChecked bool
+	NetworkSecurity   []NetworkSecurityFinding
+	// NetworkSecurityChecked is set when the transport security settings of the artifact were audited
+	NetworkSecurityChecked bool
+	LanguageOverhead       LanguageOverhead
+	// LanguageOverheadRead is set when the language runtimes of the artifact were measured
+	LanguageOverheadRead bool
+	WhatIf               []WhatIfScenario
+	// WhatIfChecked is set when the whatif_scenarios were simulated on the artifact
+	WhatIfChecked bool
+	DebugSymbols  DebugSymbols
+	// SymbolsChecked is set when the debug symbols of the artifact were looked up
+	SymbolsChecked bool
+	// PluginDuration is the time bundle-inspector ran, zero for cached and imported reports
+	PluginDuration time.Duration
+	TimedOut       bool
+	Canceled       bool
+	Cached         bool
+	Violations     []ThresholdViolation
+}
+
+// ArtifactName returns the file name of the artifact, used to identify it in reports and the build history
+func ArtifactName(artifactPath string) string {
+	return filepath.Base(artifactPath)
+}
+
+// Analyzer runs bundle-inspector and the content-based analysis on artifacts
+type Analyzer struct {
+	cmdFactory command.Factory
+	envRepo    env.Repository
+	logger     log.Logger
+}
+
+// NewAnalyzer returns an Analyzer running bundle-inspector with the command factory
+func NewAnalyzer(cmdFactory command.Factory, envRepo env.Repository, logger log.Logger) Analyzer {
+	return Analyzer{cmdFactory: cmdFactory, envRepo: envRepo, logger: logger}
+}
+
+// Analyze runs bundle-inspector and the content-based analysis on a single artifact
+func (a Analyzer) Analyze(ctx context.Context, cfg config.Config, artifactPath, workDir string, history *History, retry executor.RetryOptions) (ArtifactResult, error) {
+	logger := a.logger
+	result := ArtifactResult{
+		ArtifactPath: artifactPath,
+		Name:         ArtifactName(artifactPath),
+	}
+
+	// Run bundle-inspector, or use the report generated earlier in the pipeline
+	logger.Println()
+	var manifest *pluginManifest
+	if cfg.ExistingReportPath != "" {
+		logger.Infof("Using existing report: %s", cfg.ExistingReportPath)
+		if err := importExistingReport(cfg.ExistingReportPath, artifactPath, workDir, cfg.Units(), logger); err != nil {
+			return result, err
+		}
+	} else {
+		cacheKey := ""
+		if cfg.ResultCacheDir != "" {
+			var err error
+			if pluginVersion := a.pluginVersion(cfg); pluginVersion == "" {
+				logger.Warnf("Failed to determine the installed bundle-inspector version, skipping the result cache")
+			} else if cacheKey, err = resultCacheKey(cfg, artifactPath, pluginVersion); err != nil {
+				logger.Warnf("Failed to compute result cache key: %s", err)
+			} else if manifest, result.Cached, err = a.restoreCachedResult(cfg.ResultCacheDir, cacheKey, workDir); err != nil {
+				logger.Warnf("Failed to restore cached analysis: %s", err)
+			} else if result.Cached {
+				logger.Donef("Restored the analysis of %s from the result cache, skipping bundle-inspector", result.Name)
+			} else {
+				logger.Printf("No cached analysis of %s found in %s", result.Name, cfg.ResultCacheDir)
+			}
+		}
+
+		if !result.Cached {
+			logger.Infof("Running bundle-inspector analysis...")
+			var err error
+			pluginStart := time.Now()
+			manifest, err = a.runBundleInspector(ctx, artifactPath, cfg.PluginOutputFormats(), workDir, cfg.LogLevel == logging.LevelDebug, reportLocaleEnv(cfg.ReportLocale, cfg.ReportTimezone), retry)
+			result.PluginDuration = time.Since(pluginStart)
+			if errors.Is(err, ErrAnalysisTimedOut) {
+				logger.Warnf("bundle-inspector did not finish within analysis_timeout, using the reports generated so far")
+				result.TimedOut = true
+			} else if errors.Is(err, ErrAnalysisCanceled) {
+				logger.Warnf("Step canceled during the analysis, using the reports generated so far")
+				result.Canceled = true
+			} else if err != nil {
+				return result, err
+			} else if cacheKey != "" {
+				if err := a.saveCachedResult(cfg.ResultCacheDir, cacheKey, artifactPath, workDir, manifest); err != nil {
+					logger.Warnf("Failed to cache analysis: %s", err)
+				}
+			}
+		}
+	}
+
+	// Find generated report files
+	logger.Println()
+	logger.Infof("Locating generated report files...")
+	generatedFiles, err := findGeneratedReports(workDir, artifactPath, logger)
+	if err != nil {
+		logger.Warnf("Failed to locate reports: %s", err)
+	}
+	result.GeneratedFiles = generatedFiles
+	if result.TimedOut && generatedFiles == (ReportPaths{}) {
+		return result, fmt.Errorf("bundle-inspector timed out before generating any report")
+	}
+	if result.Canceled && generatedFiles == (ReportPaths{}) {
+		return result, fmt.Errorf("step canceled before bundle-inspector generated any report")
+	}
+
+	// Take the metrics from the plugin's machine output, or parse them from the JSON report
+	if metrics, ok := manifest.bundleMetrics(); ok {
+		logger.Println()
+		logger.Printf("Metrics reported by bundle-inspector: %s, %d bytes potential savings", cfg.Units().Format(metrics.SizeBytes), metrics.PotentialSavingsBytes)
+		result.Metrics = metrics
+	} else if (cfg.HasPluginOutputFormat("json") || cfg.ExistingReportPath != "") && generatedFiles.JSON != "" {
+		logger.Println()
+		logger.Infof("Parsing JSON report for metrics...")
+		result.Metrics, err = ParseJSONReport(generatedFiles.JSON, logger)
+		if err != nil {
+			logger.Warnf("Failed to parse JSON report (will use empty metrics): %s", err)
+		}
+	}
+
+	// Artifacts above 4 GB are zip64 archives, a 32-bit size means the report was generated without zip64 support
+	if sizeBytes, truncated := correctTruncatedSize(result.Metrics.SizeBytes, artifactPath); truncated {
+		logger.Warnf("The report states %d bytes for the %d bytes artifact (32-bit overflow), using the file size", result.Metrics.SizeBytes, sizeBytes)
+		result.Metrics.SizeBytes = sizeBytes
+		result.Metrics.SizeMB = newBundleMetrics(sizeBytes, 0).SizeMB
+	}
+
+	// Read artifact contents for content-based checks, skipped when the step is canceled to flush the results quickly
+	if errors.Is(ctx.Err(), context.Canceled) {
+		logger.Warnf("Step canceled, skipping content-based checks")
+	} else if _, err := os.Stat(artifactPath); err == nil {
+		logger.Println()
+		logger.Infof("Reading artifact contents...")
+		result.Inventory, err = readInventory(artifactPath)
+		if err != nil {
+			logger.Warnf("Failed to read artifact contents: %s", err)
+		} else {
+			logger.Printf("Found %d files in the artifact", len(result.Inventory.Entries))
+			result.Metrics.FileCount = int64(len(result.Inventory.Entries))
+			if NewEntryScope(cfg).Limited() {
+				applyEntryScopeFromConfig(cfg, &result, logger)
+			}
+			if cfg.QuickAnalysis() {
+				logger.Printf("Download size estimation skipped with analysis_scope %s", cfg.AnalysisScope)
+			} else {
+				result.DownloadBytes, result.DownloadCompression = estimateDownloadSizeFromConfig(ctx, cfg, artifactPath, result.Inventory, a.envRepo.List(), logger)
+			}
+		}
+		if cfg.ReportTitle != "" || cfg.DatadogAPIKey != "" || cfg.NewRelicLicenseKey != "" {
+			result.App = readAppIdentityFromConfig(artifactPath, logger)
+		}
+
+		result.DexMethodCounts, err = readDexMethodCounts(artifactPath)
+		if err != nil {
+			logger.Warnf("Failed to read DEX method counts: %s", err)
+		}
+		for _, dexPath := range SortedKeys(result.DexMethodCounts) {
+			logger.Printf("%s: %d method references", dexPath, result.DexMethodCounts[dexPath])
+			result.Metrics.DexMethodCount += result.DexMethodCounts[dexPath]
+		}
+
+		if len(result.DexMethodCounts) > 0 {
+			classes, obfuscated, err := readObfuscationCoverage(artifactPath)
+			switch {
+			case err != nil:
+				logger.Warnf("Failed to measure obfuscation coverage: %s", err)
+			case classes > 0:
+				result.Metrics.ObfuscationCoverage = float64(obfuscated) * 100 / float64(classes)
+				result.Metrics.ObfuscationMeasured = true
+				logger.Printf("Obfuscation coverage: %.1f%% (%d of %d classes)", result.Metrics.ObfuscationCoverage, obfuscated, classes)
+			}
+		}
+
+		if cfg.ScanSecrets || cfg.FailOnSecrets {
+			logger.Println()
+			result.Secrets, result.SecretsScanned = scanSecretsFromConfig(cfg, artifactPath, logger)
+		}
+
+		if cfg.ScanVulnerableSDKs || cfg.FailOnVulnerableSDKs != "" {
+			logger.Println()
+			result.VulnerableSDKs, result.SDKsScanned = scanVulnerableSDKsFromConfig(cfg, artifactPath, logger)
+		}
+
+		if cfg.LicenseInventory || cfg.DeniedLicenses != "" {
+			logger.Println()
+			result.Licenses, result.GeneratedFiles.Licenses, result.LicensesRead = readLicensesFromConfig(cfg, artifactPath, workDir, logger)
+		}
+
+		if cfg.VerifySignature || cfg.FailOnUnsigned || cfg.CertExpiryWarningDays != "" {
+			logger.Println()
+			result.Signature, result.SignatureVerified = verifyAndLogSignature(artifactPath, workDir, logger)
+			if result.SignatureVerified && cfg.CertExpiryWarningDays != "" {
+				warnCertificateExpiry(cfg.CertExpiryWarningDays, result.Signature, time.Now(), logger)
+			}
+		}
+
+		if cfg.ProvisioningProfileReport || cfg.ExpectedDistribution != "" {
+			logger.Println()
+			result.Profile, result.ProfileRead = readProvisioningProfileFromConfig(artifactPath, cfg.CertExpiryWarningDays, time.Now(), logger)
+		}
+
+		if cfg.ValidatePrivacyManifest || cfg.FailOnPrivacyManifest {
+			logger.Println()
+			result.Privacy, result.PrivacyChecked = validatePrivacyManifestsFromConfig(artifactPath, workDir, logger)
+		}
+
+		if cfg.TrackPermissions || cfg.FailOnNewPermissions {
+			logger.Println()
+			result.Permissions, result.PermissionsRead = readPermissionsFromConfig(artifactPath, workDir, logger)
+		}
+
+		if cfg.AttackSurfaceReport {
+			logger.Println()
+			result.AttackSurface, result.AttackSurfaceRead = readAttackSurfaceFromConfig(artifactPath, workDir, logger)
+		}
+
+		if cfg.SBOMDiff {
+			logger.Println()
+			result.Libraries, result.LibrariesRead = readLibrariesFromConfig(artifactPath, logger)
+		}
+
+		if cfg.DetectDebugArtifacts || cfg.FailOnDebugArtifacts {
+			logger.Println()
+			result.DebugFindings, result.DebugChecked = detectDebugArtifactsFromConfig(artifactPath, workDir, result.Signature, result.SignatureVerified, logger)
+		}
+
+		if (cfg.CheckNativeHardening || cfg.FailOnUnhardened != "") && cfg.QuickAnalysis() {
+			logger.Println()
+			logger.Warnf("Native binary hardening check skipped with analysis_scope %s", cfg.AnalysisScope)
+		} else if cfg.CheckNativeHardening || cfg.FailOnUnhardened != "" {
+			logger.Println()
+			result.Hardening, result.HardeningChecked = checkNativeHardeningFromConfig(artifactPath, workDir, logger)
+		}
+
+		if (cfg.HashReputationURL != "" || cfg.HashAllowlist != "" || cfg.HashDenylist != "") && cfg.QuickAnalysis() {
+			logger.Println()
+			logger.Warnf("Native binary screening skipped with analysis_scope %s", cfg.AnalysisScope)
+		} else if cfg.HashReputationURL != "" || cfg.HashAllowlist != "" || cfg.HashDenylist != "" {
+			logger.Println()
+			result.Reputation, result.ReputationChecked = screenNativeBinariesFromConfig(ctx, cfg, artifactPath, retry, logger)
+		}
+
+		if cfg.NetworkSecurityAudit {
+			logger.Println()
+			result.NetworkSecurity, result.NetworkSecurityChecked = auditNetworkSecurityFromConfig(artifactPath, logger)
+		}
+
+		if cfg.LanguageOverheadReport {
+			logger.Println()
+			result.LanguageOverhead, result.LanguageOverheadRead = readLanguageOverheadFromConfig(cfg, artifactPath, result.Scope, logger)
+		}
+
+		if cfg.WhatIfScenarios != "" && len(result.Inventory.Entries) > 0 {
+			logger.Println()
+			result.WhatIf, result.WhatIfChecked = simulateWhatIfFromConfig(cfg, artifactPath, result, logger)
+		}
+
+		if cfg.CheckDebugSymbols {
+			logger.Println()
+			result.DebugSymbols, result.SymbolsChecked = checkDebugSymbolsFromConfig(artifactPath, result, a.envRepo, logger)
+		}
+
+		result.DebugBuildReasons, result.DebugBuildChecked = detectDebugBuildFromConfig(artifactPath, result, logger)
+	}
+
+	// Compare with the baseline build recorded in the history file
+	if history != nil {
+		logger.Println()
+		if baseline, ok := history.Baseline(cfg.BaselineBranch, result.Name); ok {
+			comparison := compareWithBaseline(result.Metrics, baseline)
+			result.Comparison = &comparison
+			logger.Infof("Baseline: build #%s on %s (%s)", baseline.BuildNumber, baseline.Branch, baseline.Commit)
+			logger.Printf("Size: %s (%s)", cfg.Units().Format(result.Metrics.SizeBytes), cfg.Units().FormatDelta(comparison.SizeDeltaBytes))
+			logger.Printf("File count: %d (%+d)", result.Metrics.FileCount, comparison.FileCountDelta)
+			if result.PermissionsRead {
+				comparison.Permissions = comparePermissionsFromConfig(cfg, result.Permissions, baseline, logger)
+			}
+			if result.AttackSurfaceRead {
+				comparison.AttackSurface = compareAttackSurfaceFromConfig(result.AttackSurface, baseline, logger)
+			}
+			if result.LibrariesRead {
+				comparison.Libraries = compareLibrariesFromConfig(result.Libraries, baseline, logger)
+			}
+		} else {
+			logger.Infof("No baseline build of %s recorded for branch %s yet", result.Name, cfg.BaselineBranch)
+		}
+
+		if smallest, ok := history.Smallest(cfg.BaselineBranch, result.Name); ok {
+			result.RatchetRecord = &smallest
+		}
+	}
+
+	// Evaluate module budgets
+	if cfg.ModuleBudgets != "" {
+		logger.Println()
+		result.ModuleResults = evaluateModuleBudgetsFromConfig(cfg, result.Inventory, logger)
+	}
+
+	return result, nil
+}
+
+// runBundleInspector executes the bundle-inspector plugin.
+// Plugins supporting --machine-output describe the generated reports and metrics in the returned manifest,
+// the manifest is nil for older plugin versions and the reports are located by their names.
+// The full output of the plugin is only logged with debug, or when it fails. localeEnv sets the locale and timezone
+// of the reports.
+func (a Analyzer) runBundleInspector(ctx context.Context, artifactPath, formats, workingDir string, debug bool, localeEnv []string, retry executor.RetryOptions) (*pluginManifest, error) {
+	logger := a.logger
+
+	// Unset BITRISE_DEPLOY_DIR to prevent bundle-inspector from auto-exporting
+	// We'll handle the deployment ourselves to have full control over output location
+	pluginEnv := append([]string{"BITRISE_DEPLOY_DIR="}, localeEnv...)
+
+	args := []string{":bundle-inspector", "analyze", artifactPath, "-o", formats}
+	machineOutput := a.supportsMachineOutput()
+	if machineOutput {
+		args = append(args, machineOutputFlag)
+	} else {
+		logger.Printf("bundle-inspector doesn't support %s, the reports are located by their names", machineOutputFlag)
+	}
+	logger.Printf("$ %s", a.cmdFactory.Create("bitrise", args, nil).PrintableCommandArgs())
+	logger.Printf("Working directory: %s", workingDir)
+
+	if deadline, ok := ctx.Deadline(); ok {
+		logger.Printf("Timeout: %s", time.Until(deadline).Round(time.Second))
+	}
+
+	// The plugin output is only logged once it exits, the progress lines show the step is not hung meanwhile
+	running := startProgress("bundle-inspector", 0, 0, logger)
+	var stdout bytes.Buffer
+	// analysis_timeout limits the analysis as a whole, command_timeout doesn't apply to it
+	retry.Timeout = 0
+	out, err := executor.Retry(ctx, retry, "Analysis", logger, func(ctx context.Context) (string, error) {
+		// Drop the reports of a failed attempt, only the artifact's own reports so other files in the directory are kept
+		for _, ext := range reportExtensions {
+			_ = os.Remove(filepath.Join(workingDir, ReportName(artifactPath, ext)))
+		}
+
+		// With machine output stdout only holds the manifest, the log of the plugin goes to stderr
+		stdout.Reset()
+		var manifestOutput io.Writer
+		if machineOutput {
+			manifestOutput = &stdout
+		}
+
+		// A context that can't be canceled doesn't need the process group handling
+		if ctx.Done() != nil {
+			out, err := executor.RunCommand(ctx, "bundle-inspector", workingDir, append(a.envRepo.List(), pluginEnv...), "bitrise", args, manifestOutput, logger)
+			return out, analysisError(err)
+		}
+		if machineOutput {
+			var stderr bytes.Buffer
+			err := a.cmdFactory.Create("bitrise", args, &command.Opts{Dir: workingDir, Env: pluginEnv, Stdout: manifestOutput, Stderr: &stderr}).Run()
+			return strings.TrimSpace(stderr.String()), err
+		}
+		return a.cmdFactory.Create("bitrise", args, &command.Opts{Dir: workingDir, Env: pluginEnv}).RunAndReturnTrimmedCombinedOutput()
+	})
+	running.finish()
+
+	// Keep the output for the debug bundle
+	if writeErr := os.WriteFile(filepath.Join(workingDir, PluginOutputName), []byte(out+"\n"), 0644); writeErr != nil {
+		logger.Warnf("Failed to save bundle-inspector output: %s", writeErr)
+	}
+	if err != nil {
+		if out != "" {
+			logger.Printf("%s", out)
+		}
+		if errors.Is(err, ErrAnalysisTimedOut) || errors.Is(err, ErrAnalysisCanceled) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("bundle-inspector failed: %w", err)
+	}
+
+	// Only print output in debug mode to avoid duplicate logging
+	// (we'll log the located files separately)
+	if out != "" && debug {
+		logger.Printf("%s", out)
+	}
+
+	if !machineOutput {
+		return nil, nil
+	}
+	manifest, err := parsePluginManifest(stdout.String())
+	if err == nil {
+		err = manifest.applyTo(workingDir, artifactPath)
+	}
+	if err != nil {
+		logger.Warnf("%s, locating the reports by their names", err)
+		return nil, nil
+	}
+	return manifest, nil
+}
+
+// pluginVersion returns the version of the installed bundle-inspector plugin the reports are generated with, the pinned
+// plugin_version if the installed one can't be determined
+func (a Analyzer) pluginVersion(cfg config.Config) string {
+	if _, version, err := installedBundleInspector(a.cmdFactory); err == nil && version != "" {
+		return version
+	}
+	return strings.TrimPrefix(cfg.PluginVersion, "v")
+}
+
+// reportLocaleEnv returns the environment making bundle-inspector write its reports in report_locale and report_timezone:
+// LC_ALL and LANG in POSIX form (de_DE.UTF-8 for de-DE) and TZ
+func reportLocaleEnv(locale, timezone string) []string {
+	var env []string
+	if locale != "" {
+		name, _, _ := strings.Cut(locale, ".")
+		posix := strings.ReplaceAll(name, "-", "_") + ".UTF-8"
+		env = append(env, "LC_ALL="+posix, "LANG="+posix)
+	}
+	if timezone != "" {
+		env = append(env, "TZ="+timezone)
+	}
+	return env
+}
+
+// supportsMachineOutput checks the usage of the installed plugin's analyze command for --machine-output
+func (a Analyzer) supportsMachineOutput() bool {
+	out, err := a.cmdFactory.Create("bitrise", []string{":bundle-inspector", "analyze", "--help"}, nil).RunAndReturnTrimmedCombinedOutput()
+	return err == nil && strings.Contains(out, machineOutputFlag)
+}
+
+// reportExtensions are the extensions of the markdown, HTML, JSON and text reports of bundle-inspector
+var reportExtensions = []string{".md", ".html", ".json", ".txt"}
+
+// ReportName returns the file name bundle-inspector gives the report of the artifact with the extension,
+// e.g. bundle-analysis-app-release.json for app-release.apk. The reports rendered by the step are named alike.
+func ReportName(artifactPath, ext string) string {
+	name := ArtifactName(artifactPath)
+	return "bundle-analysis-" + strings.TrimSuffix(name, filepath.Ext(name)) + ext
+}
+
+// findGeneratedReports locates the reports bundle-inspector generated for the artifact.
+// Only the artifact's own file names are accepted, so reports of other artifacts or earlier runs in the directory are never picked up.
+func findGeneratedReports(searchDir, artifactPath string, logger log.Logger) (ReportPaths, error) {
+	find := func(ext string) string {
+		reportPath := filepath.Join(searchDir, ReportName(artifactPath, ext))
+		if _, err := os.Stat(reportPath); err != nil {
+			return ""
+		}
+		logger.Printf("Found: %s", reportPath)
+		return reportPath
+	}
+
+	paths := ReportPaths{
+		Markdown: find(".md"),
+		HTML:     find(".html"),
+		JSON:     find(".json"),
+	}
+	if paths == (ReportPaths{}) {
+		return paths, fmt.Errorf("no %s report found in %s", ReportName(artifactPath, ".*"), searchDir)
+	}
+	return paths, nil
+}
+
+// ParseJSONReport extracts metrics from the JSON report
+func ParseJSONReport(jsonPath string, logger log.Logger) (BundleMetrics, error) {
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return BundleMetrics{}, fmt.Errorf("failed to read JSON report: %w", err)
+	}
+
+	var report struct {
+		ArtifactInfo struct {
+			Size          reportSize `json:"size"`
+			SizeFormatted string     `json:"size_formatted"`
+		} `json:"artifact_info"`
+		PotentialSavings reportSize `json:"potential_savings"`
+	}
+
+	if err := json.Unmarshal(data, &report); err != nil {
+		return BundleMetrics{}, fmt.Errorf("failed to parse JSON report: %w", err)
+	}
+
+	return newBundleMetrics(int64(report.ArtifactInfo.Size), int64(report.PotentialSavings)), nil
+}
+
+// newBundleMetrics returns the metrics of bundle-inspector's analysis, the content-based metrics are added later
+func newBundleMetrics(sizeBytes, potentialSavingsBytes int64) BundleMetrics {
+	return BundleMetrics{
+		SizeBytes:             sizeBytes,
+		SizeMB:                fmt.Sprintf("%.2f", float64(sizeBytes)/(1024*1024)),
+		PotentialSavingsBytes: potentialSavingsBytes,
+	}
+}
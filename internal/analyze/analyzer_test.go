@@ -0,0 +1,336 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// fakeEnvRepository is an env.Repository backed by a map
+type fakeEnvRepository map[string]string
+
+func (r fakeEnvRepository) List() []string {
+	var envs []string
+	for key, value := range r {
+		envs = append(envs, key+"="+value)
+	}
+	return envs
+}
+
+func (r fakeEnvRepository) Unset(key string) error {
+	delete(r, key)
+	return nil
+}
+
+func (r fakeEnvRepository) Get(key string) string {
+	return r[key]
+}
+
+func (r fakeEnvRepository) Set(key, value string) error {
+	r[key] = value
+	return nil
+}
+
+// fakeCommandFactory creates commands answered by run instead of executing them, the invoked argument lists are
+// recorded
+type fakeCommandFactory struct {
+	run   func(args []string, opts *command.Opts) (string, error)
+	calls *[]string
+}
+
+func (f fakeCommandFactory) Create(name string, args []string, opts *command.Opts) command.Command {
+	return fakeCommand{factory: f, name: name, args: args, opts: opts}
+}
+
+type fakeCommand struct {
+	factory fakeCommandFactory
+	name    string
+	args    []string
+	opts    *command.Opts
+}
+
+func (c fakeCommand) PrintableCommandArgs() string {
+	return strings.Join(append([]string{c.name}, c.args...), " ")
+}
+
+func (c fakeCommand) Run() error {
+	_, err := c.RunAndReturnTrimmedCombinedOutput()
+	return err
+}
+
+func (c fakeCommand) RunAndReturnExitCode() (int, error) {
+	if err := c.Run(); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func (c fakeCommand) RunAndReturnTrimmedOutput() (string, error) {
+	return c.RunAndReturnTrimmedCombinedOutput()
+}
+
+func (c fakeCommand) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	*c.factory.calls = append(*c.factory.calls, strings.Join(c.args, " "))
+	out, err := c.factory.run(c.args, c.opts)
+	if c.opts != nil && c.opts.Stdout != nil {
+		_, _ = fmt.Fprint(c.opts.Stdout, out)
+		return "", err
+	}
+	return out, err
+}
+
+func (c fakeCommand) Start() error {
+	return c.Run()
+}
+
+func (c fakeCommand) Wait() error {
+	return nil
+}
+
+// fakeBundleInspector answers the bitrise commands like a bundle-inspector plugin writing the reports of the formats
+// into the working directory. With machineOutput the reports are written to a subdirectory and listed in the manifest.
+func fakeBundleInspector(machineOutput bool, formats ...string) func(args []string, opts *command.Opts) (string, error) {
+	return func(args []string, opts *command.Opts) (string, error) {
+		switch {
+		case args[0] == "plugin":
+			return "⚡️ bundle-inspector (1.4.0)", nil
+		case len(args) > 2 && args[2] == "--help":
+			if machineOutput {
+				return "Flags:\n  " + machineOutputFlag, nil
+			}
+			return "Flags:\n  -o", nil
+		}
+
+		artifactPath := args[2]
+		reportDir := opts.Dir
+		if machineOutput {
+			reportDir = filepath.Join(opts.Dir, "out")
+			if err := os.MkdirAll(reportDir, 0755); err != nil {
+				return "", err
+			}
+		}
+		var files []string
+		for _, format := range formats {
+			reportPath := filepath.Join(reportDir, ReportName(artifactPath, manifestFormatExtensions[format]))
+			content := "# Report"
+			if format == "json" {
+				content = `{"artifact_info": {"size": 5242880}, "potential_savings": 1024}`
+			}
+			if err := os.WriteFile(reportPath, []byte(content), 0644); err != nil {
+				return "", err
+			}
+			files = append(files, fmt.Sprintf("%q: %q", format, reportPath))
+		}
+		if machineOutput {
+			return fmt.Sprintf(`{"schema_version": 1, "files": {%s}, "metrics": {"size_bytes": 7340032, "potential_savings_bytes": 2048}}`, strings.Join(files, ", ")), nil
+		}
+		return "Analysis complete", nil
+	}
+}
+
+func TestAnalyzeReportDiscovery(t *testing.T) {
+	tests := []struct {
+		name          string
+		envs          fakeEnvRepository
+		machineOutput bool
+		generated     []string
+		wantFormats   string
+		wantJSON      bool
+		wantMarkdown  bool
+		wantHTML      bool
+		wantSizeBytes int64
+	}{
+		{name: "default formats", envs: fakeEnvRepository{}, generated: []string{"json", "markdown"}, wantFormats: "json,markdown", wantJSON: true, wantMarkdown: true, wantSizeBytes: 5242880},
+		{name: "step formats only", envs: fakeEnvRepository{"output_formats": "csv,sarif"}, generated: []string{"json"}, wantFormats: "json", wantJSON: true, wantSizeBytes: 5242880},
+		{name: "html without json", envs: fakeEnvRepository{"output_formats": "html"}, generated: []string{"html"}, wantFormats: "html", wantHTML: true},
+		{name: "machine output", envs: fakeEnvRepository{"output_formats": "markdown,json"}, machineOutput: true, generated: []string{"markdown", "json"}, wantFormats: "markdown,json", wantJSON: true, wantMarkdown: true, wantSizeBytes: 7340032},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Parse(tt.envs)
+			if err != nil {
+				t.Fatalf("config.Parse() error = %s", err)
+			}
+			workDir := t.TempDir()
+			artifactPath := filepath.Join(t.TempDir(), "app-release.apk")
+			// A report left by an earlier run must not be picked up
+			if err := os.WriteFile(filepath.Join(workDir, ReportName(artifactPath, ".html")), []byte("stale"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			var calls []string
+			factory := fakeCommandFactory{run: fakeBundleInspector(tt.machineOutput, tt.generated...), calls: &calls}
+			result, err := NewAnalyzer(factory, tt.envs, log.NewLogger()).Analyze(context.Background(), cfg, artifactPath, workDir, nil, executor.RetryOptions{})
+			if err != nil {
+				t.Fatalf("Analyze() error = %s", err)
+			}
+
+			wantCall := ":bundle-inspector analyze " + artifactPath + " -o " + tt.wantFormats
+			if !containsCallPrefix(calls, wantCall) {
+				t.Errorf("bundle-inspector calls = %q, want %q", calls, wantCall)
+			}
+			paths := result.GeneratedFiles
+			if got := paths.JSON != ""; got != tt.wantJSON {
+				t.Errorf("JSON found = %t, want %t", got, tt.wantJSON)
+			}
+			if got := paths.Markdown != ""; got != tt.wantMarkdown {
+				t.Errorf("Markdown found = %t, want %t", got, tt.wantMarkdown)
+			}
+			if got := paths.HTML != ""; got != tt.wantHTML {
+				t.Errorf("HTML found = %t, want %t", got, tt.wantHTML)
+			}
+			for _, reportPath := range []string{paths.JSON, paths.Markdown, paths.HTML} {
+				if reportPath != "" && filepath.Dir(reportPath) != workDir {
+					t.Errorf("report %s is not in the work directory", reportPath)
+				}
+			}
+			if result.Metrics.SizeBytes != tt.wantSizeBytes {
+				t.Errorf("SizeBytes = %d, want %d", result.Metrics.SizeBytes, tt.wantSizeBytes)
+			}
+		})
+	}
+}
+
+func TestAnalyzePluginFailure(t *testing.T) {
+	cfg, err := config.Parse(fakeEnvRepository{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	factory := fakeCommandFactory{calls: &calls, run: func(args []string, opts *command.Opts) (string, error) {
+		if args[0] == "plugin" || args[len(args)-1] == "--help" {
+			return "", nil
+		}
+		return "Error: unsupported artifact", fmt.Errorf("exit status 2")
+	}}
+	_, err = NewAnalyzer(factory, fakeEnvRepository{}, log.NewLogger()).Analyze(context.Background(), cfg, "/builds/app.apk", t.TempDir(), nil, executor.RetryOptions{})
+	if err == nil || !strings.Contains(err.Error(), "bundle-inspector failed") {
+		t.Errorf("Analyze() error = %v, want bundle-inspector failure", err)
+	}
+}
+
+func TestAnalyzeRetryDropsFailedAttemptReports(t *testing.T) {
+	cfg, err := config.Parse(fakeEnvRepository{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	workDir := t.TempDir()
+	artifactPath := filepath.Join(t.TempDir(), "app-release.apk")
+	otherReport := filepath.Join(workDir, ReportName("/builds/app-debug.apk", ".md"))
+	if err := os.WriteFile(otherReport, []byte("# Other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first attempt fails after writing the markdown report, the second one only writes the JSON report
+	attempts := 0
+	succeeding := fakeBundleInspector(false, "json")
+	factory := fakeCommandFactory{calls: &[]string{}, run: func(args []string, opts *command.Opts) (string, error) {
+		if args[0] == "plugin" || args[len(args)-1] == "--help" {
+			return succeeding(args, opts)
+		}
+		if attempts++; attempts == 1 {
+			if err := os.WriteFile(filepath.Join(opts.Dir, ReportName(artifactPath, ".md")), []byte("# Partial"), 0644); err != nil {
+				return "", err
+			}
+			return "connection reset by peer", fmt.Errorf("exit status 1")
+		}
+		return succeeding(args, opts)
+	}}
+	retry := executor.RetryOptions{Count: 1, Wait: time.Millisecond}
+	result, err := NewAnalyzer(factory, fakeEnvRepository{}, log.NewLogger()).Analyze(context.Background(), cfg, artifactPath, workDir, nil, retry)
+	if err != nil {
+		t.Fatalf("Analyze() error = %s", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("got %d attempts, want 2", attempts)
+	}
+	if result.GeneratedFiles.Markdown != "" || result.GeneratedFiles.JSON == "" {
+		t.Errorf("GeneratedFiles = %+v, want only the JSON report of the second attempt", result.GeneratedFiles)
+	}
+	if _, err := os.Stat(otherReport); err != nil {
+		t.Errorf("report of another artifact was removed: %s", err)
+	}
+}
+
+func TestAnalyzeResultCache(t *testing.T) {
+	envs := fakeEnvRepository{"output_formats": "markdown", "result_cache_dir": t.TempDir()}
+	cfg, err := config.Parse(envs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifactPath := filepath.Join(t.TempDir(), "app-release.apk")
+	if err := os.WriteFile(artifactPath, []byte("artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	factory := fakeCommandFactory{run: fakeBundleInspector(true, "markdown"), calls: &calls}
+	analyzer := NewAnalyzer(factory, envs, log.NewLogger())
+	for _, wantCached := range []bool{false, true} {
+		result, err := analyzer.Analyze(context.Background(), cfg, artifactPath, t.TempDir(), nil, executor.RetryOptions{})
+		if err != nil {
+			t.Fatalf("Analyze() error = %s", err)
+		}
+		if result.Cached != wantCached {
+			t.Errorf("Cached = %t, want %t", result.Cached, wantCached)
+		}
+		// Without the JSON report the metrics of a cached analysis come from the cached machine output
+		if result.Metrics.SizeBytes != 7340032 || result.Metrics.PotentialSavingsBytes != 2048 {
+			t.Errorf("Metrics = %+v, want the metrics of the machine output", result.Metrics)
+		}
+		if result.GeneratedFiles.Markdown == "" {
+			t.Errorf("GeneratedFiles = %+v, want the markdown report", result.GeneratedFiles)
+		}
+	}
+
+	analyses := 0
+	for _, call := range calls {
+		if strings.HasPrefix(call, ":bundle-inspector analyze "+artifactPath) {
+			analyses++
+		}
+	}
+	if analyses != 1 {
+		t.Errorf("bundle-inspector analyzed the artifact %d times, want 1", analyses)
+	}
+}
+
+func TestFindGeneratedReports(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"bundle-analysis-app.json", "bundle-analysis-app.md", "bundle-analysis-app-debug.html"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := findGeneratedReports(dir, "/builds/app.apk", log.NewLogger())
+	if err != nil {
+		t.Fatalf("findGeneratedReports() error = %s", err)
+	}
+	want := ReportPaths{JSON: filepath.Join(dir, "bundle-analysis-app.json"), Markdown: filepath.Join(dir, "bundle-analysis-app.md")}
+	if paths != want {
+		t.Errorf("findGeneratedReports() = %+v, want %+v", paths, want)
+	}
+
+	if _, err := findGeneratedReports(dir, "/builds/other.ipa", log.NewLogger()); err == nil {
+		t.Errorf("findGeneratedReports() found reports of another artifact")
+	}
+}
+
+func containsCallPrefix(calls []string, prefix string) bool {
+	for _, item := range calls {
+		if strings.HasPrefix(item, prefix) {
+			return true
+		}
+	}
+	return false
+}
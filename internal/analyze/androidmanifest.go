@@ -0,0 +1,444 @@
+package analyze
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Android manifest locations: APKs compile it to binary XML, AABs to the protobuf format of aapt2
+const (
+	apkManifestPath = "AndroidManifest.xml"
+	aabManifestPath = "base/manifest/AndroidManifest.xml"
+)
+
+// Binary XML chunk types, see ResourceTypes.h of the Android framework
+const (
+	axmlChunkStringPool   = 0x0001
+	axmlChunkXML          = 0x0003
+	axmlChunkResourceMap  = 0x0180
+	axmlChunkStartElement = 0x0102
+	axmlChunkEndElement   = 0x0103
+	axmlChunkCData        = 0x0104
+	axmlStringPoolUTF8    = 0x100
+	axmlNoEntry           = 0xffffffff
+)
+
+// Binary XML typed value types
+const (
+	axmlTypeReference = 0x01
+	axmlTypeString    = 0x03
+	axmlTypeIntHex    = 0x11
+	axmlTypeBoolean   = 0x12
+)
+
+// androidAttributeNames names the framework attributes by resource ID, shrunk manifests drop the attribute names
+var androidAttributeNames = map[uint32]string{
+	0x01010003: "name",
+	0x01010006: "permission",
+	0x0101000f: "debuggable",
+	0x01010010: "exported",
+	0x01010018: "authorities",
+	0x01010027: "scheme",
+	0x01010028: "host",
+	0x01010029: "port",
+	0x0101002a: "path",
+	0x0101002b: "pathPrefix",
+	0x0101002c: "pathPattern",
+	0x0101020c: "minSdkVersion",
+	0x01010270: "targetSdkVersion",
+	0x01010272: "testOnly",
+	0x01010280: "allowBackup",
+	0x010104ea: "extractNativeLibs",
+	0x010104ec: "usesCleartextTraffic",
+	0x010104ee: "autoVerify",
+	0x01010527: "networkSecurityConfig",
+}
+
+// manifestElement is an element of an Android manifest or XML resource, attributes are keyed by their name without namespace
+type manifestElement struct {
+	Name     string
+	Attrs    map[string]string
+	Children []*manifestElement
+	// Text is the character data of the element, trimmed
+	Text string
+}
+
+// ChildrenNamed returns the child elements with the name
+func (e *manifestElement) ChildrenNamed(name string) []*manifestElement {
+	var children []*manifestElement
+	for _, child := range e.Children {
+		if child.Name == name {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// readAndroidManifest reads and parses the manifest of an APK or the base module of an AAB
+func readAndroidManifest(reader *zip.Reader) (*manifestElement, error) {
+	for _, file := range reader.File {
+		if file.Name != apkManifestPath && file.Name != aabManifestPath {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		if file.Name == aabManifestPath {
+			return parseProtoXML(data)
+		}
+		return parseBinaryXML(data)
+	}
+	return nil, fmt.Errorf("no AndroidManifest.xml found")
+}
+
+// parseAndroidXML parses a compiled XML file of an APK (binary XML) or AAB (proto XML) and returns its root element
+func parseAndroidXML(data []byte) (*manifestElement, error) {
+	if len(data) >= 4 && binary.LittleEndian.Uint16(data) == axmlChunkXML && binary.LittleEndian.Uint16(data[2:]) == 8 {
+		return parseBinaryXML(data)
+	}
+	return parseProtoXML(data)
+}
+
+// parseBinaryXML parses an Android binary XML document and returns its root element
+func parseBinaryXML(data []byte) (*manifestElement, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != axmlChunkXML {
+		return nil, fmt.Errorf("invalid binary XML: unexpected header")
+	}
+
+	var pool []string
+	var resourceIDs []uint32
+	var root *manifestElement
+	var stack []*manifestElement
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	for offset+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[offset:])
+		headerSize := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if chunkSize < 8 || offset+chunkSize > len(data) || headerSize > chunkSize {
+			return nil, fmt.Errorf("invalid binary XML: corrupt chunk at %d", offset)
+		}
+		chunk := data[offset : offset+chunkSize]
+
+		switch chunkType {
+		case axmlChunkStringPool:
+			var err error
+			if pool, err = parseStringPool(chunk); err != nil {
+				return nil, err
+			}
+		case axmlChunkResourceMap:
+			for idx := headerSize; idx+4 <= len(chunk); idx += 4 {
+				resourceIDs = append(resourceIDs, binary.LittleEndian.Uint32(chunk[idx:]))
+			}
+		case axmlChunkStartElement:
+			element, err := parseStartElement(chunk, headerSize, pool, resourceIDs)
+			if err != nil {
+				return nil, err
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, element)
+			} else if root == nil {
+				root = element
+			}
+			stack = append(stack, element)
+		case axmlChunkEndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case axmlChunkCData:
+			if len(stack) > 0 && headerSize+4 <= len(chunk) {
+				idx := binary.LittleEndian.Uint32(chunk[headerSize:])
+				if idx != axmlNoEntry && int(idx) < len(pool) {
+					stack[len(stack)-1].Text += strings.TrimSpace(pool[idx])
+				}
+			}
+		}
+		offset += chunkSize
+	}
+	if root == nil {
+		return nil, fmt.Errorf("invalid binary XML: no root element")
+	}
+	return root, nil
+}
+
+// parseStringPool decodes the strings of a string pool chunk
+func parseStringPool(chunk []byte) ([]string, error) {
+	if len(chunk) < 28 {
+		return nil, fmt.Errorf("invalid binary XML: truncated string pool")
+	}
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	utf8 := binary.LittleEndian.Uint32(chunk[16:])&axmlStringPoolUTF8 != 0
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	if headerSize+count*4 > len(chunk) || stringsStart > len(chunk) {
+		return nil, fmt.Errorf("invalid binary XML: corrupt string pool")
+	}
+
+	values := make([]string, count)
+	for idx := range values {
+		start := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+idx*4:]))
+		if start >= len(chunk) {
+			continue
+		}
+		if utf8 {
+			values[idx] = decodeUTF8PoolString(chunk[start:])
+		} else {
+			values[idx] = decodeUTF16PoolString(chunk[start:])
+		}
+	}
+	return values, nil
+}
+
+// decodeUTF8PoolString decodes a string of an UTF-8 string pool: its UTF-16 and UTF-8 lengths followed by the bytes
+func decodeUTF8PoolString(data []byte) string {
+	_, rest := poolStringLength8(data)
+	size, rest := poolStringLength8(rest)
+	if size > len(rest) {
+		return ""
+	}
+	return string(rest[:size])
+}
+
+// poolStringLength8 reads a length of 1 or 2 bytes of an UTF-8 string pool
+func poolStringLength8(data []byte) (int, []byte) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+	if data[0]&0x80 == 0 {
+		return int(data[0]), data[1:]
+	}
+	if len(data) < 2 {
+		return 0, nil
+	}
+	return int(data[0]&0x7f)<<8 | int(data[1]), data[2:]
+}
+
+// decodeUTF16PoolString decodes a string of an UTF-16 string pool: its length in units of 1 or 2 uint16 and the units
+func decodeUTF16PoolString(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	size, start := int(binary.LittleEndian.Uint16(data)), 2
+	if size&0x8000 != 0 {
+		if len(data) < 4 {
+			return ""
+		}
+		size = (size&0x7fff)<<16 | int(binary.LittleEndian.Uint16(data[2:]))
+		start = 4
+	}
+	if start+size*2 > len(data) {
+		return ""
+	}
+	units := make([]uint16, size)
+	for idx := range units {
+		units[idx] = binary.LittleEndian.Uint16(data[start+idx*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// parseStartElement decodes the name and attributes of a start element chunk
+func parseStartElement(chunk []byte, headerSize int, pool []string, resourceIDs []uint32) (*manifestElement, error) {
+	if headerSize+20 > len(chunk) {
+		return nil, fmt.Errorf("invalid binary XML: truncated element")
+	}
+	ext := chunk[headerSize:]
+	poolString := func(idx uint32) string {
+		if idx == axmlNoEntry || int(idx) >= len(pool) {
+			return ""
+		}
+		return pool[idx]
+	}
+
+	element := &manifestElement{Name: poolString(binary.LittleEndian.Uint32(ext[4:])), Attrs: map[string]string{}}
+	attributeStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attributeSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attributeCount := int(binary.LittleEndian.Uint16(ext[12:]))
+	if attributeSize < 20 || attributeStart+attributeCount*attributeSize > len(ext) {
+		return nil, fmt.Errorf("invalid binary XML: corrupt attributes of <%s>", element.Name)
+	}
+
+	for idx := 0; idx < attributeCount; idx++ {
+		attr := ext[attributeStart+idx*attributeSize:]
+		nameIdx := binary.LittleEndian.Uint32(attr[4:])
+		name := poolString(nameIdx)
+		if int(nameIdx) < len(resourceIDs) {
+			if known, ok := androidAttributeNames[resourceIDs[nameIdx]]; ok {
+				name = known
+			}
+		}
+
+		value := poolString(binary.LittleEndian.Uint32(attr[8:]))
+		dataType, valueData := attr[15], binary.LittleEndian.Uint32(attr[16:])
+		switch dataType {
+		case axmlTypeString:
+			if value == "" {
+				value = poolString(valueData)
+			}
+		case axmlTypeBoolean:
+			value = strconv.FormatBool(valueData != 0)
+		case axmlTypeReference:
+			value = fmt.Sprintf("@0x%08x", valueData)
+		case axmlTypeIntHex:
+			value = fmt.Sprintf("0x%x", valueData)
+		default:
+			if value == "" {
+				value = strconv.FormatInt(int64(int32(valueData)), 10)
+			}
+		}
+		element.Attrs[name] = value
+	}
+	return element, nil
+}
+
+// parseProtoXML parses the XmlNode protobuf of aapt2 (Resources.proto) and returns its root element
+func parseProtoXML(data []byte) (*manifestElement, error) {
+	var root *manifestElement
+	err := forEachProtoField(data, func(num protowire.Number, value []byte) error {
+		if num != 1 {
+			return nil
+		}
+		var err error
+		root, err = parseProtoXMLElement(value)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, fmt.Errorf("invalid proto XML: no root element")
+	}
+	return root, nil
+}
+
+// parseProtoXMLElement parses an XmlElement: its name (3), attributes (4) and child nodes (5), element (1) or text (2)
+func parseProtoXMLElement(data []byte) (*manifestElement, error) {
+	element := &manifestElement{Attrs: map[string]string{}}
+	err := forEachProtoField(data, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 3:
+			element.Name = string(value)
+		case 4:
+			name, attrValue, err := parseProtoXMLAttribute(value)
+			if err != nil {
+				return err
+			}
+			element.Attrs[name] = attrValue
+		case 5:
+			return forEachProtoField(value, func(num protowire.Number, value []byte) error {
+				if num == 2 {
+					element.Text += strings.TrimSpace(string(value))
+					return nil
+				}
+				if num != 1 {
+					return nil
+				}
+				child, err := parseProtoXMLElement(value)
+				if err != nil {
+					return err
+				}
+				element.Children = append(element.Children, child)
+				return nil
+			})
+		}
+		return nil
+	})
+	return element, err
+}
+
+// parseProtoXMLAttribute parses an XmlAttribute: its name (2), value (3), resource ID (5) and compiled item (6)
+func parseProtoXMLAttribute(data []byte) (string, string, error) {
+	name, value := "", ""
+	var resourceID uint32
+	var compiled []byte
+	err := forEachProtoField(data, func(num protowire.Number, field []byte) error {
+		switch num {
+		case 2:
+			name = string(field)
+		case 3:
+			value = string(field)
+		case 5:
+			id, n := protowire.ConsumeVarint(field)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			resourceID = uint32(id)
+		case 6:
+			compiled = field
+		}
+		return nil
+	})
+	if known, ok := androidAttributeNames[resourceID]; ok && name == "" {
+		name = known
+	}
+	if value == "" && compiled != nil {
+		value = protoCompiledBoolean(compiled)
+	}
+	return name, value, err
+}
+
+// protoCompiledBoolean returns the boolean_value (8) of the primitive (7) of a compiled item, empty for other items
+func protoCompiledBoolean(item []byte) string {
+	value := ""
+	_ = forEachProtoField(item, func(num protowire.Number, field []byte) error {
+		if num != 7 {
+			return nil
+		}
+		return forEachProtoField(field, func(num protowire.Number, field []byte) error {
+			if num == 8 {
+				flag, n := protowire.ConsumeVarint(field)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				value = strconv.FormatBool(flag != 0)
+			}
+			return nil
+		})
+	})
+	return value
+}
+
+// forEachProtoField calls fn with the number and the raw value of each field of a protobuf message:
+// the bytes of length-delimited fields, the encoded varint of varint fields. Other wire types are skipped.
+func forEachProtoField(data []byte, fn func(protowire.Number, []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid proto XML: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		var value []byte
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return fmt.Errorf("invalid proto XML: %w", protowire.ParseError(n))
+			}
+			value, data = v, data[n:]
+		case protowire.VarintType:
+			_, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return fmt.Errorf("invalid proto XML: %w", protowire.ParseError(n))
+			}
+			value, data = data[:n], data[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return fmt.Errorf("invalid proto XML: %w", protowire.ParseError(n))
+			}
+			data = data[n:]
+			continue
+		}
+		if err := fn(num, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
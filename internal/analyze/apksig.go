@@ -0,0 +1,629 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// apkSigBlockMagic ends the APK Signing Block stored right before the central directory of APKs signed with v2 or later
+const apkSigBlockMagic = "APK Sig Block 42"
+
+// maxAPKSigBlockSize guards against corrupt block sizes, real blocks are a few kilobytes or padded to a few pages
+const maxAPKSigBlockSize = 16 * 1024 * 1024
+
+// apkContentChunkSize is the size of the chunks hashed for the content digests of v2 and later signatures
+const apkContentChunkSize = 1024 * 1024
+
+// apkSignatureSchemes are the IDs of the signature scheme blocks in the APK Signing Block, the newest scheme first
+var apkSignatureSchemes = []struct {
+	name string
+	id   uint32
+	// v3 and later signers carry the SDK range they apply to
+	sdkRange bool
+}{
+	{name: "v3.1", id: 0x1b93ad61, sdkRange: true},
+	{name: "v3", id: 0xf05368c0, sdkRange: true},
+	{name: "v2", id: 0x7109871a},
+}
+
+// apkSignatureAlgorithm is a signature algorithm of v2 and later signatures
+type apkSignatureAlgorithm struct {
+	hash crypto.Hash
+	// pss selects RSASSA-PSS instead of RSASSA-PKCS1-v1_5 for RSA keys
+	pss bool
+}
+
+// apkSignatureAlgorithms are the supported signature algorithms by ID, DSA and the verity variants aren't verified
+var apkSignatureAlgorithms = map[uint32]apkSignatureAlgorithm{
+	0x0101: {hash: crypto.SHA256, pss: true},
+	0x0102: {hash: crypto.SHA512, pss: true},
+	0x0103: {hash: crypto.SHA256},
+	0x0104: {hash: crypto.SHA512},
+	0x0201: {hash: crypto.SHA256},
+	0x0202: {hash: crypto.SHA512},
+}
+
+// jarSignatureExtensions are the extensions of the PKCS #7 signature files of JAR (v1) signatures
+var jarSignatureExtensions = map[string]bool{".rsa": true, ".dsa": true, ".ec": true}
+
+// errNoAPKSigningBlock is returned for APKs signed with v1 only or not signed at all
+var errNoAPKSigningBlock = errors.New("no APK Signing Block")
+
+// apkLayout is the position of the APK Signing Block, the central directory and the end of central directory record
+type apkLayout struct {
+	size       int64
+	blockStart int64
+	cdOffset   int64
+	cdSize     int64
+	eocd       []byte
+}
+
+// verifyAPKSignature verifies the v2, v3 and v3.1 signatures of an APK like Android 7.0 and later devices,
+// APKs signed with v1 only are verified as JARs
+func verifyAPKSignature(artifactPath string) (SignatureInfo, error) {
+	file, err := os.Open(artifactPath)
+	if err != nil {
+		return SignatureInfo{}, err
+	}
+	defer file.Close()
+
+	layout, block, err := readAPKSigningBlock(file)
+	if errors.Is(err, errNoAPKSigningBlock) {
+		return verifyJARSignature(artifactPath)
+	}
+	if err != nil {
+		return SignatureInfo{}, err
+	}
+
+	schemeBlocks, err := apkSchemeBlocks(block)
+	if err != nil {
+		return SignatureInfo{}, err
+	}
+
+	info := SignatureInfo{Status: SignatureSigned}
+	var signingCert *x509.Certificate
+	digests := map[crypto.Hash][]byte{}
+	for _, scheme := range apkSignatureSchemes {
+		value, ok := schemeBlocks[scheme.id]
+		if !ok {
+			continue
+		}
+		info.Schemes = append(info.Schemes, scheme.name)
+
+		cert, err := verifyAPKSchemeBlock(value, scheme.sdkRange, file, layout, digests)
+		if err != nil {
+			info.Status = SignatureInvalid
+			info.Problems = append(info.Problems, fmt.Sprintf("%s signature: %s", scheme.name, err))
+			continue
+		}
+		if signingCert == nil {
+			signingCert = cert
+			info.describeCertificate(cert)
+		}
+	}
+	if len(info.Schemes) == 0 {
+		return verifyJARSignature(artifactPath)
+	}
+
+	if hasJARSignature(artifactPath) {
+		info.Schemes = append(info.Schemes, "v1")
+	}
+	sort.Strings(info.Schemes)
+	if problem := androidDebugCertificate(signingCert); info.Status == SignatureSigned && problem != "" {
+		info.Status = SignatureDebug
+		info.Problems = append(info.Problems, problem)
+	}
+	return info, nil
+}
+
+// readAPKSigningBlock locates the central directory of the APK and reads the APK Signing Block preceding it
+func readAPKSigningBlock(file *os.File) (apkLayout, []byte, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return apkLayout{}, nil, err
+	}
+	layout := apkLayout{size: stat.Size()}
+
+	// The end of central directory record is 22 bytes followed by a comment of at most 65535 bytes
+	tailSize := int64(22 + 65535)
+	if tailSize > layout.size {
+		tailSize = layout.size
+	}
+	tail := make([]byte, tailSize)
+	if _, err := file.ReadAt(tail, layout.size-tailSize); err != nil {
+		return apkLayout{}, nil, fmt.Errorf("failed to read end of central directory: %w", err)
+	}
+	eocdIdx := -1
+	for idx := len(tail) - 22; idx >= 0; idx-- {
+		if binary.LittleEndian.Uint32(tail[idx:]) == 0x06054b50 && idx+22+int(binary.LittleEndian.Uint16(tail[idx+20:])) == len(tail) {
+			eocdIdx = idx
+			break
+		}
+	}
+	if eocdIdx < 0 {
+		return apkLayout{}, nil, fmt.Errorf("not a zip archive: end of central directory not found")
+	}
+	layout.eocd = tail[eocdIdx:]
+	layout.cdSize = int64(binary.LittleEndian.Uint32(layout.eocd[12:]))
+	layout.cdOffset = int64(binary.LittleEndian.Uint32(layout.eocd[16:]))
+	// Zip64 archives can't carry an APK Signing Block, apksigner rejects them
+	if layout.cdOffset == 0xffffffff || layout.cdOffset < 32 || layout.cdOffset+layout.cdSize > layout.size {
+		return apkLayout{}, nil, errNoAPKSigningBlock
+	}
+
+	footer := make([]byte, 24)
+	if _, err := file.ReadAt(footer, layout.cdOffset-24); err != nil {
+		return apkLayout{}, nil, fmt.Errorf("failed to read APK Signing Block: %w", err)
+	}
+	if string(footer[8:]) != apkSigBlockMagic {
+		return apkLayout{}, nil, errNoAPKSigningBlock
+	}
+	blockSize := int64(binary.LittleEndian.Uint64(footer))
+	if blockSize < 24 || blockSize > maxAPKSigBlockSize || blockSize+8 > layout.cdOffset {
+		return apkLayout{}, nil, fmt.Errorf("corrupt APK Signing Block size: %d", blockSize)
+	}
+	layout.blockStart = layout.cdOffset - blockSize - 8
+
+	block := make([]byte, blockSize+8)
+	if _, err := file.ReadAt(block, layout.blockStart); err != nil {
+		return apkLayout{}, nil, fmt.Errorf("failed to read APK Signing Block: %w", err)
+	}
+	if int64(binary.LittleEndian.Uint64(block)) != blockSize {
+		return apkLayout{}, nil, fmt.Errorf("corrupt APK Signing Block: mismatching sizes")
+	}
+	return layout, block[8 : len(block)-24], nil
+}
+
+// apkSchemeBlocks splits the ID-value pairs of the APK Signing Block
+func apkSchemeBlocks(pairs []byte) (map[uint32][]byte, error) {
+	blocks := map[uint32][]byte{}
+	for len(pairs) > 0 {
+		if len(pairs) < 12 {
+			return nil, fmt.Errorf("corrupt APK Signing Block: truncated pair")
+		}
+		size := binary.LittleEndian.Uint64(pairs)
+		if size < 4 || size > uint64(len(pairs)-8) {
+			return nil, fmt.Errorf("corrupt APK Signing Block: invalid pair size")
+		}
+		blocks[binary.LittleEndian.Uint32(pairs[8:])] = pairs[12 : 8+size]
+		pairs = pairs[8+size:]
+	}
+	return blocks, nil
+}
+
+// lengthPrefixed splits a value prefixed with its uint32 length from the rest of the data
+func lengthPrefixed(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("truncated length-prefixed value")
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if uint64(size) > uint64(len(data)-4) {
+		return nil, nil, fmt.Errorf("length-prefixed value out of range")
+	}
+	return data[4 : 4+size], data[4+size:], nil
+}
+
+// lengthPrefixedSequence splits a sequence of length-prefixed values
+func lengthPrefixedSequence(data []byte) ([][]byte, error) {
+	var values [][]byte
+	for len(data) > 0 {
+		value, rest, err := lengthPrefixed(data)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		data = rest
+	}
+	return values, nil
+}
+
+// verifyAPKSchemeBlock verifies every signer of a signature scheme block and returns the certificate of the first signer
+func verifyAPKSchemeBlock(value []byte, sdkRange bool, file *os.File, layout apkLayout, digests map[crypto.Hash][]byte) (*x509.Certificate, error) {
+	signersValue, _, err := lengthPrefixed(value)
+	if err != nil {
+		return nil, err
+	}
+	signers, err := lengthPrefixedSequence(signersValue)
+	if err != nil {
+		return nil, err
+	}
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no signers")
+	}
+
+	var first *x509.Certificate
+	for _, signer := range signers {
+		cert, err := verifyAPKSigner(signer, sdkRange, file, layout, digests)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = cert
+		}
+	}
+	return first, nil
+}
+
+// verifyAPKSigner verifies the signature of a signer over its signed data and the content digest of the APK
+func verifyAPKSigner(signer []byte, sdkRange bool, file *os.File, layout apkLayout, digests map[crypto.Hash][]byte) (*x509.Certificate, error) {
+	signedData, rest, err := lengthPrefixed(signer)
+	if err != nil {
+		return nil, err
+	}
+	if sdkRange {
+		if len(rest) < 8 {
+			return nil, fmt.Errorf("truncated signer")
+		}
+		rest = rest[8:]
+	}
+	signaturesValue, rest, err := lengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+	publicKeyDER, _, err := lengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	digestsValue, rest, err := lengthPrefixed(signedData)
+	if err != nil {
+		return nil, err
+	}
+	certificatesValue, _, err := lengthPrefixed(rest)
+	if err != nil {
+		return nil, err
+	}
+	certificates, err := lengthPrefixedSequence(certificatesValue)
+	if err != nil || len(certificates) == 0 {
+		return nil, fmt.Errorf("no signing certificate")
+	}
+	cert, err := x509.ParseCertificate(certificates[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing certificate: %w", err)
+	}
+	if !bytes.Equal(cert.RawSubjectPublicKeyInfo, publicKeyDER) {
+		return nil, fmt.Errorf("the public key doesn't match the signing certificate")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(publicKeyDER)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	// Verify the strongest supported signature, like the platform does
+	signatures, err := lengthPrefixedSequence(signaturesValue)
+	if err != nil {
+		return nil, err
+	}
+	algorithmID, signature := uint32(0), []byte(nil)
+	for _, entry := range signatures {
+		if len(entry) < 4 {
+			return nil, fmt.Errorf("truncated signature")
+		}
+		id := binary.LittleEndian.Uint32(entry)
+		candidate, ok := apkSignatureAlgorithms[id]
+		if !ok || (signature != nil && candidate.hash <= apkSignatureAlgorithms[algorithmID].hash) {
+			continue
+		}
+		value, _, err := lengthPrefixed(entry[4:])
+		if err != nil {
+			return nil, err
+		}
+		algorithmID, signature = id, value
+	}
+	if signature == nil {
+		return nil, fmt.Errorf("no supported signature algorithm")
+	}
+	algorithm := apkSignatureAlgorithms[algorithmID]
+	if err := verifyAPKSignatureValue(publicKey, algorithm, signedData, signature); err != nil {
+		return nil, err
+	}
+
+	var signedDigest []byte
+	digestEntries, err := lengthPrefixedSequence(digestsValue)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range digestEntries {
+		if len(entry) >= 4 && binary.LittleEndian.Uint32(entry) == algorithmID {
+			if signedDigest, _, err = lengthPrefixed(entry[4:]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if signedDigest == nil {
+		return nil, fmt.Errorf("no content digest for signature algorithm 0x%04x", algorithmID)
+	}
+
+	digest, ok := digests[algorithm.hash]
+	if !ok {
+		if digest, err = apkContentDigest(file, layout, algorithm.hash); err != nil {
+			return nil, err
+		}
+		digests[algorithm.hash] = digest
+	}
+	if !bytes.Equal(digest, signedDigest) {
+		return nil, fmt.Errorf("the content of the APK doesn't match the signed digest")
+	}
+	return cert, nil
+}
+
+// verifyAPKSignatureValue verifies a signature over the signed data of a signer
+func verifyAPKSignatureValue(publicKey interface{}, algorithm apkSignatureAlgorithm, signedData, signature []byte) error {
+	hasher := algorithm.hash.New()
+	hasher.Write(signedData)
+	hashed := hasher.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm.pss {
+			if err := rsa.VerifyPSS(key, algorithm.hash, hashed, signature, &rsa.PSSOptions{SaltLength: algorithm.hash.Size()}); err != nil {
+				return fmt.Errorf("signature verification failed: %w", err)
+			}
+			return nil
+		}
+		if err := rsa.VerifyPKCS1v15(key, algorithm.hash, hashed, signature); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hashed, signature) {
+			return fmt.Errorf("signature verification failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", publicKey)
+}
+
+// apkContentDigest computes the chunked digest of the APK contents protected by v2 and later signatures:
+// the zip entries, the central directory and the end of central directory record pointing to the signing block
+func apkContentDigest(file *os.File, layout apkLayout, hashType crypto.Hash) ([]byte, error) {
+	eocd := append([]byte(nil), layout.eocd...)
+	binary.LittleEndian.PutUint32(eocd[16:], uint32(layout.blockStart))
+	sections := []io.Reader{
+		io.NewSectionReader(file, 0, layout.blockStart),
+		io.NewSectionReader(file, layout.cdOffset, layout.cdSize),
+		bytes.NewReader(eocd),
+	}
+
+	var chunkDigests []byte
+	chunks := uint32(0)
+	buf := make([]byte, apkContentChunkSize)
+	for _, section := range sections {
+		for {
+			n, err := io.ReadFull(section, buf)
+			if n > 0 {
+				chunk := hashType.New()
+				header := [5]byte{0xa5}
+				binary.LittleEndian.PutUint32(header[1:], uint32(n))
+				chunk.Write(header[:])
+				chunk.Write(buf[:n])
+				chunkDigests = chunk.Sum(chunkDigests)
+				chunks++
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to read APK contents: %w", err)
+			}
+		}
+	}
+
+	top := hashType.New()
+	header := [5]byte{0x5a}
+	binary.LittleEndian.PutUint32(header[1:], chunks)
+	top.Write(header[:])
+	top.Write(chunkDigests)
+	return top.Sum(nil), nil
+}
+
+// hasJARSignature checks whether the artifact carries a v1 (JAR) signature
+func hasJARSignature(artifactPath string) bool {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if isJARSignatureFile(file.Name) && strings.EqualFold(path.Ext(file.Name), ".sf") {
+			return true
+		}
+	}
+	return false
+}
+
+// isJARSignatureFile checks whether an entry is a signature file of a JAR signature, these aren't signed themselves
+func isJARSignatureFile(name string) bool {
+	dir, base := path.Split(name)
+	if dir != "META-INF/" {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(base))
+	return ext == ".sf" || jarSignatureExtensions[ext] || strings.HasPrefix(strings.ToUpper(base), "SIG-")
+}
+
+// jarDigestAlgorithms are the digest algorithms of JAR manifests, the strongest first
+var jarDigestAlgorithms = []struct {
+	name string
+	new  func() hash.Hash
+}{
+	{name: "SHA-512", new: sha512.New},
+	{name: "SHA-384", new: sha512.New384},
+	{name: "SHA-256", new: sha256.New},
+	{name: "SHA1", new: sha1.New},
+	{name: "SHA-1", new: sha1.New},
+}
+
+// verifyJARSignature verifies a v1 (JAR) signature, used by AABs and APKs without v2 signatures:
+// every entry has to match its digest in META-INF/MANIFEST.MF and the manifest the digest of the .SF file.
+// The PKCS #7 signature over the .SF file provides the signing certificate and isn't verified.
+func verifyJARSignature(artifactPath string) (SignatureInfo, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var manifestFile, signatureFile, blockFile *zip.File
+	for _, file := range reader.File {
+		switch {
+		case file.Name == "META-INF/MANIFEST.MF":
+			manifestFile = file
+		case isJARSignatureFile(file.Name) && strings.EqualFold(path.Ext(file.Name), ".sf"):
+			signatureFile = file
+		case isJARSignatureFile(file.Name) && jarSignatureExtensions[strings.ToLower(path.Ext(file.Name))]:
+			blockFile = file
+		}
+	}
+	if manifestFile == nil || signatureFile == nil || blockFile == nil {
+		return SignatureInfo{Status: SignatureUnsigned, Problems: []string{"the artifact isn't signed"}}, nil
+	}
+
+	info := SignatureInfo{Status: SignatureSigned, Schemes: []string{"v1"}}
+	block, err := readZipEntry(blockFile)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to read %s: %w", blockFile.Name, err)
+	}
+	certs, err := pkcs7Certificates(block)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("%s: %w", blockFile.Name, err)
+	}
+	cert := leafCertificate(certs)
+	if cert == nil {
+		return SignatureInfo{}, fmt.Errorf("%s has no signing certificate", blockFile.Name)
+	}
+	info.describeCertificate(cert)
+
+	manifest, err := readZipEntry(manifestFile)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to read %s: %w", manifestFile.Name, err)
+	}
+	signature, err := readZipEntry(signatureFile)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to read %s: %w", signatureFile.Name, err)
+	}
+	// Signers without the digest of the whole manifest sign its sections one by one, those aren't checked
+	if signed := parseJARManifest(signature); len(signed) == 0 {
+		info.Status = SignatureInvalid
+		info.Problems = append(info.Problems, fmt.Sprintf("%s is empty", signatureFile.Name))
+	} else if ok, checked := matchJARDigest(signed[0], "-Digest-Manifest", manifest); checked && !ok {
+		info.Status = SignatureInvalid
+		info.Problems = append(info.Problems, fmt.Sprintf("%s doesn't match the digest of the manifest", signatureFile.Name))
+	}
+
+	entries := map[string]map[string]string{}
+	for idx, section := range parseJARManifest(manifest) {
+		if idx > 0 {
+			entries[section["Name"]] = section
+		}
+	}
+	var modified, unsigned []string
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || file.Name == manifestFile.Name || isJARSignatureFile(file.Name) {
+			continue
+		}
+		section, ok := entries[file.Name]
+		if !ok {
+			unsigned = append(unsigned, file.Name)
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return SignatureInfo{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		ok, checked := matchJARDigest(section, "-Digest", data)
+		switch {
+		case !checked:
+			unsigned = append(unsigned, file.Name)
+		case !ok:
+			modified = append(modified, file.Name)
+		}
+	}
+	if len(modified) > 0 {
+		info.Status = SignatureInvalid
+		info.Problems = append(info.Problems, fmt.Sprintf("%d entries don't match their signed digests: %s", len(modified), summarizeNames(modified)))
+	}
+	if len(unsigned) > 0 {
+		info.Status = SignatureInvalid
+		info.Problems = append(info.Problems, fmt.Sprintf("%d entries aren't covered by the signature: %s", len(unsigned), summarizeNames(unsigned)))
+	}
+
+	if problem := androidDebugCertificate(cert); info.Status == SignatureSigned && problem != "" {
+		info.Status = SignatureDebug
+		info.Problems = append(info.Problems, problem)
+	}
+	return info, nil
+}
+
+// parseJARManifest parses the sections of a JAR manifest or signature file, the first one holds the main attributes
+func parseJARManifest(data []byte) []map[string]string {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	var sections []map[string]string
+	section := map[string]string{}
+	lastKey := ""
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case line == "":
+			if len(section) > 0 || len(sections) == 0 {
+				sections = append(sections, section)
+			}
+			section, lastKey = map[string]string{}, ""
+		case strings.HasPrefix(line, " ") && lastKey != "":
+			// Lines are wrapped at 72 bytes, continuations start with a space
+			section[lastKey] += line[1:]
+		default:
+			if key, value, ok := strings.Cut(line, ": "); ok {
+				section[key], lastKey = value, key
+			}
+		}
+	}
+	if len(section) > 0 {
+		sections = append(sections, section)
+	}
+	return sections
+}
+
+// matchJARDigest compares data with the strongest supported digest attribute of a manifest section,
+// checked is false if the section has no supported digest
+func matchJARDigest(section map[string]string, suffix string, data []byte) (bool, bool) {
+	for _, algorithm := range jarDigestAlgorithms {
+		encoded, ok := section[algorithm.name+suffix]
+		if !ok {
+			continue
+		}
+		expected, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return false, true
+		}
+		hasher := algorithm.new()
+		hasher.Write(data)
+		return bytes.Equal(hasher.Sum(nil), expected), true
+	}
+	return false, false
+}
+
+// summarizeNames lists the first few names of a list
+func summarizeNames(names []string) string {
+	const maxNames = 5
+	if len(names) <= maxNames {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxNames], ", "), len(names)-maxNames)
+}
@@ -0,0 +1,122 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// AppIdentity names the app of an artifact, it fills the placeholders of report_title
+type AppIdentity struct {
+	Name    string
+	Version string
+	// Variant is the build variant of APKs and AABs taken from the file name (free-release of app-free-release.apk),
+	// the distribution type of the provisioning profile for IPAs
+	Variant string
+}
+
+// ReadAppIdentity reads the app name and version from the Info.plist of IPAs and the manifest of APKs and AABs.
+// Android apps are named by their package unless the application label is a literal string, resource references
+// aren't resolved.
+func ReadAppIdentity(artifactPath string) (AppIdentity, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return AppIdentity{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		_, info, err := readAppInfoPlist(&reader.Reader)
+		if err != nil {
+			return AppIdentity{}, err
+		}
+		identity := AppIdentity{Name: plistString(info, "CFBundleDisplayName"), Version: plistString(info, "CFBundleShortVersionString")}
+		if identity.Name == "" {
+			identity.Name = plistString(info, "CFBundleName")
+		}
+		if profile, err := ReadProvisioningProfile(artifactPath); err == nil {
+			identity.Variant = profile.Type
+		}
+		return identity, nil
+	}
+
+	manifest, err := readAndroidManifest(&reader.Reader)
+	if err != nil {
+		return AppIdentity{}, err
+	}
+	identity := AppIdentity{Name: manifest.Attrs["package"], Version: manifest.Attrs["versionName"], Variant: androidVariant(artifactPath)}
+	for _, application := range manifest.ChildrenNamed("application") {
+		if label := application.Attrs["label"]; label != "" && !strings.HasPrefix(label, "@") {
+			identity.Name = label
+		}
+	}
+	if strings.HasPrefix(identity.Version, "@") {
+		identity.Version = ""
+	}
+	return identity, nil
+}
+
+// IsAndroidArtifact reports whether the artifact is an APK or AAB
+func IsAndroidArtifact(artifactPath string) bool {
+	ext := strings.ToLower(filepath.Ext(artifactPath))
+	return ext == ".apk" || ext == ".aab"
+}
+
+// ReadPackageName reads the package name from the manifest of an APK or AAB
+func ReadPackageName(artifactPath string) (string, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	manifest, err := readAndroidManifest(&reader.Reader)
+	if err != nil {
+		return "", err
+	}
+	if manifest.Attrs["package"] == "" {
+		return "", fmt.Errorf("the manifest has no package name")
+	}
+	return manifest.Attrs["package"], nil
+}
+
+// ReadBundleID reads the bundle identifier from the Info.plist of the app bundle of an IPA
+func ReadBundleID(artifactPath string) (string, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	_, info, err := readAppInfoPlist(&reader.Reader)
+	if err != nil {
+		return "", err
+	}
+	if bundleID := plistString(info, "CFBundleIdentifier"); bundleID != "" {
+		return bundleID, nil
+	}
+	return "", fmt.Errorf("the Info.plist has no bundle identifier")
+}
+
+// androidVariant returns the variant part of a Gradle output name: <module>-<flavor>-<build type>.apk,
+// empty if the name has no variant
+func androidVariant(artifactPath string) string {
+	name := strings.TrimSuffix(filepath.Base(artifactPath), filepath.Ext(artifactPath))
+	_, variant, _ := strings.Cut(name, "-")
+	return variant
+}
+
+// readAppIdentityFromConfig reads the app identity for report_title and logs it
+func readAppIdentityFromConfig(artifactPath string, logger log.Logger) AppIdentity {
+	identity, err := ReadAppIdentity(artifactPath)
+	if err != nil {
+		logger.Warnf("Failed to read app name and version for report_title: %s", err)
+		return AppIdentity{Variant: androidVariant(artifactPath)}
+	}
+	logger.Printf("App name: %s, version: %s, variant: %s", identity.Name, identity.Version, identity.Variant)
+	return identity
+}
@@ -0,0 +1,67 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadAppIdentity(t *testing.T) {
+	manifest := func(application ...[2]string) xmlNode {
+		return xmlNode{name: "manifest", attrs: [][2]string{{"package", "com.example.app"}, {"versionName", "2.4.0"}},
+			children: []xmlNode{{name: "application", attrs: application}}}
+	}
+	tests := []struct {
+		name     string
+		manifest xmlNode
+		want     AppIdentity
+	}{
+		{name: "literal label", manifest: manifest([2]string{"label", "My App"}), want: AppIdentity{Name: "My App", Version: "2.4.0"}},
+		{name: "resource label", manifest: manifest([2]string{"label", "@string/app_name"}), want: AppIdentity{Name: "com.example.app", Version: "2.4.0"}},
+		{name: "resource version", manifest: xmlNode{name: "manifest", attrs: [][2]string{{"package", "com.example.app"}, {"versionName", "@string/version"}}},
+			want: AppIdentity{Name: "com.example.app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadAppIdentity(writeAAB(t, tt.manifest))
+			if err != nil {
+				t.Fatalf("ReadAppIdentity() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadAppIdentity() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAppIdentityIPA(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+	plist := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>CFBundleExecutable</key><string>Test</string>
+<key>CFBundleName</key><string>Test</string><key>CFBundleDisplayName</key><string>My App</string>
+<key>CFBundleShortVersionString</key><string>3.1</string></dict></plist>`
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{{name: "Payload/Test.app/Info.plist", content: plist}}, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadAppIdentity(artifactPath)
+	if err != nil {
+		t.Fatalf("ReadAppIdentity() error = %s", err)
+	}
+	if want := (AppIdentity{Name: "My App", Version: "3.1"}); got != want {
+		t.Errorf("ReadAppIdentity() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAndroidVariant(t *testing.T) {
+	tests := map[string]string{
+		"/builds/app-free-release.apk": "free-release",
+		"app-debug.aab":                "debug",
+		"app.apk":                      "",
+	}
+	for artifactPath, want := range tests {
+		if got := androidVariant(artifactPath); got != want {
+			t.Errorf("androidVariant(%q) = %q, want %q", artifactPath, got, want)
+		}
+	}
+}
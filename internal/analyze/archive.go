@@ -0,0 +1,51 @@
+package analyze
+
+import (
+	"archive/zip"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// entryBufferSize is the size of the buffers archive entries are streamed through
+const entryBufferSize = 64 * 1024
+
+// entryBuffers are shared by the concurrent analyses, so the memory used for reading entries doesn't depend on their size
+var entryBuffers = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, entryBufferSize)
+		return &buffer
+	},
+}
+
+// streamEntry copies the uncompressed content of an archive entry to dst, the entry is never held in memory as a whole
+func streamEntry(dst io.Writer, file *zip.File) (int64, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	buffer := entryBuffers.Get().(*[]byte)
+	defer entryBuffers.Put(buffer)
+	return io.CopyBuffer(dst, rc, *buffer)
+}
+
+// ApplyMemoryLimit parses memory_limit_mb and sets it as the soft memory limit of the step,
+// the garbage collector runs more often as the limit gets close instead of growing the heap
+func ApplyMemoryLimit(value string, logger log.Logger) {
+	if value == "" {
+		return
+	}
+
+	limitMB, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limitMB <= 0 {
+		logger.Warnf("Invalid memory_limit_mb value: %s", value)
+		return
+	}
+	debug.SetMemoryLimit(limitMB * 1024 * 1024)
+	logger.Printf("Memory limit: %d MB", limitMB)
+}
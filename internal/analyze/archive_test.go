@@ -0,0 +1,91 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"math"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestStreamEntry(t *testing.T) {
+	// 64 MB of zeros compress to a few hundred kilobytes
+	const entrySize = 64 * 1024 * 1024
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	entry, err := writer.Create("assets/large.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.CopyN(entry, zeroReader{}, entrySize); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	hash := sha256.New()
+	written, err := streamEntry(hash, reader.File[0])
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatalf("streamEntry() error = %s", err)
+	}
+	if written != entrySize {
+		t.Errorf("streamEntry() = %d bytes, want %d", written, entrySize)
+	}
+
+	want := sha256.New()
+	if _, err := io.CopyN(want, zeroReader{}, entrySize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(hash.Sum(nil), want.Sum(nil)) {
+		t.Errorf("streamEntry() content doesn't match the entry")
+	}
+	// The decompressor's state is allocated, the entry content isn't
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 4*1024*1024 {
+		t.Errorf("streamEntry() allocated %d bytes for a %d bytes entry", allocated, entrySize)
+	}
+}
+
+func TestApplyMemoryLimit(t *testing.T) {
+	defer debug.SetMemoryLimit(math.MaxInt64)
+
+	tests := []struct {
+		value string
+		want  int64
+	}{
+		{value: "", want: math.MaxInt64},
+		{value: "512", want: 512 * 1024 * 1024},
+		{value: "0", want: math.MaxInt64},
+		{value: "1GB", want: math.MaxInt64},
+	}
+	for _, tt := range tests {
+		debug.SetMemoryLimit(math.MaxInt64)
+		ApplyMemoryLimit(tt.value, log.NewLogger())
+		// A negative limit only reads the current limit
+		if got := debug.SetMemoryLimit(-1); got != tt.want {
+			t.Errorf("ApplyMemoryLimit(%q) set %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+// zeroReader is an endless stream of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for idx := range p {
+		p[idx] = 0
+	}
+	return len(p), nil
+}
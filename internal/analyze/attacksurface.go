@@ -0,0 +1,273 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Kinds of deep links
+const (
+	DeepLinkCustomScheme  = "custom scheme"
+	DeepLinkWebLink       = "web link"
+	DeepLinkAppLink       = "app link"
+	DeepLinkUniversalLink = "universal link"
+)
+
+// androidComponentTypes are the manifest elements of the application which other apps can start or bind to
+var androidComponentTypes = []string{"activity", "activity-alias", "service", "receiver", "provider"}
+
+// ExportedComponent is an Android component other apps can start, bind to or query
+type ExportedComponent struct {
+	Type string
+	Name string
+	// Permission guards the component, empty if any app can reach it
+	Permission string
+	// Implicit is set when the component is exported by its intent filters, without android:exported
+	Implicit bool
+	Actions  []string
+}
+
+// DeepLink is a URI opening the app
+type DeepLink struct {
+	URI  string
+	Kind string
+	// Handler is the component handling the link, empty for IPAs
+	Handler string
+}
+
+// AttackSurface holds the entry points of the app reachable from other apps and the web
+type AttackSurface struct {
+	Components []ExportedComponent
+	DeepLinks  []DeepLink
+}
+
+// Entries returns a line per component and deep link, used to compare the attack surface of builds
+func (s AttackSurface) Entries() []string {
+	var entries []string
+	for _, component := range s.Components {
+		entries = append(entries, component.Type+" "+component.Name)
+	}
+	for _, link := range s.DeepLinks {
+		entries = append(entries, link.Kind+" "+link.URI)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// AttackSurfaceDiff holds the entry points added and removed since the baseline build, see AttackSurface.Entries
+type AttackSurfaceDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// ReadAttackSurface returns the exported components and deep links of an APK or AAB,
+// and the URL schemes and universal links of an IPA
+func ReadAttackSurface(artifactPath, workDir string) (AttackSurface, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return AttackSurface{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		return readIPAAttackSurface(&reader.Reader, workDir)
+	}
+	manifest, err := readAndroidManifest(&reader.Reader)
+	if err != nil {
+		return AttackSurface{}, err
+	}
+	return androidAttackSurface(manifest), nil
+}
+
+// androidAttackSurface collects the exported components of the manifest and the links their intent filters handle
+func androidAttackSurface(manifest *manifestElement) AttackSurface {
+	var surface AttackSurface
+	for _, application := range manifest.ChildrenNamed("application") {
+		for _, componentType := range androidComponentTypes {
+			for _, element := range application.ChildrenNamed(componentType) {
+				filters := element.ChildrenNamed("intent-filter")
+				exported, explicit := element.Attrs["exported"]
+				// Before Android 12 components with intent filters were exported by default, providers never are since Android 4.2
+				implicit := !explicit && len(filters) > 0 && componentType != "provider"
+				if exported != "true" && !implicit {
+					continue
+				}
+
+				component := ExportedComponent{
+					Type:       componentType,
+					Name:       qualifiedComponentName(manifest.Attrs["package"], element.Attrs["name"]),
+					Permission: element.Attrs["permission"],
+					Implicit:   implicit,
+				}
+				for _, filter := range filters {
+					for _, action := range filter.ChildrenNamed("action") {
+						if name := action.Attrs["name"]; name != "" && !containsString(component.Actions, name) {
+							component.Actions = append(component.Actions, name)
+						}
+					}
+					surface.DeepLinks = append(surface.DeepLinks, intentFilterLinks(filter, component.Name)...)
+				}
+				surface.Components = append(surface.Components, component)
+			}
+		}
+	}
+	return surface
+}
+
+// qualifiedComponentName resolves component names relative to the package (".MainActivity")
+func qualifiedComponentName(packageName, name string) string {
+	if strings.HasPrefix(name, ".") {
+		return packageName + name
+	}
+	if !strings.Contains(name, ".") && packageName != "" {
+		return packageName + "." + name
+	}
+	return name
+}
+
+// intentFilterLinks returns the URIs of a browsable VIEW intent filter. The data elements of a filter are merged:
+// every scheme is combined with every host and path.
+func intentFilterLinks(filter *manifestElement, handler string) []DeepLink {
+	view, browsable := false, false
+	for _, action := range filter.ChildrenNamed("action") {
+		view = view || action.Attrs["name"] == "android.intent.action.VIEW"
+	}
+	for _, category := range filter.ChildrenNamed("category") {
+		browsable = browsable || category.Attrs["name"] == "android.intent.category.BROWSABLE"
+	}
+	if !view || !browsable {
+		return nil
+	}
+
+	var schemes, hosts, paths []string
+	for _, data := range filter.ChildrenNamed("data") {
+		if scheme := data.Attrs["scheme"]; scheme != "" {
+			schemes = append(schemes, scheme)
+		}
+		if host := data.Attrs["host"]; host != "" {
+			if port := data.Attrs["port"]; port != "" {
+				host += ":" + port
+			}
+			hosts = append(hosts, host)
+		}
+		for _, attr := range []string{"path", "pathPrefix", "pathPattern"} {
+			if value := data.Attrs[attr]; value != "" {
+				if attr == "pathPrefix" {
+					value += "*"
+				}
+				paths = append(paths, value)
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+
+	var links []DeepLink
+	for _, scheme := range schemes {
+		kind := DeepLinkCustomScheme
+		if scheme == "http" || scheme == "https" {
+			kind = DeepLinkWebLink
+			if filter.Attrs["autoVerify"] == "true" {
+				kind = DeepLinkAppLink
+			}
+		}
+		for _, host := range hosts {
+			for _, linkPath := range paths {
+				uri := scheme + ":"
+				if host != "" {
+					uri += "//" + host + linkPath
+				}
+				links = append(links, DeepLink{URI: uri, Kind: kind, Handler: handler})
+			}
+		}
+	}
+	return links
+}
+
+// readIPAAttackSurface returns the URL schemes registered in the app's Info.plist and the universal links of its
+// associated domains entitlement
+func readIPAAttackSurface(reader *zip.Reader, workDir string) (AttackSurface, error) {
+	appDir, info, err := readAppInfoPlist(reader)
+	if err != nil {
+		return AttackSurface{}, err
+	}
+
+	var surface AttackSurface
+	urlTypes, _ := info["CFBundleURLTypes"].([]interface{})
+	for _, urlType := range urlTypes {
+		dict, _ := urlType.(map[string]interface{})
+		schemes, _ := dict["CFBundleURLSchemes"].([]interface{})
+		for _, scheme := range schemes {
+			if name, ok := scheme.(string); ok && name != "" {
+				surface.DeepLinks = append(surface.DeepLinks, DeepLink{URI: name + "://", Kind: DeepLinkCustomScheme})
+			}
+		}
+	}
+
+	entitlements, err := readAppEntitlements(reader, appDir, info, workDir)
+	if err != nil {
+		return AttackSurface{}, err
+	}
+	domains, _ := entitlements["com.apple.developer.associated-domains"].([]interface{})
+	for _, domain := range domains {
+		if name, ok := domain.(string); ok && strings.HasPrefix(name, "applinks:") {
+			// Strip the alternate mode suffix: applinks:example.com?mode=developer
+			host, _, _ := strings.Cut(strings.TrimPrefix(name, "applinks:"), "?")
+			surface.DeepLinks = append(surface.DeepLinks, DeepLink{URI: "https://" + host, Kind: DeepLinkUniversalLink})
+		}
+	}
+	return surface, nil
+}
+
+// readAttackSurfaceFromConfig reads the attack surface of the artifact and logs it,
+// false is returned when the artifact couldn't be read
+func readAttackSurfaceFromConfig(artifactPath, workDir string, logger log.Logger) (AttackSurface, bool) {
+	logger.Infof("Reading attack surface...")
+	surface, err := ReadAttackSurface(artifactPath, workDir)
+	if err != nil {
+		logger.Warnf("Failed to read attack surface: %s", err)
+		return AttackSurface{}, false
+	}
+
+	logger.Printf("Found %d exported component(s) and %d deep link(s)", len(surface.Components), len(surface.DeepLinks))
+	for _, component := range surface.Components {
+		if component.Permission == "" {
+			logger.Printf("- %s %s (unprotected)", component.Type, component.Name)
+		} else {
+			logger.Printf("- %s %s (%s)", component.Type, component.Name, component.Permission)
+		}
+	}
+	for _, link := range surface.DeepLinks {
+		logger.Printf("- %s %s", link.Kind, link.URI)
+	}
+	return surface, true
+}
+
+// compareAttackSurfaceFromConfig diffs the attack surface with the baseline build and logs the changes,
+// nil is returned if the baseline build has no recorded attack surface
+func compareAttackSurfaceFromConfig(surface AttackSurface, baseline HistoryRecord, logger log.Logger) *AttackSurfaceDiff {
+	if baseline.AttackSurface == nil {
+		logger.Printf("No attack surface recorded for the baseline build yet")
+		return nil
+	}
+
+	var diff AttackSurfaceDiff
+	diff.Added, diff.Removed = diffStrings(*baseline.AttackSurface, surface.Entries())
+	logger.Printf("Attack surface: %d added, %d removed", len(diff.Added), len(diff.Removed))
+	for _, entry := range diff.Added {
+		logger.Warnf("+ %s", entry)
+	}
+	for _, entry := range diff.Removed {
+		logger.Printf("- %s", entry)
+	}
+	return &diff
+}
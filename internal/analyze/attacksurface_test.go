@@ -0,0 +1,76 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestReadAttackSurface(t *testing.T) {
+	browsable := func(autoVerify string, data ...[][2]string) xmlNode {
+		filter := xmlNode{name: "intent-filter", children: []xmlNode{
+			{name: "action", attrs: [][2]string{{"name", "android.intent.action.VIEW"}}},
+			{name: "category", attrs: [][2]string{{"name", "android.intent.category.BROWSABLE"}}},
+		}}
+		if autoVerify != "" {
+			filter.attrs = [][2]string{{"autoVerify", autoVerify}}
+		}
+		for _, attrs := range data {
+			filter.children = append(filter.children, xmlNode{name: "data", attrs: attrs})
+		}
+		return filter
+	}
+	launcher := xmlNode{name: "intent-filter", children: []xmlNode{{name: "action", attrs: [][2]string{{"name", "android.intent.action.MAIN"}}}}}
+
+	manifest := xmlNode{name: "manifest", attrs: [][2]string{{"package", "com.example.app"}}, children: []xmlNode{{name: "application", children: []xmlNode{
+		{name: "activity", attrs: [][2]string{{"name", ".MainActivity"}, {"exported", "true"}}, children: []xmlNode{launcher,
+			browsable("true", [][2]string{{"scheme", "https"}, {"host", "example.com"}, {"pathPrefix", "/items"}}),
+			browsable("", [][2]string{{"scheme", "example"}}),
+		}},
+		{name: "activity", attrs: [][2]string{{"name", ".SettingsActivity"}, {"exported", "false"}}, children: []xmlNode{launcher}},
+		// Components with intent filters are exported by default before Android 12
+		{name: "receiver", attrs: [][2]string{{"name", "PushReceiver"}}, children: []xmlNode{
+			{name: "intent-filter", children: []xmlNode{{name: "action", attrs: [][2]string{{"name", "com.example.PUSH"}}}}},
+		}},
+		{name: "service", attrs: [][2]string{{"name", "com.example.sync.SyncService"}, {"exported", "true"}, {"permission", "com.example.permission.SYNC"}}},
+		{name: "provider", attrs: [][2]string{{"name", ".FilesProvider"}}, children: []xmlNode{launcher}},
+	}}}}
+
+	surface, err := ReadAttackSurface(writeAAB(t, manifest), t.TempDir())
+	if err != nil {
+		t.Fatalf("ReadAttackSurface() error = %s", err)
+	}
+	wantComponents := []ExportedComponent{
+		{Type: "activity", Name: "com.example.app.MainActivity", Actions: []string{"android.intent.action.MAIN", "android.intent.action.VIEW"}},
+		{Type: "service", Name: "com.example.sync.SyncService", Permission: "com.example.permission.SYNC"},
+		{Type: "receiver", Name: "com.example.app.PushReceiver", Implicit: true, Actions: []string{"com.example.PUSH"}},
+	}
+	if !reflect.DeepEqual(surface.Components, wantComponents) {
+		t.Errorf("Components = %+v, want %+v", surface.Components, wantComponents)
+	}
+	wantLinks := []DeepLink{
+		{URI: "https://example.com/items*", Kind: DeepLinkAppLink, Handler: "com.example.app.MainActivity"},
+		{URI: "example:", Kind: DeepLinkCustomScheme, Handler: "com.example.app.MainActivity"},
+	}
+	if !reflect.DeepEqual(surface.DeepLinks, wantLinks) {
+		t.Errorf("DeepLinks = %+v, want %+v", surface.DeepLinks, wantLinks)
+	}
+}
+
+func TestCompareAttackSurface(t *testing.T) {
+	surface := AttackSurface{
+		Components: []ExportedComponent{{Type: "activity", Name: "com.example.app.MainActivity"}},
+		DeepLinks:  []DeepLink{{URI: "example:", Kind: DeepLinkCustomScheme}},
+	}
+	if diff := compareAttackSurfaceFromConfig(surface, HistoryRecord{}, log.NewLogger()); diff != nil {
+		t.Errorf("compareAttackSurfaceFromConfig() without a recorded baseline = %+v", diff)
+	}
+
+	baseline := []string{"activity com.example.app.MainActivity", "receiver com.example.app.PushReceiver"}
+	diff := compareAttackSurfaceFromConfig(surface, HistoryRecord{AttackSurface: &baseline}, log.NewLogger())
+	want := &AttackSurfaceDiff{Added: []string{"custom scheme example:"}, Removed: []string{"receiver com.example.app.PushReceiver"}}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("compareAttackSurfaceFromConfig() = %+v, want %+v", diff, want)
+	}
+}
@@ -0,0 +1,95 @@
+package analyze
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// ModuleBudget is a size budget for the files under a path prefix
+type ModuleBudget struct {
+	Name        string
+	Prefix      string
+	BudgetBytes int64
+}
+
+// ModuleBudgetResult holds the evaluated size of a module
+type ModuleBudgetResult struct {
+	ModuleBudget
+	SizeBytes int64
+	FileCount int
+}
+
+// Exceeded reports whether the module is over its budget
+func (r ModuleBudgetResult) Exceeded() bool {
+	return r.SizeBytes > r.BudgetBytes
+}
+
+// parseModuleBudgets parses lines of "<path prefix>: <budget> [module name]", the budget is parsed in the units
+func parseModuleBudgets(input string, units config.Units) ([]ModuleBudget, error) {
+	pairs, err := config.ParseKeyValueLines(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var budgets []ModuleBudget
+	for _, pair := range pairs {
+		fields := strings.Fields(pair.Value)
+		budgetBytes, err := units.ParseSize(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget for %s: %w", pair.Key, err)
+		}
+
+		name := strings.Join(fields[1:], " ")
+		if name == "" {
+			name = pair.Key
+		}
+
+		budgets = append(budgets, ModuleBudget{
+			Name:        name,
+			Prefix:      pair.Key,
+			BudgetBytes: budgetBytes,
+		})
+	}
+	return budgets, nil
+}
+
+// evaluateModuleBudgets sums the stored size of the files under each module's path prefix
+func evaluateModuleBudgets(budgets []ModuleBudget, inventory Inventory) []ModuleBudgetResult {
+	var results []ModuleBudgetResult
+	for _, budget := range budgets {
+		result := ModuleBudgetResult{ModuleBudget: budget}
+		for _, entry := range inventory.Entries {
+			if strings.HasPrefix(entry.Path, budget.Prefix) {
+				result.SizeBytes += entry.CompressedSize
+				result.FileCount++
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// evaluateModuleBudgetsFromConfig evaluates the configured module budgets and logs the compliance of each module
+func evaluateModuleBudgetsFromConfig(cfg config.Config, inventory Inventory, logger log.Logger) []ModuleBudgetResult {
+	units := cfg.Units()
+	budgets, err := parseModuleBudgets(cfg.ModuleBudgets, units)
+	if err != nil {
+		logger.Warnf("Invalid module_budgets value: %s", err)
+		return nil
+	}
+
+	logger.Infof("Evaluating module budgets:")
+	results := evaluateModuleBudgets(budgets, inventory)
+	for _, result := range results {
+		status := "within budget"
+		if result.Exceeded() {
+			status = "over budget"
+		}
+		logger.Printf("- %s (%s): %s / %s in %d file(s), %s", result.Name, result.Prefix,
+			units.Format(result.SizeBytes), units.Format(result.BudgetBytes), result.FileCount, status)
+	}
+	return results
+}
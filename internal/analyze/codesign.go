@@ -0,0 +1,395 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// appInfoPlistPattern matches the Info.plist of the app bundle of an IPA
+var appInfoPlistPattern = regexp.MustCompile(`^Payload/[^/]+\.app/Info\.plist$`)
+
+// Mach-O and code signature constants, see <kern/cs_blobs.h>
+const (
+	loadCmdCodeSignature  = 0x1d
+	csMagicEmbeddedSig    = 0xfade0cc0
+	csMagicCodeDirectory  = 0xfade0c02
+	csMagicEntitlements   = 0xfade7171
+	csMagicBlobWrapper    = 0xfade0b01
+	csSlotCodeDirectory   = 0
+	csSlotEntitlements    = 5
+	csSlotAlternateFirst  = 0x1000
+	csSlotAlternateLast   = 0x1004
+	csSlotSignature       = 0x10000
+	csSpecialSlotInfo     = 1
+	csSpecialSlotResource = 3
+	csFlagAdhoc           = 0x2
+)
+
+// codeDirectoryHashes are the hash types of code directories
+var codeDirectoryHashes = map[uint8]func() hash.Hash{
+	1: sha1.New,
+	2: sha256.New,
+	// SHA-256 truncated to 20 bytes
+	3: sha256.New,
+	4: sha512.New384,
+}
+
+// codeSignatureSlice is the code signature of one architecture of the app executable
+type codeSignatureSlice struct {
+	// offset is the position of the slice in the executable, code pages are hashed from it
+	offset            int64
+	codeDirectories   [][]byte
+	entitlements      []byte
+	cms               []byte
+	hasCodeSignature  bool
+	superBlobProblems []string
+}
+
+// verifyCodeSignature verifies the code signature of the app executable of an IPA: the hashes of its code pages,
+// its Info.plist and the sealed resources in _CodeSignature/CodeResources. The CMS signature over the code
+// directory provides the signing certificate and isn't verified. Embedded frameworks are signed on their own
+// and aren't checked.
+func verifyCodeSignature(artifactPath, workDir string) (SignatureInfo, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var infoPlist []byte
+	appDir := ""
+	for _, file := range reader.File {
+		if appInfoPlistPattern.MatchString(file.Name) {
+			appDir = path.Dir(file.Name)
+			if infoPlist, err = readZipEntry(file); err != nil {
+				return SignatureInfo{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+			}
+			break
+		}
+	}
+	if appDir == "" {
+		return SignatureInfo{}, fmt.Errorf("no app bundle found in the IPA")
+	}
+	info, err := parsePlist(infoPlist)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to parse %s/Info.plist: %w", appDir, err)
+	}
+	dict, _ := info.(map[string]interface{})
+	executableName := plistString(dict, "CFBundleExecutable")
+	if executableName == "" {
+		return SignatureInfo{}, fmt.Errorf("%s/Info.plist has no CFBundleExecutable", appDir)
+	}
+
+	var executable *zip.File
+	var codeResources []byte
+	for _, file := range reader.File {
+		switch file.Name {
+		case appDir + "/" + executableName:
+			executable = file
+		case appDir + "/_CodeSignature/CodeResources":
+			if codeResources, err = readZipEntry(file); err != nil {
+				return SignatureInfo{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+			}
+		}
+	}
+	if executable == nil {
+		return SignatureInfo{}, fmt.Errorf("the app executable %s is missing", executableName)
+	}
+
+	// debug/macho needs random access, the executable is extracted next to the reports
+	extracted, err := extractZipEntry(executable, workDir)
+	if err != nil {
+		return SignatureInfo{}, err
+	}
+	defer func() { _ = os.Remove(extracted.Name()) }()
+	defer extracted.Close()
+
+	slices, err := readCodeSignatures(extracted)
+	if err != nil {
+		return SignatureInfo{}, fmt.Errorf("failed to read the code signature of %s: %w", executableName, err)
+	}
+
+	result := SignatureInfo{Status: SignatureSigned, Schemes: []string{"codesign"}}
+	invalid := func(problem string) {
+		result.Status = SignatureInvalid
+		result.Problems = append(result.Problems, problem)
+	}
+	adhoc, debugEntitlement := false, false
+	var cms []byte
+	for _, slice := range slices {
+		if !slice.hasCodeSignature {
+			return SignatureInfo{Status: SignatureUnsigned, Problems: []string{"the app executable isn't code signed"}}, nil
+		}
+		for _, problem := range slice.superBlobProblems {
+			invalid(problem)
+		}
+		for _, codeDirectory := range slice.codeDirectories {
+			cd, err := verifyCodeDirectory(codeDirectory, extracted, slice.offset, infoPlist, codeResources)
+			if err != nil {
+				invalid(err.Error())
+				continue
+			}
+			adhoc = adhoc || cd.flags&csFlagAdhoc != 0
+			if result.TeamID == "" {
+				result.TeamID = cd.teamID
+			}
+		}
+		if len(slice.entitlements) > 0 {
+			if entitlements, err := parsePlist(slice.entitlements); err == nil {
+				if dict, ok := entitlements.(map[string]interface{}); ok && dict["get-task-allow"] == true {
+					debugEntitlement = true
+				}
+			}
+		}
+		if cms == nil {
+			cms = slice.cms
+		}
+	}
+
+	if adhoc || len(cms) == 0 {
+		result.Status = SignatureUnsigned
+		result.Problems = append(result.Problems, "ad-hoc signed, without a signing identity")
+		return result, nil
+	}
+	certs, err := pkcs7Certificates(cms)
+	if err != nil {
+		return SignatureInfo{}, err
+	}
+	cert := leafCertificate(certs)
+	if cert == nil {
+		return SignatureInfo{}, fmt.Errorf("the code signature has no signing certificate")
+	}
+	result.describeCertificate(cert)
+	if result.TeamID == "" && len(cert.Subject.OrganizationalUnit) > 0 {
+		result.TeamID = cert.Subject.OrganizationalUnit[0]
+	}
+
+	if result.Status == SignatureSigned {
+		for _, prefix := range appleDevelopmentPrefixes {
+			if strings.HasPrefix(cert.Subject.CommonName, prefix) {
+				result.Status = SignatureDebug
+				result.Problems = append(result.Problems, "signed with a development certificate")
+				break
+			}
+		}
+	}
+	if result.Status != SignatureInvalid && debugEntitlement {
+		result.Status = SignatureDebug
+		result.Problems = append(result.Problems, "the get-task-allow entitlement allows attaching a debugger")
+	}
+	return result, nil
+}
+
+// extractZipEntry copies an entry of the artifact to a temporary file in the directory
+func extractZipEntry(file *zip.File, dir string) (*os.File, error) {
+	entry, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer entry.Close()
+
+	extracted, err := os.CreateTemp(dir, "codesign-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	if _, err := io.Copy(extracted, entry); err != nil {
+		_ = extracted.Close()
+		_ = os.Remove(extracted.Name())
+		return nil, fmt.Errorf("failed to extract %s: %w", file.Name, err)
+	}
+	return extracted, nil
+}
+
+// readCodeSignatures reads the embedded code signature of every architecture of a thin or universal executable
+func readCodeSignatures(file *os.File) ([]codeSignatureSlice, error) {
+	type machoSlice struct {
+		file   *macho.File
+		offset int64
+	}
+	var machoSlices []machoSlice
+	fat, err := macho.NewFatFile(file)
+	switch {
+	case err == nil:
+		for _, arch := range fat.Arches {
+			machoSlices = append(machoSlices, machoSlice{file: arch.File, offset: int64(arch.Offset)})
+		}
+	case errors.Is(err, macho.ErrNotFat):
+		thin, err := macho.NewFile(file)
+		if err != nil {
+			return nil, err
+		}
+		machoSlices = append(machoSlices, machoSlice{file: thin})
+	default:
+		return nil, err
+	}
+
+	var slices []codeSignatureSlice
+	for _, machoSlice := range machoSlices {
+		slice := codeSignatureSlice{offset: machoSlice.offset}
+		for _, load := range machoSlice.file.Loads {
+			raw := load.Raw()
+			if len(raw) < 16 || machoSlice.file.ByteOrder.Uint32(raw) != loadCmdCodeSignature {
+				continue
+			}
+			dataOffset := int64(machoSlice.file.ByteOrder.Uint32(raw[8:]))
+			dataSize := int64(machoSlice.file.ByteOrder.Uint32(raw[12:]))
+			superBlob := make([]byte, dataSize)
+			if _, err := file.ReadAt(superBlob, machoSlice.offset+dataOffset); err != nil {
+				return nil, fmt.Errorf("failed to read code signature: %w", err)
+			}
+			slice.hasCodeSignature = true
+			parseSuperBlob(superBlob, &slice)
+		}
+		slices = append(slices, slice)
+	}
+	return slices, nil
+}
+
+// parseSuperBlob splits the blobs of an embedded signature
+func parseSuperBlob(data []byte, slice *codeSignatureSlice) {
+	if len(data) < 12 || binary.BigEndian.Uint32(data) != csMagicEmbeddedSig {
+		slice.superBlobProblems = append(slice.superBlobProblems, "the code signature is corrupt")
+		return
+	}
+	count := binary.BigEndian.Uint32(data[8:])
+	if uint64(count)*8 > uint64(len(data)-12) {
+		slice.superBlobProblems = append(slice.superBlobProblems, "the code signature is corrupt")
+		return
+	}
+	for idx := uint32(0); idx < count; idx++ {
+		entry := data[12+idx*8:]
+		slot, offset := binary.BigEndian.Uint32(entry), binary.BigEndian.Uint32(entry[4:])
+		if uint64(offset)+8 > uint64(len(data)) {
+			slice.superBlobProblems = append(slice.superBlobProblems, "the code signature is corrupt")
+			return
+		}
+		length := binary.BigEndian.Uint32(data[offset+4:])
+		if length < 8 || uint64(offset)+uint64(length) > uint64(len(data)) {
+			slice.superBlobProblems = append(slice.superBlobProblems, "the code signature is corrupt")
+			return
+		}
+		blob := data[offset : offset+length]
+		magic := binary.BigEndian.Uint32(blob)
+		switch {
+		case (slot == csSlotCodeDirectory || slot >= csSlotAlternateFirst && slot <= csSlotAlternateLast) && magic == csMagicCodeDirectory:
+			slice.codeDirectories = append(slice.codeDirectories, blob)
+		case slot == csSlotEntitlements && magic == csMagicEntitlements:
+			slice.entitlements = blob[8:]
+		case slot == csSlotSignature && magic == csMagicBlobWrapper:
+			slice.cms = blob[8:]
+		}
+	}
+	if len(slice.codeDirectories) == 0 {
+		slice.superBlobProblems = append(slice.superBlobProblems, "the code signature has no code directory")
+	}
+}
+
+// codeDirectory holds the fields of a verified code directory reported about the signature
+type codeDirectory struct {
+	flags  uint32
+	teamID string
+}
+
+// verifyCodeDirectory checks the code page hashes and the Info.plist and CodeResources hashes of a code directory
+func verifyCodeDirectory(data []byte, file *os.File, sliceOffset int64, infoPlist, codeResources []byte) (codeDirectory, error) {
+	if len(data) < 44 {
+		return codeDirectory{}, fmt.Errorf("the code directory is truncated")
+	}
+	version := binary.BigEndian.Uint32(data[8:])
+	cd := codeDirectory{flags: binary.BigEndian.Uint32(data[12:])}
+	hashOffset := binary.BigEndian.Uint32(data[16:])
+	specialSlots := binary.BigEndian.Uint32(data[24:])
+	codeSlots := binary.BigEndian.Uint32(data[28:])
+	codeLimit := uint64(binary.BigEndian.Uint32(data[32:]))
+	hashSize, hashType, pageShift := int(data[36]), data[37], data[39]
+	if version >= 0x20200 && len(data) >= 52 {
+		if teamOffset := binary.BigEndian.Uint32(data[48:]); teamOffset > 0 && int(teamOffset) < len(data) {
+			cd.teamID, _, _ = strings.Cut(string(data[teamOffset:]), "\x00")
+		}
+	}
+	if version >= 0x20300 && len(data) >= 64 {
+		if codeLimit64 := binary.BigEndian.Uint64(data[56:]); codeLimit64 > 0 {
+			codeLimit = codeLimit64
+		}
+	}
+
+	newHash, ok := codeDirectoryHashes[hashType]
+	if !ok {
+		return codeDirectory{}, fmt.Errorf("unsupported code directory hash type %d", hashType)
+	}
+	if hashSize == 0 || hashSize > newHash().Size() {
+		return codeDirectory{}, fmt.Errorf("the code directory is corrupt")
+	}
+	if uint64(hashOffset) < uint64(specialSlots)*uint64(hashSize) || uint64(hashOffset)+uint64(codeSlots)*uint64(hashSize) > uint64(len(data)) {
+		return codeDirectory{}, fmt.Errorf("the code directory is corrupt")
+	}
+	digest := func(content []byte) []byte {
+		hasher := newHash()
+		hasher.Write(content)
+		return hasher.Sum(nil)[:hashSize]
+	}
+	slotHash := func(slot int64) []byte {
+		start := int64(hashOffset) + slot*int64(hashSize)
+		return data[start : start+int64(hashSize)]
+	}
+
+	specialFiles := []struct {
+		slot    uint32
+		name    string
+		content []byte
+	}{
+		{slot: csSpecialSlotInfo, name: "Info.plist", content: infoPlist},
+		{slot: csSpecialSlotResource, name: "_CodeSignature/CodeResources", content: codeResources},
+	}
+	for _, special := range specialFiles {
+		if special.slot > specialSlots {
+			continue
+		}
+		expected := slotHash(-int64(special.slot))
+		if bytes.Equal(expected, make([]byte, hashSize)) {
+			continue
+		}
+		if special.content == nil {
+			return codeDirectory{}, fmt.Errorf("%s is sealed by the signature but missing", special.name)
+		}
+		if !bytes.Equal(digest(special.content), expected) {
+			return codeDirectory{}, fmt.Errorf("%s was modified after signing", special.name)
+		}
+	}
+
+	pageSize := uint64(1) << pageShift
+	if pageShift == 0 {
+		pageSize = codeLimit
+	}
+	page := make([]byte, pageSize)
+	for slot := uint32(0); slot < codeSlots; slot++ {
+		start := uint64(slot) * pageSize
+		if start >= codeLimit {
+			return codeDirectory{}, fmt.Errorf("the code directory is corrupt")
+		}
+		size := pageSize
+		if start+size > codeLimit {
+			size = codeLimit - start
+		}
+		if _, err := file.ReadAt(page[:size], sliceOffset+int64(start)); err != nil {
+			return codeDirectory{}, fmt.Errorf("failed to read code page %d: %w", slot, err)
+		}
+		if !bytes.Equal(digest(page[:size]), slotHash(int64(slot))) {
+			return codeDirectory{}, fmt.Errorf("the executable was modified after signing (code page %d)", slot)
+		}
+	}
+	return cd, nil
+}
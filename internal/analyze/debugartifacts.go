@@ -0,0 +1,187 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Kinds of debug findings
+const (
+	DebugFindingDebuggable      = "debuggable"
+	DebugFindingSignature       = "debug signature"
+	DebugFindingTestFramework   = "test framework"
+	DebugFindingInstrumentation = "instrumentation"
+)
+
+// androidTestLibraries maps the DEX type descriptor prefixes of test frameworks and debug tools to their name
+var androidTestLibraries = map[string]string{
+	"Lorg/junit/":               "JUnit",
+	"Ljunit/framework/":         "JUnit",
+	"Landroidx/test/espresso/":  "Espresso",
+	"Landroidx/test/runner/":    "AndroidX Test",
+	"Landroid/support/test/":    "Android Testing Support Library",
+	"Lorg/mockito/":             "Mockito",
+	"Lio/mockk/":                "MockK",
+	"Lorg/robolectric/":         "Robolectric",
+	"Lleakcanary/":              "LeakCanary",
+	"Lcom/facebook/flipper/":    "Flipper",
+	"Lcom/chuckerteam/chucker/": "Chucker",
+}
+
+// appleTestFrameworks are the frameworks and libraries of XCTest, only needed to run tests
+var appleTestFrameworks = map[string]bool{
+	"XCTest.framework":               true,
+	"XCTestCore.framework":           true,
+	"XCTestSupport.framework":        true,
+	"XCTAutomationSupport.framework": true,
+	"XCUIAutomation.framework":       true,
+	"XCUnit.framework":               true,
+	"libXCTestSwiftSupport.dylib":    true,
+	"libXCTestBundleInject.dylib":    true,
+}
+
+// DebugFinding is a sign of a debug build in the artifact
+type DebugFinding struct {
+	Kind   string
+	Detail string
+}
+
+// DetectDebugArtifacts returns the signs of a debug build in the artifact: debuggable or test-only manifests,
+// debug signatures and bundled test frameworks. signature is the verified signature of the artifact.
+func DetectDebugArtifacts(artifactPath string, signature SignatureInfo) ([]DebugFinding, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var findings []DebugFinding
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		findings = append(findings, appleTestFrameworkFindings(&reader.Reader)...)
+	} else {
+		manifestFindings, err := androidManifestDebugFindings(&reader.Reader)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, manifestFindings...)
+
+		libraryFindings, err := androidTestLibraryFindings(&reader.Reader)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, libraryFindings...)
+	}
+
+	if signature.Status == SignatureDebug {
+		for _, problem := range signature.Problems {
+			findings = append(findings, DebugFinding{Kind: DebugFindingSignature, Detail: problem})
+		}
+	}
+	return findings, nil
+}
+
+// androidManifestDebugFindings checks the debuggable and testOnly flags of the application and instrumentation entries
+func androidManifestDebugFindings(reader *zip.Reader) ([]DebugFinding, error) {
+	manifest, err := readAndroidManifest(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []DebugFinding
+	for _, application := range manifest.ChildrenNamed("application") {
+		if application.Attrs["debuggable"] == "true" {
+			findings = append(findings, DebugFinding{Kind: DebugFindingDebuggable, Detail: "android:debuggable is set, anyone can attach a debugger"})
+		}
+		if application.Attrs["testOnly"] == "true" {
+			findings = append(findings, DebugFinding{Kind: DebugFindingDebuggable, Detail: "android:testOnly is set, the Play Store rejects the upload"})
+		}
+	}
+	for _, instrumentation := range manifest.ChildrenNamed("instrumentation") {
+		findings = append(findings, DebugFinding{Kind: DebugFindingInstrumentation, Detail: fmt.Sprintf("declares the instrumentation %s", instrumentation.Attrs["name"])})
+	}
+	return findings, nil
+}
+
+// androidTestLibraryFindings looks for the classes of test frameworks and debug tools in the DEX files
+func androidTestLibraryFindings(reader *zip.Reader) ([]DebugFinding, error) {
+	found := map[string]string{}
+	for _, file := range reader.File {
+		if !isDexEntry(file.Name) {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		for descriptor, library := range androidTestLibraries {
+			if _, ok := found[library]; !ok && bytes.Contains(data, []byte(descriptor)) {
+				found[library] = file.Name
+			}
+		}
+	}
+
+	var libraries []string
+	for library := range found {
+		libraries = append(libraries, library)
+	}
+	sort.Strings(libraries)
+
+	var findings []DebugFinding
+	for _, library := range libraries {
+		findings = append(findings, DebugFinding{Kind: DebugFindingTestFramework, Detail: fmt.Sprintf("%s classes in %s", library, found[library])})
+	}
+	return findings, nil
+}
+
+// appleTestFrameworkFindings looks for XCTest frameworks and test bundles (.xctest) in the IPA
+func appleTestFrameworkFindings(reader *zip.Reader) []DebugFinding {
+	seen := map[string]bool{}
+	var findings []DebugFinding
+	for _, file := range reader.File {
+		parts := strings.Split(file.Name, "/")
+		for idx, part := range parts {
+			if !appleTestFrameworks[part] && !strings.HasSuffix(part, ".xctest") {
+				continue
+			}
+			bundlePath := strings.Join(parts[:idx+1], "/")
+			if !seen[bundlePath] {
+				seen[bundlePath] = true
+				findings = append(findings, DebugFinding{Kind: DebugFindingTestFramework, Detail: bundlePath})
+			}
+			break
+		}
+	}
+	return findings
+}
+
+// detectDebugArtifactsFromConfig checks the artifact for signs of a debug build and logs them, the signature
+// is verified unless verified is set. False is returned when the artifact couldn't be checked.
+func detectDebugArtifactsFromConfig(artifactPath, workDir string, signature SignatureInfo, verified bool, logger log.Logger) ([]DebugFinding, bool) {
+	logger.Infof("Checking for debug artifacts...")
+	if !verified {
+		var err error
+		if signature, err = VerifySignature(artifactPath, workDir); err != nil {
+			logger.Warnf("Failed to verify signature: %s", err)
+		}
+	}
+
+	findings, err := DetectDebugArtifacts(artifactPath, signature)
+	if err != nil {
+		logger.Warnf("Failed to check for debug artifacts: %s", err)
+		return nil, false
+	}
+
+	if len(findings) == 0 {
+		logger.Donef("No signs of a debug build found")
+	}
+	for _, finding := range findings {
+		logger.Warnf("%s: %s", finding.Kind, finding.Detail)
+	}
+	return findings, true
+}
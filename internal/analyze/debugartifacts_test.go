@@ -0,0 +1,75 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectDebugArtifacts(t *testing.T) {
+	release := xmlNode{name: "manifest", children: []xmlNode{{name: "application"}}}
+	debuggable := xmlNode{name: "manifest", children: []xmlNode{
+		{name: "application", attrs: [][2]string{{"debuggable", "true"}, {"testOnly", "true"}}},
+		{name: "instrumentation", attrs: [][2]string{{"name", "androidx.test.runner.AndroidJUnitRunner"}}},
+	}}
+	testClasses := zipEntry{name: "base/dex/classes2.dex", content: "dex\n035\x00Lorg/junit/Assert;\x00Lleakcanary/LeakCanary;"}
+	debugSignature := SignatureInfo{Status: SignatureDebug, Problems: []string{"signed with the Android debug keystore"}}
+
+	tests := []struct {
+		name      string
+		manifest  xmlNode
+		entries   []zipEntry
+		signature SignatureInfo
+		want      []DebugFinding
+	}{
+		{name: "release build", manifest: release, entries: []zipEntry{{name: "base/dex/classes.dex", content: "dex\n035\x00Lcom/example/App;"}}, signature: SignatureInfo{Status: SignatureSigned}},
+		{name: "debuggable manifest", manifest: debuggable, want: []DebugFinding{
+			{Kind: DebugFindingDebuggable, Detail: "android:debuggable is set, anyone can attach a debugger"},
+			{Kind: DebugFindingDebuggable, Detail: "android:testOnly is set, the Play Store rejects the upload"},
+			{Kind: DebugFindingInstrumentation, Detail: "declares the instrumentation androidx.test.runner.AndroidJUnitRunner"},
+		}},
+		{name: "test frameworks and debug signature", manifest: release, entries: []zipEntry{testClasses}, signature: debugSignature, want: []DebugFinding{
+			{Kind: DebugFindingTestFramework, Detail: "JUnit classes in base/dex/classes2.dex"},
+			{Kind: DebugFindingTestFramework, Detail: "LeakCanary classes in base/dex/classes2.dex"},
+			{Kind: DebugFindingSignature, Detail: "signed with the Android debug keystore"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := DetectDebugArtifacts(writeAAB(t, tt.manifest, tt.entries...), tt.signature)
+			if err != nil {
+				t.Fatalf("DetectDebugArtifacts() error = %s", err)
+			}
+			if !reflect.DeepEqual(findings, tt.want) {
+				t.Errorf("DetectDebugArtifacts() = %+v, want %+v", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectDebugArtifactsIPA(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+	entries := []zipEntry{
+		{name: "Payload/Test.app/Info.plist", content: infoPlist("Test")},
+		{name: "Payload/Test.app/Frameworks/XCTest.framework/XCTest", content: "xctest"},
+		{name: "Payload/Test.app/Frameworks/XCTest.framework/Info.plist", content: infoPlist("XCTest")},
+		{name: "Payload/Test.app/PlugIns/TestTests.xctest/TestTests", content: "tests"},
+	}
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := DetectDebugArtifacts(artifactPath, SignatureInfo{})
+	if err != nil {
+		t.Fatalf("DetectDebugArtifacts() error = %s", err)
+	}
+	want := []DebugFinding{
+		{Kind: DebugFindingTestFramework, Detail: "Payload/Test.app/Frameworks/XCTest.framework"},
+		{Kind: DebugFindingTestFramework, Detail: "Payload/Test.app/PlugIns/TestTests.xctest"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("DetectDebugArtifacts() = %+v, want %+v", findings, want)
+	}
+}
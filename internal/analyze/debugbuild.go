@@ -0,0 +1,78 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// unminifiedCoverageBelow is the obfuscation coverage below which the DEX code is considered not minified,
+// R8 and ProGuard rename nearly every class of the app and its libraries
+const unminifiedCoverageBelow = 1.0
+
+// DetectDebugBuild returns the signs that the artifact isn't a release build: debuggable flags, debug signing and
+// missing minification. The cheap checks run on every artifact, the signature and the provisioning profile are
+// only taken into account when verify_signature or provisioning_profile_report read them.
+func DetectDebugBuild(artifactPath string, result ArtifactResult) ([]string, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var reasons []string
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		for _, file := range reader.File {
+			// Xcode moves the code of Debug builds to a .debug.dylib next to a stub executable
+			if strings.HasSuffix(file.Name, ".debug.dylib") {
+				reasons = append(reasons, fmt.Sprintf("%s is only built with the Debug configuration", path.Base(file.Name)))
+				break
+			}
+		}
+		if result.ProfileRead && result.Profile.Type == DistributionDevelopment {
+			reasons = append(reasons, "signed with a development provisioning profile")
+		}
+	} else {
+		findings, err := androidManifestDebugFindings(&reader.Reader)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range findings {
+			if finding.Kind == DebugFindingDebuggable {
+				reasons = append(reasons, finding.Detail)
+			}
+		}
+		if result.Metrics.ObfuscationMeasured && result.Metrics.ObfuscationCoverage < unminifiedCoverageBelow {
+			reasons = append(reasons, fmt.Sprintf("%.1f%% of the DEX classes are obfuscated, R8 or ProGuard minification is disabled", result.Metrics.ObfuscationCoverage))
+		}
+	}
+
+	if result.SignatureVerified && result.Signature.Status == SignatureDebug {
+		reasons = append(reasons, "signed with a debug certificate")
+	}
+	return reasons, nil
+}
+
+// detectDebugBuildFromConfig checks whether the artifact looks like a debug build and warns prominently about it,
+// the sizes of debug builds don't tell how large the release is. False is returned when the artifact couldn't be checked.
+func detectDebugBuildFromConfig(artifactPath string, result ArtifactResult, logger log.Logger) ([]string, bool) {
+	reasons, err := DetectDebugBuild(artifactPath, result)
+	if err != nil {
+		logger.Warnf("Failed to check for a debug build: %s", err)
+		return nil, false
+	}
+	if len(reasons) == 0 {
+		return nil, true
+	}
+
+	logger.Println()
+	logger.Warnf("⚠️  %s looks like a debug build, its size doesn't reflect the release build:", result.Name)
+	for _, reason := range reasons {
+		logger.Warnf("- %s", reason)
+	}
+	logger.Warnf("Analyze the release artifact to draw conclusions about the size users download.")
+	return reasons, true
+}
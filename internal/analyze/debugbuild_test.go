@@ -0,0 +1,81 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDetectDebugBuild(t *testing.T) {
+	release := xmlNode{name: "manifest", children: []xmlNode{{name: "application"}}}
+	debuggable := xmlNode{name: "manifest", children: []xmlNode{{name: "application", attrs: [][2]string{{"debuggable", "true"}}}}}
+
+	tests := []struct {
+		name     string
+		manifest xmlNode
+		result   ArtifactResult
+		want     []string
+	}{
+		{name: "release build", manifest: release, result: ArtifactResult{
+			Metrics:           BundleMetrics{ObfuscationMeasured: true, ObfuscationCoverage: 97.5},
+			SignatureVerified: true, Signature: SignatureInfo{Status: SignatureSigned},
+		}},
+		{name: "debuggable manifest", manifest: debuggable, want: []string{"android:debuggable is set, anyone can attach a debugger"}},
+		{name: "unminified and debug signed", manifest: release, result: ArtifactResult{
+			Metrics:           BundleMetrics{ObfuscationMeasured: true, ObfuscationCoverage: 0.4},
+			SignatureVerified: true, Signature: SignatureInfo{Status: SignatureDebug},
+		}, want: []string{
+			"0.4% of the DEX classes are obfuscated, R8 or ProGuard minification is disabled",
+			"signed with a debug certificate",
+		}},
+		// The signature is only taken into account when verify_signature read it
+		{name: "unverified signature", manifest: release, result: ArtifactResult{Signature: SignatureInfo{Status: SignatureDebug}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons, err := DetectDebugBuild(writeAAB(t, tt.manifest), tt.result)
+			if err != nil {
+				t.Fatalf("DetectDebugBuild() error = %s", err)
+			}
+			if !reflect.DeepEqual(reasons, tt.want) {
+				t.Errorf("DetectDebugBuild() = %q, want %q", reasons, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectDebugBuildIPA(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []zipEntry
+		result  ArtifactResult
+		want    []string
+	}{
+		{name: "release build", entries: []zipEntry{{name: "Payload/Test.app/Test", content: "executable"}},
+			result: ArtifactResult{ProfileRead: true, Profile: ProvisioningProfile{Type: DistributionAppStore}}},
+		{name: "debug dylib and development profile", entries: []zipEntry{
+			{name: "Payload/Test.app/Test", content: "stub"},
+			{name: "Payload/Test.app/Test.debug.dylib", content: "executable"},
+		}, result: ArtifactResult{ProfileRead: true, Profile: ProvisioningProfile{Type: DistributionDevelopment}}, want: []string{
+			"Test.debug.dylib is only built with the Debug configuration",
+			"signed with a development provisioning profile",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+			if err := os.WriteFile(artifactPath, writeZip(t, tt.entries, zip.Deflate), 0644); err != nil {
+				t.Fatal(err)
+			}
+			reasons, err := DetectDebugBuild(artifactPath, tt.result)
+			if err != nil {
+				t.Fatalf("DetectDebugBuild() error = %s", err)
+			}
+			if !reflect.DeepEqual(reasons, tt.want) {
+				t.Errorf("DetectDebugBuild() = %q, want %q", reasons, tt.want)
+			}
+		})
+	}
+}
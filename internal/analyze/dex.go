@@ -0,0 +1,71 @@
+package analyze
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+)
+
+// dexHeaderSize is the number of header bytes needed to read the method_ids_size field
+const dexHeaderSize = 0x5C
+
+// dexMethodIDsSizeOffset is the offset of the method_ids_size field in the DEX header
+const dexMethodIDsSizeOffset = 0x58
+
+var dexFilePattern = regexp.MustCompile(`^classes\d*\.dex$`)
+
+// isDexEntry reports whether the entry is a DEX file (classes*.dex in APKs, <module>/dex/classes*.dex in AABs)
+func isDexEntry(entryPath string) bool {
+	if !dexFilePattern.MatchString(path.Base(entryPath)) {
+		return false
+	}
+	dir := path.Dir(entryPath)
+	return dir == "." || path.Base(dir) == "dex"
+}
+
+// readDexMethodCounts returns the number of method references of each DEX file in the artifact
+func readDexMethodCounts(artifactPath string) (map[string]int64, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	counts := map[string]int64{}
+	for _, file := range reader.File {
+		if !isDexEntry(file.Name) {
+			continue
+		}
+
+		count, err := readDexMethodCount(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		counts[file.Name] = count
+	}
+
+	return counts, nil
+}
+
+// readDexMethodCount reads the method_ids_size field from the header of a DEX file
+func readDexMethodCount(file *zip.File) (int64, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	header := make([]byte, dexHeaderSize)
+	if _, err := io.ReadFull(rc, header); err != nil {
+		return 0, fmt.Errorf("failed to read DEX header: %w", err)
+	}
+
+	if string(header[:4]) != "dex\n" {
+		return 0, fmt.Errorf("invalid DEX magic")
+	}
+
+	return int64(binary.LittleEndian.Uint32(header[dexMethodIDsSizeOffset:])), nil
+}
@@ -0,0 +1,104 @@
+package analyze
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Delivery compressions of download_compression
+const (
+	// CompressionStoreDefault estimates the download as the compressed entries of the artifact, like the stores deliver it
+	CompressionStoreDefault = "store-default"
+	CompressionGzip         = "gzip"
+	CompressionBrotli       = "brotli"
+	CompressionZstd         = "zstd"
+)
+
+// compressionCommands are the CLI tools compressing the artifact to stdout for the compressions without a Go implementation
+var compressionCommands = map[string][]string{
+	CompressionBrotli: {"brotli", "--stdout"},
+	CompressionZstd:   {"zstd", "--stdout", "--quiet"},
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// estimateDownloadSize estimates the bytes users download when the artifact is delivered with the compression:
+// the artifact file compressed as a whole for gzip, brotli and zstd, the compressed entries for store-default.
+// brotli and zstd run the CLI tools of the same name, they fail when the tool isn't installed.
+func estimateDownloadSize(ctx context.Context, compression, artifactPath string, inventory Inventory, environ []string, logger log.Logger) (int64, error) {
+	switch compression {
+	case "", CompressionStoreDefault:
+		return inventory.DownloadSize(), nil
+	case CompressionGzip:
+		return gzipSize(artifactPath)
+	}
+
+	command, ok := compressionCommands[compression]
+	if !ok {
+		return 0, fmt.Errorf("unknown compression %s", compression)
+	}
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return 0, fmt.Errorf("%s isn't installed", command[0])
+	}
+	var counter countingWriter
+	args := append(append([]string{}, command[1:]...), artifactPath)
+	if out, err := executor.RunCommand(ctx, command[0], "", environ, command[0], args, &counter, logger); err != nil {
+		if out != "" {
+			return 0, fmt.Errorf("%w: %s", err, out)
+		}
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// gzipSize returns the size of the artifact compressed with gzip at the default level, like web servers deliver it
+func gzipSize(artifactPath string) (int64, error) {
+	file, err := os.Open(artifactPath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var counter countingWriter
+	writer := gzip.NewWriter(&counter)
+	if _, err := io.Copy(writer, file); err != nil {
+		return 0, fmt.Errorf("failed to compress artifact: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return 0, fmt.Errorf("failed to compress artifact: %w", err)
+	}
+	return counter.n, nil
+}
+
+// estimateDownloadSizeFromConfig estimates the download size with download_compression and logs it,
+// falling back to the store-default estimate when the compression fails
+func estimateDownloadSizeFromConfig(ctx context.Context, cfg config.Config, artifactPath string, inventory Inventory, environ []string, logger log.Logger) (int64, string) {
+	compression := cfg.DownloadCompression
+	if compression == "" {
+		compression = CompressionStoreDefault
+	}
+	size, err := estimateDownloadSize(ctx, compression, artifactPath, inventory, environ, logger)
+	if err != nil {
+		logger.Warnf("Failed to estimate the %s download size, using %s: %s", compression, CompressionStoreDefault, err)
+		compression = CompressionStoreDefault
+		size = inventory.DownloadSize()
+	}
+	logger.Printf("Estimated download size (%s): %s", compression, cfg.Units().Format(size))
+	return size, compression
+}
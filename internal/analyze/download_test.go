@@ -0,0 +1,55 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+func TestEstimateDownloadSize(t *testing.T) {
+	content := writeZip(t, []zipEntry{{name: "assets/data.txt", content: strings.Repeat("data", 4096)}}, zip.Store)
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	if _, err := writer.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	inventory := Inventory{Entries: []ArchiveEntry{{Path: "assets/data.txt", CompressedSize: 16384}, {Path: "classes.dex", CompressedSize: 1000}}}
+	// The compression tools aren't found in an empty PATH
+	t.Setenv("PATH", t.TempDir())
+
+	tests := []struct {
+		name            string
+		compression     string
+		wantSize        int64
+		wantCompression string
+	}{
+		{name: "default", wantSize: 17384, wantCompression: CompressionStoreDefault},
+		{name: "store default", compression: CompressionStoreDefault, wantSize: 17384, wantCompression: CompressionStoreDefault},
+		{name: "gzip", compression: CompressionGzip, wantSize: int64(gzipped.Len()), wantCompression: CompressionGzip},
+		{name: "missing tool falls back to store default", compression: CompressionBrotli, wantSize: 17384, wantCompression: CompressionStoreDefault},
+		{name: "unknown compression falls back to store default", compression: "lzma", wantSize: 17384, wantCompression: CompressionStoreDefault},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, compression := estimateDownloadSizeFromConfig(context.Background(), config.Config{DownloadCompression: tt.compression}, artifactPath, inventory, nil, log.NewLogger())
+			if size != tt.wantSize || compression != tt.wantCompression {
+				t.Errorf("estimateDownloadSizeFromConfig() = %d, %s, want %d, %s", size, compression, tt.wantSize, tt.wantCompression)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package analyze
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// DuplicateSet is a group of byte-identical files in the artifact
+type DuplicateSet struct {
+	Hash        string
+	Paths       []string
+	SizeBytes   int64
+	WastedBytes int64
+}
+
+// duplicateCandidateKey groups entries that may have identical content
+type duplicateCandidateKey struct {
+	size  int64
+	crc32 uint32
+}
+
+// FindDuplicates finds byte-identical files in the artifact.
+// Entries with matching size and CRC32 are candidates, their content is confirmed with SHA-256 hashed in parallel.
+// The progress of long hashing runs is logged periodically. Entries outside of the scope are skipped.
+func FindDuplicates(artifactPath string, scope EntryScope, logger log.Logger) ([]DuplicateSet, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	candidates := map[duplicateCandidateKey][]*zip.File{}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || file.UncompressedSize64 == 0 || !scope.Contains(file.Name) {
+			continue
+		}
+		key := duplicateCandidateKey{size: int64(file.UncompressedSize64), crc32: file.CRC32}
+		candidates[key] = append(candidates[key], file)
+	}
+
+	// Only the candidates sharing size and CRC32 with another entry are hashed, all of them in one parallel pass
+	var hashed []*zip.File
+	for _, files := range candidates {
+		if len(files) >= 2 {
+			hashed = append(hashed, files...)
+		}
+	}
+	hashes, err := hashEntries(hashed, logger)
+	if err != nil {
+		return nil, err
+	}
+	hashOf := make(map[*zip.File]string, len(hashed))
+	for idx, file := range hashed {
+		hashOf[file] = hashes[idx]
+	}
+
+	var sets []DuplicateSet
+	for _, files := range candidates {
+		if len(files) < 2 {
+			continue
+		}
+
+		byHash := map[string][]*zip.File{}
+		for _, file := range files {
+			byHash[hashOf[file]] = append(byHash[hashOf[file]], file)
+		}
+
+		for hash, identical := range byHash {
+			if len(identical) < 2 {
+				continue
+			}
+
+			set := DuplicateSet{Hash: hash, SizeBytes: int64(identical[0].UncompressedSize64)}
+			for idx, file := range identical {
+				set.Paths = append(set.Paths, file.Name)
+				if idx > 0 {
+					set.WastedBytes += int64(file.CompressedSize64)
+				}
+			}
+			sort.Strings(set.Paths)
+			sets = append(sets, set)
+		}
+	}
+
+	sort.Slice(sets, func(a, b int) bool {
+		if sets[a].WastedBytes != sets[b].WastedBytes {
+			return sets[a].WastedBytes > sets[b].WastedBytes
+		}
+		return sets[a].Paths[0] < sets[b].Paths[0]
+	})
+	return sets, nil
+}
+
+// hashZipEntry returns the hex encoded SHA-256 of an entry's content
+func hashZipEntry(file *zip.File) (string, error) {
+	hash := sha256.New()
+	if _, err := streamEntry(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// TotalWastedBytes sums the bytes wasted by all duplicate sets
+func TotalWastedBytes(sets []DuplicateSet) int64 {
+	var total int64
+	for _, set := range sets {
+		total += set.WastedBytes
+	}
+	return total
+}
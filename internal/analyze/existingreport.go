@@ -0,0 +1,96 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// importExistingReport copies a pre-generated JSON report, and the markdown and HTML reports next to it, into the work directory
+// named like bundle-inspector names the reports of the artifact, so the rest of the step handles them as if they were just generated
+func importExistingReport(reportPath, artifactPath, workDir string, units config.Units, logger log.Logger) error {
+	if filepath.Ext(reportPath) != ".json" {
+		return fmt.Errorf("existing_report_path has to point to a JSON report: %s", reportPath)
+	}
+
+	for _, srcPath := range ExistingReportFiles(reportPath) {
+		ext := filepath.Ext(srcPath)
+		data, err := os.ReadFile(srcPath)
+		if err != nil {
+			if ext == ".json" {
+				return fmt.Errorf("failed to read existing report: %w", err)
+			}
+			continue
+		}
+
+		dstPath := filepath.Join(workDir, ReportName(artifactPath, ext))
+		if err := os.WriteFile(dstPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to copy existing report: %w", err)
+		}
+		logger.Printf("Imported: %s", srcPath)
+	}
+
+	// Comments need a markdown report, write a summary if none was generated with the JSON report
+	markdownPath := filepath.Join(workDir, ReportName(artifactPath, ".md"))
+	if _, err := os.Stat(markdownPath); os.IsNotExist(err) {
+		metrics, err := ParseJSONReport(filepath.Join(workDir, ReportName(artifactPath, ".json")), logger)
+		if err != nil {
+			return err
+		}
+		if err := writeSummaryMarkdown(markdownPath, metrics, units); err != nil {
+			return err
+		}
+		logger.Printf("No markdown report found next to the JSON report, generated a summary")
+	}
+
+	return nil
+}
+
+// ExistingReportFiles returns the JSON report of existing_report_path and the markdown and HTML reports next to it
+func ExistingReportFiles(reportPath string) []string {
+	if reportPath == "" {
+		return nil
+	}
+	var files []string
+	for _, ext := range []string{".json", ".md", ".html"} {
+		files = append(files, strings.TrimSuffix(reportPath, ".json")+ext)
+	}
+	return files
+}
+
+// ReportArtifactPath returns the artifact path recorded in a JSON report
+func ReportArtifactPath(reportPath string) (string, error) {
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read existing report: %w", err)
+	}
+
+	var report struct {
+		ArtifactInfo struct {
+			Path string `json:"path"`
+		} `json:"artifact_info"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return "", fmt.Errorf("failed to parse existing report: %w", err)
+	}
+	if report.ArtifactInfo.Path == "" {
+		return "", fmt.Errorf("existing report doesn't contain the artifact path")
+	}
+	return report.ArtifactInfo.Path, nil
+}
+
+// writeSummaryMarkdown writes a minimal markdown report of the bundle metrics, the sizes are shown in the units
+func writeSummaryMarkdown(markdownPath string, metrics BundleMetrics, units config.Units) error {
+	content := fmt.Sprintf("## 📦 Bundle Analysis Report\n\n| Metric | Value |\n|--------|-------|\n| Bundle Size | %s |\n| Potential Savings | %s |\n",
+		units.Format(metrics.SizeBytes), units.Format(metrics.PotentialSavingsBytes))
+
+	if err := os.WriteFile(markdownPath, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write markdown report: %w", err)
+	}
+	return nil
+}
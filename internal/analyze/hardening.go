@@ -0,0 +1,273 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Formats of native binaries
+const (
+	BinaryFormatELF   = "ELF"
+	BinaryFormatMachO = "Mach-O"
+)
+
+// Hardening checks of native binaries
+const (
+	HardeningPIE         = "PIE"
+	HardeningStackCanary = "stack canary"
+	HardeningRELRO       = "full RELRO"
+	HardeningNX          = "NX"
+)
+
+// machoAllowStackExecution is the MH_ALLOW_STACK_EXECUTION header flag, debug/macho doesn't define it
+const machoAllowStackExecution = 0x20000
+
+// stackCanarySymbols are imported by binaries compiled with stack protectors (-fstack-protector)
+var stackCanarySymbols = []string{"__stack_chk_fail", "__stack_chk_guard", "___stack_chk_fail", "___stack_chk_guard"}
+
+// machoMagics are the first bytes of thin (32 and 64-bit, little-endian) and fat Mach-O files
+var machoMagics = [][]byte{{0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe}, {0xca, 0xfe, 0xba, 0xbe}}
+
+// BinaryHardening holds the exploit mitigations of a native binary
+type BinaryHardening struct {
+	Path   string
+	Format string
+	// PIE is set for position independent code: executables built as PIE, shared libraries without text relocations
+	PIE         bool
+	StackCanary bool
+	// RELRO is set for ELF binaries with read-only relocations and immediate binding, always set for Mach-O
+	RELRO bool
+	// NX is set when the stack isn't executable
+	NX bool
+}
+
+// Missing returns the hardening checks the binary fails
+func (b BinaryHardening) Missing() []string {
+	var missing []string
+	if !b.PIE {
+		missing = append(missing, HardeningPIE)
+	}
+	if !b.StackCanary {
+		missing = append(missing, HardeningStackCanary)
+	}
+	if !b.RELRO {
+		missing = append(missing, HardeningRELRO)
+	}
+	if !b.NX {
+		missing = append(missing, HardeningNX)
+	}
+	return missing
+}
+
+// Hardened reports whether the binary passes every hardening check
+func (b BinaryHardening) Hardened() bool {
+	return len(b.Missing()) == 0
+}
+
+// CheckNativeHardening checks the native libraries of APKs and AABs and the Mach-O binaries of IPAs, sorted by path
+func CheckNativeHardening(artifactPath, workDir string) ([]BinaryHardening, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	ipa := strings.EqualFold(path.Ext(artifactPath), ".ipa")
+	var binaries []BinaryHardening
+	for _, file := range reader.File {
+		native, err := isNativeBinary(file, ipa)
+		if err != nil {
+			return nil, err
+		}
+		if !native {
+			continue
+		}
+		check := checkELFHardening
+		if ipa {
+			check = checkMachOHardening
+		}
+
+		// debug/elf and debug/macho need random access, the binary is extracted next to the reports
+		extracted, err := extractZipEntry(file, workDir)
+		if err != nil {
+			return nil, err
+		}
+		binary, err := check(extracted)
+		_ = extracted.Close()
+		_ = os.Remove(extracted.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file.Name, err)
+		}
+		binary.Path = file.Name
+		binaries = append(binaries, binary)
+	}
+
+	sort.Slice(binaries, func(i, j int) bool { return binaries[i].Path < binaries[j].Path })
+	return binaries, nil
+}
+
+// checkELFHardening checks a native library of an Android artifact
+func checkELFHardening(file *os.File) (BinaryHardening, error) {
+	binary, err := elf.NewFile(file)
+	if err != nil {
+		return BinaryHardening{}, err
+	}
+	defer binary.Close()
+
+	result := BinaryHardening{Format: BinaryFormatELF, PIE: binary.Type == elf.ET_DYN}
+	textRelocations, _ := binary.DynValue(elf.DT_TEXTREL)
+	flags, _ := binary.DynValue(elf.DT_FLAGS)
+	flags1, _ := binary.DynValue(elf.DT_FLAGS_1)
+	bindNow, _ := binary.DynValue(elf.DT_BIND_NOW)
+	if len(textRelocations) > 0 || hasDynFlag(flags, uint64(elf.DF_TEXTREL)) {
+		result.PIE = false
+	}
+
+	relro := false
+	for _, prog := range binary.Progs {
+		switch prog.Type {
+		case elf.PT_GNU_RELRO:
+			relro = true
+		case elf.PT_GNU_STACK:
+			result.NX = prog.Flags&elf.PF_X == 0
+		}
+	}
+	result.RELRO = relro && (len(bindNow) > 0 || hasDynFlag(flags, uint64(elf.DF_BIND_NOW)) || hasDynFlag(flags1, uint64(elf.DF_1_NOW)))
+
+	if symbols, err := binary.ImportedSymbols(); err == nil {
+		for _, symbol := range symbols {
+			result.StackCanary = result.StackCanary || containsString(stackCanarySymbols, symbol.Name)
+		}
+	}
+	return result, nil
+}
+
+// hasDynFlag checks whether a DT_FLAGS or DT_FLAGS_1 entry has the flag set
+func hasDynFlag(values []uint64, flag uint64) bool {
+	for _, value := range values {
+		if value&flag != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMachOHardening checks a Mach-O binary of an IPA, every architecture of fat binaries has to pass a check
+func checkMachOHardening(file *os.File) (BinaryHardening, error) {
+	var slices []*macho.File
+	fat, err := macho.NewFatFile(file)
+	switch {
+	case err == nil:
+		defer fat.Close()
+		for _, arch := range fat.Arches {
+			slices = append(slices, arch.File)
+		}
+	case errors.Is(err, macho.ErrNotFat):
+		thin, err := macho.NewFile(file)
+		if err != nil {
+			return BinaryHardening{}, err
+		}
+		defer thin.Close()
+		slices = append(slices, thin)
+	default:
+		return BinaryHardening{}, err
+	}
+
+	result := BinaryHardening{Format: BinaryFormatMachO, PIE: true, StackCanary: true, RELRO: true, NX: true}
+	for _, slice := range slices {
+		// Only executables are linked as PIE, dylibs and bundles are position independent anyway
+		if slice.Type == macho.TypeExec && slice.Flags&macho.FlagPIE == 0 {
+			result.PIE = false
+		}
+		if slice.Flags&machoAllowStackExecution != 0 {
+			result.NX = false
+		}
+
+		canary := false
+		if symbols, err := slice.ImportedSymbols(); err == nil {
+			for _, symbol := range symbols {
+				canary = canary || containsString(stackCanarySymbols, symbol)
+			}
+		}
+		result.StackCanary = result.StackCanary && canary
+	}
+	return result, nil
+}
+
+// isNativeBinary reports whether an entry is a native library of an APK or AAB or a Mach-O binary of an IPA
+func isNativeBinary(file *zip.File, ipa bool) (bool, error) {
+	if !ipa {
+		_, ok := nativeLibABI(file.Name)
+		return ok, nil
+	}
+	if !isMachOCandidate(file) {
+		return false, nil
+	}
+	isMachO, err := hasMachOMagic(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	return isMachO, nil
+}
+
+// isMachOCandidate reports whether an IPA entry could be a Mach-O binary: files without extension and dylibs
+func isMachOCandidate(file *zip.File) bool {
+	if file.FileInfo().IsDir() || !strings.HasPrefix(file.Name, "Payload/") {
+		return false
+	}
+	ext := path.Ext(path.Base(file.Name))
+	return ext == "" || ext == ".dylib"
+}
+
+// hasMachOMagic checks the first bytes of the entry
+func hasMachOMagic(file *zip.File) (bool, error) {
+	entry, err := file.Open()
+	if err != nil {
+		return false, err
+	}
+	defer entry.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(entry, magic); err != nil {
+		// Entries shorter than the magic aren't binaries
+		return false, nil
+	}
+	for _, candidate := range machoMagics {
+		if bytes.Equal(magic, candidate) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkNativeHardeningFromConfig checks the native binaries of the artifact and logs the unhardened ones,
+// false is returned when the artifact couldn't be checked
+func checkNativeHardeningFromConfig(artifactPath, workDir string, logger log.Logger) ([]BinaryHardening, bool) {
+	logger.Infof("Checking native binary hardening...")
+	binaries, err := CheckNativeHardening(artifactPath, workDir)
+	if err != nil {
+		logger.Warnf("Failed to check native binary hardening: %s", err)
+		return nil, false
+	}
+
+	unhardened := 0
+	for _, binary := range binaries {
+		if !binary.Hardened() {
+			unhardened++
+			logger.Warnf("%s: missing %s", binary.Path, strings.Join(binary.Missing(), ", "))
+		}
+	}
+	logger.Printf("%d of %d native binaries are hardened", len(binaries)-unhardened, len(binaries))
+	return binaries, true
+}
@@ -0,0 +1,163 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// elfOptions are the hardening properties of a test ELF shared library
+type elfOptions struct {
+	textRelocations bool
+	canary          bool
+	relro           bool
+	bindNow         bool
+	executableStack bool
+}
+
+// writeELF builds a minimal 64-bit ELF shared library with the program headers, dynamic entries and imported symbols
+// the hardening checks read
+func writeELF(t *testing.T, opts elfOptions) string {
+	t.Helper()
+	dynstr := []byte("\x00__android_log_print\x00")
+	if opts.canary {
+		dynstr = append(dynstr, "__stack_chk_fail\x00"...)
+	}
+	var dynsym bytes.Buffer
+	symbols := []elf.Sym64{{}, {Name: 1, Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC)}}
+	if opts.canary {
+		symbols = append(symbols, elf.Sym64{Name: uint32(len("\x00__android_log_print\x00")), Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC)})
+	}
+	for _, symbol := range symbols {
+		mustWrite(t, &dynsym, symbol)
+	}
+	var dynamic bytes.Buffer
+	if opts.textRelocations {
+		mustWrite(t, &dynamic, elf.Dyn64{Tag: int64(elf.DT_TEXTREL)})
+	}
+	if opts.bindNow {
+		mustWrite(t, &dynamic, elf.Dyn64{Tag: int64(elf.DT_FLAGS), Val: uint64(elf.DF_BIND_NOW)})
+	}
+	mustWrite(t, &dynamic, elf.Dyn64{Tag: int64(elf.DT_NULL)})
+	shstrtab := []byte("\x00.dynstr\x00.dynsym\x00.dynamic\x00.shstrtab\x00")
+
+	stackFlags := elf.PF_R | elf.PF_W
+	if opts.executableStack {
+		stackFlags |= elf.PF_X
+	}
+	progs := []elf.Prog64{{Type: uint32(elf.PT_GNU_STACK), Flags: uint32(stackFlags)}}
+	if opts.relro {
+		progs = append(progs, elf.Prog64{Type: uint32(elf.PT_GNU_RELRO), Flags: uint32(elf.PF_R)})
+	}
+
+	dataOffset := uint64(64 + 56*len(progs))
+	sections := []elf.Section64{{}}
+	var data []byte
+	for _, section := range []struct {
+		name, link    uint32
+		kind          elf.SectionType
+		content       []byte
+		entsize, info uint64
+	}{
+		{name: 1, kind: elf.SHT_STRTAB, content: dynstr},
+		{name: 9, kind: elf.SHT_DYNSYM, content: dynsym.Bytes(), link: 1, entsize: 24, info: 1},
+		{name: 17, kind: elf.SHT_DYNAMIC, content: dynamic.Bytes(), link: 1, entsize: 16},
+		{name: 26, kind: elf.SHT_STRTAB, content: shstrtab},
+	} {
+		sections = append(sections, elf.Section64{Name: section.name, Type: uint32(section.kind), Off: dataOffset + uint64(len(data)),
+			Size: uint64(len(section.content)), Link: section.link, Info: uint32(section.info), Addralign: 1, Entsize: section.entsize})
+		data = append(data, section.content...)
+	}
+
+	header := elf.Header64{Type: uint16(elf.ET_DYN), Machine: uint16(elf.EM_AARCH64), Version: uint32(elf.EV_CURRENT), Phoff: 64,
+		Shoff: dataOffset + uint64(len(data)), Ehsize: 64, Phentsize: 56, Phnum: uint16(len(progs)), Shentsize: 64,
+		Shnum: uint16(len(sections)), Shstrndx: uint16(len(sections) - 1)}
+	copy(header.Ident[:], elf.ELFMAG)
+	header.Ident[elf.EI_CLASS], header.Ident[elf.EI_DATA], header.Ident[elf.EI_VERSION] = byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)
+
+	var binaryFile bytes.Buffer
+	mustWrite(t, &binaryFile, header)
+	for _, prog := range progs {
+		mustWrite(t, &binaryFile, prog)
+	}
+	binaryFile.Write(data)
+	for _, section := range sections {
+		mustWrite(t, &binaryFile, section)
+	}
+	return binaryFile.String()
+}
+
+func mustWrite(t *testing.T, buf *bytes.Buffer, value interface{}) {
+	t.Helper()
+	if err := binary.Write(buf, binary.LittleEndian, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckNativeHardening(t *testing.T) {
+	hardened := elfOptions{canary: true, relro: true, bindNow: true}
+	entries := []zipEntry{
+		{name: "lib/arm64-v8a/libhardened.so", content: writeELF(t, hardened)},
+		{name: "lib/arm64-v8a/libpartial.so", content: writeELF(t, elfOptions{relro: true, executableStack: true})},
+		{name: "lib/armeabi-v7a/libtextrel.so", content: writeELF(t, elfOptions{textRelocations: true, canary: true, relro: true, bindNow: true})},
+		{name: "assets/lib/libignored.so", content: "not a native library of the app"},
+	}
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaries, err := CheckNativeHardening(artifactPath, t.TempDir())
+	if err != nil {
+		t.Fatalf("CheckNativeHardening() error = %s", err)
+	}
+	var got [][]string
+	for _, binary := range binaries {
+		if binary.Format != BinaryFormatELF {
+			t.Errorf("%s format = %s", binary.Path, binary.Format)
+		}
+		got = append(got, append([]string{binary.Path}, binary.Missing()...))
+	}
+	want := [][]string{
+		{"lib/arm64-v8a/libhardened.so"},
+		// RELRO without immediate binding is partial RELRO
+		{"lib/arm64-v8a/libpartial.so", HardeningStackCanary, HardeningRELRO, HardeningNX},
+		{"lib/armeabi-v7a/libtextrel.so", HardeningPIE},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckNativeHardening() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckMachOHardening(t *testing.T) {
+	var executable bytes.Buffer
+	// A non-PIE executable without load commands, it imports no stack protector symbols
+	mustWrite(t, &executable, macho.FileHeader{Magic: macho.Magic64, Cpu: macho.CpuArm64, Type: macho.TypeExec})
+	executable.Write(make([]byte, 4))
+	entries := []zipEntry{
+		{name: "Payload/Test.app/Info.plist", content: infoPlist("Test")},
+		{name: "Payload/Test.app/Test", content: executable.String()},
+		{name: "Payload/Test.app/run.sh", content: "#!/bin/sh"},
+	}
+	artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	binaries, err := CheckNativeHardening(artifactPath, t.TempDir())
+	if err != nil {
+		t.Fatalf("CheckNativeHardening() error = %s", err)
+	}
+	if len(binaries) != 1 || binaries[0].Path != "Payload/Test.app/Test" || binaries[0].Format != BinaryFormatMachO {
+		t.Fatalf("CheckNativeHardening() = %+v, want the executable", binaries)
+	}
+	if missing := binaries[0].Missing(); !reflect.DeepEqual(missing, []string{HardeningPIE, HardeningStackCanary}) {
+		t.Errorf("Missing() = %v, want PIE and stack canary", missing)
+	}
+}
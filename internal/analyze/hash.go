@@ -0,0 +1,47 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// hashEntries returns the SHA-256 of every entry, in the order of the entries.
+// Entries are hashed in parallel, at most one entry per CPU at a time, zip.File.Open is safe for concurrent use.
+func hashEntries(files []*zip.File, logger log.Logger) ([]string, error) {
+	hashes := make([]string, len(files))
+	errs := make([]error, len(files))
+
+	var totalBytes int64
+	for _, file := range files {
+		totalBytes += int64(file.UncompressedSize64)
+	}
+	hashing := startProgress("Hashing files", int64(len(files)), totalBytes, logger)
+	defer hashing.finish()
+
+	// Stop starting new entries after the first failure, the result is discarded anyway
+	var failed atomic.Bool
+	RunConcurrently(runtime.NumCPU(), len(files), func(idx int) {
+		if failed.Load() {
+			return
+		}
+		hash, err := hashZipEntry(files[idx])
+		if err != nil {
+			errs[idx] = fmt.Errorf("failed to hash %s: %w", files[idx].Name, err)
+			failed.Store(true)
+			return
+		}
+		hashes[idx] = hash
+		hashing.add(1, int64(files[idx].UncompressedSize64))
+	})
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
@@ -0,0 +1,114 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// zipEntry is a file of a test archive
+type zipEntry struct {
+	name    string
+	content string
+}
+
+// writeZip writes the entries to a zip archive in order, stored entries aren't compressed
+func writeZip(t *testing.T, entries []zipEntry, method uint16) []byte {
+	t.Helper()
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for _, entry := range entries {
+		w, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return archive.Bytes()
+}
+
+func TestHashEntries(t *testing.T) {
+	var entries []zipEntry
+	for idx := 0; idx < 100; idx++ {
+		entries = append(entries, zipEntry{name: fmt.Sprintf("assets/%03d.bin", idx), content: strings.Repeat(fmt.Sprint(idx), 1000+idx)})
+	}
+	archive := writeZip(t, entries, zip.Deflate)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes, err := hashEntries(reader.File, log.NewLogger())
+	if err != nil {
+		t.Fatalf("hashEntries() error = %s", err)
+	}
+	// The parallel hashing keeps the order of the entries
+	for idx, entry := range entries {
+		sum := sha256.Sum256([]byte(entry.content))
+		if want := hex.EncodeToString(sum[:]); hashes[idx] != want {
+			t.Errorf("hash of %s = %s, want %s", entry.name, hashes[idx], want)
+		}
+	}
+}
+
+func TestHashEntriesCorruptEntry(t *testing.T) {
+	archive := writeZip(t, []zipEntry{
+		{name: "assets/a.bin", content: strings.Repeat("a", 4096)},
+		{name: "assets/b.bin", content: strings.Repeat("b", 4096)},
+	}, zip.Store)
+	// The stored content no longer matches the CRC32 of the entry
+	archive = bytes.Replace(archive, []byte(strings.Repeat("b", 4096)), []byte(strings.Repeat("c", 4096)), 1)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = hashEntries(reader.File, log.NewLogger())
+	if err == nil || !strings.Contains(err.Error(), "failed to hash assets/b.bin") {
+		t.Errorf("hashEntries() error = %v, want the corrupt entry", err)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	icon := strings.Repeat("icon", 512)
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{
+		{name: "res/drawable-hdpi/icon.png", content: icon},
+		{name: "res/drawable-xhdpi/icon.png", content: icon},
+		{name: "res/drawable-xxhdpi/icon.png", content: icon},
+		{name: "res/drawable/other.png", content: strings.Repeat("othr", 512)},
+		{name: "assets/empty.txt", content: ""},
+		{name: "assets/empty-copy.txt", content: ""},
+	}, zip.Store), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sets, err := FindDuplicates(artifactPath, EntryScope{}, log.NewLogger())
+	if err != nil {
+		t.Fatalf("FindDuplicates() error = %s", err)
+	}
+	// Empty files don't waste anything and aren't reported, the stored copies waste their full size
+	if len(sets) != 1 {
+		t.Fatalf("FindDuplicates() = %+v, want the icons", sets)
+	}
+	want := []string{"res/drawable-hdpi/icon.png", "res/drawable-xhdpi/icon.png", "res/drawable-xxhdpi/icon.png"}
+	if strings.Join(sets[0].Paths, ",") != strings.Join(want, ",") {
+		t.Errorf("Paths = %v, want %v", sets[0].Paths, want)
+	}
+	if sets[0].SizeBytes != int64(len(icon)) || sets[0].WastedBytes != 2*int64(len(icon)) {
+		t.Errorf("SizeBytes = %d, WastedBytes = %d", sets[0].SizeBytes, sets[0].WastedBytes)
+	}
+}
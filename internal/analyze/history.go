@@ -0,0 +1,173 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+)
+
+// maxHistoryRecords caps the number of builds kept in the history file
+const maxHistoryRecords = 500
+
+// HistoryRecord holds the metrics of a single analyzed build
+type HistoryRecord struct {
+	Artifact       string    `json:"artifact"`
+	BuildNumber    string    `json:"build_number"`
+	Branch         string    `json:"branch"`
+	Commit         string    `json:"commit"`
+	PullRequest    bool      `json:"pull_request"`
+	Timestamp      time.Time `json:"timestamp"`
+	SizeBytes      int64     `json:"size_bytes"`
+	FileCount      int64     `json:"file_count"`
+	DexMethodCount int64     `json:"dex_method_count"`
+	Violations     []string  `json:"violations,omitempty"`
+	// Permissions is nil for builds recorded without track_permissions
+	Permissions *[]string `json:"permissions,omitempty"`
+	// AttackSurface is nil for builds recorded without attack_surface_report, see AttackSurface.Entries
+	AttackSurface *[]string `json:"attack_surface,omitempty"`
+	// Libraries is nil for builds recorded without sbom_diff, see LibraryEntries
+	Libraries *[]string `json:"libraries,omitempty"`
+}
+
+// History holds the records of previously analyzed builds, oldest first
+type History struct {
+	Records []HistoryRecord `json:"records"`
+}
+
+// BaselineComparison holds the differences between the current build and the baseline build
+type BaselineComparison struct {
+	Baseline            HistoryRecord
+	SizeDeltaBytes      int64
+	FileCountDelta      int64
+	DexMethodCountDelta int64
+	// Permissions is set when the permissions of both builds are known
+	Permissions *PermissionDiff
+	// AttackSurface is set when the attack surface of both builds is known
+	AttackSurface *AttackSurfaceDiff
+	// Libraries is set when the library inventory of both builds is known
+	Libraries *LibraryDiff
+}
+
+// LoadHistory reads the history file, a missing file results in an empty history
+func LoadHistory(historyPath string) (History, error) {
+	data, err := os.ReadFile(historyPath)
+	if os.IsNotExist(err) {
+		return History{}, nil
+	}
+	if err != nil {
+		return History{}, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	var history History
+	if err := json.Unmarshal(data, &history); err != nil {
+		return History{}, fmt.Errorf("failed to parse history file: %w", err)
+	}
+	return history, nil
+}
+
+// SaveHistory writes the history file, creating its directory if needed
+func SaveHistory(historyPath string, history History) error {
+	if err := os.MkdirAll(filepath.Dir(historyPath), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	if err := os.WriteFile(historyPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	return nil
+}
+
+// Append adds a record to the history, dropping the oldest records above maxHistoryRecords
+func (h *History) Append(record HistoryRecord) {
+	h.Records = append(h.Records, record)
+	if len(h.Records) > maxHistoryRecords {
+		h.Records = h.Records[len(h.Records)-maxHistoryRecords:]
+	}
+}
+
+// Baseline returns the most recent non pull request record of the artifact on the given branch
+func (h History) Baseline(branch, artifact string) (HistoryRecord, bool) {
+	for idx := len(h.Records) - 1; idx >= 0; idx-- {
+		record := h.Records[idx]
+		if record.Branch == branch && record.Artifact == artifact && !record.PullRequest {
+			return record, true
+		}
+	}
+	return HistoryRecord{}, false
+}
+
+// Smallest returns the non pull request record of the artifact on the given branch with the smallest recorded size
+func (h History) Smallest(branch, artifact string) (HistoryRecord, bool) {
+	var smallest HistoryRecord
+	found := false
+	for _, record := range h.Records {
+		if record.Branch != branch || record.Artifact != artifact || record.PullRequest || record.SizeBytes <= 0 {
+			continue
+		}
+		if !found || record.SizeBytes < smallest.SizeBytes {
+			smallest = record
+			found = true
+		}
+	}
+	return smallest, found
+}
+
+// ConsecutiveViolations counts the most recent records of the artifact on the given branch that violated the check without interruption
+func (h History) ConsecutiveViolations(branch, artifact, check string) int {
+	count := 0
+	for idx := len(h.Records) - 1; idx >= 0; idx-- {
+		record := h.Records[idx]
+		if record.Branch != branch || record.Artifact != artifact {
+			continue
+		}
+		if !containsCheck(record.Violations, check) {
+			break
+		}
+		count++
+	}
+	return count
+}
+
+func containsCheck(checks []string, check string) bool {
+	for _, item := range checks {
+		if item == check {
+			return true
+		}
+	}
+	return false
+}
+
+// NewHistoryRecord creates the history record of an artifact of the current build
+func NewHistoryRecord(artifact string, metrics BundleMetrics, envRepo env.Repository) HistoryRecord {
+	pullRequest := envRepo.Get("BITRISE_PULL_REQUEST")
+	return HistoryRecord{
+		Artifact:       artifact,
+		BuildNumber:    envRepo.Get("BITRISE_BUILD_NUMBER"),
+		Branch:         envRepo.Get("BITRISE_GIT_BRANCH"),
+		Commit:         envRepo.Get("BITRISE_GIT_COMMIT"),
+		PullRequest:    pullRequest != "" && pullRequest != "false",
+		Timestamp:      time.Now().UTC(),
+		SizeBytes:      metrics.SizeBytes,
+		FileCount:      metrics.FileCount,
+		DexMethodCount: metrics.DexMethodCount,
+	}
+}
+
+// compareWithBaseline calculates the differences between the current metrics and the baseline record
+func compareWithBaseline(metrics BundleMetrics, baseline HistoryRecord) BaselineComparison {
+	return BaselineComparison{
+		Baseline:            baseline,
+		SizeDeltaBytes:      metrics.SizeBytes - baseline.SizeBytes,
+		FileCountDelta:      metrics.FileCount - baseline.FileCount,
+		DexMethodCountDelta: metrics.DexMethodCount - baseline.DexMethodCount,
+	}
+}
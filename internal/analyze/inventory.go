@@ -0,0 +1,244 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ArchiveEntry describes a single file stored in the analyzed artifact
+type ArchiveEntry struct {
+	Path             string
+	CompressedSize   int64
+	UncompressedSize int64
+	CRC32            uint32
+}
+
+// Inventory holds the file listing of the analyzed artifact
+type Inventory struct {
+	Entries []ArchiveEntry
+}
+
+// readInventory lists the files of the artifact (IPA, APK and AAB files are all zip archives)
+func readInventory(artifactPath string) (Inventory, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return Inventory{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var inventory Inventory
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		inventory.Entries = append(inventory.Entries, ArchiveEntry{
+			Path:             file.Name,
+			CompressedSize:   int64(file.CompressedSize64),
+			UncompressedSize: int64(file.UncompressedSize64),
+			CRC32:            file.CRC32,
+		})
+	}
+
+	return inventory, nil
+}
+
+// NativeLibSizesByABI sums the compressed size of native libraries per ABI
+func (i Inventory) NativeLibSizesByABI() map[string]int64 {
+	sizes := map[string]int64{}
+	for _, entry := range i.Entries {
+		if abi, ok := nativeLibABI(entry.Path); ok {
+			sizes[abi] += entry.CompressedSize
+		}
+	}
+	return sizes
+}
+
+// NativeLibUncompressedSizesByABI sums the uncompressed (extracted) size of native libraries per ABI
+func (i Inventory) NativeLibUncompressedSizesByABI() map[string]int64 {
+	sizes := map[string]int64{}
+	for _, entry := range i.Entries {
+		if abi, ok := nativeLibABI(entry.Path); ok {
+			sizes[abi] += entry.UncompressedSize
+		}
+	}
+	return sizes
+}
+
+// EntriesLargerThan returns the entries whose uncompressed size exceeds the limit, largest first
+func (i Inventory) EntriesLargerThan(limitBytes int64) []ArchiveEntry {
+	var entries []ArchiveEntry
+	for _, entry := range i.Entries {
+		if entry.UncompressedSize > limitBytes {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].UncompressedSize > entries[b].UncompressedSize
+	})
+	return entries
+}
+
+// LargestEntries returns the n entries taking the most space in the artifact (stored size), largest first
+func (i Inventory) LargestEntries(n int) []ArchiveEntry {
+	entries := append([]ArchiveEntry{}, i.Entries...)
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].CompressedSize > entries[b].CompressedSize
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// DirectorySize sums the entries below a top-level directory of the artifact
+type DirectorySize struct {
+	Path      string `json:"path"`
+	SizeBytes int64  `json:"size_bytes"`
+	FileCount int64  `json:"file_count"`
+}
+
+// rootDirectory is the path of the files stored at the root of the artifact in TopDirectories
+const rootDirectory = "/"
+
+// TopDirectories returns the n top-level directories taking the most space in the artifact (stored size), largest
+// first. The directories of an IPA are the ones of its app bundle (Payload/App.app/Frameworks), the files at the root
+// of the artifact or the app bundle are summed as the artifact or bundle root.
+func (i Inventory) TopDirectories(n int) []DirectorySize {
+	sizes := map[string]*DirectorySize{}
+	var directories []*DirectorySize
+	for _, entry := range i.Entries {
+		directory := topDirectory(entry.Path)
+		size, ok := sizes[directory]
+		if !ok {
+			size = &DirectorySize{Path: directory}
+			sizes[directory] = size
+			directories = append(directories, size)
+		}
+		size.SizeBytes += entry.CompressedSize
+		size.FileCount++
+	}
+	sort.SliceStable(directories, func(a, b int) bool {
+		return directories[a].SizeBytes > directories[b].SizeBytes
+	})
+
+	top := []DirectorySize{}
+	for _, directory := range directories {
+		if len(top) == n {
+			break
+		}
+		top = append(top, *directory)
+	}
+	return top
+}
+
+// topDirectory returns the top-level directory of an entry, below Payload/<App>.app/ for IPA entries
+func topDirectory(entryPath string) string {
+	parts := strings.Split(entryPath, "/")
+	prefix := 0
+	if len(parts) > 2 && parts[0] == "Payload" && strings.HasSuffix(parts[1], ".app") {
+		prefix = 2
+	}
+	if len(parts) <= prefix+1 {
+		if prefix == 0 {
+			return rootDirectory
+		}
+		return strings.Join(parts[:prefix], "/")
+	}
+	return strings.Join(parts[:prefix+1], "/")
+}
+
+// DownloadSize estimates the bytes users download: the compressed size of the entries, without the archive overhead
+func (i Inventory) DownloadSize() int64 {
+	var size int64
+	for _, entry := range i.Entries {
+		size += entry.CompressedSize
+	}
+	return size
+}
+
+// screenDensities are the density qualifiers of Android resource directories, nodpi and anydpi resources are used on
+// every device
+var screenDensities = map[string]bool{"ldpi": true, "mdpi": true, "tvdpi": true, "hdpi": true, "xhdpi": true, "xxhdpi": true, "xxxhdpi": true}
+
+// DeviceDownloadSize estimates the bytes a device with the ABI and screen density downloads of an APK or AAB: the
+// native libraries of other ABIs and the resources of other densities are left out. Only the base module of an AAB
+// is counted, its bundle metadata isn't delivered. An empty ABI keeps every native library.
+func (i Inventory) DeviceDownloadSize(abi, density string) int64 {
+	bundle := false
+	for _, entry := range i.Entries {
+		if entry.Path == "BundleConfig.pb" {
+			bundle = true
+		}
+	}
+
+	var size int64
+	for _, entry := range i.Entries {
+		entryPath := entry.Path
+		if bundle {
+			modulePath, ok := strings.CutPrefix(entryPath, "base/")
+			if !ok {
+				continue
+			}
+			entryPath = modulePath
+		}
+		if libPath, ok := strings.CutPrefix(entryPath, "lib/"); ok && abi != "" {
+			if entryABI, _, _ := strings.Cut(libPath, "/"); entryABI != abi {
+				continue
+			}
+		}
+		if resPath, ok := strings.CutPrefix(entryPath, "res/"); ok && otherDensity(resPath, density) {
+			continue
+		}
+		size += entry.CompressedSize
+	}
+	return size
+}
+
+// otherDensity reports whether the resource directory of the path is qualified with a density other than the given one
+func otherDensity(resPath, density string) bool {
+	dir, _, _ := strings.Cut(resPath, "/")
+	for _, qualifier := range strings.Split(dir, "-")[1:] {
+		if screenDensities[qualifier] && qualifier != density {
+			return true
+		}
+	}
+	return false
+}
+
+// InstallSize estimates the bytes the app takes up on the device: the uncompressed size of the entries
+func (i Inventory) InstallSize() int64 {
+	var size int64
+	for _, entry := range i.Entries {
+		size += entry.UncompressedSize
+	}
+	return size
+}
+
+// nativeLibABI returns the ABI of a native library entry.
+// APKs store libraries as lib/<abi>/<name>.so, AABs as <module>/lib/<abi>/<name>.so.
+func nativeLibABI(entryPath string) (string, bool) {
+	if !strings.HasSuffix(entryPath, ".so") {
+		return "", false
+	}
+
+	parts := strings.Split(entryPath, "/")
+	for idx := 0; idx+2 < len(parts); idx++ {
+		if parts[idx] == "lib" && idx <= 1 {
+			return parts[idx+1], true
+		}
+	}
+	return "", false
+}
+
+// SortedKeys returns the keys of a size map in alphabetical order
+func SortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,44 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopDirectories(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []ArchiveEntry
+		n       int
+		want    []DirectorySize
+	}{
+		{name: "APK", n: 2, entries: []ArchiveEntry{
+			{Path: "classes.dex", CompressedSize: 400},
+			{Path: "lib/arm64-v8a/libapp.so", CompressedSize: 900},
+			{Path: "res/drawable/icon.png", CompressedSize: 100},
+			{Path: "lib/armeabi-v7a/libapp.so", CompressedSize: 700},
+			{Path: "resources.arsc", CompressedSize: 300},
+		}, want: []DirectorySize{{Path: "lib", SizeBytes: 1600, FileCount: 2}, {Path: "/", SizeBytes: 700, FileCount: 2}}},
+		{name: "IPA directories are below the app bundle", n: 10, entries: []ArchiveEntry{
+			{Path: "Payload/App.app/App", CompressedSize: 5000},
+			{Path: "Payload/App.app/Frameworks/A.framework/A", CompressedSize: 3000},
+			{Path: "Payload/App.app/Frameworks/B.framework/B", CompressedSize: 2500},
+			{Path: "Payload/App.app/Assets.car", CompressedSize: 1000},
+			{Path: "Payload/App.app/PlugIns/Widget.appex/Widget", CompressedSize: 800},
+			{Path: "iTunesMetadata.plist", CompressedSize: 10},
+		}, want: []DirectorySize{
+			{Path: "Payload/App.app", SizeBytes: 6000, FileCount: 2},
+			{Path: "Payload/App.app/Frameworks", SizeBytes: 5500, FileCount: 2},
+			{Path: "Payload/App.app/PlugIns", SizeBytes: 800, FileCount: 1},
+			{Path: "/", SizeBytes: 10, FileCount: 1},
+		}},
+		{name: "empty inventory", n: 10, want: []DirectorySize{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Inventory{Entries: tt.entries}).TopDirectories(tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TopDirectories() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// languageRuntime describes a language runtime or framework payload bundled into apps, matched by the entries of an
+// IPA or the DEX classes of an Android artifact
+type languageRuntime struct {
+	name string
+	// entryPattern matches the entries of the runtime in an IPA
+	entryPattern *regexp.Regexp
+	// classPrefixes are the type descriptor prefixes of the runtime classes, classes matching excludedPrefixes belong
+	// to another runtime
+	classPrefixes    []string
+	excludedPrefixes []string
+	// versionSDKs are the names of the DetectSDKs entries holding the version of the runtime
+	versionSDKs []string
+	// droppedAt is the minimum OS version or SDK level shipping the runtime with the OS, empty if it's always bundled
+	droppedAt string
+	guidance  string
+}
+
+// languageRuntimes are the runtimes reported by language_overhead_report
+var languageRuntimes = []languageRuntime{
+	{
+		name:         "Swift runtime",
+		entryPattern: regexp.MustCompile(`^Payload/[^/]+\.app/Frameworks/libswift[^_/][^/]*\.dylib$`),
+		droppedAt:    "12.2",
+		guidance:     "iOS 12.2 and later ship the Swift runtime with the OS, the app stops embedding it",
+	},
+	{
+		name:         "Swift Concurrency back-deployment",
+		entryPattern: regexp.MustCompile(`^Payload/[^/]+\.app/Frameworks/libswift_Concurrency\.dylib$`),
+		droppedAt:    "15.0",
+		guidance:     "iOS 15 and later ship Swift Concurrency with the OS",
+	},
+	{
+		name:             "Kotlin standard library",
+		classPrefixes:    []string{"Lkotlin/"},
+		excludedPrefixes: []string{"Lkotlin/reflect/jvm/"},
+		versionSDKs:      []string{"org.jetbrains.kotlin:kotlin-stdlib"},
+		guidance:         "Bundled on every Android version, R8 removes its unused classes when minification is enabled",
+	},
+	{
+		name:          "Kotlin reflection",
+		classPrefixes: []string{"Lkotlin/reflect/jvm/"},
+		versionSDKs:   []string{"org.jetbrains.kotlin:kotlin-reflect"},
+		guidance:      "Rarely needed at runtime, find the dependency pulling in kotlin-reflect and prefer generated code",
+	},
+	{
+		name:          "Kotlin coroutines",
+		classPrefixes: []string{"Lkotlinx/coroutines/"},
+		versionSDKs:   []string{"kotlinx_coroutines:core"},
+		guidance:      "Bundled on every Android version",
+	},
+	{
+		name:          "Jetpack Compose",
+		classPrefixes: []string{"Landroidx/compose/"},
+		versionSDKs:   []string{"androidx.compose.runtime:runtime"},
+		guidance:      "Bundled on every Android version, keep the Compose BOM up to date as releases shrink the runtime",
+	},
+	{
+		name:          "Java 8+ API desugaring",
+		classPrefixes: []string{"Lj$/"},
+		droppedAt:     "26",
+		guidance:      "Android 8.0 (API 26) ships java.time and most desugared APIs, coreLibraryDesugaring can usually be disabled",
+	},
+	{
+		name:          "MultiDex support library",
+		classPrefixes: []string{"Landroidx/multidex/", "Landroid/support/multidex/"},
+		droppedAt:     "21",
+		guidance:      "Android 5.0 (API 21) loads multiple DEX files natively, the MultiDex library isn't needed",
+	},
+}
+
+// LanguageOverhead is the size of the language runtimes and framework payloads bundled into the artifact
+type LanguageOverhead struct {
+	Platform string
+	// MinimumOS is the MinimumOSVersion of an IPA or the minSdkVersion of an Android artifact, empty if unknown
+	MinimumOS string
+	Runtimes  []RuntimeOverhead
+}
+
+// RuntimeOverhead is a language runtime found in the artifact
+type RuntimeOverhead struct {
+	Name    string
+	Version string
+	// Files is the number of IPA entries of the runtime, Classes the number of DEX classes of an Android runtime
+	Files   int
+	Classes int
+	// SizeBytes is the compressed size of the runtime, the DEX size is attributed by the share of classes
+	SizeBytes int64
+	// Droppable is set when a higher minimum OS or SDK than the artifact's would drop the runtime
+	Droppable bool
+	// DroppedAt is the minimum OS version or SDK level shipping the runtime with the OS
+	DroppedAt string
+	Guidance  string
+}
+
+// ReadLanguageOverhead measures the language runtimes of the artifact within the scope. Android runtimes are measured
+// by the share of their classes in each DEX file, so their size is an estimate.
+func ReadLanguageOverhead(artifactPath string, scope EntryScope) (LanguageOverhead, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return LanguageOverhead{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	overhead := LanguageOverhead{Platform: "android"}
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		overhead.Platform = "ios"
+		if _, info, err := readAppInfoPlist(&reader.Reader); err == nil {
+			overhead.MinimumOS = plistString(info, "MinimumOSVersion")
+		}
+	} else if manifest, err := readAndroidManifest(&reader.Reader); err == nil {
+		for _, sdk := range manifest.ChildrenNamed("uses-sdk") {
+			overhead.MinimumOS = sdk.Attrs["minSdkVersion"]
+		}
+	}
+
+	found := map[string]*RuntimeOverhead{}
+	measured := func(runtime languageRuntime) *RuntimeOverhead {
+		if found[runtime.name] == nil {
+			found[runtime.name] = &RuntimeOverhead{Name: runtime.name, DroppedAt: runtime.droppedAt, Guidance: runtime.guidance}
+		}
+		return found[runtime.name]
+	}
+	for _, file := range reader.File {
+		if !scope.Contains(file.Name) {
+			continue
+		}
+		if overhead.Platform == "ios" {
+			for _, runtime := range languageRuntimes {
+				if runtime.entryPattern != nil && runtime.entryPattern.MatchString(file.Name) {
+					current := measured(runtime)
+					current.Files++
+					current.SizeBytes += int64(file.CompressedSize64)
+				}
+			}
+			continue
+		}
+		if !isDexEntry(file.Name) {
+			continue
+		}
+
+		data, err := readZipEntry(file)
+		if err != nil {
+			return LanguageOverhead{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		descriptors, err := dexClassDescriptors(data)
+		if err != nil {
+			return LanguageOverhead{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		classes := map[string]int{}
+		for _, descriptor := range descriptors {
+			if runtime, ok := runtimeOfClass(descriptor); ok {
+				classes[runtime.name]++
+			}
+		}
+		for _, runtime := range languageRuntimes {
+			if classes[runtime.name] == 0 {
+				continue
+			}
+			current := measured(runtime)
+			current.Classes += classes[runtime.name]
+			current.SizeBytes += int64(file.CompressedSize64) * int64(classes[runtime.name]) / int64(len(descriptors))
+		}
+	}
+
+	var versions map[string]string
+	if overhead.Platform == "android" && len(found) > 0 {
+		versions = map[string]string{}
+		if sdks, err := DetectSDKs(artifactPath); err == nil {
+			for _, sdk := range sdks {
+				versions[sdk.Name] = sdk.Version
+			}
+		}
+	}
+	for _, runtime := range languageRuntimes {
+		current := found[runtime.name]
+		if current == nil {
+			continue
+		}
+		for _, name := range runtime.versionSDKs {
+			if current.Version == "" {
+				current.Version = versions[name]
+			}
+		}
+		current.Droppable = runtime.droppedAt != "" && overhead.MinimumOS != "" && !strings.HasPrefix(overhead.MinimumOS, "@") &&
+			compareVersions(overhead.MinimumOS, runtime.droppedAt) < 0
+		overhead.Runtimes = append(overhead.Runtimes, *current)
+	}
+	sort.SliceStable(overhead.Runtimes, func(i, j int) bool {
+		return overhead.Runtimes[i].SizeBytes > overhead.Runtimes[j].SizeBytes
+	})
+	return overhead, nil
+}
+
+// runtimeOfClass returns the Android runtime the class belongs to
+func runtimeOfClass(descriptor string) (languageRuntime, bool) {
+	for _, runtime := range languageRuntimes {
+		if matchesClassPrefix(runtime.classPrefixes, descriptor) && !matchesClassPrefix(runtime.excludedPrefixes, descriptor) {
+			return runtime, true
+		}
+	}
+	return languageRuntime{}, false
+}
+
+// matchesClassPrefix reports whether the type descriptor starts with any of the prefixes
+func matchesClassPrefix(prefixes []string, descriptor string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(descriptor, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MinimumOSName names the minimum OS setting of the platform
+func (o LanguageOverhead) MinimumOSName() string {
+	if o.Platform == "ios" {
+		return "the minimum iOS version"
+	}
+	return "minSdk"
+}
+
+// TotalBytes sums the size of the runtimes
+func (o LanguageOverhead) TotalBytes() int64 {
+	var total int64
+	for _, runtime := range o.Runtimes {
+		total += runtime.SizeBytes
+	}
+	return total
+}
+
+// readLanguageOverheadFromConfig measures the language runtimes for language_overhead_report and logs them
+func readLanguageOverheadFromConfig(cfg config.Config, artifactPath string, scope EntryScope, logger log.Logger) (LanguageOverhead, bool) {
+	logger.Infof("Measuring language runtime overhead...")
+	overhead, err := ReadLanguageOverhead(artifactPath, scope)
+	if err != nil {
+		logger.Warnf("Failed to measure language runtime overhead: %s", err)
+		return LanguageOverhead{}, false
+	}
+
+	if len(overhead.Runtimes) == 0 {
+		logger.Donef("No bundled language runtime found")
+	}
+	for _, runtime := range overhead.Runtimes {
+		name := runtime.Name
+		if runtime.Version != "" {
+			name += " " + runtime.Version
+		}
+		logger.Printf("%s: %s", name, cfg.Units().Format(runtime.SizeBytes))
+		if runtime.Droppable {
+			logger.Warnf("Raising %s from %s to %s drops the %s: %s", overhead.MinimumOSName(), overhead.MinimumOS, runtime.DroppedAt, runtime.Name, runtime.Guidance)
+		}
+	}
+	return overhead, true
+}
@@ -0,0 +1,116 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadLanguageOverheadAndroid(t *testing.T) {
+	manifest := xmlNode{name: "manifest", children: []xmlNode{
+		{name: "uses-sdk", attrs: [][2]string{{"minSdkVersion", "21"}}},
+	}}
+	dex := string(writeDex([]string{"Lkotlin/Unit;", "Lkotlin/collections/CollectionsKt;", "Lkotlin/reflect/jvm/KTypesJvm;", "Lj$/time/Instant;", "Lcom/example/MainActivity;"}))
+	artifactPath := filepath.Join(t.TempDir(), "app.aab")
+	entries := []zipEntry{
+		{name: aabManifestPath, content: string(manifest.protoXML())},
+		{name: "base/dex/classes.dex", content: dex},
+		{name: "base/root/META-INF/org.jetbrains.kotlin_kotlin-stdlib.version", content: "1.9.22"},
+	}
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Store), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overhead, err := ReadLanguageOverhead(artifactPath, EntryScope{})
+	if err != nil {
+		t.Fatalf("ReadLanguageOverhead() error = %s", err)
+	}
+	if overhead.Platform != "android" || overhead.MinimumOS != "21" || overhead.MinimumOSName() != "minSdk" {
+		t.Errorf("ReadLanguageOverhead() = %s with %s %s", overhead.Platform, overhead.MinimumOSName(), overhead.MinimumOS)
+	}
+
+	// The stored DEX size is attributed by the share of classes, the largest runtime comes first
+	size := int64(len(dex))
+	want := []RuntimeOverhead{
+		{Name: "Kotlin standard library", Version: "1.9.22", Classes: 2, SizeBytes: size * 2 / 5},
+		{Name: "Kotlin reflection", Classes: 1, SizeBytes: size / 5},
+		{Name: "Java 8+ API desugaring", Classes: 1, SizeBytes: size / 5, Droppable: true, DroppedAt: "26"},
+	}
+	if len(overhead.Runtimes) != len(want) {
+		t.Fatalf("Runtimes = %+v, want %d runtimes", overhead.Runtimes, len(want))
+	}
+	for idx, runtime := range overhead.Runtimes {
+		runtime.Guidance = ""
+		if runtime != want[idx] {
+			t.Errorf("Runtimes[%d] = %+v, want %+v", idx, runtime, want[idx])
+		}
+	}
+	if overhead.TotalBytes() != size*2/5+2*(size/5) {
+		t.Errorf("TotalBytes() = %d", overhead.TotalBytes())
+	}
+
+	// Runtimes outside of the scope aren't measured
+	overhead, err = ReadLanguageOverhead(artifactPath, EntryScope{Exclude: []string{"base/dex/**"}})
+	if err != nil || len(overhead.Runtimes) != 0 {
+		t.Errorf("ReadLanguageOverhead() without the DEX files = %+v, %v", overhead.Runtimes, err)
+	}
+}
+
+func TestReadLanguageOverheadIOS(t *testing.T) {
+	swiftCore := strings.Repeat("core", 256)
+	swiftFoundation := strings.Repeat("foundation", 64)
+	concurrency := strings.Repeat("async", 32)
+
+	tests := []struct {
+		name          string
+		minimumOS     string
+		wantDroppable map[string]bool
+	}{
+		{name: "both runtimes are droppable", minimumOS: "12.0", wantDroppable: map[string]bool{"Swift runtime": true, "Swift Concurrency back-deployment": true}},
+		{name: "the OS ships the Swift runtime", minimumOS: "13.0", wantDroppable: map[string]bool{"Swift Concurrency back-deployment": true}},
+		{name: "the OS ships both runtimes", minimumOS: "15.0", wantDroppable: map[string]bool{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>CFBundleExecutable</key><string>App</string><key>MinimumOSVersion</key><string>%s</string></dict></plist>`, tt.minimumOS)
+			artifactPath := filepath.Join(t.TempDir(), "App.ipa")
+			if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{
+				{name: "Payload/App.app/Info.plist", content: info},
+				{name: "Payload/App.app/App", content: "not a Mach-O"},
+				{name: "Payload/App.app/Frameworks/libswiftCore.dylib", content: swiftCore},
+				{name: "Payload/App.app/Frameworks/libswiftFoundation.dylib", content: swiftFoundation},
+				{name: "Payload/App.app/Frameworks/libswift_Concurrency.dylib", content: concurrency},
+				{name: "Payload/App.app/Frameworks/Alamofire.framework/Alamofire", content: "not a runtime"},
+			}, zip.Store), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			overhead, err := ReadLanguageOverhead(artifactPath, EntryScope{})
+			if err != nil {
+				t.Fatalf("ReadLanguageOverhead() error = %s", err)
+			}
+			if overhead.Platform != "ios" || overhead.MinimumOS != tt.minimumOS || overhead.MinimumOSName() != "the minimum iOS version" {
+				t.Errorf("ReadLanguageOverhead() = %s with %s %s", overhead.Platform, overhead.MinimumOSName(), overhead.MinimumOS)
+			}
+			if len(overhead.Runtimes) != 2 {
+				t.Fatalf("Runtimes = %+v, want the Swift runtime and Swift Concurrency", overhead.Runtimes)
+			}
+			swift, back := overhead.Runtimes[0], overhead.Runtimes[1]
+			if swift.Name != "Swift runtime" || swift.Files != 2 || swift.SizeBytes != int64(len(swiftCore)+len(swiftFoundation)) {
+				t.Errorf("Runtimes[0] = %+v, want the 2 Swift runtime libraries", swift)
+			}
+			if back.Name != "Swift Concurrency back-deployment" || back.Files != 1 || back.SizeBytes != int64(len(concurrency)) {
+				t.Errorf("Runtimes[1] = %+v, want libswift_Concurrency.dylib", back)
+			}
+			for _, runtime := range overhead.Runtimes {
+				if runtime.Droppable != tt.wantDroppable[runtime.Name] {
+					t.Errorf("%s Droppable = %t, want %t", runtime.Name, runtime.Droppable, tt.wantDroppable[runtime.Name])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+)
+
+// reportSize is a byte count of the JSON report. Reports of artifacts above 4 GB may write it in floating point
+// notation (e.g. 5.36870912e+09), which doesn't decode into an int64.
+type reportSize int64
+
+// UnmarshalJSON decodes integers and whole numbers in floating point notation
+func (s *reportSize) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	if value, err := number.Int64(); err == nil && value >= 0 {
+		*s = reportSize(value)
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(number.String(), 64)
+	if err != nil || value < 0 || value >= math.MaxInt64 || value != math.Trunc(value) {
+		return fmt.Errorf("invalid size: %s", number)
+	}
+	*s = reportSize(value)
+	return nil
+}
+
+// correctTruncatedSize returns the size of the artifact file when the reported size is the file size truncated to 32 bits,
+// tools without zip64 support report artifacts above 4 GB that way
+func correctTruncatedSize(reportedBytes int64, artifactPath string) (int64, bool) {
+	info, err := os.Stat(artifactPath)
+	if err != nil || reportedBytes <= 0 || info.Size() <= math.MaxUint32 || reportedBytes >= info.Size() {
+		return 0, false
+	}
+	if (info.Size()-reportedBytes)%(math.MaxUint32+1) != 0 {
+		return 0, false
+	}
+	return info.Size(), true
+}
@@ -0,0 +1,113 @@
+package analyze
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// fiveGB is above the 32-bit limit of zip archives without zip64
+const fiveGB = 5 * 1024 * 1024 * 1024
+
+func TestReportSizeUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    int64
+		wantErr bool
+	}{
+		{name: "integer", json: "52428800", want: 52428800},
+		{name: "integer above 32 bits", json: "5368709120", want: fiveGB},
+		{name: "float notation", json: "5.36870912e+09", want: fiveGB},
+		{name: "float with zero fraction", json: "5368709120.0", want: fiveGB},
+		{name: "fraction", json: "1.5", wantErr: true},
+		{name: "negative integer", json: "-1", wantErr: true},
+		{name: "negative float", json: "-5.36870912e+09", wantErr: true},
+		{name: "above int64", json: "9.3e+18", wantErr: true},
+		{name: "not a number", json: `"5 GB"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var size reportSize
+			err := json.Unmarshal([]byte(tt.json), &size)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON(%s) error = %v, wantErr %t", tt.json, err, tt.wantErr)
+			}
+			if !tt.wantErr && int64(size) != tt.want {
+				t.Errorf("UnmarshalJSON(%s) = %d, want %d", tt.json, size, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONReportLargeSizes(t *testing.T) {
+	tests := []struct {
+		name        string
+		report      string
+		wantSize    int64
+		wantSavings int64
+	}{
+		{name: "integers", report: `{"artifact_info": {"size": 5368709120}, "potential_savings": 4294967296}`, wantSize: fiveGB, wantSavings: 4294967296},
+		{name: "float notation", report: `{"artifact_info": {"size": 5.36870912e+09}, "potential_savings": 4.294967296e+09}`, wantSize: fiveGB, wantSavings: 4294967296},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jsonPath := filepath.Join(t.TempDir(), "bundle-analysis-app.json")
+			if err := os.WriteFile(jsonPath, []byte(tt.report), 0644); err != nil {
+				t.Fatal(err)
+			}
+			metrics, err := ParseJSONReport(jsonPath, log.NewLogger())
+			if err != nil {
+				t.Fatalf("ParseJSONReport() error = %s", err)
+			}
+			if metrics.SizeBytes != tt.wantSize || metrics.PotentialSavingsBytes != tt.wantSavings {
+				t.Errorf("ParseJSONReport() = %d, %d, want %d, %d", metrics.SizeBytes, metrics.PotentialSavingsBytes, tt.wantSize, tt.wantSavings)
+			}
+			if metrics.SizeMB != "5120.00" {
+				t.Errorf("SizeMB = %s, want 5120.00", metrics.SizeMB)
+			}
+		})
+	}
+}
+
+func TestCorrectTruncatedSize(t *testing.T) {
+	// Sparse files, the archives aren't read
+	dir := t.TempDir()
+	largePath := filepath.Join(dir, "large.ipa")
+	if err := os.WriteFile(largePath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(largePath, fiveGB); err != nil {
+		t.Skipf("failed to create a sparse file: %s", err)
+	}
+	smallPath := filepath.Join(dir, "small.ipa")
+	if err := os.WriteFile(smallPath, make([]byte, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		artifactPath  string
+		reportedBytes int64
+		wantBytes     int64
+		wantTruncated bool
+	}{
+		{name: "truncated to 32 bits", artifactPath: largePath, reportedBytes: fiveGB - 4294967296, wantBytes: fiveGB, wantTruncated: true},
+		{name: "correct size", artifactPath: largePath, reportedBytes: fiveGB},
+		{name: "smaller without 32-bit overflow", artifactPath: largePath, reportedBytes: fiveGB - 1000},
+		{name: "artifact below 4 GB", artifactPath: smallPath, reportedBytes: 512},
+		{name: "no reported size", artifactPath: largePath},
+		{name: "missing artifact", artifactPath: filepath.Join(dir, "missing.ipa"), reportedBytes: 1073741824},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBytes, gotTruncated := correctTruncatedSize(tt.reportedBytes, tt.artifactPath)
+			if gotBytes != tt.wantBytes || gotTruncated != tt.wantTruncated {
+				t.Errorf("correctTruncatedSize(%d) = %d, %t, want %d, %t", tt.reportedBytes, gotBytes, gotTruncated, tt.wantBytes, tt.wantTruncated)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// maxLicenseEntrySize is the largest entry read for license texts
+const maxLicenseEntrySize = 10 * 1024 * 1024
+
+// unknownLicense is reported for license texts which don't match any known license
+const unknownLicense = "Unknown"
+
+// licenseFilePattern matches license files, optionally suffixed with the component name or an extension (LICENSE-okhttp.txt)
+var licenseFilePattern = regexp.MustCompile(`(?i)^(?:LICENSE|LICENCE|COPYING)(?:[._-](.*))?$`)
+
+// licenseMatchers identify licenses by characteristic phrases, the first match wins. Licenses whose texts mention
+// other licenses come first: the Mozilla and Eclipse licenses name the GNU licenses as secondary licenses.
+var licenseMatchers = []struct {
+	id      string
+	phrases []string
+}{
+	{id: "MPL-2.0", phrases: []string{"mozilla public license"}},
+	{id: "EPL-2.0", phrases: []string{"eclipse public license", "2.0"}},
+	{id: "EPL-1.0", phrases: []string{"eclipse public license"}},
+	{id: "SSPL-1.0", phrases: []string{"server side public license"}},
+	{id: "AGPL-3.0", phrases: []string{"gnu affero general public license"}},
+	{id: "LGPL-2.1", phrases: []string{"gnu lesser general public license", "version 2.1"}},
+	{id: "LGPL-3.0", phrases: []string{"gnu lesser general public license"}},
+	{id: "LGPL-2.0", phrases: []string{"gnu library general public license"}},
+	{id: "GPL-3.0", phrases: []string{"gnu general public license", "version 3"}},
+	{id: "GPL-2.0", phrases: []string{"gnu general public license"}},
+	{id: "Apache-2.0", phrases: []string{"apache license", "2.0"}},
+	{id: "Apache-2.0", phrases: []string{"apache software license", "2.0"}},
+	{id: "Apache-2.0", phrases: []string{"apache.org/licenses/license-2.0"}},
+	{id: "Apache-2.0", phrases: []string{"apache-2.0"}},
+	{id: "Apache-2.0", phrases: []string{"apache 2.0"}},
+	{id: "BSD-3-Clause", phrases: []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{id: "BSD-2-Clause", phrases: []string{"redistribution and use in source and binary forms"}},
+	{id: "BSD-3-Clause", phrases: []string{"bsd-3-clause"}},
+	{id: "BSD-2-Clause", phrases: []string{"bsd-2-clause"}},
+	{id: "MIT", phrases: []string{"permission is hereby granted, free of charge"}},
+	{id: "MIT", phrases: []string{"mit license"}},
+	{id: "MIT", phrases: []string{"licenses/mit"}},
+	{id: "ISC", phrases: []string{"isc license"}},
+	{id: "ISC", phrases: []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{id: "Zlib", phrases: []string{"this software is provided 'as-is'", "altered source versions must be plainly marked"}},
+	{id: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}},
+	{id: "CC0-1.0", phrases: []string{"cc0"}},
+	// Unversioned names of the GNU licenses, like the license names of POMs
+	{id: "AGPL", phrases: []string{"agpl"}},
+	{id: "LGPL", phrases: []string{"lgpl"}},
+	{id: "GPL", phrases: []string{"gpl"}},
+}
+
+// LicenseEntry is a license of a component shipped in the artifact
+type LicenseEntry struct {
+	Component string `json:"component"`
+	License   string `json:"license"`
+	Source    string `json:"source"`
+}
+
+// Denied checks whether the license is on the denied list. A denied ID also matches its versions, GPL denies GPL-2.0 and GPL-3.0.
+func (e LicenseEntry) Denied(denied []string) bool {
+	for _, id := range denied {
+		if strings.EqualFold(e.License, id) || strings.HasPrefix(strings.ToLower(e.License), strings.ToLower(id)+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyLicense returns the SPDX ID of a license text or name, Unknown if it isn't recognized
+func ClassifyLicense(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, matcher := range licenseMatchers {
+		matches := true
+		for _, phrase := range matcher.phrases {
+			if !strings.Contains(text, phrase) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return matcher.id
+		}
+	}
+	return unknownLicense
+}
+
+// ReadLicenses collects the licenses of the artifact from license files, Maven metadata, the notices of the
+// Google OSS licenses plugin and CocoaPods acknowledgements
+func ReadLicenses(artifactPath string) ([]LicenseEntry, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	var entries []LicenseEntry
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || file.UncompressedSize64 > maxLicenseEntrySize {
+			continue
+		}
+		name := path.Base(file.Name)
+
+		switch {
+		case licenseFilePattern.MatchString(name):
+			data, err := readZipEntry(file)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, LicenseEntry{Component: licenseFileComponent(file.Name), License: ClassifyLicense(string(data)), Source: file.Name})
+		case name == "pom.xml" && strings.Contains(file.Name, "META-INF/maven/"):
+			data, err := readZipEntry(file)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, pomLicenses(file.Name, data)...)
+		case name == "third_party_license_metadata":
+			textFile, ok := files[path.Join(path.Dir(file.Name), "third_party_licenses")]
+			if !ok {
+				continue
+			}
+			metadata, err := readZipEntry(file)
+			if err != nil {
+				continue
+			}
+			texts, err := readZipEntry(textFile)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, ossLicenses(textFile.Name, metadata, texts)...)
+		case strings.HasSuffix(name, "Acknowledgements.plist"):
+			entry, err := file.Open()
+			if err != nil {
+				continue
+			}
+			acknowledgements, err := readPlistEntry(entry)
+			_ = entry.Close()
+			if err != nil {
+				continue
+			}
+			entries = append(entries, cocoaPodsLicenses(file.Name, acknowledgements)...)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].Component) < strings.ToLower(entries[j].Component)
+	})
+	return entries, nil
+}
+
+// licenseFileComponent names the component of a license file: the suffix of the file name (LICENSE-okhttp),
+// or the framework, bundle or directory the file is in
+func licenseFileComponent(entryPath string) string {
+	match := licenseFilePattern.FindStringSubmatch(path.Base(entryPath))
+	if suffix := strings.TrimSuffix(match[1], path.Ext(match[1])); suffix != "" && !strings.EqualFold(suffix, "txt") && !strings.EqualFold(suffix, "md") {
+		return suffix
+	}
+
+	dir := path.Dir(entryPath)
+	if dir == "." || path.Base(dir) == "META-INF" {
+		return entryPath
+	}
+	base := path.Base(dir)
+	for _, ext := range []string{".framework", ".bundle", ".xcframework"} {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return base
+}
+
+// pomLicenses reads the licenses declared in the Maven POM of a Java library
+func pomLicenses(pomPath string, data []byte) []LicenseEntry {
+	var pom struct {
+		GroupID    string `xml:"groupId"`
+		ArtifactID string `xml:"artifactId"`
+		Parent     struct {
+			GroupID string `xml:"groupId"`
+		} `xml:"parent"`
+		Licenses []struct {
+			Name string `xml:"name"`
+			URL  string `xml:"url"`
+		} `xml:"licenses>license"`
+	}
+	if err := xml.Unmarshal(data, &pom); err != nil {
+		return nil
+	}
+
+	groupID := pom.GroupID
+	if groupID == "" {
+		groupID = pom.Parent.GroupID
+	}
+	component := groupID + ":" + pom.ArtifactID
+	if len(pom.Licenses) == 0 {
+		return []LicenseEntry{{Component: component, License: unknownLicense, Source: pomPath}}
+	}
+
+	var entries []LicenseEntry
+	for _, license := range pom.Licenses {
+		id := ClassifyLicense(license.Name)
+		if id == unknownLicense {
+			id = ClassifyLicense(license.URL)
+		}
+		entries = append(entries, LicenseEntry{Component: component, License: id, Source: pomPath})
+	}
+	return entries
+}
+
+// ossLicenses reads the notices of the Google OSS licenses plugin: every metadata line is "<offset>:<length> <component>"
+// pointing into the concatenated license texts
+func ossLicenses(textsPath string, metadata, texts []byte) []LicenseEntry {
+	var entries []LicenseEntry
+	for _, line := range strings.Split(string(metadata), "\n") {
+		position, component, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		offsetValue, lengthValue, ok := strings.Cut(position, ":")
+		if !ok {
+			continue
+		}
+		offset, err1 := strconv.Atoi(offsetValue)
+		length, err2 := strconv.Atoi(lengthValue)
+		if err1 != nil || err2 != nil || offset < 0 || length < 0 || offset+length > len(texts) {
+			continue
+		}
+		entries = append(entries, LicenseEntry{Component: component, License: ClassifyLicense(string(texts[offset : offset+length])), Source: textsPath})
+	}
+	return entries
+}
+
+// cocoaPodsLicenses reads the acknowledgements CocoaPods generates for the Settings bundle
+func cocoaPodsLicenses(plistPath string, acknowledgements map[string]interface{}) []LicenseEntry {
+	specifiers, _ := acknowledgements["PreferenceSpecifiers"].([]interface{})
+	var entries []LicenseEntry
+	for _, specifier := range specifiers {
+		item, ok := specifier.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		title, text := plistString(item, "Title"), plistString(item, "FooterText")
+		// The header and footer items of the acknowledgements aren't pods
+		if title == "" || plistString(item, "Type") != "PSGroupSpecifier" || title == "Acknowledgements" || strings.HasPrefix(text, "Generated by CocoaPods") {
+			continue
+		}
+
+		id := ClassifyLicense(plistString(item, "License"))
+		if id == unknownLicense {
+			id = ClassifyLicense(text)
+		}
+		entries = append(entries, LicenseEntry{Component: title, License: id, Source: plistPath})
+	}
+	return entries
+}
+
+// ParseDeniedLicenses splits denied_licenses, comma or newline separated SPDX IDs
+func ParseDeniedLicenses(input string) []string {
+	var denied []string
+	for _, id := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if id = strings.TrimSpace(id); id != "" {
+			denied = append(denied, id)
+		}
+	}
+	return denied
+}
+
+// LicenseInventoryName returns the file name of the license inventory of an artifact, next to its reports
+func LicenseInventoryName(artifactPath string) string {
+	return strings.TrimSuffix(ReportName(artifactPath, ".json"), ".json") + "-licenses.json"
+}
+
+// writeLicenseInventory writes the license inventory of the artifact into the working directory
+func writeLicenseInventory(workDir, artifactPath string, entries []LicenseEntry) (string, error) {
+	summary := map[string]int{}
+	for _, entry := range entries {
+		summary[entry.License]++
+	}
+	inventory := struct {
+		Artifact string         `json:"artifact"`
+		Summary  map[string]int `json:"summary"`
+		Licenses []LicenseEntry `json:"licenses"`
+	}{Artifact: ArtifactName(artifactPath), Summary: summary, Licenses: entries}
+	if inventory.Licenses == nil {
+		inventory.Licenses = []LicenseEntry{}
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(inventory); err != nil {
+		return "", fmt.Errorf("failed to encode license inventory: %w", err)
+	}
+	inventoryPath := filepath.Join(workDir, LicenseInventoryName(artifactPath))
+	if err := os.WriteFile(inventoryPath, data.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write license inventory: %w", err)
+	}
+	return inventoryPath, nil
+}
+
+// readLicensesFromConfig collects the licenses of the artifact, logs a summary and writes the license inventory,
+// false is returned when the artifact couldn't be read
+func readLicensesFromConfig(cfg config.Config, artifactPath, workDir string, logger log.Logger) ([]LicenseEntry, string, bool) {
+	logger.Infof("Collecting licenses...")
+	entries, err := ReadLicenses(artifactPath)
+	if err != nil {
+		logger.Warnf("Failed to collect licenses: %s", err)
+		return nil, "", false
+	}
+
+	counts := map[string]int64{}
+	for _, entry := range entries {
+		counts[entry.License]++
+	}
+	for _, id := range SortedKeys(counts) {
+		logger.Printf("- %s: %d component(s)", id, counts[id])
+	}
+	logger.Printf("Found %d license(s)", len(entries))
+
+	denied := ParseDeniedLicenses(cfg.DeniedLicenses)
+	for _, entry := range entries {
+		if entry.Denied(denied) {
+			logger.Warnf("Denied license %s: %s (%s)", entry.License, entry.Component, entry.Source)
+		}
+	}
+
+	inventoryPath, err := writeLicenseInventory(workDir, artifactPath, entries)
+	if err != nil {
+		logger.Warnf("%s", err)
+	}
+	return entries, inventoryPath, true
+}
@@ -0,0 +1,87 @@
+package analyze
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// machineOutputFlag makes bundle-inspector print a pluginManifest on stdout, its log goes to stderr
+const machineOutputFlag = "--machine-output"
+
+// machineOutputSchemaVersion is the only manifest schema the step understands
+const machineOutputSchemaVersion = 1
+
+// pluginManifest is the JSON document bundle-inspector prints on stdout with --machine-output:
+//
+//	{
+//	  "schema_version": 1,
+//	  "files": {"markdown": "...", "html": "...", "json": "...", "text": "..."},
+//	  "metrics": {"size_bytes": 52428800, "potential_savings_bytes": 1048576}
+//	}
+//
+// File paths are absolute or relative to the working directory of the plugin, formats which weren't requested are omitted.
+type pluginManifest struct {
+	SchemaVersion int               `json:"schema_version"`
+	Files         map[string]string `json:"files"`
+	Metrics       *struct {
+		SizeBytes             int64 `json:"size_bytes"`
+		PotentialSavingsBytes int64 `json:"potential_savings_bytes"`
+	} `json:"metrics"`
+}
+
+// manifestFormatExtensions maps the formats of the manifest to the extensions of the step's report names
+var manifestFormatExtensions = map[string]string{
+	"markdown": ".md",
+	"html":     ".html",
+	"json":     ".json",
+	"text":     ".txt",
+}
+
+// parsePluginManifest parses the stdout of bundle-inspector run with --machine-output
+func parsePluginManifest(stdout string) (*pluginManifest, error) {
+	var manifest pluginManifest
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle-inspector machine output: %w", err)
+	}
+	if manifest.SchemaVersion != machineOutputSchemaVersion {
+		return nil, fmt.Errorf("unsupported bundle-inspector machine output schema version: %d", manifest.SchemaVersion)
+	}
+	return &manifest, nil
+}
+
+// applyTo moves the reports listed in the manifest to the artifact's report names in the working directory,
+// so the rest of the step finds them like the reports of plugins without --machine-output
+func (m *pluginManifest) applyTo(workDir, artifactPath string) error {
+	for format, reportPath := range m.Files {
+		ext, ok := manifestFormatExtensions[format]
+		if !ok || reportPath == "" {
+			continue
+		}
+		if !filepath.IsAbs(reportPath) {
+			reportPath = filepath.Join(workDir, reportPath)
+		}
+
+		dstPath := filepath.Join(workDir, ReportName(artifactPath, ext))
+		if filepath.Clean(reportPath) == dstPath {
+			continue
+		}
+		if err := os.Rename(reportPath, dstPath); err != nil {
+			// The plugin may have written the report to a different file system
+			if err := copyFileWithMode(reportPath, dstPath, 0644); err != nil {
+				return fmt.Errorf("failed to collect %s report: %w", format, err)
+			}
+		}
+	}
+	return nil
+}
+
+// bundleMetrics returns the metrics of the manifest, false without a manifest or when the plugin didn't report any
+func (m *pluginManifest) bundleMetrics() (BundleMetrics, bool) {
+	if m == nil || m.Metrics == nil {
+		return BundleMetrics{}, false
+	}
+	return newBundleMetrics(m.Metrics.SizeBytes, m.Metrics.PotentialSavingsBytes), true
+}
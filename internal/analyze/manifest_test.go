@@ -0,0 +1,107 @@
+package analyze
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+func TestParsePluginManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		stdout      string
+		wantErr     string
+		wantMetrics bool
+	}{
+		{name: "files and metrics", stdout: `{"schema_version": 1, "files": {"json": "out/report.json"}, "metrics": {"size_bytes": 1024}}` + "\n", wantMetrics: true},
+		{name: "files only", stdout: `{"schema_version": 1, "files": {"json": "out/report.json"}}`},
+		{name: "log on stdout", stdout: "Analyzing app.apk...", wantErr: "failed to parse bundle-inspector machine output: invalid character 'A' looking for beginning of value"},
+		{name: "newer schema", stdout: `{"schema_version": 2, "files": {}}`, wantErr: "unsupported bundle-inspector machine output schema version: 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := parsePluginManifest(tt.stdout)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parsePluginManifest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePluginManifest() error = %s", err)
+			}
+			metrics, ok := manifest.bundleMetrics()
+			if ok != tt.wantMetrics || (ok && metrics.SizeBytes != 1024) {
+				t.Errorf("bundleMetrics() = %+v, %t", metrics, ok)
+			}
+		})
+	}
+}
+
+func TestPluginManifestApplyTo(t *testing.T) {
+	workDir := t.TempDir()
+	outsideDir := t.TempDir()
+	artifactPath := "/builds/app-release.apk"
+	for _, path := range []string{filepath.Join(workDir, "out", "report.md"), filepath.Join(outsideDir, "report.json"), filepath.Join(workDir, ReportName(artifactPath, ".html"))} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(filepath.Base(path)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest := &pluginManifest{SchemaVersion: 1, Files: map[string]string{
+		"markdown": "out/report.md",
+		"json":     filepath.Join(outsideDir, "report.json"),
+		"html":     ReportName(artifactPath, ".html"),
+		"sarif":    "out/report.sarif",
+	}}
+	if err := manifest.applyTo(workDir, artifactPath); err != nil {
+		t.Fatalf("applyTo() error = %s", err)
+	}
+
+	// Relative and absolute paths are moved to the report names, unknown formats are ignored
+	want := map[string]string{".md": "report.md", ".json": "report.json", ".html": ReportName(artifactPath, ".html")}
+	for ext, content := range want {
+		got, err := os.ReadFile(filepath.Join(workDir, ReportName(artifactPath, ext)))
+		if err != nil {
+			t.Errorf("%s report not collected: %s", ext, err)
+		} else if string(got) != content {
+			t.Errorf("%s report = %q, want %q", ext, got, content)
+		}
+	}
+
+	missing := &pluginManifest{SchemaVersion: 1, Files: map[string]string{"json": "missing.json"}}
+	if err := missing.applyTo(workDir, artifactPath); err == nil {
+		t.Errorf("applyTo() collected a missing report")
+	}
+}
+
+func TestAnalyzeInvalidMachineOutput(t *testing.T) {
+	// A plugin advertising --machine-output that prints its log instead falls back to the report names
+	run := fakeBundleInspector(false, "json")
+	factory := fakeCommandFactory{calls: &[]string{}, run: func(args []string, opts *command.Opts) (string, error) {
+		if len(args) > 2 && args[2] == "--help" {
+			return "Flags:\n  " + machineOutputFlag, nil
+		}
+		return run(args, opts)
+	}}
+	cfg, err := config.Parse(fakeEnvRepository{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := NewAnalyzer(factory, fakeEnvRepository{}, log.NewLogger()).Analyze(context.Background(), cfg, "/builds/app-release.apk", t.TempDir(), nil, executor.RetryOptions{})
+	if err != nil {
+		t.Fatalf("Analyze() error = %s", err)
+	}
+	if result.GeneratedFiles.JSON == "" || result.Metrics.SizeBytes != 5242880 {
+		t.Errorf("Analyze() = %+v, %+v, want the metrics of the JSON report", result.GeneratedFiles, result.Metrics)
+	}
+}
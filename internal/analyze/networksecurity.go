@@ -0,0 +1,244 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// networkSecurityConfigRoot is the root element of Android network security configuration files
+const networkSecurityConfigRoot = "network-security-config"
+
+// cleartextDefaultTargetSdk is the target SDK from which Android blocks cleartext traffic by default
+const cleartextDefaultTargetSdk = 28
+
+// atsScopeAll is the scope of findings applying to every connection
+const atsScopeAll = "all domains"
+
+// weakTLSVersions are the NSExceptionMinimumTLSVersion values below TLS 1.2
+var weakTLSVersions = map[string]bool{"TLSv1.0": true, "TLSv1.1": true}
+
+// NetworkSecurityFinding is a cleartext traffic allowance, TLS exception or trust anchor weakening the transport security
+type NetworkSecurityFinding struct {
+	// Source is the file declaring the setting
+	Source string
+	// Scope is atsScopeAll or the domains the setting applies to
+	Scope string
+	Issue string
+}
+
+// AuditNetworkSecurity evaluates the transport security settings of the artifact: the cleartext traffic flag and
+// network security configuration of APKs and AABs, the App Transport Security settings of IPAs
+func AuditNetworkSecurity(artifactPath string) ([]NetworkSecurityFinding, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		_, info, err := readAppInfoPlist(&reader.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return auditAppTransportSecurity(info), nil
+	}
+	return auditAndroidNetworkSecurity(&reader.Reader)
+}
+
+// auditAndroidNetworkSecurity checks the manifest flags and every network security configuration file
+func auditAndroidNetworkSecurity(reader *zip.Reader) ([]NetworkSecurityFinding, error) {
+	manifest, err := readAndroidManifest(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []NetworkSecurityFinding
+	configs, err := readNetworkSecurityConfigs(reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range sortedConfigNames(configs) {
+		findings = append(findings, auditNetworkSecurityConfig(name, configs[name].Children, atsScopeAll)...)
+	}
+
+	for _, application := range manifest.ChildrenNamed("application") {
+		if application.Attrs["usesCleartextTraffic"] == "true" {
+			findings = append(findings, NetworkSecurityFinding{Source: apkManifestPath, Scope: atsScopeAll,
+				Issue: "android:usesCleartextTraffic allows cleartext HTTP"})
+			continue
+		}
+		if _, ok := application.Attrs["usesCleartextTraffic"]; ok || len(configs) > 0 {
+			continue
+		}
+		for _, sdk := range manifest.ChildrenNamed("uses-sdk") {
+			target, err := strconv.Atoi(sdk.Attrs["targetSdkVersion"])
+			if err == nil && target < cleartextDefaultTargetSdk {
+				findings = append(findings, NetworkSecurityFinding{Source: apkManifestPath, Scope: atsScopeAll,
+					Issue: fmt.Sprintf("cleartext HTTP is allowed by default with targetSdkVersion %d", target)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// readNetworkSecurityConfigs parses the XML resources with a network-security-config root, keyed by path.
+// Resource paths aren't resolved through resources.arsc: shrunk artifacts rename the files.
+func readNetworkSecurityConfigs(reader *zip.Reader) (map[string]*manifestElement, error) {
+	// Binary XML string pools are UTF-16 or UTF-8, proto XML is UTF-8
+	markers := [][]byte{[]byte(networkSecurityConfigRoot), utf16LE(networkSecurityConfigRoot)}
+	configs := map[string]*manifestElement{}
+	for _, file := range reader.File {
+		if path.Ext(file.Name) != ".xml" || !strings.HasPrefix(file.Name, "res/") && !strings.Contains(file.Name, "/res/") {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		if !bytes.Contains(data, markers[0]) && !bytes.Contains(data, markers[1]) {
+			continue
+		}
+		root, err := parseAndroidXML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file.Name, err)
+		}
+		if root.Name == networkSecurityConfigRoot {
+			configs[file.Name] = root
+		}
+	}
+	return configs, nil
+}
+
+// auditNetworkSecurityConfig checks the base-config and domain-config elements, nested domain configs included.
+// debug-overrides only apply to debuggable builds and are skipped.
+func auditNetworkSecurityConfig(source string, elements []*manifestElement, scope string) []NetworkSecurityFinding {
+	var findings []NetworkSecurityFinding
+	for _, element := range elements {
+		elementScope := scope
+		switch element.Name {
+		case "base-config":
+		case "domain-config":
+			var domains []string
+			for _, domain := range element.ChildrenNamed("domain") {
+				if domain.Attrs["includeSubdomains"] == "true" {
+					domains = append(domains, "*."+domain.Text)
+				} else {
+					domains = append(domains, domain.Text)
+				}
+			}
+			elementScope = strings.Join(domains, ", ")
+		default:
+			continue
+		}
+
+		if element.Attrs["cleartextTrafficPermitted"] == "true" {
+			findings = append(findings, NetworkSecurityFinding{Source: source, Scope: elementScope, Issue: "cleartext HTTP is permitted"})
+		}
+		for _, anchors := range element.ChildrenNamed("trust-anchors") {
+			for _, certificates := range anchors.ChildrenNamed("certificates") {
+				if certificates.Attrs["src"] == "user" {
+					findings = append(findings, NetworkSecurityFinding{Source: source, Scope: elementScope, Issue: "user-installed CA certificates are trusted"})
+				}
+				if certificates.Attrs["overridePins"] == "true" {
+					findings = append(findings, NetworkSecurityFinding{Source: source, Scope: elementScope, Issue: "trust anchors override certificate pins"})
+				}
+			}
+		}
+		findings = append(findings, auditNetworkSecurityConfig(source, element.ChildrenNamed("domain-config"), elementScope)...)
+	}
+	return findings
+}
+
+// auditAppTransportSecurity checks the NSAppTransportSecurity dictionary of the app's Info.plist
+func auditAppTransportSecurity(info map[string]interface{}) []NetworkSecurityFinding {
+	const source = "Info.plist"
+	ats, _ := info["NSAppTransportSecurity"].(map[string]interface{})
+	var findings []NetworkSecurityFinding
+	allowances := []struct {
+		key   string
+		issue string
+	}{
+		{"NSAllowsArbitraryLoads", "NSAllowsArbitraryLoads disables ATS"},
+		{"NSAllowsArbitraryLoadsInWebContent", "NSAllowsArbitraryLoadsInWebContent disables ATS for web views"},
+		{"NSAllowsArbitraryLoadsForMedia", "NSAllowsArbitraryLoadsForMedia disables ATS for AVFoundation media"},
+	}
+	for _, allowance := range allowances {
+		if ats[allowance.key] == true {
+			findings = append(findings, NetworkSecurityFinding{Source: source, Scope: atsScopeAll, Issue: allowance.issue})
+		}
+	}
+
+	domains, _ := ats["NSExceptionDomains"].(map[string]interface{})
+	var names []string
+	for name := range domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		exception, _ := domains[name].(map[string]interface{})
+		scope := name
+		if exception["NSIncludesSubdomains"] == true {
+			scope = "*." + name
+		}
+		if exception["NSExceptionAllowsInsecureHTTPLoads"] == true || exception["NSThirdPartyExceptionAllowsInsecureHTTPLoads"] == true {
+			findings = append(findings, NetworkSecurityFinding{Source: source, Scope: scope, Issue: "cleartext HTTP is allowed"})
+		}
+		for _, key := range []string{"NSExceptionMinimumTLSVersion", "NSThirdPartyExceptionMinimumTLSVersion"} {
+			if version, ok := exception[key].(string); ok && weakTLSVersions[version] {
+				findings = append(findings, NetworkSecurityFinding{Source: source, Scope: scope, Issue: fmt.Sprintf("TLS versions from %s are accepted", version)})
+			}
+		}
+		for _, key := range []string{"NSExceptionRequiresForwardSecrecy", "NSThirdPartyExceptionRequiresForwardSecrecy"} {
+			if exception[key] == false {
+				findings = append(findings, NetworkSecurityFinding{Source: source, Scope: scope, Issue: "forward secrecy isn't required"})
+			}
+		}
+	}
+	return findings
+}
+
+// sortedConfigNames returns the paths of the network security configuration files, sorted
+func sortedConfigNames(configs map[string]*manifestElement) []string {
+	var names []string
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// utf16LE encodes a string in UTF-16 little-endian, the string pool encoding of most binary XML files
+func utf16LE(value string) []byte {
+	var encoded []byte
+	for _, unit := range utf16.Encode([]rune(value)) {
+		encoded = append(encoded, byte(unit), byte(unit>>8))
+	}
+	return encoded
+}
+
+// auditNetworkSecurityFromConfig audits the transport security settings of the artifact and logs the findings,
+// false is returned when the artifact couldn't be audited
+func auditNetworkSecurityFromConfig(artifactPath string, logger log.Logger) ([]NetworkSecurityFinding, bool) {
+	logger.Infof("Auditing network security configuration...")
+	findings, err := AuditNetworkSecurity(artifactPath)
+	if err != nil {
+		logger.Warnf("Failed to audit network security configuration: %s", err)
+		return nil, false
+	}
+
+	if len(findings) == 0 {
+		logger.Donef("No cleartext traffic allowances or transport security exceptions found")
+	}
+	for _, finding := range findings {
+		logger.Warnf("%s (%s): %s", finding.Scope, finding.Source, finding.Issue)
+	}
+	return findings, true
+}
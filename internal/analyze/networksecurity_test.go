@@ -0,0 +1,101 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAuditNetworkSecurity(t *testing.T) {
+	application := func(attrs ...[2]string) xmlNode {
+		return xmlNode{name: "application", attrs: attrs}
+	}
+	legacySdk := xmlNode{name: "uses-sdk", attrs: [][2]string{{"targetSdkVersion", "27"}}}
+	networkSecurityConfig := xmlNode{name: "network-security-config", children: []xmlNode{
+		{name: "base-config", children: []xmlNode{{name: "trust-anchors", children: []xmlNode{
+			{name: "certificates", attrs: [][2]string{{"src", "system"}}},
+			{name: "certificates", attrs: [][2]string{{"src", "user"}}},
+		}}}},
+		{name: "domain-config", attrs: [][2]string{{"cleartextTrafficPermitted", "true"}}, children: []xmlNode{
+			{name: "domain", attrs: [][2]string{{"includeSubdomains", "true"}}, text: "example.com"},
+			{name: "domain-config", children: []xmlNode{
+				{name: "domain", text: "pinned.example.com"},
+				{name: "trust-anchors", children: []xmlNode{{name: "certificates", attrs: [][2]string{{"src", "@raw/ca"}, {"overridePins", "true"}}}}},
+			}},
+		}},
+		// Debug overrides only apply to debuggable builds
+		{name: "debug-overrides", attrs: [][2]string{{"cleartextTrafficPermitted", "true"}}},
+	}}
+	configEntry := zipEntry{name: "base/res/xml/network_security_config.xml", content: string(networkSecurityConfig.protoXML())}
+
+	tests := []struct {
+		name     string
+		manifest xmlNode
+		entries  []zipEntry
+		want     []NetworkSecurityFinding
+	}{
+		{name: "secure defaults", manifest: xmlNode{name: "manifest", children: []xmlNode{application()}}},
+		{name: "cleartext traffic flag", manifest: xmlNode{name: "manifest", children: []xmlNode{application([2]string{"usesCleartextTraffic", "true"})}},
+			want: []NetworkSecurityFinding{{Source: apkManifestPath, Scope: atsScopeAll, Issue: "android:usesCleartextTraffic allows cleartext HTTP"}}},
+		{name: "legacy target SDK", manifest: xmlNode{name: "manifest", children: []xmlNode{legacySdk, application()}},
+			want: []NetworkSecurityFinding{{Source: apkManifestPath, Scope: atsScopeAll, Issue: "cleartext HTTP is allowed by default with targetSdkVersion 27"}}},
+		{name: "legacy target SDK with explicit flag", manifest: xmlNode{name: "manifest", children: []xmlNode{legacySdk, application([2]string{"usesCleartextTraffic", "false"})}}},
+		{name: "network security config", manifest: xmlNode{name: "manifest", children: []xmlNode{legacySdk, application()}}, entries: []zipEntry{configEntry}, want: []NetworkSecurityFinding{
+			{Source: configEntry.name, Scope: atsScopeAll, Issue: "user-installed CA certificates are trusted"},
+			{Source: configEntry.name, Scope: "*.example.com", Issue: "cleartext HTTP is permitted"},
+			{Source: configEntry.name, Scope: "pinned.example.com", Issue: "trust anchors override certificate pins"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, err := AuditNetworkSecurity(writeAAB(t, tt.manifest, tt.entries...))
+			if err != nil {
+				t.Fatalf("AuditNetworkSecurity() error = %s", err)
+			}
+			if !reflect.DeepEqual(findings, tt.want) {
+				t.Errorf("AuditNetworkSecurity() = %+v, want %+v", findings, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditAppTransportSecurity(t *testing.T) {
+	info := `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>CFBundleExecutable</key><string>Test</string>
+<key>NSAppTransportSecurity</key><dict>
+	<key>NSAllowsArbitraryLoadsInWebContent</key><true/>
+	<key>NSExceptionDomains</key><dict>
+		<key>legacy.example.com</key><dict>
+			<key>NSExceptionAllowsInsecureHTTPLoads</key><true/>
+			<key>NSExceptionMinimumTLSVersion</key><string>TLSv1.0</string>
+		</dict>
+		<key>cdn.example.com</key><dict>
+			<key>NSIncludesSubdomains</key><true/>
+			<key>NSExceptionRequiresForwardSecrecy</key><false/>
+			<key>NSExceptionMinimumTLSVersion</key><string>TLSv1.2</string>
+		</dict>
+	</dict>
+</dict>
+</dict></plist>`
+	artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{{name: "Payload/Test.app/Info.plist", content: info}}, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings, err := AuditNetworkSecurity(artifactPath)
+	if err != nil {
+		t.Fatalf("AuditNetworkSecurity() error = %s", err)
+	}
+	want := []NetworkSecurityFinding{
+		{Source: "Info.plist", Scope: atsScopeAll, Issue: "NSAllowsArbitraryLoadsInWebContent disables ATS for web views"},
+		{Source: "Info.plist", Scope: "*.cdn.example.com", Issue: "forward secrecy isn't required"},
+		{Source: "Info.plist", Scope: "legacy.example.com", Issue: "cleartext HTTP is allowed"},
+		{Source: "Info.plist", Scope: "legacy.example.com", Issue: "TLS versions from TLSv1.0 are accepted"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("AuditNetworkSecurity() = %+v, want %+v", findings, want)
+	}
+}
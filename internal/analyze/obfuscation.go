@@ -0,0 +1,159 @@
+package analyze
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Offsets of the DEX header fields locating the string IDs, type IDs and class definitions
+const (
+	dexStringIDsOffset = 0x38
+	dexTypeIDsOffset   = 0x40
+	dexClassDefsOffset = 0x60
+	dexClassDefSize    = 0x20
+)
+
+// unobfuscatablePackages hold classes R8 never renames: the platform and the desugared library
+var unobfuscatablePackages = []string{"java/", "javax/", "android/", "dalvik/", "j$/"}
+
+// readObfuscationCoverage counts the classes defined in the DEX files of the artifact and the ones with obfuscated names
+func readObfuscationCoverage(artifactPath string) (int64, int64, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var classes, obfuscated int64
+	for _, file := range reader.File {
+		if !isDexEntry(file.Name) {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		descriptors, err := dexClassDescriptors(data)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		for _, descriptor := range descriptors {
+			counted, renamed := classObfuscation(descriptor)
+			if counted {
+				classes++
+			}
+			if renamed {
+				obfuscated++
+			}
+		}
+	}
+	return classes, obfuscated, nil
+}
+
+// dexClassDescriptors returns the type descriptors (Lcom/example/Main;) of the classes defined in a DEX file
+func dexClassDescriptors(data []byte) ([]string, error) {
+	if len(data) < dexHeaderSize+0x10 || string(data[:4]) != "dex\n" {
+		return nil, fmt.Errorf("invalid DEX header")
+	}
+	u32 := func(offset uint64) (uint32, error) {
+		if offset+4 > uint64(len(data)) {
+			return 0, fmt.Errorf("invalid DEX: offset %d out of range", offset)
+		}
+		return binary.LittleEndian.Uint32(data[offset:]), nil
+	}
+
+	stringIDsSize, stringIDsOff := binary.LittleEndian.Uint32(data[dexStringIDsOffset:]), binary.LittleEndian.Uint32(data[dexStringIDsOffset+4:])
+	typeIDsSize, typeIDsOff := binary.LittleEndian.Uint32(data[dexTypeIDsOffset:]), binary.LittleEndian.Uint32(data[dexTypeIDsOffset+4:])
+	classDefsSize, classDefsOff := binary.LittleEndian.Uint32(data[dexClassDefsOffset:]), binary.LittleEndian.Uint32(data[dexClassDefsOffset+4:])
+
+	var descriptors []string
+	for idx := uint32(0); idx < classDefsSize; idx++ {
+		typeIdx, err := u32(uint64(classDefsOff) + uint64(idx)*dexClassDefSize)
+		if err != nil {
+			return nil, err
+		}
+		if typeIdx >= typeIDsSize {
+			return nil, fmt.Errorf("invalid DEX: type index %d out of range", typeIdx)
+		}
+		stringIdx, err := u32(uint64(typeIDsOff) + uint64(typeIdx)*4)
+		if err != nil {
+			return nil, err
+		}
+		if stringIdx >= stringIDsSize {
+			return nil, fmt.Errorf("invalid DEX: string index %d out of range", stringIdx)
+		}
+		stringOff, err := u32(uint64(stringIDsOff) + uint64(stringIdx)*4)
+		if err != nil {
+			return nil, err
+		}
+		descriptor, err := dexString(data, stringOff)
+		if err != nil {
+			return nil, err
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	return descriptors, nil
+}
+
+// dexString reads a string_data_item: the ULEB128 UTF-16 length followed by the NUL terminated MUTF-8 bytes
+func dexString(data []byte, offset uint32) (string, error) {
+	pos := int(offset)
+	for ; pos < len(data) && data[pos]&0x80 != 0; pos++ {
+	}
+	pos++
+	if pos > len(data) {
+		return "", fmt.Errorf("invalid DEX: string at %d out of range", offset)
+	}
+	end := pos
+	for end < len(data) && data[end] != 0 {
+		end++
+	}
+	return string(data[pos:end]), nil
+}
+
+// classObfuscation tells whether a class counts towards the obfuscation coverage and whether its name is obfuscated.
+// Platform, resource (R, BR) and compiler generated classes don't count. Names of at most two characters and
+// lowercase three character names are considered obfuscated, the names R8 and ProGuard assign (a, b, ..., aa, ab, ...).
+func classObfuscation(descriptor string) (bool, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(descriptor, "L"), ";")
+	for _, prefix := range unobfuscatablePackages {
+		// The support library is bundled with the app, unlike the rest of the android package
+		if strings.HasPrefix(name, prefix) && !strings.HasPrefix(name, "android/support/") {
+			return false, false
+		}
+	}
+
+	simpleName := name[strings.LastIndex(name, "/")+1:]
+	if strings.HasPrefix(simpleName, "-") || strings.Contains(simpleName, "$$") {
+		return false, false
+	}
+
+	parts := strings.Split(simpleName, "$")
+	if parts[0] == "R" || parts[0] == "BR" {
+		return false, false
+	}
+	// Anonymous classes are numbered (Main$1), the name of the enclosing class tells
+	part := parts[len(parts)-1]
+	for idx := len(parts) - 1; idx > 0 && isNumeric(part); idx-- {
+		part = parts[idx-1]
+	}
+
+	length := len([]rune(part))
+	return true, length <= 2 || (length == 3 && strings.ToLower(part) == part)
+}
+
+// isNumeric reports whether the string is a non-empty run of digits
+func isNumeric(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
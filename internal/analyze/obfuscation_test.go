@@ -0,0 +1,92 @@
+package analyze
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDex builds a DEX file defining a class for each descriptor, only the string IDs, type IDs and class
+// definitions are filled in
+func writeDex(descriptors []string) []byte {
+	count := uint32(len(descriptors))
+	stringIDsOff := uint32(0x70)
+	typeIDsOff := stringIDsOff + 4*count
+	classDefsOff := typeIDsOff + 4*count
+	dataOff := classDefsOff + dexClassDefSize*count
+
+	data := make([]byte, dataOff)
+	copy(data, "dex\n035\x00")
+	binary.LittleEndian.PutUint32(data[dexStringIDsOffset:], count)
+	binary.LittleEndian.PutUint32(data[dexStringIDsOffset+4:], stringIDsOff)
+	binary.LittleEndian.PutUint32(data[dexTypeIDsOffset:], count)
+	binary.LittleEndian.PutUint32(data[dexTypeIDsOffset+4:], typeIDsOff)
+	binary.LittleEndian.PutUint32(data[dexClassDefsOffset:], count)
+	binary.LittleEndian.PutUint32(data[dexClassDefsOffset+4:], classDefsOff)
+	for idx, descriptor := range descriptors {
+		binary.LittleEndian.PutUint32(data[stringIDsOff+4*uint32(idx):], uint32(len(data)))
+		binary.LittleEndian.PutUint32(data[typeIDsOff+4*uint32(idx):], uint32(idx))
+		binary.LittleEndian.PutUint32(data[classDefsOff+dexClassDefSize*uint32(idx):], uint32(idx))
+		data = append(data, byte(len(descriptor)))
+		data = append(data, descriptor...)
+		data = append(data, 0)
+	}
+	return data
+}
+
+func TestReadObfuscationCoverage(t *testing.T) {
+	entries := []zipEntry{
+		{name: "classes.dex", content: string(writeDex([]string{"Lcom/example/MainActivity;", "La/b;", "Lcom/example/a$1;", "Lcom/example/R$string;"}))},
+		{name: "classes2.dex", content: string(writeDex([]string{"Lj$/util/Optional;", "Lokhttp3/c;", "Lcom/example/Repository;"}))},
+		{name: "assets/classes.dex", content: "not a DEX file of the app"},
+	}
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	classes, obfuscated, err := readObfuscationCoverage(artifactPath)
+	if err != nil {
+		t.Fatalf("readObfuscationCoverage() error = %s", err)
+	}
+	if classes != 5 || obfuscated != 3 {
+		t.Errorf("readObfuscationCoverage() = %d classes, %d obfuscated, want 5 and 3", classes, obfuscated)
+	}
+
+	if _, err := dexClassDescriptors([]byte("dex\n035")); err == nil {
+		t.Errorf("dexClassDescriptors() of a truncated DEX succeeded")
+	}
+}
+
+func TestClassObfuscation(t *testing.T) {
+	tests := []struct {
+		descriptor     string
+		wantCounted    bool
+		wantObfuscated bool
+	}{
+		{descriptor: "Lcom/example/MainActivity;", wantCounted: true},
+		{descriptor: "La;", wantCounted: true, wantObfuscated: true},
+		{descriptor: "Lcom/example/ab;", wantCounted: true, wantObfuscated: true},
+		{descriptor: "Lcom/example/abc;", wantCounted: true, wantObfuscated: true},
+		{descriptor: "Lcom/example/App;", wantCounted: true},
+		{descriptor: "Lcom/example/Main$Builder;", wantCounted: true},
+		{descriptor: "Lcom/example/Main$a;", wantCounted: true, wantObfuscated: true},
+		{descriptor: "Lcom/example/ab$1$2;", wantCounted: true, wantObfuscated: true},
+		{descriptor: "Lcom/example/Main$1;", wantCounted: true},
+		{descriptor: "Landroid/support/v4/a;", wantCounted: true, wantObfuscated: true},
+		{descriptor: "Landroid/app/Activity;"},
+		{descriptor: "Lj$/time/Instant;"},
+		{descriptor: "Lcom/example/R$layout;"},
+		{descriptor: "Lcom/example/databinding/BR;"},
+		{descriptor: "Lcom/example/-$$Lambda$Main$1;"},
+		{descriptor: "Lcom/example/Main$$ExternalSyntheticLambda0;"},
+	}
+	for _, tt := range tests {
+		counted, obfuscated := classObfuscation(tt.descriptor)
+		if counted != tt.wantCounted || obfuscated != tt.wantObfuscated {
+			t.Errorf("classObfuscation(%s) = %t, %t, want %t, %t", tt.descriptor, counted, obfuscated, tt.wantCounted, tt.wantObfuscated)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// sensitivePermissions are the runtime (dangerous) and special Android permissions and the iOS entitlements
+// granting access to personal data or device capabilities, see
+// https://developer.android.com/reference/android/Manifest.permission
+var sensitivePermissions = map[string]bool{
+	"android.permission.ACCESS_BACKGROUND_LOCATION":         true,
+	"android.permission.ACCESS_COARSE_LOCATION":             true,
+	"android.permission.ACCESS_FINE_LOCATION":               true,
+	"android.permission.ACCESS_MEDIA_LOCATION":              true,
+	"android.permission.ACTIVITY_RECOGNITION":               true,
+	"android.permission.ADD_VOICEMAIL":                      true,
+	"android.permission.ANSWER_PHONE_CALLS":                 true,
+	"android.permission.BIND_ACCESSIBILITY_SERVICE":         true,
+	"android.permission.BIND_DEVICE_ADMIN":                  true,
+	"android.permission.BLUETOOTH_ADVERTISE":                true,
+	"android.permission.BLUETOOTH_CONNECT":                  true,
+	"android.permission.BLUETOOTH_SCAN":                     true,
+	"android.permission.BODY_SENSORS":                       true,
+	"android.permission.BODY_SENSORS_BACKGROUND":            true,
+	"android.permission.CALL_PHONE":                         true,
+	"android.permission.CAMERA":                             true,
+	"android.permission.GET_ACCOUNTS":                       true,
+	"android.permission.MANAGE_EXTERNAL_STORAGE":            true,
+	"android.permission.NEARBY_WIFI_DEVICES":                true,
+	"android.permission.PACKAGE_USAGE_STATS":                true,
+	"android.permission.POST_NOTIFICATIONS":                 true,
+	"android.permission.PROCESS_OUTGOING_CALLS":             true,
+	"android.permission.QUERY_ALL_PACKAGES":                 true,
+	"android.permission.READ_CALENDAR":                      true,
+	"android.permission.READ_CALL_LOG":                      true,
+	"android.permission.READ_CONTACTS":                      true,
+	"android.permission.READ_EXTERNAL_STORAGE":              true,
+	"android.permission.READ_MEDIA_AUDIO":                   true,
+	"android.permission.READ_MEDIA_IMAGES":                  true,
+	"android.permission.READ_MEDIA_VIDEO":                   true,
+	"android.permission.READ_MEDIA_VISUAL_USER_SELECTED":    true,
+	"android.permission.READ_PHONE_NUMBERS":                 true,
+	"android.permission.READ_PHONE_STATE":                   true,
+	"android.permission.READ_SMS":                           true,
+	"android.permission.RECEIVE_MMS":                        true,
+	"android.permission.RECEIVE_SMS":                        true,
+	"android.permission.RECEIVE_WAP_PUSH":                   true,
+	"android.permission.RECORD_AUDIO":                       true,
+	"android.permission.REQUEST_INSTALL_PACKAGES":           true,
+	"android.permission.SCHEDULE_EXACT_ALARM":               true,
+	"android.permission.SEND_SMS":                           true,
+	"android.permission.SYSTEM_ALERT_WINDOW":                true,
+	"android.permission.USE_FULL_SCREEN_INTENT":             true,
+	"android.permission.USE_SIP":                            true,
+	"android.permission.UWB_RANGING":                        true,
+	"android.permission.WRITE_CALENDAR":                     true,
+	"android.permission.WRITE_CALL_LOG":                     true,
+	"android.permission.WRITE_CONTACTS":                     true,
+	"android.permission.WRITE_EXTERNAL_STORAGE":             true,
+	"android.permission.WRITE_SETTINGS":                     true,
+	"com.google.android.gms.permission.AD_ID":               true,
+	"com.apple.developer.contacts.notes":                    true,
+	"com.apple.developer.family-controls":                   true,
+	"com.apple.developer.healthkit":                         true,
+	"com.apple.developer.homekit":                           true,
+	"com.apple.developer.location.push":                     true,
+	"com.apple.developer.networking.networkextension":       true,
+	"com.apple.developer.networking.vpn.api":                true,
+	"com.apple.developer.networking.wifi-info":              true,
+	"com.apple.developer.nfc.readersession.formats":         true,
+	"com.apple.developer.usernotifications.critical-alerts": true,
+}
+
+// identityEntitlements are present in every signed app, they identify it and aren't capabilities
+var identityEntitlements = map[string]bool{
+	"application-identifier":              true,
+	"com.apple.developer.team-identifier": true,
+	"get-task-allow":                      true,
+}
+
+// PermissionDiff holds the permissions added and removed since the baseline build
+type PermissionDiff struct {
+	Added   []string
+	Removed []string
+	// SensitiveAdded are the added permissions which are sensitive
+	SensitiveAdded []string
+}
+
+// ReadPermissions returns the permissions of the artifact, sorted: the permissions requested in the Android manifest
+// of APKs and AABs, and the entitlements and privacy usage descriptions (NS*UsageDescription) of IPAs
+func ReadPermissions(artifactPath, workDir string) ([]string, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var permissions []string
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		permissions, err = readIPAPermissions(&reader.Reader, workDir)
+	} else {
+		permissions, err = readAndroidPermissions(&reader.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, permission := range permissions {
+		if !seen[permission] {
+			seen[permission] = true
+			unique = append(unique, permission)
+		}
+	}
+	sort.Strings(unique)
+	return unique, nil
+}
+
+// readAndroidPermissions returns the permissions requested with uses-permission and uses-permission-sdk-23
+func readAndroidPermissions(reader *zip.Reader) ([]string, error) {
+	manifest, err := readAndroidManifest(reader)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, element := range append(manifest.ChildrenNamed("uses-permission"), manifest.ChildrenNamed("uses-permission-sdk-23")...) {
+		if name := element.Attrs["name"]; name != "" {
+			permissions = append(permissions, name)
+		}
+	}
+	return permissions, nil
+}
+
+// readIPAPermissions returns the entitlements of the app executable's code signature and the privacy usage
+// descriptions of the app's Info.plist
+func readIPAPermissions(reader *zip.Reader, workDir string) ([]string, error) {
+	appDir, info, err := readAppInfoPlist(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []string
+	for key := range info {
+		if strings.HasPrefix(key, "NS") && strings.HasSuffix(key, "UsageDescription") {
+			permissions = append(permissions, key)
+		}
+	}
+
+	entitlements, err := readAppEntitlements(reader, appDir, info, workDir)
+	if err != nil {
+		return nil, err
+	}
+	for key := range entitlements {
+		if !identityEntitlements[key] {
+			permissions = append(permissions, key)
+		}
+	}
+	return permissions, nil
+}
+
+// readAppInfoPlist returns the directory and the parsed Info.plist of the app bundle of an IPA
+func readAppInfoPlist(reader *zip.Reader) (string, map[string]interface{}, error) {
+	for _, file := range reader.File {
+		if !appInfoPlistPattern.MatchString(file.Name) {
+			continue
+		}
+		entry, err := file.Open()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		info, err := readPlistEntry(entry)
+		_ = entry.Close()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse %s: %w", file.Name, err)
+		}
+		return path.Dir(file.Name), info, nil
+	}
+	return "", nil, fmt.Errorf("no app bundle found in the IPA")
+}
+
+// readAppEntitlements returns the entitlements embedded in the code signature of the app executable,
+// empty if the executable isn't signed
+func readAppEntitlements(reader *zip.Reader, appDir string, info map[string]interface{}, workDir string) (map[string]interface{}, error) {
+	executablePath := appDir + "/" + plistString(info, "CFBundleExecutable")
+	for _, file := range reader.File {
+		if file.Name != executablePath {
+			continue
+		}
+		extracted, err := extractZipEntry(file, workDir)
+		if err != nil {
+			return nil, err
+		}
+		slices, err := readCodeSignatures(extracted)
+		_ = extracted.Close()
+		_ = os.Remove(extracted.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the code signature of %s: %w", file.Name, err)
+		}
+		for _, slice := range slices {
+			if len(slice.entitlements) == 0 {
+				continue
+			}
+			entitlements, err := parsePlist(slice.entitlements)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the entitlements of %s: %w", file.Name, err)
+			}
+			dict, _ := entitlements.(map[string]interface{})
+			return dict, nil
+		}
+	}
+	return nil, nil
+}
+
+// IsSensitivePermission checks whether a permission is sensitive: a known sensitive Android permission or iOS
+// entitlement, an iOS privacy usage description, or listed in sensitive_permissions. Entries ending with * match
+// every permission with the prefix.
+func IsSensitivePermission(permission string, extra []string) bool {
+	if sensitivePermissions[permission] || strings.HasSuffix(permission, "UsageDescription") {
+		return true
+	}
+	for _, pattern := range extra {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(permission, prefix) {
+			return true
+		}
+		if pattern == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSensitivePermissions parses sensitive_permissions: permissions separated by commas or newlines
+func ParseSensitivePermissions(input string) []string {
+	var permissions []string
+	for _, line := range strings.Split(input, "\n") {
+		for _, permission := range strings.Split(line, ",") {
+			if permission = strings.TrimSpace(permission); permission != "" {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// diffPermissions compares the permissions of the current build with the baseline build
+func diffPermissions(baseline, current []string, extraSensitive []string) PermissionDiff {
+	var diff PermissionDiff
+	diff.Added, diff.Removed = diffStrings(baseline, current)
+	for _, permission := range diff.Added {
+		if IsSensitivePermission(permission, extraSensitive) {
+			diff.SensitiveAdded = append(diff.SensitiveAdded, permission)
+		}
+	}
+	return diff
+}
+
+// diffStrings returns the sorted entries only present in current and only present in baseline
+func diffStrings(baseline, current []string) ([]string, []string) {
+	before, after := map[string]bool{}, map[string]bool{}
+	for _, entry := range baseline {
+		before[entry] = true
+	}
+	for _, entry := range current {
+		after[entry] = true
+	}
+
+	var added, removed []string
+	for entry := range after {
+		if !before[entry] {
+			added = append(added, entry)
+		}
+	}
+	for entry := range before {
+		if !after[entry] {
+			removed = append(removed, entry)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// readPermissionsFromConfig reads the permissions of the artifact and logs them,
+// false is returned when the artifact couldn't be read
+func readPermissionsFromConfig(artifactPath, workDir string, logger log.Logger) ([]string, bool) {
+	logger.Infof("Reading permissions...")
+	permissions, err := ReadPermissions(artifactPath, workDir)
+	if err != nil {
+		logger.Warnf("Failed to read permissions: %s", err)
+		return nil, false
+	}
+	logger.Printf("Found %d permission(s)", len(permissions))
+	return permissions, true
+}
+
+// comparePermissionsFromConfig diffs the permissions with the baseline build and warns on added sensitive permissions,
+// nil is returned if the baseline build has no recorded permissions
+func comparePermissionsFromConfig(cfg config.Config, permissions []string, baseline HistoryRecord, logger log.Logger) *PermissionDiff {
+	if baseline.Permissions == nil {
+		logger.Printf("No permissions recorded for the baseline build yet")
+		return nil
+	}
+
+	extraSensitive := ParseSensitivePermissions(cfg.SensitivePermissions)
+	diff := diffPermissions(*baseline.Permissions, permissions, extraSensitive)
+	logger.Printf("Permissions: %d added, %d removed", len(diff.Added), len(diff.Removed))
+	for _, permission := range diff.Added {
+		if IsSensitivePermission(permission, extraSensitive) {
+			logger.Warnf("+ %s (sensitive)", permission)
+		} else {
+			logger.Printf("+ %s", permission)
+		}
+	}
+	for _, permission := range diff.Removed {
+		logger.Printf("- %s", permission)
+	}
+	return &diff
+}
@@ -0,0 +1,112 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// xmlNode is an element of a test manifest
+type xmlNode struct {
+	name     string
+	attrs    [][2]string
+	children []xmlNode
+	text     string
+}
+
+// protoXML encodes the element as the XmlNode protobuf of aapt2, the format of AAB manifests
+func (n xmlNode) protoXML() []byte {
+	var element []byte
+	element = protowire.AppendTag(element, 3, protowire.BytesType)
+	element = protowire.AppendString(element, n.name)
+	for _, attr := range n.attrs {
+		var attribute []byte
+		attribute = protowire.AppendTag(attribute, 2, protowire.BytesType)
+		attribute = protowire.AppendString(attribute, attr[0])
+		attribute = protowire.AppendTag(attribute, 3, protowire.BytesType)
+		attribute = protowire.AppendString(attribute, attr[1])
+		element = protowire.AppendTag(element, 4, protowire.BytesType)
+		element = protowire.AppendBytes(element, attribute)
+	}
+	for _, child := range n.children {
+		element = protowire.AppendTag(element, 5, protowire.BytesType)
+		element = protowire.AppendBytes(element, child.protoXML())
+	}
+	if n.text != "" {
+		text := protowire.AppendTag(nil, 2, protowire.BytesType)
+		element = protowire.AppendTag(element, 5, protowire.BytesType)
+		element = protowire.AppendBytes(element, protowire.AppendString(text, n.text))
+	}
+	node := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(node, element)
+}
+
+// writeAAB writes an AAB with the manifest of the base module and the entries
+func writeAAB(t *testing.T, manifest xmlNode, entries ...zipEntry) string {
+	t.Helper()
+	artifactPath := filepath.Join(t.TempDir(), "app.aab")
+	entries = append([]zipEntry{{name: aabManifestPath, content: string(manifest.protoXML())}}, entries...)
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return artifactPath
+}
+
+func TestReadPermissions(t *testing.T) {
+	manifest := xmlNode{name: "manifest", children: []xmlNode{
+		{name: "uses-permission", attrs: [][2]string{{"name", "android.permission.INTERNET"}}},
+		{name: "uses-permission", attrs: [][2]string{{"name", "android.permission.CAMERA"}}},
+		{name: "uses-permission-sdk-23", attrs: [][2]string{{"name", "android.permission.READ_CONTACTS"}}},
+		{name: "uses-permission", attrs: [][2]string{{"name", "android.permission.INTERNET"}}},
+		{name: "uses-feature", attrs: [][2]string{{"name", "android.hardware.camera"}}},
+	}}
+
+	permissions, err := ReadPermissions(writeAAB(t, manifest), t.TempDir())
+	if err != nil {
+		t.Fatalf("ReadPermissions() error = %s", err)
+	}
+	want := []string{"android.permission.CAMERA", "android.permission.INTERNET", "android.permission.READ_CONTACTS"}
+	if !reflect.DeepEqual(permissions, want) {
+		t.Errorf("ReadPermissions() = %v, want %v", permissions, want)
+	}
+}
+
+func TestDiffPermissions(t *testing.T) {
+	baseline := []string{"android.permission.CAMERA", "android.permission.INTERNET"}
+	current := []string{"android.permission.INTERNET", "android.permission.RECORD_AUDIO", "android.permission.VIBRATE", "com.example.permission.SYNC"}
+
+	diff := diffPermissions(baseline, current, ParseSensitivePermissions("com.example.*,\n android.permission.BIND_JOB_SERVICE"))
+	want := PermissionDiff{
+		Added:          []string{"android.permission.RECORD_AUDIO", "android.permission.VIBRATE", "com.example.permission.SYNC"},
+		Removed:        []string{"android.permission.CAMERA"},
+		SensitiveAdded: []string{"android.permission.RECORD_AUDIO", "com.example.permission.SYNC"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("diffPermissions() = %+v, want %+v", diff, want)
+	}
+}
+
+func TestIsSensitivePermission(t *testing.T) {
+	tests := []struct {
+		permission string
+		extra      []string
+		want       bool
+	}{
+		{permission: "android.permission.ACCESS_FINE_LOCATION", want: true},
+		{permission: "android.permission.INTERNET"},
+		{permission: "NSCameraUsageDescription", want: true},
+		{permission: "com.apple.developer.healthkit", want: true},
+		{permission: "com.example.permission.SYNC", extra: []string{"com.example.permission.SYNC"}, want: true},
+		{permission: "com.example.permission.SYNC", extra: []string{"com.example.*"}, want: true},
+		{permission: "com.example.permission.SYNC", extra: []string{"com.example.permission"}},
+	}
+	for _, tt := range tests {
+		if got := IsSensitivePermission(tt.permission, tt.extra); got != tt.want {
+			t.Errorf("IsSensitivePermission(%s, %v) = %t, want %t", tt.permission, tt.extra, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package analyze
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// binaryPlistMagic starts the binary property lists Xcode writes into the bundles of IPAs
+var binaryPlistMagic = []byte("bplist00")
+
+// parsePlist parses an XML or binary property list. Dictionaries are decoded into map[string]interface{},
+// arrays into []interface{}, integers into int64, reals into float64, data into []byte and dates into time.Time.
+func parsePlist(data []byte) (interface{}, error) {
+	if bytes.HasPrefix(data, binaryPlistMagic) {
+		return parseBinaryPlist(data)
+	}
+	return parseXMLPlist(data)
+}
+
+// plistString returns the string value of a key of a plist dictionary, empty if missing or not a string
+func plistString(dict map[string]interface{}, key string) string {
+	value, _ := dict[key].(string)
+	return value
+}
+
+// parseXMLPlist parses the root value of an XML property list
+func parseXMLPlist(data []byte) (interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	// Property lists don't declare external entities, the DOCTYPE is skipped
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid plist: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if start.Name.Local == "plist" {
+				continue
+			}
+			return parseXMLPlistValue(decoder, start)
+		}
+	}
+}
+
+// parseXMLPlistValue parses the value of an XML element whose start element was already read
+func parseXMLPlistValue(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]interface{}{}
+		key := ""
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid plist: %w", err)
+			}
+			switch element := token.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if element.Name.Local == "key" {
+					if key, err = xmlText(decoder); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				value, err := parseXMLPlistValue(decoder, element)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = value
+			}
+		}
+	case "array":
+		var array []interface{}
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("invalid plist: %w", err)
+			}
+			switch element := token.(type) {
+			case xml.EndElement:
+				return array, nil
+			case xml.StartElement:
+				value, err := parseXMLPlistValue(decoder, element)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+		}
+	case "true", "false":
+		if err := decoder.Skip(); err != nil {
+			return nil, fmt.Errorf("invalid plist: %w", err)
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := xmlText(decoder)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string":
+		return text, nil
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	case "data":
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+	}
+	return nil, fmt.Errorf("invalid plist: unsupported element <%s>", start.Name.Local)
+}
+
+// xmlText reads the character data of the current element up to its end element
+func xmlText(decoder *xml.Decoder) (string, error) {
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("invalid plist: %w", err)
+		}
+		switch element := token.(type) {
+		case xml.CharData:
+			text.Write(element)
+		case xml.EndElement:
+			return text.String(), nil
+		}
+	}
+}
+
+// binaryPlist holds the object table of a binary property list
+type binaryPlist struct {
+	data          []byte
+	offsets       []uint64
+	objectRefSize int
+	// depth guards against reference cycles of corrupt files
+	depth int
+}
+
+// parseBinaryPlist parses the root object of a bplist00 property list
+func parseBinaryPlist(data []byte) (interface{}, error) {
+	if len(data) < len(binaryPlistMagic)+32 {
+		return nil, fmt.Errorf("invalid binary plist: too short")
+	}
+	trailer := data[len(data)-32:]
+	offsetIntSize := int(trailer[6])
+	objectRefSize := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:32])
+	if offsetIntSize == 0 || offsetIntSize > 8 || objectRefSize == 0 || objectRefSize > 8 ||
+		numObjects > uint64(len(data)) || offsetTableOffset+numObjects*uint64(offsetIntSize) > uint64(len(data)) {
+		return nil, fmt.Errorf("invalid binary plist: corrupt trailer")
+	}
+
+	plist := binaryPlist{data: data, objectRefSize: objectRefSize, offsets: make([]uint64, numObjects)}
+	for idx := range plist.offsets {
+		start := offsetTableOffset + uint64(idx*offsetIntSize)
+		plist.offsets[idx] = readBigEndian(data[start : start+uint64(offsetIntSize)])
+	}
+	return plist.object(topObject)
+}
+
+// readBigEndian reads an unsigned big-endian integer of 1 to 8 bytes
+func readBigEndian(data []byte) uint64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// object decodes the object with the reference
+func (p *binaryPlist) object(ref uint64) (interface{}, error) {
+	if ref >= uint64(len(p.offsets)) || p.offsets[ref] >= uint64(len(p.data)) {
+		return nil, fmt.Errorf("invalid binary plist: object reference out of range")
+	}
+	if p.depth > 64 {
+		return nil, fmt.Errorf("invalid binary plist: nested too deep")
+	}
+	p.depth++
+	defer func() { p.depth-- }()
+
+	offset := p.offsets[ref]
+	marker := p.data[offset]
+	kind, info := marker>>4, int(marker&0x0f)
+	switch kind {
+	case 0x0:
+		// null, false and true
+		return info == 0x9, nil
+	case 0x1:
+		size := uint64(1) << info
+		if offset+1+size > uint64(len(p.data)) {
+			return nil, fmt.Errorf("invalid binary plist: truncated integer")
+		}
+		return int64(readBigEndian(p.data[offset+1 : offset+1+size])), nil
+	case 0x2:
+		size := uint64(1) << info
+		if offset+1+size > uint64(len(p.data)) {
+			return nil, fmt.Errorf("invalid binary plist: truncated real")
+		}
+		bits := readBigEndian(p.data[offset+1 : offset+1+size])
+		if size == 4 {
+			return float64(math.Float32frombits(uint32(bits))), nil
+		}
+		return math.Float64frombits(bits), nil
+	case 0x3:
+		if offset+9 > uint64(len(p.data)) {
+			return nil, fmt.Errorf("invalid binary plist: truncated date")
+		}
+		// Seconds since 2001-01-01
+		seconds := math.Float64frombits(readBigEndian(p.data[offset+1 : offset+9]))
+		return time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(seconds * float64(time.Second))), nil
+	}
+
+	count, start, err := p.length(offset, info)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case 0x4, 0x5:
+		if start+count > uint64(len(p.data)) {
+			return nil, fmt.Errorf("invalid binary plist: truncated data")
+		}
+		if kind == 0x4 {
+			return append([]byte(nil), p.data[start:start+count]...), nil
+		}
+		return string(p.data[start : start+count]), nil
+	case 0x6:
+		if start+2*count > uint64(len(p.data)) {
+			return nil, fmt.Errorf("invalid binary plist: truncated string")
+		}
+		units := make([]uint16, count)
+		for idx := range units {
+			units[idx] = binary.BigEndian.Uint16(p.data[start+uint64(2*idx):])
+		}
+		return string(utf16.Decode(units)), nil
+	case 0xA:
+		refs, err := p.refs(start, count)
+		if err != nil {
+			return nil, err
+		}
+		array := make([]interface{}, 0, count)
+		for _, ref := range refs {
+			value, err := p.object(ref)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	case 0xD:
+		refs, err := p.refs(start, 2*count)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]interface{}, count)
+		for idx := uint64(0); idx < count; idx++ {
+			key, err := p.object(refs[idx])
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.object(refs[count+idx])
+			if err != nil {
+				return nil, err
+			}
+			keyString, _ := key.(string)
+			dict[keyString] = value
+		}
+		return dict, nil
+	}
+	return nil, fmt.Errorf("invalid binary plist: unsupported object type 0x%x", kind)
+}
+
+// length returns the element count of a data, string, array or dictionary object and the offset of its content.
+// Counts from 15 are stored in a following integer object.
+func (p *binaryPlist) length(offset uint64, info int) (uint64, uint64, error) {
+	if info != 0x0f {
+		return uint64(info), offset + 1, nil
+	}
+	if offset+2 > uint64(len(p.data)) || p.data[offset+1]>>4 != 0x1 {
+		return 0, 0, fmt.Errorf("invalid binary plist: corrupt length")
+	}
+	size := uint64(1) << (p.data[offset+1] & 0x0f)
+	if offset+2+size > uint64(len(p.data)) {
+		return 0, 0, fmt.Errorf("invalid binary plist: corrupt length")
+	}
+	return readBigEndian(p.data[offset+2 : offset+2+size]), offset + 2 + size, nil
+}
+
+// refs reads the object references of an array or dictionary
+func (p *binaryPlist) refs(start, count uint64) ([]uint64, error) {
+	size := uint64(p.objectRefSize)
+	if count > uint64(len(p.data)) || start+count*size > uint64(len(p.data)) {
+		return nil, fmt.Errorf("invalid binary plist: truncated references")
+	}
+	refs := make([]uint64, count)
+	for idx := range refs {
+		from := start + uint64(idx)*size
+		refs[idx] = readBigEndian(p.data[from : from+size])
+	}
+	return refs, nil
+}
+
+// readPlistEntry reads and parses a property list stored in the artifact
+func readPlistEntry(reader io.Reader) (map[string]interface{}, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	value, err := parsePlist(data)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid plist: the root is not a dictionary")
+	}
+	return dict, nil
+}
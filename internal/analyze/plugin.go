@@ -0,0 +1,212 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// defaultPluginSource is the repository the bundle-inspector plugin is installed from by default
+const defaultPluginSource = "https://github.com/bitrise-io/bitrise-plugins-bundle-inspector.git"
+
+// bundleInspectorVersionPattern extracts the plugin version from the `bitrise plugin list` output
+var bundleInspectorVersionPattern = regexp.MustCompile(`bundle-inspector \(([^)]+)\)`)
+
+// PluginOptions configures how the bundle-inspector plugin is installed
+type PluginOptions struct {
+	Version  string
+	Source   string
+	CacheDir string
+	// UpdatePolicy is plugin_update_policy, it only applies when no version is pinned
+	UpdatePolicy string
+	Retry        executor.RetryOptions
+}
+
+// EnvExporter exports environment variables to the following steps of the build
+type EnvExporter interface {
+	Export(key, value string) error
+}
+
+// PluginInstaller installs the bundle-inspector plugin of the Bitrise CLI
+type PluginInstaller struct {
+	cmdFactory command.Factory
+	envRepo    env.Repository
+	exporter   EnvExporter
+	logger     log.Logger
+}
+
+// NewPluginInstaller returns a PluginInstaller running the Bitrise CLI with the command factory
+func NewPluginInstaller(cmdFactory command.Factory, envRepo env.Repository, exporter EnvExporter, logger log.Logger) PluginInstaller {
+	return PluginInstaller{cmdFactory: cmdFactory, envRepo: envRepo, exporter: exporter, logger: logger}
+}
+
+// EnsureInstalled checks if bundle-inspector is installed and installs it if needed
+func (i PluginInstaller) EnsureInstalled(ctx context.Context, opts PluginOptions) error {
+	// Check if plugin is installed
+	i.logger.Infof("Checking for bundle-inspector plugin...")
+	installed, version, err := i.installedBundleInspector()
+	if err != nil {
+		return err
+	}
+
+	// Warm builds restore the plugin from the cache instead of installing it
+	restored := false
+	if !installed && opts.CacheDir != "" && !hasPluginCache(opts.CacheDir) {
+		i.logger.Printf("No cached bundle-inspector plugin found in %s", opts.CacheDir)
+	} else if !installed && opts.CacheDir != "" {
+		if err := i.restorePluginCache(opts.CacheDir); err != nil {
+			i.logger.Warnf("Failed to restore bundle-inspector plugin from cache: %s", err)
+		} else if installed, version, err = i.installedBundleInspector(); err == nil && installed {
+			restored = true
+		}
+	}
+
+	// Without a pinned version the installed plugin is upgraded according to plugin_update_policy
+	upgrade := ""
+	if installed && opts.Version == "" {
+		if upgrade, err = i.pluginUpgradeVersion(ctx, opts, version); err != nil {
+			i.logger.Warnf("Failed to check for a bundle-inspector plugin upgrade, keeping %s: %s", version, err)
+		}
+	} else if installed && opts.UpdatePolicy != "" && opts.UpdatePolicy != PluginUpdateNever {
+		i.logger.Printf("plugin_version is pinned, plugin_update_policy %s doesn't apply", opts.UpdatePolicy)
+	}
+
+	// A pinned version is only enforced when the installed version is known, a plugin of unknown version is kept
+	if installed && opts.Version != "" && version == "" {
+		i.logger.Warnf("Failed to determine the installed bundle-inspector plugin version, keeping it instead of installing plugin_version %s", opts.Version)
+	}
+
+	if installed && upgrade == "" && (opts.Version == "" || version == "" || version == opts.Version) {
+		label := version
+		if label == "" {
+			label = "unknown version"
+		}
+		if restored {
+			i.logger.Donef("bundle-inspector plugin restored from cache (%s)", label)
+		} else {
+			i.logger.Donef("bundle-inspector plugin is already installed (%s)", label)
+		}
+		return nil
+	}
+
+	// Remove the installed plugin if its version doesn't match the pinned version or it's upgraded
+	if installed {
+		if upgrade != "" {
+			i.logger.Infof("Upgrading bundle-inspector plugin from %s to %s (plugin_update_policy %s)...", version, upgrade, opts.UpdatePolicy)
+			opts.Version = upgrade
+		} else {
+			i.logger.Warnf("bundle-inspector plugin version %s doesn't match plugin_version %s, reinstalling...", version, opts.Version)
+		}
+		deleteCmd := i.cmdFactory.Create("bitrise", []string{"plugin", "delete", "bundle-inspector"}, nil)
+		i.logger.Printf("$ %s", deleteCmd.PrintableCommandArgs())
+		if out, err := deleteCmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+			if out != "" {
+				i.logger.Printf("%s", out)
+			}
+			return fmt.Errorf("failed to remove bundle-inspector plugin: %w", err)
+		}
+	} else {
+		i.logger.Warnf("bundle-inspector plugin not found, installing...")
+	}
+
+	// Plugin not installed, install it
+	source, err := resolvePluginSource(opts.Source)
+	if err != nil {
+		return err
+	}
+
+	installArgs := []string{"plugin", "install"}
+	if opts.Version != "" {
+		installArgs = append(installArgs, "--version", opts.Version)
+	}
+	installArgs = append(installArgs, source)
+	i.logger.Printf("$ %s", i.cmdFactory.Create("bitrise", installArgs, nil).PrintableCommandArgs())
+
+	installOut, err := executor.New(i.cmdFactory, i.envRepo, opts.Retry, i.logger).Run(ctx, "Plugin installation", "bitrise", installArgs, nil)
+	if err != nil {
+		if installOut != "" {
+			i.logger.Printf("%s", installOut)
+		}
+		return fmt.Errorf("failed to install bundle-inspector plugin: %w", err)
+	}
+
+	if installOut != "" {
+		i.logger.Printf("%s", installOut)
+	}
+
+	// Verify the installed version
+	if opts.Version != "" {
+		_, version, err := i.installedBundleInspector()
+		if err != nil {
+			return err
+		}
+		if version == "" {
+			i.logger.Warnf("Failed to determine the installed bundle-inspector plugin version, expected %s", opts.Version)
+		} else if version != opts.Version {
+			return fmt.Errorf("installed bundle-inspector plugin version %s doesn't match %s", version, opts.Version)
+		}
+	}
+
+	i.logger.Donef("bundle-inspector plugin installed successfully")
+
+	if opts.CacheDir != "" {
+		if err := i.savePluginCache(opts.CacheDir); err != nil {
+			i.logger.Warnf("Failed to cache bundle-inspector plugin: %s", err)
+		}
+	}
+	return nil
+}
+
+// resolvePluginSource returns the source to install the plugin from: a Git URL is used as is,
+// a local path is resolved to an absolute path and has to exist
+func resolvePluginSource(source string) (string, error) {
+	if source == "" {
+		return defaultPluginSource, nil
+	}
+	if strings.Contains(source, "://") || strings.HasPrefix(source, "git@") {
+		return source, nil
+	}
+
+	absPath, err := filepath.Abs(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve plugin_source path: %w", err)
+	}
+	if _, err := os.Stat(absPath); err != nil {
+		return "", fmt.Errorf("plugin_source path does not exist: %s", absPath)
+	}
+	return absPath, nil
+}
+
+// installedBundleInspector checks the installed plugins of the Bitrise CLI for bundle-inspector and returns its version
+func (i PluginInstaller) installedBundleInspector() (bool, string, error) {
+	return installedBundleInspector(i.cmdFactory)
+}
+
+// installedBundleInspector runs `bitrise plugin list` and returns whether bundle-inspector is installed and its version,
+// the version is empty if the output doesn't state it
+func installedBundleInspector(cmdFactory command.Factory) (bool, string, error) {
+	checkCmd := cmdFactory.Create("bitrise", []string{"plugin", "list"}, nil)
+	out, err := checkCmd.RunAndReturnTrimmedCombinedOutput()
+	if err != nil {
+		return false, "", fmt.Errorf("failed to check installed plugins: %w", err)
+	}
+
+	// Check if bundle-inspector is in the list
+	if !strings.Contains(out, "bundle-inspector") {
+		return false, "", nil
+	}
+
+	version := ""
+	if match := bundleInspectorVersionPattern.FindStringSubmatch(out); match != nil {
+		version = strings.TrimPrefix(match[1], "v")
+	}
+	return true, version, nil
+}
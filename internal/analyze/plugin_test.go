@@ -0,0 +1,257 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Export makes fakeEnvRepository an EnvExporter, the exported variables are visible to the following Get calls
+func (r fakeEnvRepository) Export(key, value string) error {
+	return r.Set(key, value)
+}
+
+// fakeBitriseCLI answers the plugin commands of the Bitrise CLI, the plugin is installed while its directory exists in
+// the plugins directory of the home directory. An installation records the requested --version, version is reported
+// for plugins installed without one. With brokenPlugin the installed plugin fails to run.
+func fakeBitriseCLI(t *testing.T, home, version string, brokenPlugin bool) func(args []string, opts *command.Opts) (string, error) {
+	pluginDir := filepath.Join(home, ".bitrise", "plugins", "bundle-inspector")
+	return func(args []string, opts *command.Opts) (string, error) {
+		switch {
+		case len(args) > 1 && args[0] == "plugin" && args[1] == "list":
+			if _, err := os.Stat(pluginDir); err != nil {
+				return "Installed plugins:\n", nil
+			}
+			installed := version
+			if recorded, err := os.ReadFile(filepath.Join(pluginDir, "version")); err == nil {
+				installed = string(recorded)
+			}
+			return "Installed plugins:\n⚡️ bundle-inspector (" + installed + ")", nil
+		case len(args) > 1 && args[0] == "plugin" && args[1] == "install":
+			if err := os.MkdirAll(pluginDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if len(args) > 3 && args[2] == "--version" {
+				if err := os.WriteFile(filepath.Join(pluginDir, "version"), []byte(args[3]), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return "", os.WriteFile(filepath.Join(pluginDir, "bundle-inspector"), []byte("plugin"), 0755)
+		case len(args) > 1 && args[0] == "plugin" && args[1] == "delete":
+			return "", os.RemoveAll(pluginDir)
+		case args[0] == ":bundle-inspector" && brokenPlugin:
+			return "exec format error", errors.New("exit status 126")
+		}
+		return "", nil
+	}
+}
+
+func TestEnsureInstalledPluginCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		cached       bool
+		installed    bool
+		brokenPlugin bool
+		wantInstall  bool
+		wantSaved    bool
+	}{
+		{name: "cold build installs and saves the plugin", wantInstall: true, wantSaved: true},
+		{name: "warm build restores the plugin", cached: true},
+		{name: "broken cache is replaced by an installation", cached: true, brokenPlugin: true, wantInstall: true, wantSaved: true},
+		{name: "installed plugin leaves the cache alone", installed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			cacheDir := filepath.Join(t.TempDir(), "plugin-cache")
+			if tt.cached {
+				if err := os.MkdirAll(filepath.Join(cacheDir, "bundle-inspector"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.installed {
+				if err := os.MkdirAll(filepath.Join(home, ".bitrise", "plugins", "bundle-inspector"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var calls []string
+			envRepo := fakeEnvRepository{"BITRISE_CACHE_INCLUDE_PATHS": "/root/.gradle"}
+			installer := NewPluginInstaller(fakeCommandFactory{run: fakeBitriseCLI(t, home, "1.4.0", tt.brokenPlugin), calls: &calls}, envRepo, envRepo, log.NewLogger())
+			if err := installer.EnsureInstalled(context.Background(), PluginOptions{CacheDir: cacheDir}); err != nil {
+				t.Fatalf("EnsureInstalled() error = %s", err)
+			}
+
+			if got := containsCallPrefix(calls, "plugin install"); got != tt.wantInstall {
+				t.Errorf("plugin installed = %t, want %t: %v", got, tt.wantInstall, calls)
+			}
+			if _, err := os.Stat(filepath.Join(home, ".bitrise", "plugins", "bundle-inspector")); err != nil {
+				t.Errorf("plugin is missing after EnsureInstalled(): %s", err)
+			}
+			_, err := os.Stat(filepath.Join(cacheDir, "bundle-inspector", "bundle-inspector"))
+			if got := err == nil; got != tt.wantSaved {
+				t.Errorf("plugin saved to cache = %t, want %t", got, tt.wantSaved)
+			}
+			wantIncludePaths := "/root/.gradle"
+			if tt.wantSaved {
+				wantIncludePaths += "\n" + cacheDir
+			}
+			if got := envRepo["BITRISE_CACHE_INCLUDE_PATHS"]; got != wantIncludePaths {
+				t.Errorf("BITRISE_CACHE_INCLUDE_PATHS = %q, want %q", got, wantIncludePaths)
+			}
+		})
+	}
+}
+
+func TestEnsureInstalledPluginVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		installed    bool
+		pinned       string
+		reported     string
+		wantCalls    []string
+		wantNotCalls []string
+	}{
+		{name: "matching version is kept", installed: true, pinned: "1.4.0", reported: "1.4.0", wantNotCalls: []string{"plugin delete", "plugin install"}},
+		{name: "v prefix of the listed version is ignored", installed: true, pinned: "1.4.0", reported: "v1.4.0", wantNotCalls: []string{"plugin delete", "plugin install"}},
+		{name: "mismatching version is reinstalled", installed: true, pinned: "1.4.0", reported: "1.3.0",
+			wantCalls: []string{"plugin delete bundle-inspector", "plugin install --version 1.4.0 " + defaultPluginSource}},
+		{name: "missing plugin is installed at the pinned version", pinned: "1.4.0", reported: "1.3.0",
+			wantCalls: []string{"plugin install --version 1.4.0 " + defaultPluginSource}, wantNotCalls: []string{"plugin delete"}},
+		{name: "unknown installed version is kept", installed: true, pinned: "1.4.0", reported: "",
+			wantNotCalls: []string{"plugin delete", "plugin install"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			if tt.installed {
+				if err := os.MkdirAll(filepath.Join(home, ".bitrise", "plugins", "bundle-inspector"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var calls []string
+			run := fakeBitriseCLI(t, home, tt.reported, false)
+			if tt.reported == "" {
+				// A plugin list without the version in parentheses
+				run = func(args []string, opts *command.Opts) (string, error) {
+					return "⚡️ bundle-inspector", nil
+				}
+			}
+			envRepo := fakeEnvRepository{}
+			installer := NewPluginInstaller(fakeCommandFactory{run: run, calls: &calls}, envRepo, envRepo, log.NewLogger())
+			if err := installer.EnsureInstalled(context.Background(), PluginOptions{Version: tt.pinned}); err != nil {
+				t.Fatalf("EnsureInstalled() error = %s", err)
+			}
+
+			for _, call := range tt.wantCalls {
+				if !containsCallPrefix(calls, call) {
+					t.Errorf("missing call %q: %v", call, calls)
+				}
+			}
+			for _, call := range tt.wantNotCalls {
+				if containsCallPrefix(calls, call) {
+					t.Errorf("unexpected call %q: %v", call, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestEnsureInstalledPluginVersionMismatch(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// The source doesn't have the pinned version and installs its latest release
+	var calls []string
+	run := func(args []string, opts *command.Opts) (string, error) {
+		if args[0] == "plugin" && args[1] == "list" && len(calls) > 1 {
+			return "⚡️ bundle-inspector (1.5.0)", nil
+		}
+		return "", nil
+	}
+	envRepo := fakeEnvRepository{}
+	installer := NewPluginInstaller(fakeCommandFactory{run: run, calls: &calls}, envRepo, envRepo, log.NewLogger())
+	err := installer.EnsureInstalled(context.Background(), PluginOptions{Version: "1.4.0"})
+	if err == nil || err.Error() != "installed bundle-inspector plugin version 1.5.0 doesn't match 1.4.0" {
+		t.Errorf("EnsureInstalled() error = %v", err)
+	}
+}
+
+func TestResolvePluginSource(t *testing.T) {
+	localDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(localDir, "bundle-inspector"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relDir, err := filepath.Rel(workDir, localDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		want    string
+		wantErr string
+	}{
+		{name: "default", want: defaultPluginSource},
+		{name: "mirror URL", source: "https://git.example.com/mirrors/bundle-inspector.git", want: "https://git.example.com/mirrors/bundle-inspector.git"},
+		{name: "SSH URL", source: "git@git.example.com:mirrors/bundle-inspector.git", want: "git@git.example.com:mirrors/bundle-inspector.git"},
+		{name: "relative path", source: filepath.Join(relDir, "bundle-inspector"), want: filepath.Join(localDir, "bundle-inspector")},
+		{name: "absolute path", source: filepath.Join(localDir, "bundle-inspector"), want: filepath.Join(localDir, "bundle-inspector")},
+		{name: "missing path", source: filepath.Join(relDir, "missing"), wantErr: "plugin_source path does not exist: " + filepath.Join(localDir, "missing")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvePluginSource(tt.source)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("resolvePluginSource() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolvePluginSource() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolvePluginSource() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsureInstalledPluginSource(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	source := t.TempDir()
+
+	var calls []string
+	envRepo := fakeEnvRepository{}
+	installer := NewPluginInstaller(fakeCommandFactory{run: fakeBitriseCLI(t, home, "1.4.0", false), calls: &calls}, envRepo, envRepo, log.NewLogger())
+	if err := installer.EnsureInstalled(context.Background(), PluginOptions{Source: source, UpdatePolicy: PluginUpdateAlways}); err != nil {
+		t.Fatalf("EnsureInstalled() error = %s", err)
+	}
+	if !containsCallPrefix(calls, "plugin install "+source) {
+		t.Errorf("plugin not installed from %s: %v", source, calls)
+	}
+
+	// The installed plugin of a local source is never upgraded, the releases can't be listed
+	calls = nil
+	if err := installer.EnsureInstalled(context.Background(), PluginOptions{Source: source, UpdatePolicy: PluginUpdateAlways}); err != nil {
+		t.Fatalf("EnsureInstalled() error = %s", err)
+	}
+	if len(calls) != 1 || calls[0] != "plugin list" {
+		t.Errorf("installed plugin was not kept: %v", calls)
+	}
+}
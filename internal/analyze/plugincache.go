@@ -0,0 +1,120 @@
+package analyze
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// bitrisePluginsDir returns the directory the Bitrise CLI installs plugins into
+func bitrisePluginsDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".bitrise", "plugins"), nil
+}
+
+// hasPluginCache checks whether the cache directory holds a saved bundle-inspector plugin
+func hasPluginCache(cacheDir string) bool {
+	_, err := os.Stat(filepath.Join(cacheDir, "bundle-inspector"))
+	return err == nil
+}
+
+// restorePluginCache copies the cached plugins directory back into the Bitrise CLI's plugins directory
+// and verifies that the restored plugin binary runs
+func (i PluginInstaller) restorePluginCache(cacheDir string) error {
+	pluginsDir, err := bitrisePluginsDir()
+	if err != nil {
+		return err
+	}
+
+	i.logger.Printf("Restoring bundle-inspector plugin from cache: %s", cacheDir)
+	if err := copyDir(cacheDir, pluginsDir); err != nil {
+		return fmt.Errorf("failed to restore plugin cache: %w", err)
+	}
+
+	verifyCmd := i.cmdFactory.Create("bitrise", []string{":bundle-inspector", "--help"}, nil)
+	if out, err := verifyCmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		if out != "" {
+			i.logger.Printf("%s", out)
+		}
+		return fmt.Errorf("restored bundle-inspector plugin does not run: %w", err)
+	}
+	return nil
+}
+
+// savePluginCache copies the Bitrise CLI's plugins directory into the cache directory
+// and adds it to the cache paths of the legacy Cache:Push step
+func (i PluginInstaller) savePluginCache(cacheDir string) error {
+	pluginsDir, err := bitrisePluginsDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return fmt.Errorf("failed to clean plugin cache: %w", err)
+	}
+	if err := copyDir(pluginsDir, cacheDir); err != nil {
+		return fmt.Errorf("failed to save plugin cache: %w", err)
+	}
+
+	if err := AddCacheIncludePath(cacheDir, i.envRepo, i.exporter); err != nil {
+		i.logger.Warnf("Failed to add plugin cache to BITRISE_CACHE_INCLUDE_PATHS: %s", err)
+	}
+
+	i.logger.Printf("Saved bundle-inspector plugin to cache: %s", cacheDir)
+	return nil
+}
+
+// copyDir recursively copies a directory, preserving file modes and symlinks
+func copyDir(srcDir, dstDir string) error {
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dstDir, relPath)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(dstPath, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_ = os.Remove(dstPath)
+			return os.Symlink(target, dstPath)
+		default:
+			return copyFileWithMode(path, dstPath, info.Mode().Perm())
+		}
+	})
+}
+
+// copyFileWithMode copies a single file and sets its permissions
+func copyFileWithMode(srcPath, dstPath string, mode os.FileMode) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dstPath, mode)
+}
@@ -0,0 +1,73 @@
+package analyze
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Update policies of plugin_update_policy
+const (
+	// PluginUpdateNever keeps the installed plugin, it's only installed if absent
+	PluginUpdateNever = "never"
+	// PluginUpdateMinor upgrades the installed plugin to the latest release of its major version
+	PluginUpdateMinor = "minor"
+	// PluginUpdateAlways upgrades the installed plugin to the latest release
+	PluginUpdateAlways = "always"
+)
+
+// releaseTagPattern matches the release tags of the plugin repository in the `git ls-remote --tags` output
+var releaseTagPattern = regexp.MustCompile(`(?m)refs/tags/v?(\d+\.\d+\.\d+)$`)
+
+// pluginUpgradeVersion returns the release the installed plugin version is upgraded to with the update policy,
+// empty if it's kept. The releases are the tags of the plugin source, local sources are never upgraded.
+func (i PluginInstaller) pluginUpgradeVersion(ctx context.Context, opts PluginOptions, installed string) (string, error) {
+	if opts.UpdatePolicy == "" || opts.UpdatePolicy == PluginUpdateNever {
+		return "", nil
+	}
+	if installed == "" && opts.UpdatePolicy == PluginUpdateMinor {
+		i.logger.Printf("The installed bundle-inspector plugin version is unknown, plugin_update_policy %s doesn't apply", opts.UpdatePolicy)
+		return "", nil
+	}
+	source, err := resolvePluginSource(opts.Source)
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(source, "://") && !strings.HasPrefix(source, "git@") {
+		i.logger.Printf("plugin_source is a local path, plugin_update_policy %s doesn't apply", opts.UpdatePolicy)
+		return "", nil
+	}
+
+	args := []string{"ls-remote", "--tags", "--refs", source}
+	i.logger.Printf("$ %s", i.cmdFactory.Create("git", args, nil).PrintableCommandArgs())
+	out, err := executor.New(i.cmdFactory, i.envRepo, opts.Retry, i.logger).Run(ctx, "Plugin version check", "git", args, nil)
+	if err != nil {
+		if out != "" {
+			return "", fmt.Errorf("failed to list plugin releases: %w: %s", err, out)
+		}
+		return "", fmt.Errorf("failed to list plugin releases: %w", err)
+	}
+
+	installedMajor, _, _ := strings.Cut(installed, ".")
+	latest := ""
+	for _, match := range releaseTagPattern.FindAllStringSubmatch(out, -1) {
+		release := match[1]
+		if major, _, _ := strings.Cut(release, "."); opts.UpdatePolicy == PluginUpdateMinor && major != installedMajor {
+			continue
+		}
+		if latest == "" || compareVersions(release, latest) > 0 {
+			latest = release
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no release of the plugin found in %s", source)
+	}
+	if installed != "" && compareVersions(latest, installed) <= 0 {
+		i.logger.Printf("bundle-inspector plugin %s is the latest release allowed by plugin_update_policy %s", installed, opts.UpdatePolicy)
+		return "", nil
+	}
+	return latest, nil
+}
@@ -0,0 +1,72 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestEnsureInstalledUpdatePolicy(t *testing.T) {
+	tags := "a1\trefs/tags/v1.4.0\nb2\trefs/tags/1.5.2\nc3\trefs/tags/v1.5.10-beta\nd4\trefs/tags/v2.0.0\n"
+	tests := []struct {
+		name         string
+		policy       string
+		installed    string
+		pinned       string
+		listErr      bool
+		wantCalls    []string
+		wantNotCalls []string
+	}{
+		{name: "never keeps the plugin", policy: PluginUpdateNever, installed: "1.4.0", wantNotCalls: []string{"ls-remote", "plugin install"}},
+		{name: "minor upgrades within the major version", policy: PluginUpdateMinor, installed: "1.4.0",
+			wantCalls: []string{"ls-remote --tags --refs " + defaultPluginSource, "plugin delete bundle-inspector", "plugin install --version 1.5.2 " + defaultPluginSource}},
+		{name: "always upgrades to the latest release", policy: PluginUpdateAlways, installed: "1.4.0",
+			wantCalls: []string{"plugin delete bundle-inspector", "plugin install --version 2.0.0 " + defaultPluginSource}},
+		{name: "latest release is kept", policy: PluginUpdateMinor, installed: "1.5.2", wantCalls: []string{"ls-remote"}, wantNotCalls: []string{"plugin delete", "plugin install"}},
+		{name: "pinned version wins", policy: PluginUpdateAlways, installed: "1.4.0", pinned: "1.4.0", wantNotCalls: []string{"ls-remote", "plugin install"}},
+		{name: "failed release listing keeps the plugin", policy: PluginUpdateAlways, installed: "1.4.0", listErr: true,
+			wantCalls: []string{"ls-remote"}, wantNotCalls: []string{"plugin delete", "plugin install"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			if err := os.MkdirAll(filepath.Join(home, ".bitrise", "plugins", "bundle-inspector"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			var calls []string
+			bitrise := fakeBitriseCLI(t, home, tt.installed, false)
+			run := func(args []string, opts *command.Opts) (string, error) {
+				if args[0] != "ls-remote" {
+					return bitrise(args, opts)
+				}
+				if tt.listErr {
+					return "fatal: unable to access", errors.New("exit status 128")
+				}
+				return tags, nil
+			}
+			envRepo := fakeEnvRepository{}
+			installer := NewPluginInstaller(fakeCommandFactory{run: run, calls: &calls}, envRepo, envRepo, log.NewLogger())
+			if err := installer.EnsureInstalled(context.Background(), PluginOptions{Version: tt.pinned, UpdatePolicy: tt.policy}); err != nil {
+				t.Fatalf("EnsureInstalled() error = %s", err)
+			}
+
+			for _, call := range tt.wantCalls {
+				if !containsCallPrefix(calls, call) {
+					t.Errorf("missing call %q: %v", call, calls)
+				}
+			}
+			for _, call := range tt.wantNotCalls {
+				if containsCallPrefix(calls, call) {
+					t.Errorf("unexpected call %q: %v", call, calls)
+				}
+			}
+		})
+	}
+}
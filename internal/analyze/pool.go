@@ -0,0 +1,48 @@
+package analyze
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// defaultConcurrency is the number of artifacts analyzed at the same time when analysis_concurrency is not set
+const defaultConcurrency = 2
+
+// ParseConcurrency parses analysis_concurrency, invalid values fall back to the default
+func ParseConcurrency(cfg config.Config, logger log.Logger) int {
+	if cfg.AnalysisConcurrency == "" {
+		return defaultConcurrency
+	}
+	concurrency, err := strconv.Atoi(cfg.AnalysisConcurrency)
+	if err != nil || concurrency < 1 {
+		logger.Warnf("Invalid analysis_concurrency value: %s", cfg.AnalysisConcurrency)
+		return defaultConcurrency
+	}
+	return concurrency
+}
+
+// RunConcurrently calls run for every index below count, at most concurrency calls run at the same time.
+// Indexes are started in order and RunConcurrently returns once every call has finished.
+func RunConcurrently(concurrency, count int, run func(idx int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for idx := 0; idx < count; idx++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(idx int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			run(idx)
+		}(idx)
+	}
+	wg.Wait()
+}
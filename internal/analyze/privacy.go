@@ -0,0 +1,366 @@
+package analyze
+
+import (
+	"archive/zip"
+	"debug/macho"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// privacyManifestName is the file name of privacy manifests
+const privacyManifestName = "PrivacyInfo.xcprivacy"
+
+// codeBundlePattern matches the Info.plist of the app, its app extensions and embedded frameworks
+var codeBundlePattern = regexp.MustCompile(`^(Payload/[^/]+\.app(?:/(?:Frameworks|PlugIns)/[^/]+\.(?:framework|appex))?)/Info\.plist$`)
+
+// requiredReasonAPI is a category of APIs which can only be used for the reasons declared in the privacy manifest
+type requiredReasonAPI struct {
+	category string
+	// symbols are the functions, constants and classes of the category imported by the executable
+	symbols []string
+	// selectors are the Objective-C methods of the category called by the executable
+	selectors []string
+	reasons   []string
+}
+
+// requiredReasonAPIs are the required reason API categories with their approved reasons, see
+// https://developer.apple.com/documentation/bundleresources/describing-use-of-required-reason-api
+var requiredReasonAPIs = []requiredReasonAPI{
+	{
+		category: "NSPrivacyAccessedAPICategoryFileTimestamp",
+		symbols: []string{"_stat", "_fstat", "_fstatat", "_lstat", "_getattrlist", "_fgetattrlist", "_getattrlistat",
+			"_getattrlistbulk", "_NSFileCreationDate", "_NSFileModificationDate", "_NSURLContentModificationDateKey",
+			"_NSURLCreationDateKey"},
+		reasons: []string{"DDA9.1", "C617.1", "3B52.1", "0A2A.1"},
+	},
+	{
+		category:  "NSPrivacyAccessedAPICategorySystemBootTime",
+		symbols:   []string{"_mach_absolute_time"},
+		selectors: []string{"systemUptime"},
+		reasons:   []string{"35F9.1", "8FFB.1", "3D61.1"},
+	},
+	{
+		category: "NSPrivacyAccessedAPICategoryDiskSpace",
+		symbols: []string{"_statfs", "_statvfs", "_fstatfs", "_fstatvfs", "_NSFileSystemFreeSize", "_NSFileSystemSize",
+			"_NSURLVolumeAvailableCapacityKey", "_NSURLVolumeAvailableCapacityForImportantUsageKey",
+			"_NSURLVolumeAvailableCapacityForOpportunisticUsageKey", "_NSURLVolumeTotalCapacityKey"},
+		reasons: []string{"85F4.1", "E174.1", "7D9E.1", "B728.1"},
+	},
+	{
+		category:  "NSPrivacyAccessedAPICategoryActiveKeyboards",
+		selectors: []string{"activeInputModes"},
+		reasons:   []string{"3EC4.1", "54BD.1"},
+	},
+	{
+		category: "NSPrivacyAccessedAPICategoryUserDefaults",
+		symbols:  []string{"_OBJC_CLASS_$_NSUserDefaults"},
+		reasons:  []string{"CA92.1", "1C8F.1", "C56D.1", "AC6B.1"},
+	},
+}
+
+// privacyManifestSDKs are the commonly used SDKs Apple requires a privacy manifest from, see
+// https://developer.apple.com/support/third-party-SDK-requirements/
+var privacyManifestSDKs = map[string]bool{
+	"Abseil": true, "AFNetworking": true, "Alamofire": true, "AppAuth": true, "BoringSSL": true, "openssl_grpc": true,
+	"Capacitor": true, "Charts": true, "connectivity_plus": true, "Cordova": true, "device_info_plus": true,
+	"DKImagePickerController": true, "DKPhotoGallery": true, "FBAEMKit": true, "FBLPromises": true,
+	"FBSDKCoreKit": true, "FBSDKCoreKit_Basics": true, "FBSDKLoginKit": true, "FBSDKShareKit": true,
+	"file_picker": true, "FirebaseABTesting": true, "FirebaseAuth": true, "FirebaseCore": true,
+	"FirebaseCoreDiagnostics": true, "FirebaseCoreExtension": true, "FirebaseCoreInternal": true,
+	"FirebaseCrashlytics": true, "FirebaseDynamicLinks": true, "FirebaseFirestore": true,
+	"FirebaseInstallations": true, "FirebaseMessaging": true, "FirebaseRemoteConfig": true, "Flutter": true,
+	"flutter_inappwebview": true, "flutter_local_notifications": true, "fluttertoast": true, "FMDB": true,
+	"geolocator_apple": true, "GoogleDataTransport": true, "GoogleSignIn": true, "GoogleToolboxForMac": true,
+	"GoogleUtilities": true, "grpcpp": true, "GTMAppAuth": true, "GTMSessionFetcher": true, "hermes": true,
+	"image_picker_ios": true, "IQKeyboardManager": true, "IQKeyboardManagerSwift": true, "Kingfisher": true,
+	"leveldb": true, "Lottie": true, "MBProgressHUD": true, "nanopb": true, "OneSignal": true,
+	"OneSignalCore": true, "OneSignalExtension": true, "OneSignalOutcomes": true, "OpenSSL": true,
+	"OrderedSet": true, "package_info": true, "package_info_plus": true, "path_provider": true,
+	"path_provider_ios": true, "Promises": true, "Protobuf": true, "Reachability": true, "RealmSwift": true,
+	"RxCocoa": true, "RxRelay": true, "RxSwift": true, "SDWebImage": true, "share_plus": true,
+	"shared_preferences_ios": true, "SnapKit": true, "sqflite": true, "Starscream": true, "SVProgressHUD": true,
+	"SwiftyGif": true, "SwiftyJSON": true, "Toast": true, "UnityFramework": true, "url_launcher": true,
+	"url_launcher_ios": true, "video_player_avfoundation": true, "wakelock": true, "webview_flutter_wkwebview": true,
+}
+
+// PrivacyFinding is a problem of the privacy manifests of an IPA
+type PrivacyFinding struct {
+	// Bundle is the app, app extension or framework the finding is about, e.g. Alamofire.framework
+	Bundle string
+	Issue  string
+}
+
+// PrivacyReport is the result of the privacy manifest validation
+type PrivacyReport struct {
+	// Manifests are the paths of the privacy manifests found in the IPA
+	Manifests []string
+	Findings  []PrivacyFinding
+}
+
+// codeBundle is the app, an app extension or an embedded framework, with the privacy manifests of its resources
+type codeBundle struct {
+	dir        string
+	executable string
+	// declared maps the declared API categories to their reasons
+	declared    map[string][]string
+	hasManifest bool
+}
+
+// ValidatePrivacyManifests checks the privacy manifests of the app, its app extensions and embedded frameworks:
+// the required reason APIs imported by each executable have to be declared with an approved reason, and the SDKs
+// on Apple's list have to ship a manifest
+func ValidatePrivacyManifests(artifactPath, workDir string) (PrivacyReport, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return PrivacyReport{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	bundles := map[string]*codeBundle{}
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+		match := codeBundlePattern.FindStringSubmatch(file.Name)
+		if match == nil {
+			continue
+		}
+		bundle := &codeBundle{dir: match[1], declared: map[string][]string{}}
+		if entry, err := file.Open(); err == nil {
+			if info, err := readPlistEntry(entry); err == nil {
+				bundle.executable = plistString(info, "CFBundleExecutable")
+			}
+			_ = entry.Close()
+		}
+		if bundle.executable == "" {
+			bundle.executable = strings.TrimSuffix(path.Base(bundle.dir), path.Ext(bundle.dir))
+		}
+		bundles[bundle.dir] = bundle
+	}
+	if len(bundles) == 0 {
+		return PrivacyReport{}, fmt.Errorf("no app bundle found in the IPA")
+	}
+
+	var report PrivacyReport
+	// Static SDKs ship their manifest in a resource bundle of the app, e.g. Alamofire_Privacy.bundle
+	manifestBundles := map[string]bool{}
+	for _, file := range reader.File {
+		if path.Base(file.Name) != privacyManifestName {
+			continue
+		}
+		owner := owningBundle(bundles, path.Dir(file.Name))
+		if owner == nil {
+			continue
+		}
+		report.Manifests = append(report.Manifests, file.Name)
+		owner.hasManifest = owner.hasManifest || path.Dir(file.Name) == owner.dir
+		if dir := path.Base(path.Dir(file.Name)); strings.HasSuffix(dir, ".bundle") {
+			name := strings.TrimSuffix(dir, ".bundle")
+			name = strings.TrimSuffix(strings.TrimSuffix(name, "_Privacy"), "Privacy")
+			manifestBundles[name] = true
+		}
+
+		entry, err := file.Open()
+		if err != nil {
+			return PrivacyReport{}, fmt.Errorf("failed to open %s: %w", file.Name, err)
+		}
+		manifest, err := readPlistEntry(entry)
+		_ = entry.Close()
+		if err != nil {
+			report.Findings = append(report.Findings, PrivacyFinding{Bundle: path.Base(owner.dir), Issue: fmt.Sprintf("%s is invalid: %s", file.Name, err)})
+			continue
+		}
+		report.Findings = append(report.Findings, validatePrivacyManifest(path.Base(owner.dir), manifest, owner.declared)...)
+	}
+
+	dirs := make([]string, 0, len(bundles))
+	for dir := range bundles {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		bundle := bundles[dir]
+		name := path.Base(dir)
+		sdk := strings.TrimSuffix(name, ".framework")
+		isFramework := strings.HasSuffix(name, ".framework")
+
+		if executable, ok := files[dir+"/"+bundle.executable]; ok {
+			used, err := executableRequiredReasonAPIs(executable, workDir)
+			if err != nil {
+				return PrivacyReport{}, err
+			}
+			for _, category := range used {
+				if _, declared := bundle.declared[category]; !declared {
+					report.Findings = append(report.Findings, PrivacyFinding{Bundle: name, Issue: fmt.Sprintf("uses %s APIs without declaring a reason", category)})
+				}
+			}
+		}
+
+		switch {
+		case !isFramework && !bundle.hasManifest && !strings.Contains(dir, "/PlugIns/"):
+			report.Findings = append(report.Findings, PrivacyFinding{Bundle: name, Issue: "the app has no " + privacyManifestName})
+		case isFramework && privacyManifestSDKs[sdk] && !bundle.hasManifest && len(bundle.declared) == 0 && !manifestBundles[sdk]:
+			report.Findings = append(report.Findings, PrivacyFinding{Bundle: name, Issue: "Apple requires a privacy manifest from this SDK, but it ships without one"})
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Bundle < report.Findings[j].Bundle
+	})
+	return report, nil
+}
+
+// owningBundle returns the innermost app, app extension or framework containing the directory
+func owningBundle(bundles map[string]*codeBundle, dir string) *codeBundle {
+	for ; dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if bundle, ok := bundles[dir]; ok {
+			return bundle
+		}
+	}
+	return nil
+}
+
+// validatePrivacyManifest checks the reasons of the accessed API types of a privacy manifest and collects
+// the declared categories
+func validatePrivacyManifest(bundle string, manifest map[string]interface{}, declared map[string][]string) []PrivacyFinding {
+	var findings []PrivacyFinding
+	accessed, _ := manifest["NSPrivacyAccessedAPITypes"].([]interface{})
+	for _, item := range accessed {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		category := plistString(entry, "NSPrivacyAccessedAPIType")
+		var reasons []string
+		values, _ := entry["NSPrivacyAccessedAPITypeReasons"].([]interface{})
+		for _, value := range values {
+			if reason, ok := value.(string); ok {
+				reasons = append(reasons, reason)
+			}
+		}
+		declared[category] = append(declared[category], reasons...)
+
+		api, known := requiredReasonAPIByCategory(category)
+		switch {
+		case !known:
+			findings = append(findings, PrivacyFinding{Bundle: bundle, Issue: fmt.Sprintf("declares the unknown API category %q", category)})
+		case len(reasons) == 0:
+			findings = append(findings, PrivacyFinding{Bundle: bundle, Issue: fmt.Sprintf("declares %s without a reason", category)})
+		default:
+			for _, reason := range reasons {
+				if !containsString(api.reasons, reason) {
+					findings = append(findings, PrivacyFinding{Bundle: bundle, Issue: fmt.Sprintf("%s isn't an approved reason for %s", reason, category)})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// requiredReasonAPIByCategory returns the required reason API category with the name
+func requiredReasonAPIByCategory(category string) (requiredReasonAPI, bool) {
+	for _, api := range requiredReasonAPIs {
+		if api.category == category {
+			return api, true
+		}
+	}
+	return requiredReasonAPI{}, false
+}
+
+// containsString checks whether the list contains the value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// executableRequiredReasonAPIs returns the required reason API categories an executable uses, from the symbols
+// it imports and the Objective-C selectors it calls
+func executableRequiredReasonAPIs(executable *zip.File, workDir string) ([]string, error) {
+	extracted, err := extractZipEntry(executable, workDir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(extracted.Name()) }()
+	defer extracted.Close()
+
+	var file *macho.File
+	fat, err := macho.NewFatFile(extracted)
+	switch {
+	case err == nil:
+		if len(fat.Arches) == 0 {
+			return nil, nil
+		}
+		file = fat.Arches[0].File
+	case errors.Is(err, macho.ErrNotFat):
+		if file, err = macho.NewFile(extracted); err != nil {
+			// Scripts and resources named like the executable aren't Mach-O files
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	imported := map[string]bool{}
+	if symbols, err := file.ImportedSymbols(); err == nil {
+		for _, symbol := range symbols {
+			imported[symbol] = true
+		}
+	}
+	selectors := map[string]bool{}
+	if section := file.Section("__objc_methname"); section != nil {
+		if data, err := section.Data(); err == nil {
+			for _, name := range strings.Split(string(data), "\x00") {
+				selectors[name] = true
+			}
+		}
+	}
+
+	var used []string
+	for _, api := range requiredReasonAPIs {
+		found := false
+		for _, symbol := range api.symbols {
+			found = found || imported[symbol]
+		}
+		for _, selector := range api.selectors {
+			found = found || selectors[selector]
+		}
+		if found {
+			used = append(used, api.category)
+		}
+	}
+	return used, nil
+}
+
+// validatePrivacyManifestsFromConfig validates the privacy manifests of an IPA and logs the findings,
+// false is returned when the artifact couldn't be checked
+func validatePrivacyManifestsFromConfig(artifactPath, workDir string, logger log.Logger) (PrivacyReport, bool) {
+	if !strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		logger.Warnf("Privacy manifest validation only supports IPA artifacts")
+		return PrivacyReport{}, false
+	}
+
+	logger.Infof("Validating privacy manifests...")
+	report, err := ValidatePrivacyManifests(artifactPath, workDir)
+	if err != nil {
+		logger.Warnf("Failed to validate privacy manifests: %s", err)
+		return PrivacyReport{}, false
+	}
+	logger.Printf("Found %d privacy manifest(s)", len(report.Manifests))
+	for _, finding := range report.Findings {
+		logger.Printf("- %s: %s", finding.Bundle, finding.Issue)
+	}
+	if len(report.Findings) == 0 {
+		logger.Donef("Privacy manifests are complete")
+	}
+	return report, true
+}
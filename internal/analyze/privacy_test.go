@@ -0,0 +1,105 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// infoPlist is the Info.plist of a bundle with the executable name
+func infoPlist(executable string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>CFBundleExecutable</key><string>%s</string></dict></plist>`, executable)
+}
+
+// privacyManifest is a privacy manifest declaring the API category with the reasons
+func privacyManifest(category string, reasons ...string) string {
+	var values string
+	for _, reason := range reasons {
+		values += "<string>" + reason + "</string>"
+	}
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>NSPrivacyAccessedAPITypes</key><array><dict>
+<key>NSPrivacyAccessedAPIType</key><string>%s</string>
+<key>NSPrivacyAccessedAPITypeReasons</key><array>%s</array>
+</dict></array></dict></plist>`, category, values)
+}
+
+func TestValidatePrivacyManifests(t *testing.T) {
+	app := []zipEntry{
+		{name: "Payload/Test.app/Info.plist", content: infoPlist("Test")},
+		{name: "Payload/Test.app/Test", content: "not a Mach-O"},
+	}
+	alamofire := []zipEntry{
+		{name: "Payload/Test.app/Frameworks/Alamofire.framework/Info.plist", content: infoPlist("Alamofire")},
+	}
+	appManifest := zipEntry{name: "Payload/Test.app/PrivacyInfo.xcprivacy", content: privacyManifest("NSPrivacyAccessedAPICategoryUserDefaults", "CA92.1")}
+
+	tests := []struct {
+		name          string
+		entries       []zipEntry
+		wantManifests int
+		wantFindings  []string
+	}{
+		{name: "complete manifest", entries: append(app, appManifest), wantManifests: 1},
+		{name: "missing app manifest", entries: app, wantFindings: []string{"Test.app: the app has no PrivacyInfo.xcprivacy"}},
+		{name: "unapproved reason", entries: append(app, zipEntry{name: appManifest.name, content: privacyManifest("NSPrivacyAccessedAPICategoryUserDefaults", "35F9.1")}),
+			wantManifests: 1, wantFindings: []string{"Test.app: 35F9.1 isn't an approved reason for NSPrivacyAccessedAPICategoryUserDefaults"}},
+		{name: "missing reason", entries: append(app, zipEntry{name: appManifest.name, content: privacyManifest("NSPrivacyAccessedAPICategoryDiskSpace")}),
+			wantManifests: 1, wantFindings: []string{"Test.app: declares NSPrivacyAccessedAPICategoryDiskSpace without a reason"}},
+		{name: "unknown category", entries: append(app, zipEntry{name: appManifest.name, content: privacyManifest("NSPrivacyAccessedAPICategoryCamera", "CA92.1")}),
+			wantManifests: 1, wantFindings: []string{`Test.app: declares the unknown API category "NSPrivacyAccessedAPICategoryCamera"`}},
+		{name: "invalid manifest", entries: append(app, zipEntry{name: appManifest.name, content: "<plist><dict>"}),
+			wantManifests: 1, wantFindings: []string{"Test.app: Payload/Test.app/PrivacyInfo.xcprivacy is invalid"}},
+		{name: "listed SDK without manifest", entries: append(append(app, appManifest), alamofire...),
+			wantManifests: 1, wantFindings: []string{"Alamofire.framework: Apple requires a privacy manifest from this SDK, but it ships without one"}},
+		{name: "listed SDK with manifest", entries: append(append(app, appManifest), append(alamofire,
+			zipEntry{name: "Payload/Test.app/Frameworks/Alamofire.framework/PrivacyInfo.xcprivacy", content: privacyManifest("NSPrivacyAccessedAPICategoryFileTimestamp", "C617.1")})...),
+			wantManifests: 2},
+		// Static SDKs ship their manifest in a resource bundle of the app
+		{name: "listed SDK with resource bundle", entries: append(append(app, appManifest), append(alamofire,
+			zipEntry{name: "Payload/Test.app/Alamofire_Privacy.bundle/PrivacyInfo.xcprivacy", content: privacyManifest("NSPrivacyAccessedAPICategoryFileTimestamp", "C617.1")})...),
+			wantManifests: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+			if err := os.WriteFile(artifactPath, writeZip(t, tt.entries, zip.Deflate), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := ValidatePrivacyManifests(artifactPath, t.TempDir())
+			if err != nil {
+				t.Fatalf("ValidatePrivacyManifests() error = %s", err)
+			}
+			if len(report.Manifests) != tt.wantManifests {
+				t.Errorf("Manifests = %v, want %d", report.Manifests, tt.wantManifests)
+			}
+			var findings []string
+			for _, finding := range report.Findings {
+				findings = append(findings, finding.Bundle+": "+finding.Issue)
+			}
+			if len(findings) != len(tt.wantFindings) {
+				t.Fatalf("Findings = %q, want %q", findings, tt.wantFindings)
+			}
+			for idx, want := range tt.wantFindings {
+				if !strings.HasPrefix(findings[idx], want) {
+					t.Errorf("Findings[%d] = %q, want %q", idx, findings[idx], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePrivacyManifestsWithoutApp(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{{name: "Payload/readme.txt", content: "empty"}}, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidatePrivacyManifests(artifactPath, t.TempDir()); err == nil || err.Error() != "no app bundle found in the IPA" {
+		t.Errorf("ValidatePrivacyManifests() error = %v", err)
+	}
+}
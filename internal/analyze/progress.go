@@ -0,0 +1,83 @@
+package analyze
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// progressInterval is the time between the progress lines of a long-running operation
+const progressInterval = 30 * time.Second
+
+// progress logs the state of a long-running operation periodically, so a slow analysis doesn't look like a hung step.
+// Without totals only the elapsed time is logged, with totals the processed entries, scanned bytes and an ETA too.
+type progress struct {
+	label        string
+	totalEntries int64
+	totalBytes   int64
+	entries      atomic.Int64
+	bytes        atomic.Int64
+	start        time.Time
+	logger       log.Logger
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+// startProgress starts logging the progress of the operation every progressInterval until stop is called
+func startProgress(label string, totalEntries, totalBytes int64, logger log.Logger) *progress {
+	p := &progress{
+		label:        label,
+		totalEntries: totalEntries,
+		totalBytes:   totalBytes,
+		start:        time.Now(),
+		logger:       logger,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.logger.Printf("%s", p.line())
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// add records processed entries and their bytes, it is safe for concurrent use
+func (p *progress) add(entries, bytes int64) {
+	p.entries.Add(entries)
+	p.bytes.Add(bytes)
+}
+
+// finish stops the progress lines, operations shorter than progressInterval don't log any
+func (p *progress) finish() {
+	close(p.stop)
+	<-p.done
+}
+
+// line formats the current state of the operation
+func (p *progress) line() string {
+	elapsed := time.Since(p.start)
+	if p.totalEntries == 0 && p.totalBytes == 0 {
+		return fmt.Sprintf("%s: still running, %s elapsed", p.label, elapsed.Round(time.Second))
+	}
+
+	entries, bytes := p.entries.Load(), p.bytes.Load()
+	line := fmt.Sprintf("%s: %d/%d entries, %.1f/%.1f MB scanned, %s elapsed", p.label, entries, p.totalEntries,
+		float64(bytes)/(1024*1024), float64(p.totalBytes)/(1024*1024), elapsed.Round(time.Second))
+	if bytes > 0 && bytes < p.totalBytes {
+		eta := time.Duration(float64(elapsed) * float64(p.totalBytes-bytes) / float64(bytes))
+		line += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+	return line
+}
@@ -0,0 +1,52 @@
+package analyze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		totalEntries int64
+		totalBytes   int64
+		entries      int64
+		bytes        int64
+		want         string
+	}{
+		{name: "without totals", want: "Test: still running, 1m0s elapsed"},
+		{name: "nothing scanned", totalEntries: 10, totalBytes: 4 * 1024 * 1024,
+			want: "Test: 0/10 entries, 0.0/4.0 MB scanned, 1m0s elapsed"},
+		{name: "with ETA", totalEntries: 10, totalBytes: 4 * 1024 * 1024, entries: 3, bytes: 1024 * 1024,
+			want: "Test: 3/10 entries, 1.0/4.0 MB scanned, 1m0s elapsed, ETA 3m0s"},
+		{name: "everything scanned", totalEntries: 10, totalBytes: 4 * 1024 * 1024, entries: 10, bytes: 4 * 1024 * 1024,
+			want: "Test: 10/10 entries, 4.0/4.0 MB scanned, 1m0s elapsed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &progress{label: "Test", totalEntries: tt.totalEntries, totalBytes: tt.totalBytes, start: time.Now().Add(-time.Minute)}
+			p.add(tt.entries, tt.bytes)
+			if got := p.line(); got != tt.want {
+				t.Errorf("line() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressFinish(t *testing.T) {
+	p := startProgress("Test", 1, 1, log.NewLogger())
+	p.add(1, 1)
+
+	finished := make(chan struct{})
+	go func() {
+		p.finish()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("finish() didn't stop the progress lines")
+	}
+}
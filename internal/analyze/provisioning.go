@@ -0,0 +1,177 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Distribution types of provisioning profiles
+const (
+	DistributionDevelopment = "development"
+	DistributionAdHoc       = "ad-hoc"
+	DistributionAppStore    = "app-store"
+	DistributionEnterprise  = "enterprise"
+)
+
+// embeddedProfilePattern matches the provisioning profile embedded in the app bundle of an IPA
+var embeddedProfilePattern = regexp.MustCompile(`^Payload/[^/]+\.app/embedded\.mobileprovision$`)
+
+// ProvisioningProfile is the provisioning profile embedded in an IPA
+type ProvisioningProfile struct {
+	Name     string
+	UUID     string
+	TeamName string
+	TeamID   string
+	AppID    string
+	// Type is one of the Distribution* types
+	Type           string
+	ExpirationDate time.Time
+	// Devices is the number of provisioned devices, zero for App Store and enterprise profiles
+	Devices      int
+	Entitlements []string
+}
+
+// ReadProvisioningProfile decodes the embedded.mobileprovision of the app bundle of an IPA
+func ReadProvisioningProfile(artifactPath string) (ProvisioningProfile, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return ProvisioningProfile{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if !embeddedProfilePattern.MatchString(file.Name) {
+			continue
+		}
+		data, err := readZipEntry(file)
+		if err != nil {
+			return ProvisioningProfile{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		profile, err := parseProvisioningProfile(data)
+		if err != nil {
+			return ProvisioningProfile{}, fmt.Errorf("failed to parse %s: %w", file.Name, err)
+		}
+		return profile, nil
+	}
+	return ProvisioningProfile{}, fmt.Errorf("no embedded.mobileprovision found in the app bundle")
+}
+
+// parseProvisioningProfile decodes the plist signed into a provisioning profile and derives its distribution type
+func parseProvisioningProfile(data []byte) (ProvisioningProfile, error) {
+	content, err := provisioningProfileContent(data)
+	if err != nil {
+		return ProvisioningProfile{}, err
+	}
+	value, err := parsePlist(content)
+	if err != nil {
+		return ProvisioningProfile{}, err
+	}
+	plist, ok := value.(map[string]interface{})
+	if !ok {
+		return ProvisioningProfile{}, fmt.Errorf("invalid provisioning profile: not a dictionary")
+	}
+
+	profile := ProvisioningProfile{
+		Name:     plistString(plist, "Name"),
+		UUID:     plistString(plist, "UUID"),
+		TeamName: plistString(plist, "TeamName"),
+		AppID:    plistString(plist, "AppIDName"),
+	}
+	if teams, ok := plist["TeamIdentifier"].([]interface{}); ok && len(teams) > 0 {
+		profile.TeamID, _ = teams[0].(string)
+	}
+	profile.ExpirationDate, _ = plist["ExpirationDate"].(time.Time)
+
+	entitlements, _ := plist["Entitlements"].(map[string]interface{})
+	for key := range entitlements {
+		profile.Entitlements = append(profile.Entitlements, key)
+	}
+	sort.Strings(profile.Entitlements)
+	if applicationID := plistString(entitlements, "application-identifier"); applicationID != "" {
+		profile.AppID = applicationID
+	}
+
+	devices, hasDevices := plist["ProvisionedDevices"].([]interface{})
+	profile.Devices = len(devices)
+	switch {
+	case plist["ProvisionsAllDevices"] == true:
+		profile.Type = DistributionEnterprise
+	case hasDevices && entitlements["get-task-allow"] == true:
+		profile.Type = DistributionDevelopment
+	case hasDevices:
+		profile.Type = DistributionAdHoc
+	default:
+		profile.Type = DistributionAppStore
+	}
+	return profile, nil
+}
+
+// provisioningProfileContent returns the plist of a provisioning profile: the content of its CMS signature.
+// Profiles BER-encoded with indefinite lengths can't be decoded by encoding/asn1, the plist is looked up in the raw data then.
+func provisioningProfileContent(data []byte) ([]byte, error) {
+	var contentInfo pkcs7ContentInfo
+	var signedData pkcs7SignedData
+	var encapsulated pkcs7ContentInfo
+	var content []byte
+	if _, err := asn1.Unmarshal(data, &contentInfo); err == nil {
+		if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err == nil {
+			if _, err := asn1.Unmarshal(signedData.ContentInfo.FullBytes, &encapsulated); err == nil {
+				if _, err := asn1.Unmarshal(encapsulated.Content.Bytes, &content); err == nil {
+					return content, nil
+				}
+			}
+		}
+	}
+
+	start := bytes.Index(data, []byte("<?xml"))
+	end := bytes.LastIndex(data, []byte("</plist>"))
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("invalid provisioning profile: no plist found")
+	}
+	return data[start : end+len("</plist>")], nil
+}
+
+// readProvisioningProfileFromConfig decodes the provisioning profile of the IPA and logs it, warning when it expires
+// within cert_expiry_warning_days. False is returned when the profile couldn't be read.
+func readProvisioningProfileFromConfig(artifactPath, expiryWarningDays string, now time.Time, logger log.Logger) (ProvisioningProfile, bool) {
+	if !strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		logger.Warnf("Provisioning profile analysis only supports IPA artifacts")
+		return ProvisioningProfile{}, false
+	}
+
+	logger.Infof("Reading provisioning profile...")
+	profile, err := ReadProvisioningProfile(artifactPath)
+	if err != nil {
+		logger.Warnf("Failed to read provisioning profile: %s", err)
+		return ProvisioningProfile{}, false
+	}
+
+	logger.Printf("- Name: %s (%s)", profile.Name, profile.UUID)
+	logger.Printf("- Type: %s", profile.Type)
+	logger.Printf("- Team: %s (%s)", profile.TeamName, profile.TeamID)
+	logger.Printf("- App ID: %s", profile.AppID)
+	logger.Printf("- Expires: %s", profile.ExpirationDate.Format("2006-01-02"))
+	if profile.Type == DistributionDevelopment || profile.Type == DistributionAdHoc {
+		logger.Printf("- Devices: %d", profile.Devices)
+	}
+	logger.Printf("- Entitlements: %d", len(profile.Entitlements))
+
+	if profile.ExpirationDate.IsZero() || expiryWarningDays == "" {
+		return profile, true
+	}
+	// An invalid value is reported with the certificate expiry
+	if days, err := strconv.Atoi(expiryWarningDays); err == nil && days >= 0 {
+		warnExpiry("provisioning profile", profile.ExpirationDate, days, now, logger)
+	}
+	return profile, true
+}
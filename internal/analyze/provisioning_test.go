@@ -0,0 +1,124 @@
+package analyze
+
+import (
+	"archive/zip"
+	"encoding/asn1"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mobileProvision wraps the plist into a CMS SignedData like the provisioning profiles of Apple, without signer infos
+func mobileProvision(t *testing.T, plist string) []byte {
+	t.Helper()
+	explicit := func(der []byte) asn1.RawValue {
+		return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+	}
+	marshal := func(value interface{}) []byte {
+		der, err := asn1.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	type contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+	encapsulated := marshal(contentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}, Content: explicit(marshal([]byte(plist)))})
+	signedData := marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      asn1.RawValue{FullBytes: encapsulated},
+	})
+	return marshal(contentInfo{ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}, Content: explicit(signedData)})
+}
+
+// profilePlist is the plist of a provisioning profile with the extra keys and entitlements
+func profilePlist(extra, entitlements string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+<key>AppIDName</key><string>Test App</string>
+<key>ExpirationDate</key><date>2027-01-31T10:00:00Z</date>
+<key>Name</key><string>Test Profile</string>
+<key>TeamIdentifier</key><array><string>ABCDE12345</string></array>
+<key>TeamName</key><string>Example Inc.</string>
+<key>UUID</key><string>6f1c2f4e-0000-4000-8000-000000000000</string>
+<key>Entitlements</key><dict>
+	<key>application-identifier</key><string>ABCDE12345.com.example.app</string>
+	<key>aps-environment</key><string>production</string>
+	%s
+</dict>
+%s
+</dict></plist>`, entitlements, extra)
+}
+
+func TestReadProvisioningProfile(t *testing.T) {
+	devices := "<key>ProvisionedDevices</key><array><string>00008030-001</string><string>00008030-002</string></array>"
+	tests := []struct {
+		name        string
+		profile     func(t *testing.T) []byte
+		wantType    string
+		wantDevices int
+	}{
+		{name: "development", profile: func(t *testing.T) []byte {
+			return mobileProvision(t, profilePlist(devices, "<key>get-task-allow</key><true/>"))
+		}, wantType: DistributionDevelopment, wantDevices: 2},
+		{name: "ad-hoc", profile: func(t *testing.T) []byte {
+			return mobileProvision(t, profilePlist(devices, "<key>get-task-allow</key><false/>"))
+		}, wantType: DistributionAdHoc, wantDevices: 2},
+		{name: "app store", profile: func(t *testing.T) []byte { return mobileProvision(t, profilePlist("", "")) }, wantType: DistributionAppStore},
+		{name: "enterprise", profile: func(t *testing.T) []byte {
+			return mobileProvision(t, profilePlist("<key>ProvisionsAllDevices</key><true/>", ""))
+		}, wantType: DistributionEnterprise},
+		// BER-encoded profiles are searched for the plist
+		{name: "undecodable signature", profile: func(t *testing.T) []byte {
+			return append([]byte{0x30, 0x80, 0x06, 0x09}, profilePlist("", "")...)
+		}, wantType: DistributionAppStore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+			entries := []zipEntry{
+				{name: "Payload/Test.app/Info.plist", content: infoPlist("Test")},
+				{name: "Payload/Test.app/embedded.mobileprovision", content: string(tt.profile(t))},
+			}
+			if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			profile, err := ReadProvisioningProfile(artifactPath)
+			if err != nil {
+				t.Fatalf("ReadProvisioningProfile() error = %s", err)
+			}
+			if profile.Type != tt.wantType || profile.Devices != tt.wantDevices {
+				t.Errorf("ReadProvisioningProfile() = %s with %d devices, want %s with %d", profile.Type, profile.Devices, tt.wantType, tt.wantDevices)
+			}
+			if profile.Name != "Test Profile" || profile.TeamName != "Example Inc." || profile.TeamID != "ABCDE12345" || profile.AppID != "ABCDE12345.com.example.app" {
+				t.Errorf("ReadProvisioningProfile() = %+v", profile)
+			}
+			if !profile.ExpirationDate.Equal(time.Date(2027, 1, 31, 10, 0, 0, 0, time.UTC)) {
+				t.Errorf("ExpirationDate = %s", profile.ExpirationDate)
+			}
+			if !containsString(profile.Entitlements, "aps-environment") || !containsString(profile.Entitlements, "application-identifier") {
+				t.Errorf("Entitlements = %v", profile.Entitlements)
+			}
+		})
+	}
+}
+
+func TestReadProvisioningProfileMissing(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "Test.ipa")
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{{name: "Payload/Test.app/Info.plist", content: infoPlist("Test")}}, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadProvisioningProfile(artifactPath); err == nil {
+		t.Errorf("ReadProvisioningProfile() without a profile succeeded")
+	}
+}
@@ -0,0 +1,256 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Reputation verdicts of native binaries, the reputation API may return others
+const (
+	VerdictClean      = "clean"
+	VerdictAllowed    = "allowed"
+	VerdictUnknown    = "unknown"
+	VerdictSuspicious = "suspicious"
+	VerdictMalicious  = "malicious"
+	// VerdictDenied is set for binaries on the deny list
+	VerdictDenied = "denied"
+	// VerdictUnlisted is set for binaries missing from the allow list when no reputation API is configured
+	VerdictUnlisted = "unlisted"
+)
+
+// flaggedVerdicts are the verdicts reported as findings
+var flaggedVerdicts = map[string]bool{VerdictSuspicious: true, VerdictMalicious: true, VerdictDenied: true, VerdictUnlisted: true}
+
+// BinaryReputation is the screening result of a native binary
+type BinaryReputation struct {
+	Path    string
+	SHA256  string
+	Verdict string
+	// Source is the list or API the verdict comes from
+	Source string
+	Detail string
+}
+
+// Flagged reports whether the binary failed the screening
+func (r BinaryReputation) Flagged() bool {
+	return flaggedVerdicts[r.Verdict]
+}
+
+// reputationRequest is the request body of the reputation API
+type reputationRequest struct {
+	SHA256 []string `json:"sha256"`
+}
+
+// reputationResponse is the response of the reputation API, hashes it doesn't know may be left out
+type reputationResponse struct {
+	Results []struct {
+		SHA256  string `json:"sha256"`
+		Verdict string `json:"verdict"`
+		Detail  string `json:"detail"`
+	} `json:"results"`
+}
+
+// nativeBinaryHashes returns the SHA-256 of the native binaries of the artifact keyed by path,
+// the native libraries of APKs and AABs and the Mach-O binaries of IPAs
+func nativeBinaryHashes(artifactPath string) (map[string]string, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	ipa := strings.EqualFold(path.Ext(artifactPath), ".ipa")
+	hashes := map[string]string{}
+	for _, file := range reader.File {
+		native, err := isNativeBinary(file, ipa)
+		if err != nil {
+			return nil, err
+		}
+		if !native {
+			continue
+		}
+		hash, err := hashZipEntry(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", file.Name, err)
+		}
+		hashes[file.Name] = hash
+	}
+	return hashes, nil
+}
+
+// LoadHashList reads an allow or deny list: a SHA-256 per line, optionally followed by a comment like the file name.
+// Empty lines and lines starting with # are skipped.
+func LoadHashList(listPath string) (map[string]bool, error) {
+	data, err := os.ReadFile(listPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", listPath, err)
+	}
+
+	hashes := map[string]bool{}
+	for idx, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		hash := strings.ToLower(fields[0])
+		if len(hash) != 64 || strings.Trim(hash, "0123456789abcdef") != "" {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-256 %q", listPath, idx+1, fields[0])
+		}
+		hashes[hash] = true
+	}
+	return hashes, nil
+}
+
+// lookupReputation submits the hashes to the reputation API and returns its verdicts
+func lookupReputation(ctx context.Context, url, token string, hashes []string, retry executor.RetryOptions, logger log.Logger) (reputationResponse, error) {
+	body, err := json.Marshal(reputationRequest{SHA256: hashes})
+	if err != nil {
+		return reputationResponse{}, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	var response reputationResponse
+	_, err = executor.Retry(ctx, retry, "Reputation lookup", logger, func(ctx context.Context) (string, error) {
+		response = reputationResponse{}
+		return "", postReputationLookup(ctx, client, url, token, body, &response)
+	})
+	return response, err
+}
+
+// postReputationLookup posts the encoded hashes to the reputation API once
+func postReputationLookup(ctx context.Context, client *http.Client, url, token string, body []byte, v *reputationResponse) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("reputation lookup failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("reputation lookup failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse reputation response: %w", err)
+	}
+	return nil
+}
+
+// screenNativeBinaries screens the native binaries of the artifact. Allowed hashes are never flagged nor
+// submitted, denied hashes are flagged without a lookup, the rest is submitted to the reputation API.
+// Without an API, binaries missing from a configured allow list are flagged.
+func screenNativeBinaries(ctx context.Context, cfg config.Config, artifactPath string, retry executor.RetryOptions, logger log.Logger) ([]BinaryReputation, error) {
+	allowed, denied := map[string]bool{}, map[string]bool{}
+	var err error
+	if cfg.HashAllowlist != "" {
+		if allowed, err = LoadHashList(cfg.HashAllowlist); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.HashDenylist != "" {
+		if denied, err = LoadHashList(cfg.HashDenylist); err != nil {
+			return nil, err
+		}
+	}
+
+	hashes, err := nativeBinaryHashes(artifactPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var binaries []BinaryReputation
+	lookup := map[string]bool{}
+	for binaryPath, hash := range hashes {
+		binary := BinaryReputation{Path: binaryPath, SHA256: hash, Verdict: VerdictUnknown}
+		switch {
+		case allowed[hash]:
+			binary.Verdict, binary.Source = VerdictAllowed, "allow list"
+		case denied[hash]:
+			binary.Verdict, binary.Source = VerdictDenied, "deny list"
+		case cfg.HashReputationURL != "":
+			lookup[hash] = true
+		case cfg.HashAllowlist != "":
+			binary.Verdict, binary.Source = VerdictUnlisted, "allow list"
+		}
+		binaries = append(binaries, binary)
+	}
+
+	if len(lookup) > 0 {
+		var submitted []string
+		for hash := range lookup {
+			submitted = append(submitted, hash)
+		}
+		sort.Strings(submitted)
+		logger.Printf("Submitting %d hash(es) for reputation lookup", len(submitted))
+		response, err := lookupReputation(ctx, cfg.HashReputationURL, string(cfg.HashReputationToken), submitted, retry, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		verdicts := map[string]int{}
+		for idx, result := range response.Results {
+			verdicts[strings.ToLower(result.SHA256)] = idx
+		}
+		for idx, binary := range binaries {
+			resultIdx, ok := verdicts[binary.SHA256]
+			if !lookup[binary.SHA256] || !ok {
+				continue
+			}
+			result := response.Results[resultIdx]
+			if result.Verdict != "" {
+				binaries[idx].Verdict = strings.ToLower(result.Verdict)
+			}
+			binaries[idx].Source, binaries[idx].Detail = "reputation API", result.Detail
+		}
+	}
+
+	sort.Slice(binaries, func(i, j int) bool { return binaries[i].Path < binaries[j].Path })
+	return binaries, nil
+}
+
+// screenNativeBinariesFromConfig screens the native binaries of the artifact and logs the flagged ones,
+// false is returned when the artifact couldn't be screened
+func screenNativeBinariesFromConfig(ctx context.Context, cfg config.Config, artifactPath string, retry executor.RetryOptions, logger log.Logger) ([]BinaryReputation, bool) {
+	logger.Infof("Screening native binaries...")
+	binaries, err := screenNativeBinaries(ctx, cfg, artifactPath, retry, logger)
+	if err != nil {
+		logger.Warnf("Failed to screen native binaries: %s", err)
+		return nil, false
+	}
+
+	flagged := 0
+	for _, binary := range binaries {
+		if binary.Flagged() {
+			flagged++
+			if binary.Detail != "" {
+				logger.Warnf("%s: %s (%s): %s", binary.Path, binary.Verdict, binary.Source, binary.Detail)
+			} else {
+				logger.Warnf("%s: %s (%s)", binary.Path, binary.Verdict, binary.Source)
+			}
+		}
+	}
+	logger.Printf("%d of %d native binaries flagged", flagged, len(binaries))
+	return binaries, true
+}
@@ -0,0 +1,139 @@
+package analyze
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestScreenNativeBinaries(t *testing.T) {
+	libraries := map[string]string{
+		"lib/arm64-v8a/libapp.so":       "app",
+		"lib/arm64-v8a/libvendor.so":    "vendor",
+		"lib/arm64-v8a/libtracker.so":   "tracker",
+		"lib/armeabi-v7a/libunknown.so": "unknown",
+	}
+	var entries []zipEntry
+	for name, content := range libraries {
+		entries = append(entries, zipEntry{name: name, content: content})
+	}
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, writeZip(t, entries, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	allowlist := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(allowlist, []byte(fmt.Sprintf("# Reviewed binaries\n%s libapp.so\n\n", sha256Hex("app"))), 0644); err != nil {
+		t.Fatal(err)
+	}
+	denylist := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(denylist, []byte(sha256Hex("vendor")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var submitted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request reputationRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		submitted = request.SHA256
+		// Unknown hashes are left out of the response
+		_, _ = fmt.Fprintf(w, `{"results": [{"sha256": %q, "verdict": "Malicious", "detail": "known tracker"}]}`, sha256Hex("tracker"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		cfg           config.Config
+		wantVerdicts  map[string]string
+		wantSubmitted []string
+	}{
+		{name: "allow and deny lists", cfg: config.Config{HashAllowlist: allowlist, HashDenylist: denylist}, wantVerdicts: map[string]string{
+			"lib/arm64-v8a/libapp.so": VerdictAllowed, "lib/arm64-v8a/libvendor.so": VerdictDenied,
+			"lib/arm64-v8a/libtracker.so": VerdictUnlisted, "lib/armeabi-v7a/libunknown.so": VerdictUnlisted,
+		}},
+		{name: "reputation API", cfg: config.Config{HashAllowlist: allowlist, HashDenylist: denylist, HashReputationURL: server.URL, HashReputationToken: "test-token"},
+			wantVerdicts: map[string]string{
+				"lib/arm64-v8a/libapp.so": VerdictAllowed, "lib/arm64-v8a/libvendor.so": VerdictDenied,
+				"lib/arm64-v8a/libtracker.so": VerdictMalicious, "lib/armeabi-v7a/libunknown.so": VerdictUnknown,
+			},
+			wantSubmitted: []string{sha256Hex("tracker"), sha256Hex("unknown")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitted = nil
+			binaries, err := screenNativeBinaries(context.Background(), tt.cfg, artifactPath, executor.RetryOptions{}, log.NewLogger())
+			if err != nil {
+				t.Fatalf("screenNativeBinaries() error = %s", err)
+			}
+			verdicts := map[string]string{}
+			for _, binary := range binaries {
+				verdicts[binary.Path] = binary.Verdict
+			}
+			if !reflect.DeepEqual(verdicts, tt.wantVerdicts) {
+				t.Errorf("verdicts = %v, want %v", verdicts, tt.wantVerdicts)
+			}
+			// The hashes are submitted sorted
+			sort.Strings(tt.wantSubmitted)
+			if !reflect.DeepEqual(submitted, tt.wantSubmitted) {
+				t.Errorf("submitted hashes = %v, want %v", submitted, tt.wantSubmitted)
+			}
+		})
+	}
+}
+
+func TestScreenNativeBinariesAPIFailure(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{{name: "lib/arm64-v8a/libapp.so", content: "app"}}, zip.Deflate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("maintenance"))
+	}))
+	defer server.Close()
+
+	_, err := screenNativeBinaries(context.Background(), config.Config{HashReputationURL: server.URL}, artifactPath,
+		executor.RetryOptions{Count: 1, Wait: time.Millisecond}, log.NewLogger())
+	if err == nil || attempts != 2 {
+		t.Errorf("screenNativeBinaries() = %d attempts, error %v, want 2 attempts and the error", attempts, err)
+	}
+}
+
+func TestLoadHashList(t *testing.T) {
+	listPath := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(listPath, []byte("# comment\n"+sha256Hex("a")+"\nnot-a-hash\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadHashList(listPath); err == nil || err.Error() != listPath+`:3: invalid SHA-256 "not-a-hash"` {
+		t.Errorf("LoadHashList() error = %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package analyze
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// maxCachedResults caps the number of analyses kept in the result cache, the least recently used ones are removed
+const maxCachedResults = 20
+
+// cachedManifestName is the file of a cache entry keeping the metrics of the plugin's machine output, the reports don't
+// contain them without the JSON format
+const cachedManifestName = "manifest.json"
+
+// resultCacheVersion is part of the cache key, it's increased when the content of the entries changes so the entries of
+// earlier step versions aren't restored
+const resultCacheVersion = 2
+
+// resultCacheKey identifies an analysis in the result cache: the SHA-256 and the name of the artifact (reports refer to it by name),
+// the report formats, the locale and timezone the reports are written in, the version of the installed plugin and resultCacheVersion
+func resultCacheKey(cfg config.Config, artifactPath, pluginVersion string) (string, error) {
+	file, err := os.Open(artifactPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	artifactHash := sha256.New()
+	buffer := entryBuffers.Get().(*[]byte)
+	defer entryBuffers.Put(buffer)
+	if _, err := io.CopyBuffer(artifactHash, file, *buffer); err != nil {
+		return "", fmt.Errorf("failed to hash artifact: %w", err)
+	}
+
+	var formats []string
+	for _, format := range strings.Split(cfg.PluginOutputFormats(), ",") {
+		if format = strings.TrimSpace(format); format != "" {
+			formats = append(formats, format)
+		}
+	}
+	sort.Strings(formats)
+
+	key := sha256.New()
+	fmt.Fprintf(key, "%d\n%x\n%s\n%s\n%s\n%s\n%s", resultCacheVersion, artifactHash.Sum(nil), ArtifactName(artifactPath), strings.Join(formats, ","), cfg.ReportLocale, cfg.ReportTimezone, strings.TrimPrefix(pluginVersion, "v"))
+	return hex.EncodeToString(key.Sum(nil)), nil
+}
+
+// restoreCachedResult copies the cached reports of the key into the work directory and returns the cached machine output
+// of the plugin, nil for entries of plugins without one. It returns false on a cache miss.
+func (a Analyzer) restoreCachedResult(cacheDir, key, workDir string) (*pluginManifest, bool, error) {
+	entryDir := filepath.Join(cacheDir, key)
+	reports, err := filepath.Glob(filepath.Join(entryDir, "bundle-analysis-*"))
+	if err != nil || len(reports) == 0 {
+		return nil, false, err
+	}
+
+	var manifest *pluginManifest
+	if data, err := os.ReadFile(filepath.Join(entryDir, cachedManifestName)); err == nil {
+		if manifest, err = parsePluginManifest(string(data)); err != nil {
+			return nil, false, fmt.Errorf("failed to restore cached metrics: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, false, fmt.Errorf("failed to restore cached metrics: %w", err)
+	}
+
+	for _, reportPath := range reports {
+		if err := copyFileWithMode(reportPath, filepath.Join(workDir, filepath.Base(reportPath)), 0644); err != nil {
+			return nil, false, fmt.Errorf("failed to restore cached report: %w", err)
+		}
+	}
+
+	// The modification time orders the entries for pruning
+	now := time.Now()
+	_ = os.Chtimes(entryDir, now, now)
+	return manifest, true, nil
+}
+
+// saveCachedResult copies the reports generated for the artifact in the work directory and the metrics of the manifest
+// into the cache and prunes the oldest entries
+func (a Analyzer) saveCachedResult(cacheDir, key, artifactPath, workDir string, manifest *pluginManifest) error {
+	var reports []string
+	for _, ext := range reportExtensions {
+		reportPath := filepath.Join(workDir, ReportName(artifactPath, ext))
+		if _, err := os.Stat(reportPath); err == nil {
+			reports = append(reports, reportPath)
+		}
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create result cache: %w", err)
+	}
+
+	// Concurrent analyses of identical artifacts may save the same entry, so the entry is moved into place when complete
+	tmpDir, err := os.MkdirTemp(cacheDir, ".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create result cache entry: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.Chmod(tmpDir, 0755); err != nil {
+		return fmt.Errorf("failed to create result cache entry: %w", err)
+	}
+
+	for _, reportPath := range reports {
+		if err := copyFileWithMode(reportPath, filepath.Join(tmpDir, filepath.Base(reportPath)), 0644); err != nil {
+			return fmt.Errorf("failed to cache report: %w", err)
+		}
+	}
+	// The report files were moved to the step's names already, only the metrics are kept
+	if manifest != nil && manifest.Metrics != nil {
+		data, err := json.Marshal(pluginManifest{SchemaVersion: manifest.SchemaVersion, Metrics: manifest.Metrics})
+		if err != nil {
+			return fmt.Errorf("failed to cache metrics: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(tmpDir, cachedManifestName), data, 0644); err != nil {
+			return fmt.Errorf("failed to cache metrics: %w", err)
+		}
+	}
+
+	entryDir := filepath.Join(cacheDir, key)
+	_ = os.RemoveAll(entryDir)
+	if err := os.Rename(tmpDir, entryDir); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("failed to save result cache entry: %w", err)
+	}
+
+	pruneResultCache(cacheDir)
+	return nil
+}
+
+// pruneResultCache removes the least recently used entries above maxCachedResults
+func pruneResultCache(cacheDir string) {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+
+	var dirs []os.FileInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			dirs = append(dirs, info)
+		}
+	}
+	if len(dirs) <= maxCachedResults {
+		return
+	}
+
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].ModTime().After(dirs[j].ModTime()) })
+	for _, info := range dirs[maxCachedResults:] {
+		_ = os.RemoveAll(filepath.Join(cacheDir, info.Name()))
+	}
+}
+
+// AddCacheIncludePath adds the path to the cache paths of the legacy Cache:Push step
+func AddCacheIncludePath(path string, envRepo env.Repository, exporter EnvExporter) error {
+	includePaths := envRepo.Get("BITRISE_CACHE_INCLUDE_PATHS")
+	for _, includePath := range strings.Split(includePaths, "\n") {
+		if strings.TrimSpace(includePath) == path {
+			return nil
+		}
+	}
+	return exporter.Export("BITRISE_CACHE_INCLUDE_PATHS", strings.TrimSpace(includePaths+"\n"+path))
+}
@@ -0,0 +1,116 @@
+package analyze
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// LibraryChange is a library shipped in both builds with a different version
+type LibraryChange struct {
+	Name string
+	From string
+	To   string
+}
+
+// Downgrade reports whether the library went back to an older version
+func (c LibraryChange) Downgrade() bool {
+	return compareVersions(c.To, c.From) < 0
+}
+
+// LibraryDiff is the change of the library inventory since the baseline build
+type LibraryDiff struct {
+	// Added and Removed are name@version entries, see LibraryEntries
+	Added   []string
+	Changed []LibraryChange
+	Removed []string
+}
+
+// LibraryEntries returns a name@version line per detected library, the inventory recorded in the history file
+func LibraryEntries(sdks []DetectedSDK) []string {
+	var entries []string
+	for _, sdk := range sdks {
+		entries = append(entries, sdk.Name+"@"+sdk.Version)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// diffLibraries compares two library inventories: libraries shipped in both builds with another version are
+// changes, the rest are added or removed
+func diffLibraries(baseline, current []string) LibraryDiff {
+	versions := func(entries []string) map[string][]string {
+		byName := map[string][]string{}
+		for _, entry := range entries {
+			name, version := SplitLibraryEntry(entry)
+			byName[name] = append(byName[name], version)
+		}
+		return byName
+	}
+	baselineVersions, currentVersions := versions(baseline), versions(current)
+
+	var diff LibraryDiff
+	added, removed := diffStrings(baseline, current)
+	for _, entry := range added {
+		name, version := SplitLibraryEntry(entry)
+		// A library shipped once in both builds was upgraded or downgraded, several versions can't be paired up
+		if previous := baselineVersions[name]; len(previous) == 1 && len(currentVersions[name]) == 1 {
+			diff.Changed = append(diff.Changed, LibraryChange{Name: name, From: previous[0], To: version})
+			continue
+		}
+		diff.Added = append(diff.Added, entry)
+	}
+	for _, entry := range removed {
+		name, _ := SplitLibraryEntry(entry)
+		if len(baselineVersions[name]) == 1 && len(currentVersions[name]) == 1 {
+			continue
+		}
+		diff.Removed = append(diff.Removed, entry)
+	}
+	return diff
+}
+
+// SplitLibraryEntry splits a name@version entry of the library inventory
+func SplitLibraryEntry(entry string) (string, string) {
+	idx := strings.LastIndex(entry, "@")
+	if idx < 0 {
+		return entry, ""
+	}
+	return entry[:idx], entry[idx+1:]
+}
+
+// readLibrariesFromConfig detects the versioned libraries of the artifact for the SBOM diff,
+// false is returned when the artifact couldn't be read
+func readLibrariesFromConfig(artifactPath string, logger log.Logger) ([]DetectedSDK, bool) {
+	logger.Infof("Reading library inventory...")
+	sdks, err := DetectSDKs(artifactPath)
+	if err != nil {
+		logger.Warnf("Failed to detect SDKs: %s", err)
+		return nil, false
+	}
+	logger.Printf("Found %d versioned libraries", len(sdks))
+	return sdks, true
+}
+
+// compareLibrariesFromConfig diffs the library inventory with the baseline build and logs the changes,
+// nil is returned if the baseline build has no recorded library inventory
+func compareLibrariesFromConfig(sdks []DetectedSDK, baseline HistoryRecord, logger log.Logger) *LibraryDiff {
+	if baseline.Libraries == nil {
+		logger.Printf("No library inventory recorded for the baseline build yet")
+		return nil
+	}
+
+	diff := diffLibraries(*baseline.Libraries, LibraryEntries(sdks))
+	logger.Printf("Libraries: %d added, %d changed, %d removed", len(diff.Added), len(diff.Changed), len(diff.Removed))
+	for _, entry := range diff.Added {
+		logger.Warnf("+ %s", entry)
+	}
+	for _, change := range diff.Changed {
+		logger.Printf("~ %s %s -> %s", change.Name, change.From, change.To)
+	}
+	for _, entry := range diff.Removed {
+		logger.Printf("- %s", entry)
+	}
+	return &diff
+}
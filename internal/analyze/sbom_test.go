@@ -0,0 +1,46 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestDiffLibraries(t *testing.T) {
+	baseline := []string{"com.google.code.gson:gson@2.8.6", "com.squareup.okio:okio@3.5.0", "io.reactivex:rxjava@2.2.21", "org.jetbrains:annotations@13.0", "org.jetbrains:annotations@23.0.0"}
+	current := []string{"com.google.code.gson:gson@2.10.1", "com.squareup.okhttp3:okhttp@4.12.0", "com.squareup.okio:okio@3.4.0", "org.jetbrains:annotations@23.0.0"}
+
+	diff := diffLibraries(baseline, current)
+	want := LibraryDiff{
+		Added: []string{"com.squareup.okhttp3:okhttp@4.12.0"},
+		Changed: []LibraryChange{
+			{Name: "com.google.code.gson:gson", From: "2.8.6", To: "2.10.1"},
+			{Name: "com.squareup.okio:okio", From: "3.5.0", To: "3.4.0"},
+		},
+		// Libraries shipped in several versions aren't paired up
+		Removed: []string{"io.reactivex:rxjava@2.2.21", "org.jetbrains:annotations@13.0"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("diffLibraries() = %+v, want %+v", diff, want)
+	}
+	if diff.Changed[0].Downgrade() || !diff.Changed[1].Downgrade() {
+		t.Errorf("Downgrade() = %t, %t, want an upgrade and a downgrade", diff.Changed[0].Downgrade(), diff.Changed[1].Downgrade())
+	}
+}
+
+func TestCompareLibraries(t *testing.T) {
+	sdks := []DetectedSDK{{Name: "com.squareup.okio:okio", Version: "3.5.0"}, {Name: "com.google.code.gson:gson", Version: "2.10.1"}}
+	if entries := LibraryEntries(sdks); !reflect.DeepEqual(entries, []string{"com.google.code.gson:gson@2.10.1", "com.squareup.okio:okio@3.5.0"}) {
+		t.Errorf("LibraryEntries() = %v", entries)
+	}
+	if diff := compareLibrariesFromConfig(sdks, HistoryRecord{}, log.NewLogger()); diff != nil {
+		t.Errorf("compareLibrariesFromConfig() without a recorded baseline = %+v", diff)
+	}
+
+	baseline := []string{"com.squareup.okio:okio@3.5.0"}
+	diff := compareLibrariesFromConfig(sdks, HistoryRecord{Libraries: &baseline}, log.NewLogger())
+	if diff == nil || !reflect.DeepEqual(diff.Added, []string{"com.google.code.gson:gson@2.10.1"}) || diff.Changed != nil || diff.Removed != nil {
+		t.Errorf("compareLibrariesFromConfig() = %+v, want gson added", diff)
+	}
+}
@@ -0,0 +1,193 @@
+package analyze
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// EntryScope selects the artifact entries counted in the size totals and the thresholds, the zero value selects all.
+// With Include only the matching entries are selected, Exclude leaves entries out of them.
+type EntryScope struct {
+	Include []string
+	Exclude []string
+	// Content is the code_only or assets_only analysis_scope, it selects the code or the other entries
+	Content string
+}
+
+// ExcludedEntries sums the entries left out of the size totals, outside of include_patterns or matching
+// exclude_patterns, they're reported separately
+type ExcludedEntries struct {
+	Files int64
+	Bytes int64
+}
+
+// NewEntryScope returns the scope configured by include_patterns and exclude_patterns
+func NewEntryScope(cfg config.Config) EntryScope {
+	scope := EntryScope{Include: ParsePatterns(cfg.IncludePatterns), Exclude: ParsePatterns(cfg.ExcludePatterns)}
+	if cfg.AnalysisScope == config.AnalysisScopeCodeOnly || cfg.AnalysisScope == config.AnalysisScopeAssetsOnly {
+		scope.Content = cfg.AnalysisScope
+	}
+	return scope
+}
+
+// Limited reports whether the scope leaves out any entry
+func (s EntryScope) Limited() bool {
+	return len(s.Include) > 0 || len(s.Exclude) > 0 || s.Content != ""
+}
+
+// ParsePatterns splits a comma or newline separated list of entry patterns
+func ParsePatterns(input string) []string {
+	var patterns []string
+	for _, pattern := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// Contains reports whether the entry is counted
+func (s EntryScope) Contains(entryPath string) bool {
+	switch {
+	case s.Content == config.AnalysisScopeCodeOnly && !IsCodeEntry(entryPath):
+		return false
+	case s.Content == config.AnalysisScopeAssetsOnly && IsCodeEntry(entryPath):
+		return false
+	case len(s.Include) > 0 && !matchAny(s.Include, entryPath):
+		return false
+	}
+	return !matchAny(s.Exclude, entryPath)
+}
+
+// codeExtensions are the extensions of the entries holding code: DEX files, native libraries and JavaScript bundles
+var codeExtensions = map[string]bool{".dex": true, ".so": true, ".dylib": true, ".jsbundle": true, ".hbc": true}
+
+// codeBundleExtensions are the extensions of the Apple bundles whose executable has the name of the bundle
+var codeBundleExtensions = map[string]bool{".app": true, ".framework": true, ".appex": true, ".xpc": true}
+
+// IsCodeEntry reports whether the artifact entry is code: a DEX file, a native library, a JavaScript bundle or the
+// executable of an app, framework or extension bundle (Payload/App.app/App)
+func IsCodeEntry(entryPath string) bool {
+	if codeExtensions[path.Ext(entryPath)] {
+		return true
+	}
+	bundle := path.Base(path.Dir(entryPath))
+	ext := path.Ext(bundle)
+	return codeBundleExtensions[ext] && path.Base(entryPath) == strings.TrimSuffix(bundle, ext)
+}
+
+// matchAny reports whether the entry matches any of the patterns
+func matchAny(patterns []string, entryPath string) bool {
+	for _, pattern := range patterns {
+		if MatchPattern(pattern, entryPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPattern matches an artifact entry path against a glob. Patterns without a slash match the file name at any
+// depth (*.map), patterns ending with a slash match everything below the directory (assets/fixtures/), and **
+// matches any number of directories (Payload/**/*.dSYM/**). Invalid patterns match nothing.
+func MatchPattern(pattern, entryPath string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	if !strings.Contains(pattern, "/") {
+		matched, err := path.Match(pattern, path.Base(entryPath))
+		return err == nil && matched
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(entryPath, "/"))
+}
+
+// matchSegments matches the path segments against the pattern segments, ** matches zero or more segments
+func matchSegments(patterns, segments []string) bool {
+	if len(patterns) == 0 {
+		return len(segments) == 0
+	}
+	if patterns[0] == "**" {
+		for idx := 0; idx <= len(segments); idx++ {
+			if matchSegments(patterns[1:], segments[idx:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, err := path.Match(patterns[0], segments[0])
+	return err == nil && matched && matchSegments(patterns[1:], segments[1:])
+}
+
+// ValidatePatterns returns an error for the first malformed pattern
+func ValidatePatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("%s: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Scoped returns the entries of the inventory within the scope and the sum of the others
+func (i Inventory) Scoped(scope EntryScope) (Inventory, ExcludedEntries) {
+	var scoped Inventory
+	var excluded ExcludedEntries
+	for _, entry := range i.Entries {
+		if scope.Contains(entry.Path) {
+			scoped.Entries = append(scoped.Entries, entry)
+			continue
+		}
+		excluded.Files++
+		excluded.Bytes += entry.CompressedSize
+	}
+	return scoped, excluded
+}
+
+// applyEntryScopeFromConfig leaves the entries outside of include_patterns and those matching exclude_patterns out of
+// the inventory and the size totals, their size is subtracted from the artifact size and kept in result.Excluded.
+// With include_patterns the size is the sum of the included entries, the archive overhead isn't counted.
+func applyEntryScopeFromConfig(cfg config.Config, result *ArtifactResult, logger log.Logger) {
+	scope := NewEntryScope(cfg)
+	if err := ValidatePatterns(scope.Include); err != nil {
+		logger.Warnf("Invalid include_patterns value: %s", err)
+		return
+	}
+	if err := ValidatePatterns(scope.Exclude); err != nil {
+		logger.Warnf("Invalid exclude_patterns value: %s", err)
+		return
+	}
+
+	result.Scope = scope
+	if scope.Content != "" {
+		logger.Printf("analysis_scope %s: the size totals and thresholds cover only the %s", scope.Content, ScopeContentName(scope.Content))
+	}
+	result.Inventory, result.Excluded = result.Inventory.Scoped(scope)
+	result.Metrics.FileCount = int64(len(result.Inventory.Entries))
+	if len(scope.Include) > 0 {
+		result.Metrics.SizeBytes = result.Inventory.DownloadSize()
+		logger.Printf("Included %d file(s), %s, in the size totals and thresholds", result.Metrics.FileCount, cfg.Units().Format(result.Metrics.SizeBytes))
+	} else {
+		if result.Excluded.Bytes > result.Metrics.SizeBytes {
+			result.Excluded.Bytes = result.Metrics.SizeBytes
+		}
+		result.Metrics.SizeBytes -= result.Excluded.Bytes
+	}
+	result.Metrics.SizeMB = newBundleMetrics(result.Metrics.SizeBytes, 0).SizeMB
+	logger.Printf("Excluded %d file(s), %s, from the size totals and thresholds", result.Excluded.Files, cfg.Units().Format(result.Excluded.Bytes))
+}
+
+// ScopeContentName describes the entries selected by the code_only and assets_only analysis_scope
+func ScopeContentName(content string) string {
+	if content == config.AnalysisScopeCodeOnly {
+		return "code (DEX files, native libraries, JavaScript bundles and executables)"
+	}
+	return "assets (everything but the code)"
+}
@@ -0,0 +1,115 @@
+package analyze
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.map", path: "assets/www/main.js.map", want: true},
+		{pattern: "*.map", path: "assets/www/main.js", want: false},
+		{pattern: "assets/fixtures/", path: "assets/fixtures/users/large.json", want: true},
+		{pattern: "assets/fixtures/", path: "assets/fixtures.json", want: false},
+		{pattern: "lib/*/libflutter.so", path: "lib/arm64-v8a/libflutter.so", want: true},
+		{pattern: "lib/*/libflutter.so", path: "lib/arm64-v8a/x/libflutter.so", want: false},
+		{pattern: "Payload/**/*.dSYM/**", path: "Payload/App.app/Frameworks/A.framework/A.dSYM/Contents/Info.plist", want: true},
+		{pattern: "/res/raw/**", path: "res/raw/intro.mp4", want: true},
+		{pattern: "res/[", path: "res/[", want: false},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %t, want %t", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParsePatterns(t *testing.T) {
+	want := []string{"*.map", "assets/fixtures/", "res/raw/**"}
+	if got := ParsePatterns(" *.map,assets/fixtures/\n\n res/raw/** ,"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsePatterns() = %q, want %q", got, want)
+	}
+	if err := ValidatePatterns(want); err != nil {
+		t.Errorf("ValidatePatterns() error = %s", err)
+	}
+	if err := ValidatePatterns([]string{"*.map", "res/[raw/*"}); err == nil {
+		t.Errorf("ValidatePatterns() of a malformed pattern succeeded")
+	}
+}
+
+func TestApplyEntryScope(t *testing.T) {
+	inventory := Inventory{Entries: []ArchiveEntry{
+		{Path: "classes.dex", CompressedSize: 4000},
+		{Path: "assets/www/main.js.map", CompressedSize: 3000},
+		{Path: "assets/fixtures/users.json", CompressedSize: 2000},
+		{Path: "res/drawable/icon.png", CompressedSize: 1000},
+	}}
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		wantPaths    []string
+		wantSize     int64
+		wantExcluded ExcludedEntries
+	}{
+		{name: "exclude patterns", cfg: config.Config{ExcludePatterns: "*.map\nassets/fixtures/"},
+			wantPaths: []string{"classes.dex", "res/drawable/icon.png"}, wantSize: 5500, wantExcluded: ExcludedEntries{Files: 2, Bytes: 5000}},
+		// The included entries are summed, the archive overhead isn't counted
+		{name: "include patterns", cfg: config.Config{IncludePatterns: "assets/**"},
+			wantPaths: []string{"assets/www/main.js.map", "assets/fixtures/users.json"}, wantSize: 5000, wantExcluded: ExcludedEntries{Files: 2, Bytes: 5000}},
+		{name: "include and exclude patterns", cfg: config.Config{IncludePatterns: "assets/", ExcludePatterns: "*.map"},
+			wantPaths: []string{"assets/fixtures/users.json"}, wantSize: 2000, wantExcluded: ExcludedEntries{Files: 3, Bytes: 8000}},
+		{name: "code only", cfg: config.Config{AnalysisScope: config.AnalysisScopeCodeOnly},
+			wantPaths: []string{"classes.dex"}, wantSize: 4500, wantExcluded: ExcludedEntries{Files: 3, Bytes: 6000}},
+		{name: "assets only", cfg: config.Config{AnalysisScope: config.AnalysisScopeAssetsOnly, ExcludePatterns: "*.map"},
+			wantPaths: []string{"assets/fixtures/users.json", "res/drawable/icon.png"}, wantSize: 3500, wantExcluded: ExcludedEntries{Files: 2, Bytes: 7000}},
+		{name: "invalid pattern keeps the artifact", cfg: config.Config{ExcludePatterns: "*.map,res/[raw/*"},
+			wantPaths: []string{"classes.dex", "assets/www/main.js.map", "assets/fixtures/users.json", "res/drawable/icon.png"}, wantSize: 10500},
+		{name: "invalid include pattern keeps the artifact", cfg: config.Config{IncludePatterns: "assets/[www/**"},
+			wantPaths: []string{"classes.dex", "assets/www/main.js.map", "assets/fixtures/users.json", "res/drawable/icon.png"}, wantSize: 10500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The artifact size has 500 bytes of archive overhead over the entries
+			result := ArtifactResult{Inventory: inventory, Metrics: newBundleMetrics(10500, 0)}
+			applyEntryScopeFromConfig(tt.cfg, &result, log.NewLogger())
+
+			var paths []string
+			for _, entry := range result.Inventory.Entries {
+				paths = append(paths, entry.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("Inventory = %q, want %q", paths, tt.wantPaths)
+			}
+			if result.Metrics.SizeBytes != tt.wantSize || result.Excluded != tt.wantExcluded {
+				t.Errorf("SizeBytes = %d, Excluded = %+v, want %d, %+v", result.Metrics.SizeBytes, result.Excluded, tt.wantSize, tt.wantExcluded)
+			}
+		})
+	}
+}
+
+func TestIsCodeEntry(t *testing.T) {
+	tests := map[string]bool{
+		"classes2.dex":                                      true,
+		"base/lib/arm64-v8a/libapp.so":                      true,
+		"assets/index.android.bundle.hbc":                   true,
+		"Payload/App.app/main.jsbundle":                     true,
+		"Payload/App.app/App":                               true,
+		"Payload/App.app/Frameworks/A.framework/A":          true,
+		"Payload/App.app/PlugIns/Widget.appex/Widget":       true,
+		"Payload/App.app/Assets.car":                        false,
+		"Payload/App.app/Frameworks/A.framework/Info.plist": false,
+		"res/drawable/icon.png":                             false,
+	}
+	for entryPath, want := range tests {
+		if got := IsCodeEntry(entryPath); got != want {
+			t.Errorf("IsCodeEntry(%q) = %t, want %t", entryPath, got, want)
+		}
+	}
+}
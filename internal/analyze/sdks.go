@@ -0,0 +1,114 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// frameworkInfoPlistPattern matches the Info.plist of frameworks embedded in an IPA
+var frameworkInfoPlistPattern = regexp.MustCompile(`^Payload/[^/]+\.app/Frameworks/([^/]+)\.framework/Info\.plist$`)
+
+// mavenVersionFilePattern matches the version files AndroidX, Google Play services, Firebase and many other
+// Android libraries ship in META-INF, named <group>_<artifact>.version
+var mavenVersionFilePattern = regexp.MustCompile(`^(?:base/root/)?META-INF/([^/]+)_([^/_]+)\.version$`)
+
+// mavenPomPropertiesPattern matches the Maven metadata kept from the JARs of Java libraries
+var mavenPomPropertiesPattern = regexp.MustCompile(`^(?:base/root/)?META-INF/maven/([^/]+)/([^/]+)/pom\.properties$`)
+
+// DetectedSDK is a third-party SDK or framework bundled into the artifact
+type DetectedSDK struct {
+	// Name is the Maven coordinate (group:artifact) of Android libraries and the framework name of iOS frameworks
+	Name     string
+	Version  string
+	Platform string
+	Path     string
+}
+
+// DetectSDKs lists the versioned SDKs of the artifact: the embedded frameworks of IPAs and the libraries of APKs
+// and AABs shipping a META-INF version file or Maven metadata
+func DetectSDKs(artifactPath string) ([]DetectedSDK, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var sdks []DetectedSDK
+	for _, file := range reader.File {
+		if match := mavenVersionFilePattern.FindStringSubmatch(file.Name); match != nil {
+			version, err := readSmallEntry(file)
+			if err != nil || version == "" {
+				continue
+			}
+			sdks = append(sdks, DetectedSDK{Name: match[1] + ":" + match[2], Version: version, Platform: "android", Path: file.Name})
+			continue
+		}
+
+		if match := mavenPomPropertiesPattern.FindStringSubmatch(file.Name); match != nil {
+			properties, err := readSmallEntry(file)
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(properties, "\n") {
+				if version, ok := strings.CutPrefix(strings.TrimSpace(line), "version="); ok && version != "" {
+					sdks = append(sdks, DetectedSDK{Name: match[1] + ":" + match[2], Version: version, Platform: "android", Path: file.Name})
+				}
+			}
+			continue
+		}
+
+		if match := frameworkInfoPlistPattern.FindStringSubmatch(file.Name); match != nil {
+			entry, err := file.Open()
+			if err != nil {
+				continue
+			}
+			info, err := readPlistEntry(entry)
+			_ = entry.Close()
+			if err != nil {
+				continue
+			}
+			version := plistString(info, "CFBundleShortVersionString")
+			if version == "" {
+				continue
+			}
+			sdks = append(sdks, DetectedSDK{Name: match[1], Version: version, Platform: "ios", Path: path.Dir(file.Name)})
+		}
+	}
+
+	// Libraries may ship both a version file and Maven metadata
+	var unique []DetectedSDK
+	seen := map[string]bool{}
+	for _, sdk := range sdks {
+		key := sdk.Platform + " " + sdk.Name + "@" + sdk.Version
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, sdk)
+		}
+	}
+	sort.SliceStable(unique, func(i, j int) bool {
+		return unique[i].Name < unique[j].Name
+	})
+	return unique, nil
+}
+
+// readSmallEntry reads an entry of at most 4 KB, like a version file, and trims it
+func readSmallEntry(file *zip.File) (string, error) {
+	if file.UncompressedSize64 > 4096 {
+		return "", fmt.Errorf("%s is too large", file.Name)
+	}
+	entry, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer entry.Close()
+	data, err := io.ReadAll(entry)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,196 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// maxSecretScanEntrySize is the largest entry scanned for secrets, bigger files are media or databases
+const maxSecretScanEntrySize = 10 * 1024 * 1024
+
+// SecretDetector matches one kind of secret
+type SecretDetector struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// builtinSecretDetectors are the detectors used when secret_detectors doesn't select any of them
+var builtinSecretDetectors = []SecretDetector{
+	{Name: "aws_access_key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "google_api_key", Pattern: regexp.MustCompile(`AIza[0-9A-Za-z_\-]{35}`)},
+	{Name: "private_key", Pattern: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED |PGP )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{Name: "github_token", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[0-9A-Za-z]{36}|github_pat_[0-9A-Za-z_]{82})\b`)},
+	{Name: "slack_token", Pattern: regexp.MustCompile(`\bxox[abposr]-[0-9A-Za-z-]{10,}`)},
+	{Name: "stripe_secret_key", Pattern: regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{24,}\b`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[0-9A-Za-z_-]{10,}\.eyJ[0-9A-Za-z_-]{10,}\.[0-9A-Za-z_-]{10,}`)},
+}
+
+// secretScanExtensions are the text, plist and resource files scanned for secrets besides the asset directories
+var secretScanExtensions = map[string]bool{
+	".plist": true, ".strings": true, ".json": true, ".xml": true, ".properties": true, ".txt": true,
+	".js": true, ".html": true, ".yml": true, ".yaml": true, ".cfg": true, ".conf": true, ".pem": true,
+	".key": true, ".p8": true, ".env": true, ".arsc": true,
+}
+
+// secretScanDirs are the asset directories of APKs, AABs and Flutter and React Native bundles, every file in them is scanned
+var secretScanDirs = []string{"assets/", "res/raw/", "base/assets/", "base/res/raw/", "flutter_assets/"}
+
+// SecretFinding is a potential secret found in the artifact, the value is masked
+type SecretFinding struct {
+	Detector string
+	Path     string
+	Masked   string
+}
+
+// ParseSecretDetectors parses secret_detectors: one entry per line, the name of a built-in detector
+// or a custom detector in the "name: regex" format. All built-in detectors are used unless some are listed.
+func ParseSecretDetectors(input string) ([]SecretDetector, error) {
+	var builtins, custom []SecretDetector
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		// Regular expressions may contain colons, the name ends at the first one
+		if name, pattern, ok := strings.Cut(line, ":"); ok {
+			name, pattern = strings.TrimSpace(name), strings.TrimSpace(pattern)
+			re, err := regexp.Compile(pattern)
+			if err != nil || name == "" || pattern == "" {
+				return nil, fmt.Errorf("invalid custom detector %q, expected format: name: regex", line)
+			}
+			custom = append(custom, SecretDetector{Name: name, Pattern: re})
+			continue
+		}
+
+		detector, ok := builtinSecretDetector(line)
+		if !ok {
+			return nil, fmt.Errorf("unknown detector %q", line)
+		}
+		builtins = append(builtins, detector)
+	}
+
+	if len(builtins) == 0 {
+		builtins = builtinSecretDetectors
+	}
+	return append(builtins, custom...), nil
+}
+
+// builtinSecretDetector returns the built-in detector with the name
+func builtinSecretDetector(name string) (SecretDetector, bool) {
+	for _, detector := range builtinSecretDetectors {
+		if detector.Name == name {
+			return detector, true
+		}
+	}
+	return SecretDetector{}, false
+}
+
+// isSecretScanCandidate checks whether an entry is a bundled asset, plist or string resource worth scanning
+func isSecretScanCandidate(entryPath string) bool {
+	if secretScanExtensions[strings.ToLower(path.Ext(entryPath))] {
+		return true
+	}
+	for _, dir := range secretScanDirs {
+		if strings.HasPrefix(entryPath, dir) || strings.Contains(entryPath, "/"+dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanSecrets scans the bundled assets, plists and string resources of the artifact with the detectors.
+// The same value found multiple times in a file is reported once.
+func ScanSecrets(artifactPath string, detectors []SecretDetector, logger log.Logger) ([]SecretFinding, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var findings []SecretFinding
+	scanned := 0
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || file.UncompressedSize64 > maxSecretScanEntrySize || !isSecretScanCandidate(file.Name) {
+			continue
+		}
+
+		data, err := readZipEntry(file)
+		if err != nil {
+			logger.Warnf("Failed to read %s: %s", file.Name, err)
+			continue
+		}
+		scanned++
+
+		seen := map[string]bool{}
+		for _, detector := range detectors {
+			for _, match := range detector.Pattern.FindAll(data, -1) {
+				if seen[string(match)] {
+					continue
+				}
+				seen[string(match)] = true
+				findings = append(findings, SecretFinding{Detector: detector.Name, Path: file.Name, Masked: maskSecret(detector.Name, string(match))})
+			}
+		}
+	}
+	logger.Printf("Scanned %d file(s) with %d detector(s)", scanned, len(detectors))
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Path < findings[j].Path
+	})
+	return findings, nil
+}
+
+// readZipEntry reads the content of an entry of the artifact
+func readZipEntry(file *zip.File) ([]byte, error) {
+	entry, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer entry.Close()
+	return io.ReadAll(entry)
+}
+
+// maskSecret keeps the first 4 characters of a secret, enough to recognize its kind (e.g. AKIA), the rest is masked.
+// Private key headers aren't secret, they are kept as they are.
+func maskSecret(detector, value string) string {
+	if detector == "private_key" {
+		return value
+	}
+	if len(value) <= 8 {
+		return "********"
+	}
+	return value[:4] + "********"
+}
+
+// scanSecretsFromConfig scans the artifact with the detectors of secret_detectors and logs the findings,
+// false is returned when the artifact couldn't be scanned
+func scanSecretsFromConfig(cfg config.Config, artifactPath string, logger log.Logger) ([]SecretFinding, bool) {
+	detectors, err := ParseSecretDetectors(cfg.SecretDetectors)
+	if err != nil {
+		logger.Warnf("Invalid secret_detectors value: %s", err)
+		return nil, false
+	}
+
+	logger.Infof("Scanning for secrets...")
+	findings, err := ScanSecrets(artifactPath, detectors, logger)
+	if err != nil {
+		logger.Warnf("Failed to scan for secrets: %s", err)
+		return nil, false
+	}
+	for _, finding := range findings {
+		logger.Printf("- %s in %s: %s", finding.Detector, finding.Path, finding.Masked)
+	}
+	if len(findings) == 0 {
+		logger.Donef("No secrets found")
+	}
+	return findings, true
+}
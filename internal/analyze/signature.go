@@ -0,0 +1,204 @@
+package analyze
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Signature statuses of an artifact
+const (
+	// SignatureSigned is a valid release signature
+	SignatureSigned = "signed"
+	// SignatureDebug is a valid signature made with the Android debug keystore or an Apple development certificate
+	SignatureDebug = "debug-signed"
+	// SignatureUnsigned is a missing signature or an ad-hoc signature without a signing identity
+	SignatureUnsigned = "unsigned"
+	// SignatureInvalid is a signature which doesn't match the content of the artifact
+	SignatureInvalid = "invalid"
+)
+
+// androidDebugSubject is the common name of the certificate of the debug keystore the Android Gradle plugin creates
+const androidDebugSubject = "Android Debug"
+
+// androidTestKeySubject is the common name, organization and unit of the public AOSP test keys
+// (testkey, platform, shared, media) platform builds are signed with by default
+const androidTestKeySubject = "Android"
+
+// appleDevelopmentPrefixes start the common names of the Apple certificates used for development builds
+var appleDevelopmentPrefixes = []string{"Apple Development:", "iPhone Developer:", "Mac Developer:"}
+
+// SignatureInfo is the verified signature of an artifact
+type SignatureInfo struct {
+	Status string
+	// Schemes are the signature schemes found: v1, v2, v3 and v3.1 of APKs and AABs, codesign of IPAs
+	Schemes []string
+	// Signer is the subject of the signing certificate
+	Signer string
+	// Fingerprint is the SHA-256 fingerprint of the signing certificate, in the format of the Play Console
+	Fingerprint string
+	// TeamID is the Apple team of IPAs
+	TeamID   string
+	NotAfter time.Time
+	// Problems explain the debug, unsigned and invalid statuses
+	Problems []string
+}
+
+// Describe summarizes the status of the signature in a sentence
+func (s SignatureInfo) Describe() string {
+	if s.Status == SignatureInvalid {
+		return "The signature of the artifact is invalid"
+	}
+	return "Artifact is " + s.Status
+}
+
+// certificateFingerprint returns the SHA-256 fingerprint of a certificate as colon separated uppercase hex
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for idx, b := range sum {
+		parts[idx] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// describeCertificate fills the signer details of the signature info from the signing certificate
+func (s *SignatureInfo) describeCertificate(cert *x509.Certificate) {
+	s.Signer = cert.Subject.String()
+	s.Fingerprint = certificateFingerprint(cert)
+	s.NotAfter = cert.NotAfter
+}
+
+// androidDebugCertificate returns why an APK signing certificate isn't fit for release, empty for release certificates
+func androidDebugCertificate(cert *x509.Certificate) string {
+	subject := cert.Subject
+	switch {
+	case subject.CommonName == androidDebugSubject:
+		return "signed with the Android debug keystore"
+	case subject.CommonName == androidTestKeySubject && len(subject.Organization) == 1 && subject.Organization[0] == androidTestKeySubject &&
+		len(subject.OrganizationalUnit) == 1 && subject.OrganizationalUnit[0] == androidTestKeySubject:
+		return "signed with a public AOSP test key"
+	}
+	return ""
+}
+
+// pkcs7ContentInfo is the ContentInfo of a PKCS #7 / CMS signature
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7SignedData holds the fields of a PKCS #7 SignedData up to the certificates, the signer infos aren't needed
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// pkcs7Certificates returns the certificates embedded into a PKCS #7 / CMS signature, like the
+// META-INF/*.RSA files of JAR signatures and the CMS blob of Apple code signatures
+func pkcs7Certificates(der []byte) ([]*x509.Certificate, error) {
+	var contentInfo pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &contentInfo); err != nil {
+		return nil, fmt.Errorf("invalid PKCS #7 signature: %w", err)
+	}
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+		return nil, fmt.Errorf("invalid PKCS #7 signed data: %w", err)
+	}
+	if len(signedData.Certificates.Bytes) == 0 {
+		return nil, nil
+	}
+	return x509.ParseCertificates(signedData.Certificates.Bytes)
+}
+
+// leafCertificate returns the certificate of the signer from a certificate chain: the first one which isn't a CA
+func leafCertificate(certs []*x509.Certificate) *x509.Certificate {
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return cert
+		}
+	}
+	if len(certs) > 0 {
+		return certs[0]
+	}
+	return nil
+}
+
+// VerifySignature verifies the signature of an APK, AAB or IPA and reports its signer
+func VerifySignature(artifactPath, workDir string) (SignatureInfo, error) {
+	switch strings.ToLower(path.Ext(artifactPath)) {
+	case ".apk":
+		return verifyAPKSignature(artifactPath)
+	case ".aab":
+		return verifyJARSignature(artifactPath)
+	case ".ipa":
+		return verifyCodeSignature(artifactPath, workDir)
+	}
+	return SignatureInfo{}, fmt.Errorf("signature verification supports APK, AAB and IPA artifacts")
+}
+
+// verifyAndLogSignature verifies the signature of the artifact and logs it,
+// false is returned when the artifact couldn't be checked
+func verifyAndLogSignature(artifactPath, workDir string, logger log.Logger) (SignatureInfo, bool) {
+	logger.Infof("Verifying signature...")
+	info, err := VerifySignature(artifactPath, workDir)
+	if err != nil {
+		logger.Warnf("Failed to verify signature: %s", err)
+		return SignatureInfo{}, false
+	}
+
+	if len(info.Schemes) > 0 {
+		logger.Printf("- Schemes: %s", strings.Join(info.Schemes, ", "))
+	}
+	if info.Signer != "" {
+		logger.Printf("- Signer: %s", info.Signer)
+		logger.Printf("- SHA-256 fingerprint: %s", info.Fingerprint)
+		logger.Printf("- Expires: %s", info.NotAfter.Format("2006-01-02"))
+	}
+	if info.TeamID != "" {
+		logger.Printf("- Team: %s", info.TeamID)
+	}
+	for _, problem := range info.Problems {
+		logger.Warnf("- %s", problem)
+	}
+	if info.Status == SignatureSigned {
+		logger.Donef("%s", info.Describe())
+	} else {
+		logger.Warnf("%s", info.Describe())
+	}
+	return info, true
+}
+
+// warnCertificateExpiry warns when the signing certificate expired or expires within the configured number of days
+func warnCertificateExpiry(value string, info SignatureInfo, now time.Time, logger log.Logger) {
+	if info.NotAfter.IsZero() {
+		return
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		logger.Warnf("Invalid cert_expiry_warning_days value: %s", value)
+		return
+	}
+	warnExpiry("signing certificate", info.NotAfter, days, now, logger)
+}
+
+// warnExpiry warns when a certificate or profile expired or expires within the given number of days
+func warnExpiry(subject string, expiry time.Time, days int, now time.Time, logger log.Logger) {
+	remaining := expiry.Sub(now)
+	switch {
+	case remaining < 0:
+		logger.Warnf("The %s expired on %s", subject, expiry.Format("2006-01-02"))
+	case remaining < time.Duration(days)*24*time.Hour:
+		logger.Warnf("The %s expires in %d days, on %s", subject, int(math.Ceil(remaining.Hours()/24)), expiry.Format("2006-01-02"))
+	}
+}
@@ -0,0 +1,190 @@
+package analyze
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/logging"
+)
+
+// selfSignedCertificate creates a signing certificate with the given common name
+func selfSignedCertificate(t *testing.T, commonName string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// pkcs7Block wraps a certificate into a PKCS #7 SignedData without signer infos, only the certificate is read from it
+func pkcs7Block(t *testing.T, cert *x509.Certificate) []byte {
+	t.Helper()
+	marshal := func(value interface{}) []byte {
+		der, err := asn1.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+	signedData := marshal(struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue
+	}{
+		Version:          1,
+		DigestAlgorithms: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true},
+		ContentInfo:      asn1.RawValue{FullBytes: marshal(struct{ ContentType asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}})},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: cert.Raw},
+	})
+	return marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2},
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+}
+
+func sha256Digest(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// jarSignedEntries signs the entries with a v1 (JAR) signature of the certificate and returns them with the signature files
+func jarSignedEntries(t *testing.T, entries []zipEntry, cert *x509.Certificate) []zipEntry {
+	t.Helper()
+	manifest := "Manifest-Version: 1.0\r\nCreated-By: test\r\n\r\n"
+	for _, entry := range entries {
+		manifest += fmt.Sprintf("Name: %s\r\nSHA-256-Digest: %s\r\n\r\n", entry.name, sha256Digest(entry.content))
+	}
+	signature := fmt.Sprintf("Signature-Version: 1.0\r\nSHA-256-Digest-Manifest: %s\r\n\r\n", sha256Digest(manifest))
+	return append([]zipEntry{
+		{name: "META-INF/MANIFEST.MF", content: manifest},
+		{name: "META-INF/CERT.SF", content: signature},
+		{name: "META-INF/CERT.RSA", content: string(pkcs7Block(t, cert))},
+	}, entries...)
+}
+
+func TestVerifySignature(t *testing.T) {
+	content := []zipEntry{
+		{name: "AndroidManifest.xml", content: "<manifest/>"},
+		{name: "classes.dex", content: strings.Repeat("dex", 100)},
+	}
+	release := selfSignedCertificate(t, "Example Release")
+
+	tampered := jarSignedEntries(t, content, release)
+	tampered[len(tampered)-1].content = "patched"
+
+	unsignedEntry := append(jarSignedEntries(t, content, release), zipEntry{name: "assets/injected.js", content: "alert(1)"})
+
+	modifiedManifest := jarSignedEntries(t, content, release)
+	modifiedManifest[0].content += "Name: assets/extra\r\nSHA-256-Digest: " + sha256Digest("extra") + "\r\n\r\n"
+
+	tests := []struct {
+		name        string
+		artifact    string
+		entries     []zipEntry
+		wantStatus  string
+		wantSigner  string
+		wantProblem string
+	}{
+		{name: "release signature", artifact: "app.aab", entries: jarSignedEntries(t, content, release), wantStatus: SignatureSigned, wantSigner: "CN=Example Release"},
+		{name: "APK without signing block", artifact: "app.apk", entries: jarSignedEntries(t, content, release), wantStatus: SignatureSigned, wantSigner: "CN=Example Release"},
+		{name: "debug keystore", artifact: "app.aab", entries: jarSignedEntries(t, content, selfSignedCertificate(t, androidDebugSubject)),
+			wantStatus: SignatureDebug, wantSigner: "CN=Android Debug", wantProblem: "signed with the Android debug keystore"},
+		{name: "unsigned", artifact: "app.aab", entries: content, wantStatus: SignatureUnsigned, wantProblem: "the artifact isn't signed"},
+		{name: "modified entry", artifact: "app.aab", entries: tampered, wantStatus: SignatureInvalid, wantSigner: "CN=Example Release", wantProblem: "1 entries don't match their signed digests: classes.dex"},
+		{name: "entry added after signing", artifact: "app.aab", entries: unsignedEntry, wantStatus: SignatureInvalid, wantSigner: "CN=Example Release", wantProblem: "1 entries aren't covered by the signature: assets/injected.js"},
+		{name: "modified manifest", artifact: "app.aab", entries: modifiedManifest, wantStatus: SignatureInvalid, wantSigner: "CN=Example Release", wantProblem: "META-INF/CERT.SF doesn't match the digest of the manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifactPath := filepath.Join(t.TempDir(), tt.artifact)
+			if err := os.WriteFile(artifactPath, writeZip(t, tt.entries, zip.Deflate), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := VerifySignature(artifactPath, t.TempDir())
+			if err != nil {
+				t.Fatalf("VerifySignature() error = %s", err)
+			}
+			if info.Status != tt.wantStatus {
+				t.Errorf("Status = %s (%q), want %s", info.Status, info.Problems, tt.wantStatus)
+			}
+			if info.Signer != tt.wantSigner {
+				t.Errorf("Signer = %q, want %q", info.Signer, tt.wantSigner)
+			}
+			if tt.wantSigner != "" && (len(info.Fingerprint) != 95 || len(info.Schemes) != 1 || info.Schemes[0] != "v1") {
+				t.Errorf("Fingerprint = %s, Schemes = %v", info.Fingerprint, info.Schemes)
+			}
+			if tt.wantProblem != "" && !strings.Contains(strings.Join(info.Problems, "\n"), tt.wantProblem) {
+				t.Errorf("Problems = %q, want %q", info.Problems, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureUnsupportedArtifact(t *testing.T) {
+	if _, err := VerifySignature(filepath.Join(t.TempDir(), "app.zip"), t.TempDir()); err == nil {
+		t.Errorf("VerifySignature() of a zip succeeded")
+	}
+}
+
+func TestWarnCertificateExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		value    string
+		notAfter time.Time
+		want     string
+	}{
+		{name: "expired", value: "30", notAfter: now.Add(-time.Hour), want: "The signing certificate expired on 2026-03-01"},
+		{name: "expires soon", value: "30", notAfter: now.Add(9*24*time.Hour + time.Hour), want: "The signing certificate expires in 10 days, on 2026-03-10"},
+		{name: "expires later", value: "30", notAfter: now.Add(31 * 24 * time.Hour)},
+		{name: "unknown expiry", value: "30"},
+		{name: "invalid value", value: "soon", notAfter: now.Add(time.Hour), want: "Invalid cert_expiry_warning_days value: soon"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			warnCertificateExpiry(tt.value, SignatureInfo{NotAfter: tt.notAfter}, now, logging.NewJSONLogger(&out))
+			if tt.want == "" && out.Len() > 0 {
+				t.Errorf("warnCertificateExpiry() logged %s", out.String())
+			}
+			if tt.want != "" && !strings.Contains(out.String(), `"level":"warn","message":"`+tt.want+`"`) {
+				t.Errorf("warnCertificateExpiry() logged %s, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package analyze
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Symbol file kinds checked by check_debug_symbols
+const (
+	SymbolsDSYM        = "dSYM"
+	SymbolsMapping     = "R8 mapping file"
+	SymbolsNativeDebug = "native debug symbols"
+)
+
+// symbolSource describes where the build steps put a kind of symbol file
+type symbolSource struct {
+	kind string
+	// envs are the variables the build steps export the path with, like BITRISE_DSYM_PATH of the Xcode archive step
+	envs []string
+	// bundleMetadata is the directory of the AAB bundle metadata embedding the files
+	bundleMetadata string
+	// siblings are the file names the files are expected under next to the artifact
+	siblings []string
+}
+
+var (
+	dsymSource = symbolSource{
+		kind: SymbolsDSYM,
+		envs: []string{"BITRISE_DSYM_PATH", "BITRISE_DSYM_DIR_PATH"},
+	}
+	mappingSource = symbolSource{
+		kind:           SymbolsMapping,
+		envs:           []string{"BITRISE_MAPPING_PATH"},
+		bundleMetadata: "BUNDLE-METADATA/com.android.tools.build.obfuscation/",
+		siblings:       []string{"mapping.txt"},
+	}
+	nativeDebugSource = symbolSource{
+		kind:           SymbolsNativeDebug,
+		bundleMetadata: "BUNDLE-METADATA/com.android.tools.build.debugsymbols/",
+		siblings:       []string{"native-debug-symbols.zip"},
+	}
+)
+
+// SymbolFile is a symbol file the crash reports of the artifact need to be symbolicated or deobfuscated
+type SymbolFile struct {
+	Kind string
+	// Location is the variable, the bundle metadata directory or the file the symbols were found in, empty if missing
+	Location string
+	Path     string
+}
+
+// Present reports whether the symbol file was found
+func (f SymbolFile) Present() bool {
+	return f.Location != ""
+}
+
+// DebugSymbols are the symbol files the artifact needs, an artifact without native code or minification needs none
+type DebugSymbols struct {
+	Files []SymbolFile
+}
+
+// Present reports whether every needed symbol file was found
+func (s DebugSymbols) Present() bool {
+	return len(s.Missing()) == 0
+}
+
+// Missing returns the symbol files that weren't found
+func (s DebugSymbols) Missing() []SymbolFile {
+	var missing []SymbolFile
+	for _, file := range s.Files {
+		if !file.Present() {
+			missing = append(missing, file)
+		}
+	}
+	return missing
+}
+
+// CheckDebugSymbols looks for the symbol files of the artifact: the dSYMs of an IPA, the R8 mapping file of minified
+// Android code and the native debug symbols of Android native libraries. They are looked up in the variables of the
+// build steps, the bundle metadata of an AAB and next to the artifact.
+func CheckDebugSymbols(artifactPath string, result ArtifactResult, envRepo env.Repository) (DebugSymbols, error) {
+	reader, err := zip.OpenReader(artifactPath)
+	if err != nil {
+		return DebugSymbols{}, fmt.Errorf("failed to open artifact as zip archive: %w", err)
+	}
+	defer reader.Close()
+
+	var sources []symbolSource
+	if strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		sources = append(sources, dsymSource)
+	} else {
+		if result.Metrics.ObfuscationMeasured && result.Metrics.ObfuscationCoverage >= unminifiedCoverageBelow {
+			sources = append(sources, mappingSource)
+		}
+		for _, file := range reader.File {
+			if isNativeLibraryEntry(file.Name) {
+				sources = append(sources, nativeDebugSource)
+				break
+			}
+		}
+	}
+
+	var symbols DebugSymbols
+	for _, source := range sources {
+		symbols.Files = append(symbols.Files, findSymbolFile(source, artifactPath, &reader.Reader, envRepo))
+	}
+	return symbols, nil
+}
+
+// isNativeLibraryEntry reports whether the entry is a native library of an APK (lib/<abi>/) or AAB (<module>/lib/<abi>/)
+func isNativeLibraryEntry(name string) bool {
+	if !strings.HasSuffix(name, ".so") {
+		return false
+	}
+	parts := strings.Split(name, "/")
+	return (len(parts) == 3 && parts[0] == "lib") || (len(parts) == 4 && parts[1] == "lib")
+}
+
+// findSymbolFile looks up the symbol file of the source, a variable pointing to a missing file doesn't count
+func findSymbolFile(source symbolSource, artifactPath string, reader *zip.Reader, envRepo env.Repository) SymbolFile {
+	file := SymbolFile{Kind: source.kind}
+	for _, name := range source.envs {
+		if value := envRepo.Get(name); value != "" {
+			if _, err := os.Stat(value); err == nil {
+				file.Location, file.Path = "$"+name, value
+				return file
+			}
+		}
+	}
+	if source.bundleMetadata != "" {
+		for _, entry := range reader.File {
+			if strings.HasPrefix(entry.Name, source.bundleMetadata) && !entry.FileInfo().IsDir() {
+				file.Location, file.Path = "AAB bundle metadata", entry.Name
+				return file
+			}
+		}
+	}
+	for _, name := range source.siblings {
+		sibling := filepath.Join(filepath.Dir(artifactPath), name)
+		if _, err := os.Stat(sibling); err == nil {
+			file.Location, file.Path = "next to the artifact", sibling
+			return file
+		}
+	}
+	return file
+}
+
+// checkDebugSymbolsFromConfig checks the symbol files for check_debug_symbols and warns about the missing ones, crash
+// reports of a release without them can't be read
+func checkDebugSymbolsFromConfig(artifactPath string, result ArtifactResult, envRepo env.Repository, logger log.Logger) (DebugSymbols, bool) {
+	logger.Infof("Checking debug symbols...")
+	symbols, err := CheckDebugSymbols(artifactPath, result, envRepo)
+	if err != nil {
+		logger.Warnf("Failed to check debug symbols: %s", err)
+		return DebugSymbols{}, false
+	}
+
+	if len(symbols.Files) == 0 {
+		logger.Printf("No native code or minified code needing debug symbols")
+		return symbols, true
+	}
+	for _, file := range symbols.Files {
+		if file.Present() {
+			logger.Printf("%s: %s (%s)", file.Kind, file.Path, file.Location)
+		} else {
+			logger.Warnf("No %s found for %s, the stack traces of its crash reports stay unreadable", file.Kind, result.Name)
+		}
+	}
+	if symbols.Present() {
+		logger.Donef("All debug symbols found")
+	}
+	return symbols, true
+}
@@ -0,0 +1,106 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDebugSymbols(t *testing.T) {
+	ipa := []zipEntry{{name: "Payload/App.app/App", content: "not a Mach-O"}}
+	native := []zipEntry{{name: "base/lib/arm64-v8a/libapp.so", content: "not an ELF"}}
+	minified := ArtifactResult{Metrics: BundleMetrics{ObfuscationMeasured: true, ObfuscationCoverage: 85}}
+	unminified := ArtifactResult{Metrics: BundleMetrics{ObfuscationMeasured: true, ObfuscationCoverage: 0.5}}
+
+	tests := []struct {
+		name        string
+		artifact    string
+		entries     []zipEntry
+		result      ArtifactResult
+		envs        map[string]string
+		siblings    []string
+		want        []string
+		wantPresent bool
+	}{
+		{name: "dSYM of the Xcode archive step", artifact: "App.ipa", entries: ipa, envs: map[string]string{"BITRISE_DSYM_PATH": "App.dSYM.zip"},
+			want: []string{"dSYM: $BITRISE_DSYM_PATH"}, wantPresent: true},
+		{name: "missing dSYM", artifact: "App.ipa", entries: ipa, want: []string{"dSYM: missing"}},
+		{name: "dSYM variable of a deleted file", artifact: "App.ipa", entries: ipa, envs: map[string]string{"BITRISE_DSYM_DIR_PATH": "/tmp/missing/dSYMs"},
+			want: []string{"dSYM: missing"}},
+		{name: "unminified code without native libraries", artifact: "app.aab", result: unminified, entries: []zipEntry{{name: "base/dex/classes.dex", content: "dex"}}, wantPresent: true},
+		{name: "symbols in the bundle metadata", artifact: "app.aab", result: minified, entries: append([]zipEntry{
+			{name: "BUNDLE-METADATA/com.android.tools.build.obfuscation/proguard.map", content: "mapping"},
+			{name: "BUNDLE-METADATA/com.android.tools.build.debugsymbols/arm64-v8a/libapp.so.sym", content: "symbols"},
+		}, native...), want: []string{"R8 mapping file: AAB bundle metadata", "native debug symbols: AAB bundle metadata"}, wantPresent: true},
+		{name: "symbols next to the artifact", artifact: "app.apk", result: minified, entries: []zipEntry{{name: "lib/x86_64/libapp.so", content: "not an ELF"}},
+			siblings: []string{"mapping.txt", "native-debug-symbols.zip"}, want: []string{"R8 mapping file: next to the artifact", "native debug symbols: next to the artifact"}, wantPresent: true},
+		{name: "missing native debug symbols", artifact: "app.aab", result: unminified, entries: native, want: []string{"native debug symbols: missing"}},
+		{name: "mapping of the Gradle step", artifact: "app.aab", result: minified, entries: native, envs: map[string]string{"BITRISE_MAPPING_PATH": "mapping.txt"},
+			want: []string{"R8 mapping file: $BITRISE_MAPPING_PATH", "native debug symbols: missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			artifactPath := filepath.Join(dir, tt.artifact)
+			if err := os.WriteFile(artifactPath, writeZip(t, tt.entries, zip.Deflate), 0644); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.siblings {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("symbols"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The variables point to files in the build dir, unless they name an absolute path
+			envRepo := fakeEnvRepository{}
+			symbolsDir := t.TempDir()
+			for key, value := range tt.envs {
+				if !filepath.IsAbs(value) {
+					value = filepath.Join(symbolsDir, value)
+					if err := os.WriteFile(value, []byte("symbols"), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				envRepo[key] = value
+			}
+
+			symbols, err := CheckDebugSymbols(artifactPath, tt.result, envRepo)
+			if err != nil {
+				t.Fatalf("CheckDebugSymbols() error = %s", err)
+			}
+			var got []string
+			for _, file := range symbols.Files {
+				location := file.Location
+				if !file.Present() {
+					location = "missing"
+				}
+				got = append(got, file.Kind+": "+location)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("CheckDebugSymbols() = %q, want %q", got, tt.want)
+			}
+			if symbols.Present() != tt.wantPresent || len(symbols.Missing()) != strings.Count(strings.Join(tt.want, "\n"), ": missing") {
+				t.Errorf("Present() = %t with %d missing, want %t", symbols.Present(), len(symbols.Missing()), tt.wantPresent)
+			}
+		})
+	}
+}
+
+func TestIsNativeLibraryEntry(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "lib/arm64-v8a/libapp.so", want: true},
+		{name: "base/lib/armeabi-v7a/libapp.so", want: true},
+		{name: "feature/lib/x86/libfeature.so", want: true},
+		{name: "lib/arm64-v8a/libapp.so.txt"},
+		{name: "libapp.so"},
+	}
+	for _, tt := range tests {
+		if got := isNativeLibraryEntry(tt.name); got != tt.want {
+			t.Errorf("isNativeLibraryEntry(%s) = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package analyze
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// ErrAnalysisTimedOut is returned when the analysis doesn't finish within analysis_timeout
+var ErrAnalysisTimedOut = errors.New("analysis timed out")
+
+// ErrAnalysisCanceled is returned when the step is canceled (the build was aborted) during the analysis
+var ErrAnalysisCanceled = errors.New("analysis canceled")
+
+// ParseAnalysisTimeout parses analysis_timeout, a Go duration (e.g. 10m) or a number of seconds
+func ParseAnalysisTimeout(value string) (time.Duration, error) {
+	return executor.ParseDuration(value)
+}
+
+// analysisError maps the error of a plugin run stopped by the analysis context to ErrAnalysisTimedOut or ErrAnalysisCanceled
+func analysisError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrAnalysisTimedOut
+	case errors.Is(err, context.Canceled):
+		return ErrAnalysisCanceled
+	}
+	return err
+}
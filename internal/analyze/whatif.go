@@ -0,0 +1,196 @@
+package analyze
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// Scenarios of whatif_scenarios
+const (
+	// WhatIfWebP converts the PNG images to lossless WebP
+	WhatIfWebP = "webp"
+	// WhatIfStripLocales removes the localizations outside of whatif_keep_locales
+	WhatIfStripLocales = "strip_locales"
+	// WhatIfDedupe keeps a single copy of the byte-identical files
+	WhatIfDedupe = "dedupe"
+)
+
+// whatIfScenarioNames are the scenarios in the order they're simulated and reported
+var whatIfScenarioNames = []string{WhatIfWebP, WhatIfStripLocales, WhatIfDedupe}
+
+// webpSavingsRatio is the share of the PNG size typically saved by lossless WebP, 26% in Google's comparison
+const webpSavingsRatio = 0.26
+
+// WhatIfScenario is the projected effect of an optimization applied virtually to the artifact
+type WhatIfScenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Files is the number of files the optimization changes or removes
+	Files        int   `json:"files"`
+	SavingsBytes int64 `json:"savings_bytes"`
+	// ProjectedBytes is the artifact size with only this optimization applied
+	ProjectedBytes int64 `json:"projected_bytes"`
+	// Skipped is the reason the scenario couldn't be simulated, empty if it was
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// ParseWhatIfScenarios returns the scenarios of whatif_scenarios in the simulation order, all expands to every scenario
+func ParseWhatIfScenarios(input string) ([]string, error) {
+	selected := map[string]bool{}
+	for _, name := range ParsePatterns(input) {
+		name = strings.ToLower(name)
+		switch name {
+		case "all":
+			for _, scenario := range whatIfScenarioNames {
+				selected[scenario] = true
+			}
+		case WhatIfWebP, WhatIfStripLocales, WhatIfDedupe:
+			selected[name] = true
+		default:
+			return nil, fmt.Errorf("unknown scenario %s, expected %s or all", name, strings.Join(whatIfScenarioNames, ", "))
+		}
+	}
+
+	var scenarios []string
+	for _, scenario := range whatIfScenarioNames {
+		if selected[scenario] {
+			scenarios = append(scenarios, scenario)
+		}
+	}
+	return scenarios, nil
+}
+
+// SimulateWhatIf projects the artifact size of each scenario separately from the inventory of the result, the
+// savings of the scenarios don't add up when they change the same files. The sizes are the compressed entry sizes.
+func SimulateWhatIf(artifactPath string, result ArtifactResult, scenarios, keepLocales []string, quick bool, logger log.Logger) []WhatIfScenario {
+	var simulated []WhatIfScenario
+	for _, name := range scenarios {
+		var scenario WhatIfScenario
+		switch name {
+		case WhatIfWebP:
+			scenario = simulateWebP(result.Inventory)
+		case WhatIfStripLocales:
+			scenario = simulateStripLocales(artifactPath, result.Inventory, keepLocales)
+		case WhatIfDedupe:
+			scenario = simulateDedupe(artifactPath, result.Scope, quick, logger)
+		}
+		scenario.Name = name
+		if scenario.SavingsBytes > result.Metrics.SizeBytes {
+			scenario.SavingsBytes = result.Metrics.SizeBytes
+		}
+		scenario.ProjectedBytes = result.Metrics.SizeBytes - scenario.SavingsBytes
+		simulated = append(simulated, scenario)
+	}
+	return simulated
+}
+
+// simulateWebP estimates the savings of converting the PNG images, nine-patch images are kept as Android requires
+// them as PNG
+func simulateWebP(inventory Inventory) WhatIfScenario {
+	scenario := WhatIfScenario{Description: "Convert the PNG images to lossless WebP"}
+	for _, entry := range inventory.Entries {
+		lower := strings.ToLower(entry.Path)
+		if path.Ext(lower) != ".png" || strings.HasSuffix(lower, ".9.png") {
+			continue
+		}
+		scenario.Files++
+		scenario.SavingsBytes += int64(float64(entry.CompressedSize) * webpSavingsRatio)
+	}
+	return scenario
+}
+
+// simulateStripLocales sums the .lproj localization directories of an IPA outside of the kept locales.
+// Base.lproj is always kept. The localized strings of Android artifacts are compiled into resources.arsc, their size
+// can't be told apart so the scenario is skipped.
+func simulateStripLocales(artifactPath string, inventory Inventory, keepLocales []string) WhatIfScenario {
+	scenario := WhatIfScenario{Description: fmt.Sprintf("Remove the localizations except %s", strings.Join(keepLocales, ", "))}
+	if !strings.EqualFold(path.Ext(artifactPath), ".ipa") {
+		scenario.Skipped = "the localized resources of Android artifacts are compiled into resources.arsc"
+		return scenario
+	}
+
+	for _, entry := range inventory.Entries {
+		locale, ok := entryLocale(entry.Path)
+		if !ok || keptLocale(locale, keepLocales) {
+			continue
+		}
+		scenario.Files++
+		scenario.SavingsBytes += entry.CompressedSize
+	}
+	return scenario
+}
+
+// entryLocale returns the locale of the .lproj directory the entry is in, like de for Payload/App.app/de.lproj/Localizable.strings
+func entryLocale(entryPath string) (string, bool) {
+	segments := strings.Split(entryPath, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if locale, ok := strings.CutSuffix(segment, ".lproj"); ok {
+			return locale, true
+		}
+	}
+	return "", false
+}
+
+// keptLocale reports whether the locale is kept, a language keeps its regional variants (en keeps en-GB and en_AU)
+func keptLocale(locale string, keepLocales []string) bool {
+	if strings.EqualFold(locale, "Base") {
+		return true
+	}
+	language, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	for _, keep := range keepLocales {
+		if strings.EqualFold(keep, locale) || strings.EqualFold(keep, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// simulateDedupe sums the compressed size of the extra copies of the byte-identical files
+func simulateDedupe(artifactPath string, scope EntryScope, quick bool, logger log.Logger) WhatIfScenario {
+	scenario := WhatIfScenario{Description: "Keep a single copy of the byte-identical files"}
+	if quick {
+		scenario.Skipped = fmt.Sprintf("the duplicate detection doesn't run with analysis_scope %s", config.AnalysisScopeQuick)
+		return scenario
+	}
+	sets, err := FindDuplicates(artifactPath, scope, logger)
+	if err != nil {
+		scenario.Skipped = err.Error()
+		return scenario
+	}
+	for _, set := range sets {
+		scenario.Files += len(set.Paths) - 1
+	}
+	scenario.SavingsBytes = TotalWastedBytes(sets)
+	return scenario
+}
+
+// simulateWhatIfFromConfig simulates the whatif_scenarios with whatif_keep_locales and logs the projected sizes
+func simulateWhatIfFromConfig(cfg config.Config, artifactPath string, result ArtifactResult, logger log.Logger) ([]WhatIfScenario, bool) {
+	scenarios, err := ParseWhatIfScenarios(cfg.WhatIfScenarios)
+	if err != nil {
+		logger.Warnf("Invalid whatif_scenarios value: %s", err)
+		return nil, false
+	}
+	if len(scenarios) == 0 {
+		return nil, false
+	}
+	keepLocales := ParsePatterns(cfg.WhatIfKeepLocales)
+	if len(keepLocales) == 0 {
+		keepLocales = []string{"en"}
+	}
+
+	logger.Infof("Simulating optimizations...")
+	simulated := SimulateWhatIf(artifactPath, result, scenarios, keepLocales, cfg.QuickAnalysis(), logger)
+	for _, scenario := range simulated {
+		if scenario.Skipped != "" {
+			logger.Warnf("%s: skipped, %s", scenario.Name, scenario.Skipped)
+			continue
+		}
+		logger.Printf("%s: %d file(s), saves %s, projected size %s", scenario.Name, scenario.Files, cfg.Units().Format(scenario.SavingsBytes), cfg.Units().Format(scenario.ProjectedBytes))
+	}
+	return simulated, true
+}
@@ -0,0 +1,125 @@
+package analyze
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestParseWhatIfScenarios(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr string
+	}{
+		{name: "empty"},
+		{name: "simulation order", input: "dedupe, WebP", want: []string{WhatIfWebP, WhatIfDedupe}},
+		{name: "all", input: "all", want: []string{WhatIfWebP, WhatIfStripLocales, WhatIfDedupe}},
+		{name: "all with a duplicate", input: "webp,all", want: []string{WhatIfWebP, WhatIfStripLocales, WhatIfDedupe}},
+		{name: "unknown scenario", input: "webp,minify", wantErr: "unknown scenario minify, expected webp, strip_locales, dedupe or all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWhatIfScenarios(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ParseWhatIfScenarios() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWhatIfScenarios() error = %s", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ParseWhatIfScenarios() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulateWhatIf(t *testing.T) {
+	ipaInventory := Inventory{Entries: []ArchiveEntry{
+		{Path: "Payload/App.app/Base.lproj/Main.storyboardc", CompressedSize: 400},
+		{Path: "Payload/App.app/en.lproj/Localizable.strings", CompressedSize: 100},
+		{Path: "Payload/App.app/en-GB.lproj/Localizable.strings", CompressedSize: 100},
+		{Path: "Payload/App.app/de.lproj/Localizable.strings", CompressedSize: 120},
+		{Path: "Payload/App.app/pt_BR.lproj/Localizable.strings", CompressedSize: 80},
+		{Path: "Payload/App.app/logo.png", CompressedSize: 1000},
+	}}
+	apkInventory := Inventory{Entries: []ArchiveEntry{
+		{Path: "res/drawable/icon.png", CompressedSize: 1000},
+		{Path: "res/drawable/button.9.png", CompressedSize: 500},
+		{Path: "res/drawable/photo.JPG", CompressedSize: 2000},
+		{Path: "res/values-de/strings.xml", CompressedSize: 300},
+	}}
+
+	tests := []struct {
+		name          string
+		artifact      string
+		inventory     Inventory
+		sizeBytes     int64
+		scenario      string
+		quick         bool
+		wantFiles     int
+		wantSavings   int64
+		wantSkipped   string
+		wantProjected int64
+	}{
+		{name: "webp keeps the nine-patch images", artifact: "app.apk", inventory: apkInventory, sizeBytes: 10000, scenario: WhatIfWebP,
+			wantFiles: 1, wantSavings: 260, wantProjected: 9740},
+		{name: "strip_locales keeps Base and the regional variants", artifact: "App.ipa", inventory: ipaInventory, sizeBytes: 10000, scenario: WhatIfStripLocales,
+			wantFiles: 2, wantSavings: 200, wantProjected: 9800},
+		{name: "strip_locales is skipped on Android", artifact: "app.apk", inventory: apkInventory, sizeBytes: 10000, scenario: WhatIfStripLocales,
+			wantSkipped: "the localized resources of Android artifacts are compiled into resources.arsc", wantProjected: 10000},
+		{name: "dedupe is skipped in quick analysis", artifact: "app.apk", inventory: apkInventory, sizeBytes: 10000, scenario: WhatIfDedupe, quick: true,
+			wantSkipped: "the duplicate detection doesn't run with analysis_scope quick", wantProjected: 10000},
+		{name: "savings are capped at the artifact size", artifact: "app.apk", inventory: apkInventory, sizeBytes: 100, scenario: WhatIfWebP,
+			wantFiles: 1, wantSavings: 100, wantProjected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ArtifactResult{Metrics: BundleMetrics{SizeBytes: tt.sizeBytes}, Inventory: tt.inventory}
+			simulated := SimulateWhatIf(filepath.Join(t.TempDir(), tt.artifact), result, []string{tt.scenario}, []string{"en"}, tt.quick, log.NewLogger())
+			if len(simulated) != 1 {
+				t.Fatalf("SimulateWhatIf() = %+v, want one scenario", simulated)
+			}
+			got := simulated[0]
+			if got.Name != tt.scenario || got.Skipped != tt.wantSkipped {
+				t.Errorf("SimulateWhatIf() = %s skipped %q, want %s skipped %q", got.Name, got.Skipped, tt.scenario, tt.wantSkipped)
+			}
+			if got.Files != tt.wantFiles || got.SavingsBytes != tt.wantSavings || got.ProjectedBytes != tt.wantProjected {
+				t.Errorf("SimulateWhatIf() = %d file(s), saves %d, projected %d, want %d, %d, %d",
+					got.Files, got.SavingsBytes, got.ProjectedBytes, tt.wantFiles, tt.wantSavings, tt.wantProjected)
+			}
+		})
+	}
+}
+
+func TestSimulateWhatIfDedupe(t *testing.T) {
+	icon := strings.Repeat("icon", 512)
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, writeZip(t, []zipEntry{
+		{name: "res/drawable-hdpi/icon.png", content: icon},
+		{name: "res/drawable-xhdpi/icon.png", content: icon},
+		{name: "res/drawable-xxhdpi/icon.png", content: icon},
+		{name: "classes.dex", content: strings.Repeat("dex", 100)},
+	}, zip.Store), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := ArtifactResult{Metrics: BundleMetrics{SizeBytes: 10000}}
+	simulated := SimulateWhatIf(artifactPath, result, []string{WhatIfDedupe}, nil, false, log.NewLogger())
+	if len(simulated) != 1 {
+		t.Fatalf("SimulateWhatIf() = %+v, want the dedupe scenario", simulated)
+	}
+	// The stored extra copies of the icon are saved in full
+	want := 2 * int64(len(icon))
+	if got := simulated[0]; got.Skipped != "" || got.Files != 2 || got.SavingsBytes != want || got.ProjectedBytes != 10000-want {
+		t.Errorf("SimulateWhatIf() = %+v, want 2 file(s) saving %d", got, want)
+	}
+}
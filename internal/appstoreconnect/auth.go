@@ -0,0 +1,101 @@
+package appstoreconnect
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// APIKey is an App Store Connect API key: the key ID, the issuer ID of the team and the .p8 private key
+type APIKey struct {
+	KeyID      string
+	IssuerID   string
+	PrivateKey string
+}
+
+// loadPrivateKey reads the private key input: the .p8 key itself, the path of the key file, or a file:// or https://
+// URL of it
+func loadPrivateKey(ctx context.Context, client *http.Client, value string) (*ecdsa.PrivateKey, error) {
+	value = strings.TrimSpace(value)
+	data := []byte(value)
+	switch {
+	case strings.HasPrefix(value, "-----BEGIN"):
+	case strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://"):
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download the private key: %w", err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download the private key: status %d", response.StatusCode)
+		}
+		if data, err = io.ReadAll(io.LimitReader(response.Body, 1<<20)); err != nil {
+			return nil, fmt.Errorf("failed to download the private key: %w", err)
+		}
+	default:
+		var err error
+		if data, err = os.ReadFile(strings.TrimPrefix(value, "file://")); err != nil {
+			return nil, fmt.Errorf("failed to read the private key: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("the private key isn't a PEM encoded .p8 key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	privateKey, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key isn't an EC key")
+	}
+	return privateKey, nil
+}
+
+// token returns the ES256 signed JWT authorizing the App Store Connect API requests for 20 minutes, the longest
+// lifetime Apple accepts
+func (k APIKey) token(privateKey *ecdsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": k.KeyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": k.IssuerID,
+		"iat": now.Unix(),
+		"exp": now.Add(20 * time.Minute).Unix(),
+		"aud": "appstoreconnect-v1",
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the API token: %w", err)
+	}
+
+	// JWS signatures are the fixed size big-endian r and s, not ASN.1
+	size := (privateKey.Curve.Params().BitSize + 7) / 8
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,112 @@
+package appstoreconnect
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// p8Key returns a new EC private key in the PEM encoded .p8 format of App Store Connect
+func p8Key(t *testing.T) (string, *ecdsa.PrivateKey) {
+	t.Helper()
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), privateKey
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	key, privateKey := p8Key(t)
+	keyPath := filepath.Join(t.TempDir(), "AuthKey_ABC123.p8")
+	if err := os.WriteFile(keyPath, []byte(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "p8 key", value: "  " + key},
+		{name: "key file", value: keyPath},
+		{name: "file URL", value: "file://" + keyPath},
+		{name: "missing file", value: filepath.Join(t.TempDir(), "AuthKey_missing.p8"), wantErr: "failed to read the private key"},
+		{name: "not PEM", value: "-----BEGIN nothing", wantErr: "the private key isn't a PEM encoded .p8 key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadPrivateKey(context.Background(), http.DefaultClient, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadPrivateKey() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadPrivateKey() error = %s", err)
+			}
+			if !got.Equal(privateKey) {
+				t.Errorf("loadPrivateKey() returned another key")
+			}
+		})
+	}
+}
+
+func TestAPIKeyToken(t *testing.T) {
+	_, privateKey := p8Key(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	token, err := APIKey{KeyID: "ABC123", IssuerID: "57246542-96fe-1a63-e053-0824d011072a"}.token(privateKey, now)
+	if err != nil {
+		t.Fatalf("token() error = %s", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token() = %s, want a JWT", token)
+	}
+	var header, claims map[string]interface{}
+	for idx, v := range []*map[string]interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[idx])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if header["alg"] != "ES256" || header["kid"] != "ABC123" {
+		t.Errorf("header = %v", header)
+	}
+	if claims["iss"] != "57246542-96fe-1a63-e053-0824d011072a" || claims["aud"] != "appstoreconnect-v1" || claims["exp"] != float64(now.Add(20*time.Minute).Unix()) {
+		t.Errorf("claims = %v", claims)
+	}
+
+	// The JWS signature is the 32 byte r and s of the P-256 signature
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		t.Fatalf("signature = %x, %v", signature, err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(&privateKey.PublicKey, digest[:], r, s) {
+		t.Errorf("token() signature doesn't verify")
+	}
+}
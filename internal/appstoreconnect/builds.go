@@ -0,0 +1,203 @@
+package appstoreconnect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// appStoreConnectAPIURL is the base URL of the App Store Connect API
+const appStoreConnectAPIURL = "https://api.appstoreconnect.apple.com/v1"
+
+// UniversalDevice is the device model of the universal download in the file sizes of a build, the other models are
+// the thinned variants
+const UniversalDevice = "Universal"
+
+// ErrNoBuild is returned when the app has no processed build yet
+var ErrNoBuild = errors.New("no processed build uploaded yet")
+
+// DeviceSize is the size of a build Apple reports for a device model
+type DeviceSize struct {
+	DeviceModel   string `json:"deviceModel"`
+	OSVersion     string `json:"osVersion"`
+	DownloadBytes int64  `json:"downloadBytes"`
+	InstallBytes  int64  `json:"installBytes"`
+}
+
+// BuildSizes are the file sizes of the latest processed build of an app, as listed in the App Store Connect app
+// thinning size report
+type BuildSizes struct {
+	BundleID     string
+	BuildNumber  string
+	UploadedDate time.Time
+	// Devices are sorted by download size, largest first
+	Devices []DeviceSize
+}
+
+// Universal returns the size of the universal download
+func (b BuildSizes) Universal() (DeviceSize, bool) {
+	for _, device := range b.Devices {
+		if device.DeviceModel == UniversalDevice {
+			return device, true
+		}
+	}
+	return DeviceSize{}, false
+}
+
+// Discrepancy returns the difference of the local estimate from the universal download size in percent
+func (b BuildSizes) Discrepancy(estimateBytes int64) (float64, bool) {
+	universal, ok := b.Universal()
+	if !ok || universal.DownloadBytes == 0 {
+		return 0, false
+	}
+	return float64(estimateBytes-universal.DownloadBytes) * 100 / float64(universal.DownloadBytes), true
+}
+
+// resource is a resource of an App Store Connect API response
+type resource struct {
+	ID         string          `json:"id"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// client calls the App Store Connect API with a token of the API key
+type client struct {
+	client *http.Client
+	token  string
+}
+
+// list calls an App Store Connect API endpoint returning a collection of resources
+func (c client) list(ctx context.Context, path string, query url.Values) ([]resource, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, appStoreConnectAPIURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("App Store Connect API request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("App Store Connect API request to %s failed with status %d: %s", path, response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var body struct {
+		Data []resource `json:"data"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse App Store Connect API response of %s: %w", path, err)
+	}
+	return body.Data, nil
+}
+
+// LatestBuildSizes fetches the file sizes per device of the latest processed build of the app with the bundle ID,
+// the previous TestFlight build when the current one isn't uploaded yet. Failed requests are retried with the retry
+// options.
+func LatestBuildSizes(ctx context.Context, key APIKey, bundleID string, retry executor.RetryOptions, logger log.Logger) (BuildSizes, error) {
+	httpClient := &http.Client{Timeout: time.Minute}
+	privateKey, err := loadPrivateKey(ctx, httpClient, key.PrivateKey)
+	if err != nil {
+		return BuildSizes{}, err
+	}
+
+	var sizes BuildSizes
+	_, err = executor.Retry(ctx, retry, "App Store Connect API request", logger, func(ctx context.Context) (string, error) {
+		token, err := key.token(privateKey, time.Now())
+		if err != nil {
+			return "", err
+		}
+		sizes, err = latestBuildSizesOnce(ctx, client{client: httpClient, token: token}, bundleID)
+		return "", err
+	})
+	return sizes, err
+}
+
+// latestBuildSizesOnce looks up the app, its latest valid build and the file sizes of the app bundle of the build
+func latestBuildSizesOnce(ctx context.Context, c client, bundleID string) (BuildSizes, error) {
+	apps, err := c.list(ctx, "/apps", url.Values{"filter[bundleId]": {bundleID}, "fields[apps]": {"bundleId"}})
+	if err != nil {
+		return BuildSizes{}, err
+	}
+	appID := ""
+	for _, app := range apps {
+		var attributes struct {
+			BundleID string `json:"bundleId"`
+		}
+		if json.Unmarshal(app.Attributes, &attributes) == nil && attributes.BundleID == bundleID {
+			appID = app.ID
+		}
+	}
+	if appID == "" {
+		return BuildSizes{}, fmt.Errorf("no app with the bundle ID %s in App Store Connect", bundleID)
+	}
+
+	builds, err := c.list(ctx, "/builds", url.Values{
+		"filter[app]":             {appID},
+		"filter[processingState]": {"VALID"},
+		"sort":                    {"-uploadedDate"},
+		"limit":                   {"1"},
+		"fields[builds]":          {"version,uploadedDate"},
+	})
+	if err != nil {
+		return BuildSizes{}, err
+	}
+	if len(builds) == 0 {
+		return BuildSizes{}, ErrNoBuild
+	}
+	var build struct {
+		Version      string    `json:"version"`
+		UploadedDate time.Time `json:"uploadedDate"`
+	}
+	if err := json.Unmarshal(builds[0].Attributes, &build); err != nil {
+		return BuildSizes{}, fmt.Errorf("failed to parse the build: %w", err)
+	}
+	sizes := BuildSizes{BundleID: bundleID, BuildNumber: build.Version, UploadedDate: build.UploadedDate}
+
+	bundles, err := c.list(ctx, "/builds/"+url.PathEscape(builds[0].ID)+"/buildBundles", url.Values{"fields[buildBundles]": {"bundleType,bundleId"}})
+	if err != nil {
+		return BuildSizes{}, err
+	}
+	bundleResourceID := ""
+	for _, bundle := range bundles {
+		var attributes struct {
+			BundleType string `json:"bundleType"`
+		}
+		if json.Unmarshal(bundle.Attributes, &attributes) == nil && attributes.BundleType == "APP" {
+			bundleResourceID = bundle.ID
+		}
+	}
+	if bundleResourceID == "" {
+		return BuildSizes{}, fmt.Errorf("build %s has no app bundle", build.Version)
+	}
+
+	fileSizes, err := c.list(ctx, "/buildBundles/"+url.PathEscape(bundleResourceID)+"/buildBundleFileSizes", url.Values{"limit": {"200"}})
+	if err != nil {
+		return BuildSizes{}, err
+	}
+	for _, fileSize := range fileSizes {
+		var device DeviceSize
+		if err := json.Unmarshal(fileSize.Attributes, &device); err != nil {
+			return BuildSizes{}, fmt.Errorf("failed to parse the file sizes of build %s: %w", build.Version, err)
+		}
+		sizes.Devices = append(sizes.Devices, device)
+	}
+	if len(sizes.Devices) == 0 {
+		return BuildSizes{}, fmt.Errorf("no file sizes reported for build %s yet", build.Version)
+	}
+	sort.SliceStable(sizes.Devices, func(i, j int) bool {
+		return sizes.Devices[i].DownloadBytes > sizes.Devices[j].DownloadBytes
+	})
+	return sizes, nil
+}
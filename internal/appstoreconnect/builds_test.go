@@ -0,0 +1,139 @@
+package appstoreconnect
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// redirectTransport sends the App Store Connect API requests to the test server
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+func (t redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	request = request.Clone(request.Context())
+	request.URL.Scheme, request.URL.Host = target.Scheme, target.Host
+	return t.server.Client().Transport.RoundTrip(request)
+}
+
+func TestLatestBuildSizesOnce(t *testing.T) {
+	responses := map[string]string{
+		"/v1/apps":   `{"data": [{"id": "1234567890", "attributes": {"bundleId": "com.example.app"}}]}`,
+		"/v1/builds": `{"data": [{"id": "build-1", "attributes": {"version": "120", "uploadedDate": "2026-03-01T12:00:00Z"}}]}`,
+		"/v1/builds/build-1/buildBundles": `{"data": [
+			{"id": "bundle-clip", "attributes": {"bundleType": "APP_CLIP"}},
+			{"id": "bundle-app", "attributes": {"bundleType": "APP"}}]}`,
+		"/v1/buildBundles/bundle-app/buildBundleFileSizes": `{"data": [
+			{"id": "1", "attributes": {"deviceModel": "iPhone SE", "osVersion": "17.0", "downloadBytes": 30000000, "installBytes": 60000000}},
+			{"id": "2", "attributes": {"deviceModel": "Universal", "osVersion": "Universal", "downloadBytes": 50000000, "installBytes": 110000000}}]}`,
+	}
+	want := BuildSizes{BundleID: "com.example.app", BuildNumber: "120", UploadedDate: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Devices: []DeviceSize{
+		{DeviceModel: "Universal", OSVersion: "Universal", DownloadBytes: 50000000, InstallBytes: 110000000},
+		{DeviceModel: "iPhone SE", OSVersion: "17.0", DownloadBytes: 30000000, InstallBytes: 60000000},
+	}}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      BuildSizes
+		wantErr   string
+	}{
+		{name: "latest build", want: want},
+		{name: "unknown app", overrides: map[string]string{"/v1/apps": `{"data": []}`}, wantErr: "no app with the bundle ID com.example.app in App Store Connect"},
+		{name: "no processed build", overrides: map[string]string{"/v1/builds": `{"data": []}`}, wantErr: ErrNoBuild.Error()},
+		{name: "sizes not reported yet", overrides: map[string]string{"/v1/buildBundles/bundle-app/buildBundleFileSizes": `{"data": []}`},
+			wantErr: "no file sizes reported for build 120 yet"},
+		{name: "revoked key", overrides: map[string]string{"/v1/apps": "401"}, wantErr: "App Store Connect API request to /apps failed with status 401: NOT_AUTHORIZED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQueries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer jwt" {
+					t.Errorf("Authorization = %s", r.Header.Get("Authorization"))
+				}
+				gotQueries = append(gotQueries, r.URL.RawQuery)
+				body, ok := tt.overrides[r.URL.Path]
+				if !ok {
+					body, ok = responses[r.URL.Path]
+				}
+				switch {
+				case !ok:
+					w.WriteHeader(http.StatusNotFound)
+				case body == "401":
+					w.WriteHeader(http.StatusUnauthorized)
+					_, _ = io.WriteString(w, "NOT_AUTHORIZED\n")
+				default:
+					_, _ = io.WriteString(w, body)
+				}
+			}))
+			defer server.Close()
+
+			got, err := latestBuildSizesOnce(context.Background(), client{client: &http.Client{Transport: redirectTransport{server: server}}, token: "jwt"}, "com.example.app")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("latestBuildSizesOnce() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr == ErrNoBuild.Error() && !errors.Is(err, ErrNoBuild) {
+					t.Errorf("latestBuildSizesOnce() error = %v, want ErrNoBuild", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("latestBuildSizesOnce() error = %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("latestBuildSizesOnce() = %+v, want %+v", got, tt.want)
+			}
+			// Only the latest valid build is requested
+			if len(gotQueries) != 4 || !strings.Contains(gotQueries[1], "filter%5BprocessingState%5D=VALID") || !strings.Contains(gotQueries[1], "sort=-uploadedDate") {
+				t.Errorf("queries = %q", gotQueries)
+			}
+		})
+	}
+}
+
+func TestDiscrepancy(t *testing.T) {
+	sizes := BuildSizes{Devices: []DeviceSize{{DeviceModel: "iPhone SE", DownloadBytes: 30000000}, {DeviceModel: UniversalDevice, DownloadBytes: 50000000}}}
+	if got, ok := sizes.Discrepancy(60000000); !ok || got != 20 {
+		t.Errorf("Discrepancy() = %f, %t, want 20%%", got, ok)
+	}
+	if _, ok := (BuildSizes{Devices: sizes.Devices[:1]}).Discrepancy(60000000); ok {
+		t.Errorf("Discrepancy() without the universal size succeeded")
+	}
+}
+
+func TestDiscrepancyPercent(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{input: "", want: defaultDiscrepancy},
+		{input: "12.5", want: 12.5},
+		{input: "0", want: 0},
+		{input: "-5", want: defaultDiscrepancy},
+		{input: "a lot", want: defaultDiscrepancy},
+	}
+	for _, tt := range tests {
+		fetcher := NewFetcher(config.Config{AppStoreSizeDiscrepancy: tt.input}, executor.RetryOptions{}, log.NewLogger())
+		if got := fetcher.DiscrepancyPercent(); got != tt.want {
+			t.Errorf("DiscrepancyPercent() of %q = %f, want %f", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package appstoreconnect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// defaultDiscrepancy is the app_store_size_discrepancy_percent used when the input is invalid
+const defaultDiscrepancy = 20.0
+
+// Fetcher fetches the file sizes of the latest App Store Connect build for the "vs App Store Connect" comparison
+type Fetcher struct {
+	cfg    config.Config
+	retry  executor.RetryOptions
+	logger log.Logger
+}
+
+// NewFetcher returns a Fetcher reading the API key and the app from the config
+func NewFetcher(cfg config.Config, retry executor.RetryOptions, logger log.Logger) Fetcher {
+	return Fetcher{cfg: cfg, retry: retry, logger: logger}
+}
+
+// DiscrepancyPercent parses app_store_size_discrepancy_percent, the difference of the local estimate from the App
+// Store Connect size in percent that is flagged
+func (f Fetcher) DiscrepancyPercent() float64 {
+	input := f.cfg.AppStoreSizeDiscrepancy
+	if input == "" {
+		return defaultDiscrepancy
+	}
+	percent, err := strconv.ParseFloat(input, 64)
+	if err != nil || percent < 0 {
+		f.logger.Warnf("Invalid app_store_size_discrepancy_percent value: %s", input)
+		return defaultDiscrepancy
+	}
+	return percent
+}
+
+// Fetch returns the file sizes of the latest build of the app, nil when there is no processed build yet or they
+// can't be fetched
+func (f Fetcher) Fetch(ctx context.Context, artifactPaths []string) *BuildSizes {
+	f.logger.Infof("Fetching the App Store Connect build file sizes...")
+	bundleID, err := f.bundleID(artifactPaths)
+	var sizes BuildSizes
+	if err == nil {
+		key := APIKey{KeyID: f.cfg.AppStoreConnectKeyID, IssuerID: f.cfg.AppStoreConnectIssuerID, PrivateKey: string(f.cfg.AppStoreConnectPrivateKey)}
+		sizes, err = LatestBuildSizes(ctx, key, bundleID, f.retry, f.logger)
+	}
+	switch {
+	case errors.Is(err, ErrNoBuild):
+		f.logger.Printf("No processed build of %s in App Store Connect yet", bundleID)
+	case err != nil:
+		f.logger.Warnf("Failed to fetch the App Store Connect build file sizes: %s", err)
+	default:
+		f.logger.Printf("Latest build: %s, file sizes of %d device model(s)", sizes.BuildNumber, len(sizes.Devices))
+		if universal, ok := sizes.Universal(); ok {
+			f.logger.Printf("- %s: %s download, %s install", universal.DeviceModel, f.cfg.Units().Format(universal.DownloadBytes), f.cfg.Units().Format(universal.InstallBytes))
+		}
+		return &sizes
+	}
+	return nil
+}
+
+// bundleID returns app_store_bundle_id, or the bundle ID of the first IPA
+func (f Fetcher) bundleID(artifactPaths []string) (string, error) {
+	if f.cfg.AppStoreBundleID != "" {
+		return f.cfg.AppStoreBundleID, nil
+	}
+	for _, artifactPath := range artifactPaths {
+		if strings.EqualFold(filepath.Ext(artifactPath), ".ipa") {
+			return analyze.ReadBundleID(artifactPath)
+		}
+	}
+	return "", fmt.Errorf("no IPA to read the bundle ID from, set app_store_bundle_id")
+}
@@ -0,0 +1,23 @@
+package bitrise
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// annotationContext identifies the annotation of the step, adding it again replaces the previous one
+const annotationContext = "bundle-analyzer"
+
+// AddBuildAnnotation publishes markdown to the build's annotations with the annotations plugin of the Bitrise CLI
+func AddBuildAnnotation(ctx context.Context, exec executor.Executor, markdown, style string) error {
+	args := []string{":annotations", "add", markdown, "--style", style, "--context", annotationContext}
+	if out, err := exec.Run(ctx, "Build annotation", "bitrise", args, nil); err != nil {
+		if out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	return nil
+}
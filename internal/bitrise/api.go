@@ -0,0 +1,239 @@
+package bitrise
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// bitriseAPIURL is the base URL of the Bitrise API
+const bitriseAPIURL = "https://api.bitrise.io/v0.1"
+
+// BuildArtifact is an artifact of a Bitrise build as listed by the Bitrise API
+type BuildArtifact struct {
+	Slug          string `json:"slug"`
+	Title         string `json:"title"`
+	ArtifactType  string `json:"artifact_type"`
+	FileSizeBytes int64  `json:"file_size_bytes"`
+}
+
+// Client calls the Bitrise API with a personal access token or workspace API token
+type Client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+	// download has no timeout, multi-GB artifacts may take longer than any fixed limit, the context cancels it
+	download *http.Client
+	retry    executor.RetryOptions
+	logger   log.Logger
+}
+
+// NewClient returns a Bitrise API client authenticating with the token, failed requests are retried with the retry options
+func NewClient(token string, retry executor.RetryOptions, logger log.Logger) Client {
+	return Client{
+		baseURL:  bitriseAPIURL,
+		token:    token,
+		client:   &http.Client{Timeout: 10 * time.Minute},
+		download: &http.Client{},
+		retry:    retry,
+		logger:   logger,
+	}
+}
+
+// withRetry runs a request with the retry options of the client
+func (c Client) withRetry(ctx context.Context, retry executor.RetryOptions, operation string, request func(ctx context.Context) error) error {
+	_, err := executor.Retry(ctx, retry, operation, c.logger, func(ctx context.Context) (string, error) {
+		return "", request(ctx)
+	})
+	return err
+}
+
+// get calls a Bitrise API endpoint and decodes the response into v
+func (c Client) get(ctx context.Context, path string, v interface{}) error {
+	return c.withRetry(ctx, c.retry, "Bitrise API request", func(ctx context.Context) error {
+		return c.getOnce(ctx, path, v)
+	})
+}
+
+// getOnce calls a Bitrise API endpoint once
+func (c Client) getOnce(ctx context.Context, path string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Authorization", c.token)
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("request to %s failed with status %d: %s", path, response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response of %s: %w", path, err)
+	}
+	return nil
+}
+
+// post calls a Bitrise API endpoint with a JSON body
+func (c Client) post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	return c.withRetry(ctx, c.retry, "Bitrise API request", func(ctx context.Context) error {
+		return c.postOnce(ctx, path, data)
+	})
+}
+
+// postOnce calls a Bitrise API endpoint once with an encoded JSON body
+func (c Client) postOnce(ctx context.Context, path string, data []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Authorization", c.token)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("request to %s failed with status %d: %s", path, response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// AbortBuild aborts a running build, the remaining steps of the build don't run
+func (c Client) AbortBuild(ctx context.Context, appSlug, buildSlug, reason string) error {
+	return c.post(ctx, fmt.Sprintf("/apps/%s/builds/%s/abort", appSlug, buildSlug), map[string]interface{}{
+		"abort_reason":       reason,
+		"abort_with_success": false,
+		"skip_notifications": false,
+	})
+}
+
+// ListBuildArtifacts lists the artifacts of a build
+func (c Client) ListBuildArtifacts(ctx context.Context, appSlug, buildSlug string) ([]BuildArtifact, error) {
+	var artifacts []BuildArtifact
+	next := ""
+	for {
+		path := fmt.Sprintf("/apps/%s/builds/%s/artifacts", appSlug, buildSlug)
+		if next != "" {
+			path += "?next=" + next
+		}
+
+		var response struct {
+			Data   []BuildArtifact `json:"data"`
+			Paging struct {
+				Next string `json:"next"`
+			} `json:"paging"`
+		}
+		if err := c.get(ctx, path, &response); err != nil {
+			return nil, err
+		}
+
+		artifacts = append(artifacts, response.Data...)
+		if response.Paging.Next == "" {
+			return artifacts, nil
+		}
+		next = response.Paging.Next
+	}
+}
+
+// DownloadBuildArtifact downloads an artifact of a build into the given directory and returns its path
+func (c Client) DownloadBuildArtifact(ctx context.Context, appSlug, buildSlug string, artifact BuildArtifact, dir string) (string, error) {
+	var response struct {
+		Data struct {
+			ExpiringDownloadURL string `json:"expiring_download_url"`
+		} `json:"data"`
+	}
+	if err := c.get(ctx, fmt.Sprintf("/apps/%s/builds/%s/artifacts/%s", appSlug, buildSlug, artifact.Slug), &response); err != nil {
+		return "", err
+	}
+	if response.Data.ExpiringDownloadURL == "" {
+		return "", fmt.Errorf("no download URL for artifact %s", artifact.Title)
+	}
+
+	// command_timeout would abort multi-GB downloads, only canceling the step stops them
+	retry := c.retry
+	retry.Timeout = 0
+	artifactPath := filepath.Join(dir, filepath.Base(artifact.Title))
+	err := c.withRetry(ctx, retry, "Artifact download", func(ctx context.Context) error {
+		return c.downloadOnce(ctx, response.Data.ExpiringDownloadURL, artifact, artifactPath)
+	})
+	if err != nil {
+		return "", err
+	}
+	return artifactPath, nil
+}
+
+// downloadOnce downloads an artifact from its pre-signed download URL to the path
+func (c Client) downloadOnce(ctx context.Context, downloadURL string, artifact BuildArtifact, artifactPath string) error {
+	// The download URL is pre-signed, it must not get the API token
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	download, err := c.download.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", artifact.Title, err)
+	}
+	defer download.Body.Close()
+
+	if download.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: status %d", artifact.Title, download.StatusCode)
+	}
+
+	file, err := os.Create(artifactPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", artifactPath, err)
+	}
+	defer file.Close()
+
+	written, err := io.Copy(file, download.Body)
+	if err == nil && artifact.FileSizeBytes > 0 && written != artifact.FileSizeBytes {
+		err = fmt.Errorf("got %d of %d bytes", written, artifact.FileSizeBytes)
+	}
+	if err != nil {
+		// Don't leave a truncated artifact behind when the download is canceled or incomplete
+		_ = os.Remove(artifactPath)
+		return fmt.Errorf("failed to download %s: %w", artifact.Title, err)
+	}
+	return nil
+}
+
+// AbortCurrentBuild aborts the current build via the Bitrise API
+func AbortCurrentBuild(ctx context.Context, token, reason string, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) error {
+	if token == "" {
+		return fmt.Errorf("bitrise_api_token is required to abort the build")
+	}
+
+	appSlug, buildSlug := envRepo.Get("BITRISE_APP_SLUG"), envRepo.Get("BITRISE_BUILD_SLUG")
+	if appSlug == "" || buildSlug == "" {
+		return fmt.Errorf("BITRISE_APP_SLUG and BITRISE_BUILD_SLUG are required to abort the build")
+	}
+
+	return NewClient(token, retry, logger).AbortBuild(ctx, appSlug, buildSlug, reason)
+}
@@ -0,0 +1,194 @@
+package bitrise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// artifactBody is the content every artifact of the test server downloads with
+const artifactBody = "synthetic artifact"
+
+func newTestClient(t *testing.T) Client {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/apps/app/builds/build/artifacts", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token" {
+			http.Error(w, `{"message": "Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("next") == "" {
+			fmt.Fprint(w, `{"data": [{"slug": "small", "title": "app.apk", "file_size_bytes": 18}], "paging": {"next": "page2"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"data": [{"slug": "large", "title": "app.ipa", "file_size_bytes": 5368709120}], "paging": {}}`)
+	})
+	mux.HandleFunc("/apps/app/builds/build/artifacts/", func(w http.ResponseWriter, r *http.Request) {
+		slug := strings.TrimPrefix(r.URL.Path, "/apps/app/builds/build/artifacts/")
+		fmt.Fprintf(w, `{"data": {"expiring_download_url": "%s/download/%s"}}`, server.URL, slug)
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Error("download URL got the API token")
+		}
+		fmt.Fprint(w, artifactBody)
+	})
+
+	return Client{
+		baseURL:  server.URL,
+		token:    "token",
+		client:   server.Client(),
+		download: server.Client(),
+		retry:    executor.RetryOptions{},
+		logger:   log.NewLogger(),
+	}
+}
+
+func TestListBuildArtifacts(t *testing.T) {
+	artifacts, err := newTestClient(t).ListBuildArtifacts(context.Background(), "app", "build")
+	if err != nil {
+		t.Fatalf("ListBuildArtifacts() error = %s", err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("got %d artifacts, want 2", len(artifacts))
+	}
+	if artifacts[1].FileSizeBytes != 5368709120 {
+		t.Errorf("FileSizeBytes = %d, want 5368709120", artifacts[1].FileSizeBytes)
+	}
+}
+
+func TestListBuildArtifactsErrors(t *testing.T) {
+	client := newTestClient(t)
+	client.token = "invalid"
+	_, err := client.ListBuildArtifacts(context.Background(), "app", "build")
+	if err == nil || !strings.Contains(err.Error(), "failed with status 401") {
+		t.Errorf("ListBuildArtifacts() error = %v, want status 401", err)
+	}
+
+	_, err = newTestClient(t).ListBuildArtifacts(context.Background(), "app", "missing")
+	if err == nil || !strings.Contains(err.Error(), "failed with status 404") {
+		t.Errorf("ListBuildArtifacts() error = %v, want status 404", err)
+	}
+}
+
+func TestDownloadBuildArtifact(t *testing.T) {
+	tests := []struct {
+		name     string
+		artifact BuildArtifact
+		wantErr  string
+	}{
+		{name: "matching size", artifact: BuildArtifact{Slug: "small", Title: "app.apk", FileSizeBytes: int64(len(artifactBody))}},
+		{name: "unknown size", artifact: BuildArtifact{Slug: "small", Title: "app.apk"}},
+		{name: "incomplete above 32 bits", artifact: BuildArtifact{Slug: "large", Title: "app.ipa", FileSizeBytes: 5368709120}, wantErr: fmt.Sprintf("got %d of 5368709120 bytes", len(artifactBody))},
+		{name: "size truncated to 32 bits", artifact: BuildArtifact{Slug: "large", Title: "app.ipa", FileSizeBytes: 5368709120 - 4294967296}, wantErr: "of 1073741824 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			artifactPath, err := newTestClient(t).DownloadBuildArtifact(context.Background(), "app", "build", tt.artifact, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("DownloadBuildArtifact() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, statErr := os.Stat(filepath.Join(dir, tt.artifact.Title)); !os.IsNotExist(statErr) {
+					t.Errorf("incomplete artifact was left behind")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadBuildArtifact() error = %s", err)
+			}
+			data, err := os.ReadFile(artifactPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != artifactBody {
+				t.Errorf("downloaded %q, want %q", data, artifactBody)
+			}
+		})
+	}
+}
+
+func TestAbortBuild(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/apps/app/builds/build/abort" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode body: %s", err)
+		}
+		fmt.Fprint(w, `{"status": "ok"}`)
+	}))
+	defer server.Close()
+
+	client := Client{baseURL: server.URL, token: "token", client: server.Client(), retry: executor.RetryOptions{}, logger: log.NewLogger()}
+	if err := client.AbortBuild(context.Background(), "app", "build", "Bundle analyzer: 1 threshold violation(s)"); err != nil {
+		t.Fatalf("AbortBuild() error = %s", err)
+	}
+	if body["abort_reason"] != "Bundle analyzer: 1 threshold violation(s)" || body["abort_with_success"] != false {
+		t.Errorf("body = %v", body)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     []int
+		slowAttempts int
+		timeout      time.Duration
+		wantAttempts int
+		wantErr      string
+	}{
+		{name: "overloaded server is retried", failures: []int{http.StatusBadGateway, http.StatusTooManyRequests}, wantAttempts: 3},
+		{name: "rejected request isn't retried", failures: []int{http.StatusForbidden}, wantAttempts: 1, wantErr: "failed with status 403"},
+		{name: "retries run out", failures: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}, wantAttempts: 3, wantErr: "(after 3 attempt(s))"},
+		{name: "timed out request is retried", slowAttempts: 1, timeout: 50 * time.Millisecond, wantAttempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tt.slowAttempts {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+					return
+				}
+				if attempts <= len(tt.failures) {
+					w.WriteHeader(tt.failures[attempts-1])
+					return
+				}
+				fmt.Fprint(w, `{"data": [], "paging": {}}`)
+			}))
+			defer server.Close()
+
+			retry := executor.RetryOptions{Count: 2, Wait: time.Millisecond, Timeout: tt.timeout}
+			client := Client{baseURL: server.URL, token: "token", client: server.Client(), retry: retry, logger: log.NewLogger()}
+			_, err := client.ListBuildArtifacts(context.Background(), "app", "build")
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("ListBuildArtifacts() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("ListBuildArtifacts() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package bitrise
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// InsightsMetric is a single metric value reported to Bitrise Insights
+type InsightsMetric struct {
+	Name  string            `json:"name"`
+	Value int64             `json:"value"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// InsightsPayload is the request body of the Insights metrics upload
+type InsightsPayload struct {
+	AppSlug     string           `json:"app_slug"`
+	BuildSlug   string           `json:"build_slug"`
+	BuildNumber string           `json:"build_number"`
+	Workflow    string           `json:"workflow"`
+	Branch      string           `json:"branch"`
+	Timestamp   time.Time        `json:"timestamp"`
+	Metrics     []InsightsMetric `json:"metrics"`
+}
+
+// newInsightsPayload collects the size metrics of every analyzed artifact, keyed by the app and workflow of the current build
+func newInsightsPayload(results []analyze.ArtifactResult, envRepo env.Repository) InsightsPayload {
+	payload := InsightsPayload{
+		AppSlug:     envRepo.Get("BITRISE_APP_SLUG"),
+		BuildSlug:   envRepo.Get("BITRISE_BUILD_SLUG"),
+		BuildNumber: envRepo.Get("BITRISE_BUILD_NUMBER"),
+		Workflow:    envRepo.Get("BITRISE_TRIGGERED_WORKFLOW_ID"),
+		Branch:      envRepo.Get("BITRISE_GIT_BRANCH"),
+		Timestamp:   time.Now().UTC(),
+	}
+
+	for _, result := range results {
+		tags := map[string]string{"artifact": result.Name}
+
+		payload.Metrics = append(payload.Metrics,
+			InsightsMetric{Name: "bundle_size_bytes", Value: result.Metrics.SizeBytes, Tags: tags},
+			InsightsMetric{Name: "bundle_file_count", Value: result.Metrics.FileCount, Tags: tags},
+			InsightsMetric{Name: "bundle_potential_savings_bytes", Value: result.Metrics.PotentialSavingsBytes, Tags: tags},
+		)
+		if result.Metrics.DexMethodCount > 0 {
+			payload.Metrics = append(payload.Metrics, InsightsMetric{Name: "bundle_dex_method_count", Value: result.Metrics.DexMethodCount, Tags: tags})
+		}
+		if result.Comparison != nil {
+			payload.Metrics = append(payload.Metrics,
+				InsightsMetric{Name: "bundle_size_delta_bytes", Value: result.Comparison.SizeDeltaBytes, Tags: tags},
+				InsightsMetric{Name: "bundle_file_count_delta", Value: result.Comparison.FileCountDelta, Tags: tags},
+			)
+		}
+	}
+
+	return payload
+}
+
+// UploadInsightsMetrics posts the bundle metrics to the configured Insights endpoint
+func UploadInsightsMetrics(ctx context.Context, endpoint, token string, results []analyze.ArtifactResult, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) error {
+	if token == "" {
+		return fmt.Errorf("insights_api_token is required to upload metrics")
+	}
+
+	payload := newInsightsPayload(results, envRepo)
+	if payload.AppSlug == "" {
+		return fmt.Errorf("BITRISE_APP_SLUG is not set, metrics can only be uploaded from Bitrise builds")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	url := strings.ReplaceAll(endpoint, "{app_slug}", payload.AppSlug)
+	logger.Printf("Uploading %d metric(s) to %s", len(payload.Metrics), url)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	_, err = executor.Retry(ctx, retry, "Metrics upload", logger, func(ctx context.Context) (string, error) {
+		return "", postInsightsMetrics(ctx, client, url, token, body)
+	})
+	return err
+}
+
+// postInsightsMetrics posts the encoded metrics to the Insights endpoint once
+func postInsightsMetrics(ctx context.Context, client *http.Client, url, token string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", token)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to upload metrics: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("metrics upload failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package bitrise
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// fakeEnvRepository is an env.Repository backed by a map
+type fakeEnvRepository map[string]string
+
+func (r fakeEnvRepository) List() []string {
+	var envs []string
+	for key, value := range r {
+		envs = append(envs, key+"="+value)
+	}
+	return envs
+}
+
+func (r fakeEnvRepository) Unset(key string) error {
+	delete(r, key)
+	return nil
+}
+
+func (r fakeEnvRepository) Get(key string) string {
+	return r[key]
+}
+
+func (r fakeEnvRepository) Set(key, value string) error {
+	r[key] = value
+	return nil
+}
+
+func TestUploadInsightsMetrics(t *testing.T) {
+	results := []analyze.ArtifactResult{
+		{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 5368709120, FileCount: 12, DexMethodCount: 40000},
+			Comparison: &analyze.BaselineComparison{SizeDeltaBytes: -1024, FileCountDelta: 2}},
+		{Name: "app.ipa", Metrics: analyze.BundleMetrics{SizeBytes: 2048, FileCount: 3}},
+	}
+	envRepo := fakeEnvRepository{
+		"BITRISE_APP_SLUG":              "app-slug",
+		"BITRISE_BUILD_NUMBER":          "42",
+		"BITRISE_TRIGGERED_WORKFLOW_ID": "primary",
+		"BITRISE_GIT_BRANCH":            "main",
+	}
+
+	var attempts int
+	var payload InsightsPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path != "/apps/app-slug/metrics" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode payload: %s", err)
+		}
+	}))
+	defer server.Close()
+
+	retry := executor.RetryOptions{Count: 1, Wait: time.Millisecond}
+	if err := UploadInsightsMetrics(context.Background(), server.URL+"/apps/{app_slug}/metrics", "token", results, envRepo, retry, log.NewLogger()); err != nil {
+		t.Fatalf("UploadInsightsMetrics() error = %s", err)
+	}
+	if attempts != 2 {
+		t.Errorf("got %d attempts, want 2", attempts)
+	}
+	if payload.AppSlug != "app-slug" || payload.BuildNumber != "42" || payload.Workflow != "primary" || payload.Branch != "main" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	want := []struct {
+		name     string
+		artifact string
+		value    int64
+	}{
+		{"bundle_size_bytes", "app.apk", 5368709120},
+		{"bundle_file_count", "app.apk", 12},
+		{"bundle_potential_savings_bytes", "app.apk", 0},
+		{"bundle_dex_method_count", "app.apk", 40000},
+		{"bundle_size_delta_bytes", "app.apk", -1024},
+		{"bundle_file_count_delta", "app.apk", 2},
+		{"bundle_size_bytes", "app.ipa", 2048},
+		{"bundle_file_count", "app.ipa", 3},
+		{"bundle_potential_savings_bytes", "app.ipa", 0},
+	}
+	if len(payload.Metrics) != len(want) {
+		t.Fatalf("got %d metrics, want %d: %+v", len(payload.Metrics), len(want), payload.Metrics)
+	}
+	for idx, item := range want {
+		got := payload.Metrics[idx]
+		if got.Name != item.name || got.Tags["artifact"] != item.artifact || got.Value != item.value {
+			t.Errorf("metric %d = %s %s %d, want %s %s %d", idx, got.Name, got.Tags["artifact"], got.Value, item.name, item.artifact, item.value)
+		}
+	}
+}
+
+func TestUploadInsightsMetricsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		token   string
+		envRepo fakeEnvRepository
+		wantErr string
+	}{
+		{name: "no token", envRepo: fakeEnvRepository{"BITRISE_APP_SLUG": "app-slug"}, wantErr: "insights_api_token is required"},
+		{name: "not a Bitrise build", token: "token", envRepo: fakeEnvRepository{}, wantErr: "BITRISE_APP_SLUG is not set"},
+		{name: "rejected", token: "token", envRepo: fakeEnvRepository{"BITRISE_APP_SLUG": "app-slug"}, wantErr: "metrics upload failed with status 401: invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry := executor.RetryOptions{Count: 2, Wait: time.Millisecond}
+			err := UploadInsightsMetrics(context.Background(), server.URL, tt.token, nil, tt.envRepo, retry, log.NewLogger())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("UploadInsightsMetrics() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,530 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-steputils/stepconf"
+	"github.com/bitrise-io/go-utils/v2/env"
+)
+
+// Config holds the step configuration
+type Config struct {
+	ArtifactPath              string          `env:"artifact_path"`
+	ArtifactFilter            string          `env:"artifact_filter"`
+	OutputFormats             string          `env:"output_formats"`
+	DeployFormats             string          `env:"deploy_formats"`
+	OutputEnvPrefix           string          `env:"output_env_prefix"`
+	SizeUnits                 string          `env:"size_units,opt[,binary,si]"`
+	TopDirectoriesCount       string          `env:"top_directories_count"`
+	ReportTitle               string          `env:"report_title"`
+	ReportLocale              string          `env:"report_locale"`
+	ReportTimezone            string          `env:"report_timezone"`
+	ReportIcons               string          `env:"report_icons,opt[,emoji,text,none]"`
+	ReportStatusIcons         string          `env:"report_status_icons"`
+	ReportSeverityColors      string          `env:"report_severity_colors"`
+	ReportMaxFiles            string          `env:"report_max_files"`
+	ReportMaxDepth            string          `env:"report_max_depth"`
+	PostGithubComment         string          `env:"post_github_comment,opt[,auto,yes,no]"`
+	GithubToken               stepconf.Secret `env:"github_token"`
+	CommentLayout             string          `env:"comment_layout,opt[,sections,table,per_comment]"`
+	FailOnLargeSize           string          `env:"fail_on_large_size"`
+	ABISizeThresholds         string          `env:"abi_size_thresholds"`
+	FailOnDexMethods          string          `env:"fail_on_dex_method_count"`
+	FailOnObfuscationBelow    string          `env:"fail_on_obfuscation_coverage_below"`
+	PolicyFile                string          `env:"policy_file"`
+	FailOnFileCount           string          `env:"fail_on_file_count"`
+	HistoryFile               string          `env:"history_file"`
+	BaselineBranch            string          `env:"baseline_branch"`
+	FailOnFileSize            string          `env:"fail_on_single_file_size"`
+	ModuleBudgets             string          `env:"module_budgets"`
+	AnalysisScope             string          `env:"analysis_scope,opt[,full,code_only,assets_only,quick]"`
+	IncludePatterns           string          `env:"include_patterns"`
+	ExcludePatterns           string          `env:"exclude_patterns"`
+	DownloadCompression       string          `env:"download_compression,opt[,store-default,gzip,brotli,zstd]"`
+	FailOnDuplicates          string          `env:"fail_on_duplicate_waste"`
+	ScanSecrets               bool            `env:"scan_secrets"`
+	SecretDetectors           string          `env:"secret_detectors"`
+	FailOnSecrets             bool            `env:"fail_on_secrets"`
+	ScanVulnerableSDKs        bool            `env:"scan_vulnerable_sdks"`
+	AdvisoryDatabasePath      string          `env:"advisory_database_path"`
+	FailOnVulnerableSDKs      string          `env:"fail_on_vulnerable_sdk_severity"`
+	LicenseInventory          bool            `env:"license_inventory"`
+	DeniedLicenses            string          `env:"denied_licenses"`
+	VerifySignature           bool            `env:"verify_signature"`
+	FailOnUnsigned            bool            `env:"fail_on_unsigned"`
+	CertExpiryWarningDays     string          `env:"cert_expiry_warning_days"`
+	ProvisioningProfileReport bool            `env:"provisioning_profile_report"`
+	ExpectedDistribution      string          `env:"expected_distribution,opt[,development,ad-hoc,app-store,enterprise]"`
+	ValidatePrivacyManifest   bool            `env:"validate_privacy_manifest"`
+	FailOnPrivacyManifest     bool            `env:"fail_on_privacy_manifest_issues"`
+	TrackPermissions          bool            `env:"track_permissions"`
+	SensitivePermissions      string          `env:"sensitive_permissions"`
+	FailOnNewPermissions      bool            `env:"fail_on_new_sensitive_permissions"`
+	AttackSurfaceReport       bool            `env:"attack_surface_report"`
+	SBOMDiff                  bool            `env:"sbom_diff"`
+	DetectDebugArtifacts      bool            `env:"detect_debug_artifacts"`
+	FailOnDebugArtifacts      bool            `env:"fail_on_debug_artifacts"`
+	CheckNativeHardening      bool            `env:"check_native_hardening"`
+	FailOnUnhardened          string          `env:"fail_on_unhardened_binaries"`
+	HashReputationURL         string          `env:"hash_reputation_url"`
+	HashReputationToken       stepconf.Secret `env:"hash_reputation_token"`
+	HashAllowlist             string          `env:"hash_allowlist"`
+	HashDenylist              string          `env:"hash_denylist"`
+	FailOnHashReputation      bool            `env:"fail_on_hash_reputation"`
+	NetworkSecurityAudit      bool            `env:"network_security_audit"`
+	LanguageOverheadReport    bool            `env:"language_overhead_report"`
+	WhatIfScenarios           string          `env:"whatif_scenarios"`
+	WhatIfKeepLocales         string          `env:"whatif_keep_locales"`
+	CheckDebugSymbols         bool            `env:"check_debug_symbols"`
+	BudgetRatchet             bool            `env:"budget_ratchet"`
+	RatchetTolerance          string          `env:"budget_ratchet_tolerance"`
+	FailOnIncrease            string          `env:"fail_on_size_increase"`
+	ArtifactThresholds        string          `env:"artifact_thresholds"`
+	FailOnNativeLibs          string          `env:"fail_on_native_lib_uncompressed_size"`
+	GraceBuilds               string          `env:"grace_builds"`
+	InsightsEndpoint          string          `env:"insights_endpoint"`
+	InsightsToken             stepconf.Secret `env:"insights_api_token"`
+	DatadogAPIKey             stepconf.Secret `env:"datadog_api_key"`
+	DatadogSite               string          `env:"datadog_site"`
+	DatadogTags               string          `env:"datadog_tags"`
+	NewRelicLicenseKey        stepconf.Secret `env:"newrelic_license_key"`
+	NewRelicAccountID         string          `env:"newrelic_account_id"`
+	NewRelicRegion            string          `env:"newrelic_region,opt[,us,eu]"`
+	JiraBaseURL               string          `env:"jira_base_url"`
+	JiraUserEmail             string          `env:"jira_user_email"`
+	JiraAPIToken              stepconf.Secret `env:"jira_api_token"`
+	JiraIssueKey              string          `env:"jira_issue_key"`
+	JiraUpdate                string          `env:"jira_update,opt[,comment,field,both]"`
+	JiraSizeField             string          `env:"jira_size_field"`
+	S3Bucket                  string          `env:"s3_bucket"`
+	S3Prefix                  string          `env:"s3_prefix"`
+	S3Region                  string          `env:"s3_region"`
+	S3URLType                 string          `env:"s3_url_type,opt[,private,public,presigned]"`
+	AWSAccessKeyID            string          `env:"aws_access_key_id"`
+	AWSSecretAccessKey        stepconf.Secret `env:"aws_secret_access_key"`
+	AWSSessionToken           stepconf.Secret `env:"aws_session_token"`
+	AWSRoleARN                string          `env:"aws_role_arn"`
+	GCSBucket                 string          `env:"gcs_bucket"`
+	GCSPrefix                 string          `env:"gcs_prefix"`
+	GCSURLType                string          `env:"gcs_url_type,opt[,private,public,presigned]"`
+	GCSServiceAccountKey      stepconf.Secret `env:"gcs_service_account_key"`
+	AzureStorageAccount       string          `env:"azure_storage_account"`
+	AzureContainer            string          `env:"azure_container"`
+	AzurePrefix               string          `env:"azure_prefix"`
+	AzureURLType              string          `env:"azure_url_type,opt[,private,presigned]"`
+	AzureConnectionString     stepconf.Secret `env:"azure_connection_string"`
+	AzureSASToken             stepconf.Secret `env:"azure_sas_token"`
+	UploadProvider            string          `env:"upload_provider,opt[,s3,gcs,azure]"`
+	FirebaseAppID             string          `env:"firebase_app_id"`
+	FirebaseServiceAccountKey stepconf.Secret `env:"firebase_service_account_key"`
+	PlayServiceAccountKey     stepconf.Secret `env:"play_service_account_key"`
+	PlayPackageName           string          `env:"play_package_name"`
+	AppStoreConnectKeyID      string          `env:"app_store_connect_key_id"`
+	AppStoreConnectIssuerID   string          `env:"app_store_connect_issuer_id"`
+	AppStoreConnectPrivateKey stepconf.Secret `env:"app_store_connect_private_key"`
+	AppStoreBundleID          string          `env:"app_store_bundle_id"`
+	AppStoreSizeDiscrepancy   string          `env:"app_store_size_discrepancy_percent"`
+	PluginCacheDir            string          `env:"plugin_cache_dir"`
+	PluginVersion             string          `env:"plugin_version"`
+	PluginSource              string          `env:"plugin_source"`
+	PluginUpdatePolicy        string          `env:"plugin_update_policy,opt[,never,minor,always]"`
+	ExistingReportPath        string          `env:"existing_report_path"`
+	SourceBuildSlug           string          `env:"source_build_slug"`
+	SourceAppSlug             string          `env:"source_app_slug"`
+	SourceArtifacts           string          `env:"source_artifact_pattern"`
+	BitriseAPIToken           stepconf.Secret `env:"bitrise_api_token"`
+	OnViolation               string          `env:"on_violation,opt[,fail_step,abort_build,continue]"`
+	AnalysisTimeout           string          `env:"analysis_timeout"`
+	RetryCount                string          `env:"retry_count"`
+	RetryWait                 string          `env:"retry_wait"`
+	RetryBackoff              string          `env:"retry_backoff"`
+	CommandTimeout            string          `env:"command_timeout"`
+	RedactEnvVars             string          `env:"redact_env_vars"`
+	PublishTestReport         bool            `env:"publish_test_report"`
+	PostAnnotation            bool            `env:"post_annotation"`
+	LogFormat                 string          `env:"log_format,opt[,text,json]"`
+	LogLevel                  string          `env:"log_level,opt[,debug,info,warn,quiet]"`
+	ExportDebugBundle         bool            `env:"export_debug_bundle"`
+	AnalysisConcurrency       string          `env:"analysis_concurrency"`
+	MemoryLimitMB             string          `env:"memory_limit_mb"`
+	ResultCacheDir            string          `env:"result_cache_dir"`
+	WorkDir                   string          `env:"work_dir"`
+	KeepWorkDir               bool            `env:"keep_work_dir"`
+	StaleReports              string          `env:"stale_reports,opt[,remove,quarantine,keep]"`
+	Profile                   bool            `env:"profile"`
+	ProfilePprof              bool            `env:"profile_pprof"`
+}
+
+// SupportedOutputFormats are the report formats bundle-inspector generates and the formats of StepOutputFormats
+var SupportedOutputFormats = []string{"text", "json", "markdown", "html", "csv", "sarif"}
+
+// StepOutputFormats are rendered by the step from the analysis result instead of bundle-inspector
+var StepOutputFormats = []string{"csv", "sarif"}
+
+// pluginFallbackFormat is generated by bundle-inspector when only StepOutputFormats are listed, the metrics are read
+// from it
+const pluginFallbackFormat = "json"
+
+// outputFormatAliases map alternative spellings to the formats of SupportedOutputFormats
+var outputFormatAliases = map[string]string{"md": "markdown", "htm": "html", "txt": "text"}
+
+// allOutputFormats selects every format of SupportedOutputFormats
+const allOutputFormats = "all"
+
+// DefaultOutputFormats are generated when output_formats is empty
+const DefaultOutputFormats = "json,markdown"
+
+// envNamePattern matches the environment variable names output_env_prefix may start
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// envProvider adapts env.Repository to the environment provider stepconf reads the inputs from
+type envProvider struct {
+	envRepo env.Repository
+}
+
+func (p envProvider) Getenv(key string) string {
+	return p.envRepo.Get(key)
+}
+
+// Parse reads the step inputs from the environment
+func Parse(envRepo env.Repository) (Config, error) {
+	var cfg Config
+	if err := stepconf.NewEnvParser(envProvider{envRepo: envRepo}).Parse(&cfg); err != nil {
+		return Config{}, err
+	}
+
+	formats, err := NormalizeOutputFormats(cfg.OutputFormats)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.OutputFormats = formats
+
+	deployFormats, err := normalizeFormats("deploy_formats", cfg.DeployFormats)
+	if err != nil {
+		return Config{}, err
+	}
+	for _, format := range deployFormats {
+		if !cfg.HasOutputFormat(format) {
+			return Config{}, fmt.Errorf("invalid deploy_formats value %q: format %q isn't generated, add it to output_formats", cfg.DeployFormats, format)
+		}
+	}
+	cfg.DeployFormats = strings.Join(deployFormats, ",")
+	if cfg.DeployFormats == "" {
+		cfg.DeployFormats = cfg.OutputFormats
+	}
+
+	for _, input := range cfg.pathInputs() {
+		*input = ExpandPath(*input, envRepo)
+	}
+
+	if cfg.OutputEnvPrefix != "" && !envNamePattern.MatchString(cfg.OutputEnvPrefix) {
+		return Config{}, fmt.Errorf("invalid output_env_prefix value %q: only letters, digits and underscores are allowed, and it can't start with a digit", cfg.OutputEnvPrefix)
+	}
+	return cfg, nil
+}
+
+// pathInputs are the inputs holding a file or directory path, Parse expands their environment variables.
+// artifact_path is a list of paths, it's expanded when it's split.
+func (c *Config) pathInputs() []*string {
+	return []*string{&c.PolicyFile, &c.HistoryFile, &c.AdvisoryDatabasePath, &c.PluginCacheDir, &c.PluginSource, &c.ExistingReportPath, &c.ResultCacheDir, &c.WorkDir}
+}
+
+// ExpandPath expands the environment variables of a path like $BITRISE_DEPLOY_DIR/app.apk or ${HOME}/reports,
+// unset variables expand to an empty string like in the shell, and a leading ~ to HOME
+func ExpandPath(value string, envRepo env.Repository) string {
+	return ExpandHome(os.Expand(value, envRepo.Get), envRepo)
+}
+
+// ExpandHome replaces the leading ~ of a path with HOME
+func ExpandHome(path string, envRepo env.Repository) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	if home := envRepo.Get("HOME"); home != "" {
+		return home + path[1:]
+	}
+	return path
+}
+
+// NormalizeOutputFormats validates a comma-separated output_formats value, trims and lowercases the formats and drops
+// duplicates. An empty value selects DefaultOutputFormats.
+func NormalizeOutputFormats(value string) (string, error) {
+	formats, err := normalizeFormats("output_formats", value)
+	if err != nil {
+		return "", err
+	}
+	if len(formats) == 0 {
+		return DefaultOutputFormats, nil
+	}
+	return strings.Join(formats, ","), nil
+}
+
+// normalizeFormats validates a comma-separated list of report formats of the input, trims and lowercases the formats,
+// resolves the aliases and drops duplicates. "all" selects every supported format.
+func normalizeFormats(input, value string) ([]string, error) {
+	var formats []string
+	seen := map[string]bool{}
+	all := false
+	for _, item := range strings.Split(value, ",") {
+		format := strings.ToLower(strings.TrimSpace(item))
+		if format == allOutputFormats {
+			all = true
+			continue
+		}
+		if canonical, ok := outputFormatAliases[format]; ok {
+			format = canonical
+		}
+		if format == "" || seen[format] {
+			continue
+		}
+		if !containsFormat(SupportedOutputFormats, format) {
+			return nil, fmt.Errorf("invalid %s value %q: unsupported format %q, supported formats are %s or all",
+				input, value, format, strings.Join(SupportedOutputFormats, ", "))
+		}
+		seen[format] = true
+		formats = append(formats, format)
+	}
+	if all {
+		return append([]string(nil), SupportedOutputFormats...), nil
+	}
+	return formats, nil
+}
+
+// containsFormat checks whether the format is in the list
+func containsFormat(formats []string, format string) bool {
+	for _, item := range formats {
+		if item == format {
+			return true
+		}
+	}
+	return false
+}
+
+// PluginOutputFormats returns the formats of output_formats bundle-inspector generates, the JSON report when only
+// formats of StepOutputFormats are listed
+func (c Config) PluginOutputFormats() string {
+	var formats []string
+	for _, item := range strings.Split(c.OutputFormats, ",") {
+		if format := strings.TrimSpace(item); format != "" && !containsFormat(StepOutputFormats, format) {
+			formats = append(formats, format)
+		}
+	}
+	if len(formats) == 0 {
+		return pluginFallbackFormat
+	}
+	return strings.Join(formats, ",")
+}
+
+// HasPluginOutputFormat checks whether bundle-inspector generates the report format, see PluginOutputFormats
+func (c Config) HasPluginOutputFormat(format string) bool {
+	return containsFormat(strings.Split(c.PluginOutputFormats(), ","), format)
+}
+
+// HasOutputFormat checks whether the report format is listed in output_formats
+func (c Config) HasOutputFormat(format string) bool {
+	for _, item := range strings.Split(c.OutputFormats, ",") {
+		if strings.TrimSpace(item) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// HasDeployFormat checks whether reports of the format are deployed to BITRISE_DEPLOY_DIR, see deploy_formats
+func (c Config) HasDeployFormat(format string) bool {
+	for _, item := range strings.Split(c.DeployFormats, ",") {
+		if strings.TrimSpace(item) == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the inputs keyed by their input name, secret inputs are masked like in stepconf.Print
+func Fields(cfg Config) map[string]interface{} {
+	fields := map[string]interface{}{}
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := strings.Split(field.Tag.Get("env"), ",")[0]
+		if name == "" {
+			continue
+		}
+
+		inputValue := value.Field(i).Interface()
+		if secret, ok := inputValue.(stepconf.Secret); ok {
+			inputValue = string(secret)
+			if secret != "" {
+				inputValue = "*****"
+			}
+		}
+		fields[name] = inputValue
+	}
+	return fields
+}
+
+// Secrets returns the values of the sensitive inputs, the stepconf.Secret fields of the config
+func Secrets(cfg Config) []string {
+	var secrets []string
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		if secret, ok := value.Field(i).Interface().(stepconf.Secret); ok && secret != "" {
+			secrets = append(secrets, string(secret))
+		}
+	}
+	return secrets
+}
+
+// KeyValue is a single entry of a multiline "key: value" input
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// ParseKeyValueLines parses a multiline input of "key: value" pairs, skipping empty lines
+func ParseKeyValueLines(input string) ([]KeyValue, error) {
+	var pairs []KeyValue
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		idx := strings.LastIndex(line, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid line %q, expected format: key: value", line)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		if key == "" || value == "" {
+			return nil, fmt.Errorf("invalid line %q, expected format: key: value", line)
+		}
+
+		pairs = append(pairs, KeyValue{Key: key, Value: value})
+	}
+	return pairs, nil
+}
+
+// sizePattern matches a size input: a number optionally followed by a unit
+var sizePattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([A-Za-z]*)$`)
+
+// Unit systems of size_units
+const (
+	// SizeUnitsBinary shows 1024-based sizes labeled MiB
+	SizeUnitsBinary = "binary"
+	// SizeUnitsSI shows 1000-based sizes labeled MB, like the App Store and Google Play display them
+	SizeUnitsSI = "si"
+)
+
+// binarySizeUnits are the byte multipliers of the size units. Decimal and binary units are both 1024-based, like the
+// MB values of the reports, and a bare number is MB.
+var binarySizeUnits = map[string]float64{
+	"":    1024 * 1024,
+	"b":   1,
+	"kb":  1024,
+	"kib": 1024,
+	"mb":  1024 * 1024,
+	"mib": 1024 * 1024,
+	"gb":  1024 * 1024 * 1024,
+	"gib": 1024 * 1024 * 1024,
+}
+
+// siSizeUnits are the byte multipliers of the size units with size_units si: decimal units are 1000-based, binary
+// units 1024-based, and a bare number is MB
+var siSizeUnits = map[string]float64{
+	"":    1000 * 1000,
+	"b":   1,
+	"kb":  1000,
+	"kib": 1024,
+	"mb":  1000 * 1000,
+	"mib": 1024 * 1024,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1024 * 1024 * 1024,
+}
+
+// Units parses and formats sizes in the unit system of size_units. The zero value keeps the format the step used
+// before size_units: 1024-based sizes labeled MB.
+type Units struct {
+	system string
+}
+
+// NewUnits returns the Units of a size_units unit system
+func NewUnits(system string) Units {
+	return Units{system: system}
+}
+
+// Units returns the unit system of size_units
+func (c Config) Units() Units {
+	return NewUnits(c.SizeUnits)
+}
+
+// Label returns the unit the sizes are shown in
+func (u Units) Label() string {
+	if u.system == SizeUnitsBinary {
+		return "MiB"
+	}
+	return "MB"
+}
+
+// Megabytes converts a size in bytes to the unit of Label
+func (u Units) Megabytes(bytes int64) float64 {
+	if u.system == SizeUnitsSI {
+		return float64(bytes) / (1000 * 1000)
+	}
+	return float64(bytes) / (1024 * 1024)
+}
+
+// Format formats a size in bytes with two decimals and its unit, like "12.34 MB"
+func (u Units) Format(bytes int64) string {
+	return fmt.Sprintf("%.2f %s", u.Megabytes(bytes), u.Label())
+}
+
+// FormatDelta formats a size change in bytes like Format with a sign, like "+1.20 MB"
+func (u Units) FormatDelta(bytes int64) string {
+	return fmt.Sprintf("%+.2f %s", u.Megabytes(bytes), u.Label())
+}
+
+// ParseSize parses a size input like "150MB", "1.2GB", "800KiB" or "150" (MB) into bytes, units are case-insensitive.
+// Decimal units are 1000-based with size_units si, 1024-based otherwise.
+func (u Units) ParseSize(value string) (int64, error) {
+	units := binarySizeUnits
+	if u.system == SizeUnitsSI {
+		units = siSizeUnits
+	}
+	match := sizePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("invalid size %q, expected a number with an optional unit like 150MB, 1.2GB or 800KiB", value)
+	}
+	multiplier, ok := units[strings.ToLower(match[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q, supported units are B, KB, KiB, MB, MiB, GB and GiB", value, match[2])
+	}
+	number, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", value, err)
+	}
+	return int64(number * multiplier), nil
+}
+
+// Scopes of analysis_scope
+const (
+	AnalysisScopeFull = "full"
+	// AnalysisScopeCodeOnly counts only the code of the artifact in the size totals and thresholds
+	AnalysisScopeCodeOnly = "code_only"
+	// AnalysisScopeAssetsOnly counts only the entries that aren't code
+	AnalysisScopeAssetsOnly = "assets_only"
+	// AnalysisScopeQuick skips the expensive passes for fast PR feedback
+	AnalysisScopeQuick = "quick"
+)
+
+// QuickAnalysis reports whether analysis_scope skips the expensive passes: hashing the entries, reading the symbol
+// tables of the native binaries and estimating the download size
+func (c Config) QuickAnalysis() bool {
+	return c.AnalysisScope == AnalysisScopeQuick
+}
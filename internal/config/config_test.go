@@ -0,0 +1,195 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// fakeEnvRepository is an env.Repository backed by a map
+type fakeEnvRepository map[string]string
+
+func (r fakeEnvRepository) List() []string {
+	var envs []string
+	for key, value := range r {
+		envs = append(envs, key+"="+value)
+	}
+	return envs
+}
+
+func (r fakeEnvRepository) Unset(key string) error {
+	delete(r, key)
+	return nil
+}
+
+func (r fakeEnvRepository) Get(key string) string {
+	return r[key]
+}
+
+func (r fakeEnvRepository) Set(key, value string) error {
+	r[key] = value
+	return nil
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name              string
+		envs              fakeEnvRepository
+		wantOutputFormats string
+		wantDeployFormats string
+		wantPluginFormats string
+		wantErr           string
+	}{
+		{name: "defaults", envs: fakeEnvRepository{}, wantOutputFormats: DefaultOutputFormats, wantDeployFormats: DefaultOutputFormats, wantPluginFormats: DefaultOutputFormats},
+		{name: "normalized formats", envs: fakeEnvRepository{"output_formats": " Markdown, md,HTML , json"}, wantOutputFormats: "markdown,html,json", wantDeployFormats: "markdown,html,json", wantPluginFormats: "markdown,html,json"},
+		{name: "deploy formats", envs: fakeEnvRepository{"output_formats": "json,markdown", "deploy_formats": "MD"}, wantOutputFormats: "json,markdown", wantDeployFormats: "markdown", wantPluginFormats: "json,markdown"},
+		{name: "step formats only", envs: fakeEnvRepository{"output_formats": "csv,sarif"}, wantOutputFormats: "csv,sarif", wantDeployFormats: "csv,sarif", wantPluginFormats: "json"},
+		{name: "step and plugin formats", envs: fakeEnvRepository{"output_formats": "sarif,html"}, wantOutputFormats: "sarif,html", wantDeployFormats: "sarif,html", wantPluginFormats: "html"},
+		{name: "all formats", envs: fakeEnvRepository{"output_formats": "all"}, wantOutputFormats: strings.Join(SupportedOutputFormats, ","), wantDeployFormats: strings.Join(SupportedOutputFormats, ","), wantPluginFormats: "text,json,markdown,html"},
+		{name: "unsupported format", envs: fakeEnvRepository{"output_formats": "json,pdf"}, wantErr: `unsupported format "pdf"`},
+		{name: "deploy format not generated", envs: fakeEnvRepository{"output_formats": "json", "deploy_formats": "html"}, wantErr: `format "html" isn't generated`},
+		{name: "invalid option", envs: fakeEnvRepository{"size_units": "kib"}, wantErr: "size_units"},
+		{name: "invalid switch", envs: fakeEnvRepository{"scan_secrets": "maybe"}, wantErr: "scan_secrets"},
+		{name: "invalid enum", envs: fakeEnvRepository{"post_github_comment": "always"}, wantErr: "post_github_comment"},
+		{name: "invalid env prefix", envs: fakeEnvRepository{"output_env_prefix": "1ST"}, wantErr: "invalid output_env_prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse(tt.envs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %s", err)
+			}
+			if cfg.OutputFormats != tt.wantOutputFormats {
+				t.Errorf("OutputFormats = %q, want %q", cfg.OutputFormats, tt.wantOutputFormats)
+			}
+			if cfg.DeployFormats != tt.wantDeployFormats {
+				t.Errorf("DeployFormats = %q, want %q", cfg.DeployFormats, tt.wantDeployFormats)
+			}
+			if got := cfg.PluginOutputFormats(); got != tt.wantPluginFormats {
+				t.Errorf("PluginOutputFormats() = %q, want %q", got, tt.wantPluginFormats)
+			}
+		})
+	}
+}
+
+func TestParseTypedInputs(t *testing.T) {
+	cfg, err := Parse(fakeEnvRepository{"fail_on_secrets": "true", "verify_signature": "false", "post_github_comment": "no", "analysis_scope": "quick"})
+	if err != nil {
+		t.Fatalf("Parse() error = %s", err)
+	}
+	if !cfg.FailOnSecrets || cfg.VerifySignature || cfg.ScanSecrets {
+		t.Errorf("switches = %t %t %t, want true false false", cfg.FailOnSecrets, cfg.VerifySignature, cfg.ScanSecrets)
+	}
+	if cfg.PostGithubComment != "no" || cfg.AnalysisScope != "quick" {
+		t.Errorf("options = %s %s", cfg.PostGithubComment, cfg.AnalysisScope)
+	}
+}
+
+func TestParseExpandsPaths(t *testing.T) {
+	envs := fakeEnvRepository{
+		"HOME":               "/home/builder",
+		"BITRISE_SOURCE_DIR": "/src",
+		"policy_file":        "$BITRISE_SOURCE_DIR/policy.cel",
+		"history_file":       "~/history.json",
+		"result_cache_dir":   "${UNSET_DIR}/cache",
+		"work_dir":           "~",
+		"plugin_cache_dir":   "${HOME}/.cache/plugins",
+	}
+	cfg, err := Parse(envs)
+	if err != nil {
+		t.Fatalf("Parse() error = %s", err)
+	}
+	if cfg.PolicyFile != "/src/policy.cel" {
+		t.Errorf("PolicyFile = %s", cfg.PolicyFile)
+	}
+	if cfg.HistoryFile != "/home/builder/history.json" {
+		t.Errorf("HistoryFile = %s", cfg.HistoryFile)
+	}
+	if cfg.ResultCacheDir != "/cache" {
+		t.Errorf("ResultCacheDir = %s", cfg.ResultCacheDir)
+	}
+	if cfg.WorkDir != "/home/builder" || cfg.PluginCacheDir != "/home/builder/.cache/plugins" {
+		t.Errorf("WorkDir = %s, PluginCacheDir = %s", cfg.WorkDir, cfg.PluginCacheDir)
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	cfg, err := Parse(fakeEnvRepository{"github_token": "ghp_secret", "insights_api_token": "insights-secret", "artifact_path": "/builds/app.apk"})
+	if err != nil {
+		t.Fatalf("Parse() error = %s", err)
+	}
+	secrets := Secrets(cfg)
+	sort.Strings(secrets)
+	if want := []string{"ghp_secret", "insights-secret"}; !reflect.DeepEqual(secrets, want) {
+		t.Errorf("Secrets() = %v, want %v", secrets, want)
+	}
+}
+
+func TestUnitsParseSize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr string
+	}{
+		{value: "150", want: 150 * 1024 * 1024},
+		{value: "150MB", want: 150 * 1024 * 1024},
+		{value: "1.5 GB", want: 1536 * 1024 * 1024},
+		{value: "800KiB", want: 800 * 1024},
+		{value: "512b", want: 512},
+		{value: "2mib", want: 2 * 1024 * 1024},
+		{value: "10 TB", wantErr: `unknown unit "TB"`},
+		{value: "-5MB", wantErr: "expected a number with an optional unit"},
+		{value: "large", wantErr: "expected a number with an optional unit"},
+	}
+	for _, tt := range tests {
+		got, err := NewUnits("").ParseSize(tt.value)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSize(%q) error = %v, want %q", tt.value, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestUnits(t *testing.T) {
+	tests := []struct {
+		system      string
+		wantFormat  string
+		wantDelta   string
+		wantMB      int64
+		wantKiB     int64
+		wantDefault int64
+	}{
+		{system: "", wantFormat: "1.50 MB", wantDelta: "-0.25 MB", wantMB: 150 * 1024 * 1024, wantKiB: 800 * 1024, wantDefault: 150 * 1024 * 1024},
+		{system: SizeUnitsBinary, wantFormat: "1.50 MiB", wantDelta: "-0.25 MiB", wantMB: 150 * 1024 * 1024, wantKiB: 800 * 1024, wantDefault: 150 * 1024 * 1024},
+		// The binary units stay 1024-based with the SI system
+		{system: SizeUnitsSI, wantFormat: "1.57 MB", wantDelta: "-0.26 MB", wantMB: 150 * 1000 * 1000, wantKiB: 800 * 1024, wantDefault: 150 * 1000 * 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.system, func(t *testing.T) {
+			units := Config{SizeUnits: tt.system}.Units()
+			if got := units.Format(1572864); got != tt.wantFormat {
+				t.Errorf("Format() = %q, want %q", got, tt.wantFormat)
+			}
+			if got := units.FormatDelta(-262144); got != tt.wantDelta {
+				t.Errorf("FormatDelta() = %q, want %q", got, tt.wantDelta)
+			}
+			for value, want := range map[string]int64{"150MB": tt.wantMB, "800KiB": tt.wantKiB, "150": tt.wantDefault} {
+				if got, err := units.ParseSize(value); err != nil || got != want {
+					t.Errorf("ParseSize(%q) = %d, %v, want %d", value, got, err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package datadog
+
+import (
+	"context"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Client submits the bundle metrics to the Datadog site of the datadog_* inputs
+type Client struct {
+	cfg     config.Config
+	envRepo env.Repository
+	retry   executor.RetryOptions
+	logger  log.Logger
+}
+
+// NewClient returns a Client reading the site, the API key and the tags from the config
+func NewClient(cfg config.Config, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) Client {
+	return Client{cfg: cfg, envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// SubmitMetrics submits the metrics of the results tagged with datadog_tags
+func (c Client) SubmitMetrics(ctx context.Context, results []analyze.ArtifactResult) error {
+	return SubmitMetrics(ctx, c.cfg.DatadogSite, string(c.cfg.DatadogAPIKey), ParseTags(c.cfg.DatadogTags), results, c.envRepo, c.retry, c.logger)
+}
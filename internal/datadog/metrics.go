@@ -0,0 +1,135 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// DefaultSite is the Datadog site of the US1 region, used when datadog_site is empty
+const DefaultSite = "datadoghq.com"
+
+// gaugeType is the metric type of the series, the latest value of an interval is kept
+const gaugeType = 3
+
+// Point is a value of a series at a point in time
+type Point struct {
+	Timestamp int64 `json:"timestamp"`
+	Value     int64 `json:"value"`
+}
+
+// Series is a metric submitted to the Datadog metrics API
+type Series struct {
+	Metric string   `json:"metric"`
+	Type   int      `json:"type"`
+	Unit   string   `json:"unit"`
+	Points []Point  `json:"points"`
+	Tags   []string `json:"tags"`
+}
+
+// Payload is the request body of the Datadog metrics submission
+type Payload struct {
+	Series []Series `json:"series"`
+}
+
+// newPayload collects the size metrics of every analyzed artifact, tagged with the app, the branch and the variant.
+// Tags without a value are left out, extraTags are added to every series.
+func newPayload(results []analyze.ArtifactResult, extraTags []string, envRepo env.Repository, now time.Time) Payload {
+	var payload Payload
+	for _, result := range results {
+		tags := append([]string{}, extraTags...)
+		for _, tag := range [][2]string{
+			{"app", result.App.Name},
+			{"branch", envRepo.Get("BITRISE_GIT_BRANCH")},
+			{"variant", result.App.Variant},
+			{"artifact", result.Name},
+		} {
+			if tag[1] != "" {
+				tags = append(tags, tag[0]+":"+tagValue(tag[1]))
+			}
+		}
+		gauge := func(metric string, value int64) Series {
+			return Series{Metric: metric, Type: gaugeType, Unit: "byte", Points: []Point{{Timestamp: now.Unix(), Value: value}}, Tags: tags}
+		}
+
+		payload.Series = append(payload.Series, gauge("bundle.size", result.Metrics.SizeBytes))
+		if result.DownloadBytes > 0 {
+			payload.Series = append(payload.Series, gauge("bundle.download_size", result.DownloadBytes))
+		}
+		if result.Comparison != nil {
+			payload.Series = append(payload.Series, gauge("bundle.delta", result.Comparison.SizeDeltaBytes))
+		}
+	}
+	return payload
+}
+
+// tagValue replaces the characters Datadog doesn't keep in tags, like the spaces of app names
+func tagValue(value string) string {
+	return strings.Join(strings.Fields(strings.ToLower(value)), "_")
+}
+
+// ParseTags parses the comma or newline separated key:value tags of datadog_tags
+func ParseTags(input string) []string {
+	var tags []string
+	for _, line := range strings.Split(input, "\n") {
+		for _, tag := range strings.Split(line, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// SubmitMetrics submits the bundle metrics to the metrics API of the Datadog site
+func SubmitMetrics(ctx context.Context, site, apiKey string, extraTags []string, results []analyze.ArtifactResult, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) error {
+	if site == "" {
+		site = DefaultSite
+	}
+	payload := newPayload(results, extraTags, envRepo, time.Now())
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	url := "https://api." + strings.TrimPrefix(site, "api.") + "/api/v2/series"
+	logger.Printf("Submitting %d metric(s) to %s", len(payload.Series), url)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	_, err = executor.Retry(ctx, retry, "Datadog metrics submission", logger, func(ctx context.Context) (string, error) {
+		return "", postSeries(ctx, client, url, apiKey, body)
+	})
+	return err
+}
+
+// postSeries posts the encoded series to the metrics API once
+func postSeries(ctx context.Context, client *http.Client, url, apiKey string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("DD-API-KEY", apiKey)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to submit metrics: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("Datadog metrics API request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
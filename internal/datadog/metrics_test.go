@@ -0,0 +1,91 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestNewPayload(t *testing.T) {
+	t.Setenv("BITRISE_GIT_BRANCH", "feature/Size Budget")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	results := []analyze.ArtifactResult{
+		{Name: "app-free-release.aab", App: analyze.AppIdentity{Name: "My App", Variant: "free-release"}, Metrics: analyze.BundleMetrics{SizeBytes: 5000},
+			DownloadBytes: 4000, Comparison: &analyze.BaselineComparison{SizeDeltaBytes: -200}},
+		{Name: "App.ipa", Metrics: analyze.BundleMetrics{SizeBytes: 9000}},
+	}
+
+	payload := newPayload(results, []string{"team:mobile"}, env.NewRepository(), now)
+	aabTags := []string{"team:mobile", "app:my_app", "branch:feature/size_budget", "variant:free-release", "artifact:app-free-release.aab"}
+	ipaTags := []string{"team:mobile", "branch:feature/size_budget", "artifact:app.ipa"}
+	want := []Series{
+		{Metric: "bundle.size", Type: gaugeType, Unit: "byte", Points: []Point{{Timestamp: now.Unix(), Value: 5000}}, Tags: aabTags},
+		{Metric: "bundle.download_size", Type: gaugeType, Unit: "byte", Points: []Point{{Timestamp: now.Unix(), Value: 4000}}, Tags: aabTags},
+		{Metric: "bundle.delta", Type: gaugeType, Unit: "byte", Points: []Point{{Timestamp: now.Unix(), Value: -200}}, Tags: aabTags},
+		{Metric: "bundle.size", Type: gaugeType, Unit: "byte", Points: []Point{{Timestamp: now.Unix(), Value: 9000}}, Tags: ipaTags},
+	}
+	if !reflect.DeepEqual(payload.Series, want) {
+		t.Errorf("newPayload() = %+v, want %+v", payload.Series, want)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags("team:mobile, env:ci\n\nplatform:android,")
+	want := []string{"team:mobile", "env:ci", "platform:android"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTags() = %q, want %q", got, want)
+	}
+	if got := ParseTags(""); got != nil {
+		t.Errorf("ParseTags() of an empty input = %q", got)
+	}
+}
+
+func TestPostSeries(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "accepted", status: http.StatusAccepted},
+		{name: "invalid API key", status: http.StatusForbidden, wantErr: `Datadog metrics API request failed with status 403: {"errors":["Forbidden"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotKey, gotType string
+			var gotPayload Payload
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotKey, gotType = r.URL.Path, r.Header.Get("DD-API-KEY"), r.Header.Get("Content-Type")
+				_ = json.NewDecoder(r.Body).Decode(&gotPayload)
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusAccepted {
+					_, _ = io.WriteString(w, `{"errors":["Forbidden"]}`+"\n")
+				}
+			}))
+			defer server.Close()
+
+			body := []byte(`{"series": [{"metric": "bundle.size", "type": 3, "points": [{"timestamp": 1772366400, "value": 5000}]}]}`)
+			err := postSeries(context.Background(), server.Client(), server.URL+"/api/v2/series", "dd-api-key", body)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("postSeries() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("postSeries() error = %v, want %q", err, tt.wantErr)
+			}
+			if gotPath != "/api/v2/series" || gotKey != "dd-api-key" || gotType != "application/json" {
+				t.Errorf("request = %s %q %q", gotPath, gotKey, gotType)
+			}
+			if len(gotPayload.Series) != 1 || gotPayload.Series[0].Metric != "bundle.size" || gotPayload.Series[0].Points[0].Value != 5000 {
+				t.Errorf("payload = %+v", gotPayload)
+			}
+		})
+	}
+}
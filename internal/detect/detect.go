@@ -0,0 +1,176 @@
+package detect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Detector determines the artifacts to analyze
+type Detector struct {
+	envRepo env.Repository
+	retry   executor.RetryOptions
+	logger  log.Logger
+}
+
+// NewDetector returns a Detector reading the Bitrise environment variables from the repository,
+// the Bitrise API requests fetching the source build's artifacts are retried with the retry options
+func NewDetector(envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) Detector {
+	return Detector{envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// Artifacts returns the paths of the artifacts to analyze: the artifacts of the source build downloaded into downloadDir,
+// the artifact of the existing report, or artifact_path and the artifacts exported by previous steps narrowed down
+// by artifact_filter.
+// The context cancels the download of the source build's artifacts.
+func (d Detector) Artifacts(ctx context.Context, cfg config.Config, downloadDir string) ([]string, error) {
+	if cfg.SourceBuildSlug != "" {
+		if err := os.MkdirAll(downloadDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create download directory: %w", err)
+		}
+		return d.fetchSourceBuildArtifacts(ctx, cfg, downloadDir)
+	}
+	if cfg.ExistingReportPath != "" {
+		return d.existingReportArtifact(cfg)
+	}
+	paths, err := d.detectArtifacts(cfg)
+	if err != nil || cfg.ArtifactFilter == "" {
+		return paths, err
+	}
+	return d.filterArtifacts(cfg.ArtifactFilter, paths)
+}
+
+// filterArtifacts keeps the artifacts whose file name matches one of the comma or newline separated artifact_filter
+// globs, in the order they were detected, so workflows building many flavors pick the same artifacts in every build
+func (d Detector) filterArtifacts(filter string, paths []string) ([]string, error) {
+	patterns := analyze.ParsePatterns(filter)
+	var filtered []string
+	for _, path := range paths {
+		for _, pattern := range patterns {
+			matched, err := filepath.Match(pattern, filepath.Base(path))
+			if err != nil {
+				return nil, fmt.Errorf("invalid artifact_filter value %q: %w", pattern, err)
+			}
+			if matched {
+				filtered = append(filtered, path)
+				break
+			}
+		}
+	}
+
+	if len(filtered) == 0 {
+		names := make([]string, 0, len(paths))
+		for _, path := range paths {
+			names = append(names, filepath.Base(path))
+		}
+		return nil, fmt.Errorf("no artifact matches artifact_filter %s, detected: %s", filter, strings.Join(names, ", "))
+	}
+	d.logger.Infof("artifact_filter selected %d of %d artifact(s)", len(filtered), len(paths))
+	for _, path := range filtered {
+		d.logger.Printf("- %s", path)
+	}
+	return filtered, nil
+}
+
+// detectArtifacts determines the artifact paths from config or environment variables
+func (d Detector) detectArtifacts(cfg config.Config) ([]string, error) {
+	logger := d.logger
+	// Priority 1: Explicit artifact_path
+	if cfg.ArtifactPath != "" {
+		return d.artifactPathsFromInput(cfg.ArtifactPath), nil
+	}
+
+	// Priority 2: BITRISE_IPA_PATH
+	if ipaPath := d.envRepo.Get("BITRISE_IPA_PATH"); ipaPath != "" {
+		logger.Infof("Auto-detected iOS artifact from BITRISE_IPA_PATH")
+		return splitPathList(ipaPath), nil
+	}
+
+	// Priority 3: BITRISE_AAB_PATH_LIST / BITRISE_AAB_PATH
+	if aabPaths := d.envRepo.Get("BITRISE_AAB_PATH_LIST"); aabPaths != "" {
+		logger.Infof("Auto-detected Android App Bundles from BITRISE_AAB_PATH_LIST")
+		return splitPathList(aabPaths), nil
+	}
+	if aabPath := d.envRepo.Get("BITRISE_AAB_PATH"); aabPath != "" {
+		logger.Infof("Auto-detected Android App Bundle from BITRISE_AAB_PATH")
+		return splitPathList(aabPath), nil
+	}
+
+	// Priority 4: BITRISE_APK_PATH_LIST / BITRISE_APK_PATH
+	if apkPaths := d.envRepo.Get("BITRISE_APK_PATH_LIST"); apkPaths != "" {
+		logger.Infof("Auto-detected Android APKs from BITRISE_APK_PATH_LIST")
+		return splitPathList(apkPaths), nil
+	}
+	if apkPath := d.envRepo.Get("BITRISE_APK_PATH"); apkPath != "" {
+		logger.Infof("Auto-detected Android APK from BITRISE_APK_PATH")
+		return splitPathList(apkPath), nil
+	}
+
+	return nil, fmt.Errorf("no artifact found: provide artifact_path input or ensure BITRISE_IPA_PATH, BITRISE_AAB_PATH, or BITRISE_APK_PATH is set")
+}
+
+// existingReportArtifact returns the artifact of the existing report: artifact_path if set, otherwise the path recorded in the report
+func (d Detector) existingReportArtifact(cfg config.Config) ([]string, error) {
+	logger := d.logger
+	if cfg.ArtifactPath != "" {
+		paths := d.artifactPathsFromInput(cfg.ArtifactPath)
+		if len(paths) > 1 {
+			logger.Warnf("existing_report_path covers a single artifact, using %s", paths[0])
+		}
+		return paths[:1], nil
+	}
+
+	artifactPath, err := analyze.ReportArtifactPath(cfg.ExistingReportPath)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Using artifact of the existing report: %s", artifactPath)
+	return []string{artifactPath}, nil
+}
+
+// splitPathList splits a `|` or newline separated list of paths, the format Bitrise uses for *_PATH_LIST variables
+func splitPathList(value string) []string {
+	var paths []string
+	for _, line := range strings.Split(value, "\n") {
+		for _, path := range strings.Split(line, "|") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// artifactPathsFromInput expands the environment variables in artifact_path, splits it into paths, expands their
+// leading ~ and resolves relative paths against BITRISE_SOURCE_DIR (the working directory when not set)
+func (d Detector) artifactPathsFromInput(value string) []string {
+	expanded := os.Expand(value, func(name string) string {
+		envValue := d.envRepo.Get(name)
+		if envValue == "" {
+			d.logger.Warnf("artifact_path references an unset environment variable: $%s", name)
+		}
+		return envValue
+	})
+
+	sourceDir := d.envRepo.Get("BITRISE_SOURCE_DIR")
+	var paths []string
+	for _, path := range splitPathList(expanded) {
+		path = config.ExpandHome(path, d.envRepo)
+		if !filepath.IsAbs(path) && sourceDir != "" {
+			path = filepath.Join(sourceDir, path)
+		}
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
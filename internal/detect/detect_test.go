@@ -0,0 +1,72 @@
+package detect
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+func TestArtifactFilter(t *testing.T) {
+	envRepo := fakeEnvRepository{"BITRISE_APK_PATH_LIST": "/deploy/app-free-debug.apk|/deploy/app-free-release.apk|/deploy/app-paid-release.apk\n/deploy/wear-release.apk"}
+	tests := []struct {
+		name    string
+		filter  string
+		want    []string
+		wantErr string
+	}{
+		{name: "no filter", want: []string{"/deploy/app-free-debug.apk", "/deploy/app-free-release.apk", "/deploy/app-paid-release.apk", "/deploy/wear-release.apk"}},
+		{name: "single glob", filter: "*-release.apk", want: []string{"/deploy/app-free-release.apk", "/deploy/app-paid-release.apk", "/deploy/wear-release.apk"}},
+		// The detection order is kept, not the order of the globs
+		{name: "multiple globs", filter: "wear-*.apk, app-free-*.apk", want: []string{"/deploy/app-free-debug.apk", "/deploy/app-free-release.apk", "/deploy/wear-release.apk"}},
+		{name: "no match", filter: "*.aab", wantErr: "no artifact matches artifact_filter *.aab, detected: app-free-debug.apk, app-free-release.apk, app-paid-release.apk, wear-release.apk"},
+		{name: "invalid glob", filter: "app-[free.apk", wantErr: `invalid artifact_filter value "app-[free.apk": syntax error in pattern`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := NewDetector(envRepo, executor.RetryOptions{}, log.NewLogger())
+			paths, err := detector.Artifacts(context.Background(), config.Config{ArtifactFilter: tt.filter}, t.TempDir())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Artifacts() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Artifacts() error = %s", err)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Artifacts() = %q, want %q", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactPathInput(t *testing.T) {
+	envRepo := fakeEnvRepository{"HOME": "/home/builder", "BITRISE_SOURCE_DIR": "/src", "BITRISE_DEPLOY_DIR": "/deploy"}
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{name: "environment variable", input: "$BITRISE_DEPLOY_DIR/app-release.apk", want: []string{"/deploy/app-release.apk"}},
+		{name: "home directory", input: "~/builds/app.ipa", want: []string{"/home/builder/builds/app.ipa"}},
+		{name: "relative to the source dir", input: "app/build/app.aab", want: []string{"/src/app/build/app.aab"}},
+		{name: "list of paths", input: "${BITRISE_DEPLOY_DIR}/app.apk|~/app.aab\nbuild/app.ipa", want: []string{"/deploy/app.apk", "/home/builder/app.aab", "/src/build/app.ipa"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := NewDetector(envRepo, executor.RetryOptions{}, log.NewLogger())
+			paths, err := detector.Artifacts(context.Background(), config.Config{ArtifactPath: tt.input}, t.TempDir())
+			if err != nil {
+				t.Fatalf("Artifacts() error = %s", err)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("Artifacts() = %q, want %q", paths, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package detect
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/bitrise"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// fetchSourceBuildArtifacts downloads the artifacts of another build matching the pattern, by default every IPA, APK and AAB
+func (d Detector) fetchSourceBuildArtifacts(ctx context.Context, cfg config.Config, dir string) ([]string, error) {
+	logger := d.logger
+	if cfg.BitriseAPIToken == "" {
+		return nil, fmt.Errorf("bitrise_api_token is required to fetch artifacts of build %s", cfg.SourceBuildSlug)
+	}
+
+	appSlug := cfg.SourceAppSlug
+	if appSlug == "" {
+		appSlug = d.envRepo.Get("BITRISE_APP_SLUG")
+	}
+	if appSlug == "" {
+		return nil, fmt.Errorf("source_app_slug is required outside of Bitrise builds")
+	}
+
+	client := bitrise.NewClient(string(cfg.BitriseAPIToken), d.retry, logger)
+
+	logger.Infof("Fetching artifacts of build %s", cfg.SourceBuildSlug)
+	artifacts, err := client.ListBuildArtifacts(ctx, appSlug, cfg.SourceBuildSlug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list build artifacts: %w", err)
+	}
+
+	var paths []string
+	for _, artifact := range artifacts {
+		if !matchesSourceArtifact(cfg.SourceArtifacts, artifact.Title) {
+			continue
+		}
+
+		logger.Printf("Downloading %s (%s)", artifact.Title, cfg.Units().Format(artifact.FileSizeBytes))
+		artifactPath, err := client.DownloadBuildArtifact(ctx, appSlug, cfg.SourceBuildSlug, artifact, dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, artifactPath)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no matching artifact found in build %s", cfg.SourceBuildSlug)
+	}
+	return paths, nil
+}
+
+// matchesSourceArtifact checks an artifact title against the source_artifact_pattern glob, without a pattern every IPA, APK and AAB matches
+func matchesSourceArtifact(pattern, title string) bool {
+	if pattern == "" {
+		switch strings.ToLower(filepath.Ext(title)) {
+		case ".ipa", ".apk", ".aab":
+			return true
+		}
+		return false
+	}
+
+	matched, err := filepath.Match(pattern, title)
+	return err == nil && matched
+}
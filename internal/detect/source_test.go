@@ -0,0 +1,81 @@
+package detect
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// fakeEnvRepository is an env.Repository backed by a map
+type fakeEnvRepository map[string]string
+
+func (r fakeEnvRepository) List() []string {
+	var envs []string
+	for key, value := range r {
+		envs = append(envs, key+"="+value)
+	}
+	return envs
+}
+
+func (r fakeEnvRepository) Unset(key string) error {
+	delete(r, key)
+	return nil
+}
+
+func (r fakeEnvRepository) Get(key string) string {
+	return r[key]
+}
+
+func (r fakeEnvRepository) Set(key, value string) error {
+	r[key] = value
+	return nil
+}
+
+func TestMatchesSourceArtifact(t *testing.T) {
+	tests := []struct {
+		pattern string
+		title   string
+		want    bool
+	}{
+		{title: "app-release.apk", want: true},
+		{title: "App.IPA", want: true},
+		{title: "app-release.aab", want: true},
+		{title: "mapping.txt", want: false},
+		{title: "app.apk.zip", want: false},
+		{pattern: "*-release.apk", title: "app-release.apk", want: true},
+		{pattern: "*-release.apk", title: "app-debug.apk", want: false},
+		{pattern: "*.txt", title: "mapping.txt", want: true},
+		{pattern: "[", title: "app.apk", want: false},
+	}
+	for _, tt := range tests {
+		if got := matchesSourceArtifact(tt.pattern, tt.title); got != tt.want {
+			t.Errorf("matchesSourceArtifact(%q, %q) = %t, want %t", tt.pattern, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestFetchSourceBuildArtifactsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		envRepo fakeEnvRepository
+		wantErr string
+	}{
+		{name: "no token", cfg: config.Config{SourceBuildSlug: "build"}, envRepo: fakeEnvRepository{"BITRISE_APP_SLUG": "app"},
+			wantErr: "bitrise_api_token is required to fetch artifacts of build build"},
+		{name: "no app", cfg: config.Config{SourceBuildSlug: "build", BitriseAPIToken: "token"}, envRepo: fakeEnvRepository{},
+			wantErr: "source_app_slug is required outside of Bitrise builds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detector := NewDetector(tt.envRepo, executor.RetryOptions{}, log.NewLogger())
+			_, err := detector.Artifacts(context.Background(), tt.cfg, t.TempDir())
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Artifacts() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// killGracePeriod is the time a command gets to exit after SIGTERM before its process tree is killed, see stopProcessTree
+const killGracePeriod = 5 * time.Second
+
+// Executor runs the external commands of the step, retrying transient failures and stopping attempts after the command timeout
+type Executor struct {
+	cmdFactory command.Factory
+	envRepo    env.Repository
+	retry      RetryOptions
+	logger     log.Logger
+}
+
+// New returns an Executor running commands with the command factory
+func New(cmdFactory command.Factory, envRepo env.Repository, retry RetryOptions, logger log.Logger) Executor {
+	return Executor{cmdFactory: cmdFactory, envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// Printable returns the command line of the command as it is logged
+func (e Executor) Printable(name string, args []string) string {
+	return e.cmdFactory.Create(name, args, nil).PrintableCommandArgs()
+}
+
+// Run runs the command with retries and returns its trimmed combined output
+func (e Executor) Run(ctx context.Context, operation, name string, args []string, opts *command.Opts) (string, error) {
+	return Retry(ctx, e.retry, operation, e.logger, func(ctx context.Context) (string, error) {
+		// A context that can't be canceled doesn't need the process group handling
+		if ctx.Done() == nil {
+			return e.cmdFactory.Create(name, args, opts).RunAndReturnTrimmedCombinedOutput()
+		}
+
+		var dir string
+		environ := e.envRepo.List()
+		if opts != nil {
+			dir = opts.Dir
+			environ = append(environ, opts.Env...)
+		}
+		return RunCommand(ctx, name, dir, environ, name, args, nil, e.logger)
+	})
+}
+
+// RunCommand runs a command in its own process group and returns its trimmed combined output,
+// or only its stderr when stdout is written to the given writer.
+// When ctx expires or is canceled the command is stopped with stopProcessTree, on Unix the whole process tree gets
+// SIGTERM, then SIGKILL after killGracePeriod, so processes spawned by the command don't outlive the step.
+// The returned error wraps ctx.Err() in that case.
+func RunCommand(ctx context.Context, label, dir string, environ []string, name string, args []string, stdout io.Writer, logger log.Logger) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = environ
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		if ctx.Err() == context.DeadlineExceeded {
+			logger.Warnf("Timed out, stopping %s...", label)
+		} else {
+			logger.Warnf("Step canceled, stopping %s...", label)
+		}
+		return stopProcessTree(cmd)
+	}
+	cmd.WaitDelay = 2 * killGracePeriod
+
+	var out []byte
+	var err error
+	if stdout != nil {
+		var stderr bytes.Buffer
+		cmd.Stdout = stdout
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		out = stderr.Bytes()
+	} else {
+		out, err = cmd.CombinedOutput()
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return strings.TrimSpace(string(out)), fmt.Errorf("%s stopped: %w", label, ctxErr)
+	}
+	if err != nil {
+		return strings.TrimSpace(string(out)), fmt.Errorf("executing command failed (%s %s): %w", name, strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestRunCommand(t *testing.T) {
+	out, err := RunCommand(context.Background(), "test", t.TempDir(), nil, "sh", []string{"-c", "echo out; echo err >&2"}, nil, log.NewLogger())
+	if err != nil {
+		t.Fatalf("RunCommand() error = %s", err)
+	}
+	if out != "out\nerr" {
+		t.Errorf("RunCommand() = %q, want the combined output", out)
+	}
+
+	_, err = RunCommand(context.Background(), "test", t.TempDir(), nil, "sh", []string{"-c", "exit 3"}, nil, log.NewLogger())
+	if err == nil || !strings.Contains(err.Error(), "executing command failed (sh -c exit 3)") {
+		t.Errorf("RunCommand() error = %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup starts the command in its own process group, so the processes it spawns can be stopped with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// stopProcessTree sends SIGTERM to the process group of the command, then SIGKILL after killGracePeriod
+func stopProcessTree(cmd *exec.Cmd) error {
+	pgid := -cmd.Process.Pid
+	time.AfterFunc(killGracePeriod, func() {
+		_ = syscall.Kill(pgid, syscall.SIGKILL)
+	})
+	return syscall.Kill(pgid, syscall.SIGTERM)
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package executor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+func TestRunCommandStopsProcessTree(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	// The command waits for a child process that would outlive it without the process group handling
+	start := time.Now()
+	out, err := RunCommand(ctx, "test", t.TempDir(), nil, "sh", []string{"-c", "sleep 30 & echo $!; wait"}, nil, log.NewLogger())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunCommand() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > killGracePeriod {
+		t.Errorf("RunCommand() returned after %s, the command didn't stop on SIGTERM", elapsed)
+	}
+
+	pid, convErr := strconv.Atoi(out)
+	if convErr != nil {
+		t.Fatalf("unexpected output %q", out)
+	}
+	// The orphaned child may briefly be a zombie until it's reaped
+	for deadline := time.Now().Add(2 * time.Second); syscall.Kill(pid, 0) == nil; time.Sleep(50 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child process %d outlived the command", pid)
+		}
+	}
+}
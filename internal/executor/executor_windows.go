@@ -0,0 +1,14 @@
+//go:build windows
+
+package executor
+
+import "os/exec"
+
+// setProcessGroup does nothing on Windows, which has no process groups to signal
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// stopProcessTree kills the command right away, Windows has no SIGTERM to give it a grace period. Processes spawned by
+// the command aren't stopped with it.
+func stopProcessTree(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,173 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// maxRetryWait caps the wait between retries when it grows with retry_backoff
+const maxRetryWait = 5 * time.Minute
+
+// RetryOptions configures how often transient failures are retried and how long a single attempt may take
+type RetryOptions struct {
+	Count int
+	Wait  time.Duration
+	// Backoff multiplies the wait after every retry, values below 1 keep the wait fixed
+	Backoff float64
+	// Timeout limits a single attempt, 0 means no limit
+	Timeout time.Duration
+}
+
+// transientFailurePatterns are output fragments of failures worth retrying: network hiccups, overloaded servers and temporary file locks
+var transientFailurePatterns = []string{
+	"could not resolve host",
+	"connection reset",
+	"connection refused",
+	"connection timed out",
+	"i/o timeout",
+	"tls handshake timeout",
+	"temporary failure in name resolution",
+	"network is unreachable",
+	"unexpected eof",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"http 502",
+	"http 503",
+	"http 504",
+	"status 429",
+	"status 500",
+	"status 502",
+	"status 503",
+	"status 504",
+	"api rate limit exceeded",
+	"resource temporarily unavailable",
+	"text file busy",
+	"database is locked",
+	"unable to create '.git/index.lock'",
+}
+
+// ParseRetryOptions parses retry_count, retry_wait (seconds), retry_backoff and command_timeout, invalid values disable retries
+func ParseRetryOptions(cfg config.Config, logger log.Logger) RetryOptions {
+	opts := RetryOptions{Wait: 5 * time.Second, Backoff: 2}
+	if cfg.CommandTimeout != "" {
+		timeout, err := ParseDuration(cfg.CommandTimeout)
+		if err != nil || timeout < 0 {
+			logger.Warnf("Invalid command_timeout value: %s", cfg.CommandTimeout)
+		} else {
+			opts.Timeout = timeout
+		}
+	}
+
+	if cfg.RetryCount != "" {
+		count, err := strconv.Atoi(cfg.RetryCount)
+		if err != nil || count < 0 {
+			logger.Warnf("Invalid retry_count value: %s", cfg.RetryCount)
+			return RetryOptions{Timeout: opts.Timeout}
+		}
+		opts.Count = count
+	}
+
+	if cfg.RetryWait != "" {
+		seconds, err := strconv.Atoi(cfg.RetryWait)
+		if err != nil || seconds < 0 {
+			logger.Warnf("Invalid retry_wait value: %s", cfg.RetryWait)
+		} else {
+			opts.Wait = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if cfg.RetryBackoff != "" {
+		backoff, err := strconv.ParseFloat(cfg.RetryBackoff, 64)
+		if err != nil || backoff < 1 {
+			logger.Warnf("Invalid retry_backoff value: %s", cfg.RetryBackoff)
+		} else {
+			opts.Backoff = backoff
+		}
+	}
+	return opts
+}
+
+// ParseDuration parses a Go duration (e.g. 10m) or a number of seconds
+func ParseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// IsTransientFailure checks the output of a failed command for errors which may not happen again
+func IsTransientFailure(out string, err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	text := strings.ToLower(out + "\n" + err.Error())
+	for _, pattern := range transientFailurePatterns {
+		if strings.Contains(text, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry runs the operation and retries it while it fails with a transient failure or runs out of its attempt timeout,
+// deterministic failures return immediately. Nothing is retried once ctx is done.
+func Retry(ctx context.Context, opts RetryOptions, operation string, logger log.Logger, run func(ctx context.Context) (string, error)) (string, error) {
+	wait := opts.Wait
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if opts.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		}
+		out, err := run(attemptCtx)
+		timedOut := err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err == nil {
+			return out, nil
+		}
+		if timedOut {
+			err = fmt.Errorf("%s didn't finish within command_timeout (%s): %w", operation, opts.Timeout, err)
+		}
+
+		transient := ctx.Err() == nil && (timedOut || IsTransientFailure(out, err))
+		if attempt >= opts.Count || !transient {
+			if attempt > 0 {
+				return out, fmt.Errorf("%w (after %d attempt(s))", err, attempt+1)
+			}
+			return out, err
+		}
+
+		if out != "" {
+			logger.Printf("%s", out)
+		}
+		logger.Warnf("%s failed with a transient error, retrying in %s (%d/%d): %s", operation, wait, attempt+1, opts.Count, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return out, err
+		}
+		wait = nextWait(wait, opts.Backoff)
+	}
+}
+
+// nextWait grows the wait between retries by the backoff multiplier, up to maxRetryWait
+func nextWait(wait time.Duration, backoff float64) time.Duration {
+	if backoff <= 1 {
+		return wait
+	}
+	next := time.Duration(float64(wait) * backoff)
+	if next > maxRetryWait {
+		return maxRetryWait
+	}
+	return next
+}
@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		count        int
+		timeout      time.Duration
+		failures     []string
+		wantAttempts int
+		wantErr      string
+	}{
+		{name: "success", count: 2, wantAttempts: 1},
+		{name: "transient failure is retried", count: 2, failures: []string{"fatal: unable to access: Could not resolve host: github.com"}, wantAttempts: 2},
+		{name: "retries run out", count: 2, failures: []string{"connection reset", "HTTP 503", "status 429"}, wantAttempts: 3, wantErr: "(after 3 attempt(s))"},
+		{name: "deterministic failure isn't retried", count: 2, failures: []string{"unsupported artifact format"}, wantAttempts: 1, wantErr: "exit status 1"},
+		{name: "no retries", failures: []string{"connection reset"}, wantAttempts: 1, wantErr: "exit status 1"},
+		{name: "timed out attempt is retried", count: 1, timeout: 20 * time.Millisecond, failures: []string{"hang"}, wantAttempts: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			opts := RetryOptions{Count: tt.count, Wait: time.Millisecond, Backoff: 2, Timeout: tt.timeout}
+			_, err := Retry(context.Background(), opts, "Test", log.NewLogger(), func(ctx context.Context) (string, error) {
+				attempts++
+				if attempts > len(tt.failures) {
+					return "done", nil
+				}
+				if tt.failures[attempts-1] == "hang" {
+					<-ctx.Done()
+					return "", ctx.Err()
+				}
+				return tt.failures[attempts-1], errors.New("exit status 1")
+			})
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Retry() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Retry() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	_, err := Retry(ctx, RetryOptions{Count: 5, Wait: time.Hour}, "Test", log.NewLogger(), func(ctx context.Context) (string, error) {
+		attempts++
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return "connection reset", errors.New("exit status 1")
+	})
+	if attempts != 1 || err == nil {
+		t.Errorf("Retry() = %d attempts, error %v, want 1 attempt and the error", attempts, err)
+	}
+}
+
+func TestParseRetryOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want RetryOptions
+	}{
+		{name: "defaults", want: RetryOptions{Wait: 5 * time.Second, Backoff: 2}},
+		{name: "configured", cfg: config.Config{RetryCount: "3", RetryWait: "10", RetryBackoff: "1.5", CommandTimeout: "2m"},
+			want: RetryOptions{Count: 3, Wait: 10 * time.Second, Backoff: 1.5, Timeout: 2 * time.Minute}},
+		{name: "timeout in seconds", cfg: config.Config{CommandTimeout: "90"}, want: RetryOptions{Wait: 5 * time.Second, Backoff: 2, Timeout: 90 * time.Second}},
+		{name: "invalid count disables retries", cfg: config.Config{RetryCount: "-1", CommandTimeout: "30"}, want: RetryOptions{Timeout: 30 * time.Second}},
+		{name: "invalid wait and backoff are ignored", cfg: config.Config{RetryCount: "1", RetryWait: "soon", RetryBackoff: "0.5"},
+			want: RetryOptions{Count: 1, Wait: 5 * time.Second, Backoff: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseRetryOptions(tt.cfg, log.NewLogger()); got != tt.want {
+				t.Errorf("ParseRetryOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWait(t *testing.T) {
+	if got := nextWait(5*time.Second, 1); got != 5*time.Second {
+		t.Errorf("nextWait() without backoff = %s", got)
+	}
+	if got := nextWait(5*time.Second, 2); got != 10*time.Second {
+		t.Errorf("nextWait() = %s, want 10s", got)
+	}
+	if got := nextWait(4*time.Minute, 2); got != maxRetryWait {
+		t.Errorf("nextWait() = %s, want %s", got, maxRetryWait)
+	}
+}
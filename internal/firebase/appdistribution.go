@@ -0,0 +1,148 @@
+package firebase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/google"
+)
+
+// appDistributionAPIURL is the base URL of the Firebase App Distribution API
+const appDistributionAPIURL = "https://firebaseappdistribution.googleapis.com/v1"
+
+// ErrNoRelease is returned when the app has no distributed release yet
+var ErrNoRelease = errors.New("no release distributed yet")
+
+// Release is the latest release of an app distributed with Firebase App Distribution
+type Release struct {
+	DisplayVersion string
+	BuildVersion   string
+	CreateTime     time.Time
+	ConsoleURI     string
+	// SizeBytes is the size of the distributed binary
+	SizeBytes int64
+	// Platform is the platform of the app ID, android or ios
+	Platform string
+}
+
+// Version names the release like the Firebase console: 1.4.0 (120)
+func (r Release) Version() string {
+	if r.BuildVersion == "" {
+		return r.DisplayVersion
+	}
+	return fmt.Sprintf("%s (%s)", r.DisplayVersion, r.BuildVersion)
+}
+
+// Comparable reports whether the artifact can be compared with the release: an IPA with an iOS app or an APK with an
+// Android app. App Distribution serves the releases of AABs as APKs generated by Google Play, their sizes differ.
+func (r Release) Comparable(artifactPath string) bool {
+	switch strings.ToLower(filepath.Ext(artifactPath)) {
+	case ".ipa":
+		return r.Platform == "ios"
+	case ".apk":
+		return r.Platform == "android"
+	}
+	return false
+}
+
+// releasesResponse is the response of the list releases endpoint
+type releasesResponse struct {
+	Releases []struct {
+		DisplayVersion     string    `json:"displayVersion"`
+		BuildVersion       string    `json:"buildVersion"`
+		CreateTime         time.Time `json:"createTime"`
+		FirebaseConsoleURI string    `json:"firebaseConsoleUri"`
+		BinaryDownloadURI  string    `json:"binaryDownloadUri"`
+	} `json:"releases"`
+}
+
+// parseAppID returns the project number and the platform of a Firebase app ID like 1:1234567890:android:0a1b2c3d4e5f
+func parseAppID(appID string) (string, string, error) {
+	parts := strings.Split(appID, ":")
+	if len(parts) != 4 || parts[1] == "" || (parts[2] != "android" && parts[2] != "ios") {
+		return "", "", fmt.Errorf("invalid Firebase app ID %s, expected the format 1:<project number>:<android|ios>:<hash>", appID)
+	}
+	return parts[1], parts[2], nil
+}
+
+// LatestRelease fetches the latest release of the app from App Distribution and the size of its binary, the API is
+// called with an access token of the service account. Failed requests are retried with the retry options.
+func LatestRelease(ctx context.Context, appID, serviceAccountKey string, retry executor.RetryOptions, logger log.Logger) (Release, error) {
+	projectNumber, platform, err := parseAppID(appID)
+	if err != nil {
+		return Release{}, err
+	}
+	client := &http.Client{Timeout: time.Minute}
+	key, err := google.LoadServiceAccountKey(ctx, client, serviceAccountKey)
+	if err != nil {
+		return Release{}, err
+	}
+
+	var release Release
+	_, err = executor.Retry(ctx, retry, "Firebase App Distribution request", logger, func(ctx context.Context) (string, error) {
+		token, err := key.AccessToken(ctx, client, google.CloudPlatformScope)
+		if err != nil {
+			return "", err
+		}
+		release, err = latestReleaseOnce(ctx, client, projectNumber, appID, token)
+		return "", err
+	})
+	if err != nil {
+		return Release{}, err
+	}
+	release.Platform = platform
+	return release, nil
+}
+
+// latestReleaseOnce lists the latest release and measures its binary once
+func latestReleaseOnce(ctx context.Context, client *http.Client, projectNumber, appID, token string) (Release, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/apps/%s/releases?pageSize=1&orderBy=%s", appDistributionAPIURL, projectNumber, appID, url.QueryEscape("createTime desc"))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return Release{}, fmt.Errorf("App Distribution API request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return Release{}, fmt.Errorf("App Distribution API request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var releases releasesResponse
+	if err := json.NewDecoder(response.Body).Decode(&releases); err != nil {
+		return Release{}, fmt.Errorf("failed to parse App Distribution API response: %w", err)
+	}
+	if len(releases.Releases) == 0 {
+		return Release{}, ErrNoRelease
+	}
+	latest := releases.Releases[0]
+	if latest.BinaryDownloadURI == "" {
+		return Release{}, fmt.Errorf("release %s has no binary download URI", latest.DisplayVersion)
+	}
+	size, err := google.RemoteFileSize(ctx, client, latest.BinaryDownloadURI, "")
+	if err != nil {
+		return Release{}, fmt.Errorf("failed to measure the release binary: %w", err)
+	}
+	return Release{
+		DisplayVersion: latest.DisplayVersion,
+		BuildVersion:   latest.BuildVersion,
+		CreateTime:     latest.CreateTime,
+		ConsoleURI:     latest.FirebaseConsoleURI,
+		SizeBytes:      size,
+	}, nil
+}
@@ -0,0 +1,143 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// redirectTransport sends the requests to the App Distribution API and the binary downloads to the test server
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+func (t redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	request = request.Clone(request.Context())
+	request.URL.Scheme, request.URL.Host = target.Scheme, target.Host
+	return t.server.Client().Transport.RoundTrip(request)
+}
+
+func TestParseAppID(t *testing.T) {
+	tests := []struct {
+		appID             string
+		wantProjectNumber string
+		wantPlatform      string
+		wantErr           bool
+	}{
+		{appID: "1:1234567890:android:0a1b2c3d4e5f", wantProjectNumber: "1234567890", wantPlatform: "android"},
+		{appID: "1:1234567890:ios:0a1b2c3d4e5f", wantProjectNumber: "1234567890", wantPlatform: "ios"},
+		{appID: "1:1234567890:web:0a1b2c3d4e5f", wantErr: true},
+		{appID: "1::android:0a1b2c3d4e5f", wantErr: true},
+		{appID: "com.example.app", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.appID, func(t *testing.T) {
+			projectNumber, platform, err := parseAppID(tt.appID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAppID() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if projectNumber != tt.wantProjectNumber || platform != tt.wantPlatform {
+				t.Errorf("parseAppID() = %s, %s, want %s, %s", projectNumber, platform, tt.wantProjectNumber, tt.wantPlatform)
+			}
+		})
+	}
+}
+
+func TestReleaseComparable(t *testing.T) {
+	android := Release{DisplayVersion: "1.4.0", BuildVersion: "120", Platform: "android"}
+	ios := Release{DisplayVersion: "1.4.0", Platform: "ios"}
+	if android.Version() != "1.4.0 (120)" || ios.Version() != "1.4.0" {
+		t.Errorf("Version() = %s, %s", android.Version(), ios.Version())
+	}
+
+	tests := []struct {
+		release      Release
+		artifactPath string
+		want         bool
+	}{
+		{release: android, artifactPath: "app-release.APK", want: true},
+		{release: android, artifactPath: "app-release.aab"},
+		{release: android, artifactPath: "App.ipa"},
+		{release: ios, artifactPath: "App.ipa", want: true},
+		{release: ios, artifactPath: "app-release.apk"},
+	}
+	for _, tt := range tests {
+		if got := tt.release.Comparable(tt.artifactPath); got != tt.want {
+			t.Errorf("Comparable(%s) of the %s release = %t, want %t", tt.artifactPath, tt.release.Platform, got, tt.want)
+		}
+	}
+}
+
+func TestLatestReleaseOnce(t *testing.T) {
+	const appID = "1:1234567890:android:0a1b2c3d4e5f"
+	release := `{"releases": [{"displayVersion": "1.4.0", "buildVersion": "120", "createTime": "2026-03-01T12:00:00Z",
+		"firebaseConsoleUri": "https://console.firebase.google.com/releases/1", "binaryDownloadUri": "https://storage.example.com/app.apk?token=signed"}]}`
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    Release
+		wantErr string
+	}{
+		{name: "latest release", status: http.StatusOK, body: release, want: Release{
+			DisplayVersion: "1.4.0", BuildVersion: "120", CreateTime: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			ConsoleURI: "https://console.firebase.google.com/releases/1", SizeBytes: 5242880,
+		}},
+		{name: "no release", status: http.StatusOK, body: `{}`, wantErr: ErrNoRelease.Error()},
+		{name: "no binary", status: http.StatusOK, body: `{"releases": [{"displayVersion": "1.4.0"}]}`, wantErr: "release 1.4.0 has no binary download URI"},
+		{name: "forbidden", status: http.StatusForbidden, body: "The caller does not have permission\n",
+			wantErr: "App Distribution API request failed with status 403: The caller does not have permission"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotQuery, gotAuth, gotRange string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/app.apk" {
+					gotRange = r.Header.Get("Range")
+					w.Header().Set("Content-Range", "bytes 0-0/5242880")
+					w.WriteHeader(http.StatusPartialContent)
+					_, _ = io.WriteString(w, "P")
+					return
+				}
+				if r.URL.Path != "/v1/projects/1234567890/apps/"+appID+"/releases" {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				gotQuery, gotAuth = r.URL.RawQuery, r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			got, err := latestReleaseOnce(context.Background(), &http.Client{Transport: redirectTransport{server: server}}, "1234567890", appID, "ya29.token")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("latestReleaseOnce() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr == ErrNoRelease.Error() && !errors.Is(err, ErrNoRelease) {
+					t.Errorf("latestReleaseOnce() error = %v, want ErrNoRelease", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("latestReleaseOnce() error = %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("latestReleaseOnce() = %+v, want %+v", got, tt.want)
+			}
+			if gotQuery != "pageSize=1&orderBy=createTime+desc" || gotAuth != "Bearer ya29.token" || gotRange != "bytes=0-0" {
+				t.Errorf("requests = %q %q %q", gotQuery, gotAuth, gotRange)
+			}
+		})
+	}
+}
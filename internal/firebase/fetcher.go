@@ -0,0 +1,38 @@
+package firebase
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Fetcher fetches the latest release of firebase_app_id for the "vs last distributed build" comparison
+type Fetcher struct {
+	cfg    config.Config
+	retry  executor.RetryOptions
+	logger log.Logger
+}
+
+// NewFetcher returns a Fetcher reading the app and the service account key from the config
+func NewFetcher(cfg config.Config, retry executor.RetryOptions, logger log.Logger) Fetcher {
+	return Fetcher{cfg: cfg, retry: retry, logger: logger}
+}
+
+// Fetch returns the latest distributed release, nil when the app has no release yet or it can't be fetched
+func (f Fetcher) Fetch(ctx context.Context) *Release {
+	f.logger.Infof("Fetching the latest Firebase App Distribution release...")
+	release, err := LatestRelease(ctx, f.cfg.FirebaseAppID, string(f.cfg.FirebaseServiceAccountKey), f.retry, f.logger)
+	switch {
+	case errors.Is(err, ErrNoRelease):
+		f.logger.Printf("No release of %s distributed yet", f.cfg.FirebaseAppID)
+	case err != nil:
+		f.logger.Warnf("Failed to fetch the latest Firebase App Distribution release: %s", err)
+	default:
+		f.logger.Printf("Latest distributed release: %s, %s", release.Version(), f.cfg.Units().Format(release.SizeBytes))
+		return &release
+	}
+	return nil
+}
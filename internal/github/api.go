@@ -0,0 +1,95 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// githubAPIURL is the base URL of the GitHub API of github.com, GitHub Enterprise serves it at /api/v3 of its host
+const githubAPIURL = "https://api.github.com"
+
+// commentRequest is the request body of the create issue comment endpoint, PRs are commented as issues
+type commentRequest struct {
+	Body string `json:"body"`
+}
+
+// parseRepositoryURL returns the API base URL and the owner/repo of a Git remote URL like
+// https://github.com/owner/repo.git, git@github.com:owner/repo.git or ssh://git@ghe.example.com/owner/repo
+func parseRepositoryURL(repositoryURL string) (string, string, error) {
+	var host, repoPath string
+	if strings.Contains(repositoryURL, "://") {
+		parsed, err := url.Parse(repositoryURL)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid repository URL %s: %w", repositoryURL, err)
+		}
+		host, repoPath = parsed.Hostname(), parsed.Path
+	} else if userHost, scpPath, ok := strings.Cut(repositoryURL, ":"); ok {
+		// scp-like syntax of SSH remotes
+		host, repoPath = userHost[strings.LastIndex(userHost, "@")+1:], scpPath
+	}
+
+	repo := strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || strings.Count(repo, "/") != 1 {
+		return "", "", fmt.Errorf("repository URL %s doesn't name a GitHub repository", repositoryURL)
+	}
+	if host == "github.com" {
+		return githubAPIURL, repo, nil
+	}
+	return "https://" + host + "/api/v3", repo, nil
+}
+
+// postCommentWithAPI posts the markdown as a PR comment with the GitHub API, used when gh isn't installed.
+// The repository is taken from GIT_REPOSITORY_URL, gh finds it in the remotes of the checkout instead.
+func (c Commenter) postCommentWithAPI(ctx context.Context, markdown, prNumber, token string) error {
+	repositoryURL := c.envRepo.Get("GIT_REPOSITORY_URL")
+	if repositoryURL == "" {
+		return fmt.Errorf("GIT_REPOSITORY_URL is required to post PR comments without gh")
+	}
+	baseURL, repo, err := parseRepositoryURL(repositoryURL)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(commentRequest{Body: markdown})
+	if err != nil {
+		return fmt.Errorf("failed to encode comment: %w", err)
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/%s/comments", baseURL, repo, prNumber)
+	client := &http.Client{Timeout: time.Minute}
+	_, err = executor.Retry(ctx, c.retry, "PR comment", c.logger, func(ctx context.Context) (string, error) {
+		return "", postComment(ctx, client, endpoint, token, body)
+	})
+	return err
+}
+
+// postComment posts the encoded comment to the GitHub API once
+func postComment(ctx context.Context, client *http.Client, endpoint, token string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Accept", "application/vnd.github+json")
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("GitHub API request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("GitHub API request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseRepositoryURL(t *testing.T) {
+	tests := []struct {
+		url         string
+		wantBaseURL string
+		wantRepo    string
+		wantErr     bool
+	}{
+		{url: "https://github.com/bitrise-io/steps-bundle-analyzer.git", wantBaseURL: "https://api.github.com", wantRepo: "bitrise-io/steps-bundle-analyzer"},
+		{url: "git@github.com:bitrise-io/steps-bundle-analyzer.git", wantBaseURL: "https://api.github.com", wantRepo: "bitrise-io/steps-bundle-analyzer"},
+		{url: "ssh://git@ghe.example.com/mobile/app", wantBaseURL: "https://ghe.example.com/api/v3", wantRepo: "mobile/app"},
+		{url: "https://ghe.example.com/mobile/app/", wantBaseURL: "https://ghe.example.com/api/v3", wantRepo: "mobile/app"},
+		{url: "https://github.com/bitrise-io", wantErr: true},
+		{url: "/local/checkout", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			baseURL, repo, err := parseRepositoryURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRepositoryURL() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if baseURL != tt.wantBaseURL || repo != tt.wantRepo {
+				t.Errorf("parseRepositoryURL() = %s, %s, want %s, %s", baseURL, repo, tt.wantBaseURL, tt.wantRepo)
+			}
+		})
+	}
+}
+
+func TestPostComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "created", status: http.StatusCreated},
+		{name: "forbidden", status: http.StatusForbidden, wantErr: "GitHub API request failed with status 403: Resource not accessible by integration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotAuth, gotBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+				var request commentRequest
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				gotBody = request.Body
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusCreated {
+					_, _ = io.WriteString(w, "Resource not accessible by integration\n")
+				}
+			}))
+			defer server.Close()
+
+			err := postComment(context.Background(), server.Client(), server.URL+"/repos/mobile/app/issues/12/comments", "ghp_token", []byte(`{"body":"## Report"}`))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("postComment() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("postComment() error = %v, want %q", err, tt.wantErr)
+			}
+			if gotPath != "/repos/mobile/app/issues/12/comments" || gotAuth != "Bearer ghp_token" || gotBody != "## Report" {
+				t.Errorf("request = %s %q %q", gotPath, gotAuth, gotBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// IsPullRequest checks if the current build is for a pull request
+func IsPullRequest(envRepo env.Repository) bool {
+	prNumber := envRepo.Get("BITRISE_PULL_REQUEST")
+	return prNumber != "" && prNumber != "false"
+}
+
+// Commenter posts reports as pull request comments with the gh CLI, or with the GitHub API on stacks without gh
+type Commenter struct {
+	exec    executor.Executor
+	envRepo env.Repository
+	retry   executor.RetryOptions
+	logger  log.Logger
+}
+
+// NewCommenter returns a Commenter running gh with the executor, API requests are retried with the retry options
+func NewCommenter(exec executor.Executor, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) Commenter {
+	return Commenter{exec: exec, envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// PostComment posts the markdown report as a PR comment
+// Note: Caller should verify IsPullRequest() before calling this function
+func (c Commenter) PostComment(ctx context.Context, markdownPath, token string) error {
+	if token == "" {
+		return fmt.Errorf("github_token is required for posting PR comments")
+	}
+
+	// Get PR number (caller already verified this is a PR build via IsPullRequest())
+	prNumber := c.envRepo.Get("BITRISE_PULL_REQUEST")
+
+	// Check if markdown file exists
+	if _, err := os.Stat(markdownPath); os.IsNotExist(err) {
+		return fmt.Errorf("markdown report not found: %s", markdownPath)
+	}
+
+	c.logger.Printf("Posting comment to PR #%s...", prNumber)
+
+	// Self-hosted runners may not have gh installed
+	if _, err := exec.LookPath("gh"); err != nil {
+		c.logger.Printf("gh CLI not found, posting the comment with the GitHub API")
+		markdown, err := os.ReadFile(markdownPath)
+		if err != nil {
+			return fmt.Errorf("failed to read markdown report: %w", err)
+		}
+		return c.postCommentWithAPI(ctx, string(markdown), prNumber, token)
+	}
+
+	// Use gh CLI to post comment
+	out, err := c.exec.Run(ctx, "PR comment", "gh", []string{"pr", "comment", prNumber, "--body-file", markdownPath}, &command.Opts{
+		Env: []string{fmt.Sprintf("GH_TOKEN=%s", token)},
+	})
+	if err != nil {
+		if out != "" {
+			c.logger.Printf("%s", out)
+		}
+		return fmt.Errorf("gh pr comment failed: %w", err)
+	}
+
+	if out != "" {
+		c.logger.Printf("%s", out)
+	}
+
+	return nil
+}
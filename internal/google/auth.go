@@ -0,0 +1,157 @@
+package google
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OAuth scopes of the Google APIs the step calls
+const (
+	CloudPlatformScope    = "https://www.googleapis.com/auth/cloud-platform"
+	AndroidPublisherScope = "https://www.googleapis.com/auth/androidpublisher"
+)
+
+// defaultTokenURI is the Google OAuth token endpoint, used when the key doesn't name one
+const defaultTokenURI = "https://oauth2.googleapis.com/token"
+
+// ServiceAccountKey holds the fields of a Google service account JSON key used to request access tokens
+type ServiceAccountKey struct {
+	Type        string `json:"type"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// LoadServiceAccountKey reads a service account key input: the JSON key itself, the path of the key file, or a
+// file:// or https:// URL of it like BITRISEIO_SERVICE_ACCOUNT_JSON_KEY_URL
+func LoadServiceAccountKey(ctx context.Context, client *http.Client, value string) (ServiceAccountKey, error) {
+	value = strings.TrimSpace(value)
+	data := []byte(value)
+	switch {
+	case strings.HasPrefix(value, "{"):
+	case strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://"):
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, value, nil)
+		if err != nil {
+			return ServiceAccountKey{}, fmt.Errorf("failed to create request: %w", err)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			return ServiceAccountKey{}, fmt.Errorf("failed to download the service account key: %w", err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return ServiceAccountKey{}, fmt.Errorf("failed to download the service account key: status %d", response.StatusCode)
+		}
+		if data, err = io.ReadAll(io.LimitReader(response.Body, 1<<20)); err != nil {
+			return ServiceAccountKey{}, fmt.Errorf("failed to download the service account key: %w", err)
+		}
+	default:
+		var err error
+		if data, err = os.ReadFile(strings.TrimPrefix(value, "file://")); err != nil {
+			return ServiceAccountKey{}, fmt.Errorf("failed to read the service account key: %w", err)
+		}
+	}
+
+	var key ServiceAccountKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return ServiceAccountKey{}, fmt.Errorf("failed to parse the service account key: %w", err)
+	}
+	if key.Type != "service_account" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return ServiceAccountKey{}, fmt.Errorf("the key isn't a service account JSON key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = defaultTokenURI
+	}
+	return key, nil
+}
+
+// Sign signs the data with the private key of the service account (RSASSA-PKCS1-v1_5 with SHA-256)
+func (k ServiceAccountKey) Sign(data []byte) ([]byte, error) {
+	block, _ := pem.Decode([]byte(k.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key of %s", k.ClientEmail)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key of %s: %w", k.ClientEmail, err)
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("the private key of %s isn't an RSA key", k.ClientEmail)
+	}
+	digest := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+}
+
+// signedAssertion returns the JWT the access token is requested with, signed with the private key of the service account
+func (k ServiceAccountKey) signedAssertion(scope string, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   k.ClientEmail,
+		"scope": scope,
+		"aud":   k.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature, err := k.Sign([]byte(unsigned))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign the token request: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// AccessToken exchanges the signed assertion of the service account for an OAuth access token of the scope
+func (k ServiceAccountKey) AccessToken(ctx context.Context, client *http.Client, scope string) (string, error) {
+	assertion, err := k.signedAssertion(scope, time.Now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, k.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("access token request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return "", fmt.Errorf("access token request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse access token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("the access token response has no token")
+	}
+	return token.AccessToken, nil
+}
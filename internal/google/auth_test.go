@@ -0,0 +1,143 @@
+package google
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// serviceAccountJSON returns a service account JSON key with a new RSA key and the token URI
+func serviceAccountJSON(t *testing.T, tokenURI string) (string, *rsa.PublicKey) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "bundle-analyzer@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(key), &privateKey.PublicKey
+}
+
+func TestLoadServiceAccountKey(t *testing.T) {
+	key, _ := serviceAccountJSON(t, "")
+	keyPath := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(keyPath, []byte(key), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/key.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, key)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "JSON key", value: "\n" + key + "\n"},
+		{name: "key file", value: keyPath},
+		{name: "file URL", value: "file://" + keyPath},
+		{name: "download URL", value: server.URL + "/key.json"},
+		{name: "missing download", value: server.URL + "/missing.json", wantErr: "failed to download the service account key: status 404"},
+		{name: "missing file", value: filepath.Join(t.TempDir(), "missing.json"), wantErr: "failed to read the service account key"},
+		{name: "not a service account", value: `{"type": "authorized_user", "client_email": "user@example.com", "private_key": "key"}`,
+			wantErr: "the key isn't a service account JSON key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadServiceAccountKey(context.Background(), server.Client(), tt.value)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadServiceAccountKey() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadServiceAccountKey() error = %s", err)
+			}
+			// The key doesn't name a token URI, the Google OAuth endpoint is used
+			if got.ClientEmail != "bundle-analyzer@example.iam.gserviceaccount.com" || got.TokenURI != defaultTokenURI {
+				t.Errorf("LoadServiceAccountKey() = %s with %s", got.ClientEmail, got.TokenURI)
+			}
+		})
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	var publicKey *rsa.PublicKey
+	var gotGrantType string
+	var gotClaims map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotGrantType = r.FormValue("grant_type")
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		if len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error": "invalid_grant"}`)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error": "invalid_grant", "error_description": "Invalid JWT Signature."}`)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		_ = json.Unmarshal(claims, &gotClaims)
+		_, _ = io.WriteString(w, `{"access_token": "ya29.token", "token_type": "Bearer", "expires_in": 3599}`)
+	}))
+	defer server.Close()
+
+	value, signingKey := serviceAccountJSON(t, server.URL+"/token")
+	key, err := LoadServiceAccountKey(context.Background(), server.Client(), value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey = signingKey
+	token, err := key.AccessToken(context.Background(), server.Client(), AndroidPublisherScope)
+	if err != nil {
+		t.Fatalf("AccessToken() error = %s", err)
+	}
+	if token != "ya29.token" || gotGrantType != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		t.Errorf("AccessToken() = %s with grant type %s", token, gotGrantType)
+	}
+	if gotClaims["iss"] != key.ClientEmail || gotClaims["scope"] != AndroidPublisherScope || gotClaims["aud"] != server.URL+"/token" {
+		t.Errorf("claims = %v", gotClaims)
+	}
+
+	// A token signed by another key is rejected
+	_, publicKey = serviceAccountJSON(t, "")
+	if _, err := key.AccessToken(context.Background(), server.Client(), AndroidPublisherScope); err == nil ||
+		!strings.Contains(err.Error(), "access token request failed with status 400: ") {
+		t.Errorf("AccessToken() error = %v, want the status 400", err)
+	}
+}
@@ -0,0 +1,44 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RemoteFileSize returns the size of the file behind the download URL without downloading it: only the first byte is
+// requested, signed URLs don't allow HEAD requests. The token authorizes the request if not empty.
+func RemoteFileSize(ctx context.Context, client *http.Client, downloadURL, token string) (int64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Range", "bytes=0-0")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("download request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		_, total, _ := strings.Cut(response.Header.Get("Content-Range"), "/")
+		size, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("file size unknown: Content-Range %q", response.Header.Get("Content-Range"))
+		}
+		return size, nil
+	case http.StatusOK:
+		if response.ContentLength < 0 {
+			return 0, fmt.Errorf("file size unknown")
+		}
+		return response.ContentLength, nil
+	}
+	return 0, fmt.Errorf("download request failed with status %d", response.StatusCode)
+}
@@ -0,0 +1,262 @@
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// androidPublisherAPIURL is the base URL of the Google Play Developer API
+const androidPublisherAPIURL = "https://androidpublisher.googleapis.com/androidpublisher/v3"
+
+// ReferenceDensity is the screen density of the device buckets, the most common density of Android phones
+const ReferenceDensity = "xxhdpi"
+
+// ErrNoProductionRelease is returned when the production track has no active release
+var ErrNoProductionRelease = errors.New("no release on the production track")
+
+// abiSplitNames maps the ABI config split IDs of Play (config.arm64_v8a) to the ABI names
+var abiSplitNames = map[string]string{
+	"armeabi":     "armeabi",
+	"armeabi_v7a": "armeabi-v7a",
+	"arm64_v8a":   "arm64-v8a",
+	"x86":         "x86",
+	"x86_64":      "x86_64",
+}
+
+// DeviceBucket is the download of the devices with an ABI and screen density
+type DeviceBucket struct {
+	// ABI is the native ABI of the devices like arm64-v8a, empty when the app has no native libraries
+	ABI       string
+	Density   string
+	SizeBytes int64
+}
+
+// Name names the bucket like arm64-v8a, xxhdpi
+func (b DeviceBucket) Name() string {
+	if b.ABI == "" {
+		return "all ABIs, " + b.Density
+	}
+	return b.ABI + ", " + b.Density
+}
+
+// PlayRelease is the release of the production track with the download sizes Google Play serves per device bucket
+type PlayRelease struct {
+	PackageName string
+	Name        string
+	VersionCode string
+	// Status is completed for a full rollout, inProgress for a staged rollout
+	Status  string
+	Buckets []DeviceBucket
+}
+
+// trackResponse is the response of the get track endpoint
+type trackResponse struct {
+	Releases []struct {
+		Name         string   `json:"name"`
+		VersionCodes []string `json:"versionCodes"`
+		Status       string   `json:"status"`
+	} `json:"releases"`
+}
+
+// generatedAPKsResponse is the response of the list generated APKs endpoint, the APKs Play generated from an AAB
+type generatedAPKsResponse struct {
+	GeneratedApks []struct {
+		GeneratedSplitApks []generatedSplitAPK `json:"generatedSplitApks"`
+	} `json:"generatedApks"`
+}
+
+// generatedSplitAPK is a split APK generated from an AAB, the master split of a module has no split ID
+type generatedSplitAPK struct {
+	DownloadID string `json:"downloadId"`
+	VariantID  int    `json:"variantId"`
+	ModuleName string `json:"moduleName"`
+	SplitID    string `json:"splitId"`
+}
+
+// playClient calls the Play Developer API for a package with an access token
+type playClient struct {
+	client      *http.Client
+	token       string
+	packageName string
+}
+
+// call sends a request to the Play Developer API and decodes the response into v if not nil
+func (c playClient) call(ctx context.Context, method, path string, v interface{}) error {
+	endpoint := androidPublisherAPIURL + "/applications/" + url.PathEscape(c.packageName) + path
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewReader([]byte("{}"))
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("Play Developer API request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("Play Developer API request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse Play Developer API response: %w", err)
+	}
+	return nil
+}
+
+// ProductionRelease fetches the release of the production track of the package and the download sizes of the APKs
+// Play generated from its AAB for each ABI at the ReferenceDensity. The full rollout is preferred over a staged one.
+// The API is called with an access token of the service account, failed requests are retried with the retry options.
+func ProductionRelease(ctx context.Context, packageName, serviceAccountKey string, retry executor.RetryOptions, logger log.Logger) (PlayRelease, error) {
+	client := &http.Client{Timeout: time.Minute}
+	key, err := LoadServiceAccountKey(ctx, client, serviceAccountKey)
+	if err != nil {
+		return PlayRelease{}, err
+	}
+
+	var release PlayRelease
+	_, err = executor.Retry(ctx, retry, "Play Developer API request", logger, func(ctx context.Context) (string, error) {
+		token, err := key.AccessToken(ctx, client, AndroidPublisherScope)
+		if err != nil {
+			return "", err
+		}
+		release, err = productionReleaseOnce(ctx, playClient{client: client, token: token, packageName: packageName})
+		return "", err
+	})
+	return release, err
+}
+
+// productionReleaseOnce reads the production track in a new edit, which is deleted afterwards as nothing is changed
+func productionReleaseOnce(ctx context.Context, c playClient) (PlayRelease, error) {
+	var edit struct {
+		ID string `json:"id"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/edits", &edit); err != nil {
+		return PlayRelease{}, err
+	}
+	defer func() {
+		_ = c.call(context.Background(), http.MethodDelete, "/edits/"+url.PathEscape(edit.ID), nil)
+	}()
+
+	var track trackResponse
+	if err := c.call(ctx, http.MethodGet, "/edits/"+url.PathEscape(edit.ID)+"/tracks/production", &track); err != nil {
+		return PlayRelease{}, err
+	}
+	release := PlayRelease{PackageName: c.packageName}
+	for _, candidate := range track.Releases {
+		if len(candidate.VersionCodes) == 0 || (candidate.Status != "completed" && candidate.Status != "inProgress") {
+			continue
+		}
+		if release.Status == "" || (release.Status == "inProgress" && candidate.Status == "completed") {
+			release.Name, release.Status, release.VersionCode = candidate.Name, candidate.Status, highestVersionCode(candidate.VersionCodes)
+		}
+	}
+	if release.VersionCode == "" {
+		return PlayRelease{}, ErrNoProductionRelease
+	}
+
+	var generated generatedAPKsResponse
+	if err := c.call(ctx, http.MethodGet, "/generatedApks/"+url.PathEscape(release.VersionCode), &generated); err != nil {
+		return PlayRelease{}, err
+	}
+	if len(generated.GeneratedApks) == 0 || len(generated.GeneratedApks[0].GeneratedSplitApks) == 0 {
+		return PlayRelease{}, fmt.Errorf("release %s has no APKs generated from an AAB, only the sizes of AAB uploads are available", release.VersionCode)
+	}
+
+	splits := baseModuleSplits(generated.GeneratedApks[0].GeneratedSplitApks)
+	sizeOf := func(splitID string) (int64, error) {
+		split, ok := splits[splitID]
+		if !ok {
+			return 0, nil
+		}
+		downloadURL := fmt.Sprintf("%s/applications/%s/generatedApks/%s/downloads/%s:download?alt=media",
+			androidPublisherAPIURL, url.PathEscape(c.packageName), url.PathEscape(release.VersionCode), url.PathEscape(split.DownloadID))
+		size, err := RemoteFileSize(ctx, c.client, downloadURL, c.token)
+		if err != nil && splitID == "" {
+			return 0, fmt.Errorf("failed to measure the master split: %w", err)
+		} else if err != nil {
+			return 0, fmt.Errorf("failed to measure the %s split: %w", splitID, err)
+		}
+		return size, nil
+	}
+
+	shared := int64(0)
+	for _, splitID := range []string{"", "config." + ReferenceDensity} {
+		size, err := sizeOf(splitID)
+		if err != nil {
+			return PlayRelease{}, err
+		}
+		shared += size
+	}
+	var abis []string
+	for splitID := range splits {
+		if abi, ok := abiSplitNames[strings.TrimPrefix(splitID, "config.")]; ok {
+			abis = append(abis, abi)
+		}
+	}
+	sort.Strings(abis)
+	if len(abis) == 0 {
+		release.Buckets = []DeviceBucket{{Density: ReferenceDensity, SizeBytes: shared}}
+		return release, nil
+	}
+	for _, abi := range abis {
+		size, err := sizeOf("config." + strings.ReplaceAll(abi, "-", "_"))
+		if err != nil {
+			return PlayRelease{}, err
+		}
+		release.Buckets = append(release.Buckets, DeviceBucket{ABI: abi, Density: ReferenceDensity, SizeBytes: shared + size})
+	}
+	return release, nil
+}
+
+// baseModuleSplits returns the split APKs of the base module by split ID, the master split has an empty ID. The
+// latest variant is used, it targets the newest Android versions.
+func baseModuleSplits(apks []generatedSplitAPK) map[string]generatedSplitAPK {
+	variant := 0
+	for _, apk := range apks {
+		if apk.ModuleName == "base" && apk.VariantID > variant {
+			variant = apk.VariantID
+		}
+	}
+	splits := map[string]generatedSplitAPK{}
+	for _, apk := range apks {
+		if apk.ModuleName == "base" && apk.VariantID == variant {
+			splits[apk.SplitID] = apk
+		}
+	}
+	return splits
+}
+
+// highestVersionCode returns the highest of the version codes of a release
+func highestVersionCode(versionCodes []string) string {
+	highest, highestValue := "", int64(-1)
+	for _, versionCode := range versionCodes {
+		if value, err := strconv.ParseInt(versionCode, 10, 64); err == nil && value > highestValue {
+			highest, highestValue = versionCode, value
+		}
+	}
+	return highest
+}
@@ -0,0 +1,182 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// redirectTransport sends the requests to the Play Developer API to the test server
+type redirectTransport struct {
+	server *httptest.Server
+}
+
+func (t redirectTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	target, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	request = request.Clone(request.Context())
+	request.URL.Scheme, request.URL.Host = target.Scheme, target.Host
+	return t.server.Client().Transport.RoundTrip(request)
+}
+
+// fakePlayAPI answers the Play Developer API with the track releases and the split APKs generated for version code 119,
+// measured by the sizes of their download IDs. The deleted edits are recorded.
+func fakePlayAPI(t *testing.T, track string, splits []generatedSplitAPK, sizes map[string]int64, deleted *[]string) *httptest.Server {
+	const prefix = "/androidpublisher/v3/applications/com.example.app"
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ya29.token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodPost && path == "/edits":
+			_, _ = io.WriteString(w, `{"id": "edit-1"}`)
+		case r.Method == http.MethodDelete && strings.HasPrefix(path, "/edits/"):
+			*deleted = append(*deleted, strings.TrimPrefix(path, "/edits/"))
+			w.WriteHeader(http.StatusNoContent)
+		case path == "/edits/edit-1/tracks/production":
+			_, _ = io.WriteString(w, track)
+		case path == "/generatedApks/119":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"generatedApks": []interface{}{map[string]interface{}{"generatedSplitApks": splits}}})
+		case strings.HasPrefix(path, "/generatedApks/119/downloads/") && strings.HasSuffix(path, ":download"):
+			size, ok := sizes[strings.TrimSuffix(strings.TrimPrefix(path, "/generatedApks/119/downloads/"), ":download")]
+			if !ok || r.Header.Get("Range") != "bytes=0-0" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-0/%d", size))
+			w.WriteHeader(http.StatusPartialContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestProductionReleaseOnce(t *testing.T) {
+	completed := `{"releases": [
+		{"name": "1.3.0", "versionCodes": ["119"], "status": "completed"},
+		{"name": "1.4.0", "versionCodes": ["120"], "status": "inProgress"},
+		{"name": "1.2.0", "versionCodes": ["118"], "status": "halted"}]}`
+	abiSplits := []generatedSplitAPK{
+		// The first variant targets older Android versions
+		{DownloadID: "old-master", VariantID: 1, ModuleName: "base"},
+		{DownloadID: "master", VariantID: 2, ModuleName: "base"},
+		{DownloadID: "xxhdpi", VariantID: 2, ModuleName: "base", SplitID: "config.xxhdpi"},
+		{DownloadID: "xhdpi", VariantID: 2, ModuleName: "base", SplitID: "config.xhdpi"},
+		{DownloadID: "arm64", VariantID: 2, ModuleName: "base", SplitID: "config.arm64_v8a"},
+		{DownloadID: "armv7", VariantID: 2, ModuleName: "base", SplitID: "config.armeabi_v7a"},
+		{DownloadID: "feature", VariantID: 2, ModuleName: "camera"},
+	}
+	sizes := map[string]int64{"master": 8000, "xxhdpi": 1500, "xhdpi": 1000, "arm64": 4000, "armv7": 3000}
+
+	tests := []struct {
+		name    string
+		track   string
+		splits  []generatedSplitAPK
+		want    PlayRelease
+		wantErr string
+	}{
+		{name: "ABI buckets", track: completed, splits: abiSplits, want: PlayRelease{PackageName: "com.example.app", Name: "1.3.0", VersionCode: "119", Status: "completed",
+			Buckets: []DeviceBucket{{ABI: "arm64-v8a", Density: "xxhdpi", SizeBytes: 13500}, {ABI: "armeabi-v7a", Density: "xxhdpi", SizeBytes: 12500}}}},
+		{name: "no native libraries", track: completed, splits: abiSplits[1:4], want: PlayRelease{PackageName: "com.example.app", Name: "1.3.0", VersionCode: "119", Status: "completed",
+			Buckets: []DeviceBucket{{Density: "xxhdpi", SizeBytes: 9500}}}},
+		{name: "staged rollout", track: `{"releases": [{"name": "1.3.0", "versionCodes": ["99", "119"], "status": "inProgress"}]}`, splits: abiSplits[1:2],
+			want: PlayRelease{PackageName: "com.example.app", Name: "1.3.0", VersionCode: "119", Status: "inProgress", Buckets: []DeviceBucket{{Density: "xxhdpi", SizeBytes: 8000}}}},
+		{name: "no production release", track: `{"releases": [{"name": "1.2.0", "versionCodes": ["118"], "status": "halted"}]}`, wantErr: ErrNoProductionRelease.Error()},
+		{name: "AAB not uploaded", track: completed, wantErr: "release 119 has no APKs generated from an AAB"},
+		{name: "unmeasured split", track: completed, splits: []generatedSplitAPK{
+			abiSplits[1], {DownloadID: "x86", VariantID: 2, ModuleName: "base", SplitID: "config.x86"}},
+			wantErr: "failed to measure the config.x86 split: download request failed with status 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted []string
+			server := fakePlayAPI(t, tt.track, tt.splits, sizes, &deleted)
+			defer server.Close()
+
+			client := playClient{client: &http.Client{Transport: redirectTransport{server: server}}, token: "ya29.token", packageName: "com.example.app"}
+			got, err := productionReleaseOnce(context.Background(), client)
+			// The edit only reads the track, it's always deleted
+			if len(deleted) != 1 || deleted[0] != "edit-1" {
+				t.Errorf("deleted edits = %v, want edit-1", deleted)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("productionReleaseOnce() error = %v, want %q", err, tt.wantErr)
+				}
+				if tt.wantErr == ErrNoProductionRelease.Error() && !errors.Is(err, ErrNoProductionRelease) {
+					t.Errorf("productionReleaseOnce() error = %v, want ErrNoProductionRelease", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("productionReleaseOnce() error = %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("productionReleaseOnce() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteFileSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int64
+		wantErr string
+	}{
+		{name: "partial content", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", "bytes 0-0/5242880")
+			w.WriteHeader(http.StatusPartialContent)
+		}, want: 5242880},
+		{name: "range ignored", handler: func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "full content")
+		}, want: 12},
+		{name: "unknown total", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Range", "bytes 0-0/*")
+			w.WriteHeader(http.StatusPartialContent)
+		}, wantErr: `file size unknown: Content-Range "bytes 0-0/*"`},
+		{name: "expired URL", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}, wantErr: "download request failed with status 403"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			got, err := RemoteFileSize(context.Background(), server.Client(), server.URL+"/app.apk", "")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("RemoteFileSize() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("RemoteFileSize() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceBucketName(t *testing.T) {
+	if got := (DeviceBucket{ABI: "arm64-v8a", Density: ReferenceDensity}).Name(); got != "arm64-v8a, xxhdpi" {
+		t.Errorf("Name() = %s", got)
+	}
+	if got := (DeviceBucket{Density: ReferenceDensity}).Name(); got != "all ABIs, xxhdpi" {
+		t.Errorf("Name() without ABI = %s", got)
+	}
+}
@@ -0,0 +1,60 @@
+package google
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// PlayFetcher fetches the production release of Google Play for the "vs Play production" comparison
+type PlayFetcher struct {
+	cfg    config.Config
+	retry  executor.RetryOptions
+	logger log.Logger
+}
+
+// NewPlayFetcher returns a PlayFetcher reading the package and the service account key from the config
+func NewPlayFetcher(cfg config.Config, retry executor.RetryOptions, logger log.Logger) PlayFetcher {
+	return PlayFetcher{cfg: cfg, retry: retry, logger: logger}
+}
+
+// Fetch returns the production release of the package, nil when there is none yet or it can't be fetched
+func (f PlayFetcher) Fetch(ctx context.Context, artifactPaths []string) *PlayRelease {
+	f.logger.Infof("Fetching the Google Play production release...")
+	packageName, err := f.packageName(artifactPaths)
+	var release PlayRelease
+	if err == nil {
+		release, err = ProductionRelease(ctx, packageName, string(f.cfg.PlayServiceAccountKey), f.retry, f.logger)
+	}
+	switch {
+	case errors.Is(err, ErrNoProductionRelease):
+		f.logger.Printf("No release of %s on the production track yet", packageName)
+	case err != nil:
+		f.logger.Warnf("Failed to fetch the Google Play production release: %s", err)
+	default:
+		f.logger.Printf("Production release: %s (version code %s)", release.Name, release.VersionCode)
+		for _, bucket := range release.Buckets {
+			f.logger.Printf("- %s: %s", bucket.Name(), f.cfg.Units().Format(bucket.SizeBytes))
+		}
+		return &release
+	}
+	return nil
+}
+
+// packageName returns play_package_name, or the package name of the first Android artifact
+func (f PlayFetcher) packageName(artifactPaths []string) (string, error) {
+	if f.cfg.PlayPackageName != "" {
+		return f.cfg.PlayPackageName, nil
+	}
+	for _, artifactPath := range artifactPaths {
+		if analyze.IsAndroidArtifact(artifactPath) {
+			return analyze.ReadPackageName(artifactPath)
+		}
+	}
+	return "", fmt.Errorf("no APK or AAB to read the package name from, set play_package_name")
+}
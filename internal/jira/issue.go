@@ -0,0 +1,230 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Update modes of jira_update
+const (
+	UpdateComment = "comment"
+	UpdateField   = "field"
+	UpdateBoth    = "both"
+)
+
+// DefaultSizeField is the name of the custom field updated when jira_size_field is empty
+const DefaultSizeField = "Bundle size"
+
+// issueKeyPattern matches issue keys like APP-123 in branch names and PR titles
+var issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// issueKeySources are the environment variables searched for an issue key, in priority order: the branch, then the
+// PR title and commit message
+var issueKeySources = []string{"BITRISE_GIT_BRANCH", "BITRISE_GIT_MESSAGE"}
+
+// FindIssueKey returns the first issue key of the branch or the PR title, and the variable it was found in
+func FindIssueKey(envRepo env.Repository) (string, string) {
+	for _, source := range issueKeySources {
+		if key := issueKeyPattern.FindString(envRepo.Get(source)); key != "" {
+			return key, source
+		}
+	}
+	return "", ""
+}
+
+// Options configure the update of the issue
+type Options struct {
+	// BaseURL is the URL of the Jira site, like https://company.atlassian.net
+	BaseURL string
+	// Email is the account of the API token on Jira Cloud, empty to authenticate with a personal access token of Jira
+	// Data Center
+	Email    string
+	APIToken string
+	IssueKey string
+	// Update is comment, field or both
+	Update string
+	// SizeField is the ID (customfield_10042) or the name of the field the size is written to
+	SizeField string
+	// BuildURL and ReportURL are linked from the comment when set
+	BuildURL  string
+	ReportURL string
+}
+
+// client calls the REST API of the site with the credentials of the options
+type client struct {
+	opts       Options
+	httpClient *http.Client
+}
+
+// call sends a request to the REST API once and decodes the JSON response into v, if it's not nil
+func (c client) call(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.opts.BaseURL, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.opts.Email != "" {
+		request.SetBasicAuth(c.opts.Email, c.opts.APIToken)
+	} else {
+		request.Header.Set("Authorization", "Bearer "+c.opts.APIToken)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("Jira request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("Jira API request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+// field is a field of the site, as listed by the field API
+type field struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Schema struct {
+		Type string `json:"type"`
+	} `json:"schema"`
+}
+
+// findField looks up the field by its ID or its name (case-insensitive)
+func (c client) findField(ctx context.Context, nameOrID string) (field, error) {
+	var fields []field
+	if err := c.call(ctx, http.MethodGet, "/rest/api/2/field", nil, &fields); err != nil {
+		return field{}, err
+	}
+	for _, f := range fields {
+		if f.ID == nameOrID || strings.EqualFold(f.Name, nameOrID) {
+			return f, nil
+		}
+	}
+	return field{}, fmt.Errorf("no field %s on the Jira site", nameOrID)
+}
+
+// UpdateIssue comments the size summary on the issue and/or writes the size to its field, as configured by the
+// update mode. Failed requests are retried with the retry options.
+func UpdateIssue(ctx context.Context, opts Options, results []analyze.ArtifactResult, units config.Units, retry executor.RetryOptions, logger log.Logger) error {
+	if opts.APIToken == "" {
+		return fmt.Errorf("jira_api_token is required to update the issue")
+	}
+	if opts.Update == "" {
+		opts.Update = UpdateComment
+	}
+	if opts.SizeField == "" {
+		opts.SizeField = DefaultSizeField
+	}
+	c := client{opts: opts, httpClient: &http.Client{Timeout: 30 * time.Second}}
+
+	if opts.Update == UpdateComment || opts.Update == UpdateBoth {
+		body := map[string]string{"body": summaryComment(results, units, opts.BuildURL, opts.ReportURL)}
+		_, err := executor.Retry(ctx, retry, "Jira comment request", logger, func(ctx context.Context) (string, error) {
+			return "", c.call(ctx, http.MethodPost, "/rest/api/2/issue/"+opts.IssueKey+"/comment", body, nil)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to comment on %s: %w", opts.IssueKey, err)
+		}
+		logger.Printf("Commented the size summary on %s", opts.IssueKey)
+	}
+
+	if opts.Update == UpdateField || opts.Update == UpdateBoth {
+		var sizeField field
+		_, err := executor.Retry(ctx, retry, "Jira field request", logger, func(ctx context.Context) (string, error) {
+			var err error
+			sizeField, err = c.findField(ctx, opts.SizeField)
+			return "", err
+		})
+		if err != nil {
+			return err
+		}
+		value := fieldValue(sizeField, results, units)
+		body := map[string]interface{}{"fields": map[string]interface{}{sizeField.ID: value}}
+		_, err = executor.Retry(ctx, retry, "Jira issue update", logger, func(ctx context.Context) (string, error) {
+			return "", c.call(ctx, http.MethodPut, "/rest/api/2/issue/"+opts.IssueKey, body, nil)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update %s of %s: %w", sizeField.Name, opts.IssueKey, err)
+		}
+		logger.Printf("Set %s of %s to %v", sizeField.Name, opts.IssueKey, value)
+	}
+	return nil
+}
+
+// fieldValue returns the value written to the size field: the size of the first artifact in the unit of size_units for
+// number fields, the sizes and changes of every artifact for text fields
+func fieldValue(sizeField field, results []analyze.ArtifactResult, units config.Units) interface{} {
+	if sizeField.Schema.Type == "number" {
+		return math.Round(units.Megabytes(results[0].Metrics.SizeBytes)*100) / 100
+	}
+	var sizes []string
+	for _, result := range results {
+		size := result.Name + ": " + units.Format(result.Metrics.SizeBytes)
+		if result.Comparison != nil {
+			size += fmt.Sprintf(" (%s vs #%s)", units.FormatDelta(result.Comparison.SizeDeltaBytes), result.Comparison.Baseline.BuildNumber)
+		}
+		sizes = append(sizes, size)
+	}
+	return strings.Join(sizes, ", ")
+}
+
+// summaryComment renders the size summary in the wiki markup of Jira comments: a table row per artifact with its
+// size, download size, change to the baseline and threshold result, and links to the build and the report
+func summaryComment(results []analyze.ArtifactResult, units config.Units, buildURL, reportURL string) string {
+	lines := []string{"h3. Bundle size", "||Artifact||Size||Download||Change||Thresholds||"}
+	for _, result := range results {
+		download, change := "-", "-"
+		if result.DownloadBytes > 0 {
+			download = "~" + units.Format(result.DownloadBytes)
+		}
+		if result.Comparison != nil {
+			change = fmt.Sprintf("%s vs #%s", units.FormatDelta(result.Comparison.SizeDeltaBytes), result.Comparison.Baseline.BuildNumber)
+		}
+		thresholds := "(/) passed"
+		if len(result.Violations) > 0 {
+			thresholds = fmt.Sprintf("(x) %d failed", len(result.Violations))
+		}
+		lines = append(lines, fmt.Sprintf("|%s|%s|%s|%s|%s|", result.Name, units.Format(result.Metrics.SizeBytes), download, change, thresholds))
+	}
+
+	var links []string
+	if buildURL != "" {
+		links = append(links, "[Build|"+buildURL+"]")
+	}
+	if reportURL != "" {
+		links = append(links, "[Report|"+reportURL+"]")
+	}
+	if len(links) > 0 {
+		lines = append(lines, "", strings.Join(links, " · "))
+	}
+	return strings.Join(lines, "\n")
+}
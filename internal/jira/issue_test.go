@@ -0,0 +1,204 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// testResults are an AAB grown since build 41 and an IPA failing a threshold, of 10.50 MB and 8.00 MB
+var testResults = []analyze.ArtifactResult{
+	{Name: "app-release.aab", Metrics: analyze.BundleMetrics{SizeBytes: 11010048}, DownloadBytes: 8388608,
+		Comparison: &analyze.BaselineComparison{Baseline: analyze.HistoryRecord{BuildNumber: "41"}, SizeDeltaBytes: 524288}},
+	{Name: "App.ipa", Metrics: analyze.BundleMetrics{SizeBytes: 8388608}, Violations: []analyze.ThresholdViolation{{Check: "max_size_mb"}}},
+}
+
+// jiraRequest is a request received by the fake Jira site
+type jiraRequest struct {
+	Method, Path, Auth string
+	Body               map[string]interface{}
+}
+
+// fakeJira serves the field list and records the comment and issue update requests, the requests of failPath fail
+// with 401
+func fakeJira(t *testing.T, failPath string) (*httptest.Server, *[]jiraRequest) {
+	t.Helper()
+	var requests []jiraRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := jiraRequest{Method: r.Method, Path: r.URL.Path, Auth: r.Header.Get("Authorization")}
+		_ = json.NewDecoder(r.Body).Decode(&request.Body)
+		requests = append(requests, request)
+		if r.URL.Path == failPath {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"errorMessages":["You do not have the permission to see the specified issue."]}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/field":
+			_, _ = io.WriteString(w, `[{"id": "summary", "name": "Summary", "schema": {"type": "string"}},
+				{"id": "customfield_10042", "name": "Bundle size", "schema": {"type": "number"}},
+				{"id": "customfield_10043", "name": "Bundle sizes", "schema": {"type": "string"}}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue/APP-123/comment":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(w, `{"id": "10000"}`)
+		case r.Method == http.MethodPut && r.URL.Path == "/rest/api/2/issue/APP-123":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFindIssueKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		branch     string
+		message    string
+		wantKey    string
+		wantSource string
+	}{
+		{name: "branch", branch: "feature/APP-123-smaller-images", message: "MOB-7 Smaller images", wantKey: "APP-123", wantSource: "BITRISE_GIT_BRANCH"},
+		{name: "PR title", branch: "feature/smaller-images", message: "[MOB_2-45] Smaller images", wantKey: "MOB_2-45", wantSource: "BITRISE_GIT_MESSAGE"},
+		{name: "no issue key", branch: "release/2-0", message: "Bump to v2-1, app-123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BITRISE_GIT_BRANCH", tt.branch)
+			t.Setenv("BITRISE_GIT_MESSAGE", tt.message)
+			if key, source := FindIssueKey(env.NewRepository()); key != tt.wantKey || source != tt.wantSource {
+				t.Errorf("FindIssueKey() = %s, %s, want %s, %s", key, source, tt.wantKey, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestSummaryComment(t *testing.T) {
+	want := "h3. Bundle size\n" +
+		"||Artifact||Size||Download||Change||Thresholds||\n" +
+		"|app-release.aab|10.50 MB|~8.00 MB|+0.50 MB vs #41|(/) passed|\n" +
+		"|App.ipa|8.00 MB|-|-|(x) 1 failed|\n" +
+		"\n" +
+		"[Build|https://app.bitrise.io/build/abc123] · [Report|https://reports.example.com/42/report.html]"
+	if got := summaryComment(testResults, config.NewUnits(""), "https://app.bitrise.io/build/abc123", "https://reports.example.com/42/report.html"); got != want {
+		t.Errorf("summaryComment() = %q, want %q", got, want)
+	}
+	if got := summaryComment(testResults[1:], config.NewUnits(""), "", ""); got != "h3. Bundle size\n||Artifact||Size||Download||Change||Thresholds||\n|App.ipa|8.00 MB|-|-|(x) 1 failed|" {
+		t.Errorf("summaryComment() without links = %q", got)
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	var number, text field
+	number.Schema.Type = "number"
+	text.Schema.Type = "string"
+	if got := fieldValue(number, testResults, config.NewUnits("")); got != 10.5 {
+		t.Errorf("fieldValue() of a number field = %v", got)
+	}
+	if got := fieldValue(number, testResults, config.NewUnits(config.SizeUnitsSI)); got != 11.01 {
+		t.Errorf("fieldValue() of a number field in SI units = %v", got)
+	}
+	if got := fieldValue(text, testResults, config.NewUnits("")); got != "app-release.aab: 10.50 MB (+0.50 MB vs #41), App.ipa: 8.00 MB" {
+		t.Errorf("fieldValue() of a text field = %v", got)
+	}
+}
+
+func TestUpdateIssue(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		failPath  string
+		wantPaths []string
+		wantAuth  string
+		wantField map[string]interface{}
+		wantErr   string
+	}{
+		{name: "comment with an API token", opts: Options{Email: "ci@example.com", APIToken: "token"},
+			wantPaths: []string{"POST /rest/api/2/issue/APP-123/comment"}, wantAuth: "Basic Y2lAZXhhbXBsZS5jb206dG9rZW4="},
+		{name: "number field by name with a personal access token", opts: Options{APIToken: "token", Update: UpdateField},
+			wantPaths: []string{"GET /rest/api/2/field", "PUT /rest/api/2/issue/APP-123"}, wantAuth: "Bearer token",
+			wantField: map[string]interface{}{"customfield_10042": 10.5}},
+		{name: "comment and text field by ID", opts: Options{APIToken: "token", Update: UpdateBoth, SizeField: "customfield_10043"},
+			wantPaths: []string{"POST /rest/api/2/issue/APP-123/comment", "GET /rest/api/2/field", "PUT /rest/api/2/issue/APP-123"}, wantAuth: "Bearer token",
+			wantField: map[string]interface{}{"customfield_10043": "app-release.aab: 10.50 MB (+0.50 MB vs #41), App.ipa: 8.00 MB"}},
+		{name: "unknown field", opts: Options{APIToken: "token", Update: UpdateField, SizeField: "APK size"},
+			wantPaths: []string{"GET /rest/api/2/field"}, wantAuth: "Bearer token", wantErr: "no field APK size on the Jira site"},
+		{name: "no permission", opts: Options{APIToken: "token"}, failPath: "/rest/api/2/issue/APP-123/comment",
+			wantPaths: []string{"POST /rest/api/2/issue/APP-123/comment"}, wantAuth: "Bearer token",
+			wantErr: `failed to comment on APP-123: Jira API request failed with status 401: {"errorMessages":["You do not have the permission to see the specified issue."]}`},
+		{name: "missing API token", opts: Options{Email: "ci@example.com"}, wantErr: "jira_api_token is required to update the issue"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := fakeJira(t, tt.failPath)
+			opts := tt.opts
+			opts.BaseURL, opts.IssueKey, opts.BuildURL = server.URL+"/", "APP-123", "https://app.bitrise.io/build/abc123"
+
+			err := UpdateIssue(context.Background(), opts, testResults, config.NewUnits(""), executor.RetryOptions{}, log.NewLogger())
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("UpdateIssue() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("UpdateIssue() error = %v, want %q", err, tt.wantErr)
+			}
+
+			var gotPaths []string
+			for _, request := range *requests {
+				gotPaths = append(gotPaths, request.Method+" "+request.Path)
+				if request.Auth != tt.wantAuth {
+					t.Errorf("%s %s authorization = %q, want %q", request.Method, request.Path, request.Auth, tt.wantAuth)
+				}
+				if request.Method == http.MethodPost && !strings.HasPrefix(request.Body["body"].(string), "h3. Bundle size\n") {
+					t.Errorf("comment = %v", request.Body)
+				}
+				if request.Method == http.MethodPut && !reflect.DeepEqual(request.Body["fields"], tt.wantField) {
+					t.Errorf("fields = %v, want %v", request.Body["fields"], tt.wantField)
+				}
+			}
+			if !reflect.DeepEqual(gotPaths, tt.wantPaths) {
+				t.Errorf("requests = %v, want %v", gotPaths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestUpdaterIssueKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		issueKey  string
+		branch    string
+		wantPaths int
+	}{
+		{name: "jira_issue_key input", issueKey: "APP-123", branch: "feature/MOB-7", wantPaths: 1},
+		{name: "branch", branch: "feature/APP-123-smaller-images", wantPaths: 1},
+		{name: "no issue key", branch: "feature/smaller-images"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BITRISE_GIT_BRANCH", tt.branch)
+			t.Setenv("BITRISE_GIT_MESSAGE", "")
+			server, requests := fakeJira(t, "")
+			cfg := config.Config{JiraBaseURL: server.URL, JiraAPIToken: "token", JiraIssueKey: tt.issueKey}
+
+			NewUpdater(cfg, env.NewRepository(), executor.RetryOptions{}, log.NewLogger()).Update(context.Background(), testResults, "")
+			if len(*requests) != tt.wantPaths {
+				t.Fatalf("requests = %+v, want %d", *requests, tt.wantPaths)
+			}
+			if tt.wantPaths > 0 && (*requests)[0].Path != "/rest/api/2/issue/APP-123/comment" {
+				t.Errorf("request = %+v", (*requests)[0])
+			}
+		})
+	}
+}
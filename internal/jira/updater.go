@@ -0,0 +1,55 @@
+package jira
+
+import (
+	"context"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Updater comments the size summary on the Jira issue of the build, as configured by the jira_* inputs
+type Updater struct {
+	cfg     config.Config
+	envRepo env.Repository
+	retry   executor.RetryOptions
+	logger  log.Logger
+}
+
+// NewUpdater returns an Updater taking the issue key from jira_issue_key, or from the branch and the PR title of the
+// environment
+func NewUpdater(cfg config.Config, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) Updater {
+	return Updater{cfg: cfg, envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// Update updates the issue with the results, reportURL links the uploaded report and may be empty. The update is
+// skipped without an issue key, a failed update is only logged.
+func (u Updater) Update(ctx context.Context, results []analyze.ArtifactResult, reportURL string) {
+	issueKey, source := u.cfg.JiraIssueKey, "jira_issue_key"
+	if issueKey == "" {
+		issueKey, source = FindIssueKey(u.envRepo)
+	}
+	if issueKey == "" {
+		u.logger.Infof("No Jira issue key in the branch or the PR title, skipping the Jira update")
+		return
+	}
+
+	u.logger.Infof("Updating Jira issue %s (from %s)...", issueKey, source)
+	opts := Options{
+		BaseURL:   u.cfg.JiraBaseURL,
+		Email:     u.cfg.JiraUserEmail,
+		APIToken:  string(u.cfg.JiraAPIToken),
+		IssueKey:  issueKey,
+		Update:    u.cfg.JiraUpdate,
+		SizeField: u.cfg.JiraSizeField,
+		BuildURL:  u.envRepo.Get("BITRISE_BUILD_URL"),
+		ReportURL: reportURL,
+	}
+	if err := UpdateIssue(ctx, opts, results, u.cfg.Units(), u.retry, u.logger); err != nil {
+		u.logger.Warnf("Failed to update Jira issue %s: %s", issueKey, err)
+	} else {
+		u.logger.Donef("Jira issue %s updated", issueKey)
+	}
+}
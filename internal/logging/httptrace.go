@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// TraceTransport logs every HTTP request with its status and duration as a debug message.
+// Headers and bodies aren't logged, they carry the API tokens and the uploaded reports.
+type TraceTransport struct {
+	transport http.RoundTripper
+	logger    log.Logger
+}
+
+// NewTraceTransport returns a transport sending the requests through transport and tracing them to logger
+func NewTraceTransport(transport http.RoundTripper, logger log.Logger) TraceTransport {
+	return TraceTransport{transport: transport, logger: logger}
+}
+
+// RoundTrip sends the request and logs its outcome
+func (t TraceTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	start := time.Now()
+	response, err := t.transport.RoundTrip(request)
+	url := request.URL.Redacted()
+	if err != nil {
+		t.logger.Debugf("HTTP %s %s failed after %s: %s", request.Method, url, time.Since(start).Round(time.Millisecond), err)
+		return nil, err
+	}
+	t.logger.Debugf("HTTP %s %s: %s in %s", request.Method, url, response.Status, time.Since(start).Round(time.Millisecond))
+	return response, nil
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// jsonLogEntry is a single line of the JSON log
+type jsonLogEntry struct {
+	Time      string                 `json:"time"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	ElapsedMs int64                  `json:"elapsed_ms"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// JSONLogger implements log.Logger, writing every message as a JSON line for log aggregation pipelines.
+// Each line carries the time elapsed since the step started, so durations of the analysis phases can be indexed.
+type JSONLogger struct {
+	mu             sync.Mutex
+	out            io.Writer
+	start          time.Time
+	enableDebugLog bool
+}
+
+// NewJSONLogger returns a logger writing JSON lines to out
+func NewJSONLogger(out io.Writer) *JSONLogger {
+	return &JSONLogger{out: out, start: time.Now()}
+}
+
+// Log writes a message with structured fields
+func (l *JSONLogger) Log(level, message string, fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	data, err := json.Marshal(jsonLogEntry{
+		Time:      now.UTC().Format(time.RFC3339Nano),
+		Level:     level,
+		Message:   message,
+		ElapsedMs: now.Sub(l.start).Milliseconds(),
+		Fields:    fields,
+	})
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"level":"error","message":"failed to encode log entry: %s"}`, err))
+	}
+	_, _ = l.out.Write(append(data, '\n'))
+}
+
+func (l *JSONLogger) Infof(format string, v ...interface{}) {
+	l.Log("info", fmt.Sprintf(format, v...), nil)
+}
+
+func (l *JSONLogger) Warnf(format string, v ...interface{}) {
+	l.Log("warn", fmt.Sprintf(format, v...), nil)
+}
+
+func (l *JSONLogger) Printf(format string, v ...interface{}) {
+	l.Log("normal", fmt.Sprintf(format, v...), nil)
+}
+
+func (l *JSONLogger) Donef(format string, v ...interface{}) {
+	l.Log("done", fmt.Sprintf(format, v...), nil)
+}
+
+func (l *JSONLogger) Debugf(format string, v ...interface{}) {
+	if l.enableDebugLog {
+		l.Log("debug", fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func (l *JSONLogger) Errorf(format string, v ...interface{}) {
+	l.Log("error", fmt.Sprintf(format, v...), nil)
+}
+
+// The JSON log lines always have a timestamp, the T variants are the same as the plain ones
+
+func (l *JSONLogger) TInfof(format string, v ...interface{})  { l.Infof(format, v...) }
+func (l *JSONLogger) TWarnf(format string, v ...interface{})  { l.Warnf(format, v...) }
+func (l *JSONLogger) TPrintf(format string, v ...interface{}) { l.Printf(format, v...) }
+func (l *JSONLogger) TDonef(format string, v ...interface{})  { l.Donef(format, v...) }
+func (l *JSONLogger) TDebugf(format string, v ...interface{}) { l.Debugf(format, v...) }
+func (l *JSONLogger) TErrorf(format string, v ...interface{}) { l.Errorf(format, v...) }
+
+// Println would only separate sections of the text log, it's dropped from the JSON log
+func (l *JSONLogger) Println() {}
+
+func (l *JSONLogger) EnableDebugLog(enable bool) {
+	l.enableDebugLog = enable
+}
+
+var _ log.Logger = (*JSONLogger)(nil)
@@ -0,0 +1,105 @@
+package logging
+
+import "github.com/bitrise-io/go-utils/v2/log"
+
+// Log levels of the log_level input
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelQuiet = "quiet"
+)
+
+// levelRanks orders the log levels, a logger drops the messages ranked below its level
+var levelRanks = map[string]int{LevelDebug: 0, LevelInfo: 1, LevelWarn: 2, LevelQuiet: 3}
+
+// LevelLogger drops the messages below its level: info drops the debug messages, warn keeps warnings and errors,
+// quiet only errors. The final summary is written to the wrapped logger, so it's printed at every level.
+type LevelLogger struct {
+	logger log.Logger
+	rank   int
+}
+
+// NewLevelLogger returns a logger writing the messages of the level and above to logger, an empty level is info
+func NewLevelLogger(logger log.Logger, level string) LevelLogger {
+	rank, ok := levelRanks[level]
+	if !ok {
+		rank = levelRanks[LevelInfo]
+	}
+	logger.EnableDebugLog(rank == levelRanks[LevelDebug])
+	return LevelLogger{logger: logger, rank: rank}
+}
+
+// Logs reports whether messages of the level are written
+func (l LevelLogger) Logs(level string) bool {
+	return levelRanks[level] >= l.rank
+}
+
+func (l LevelLogger) Infof(format string, v ...interface{}) {
+	if l.Logs(LevelInfo) {
+		l.logger.Infof(format, v...)
+	}
+}
+
+func (l LevelLogger) Warnf(format string, v ...interface{}) {
+	if l.Logs(LevelWarn) {
+		l.logger.Warnf(format, v...)
+	}
+}
+
+func (l LevelLogger) Printf(format string, v ...interface{}) {
+	if l.Logs(LevelInfo) {
+		l.logger.Printf(format, v...)
+	}
+}
+
+func (l LevelLogger) Donef(format string, v ...interface{}) {
+	if l.Logs(LevelInfo) {
+		l.logger.Donef(format, v...)
+	}
+}
+
+// Debugf is filtered by the wrapped logger, debug logging is only enabled with the debug level
+func (l LevelLogger) Debugf(format string, v ...interface{}) { l.logger.Debugf(format, v...) }
+
+func (l LevelLogger) Errorf(format string, v ...interface{}) { l.logger.Errorf(format, v...) }
+
+func (l LevelLogger) TInfof(format string, v ...interface{}) {
+	if l.Logs(LevelInfo) {
+		l.logger.TInfof(format, v...)
+	}
+}
+
+func (l LevelLogger) TWarnf(format string, v ...interface{}) {
+	if l.Logs(LevelWarn) {
+		l.logger.TWarnf(format, v...)
+	}
+}
+
+func (l LevelLogger) TPrintf(format string, v ...interface{}) {
+	if l.Logs(LevelInfo) {
+		l.logger.TPrintf(format, v...)
+	}
+}
+
+func (l LevelLogger) TDonef(format string, v ...interface{}) {
+	if l.Logs(LevelInfo) {
+		l.logger.TDonef(format, v...)
+	}
+}
+
+func (l LevelLogger) TDebugf(format string, v ...interface{}) { l.logger.TDebugf(format, v...) }
+
+func (l LevelLogger) TErrorf(format string, v ...interface{}) { l.logger.TErrorf(format, v...) }
+
+func (l LevelLogger) Println() {
+	if l.Logs(LevelInfo) {
+		l.logger.Println()
+	}
+}
+
+func (l LevelLogger) EnableDebugLog(enable bool) {
+	l.logger.EnableDebugLog(enable)
+}
+
+var _ log.Logger = LevelLogger{}
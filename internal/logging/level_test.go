@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelLogger(t *testing.T) {
+	tests := []struct {
+		level string
+		want  []string
+	}{
+		{level: LevelDebug, want: []string{"debug", "info", "normal", "done", "warn", "error"}},
+		{level: LevelInfo, want: []string{"info", "normal", "done", "warn", "error"}},
+		{level: "", want: []string{"info", "normal", "done", "warn", "error"}},
+		{level: LevelWarn, want: []string{"warn", "error"}},
+		{level: LevelQuiet, want: []string{"error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := NewLevelLogger(NewJSONLogger(&out), tt.level)
+			logger.Debugf("message")
+			logger.Infof("message")
+			logger.Printf("message")
+			logger.TDonef("message")
+			logger.Warnf("message")
+			logger.Errorf("message")
+
+			var levels []string
+			for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+				for _, level := range []string{"debug", "info", "normal", "done", "warn", "error"} {
+					if strings.Contains(line, `"level":"`+level+`"`) {
+						levels = append(levels, level)
+					}
+				}
+			}
+			if strings.Join(levels, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("logged levels = %v, want %v", levels, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	logger := NewJSONLogger(&out)
+	logger.EnableDebugLog(true)
+	client := &http.Client{Transport: NewTraceTransport(http.DefaultTransport, logger)}
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/upload", strings.NewReader("report"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer s3cr3t-token")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatalf("Do() error = %s", err)
+	}
+	_ = response.Body.Close()
+
+	if want := `"level":"debug","message":"HTTP POST ` + server.URL + `/upload: 202 Accepted in `; !strings.Contains(out.String(), want) {
+		t.Errorf("trace = %s, want %q", out.String(), want)
+	}
+	if strings.Contains(out.String(), "s3cr3t-token") {
+		t.Errorf("trace logged the authorization header: %s", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package logging
+
+import "github.com/bitrise-io/go-utils/v2/log"
+
+// PrefixLogger prepends a prefix to every message, it keeps the logs of concurrently analyzed artifacts apart
+type PrefixLogger struct {
+	logger log.Logger
+	prefix string
+}
+
+// NewPrefixLogger returns a logger writing the messages with the prefix to logger
+func NewPrefixLogger(logger log.Logger, prefix string) PrefixLogger {
+	return PrefixLogger{logger: logger, prefix: prefix}
+}
+
+func (l PrefixLogger) Infof(format string, v ...interface{})  { l.logger.Infof(l.prefix+format, v...) }
+func (l PrefixLogger) Warnf(format string, v ...interface{})  { l.logger.Warnf(l.prefix+format, v...) }
+func (l PrefixLogger) Printf(format string, v ...interface{}) { l.logger.Printf(l.prefix+format, v...) }
+func (l PrefixLogger) Donef(format string, v ...interface{})  { l.logger.Donef(l.prefix+format, v...) }
+func (l PrefixLogger) Debugf(format string, v ...interface{}) { l.logger.Debugf(l.prefix+format, v...) }
+func (l PrefixLogger) Errorf(format string, v ...interface{}) { l.logger.Errorf(l.prefix+format, v...) }
+func (l PrefixLogger) TInfof(format string, v ...interface{}) { l.logger.TInfof(l.prefix+format, v...) }
+func (l PrefixLogger) TWarnf(format string, v ...interface{}) { l.logger.TWarnf(l.prefix+format, v...) }
+func (l PrefixLogger) TPrintf(format string, v ...interface{}) {
+	l.logger.TPrintf(l.prefix+format, v...)
+}
+func (l PrefixLogger) TDonef(format string, v ...interface{}) { l.logger.TDonef(l.prefix+format, v...) }
+func (l PrefixLogger) TDebugf(format string, v ...interface{}) {
+	l.logger.TDebugf(l.prefix+format, v...)
+}
+func (l PrefixLogger) TErrorf(format string, v ...interface{}) {
+	l.logger.TErrorf(l.prefix+format, v...)
+}
+
+// Println is dropped, empty lines of concurrent analyses don't separate anything
+func (l PrefixLogger) Println() {}
+
+func (l PrefixLogger) EnableDebugLog(enable bool) {
+	l.logger.EnableDebugLog(enable)
+}
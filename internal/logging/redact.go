@@ -0,0 +1,170 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// RedactedPlaceholder replaces secrets in the log
+const RedactedPlaceholder = "[REDACTED]"
+
+// minSecretLength avoids masking short values (e.g. "1" or "true") all over the log
+const minSecretLength = 6
+
+// wellKnownSecretEnvs are environment variables which commonly hold credentials on Bitrise
+var wellKnownSecretEnvs = []string{"GIT_ACCESS_TOKEN", "GITHUB_TOKEN", "GH_TOKEN", "BITRISE_API_TOKEN", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AZURE_STORAGE_CONNECTION_STRING", "AZURE_STORAGE_SAS_TOKEN"}
+
+// secretURLPatterns match URLs which are credentials themselves: webhook URLs and URLs with embedded credentials or tokens
+var secretURLPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`https://hooks\.slack\.com/[^\s"')]+`),
+	regexp.MustCompile(`https://(?:[a-z]+\.)?discord(?:app)?\.com/api/webhooks/[^\s"')]+`),
+	regexp.MustCompile(`https://[a-z0-9-]+\.webhook\.office\.com/[^\s"')]+`),
+	regexp.MustCompile(`(https?://)[^\s/:@"']+:[^\s/@"']+@`),
+	regexp.MustCompile(`(?i)([?&](?:access_token|token|key|sig|signature|x-amz-signature)=)[^\s&"']+`),
+}
+
+// Redactor masks secret values in text written to the build log
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// Add registers secret values, empty and very short values are ignored. Every line of a multi-line secret (like a PEM
+// key) is registered too, so the secret is masked when it is logged line by line or re-wrapped.
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		r.add(value)
+		if strings.Contains(value, "\n") {
+			for _, line := range strings.Split(value, "\n") {
+				// The armor lines of PEM blocks aren't secret
+				if !strings.HasPrefix(strings.TrimSpace(line), "-----") {
+					r.add(line)
+				}
+			}
+		}
+	}
+
+	// Longer secrets first, so a secret containing another one is masked as a whole
+	sort.Slice(r.secrets, func(a, b int) bool {
+		return len(r.secrets[a]) > len(r.secrets[b])
+	})
+}
+
+func (r *Redactor) add(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) < minSecretLength || r.has(value) {
+		return
+	}
+	r.secrets = append(r.secrets, value)
+}
+
+func (r *Redactor) has(value string) bool {
+	for _, secret := range r.secrets {
+		if secret == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFromConfig registers the sensitive inputs, the well-known credential variables and the variables listed in redact_env_vars
+func (r *Redactor) AddFromConfig(cfg config.Config, envRepo env.Repository) {
+	r.Add(config.Secrets(cfg)...)
+
+	names := append([]string{}, wellKnownSecretEnvs...)
+	for _, line := range strings.Split(cfg.RedactEnvVars, "\n") {
+		names = append(names, strings.Split(line, ",")...)
+	}
+	for _, name := range names {
+		if name = strings.TrimPrefix(strings.TrimSpace(name), "$"); name != "" {
+			r.Add(envRepo.Get(name))
+		}
+	}
+}
+
+// Redact masks every registered secret and secret URL in the text
+func (r *Redactor) Redact(text string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, RedactedPlaceholder)
+	}
+
+	for _, pattern := range secretURLPatterns {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			groups := pattern.FindStringSubmatch(match)
+			if len(groups) > 1 {
+				// Keep the scheme or the parameter name to show what was masked
+				if strings.HasSuffix(match, "@") {
+					return groups[1] + RedactedPlaceholder + "@"
+				}
+				return groups[1] + RedactedPlaceholder
+			}
+			return RedactedPlaceholder
+		})
+	}
+	return text
+}
+
+// maxPendingLine bounds the text held back while waiting for the end of a line
+const maxPendingLine = 64 * 1024
+
+// RedactingWriter redacts everything written through it before passing it on. Text is passed on line by line, an
+// incomplete line is held back until it ends, so a secret split across Write calls is still masked.
+type RedactingWriter struct {
+	redactor *Redactor
+	out      io.Writer
+
+	mu      sync.Mutex
+	pending string
+}
+
+// NewRedactingWriter returns a writer masking the secrets of the redactor in everything written to out
+func NewRedactingWriter(redactor *Redactor, out io.Writer) *RedactingWriter {
+	return &RedactingWriter{redactor: redactor, out: out}
+}
+
+func (w *RedactingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending += string(p)
+	end := strings.LastIndex(w.pending, "\n") + 1
+	if end == 0 && len(w.pending) < maxPendingLine {
+		return len(p), nil
+	}
+	if end == 0 {
+		end = len(w.pending)
+	}
+
+	text := w.pending[:end]
+	w.pending = w.pending[end:]
+	if _, err := io.WriteString(w.out, w.redactor.Redact(text)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush passes on the held back incomplete line
+func (w *RedactingWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending == "" {
+		return nil
+	}
+	text := w.pending
+	w.pending = ""
+	_, err := io.WriteString(w.out, w.redactor.Redact(text))
+	return err
+}
@@ -0,0 +1,29 @@
+package newrelic
+
+import (
+	"context"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Client sends the analysis events to the New Relic account of the newrelic_* inputs
+type Client struct {
+	cfg     config.Config
+	envRepo env.Repository
+	retry   executor.RetryOptions
+	logger  log.Logger
+}
+
+// NewClient returns a Client reading the account, the region and the license key from the config
+func NewClient(cfg config.Config, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) Client {
+	return Client{cfg: cfg, envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// SendEvents sends a BundleAnalysis event per result, with the threshold outcomes
+func (c Client) SendEvents(ctx context.Context, results []analyze.ArtifactResult) error {
+	return SendEvents(ctx, c.cfg.NewRelicAccountID, c.cfg.NewRelicRegion, string(c.cfg.NewRelicLicenseKey), results, c.envRepo, c.retry, c.logger)
+}
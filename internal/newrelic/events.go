@@ -0,0 +1,132 @@
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// EventType is the New Relic event type of the analysis events, queried like SELECT * FROM BundleAnalysis
+const EventType = "BundleAnalysis"
+
+// eventAPIHosts are the hosts of the Event API by the region of the account
+var eventAPIHosts = map[string]string{
+	"us": "insights-collector.newrelic.com",
+	"eu": "insights-collector.eu01.nr-data.net",
+}
+
+// newEvents collects a BundleAnalysis event of every analyzed artifact with its metrics and threshold outcome. The
+// attributes of metrics which weren't measured and of unknown build details are left out, so they don't show up as
+// zeros or empty facets in the dashboards.
+func newEvents(results []analyze.ArtifactResult, envRepo env.Repository, now time.Time) []map[string]interface{} {
+	var events []map[string]interface{}
+	for _, result := range results {
+		event := map[string]interface{}{
+			"eventType":             EventType,
+			"timestamp":             now.Unix(),
+			"artifact":              result.Name,
+			"sizeBytes":             result.Metrics.SizeBytes,
+			"fileCount":             result.Metrics.FileCount,
+			"potentialSavingsBytes": result.Metrics.PotentialSavingsBytes,
+			"thresholdsPassed":      len(result.Violations) == 0,
+			"violationCount":        len(result.Violations),
+		}
+		for name, value := range map[string]string{
+			"appSlug":     envRepo.Get("BITRISE_APP_SLUG"),
+			"buildSlug":   envRepo.Get("BITRISE_BUILD_SLUG"),
+			"buildNumber": envRepo.Get("BITRISE_BUILD_NUMBER"),
+			"workflow":    envRepo.Get("BITRISE_TRIGGERED_WORKFLOW_ID"),
+			"branch":      envRepo.Get("BITRISE_GIT_BRANCH"),
+			"commit":      envRepo.Get("BITRISE_GIT_COMMIT"),
+			"app":         result.App.Name,
+			"version":     result.App.Version,
+			"variant":     result.App.Variant,
+		} {
+			if value != "" {
+				event[name] = value
+			}
+		}
+		if result.DownloadBytes > 0 {
+			event["downloadSizeBytes"] = result.DownloadBytes
+		}
+		if result.Metrics.DexMethodCount > 0 {
+			event["dexMethodCount"] = result.Metrics.DexMethodCount
+		}
+		if result.Metrics.ObfuscationMeasured {
+			event["obfuscationCoveragePercent"] = result.Metrics.ObfuscationCoverage
+		}
+		if result.Comparison != nil {
+			event["sizeDeltaBytes"] = result.Comparison.SizeDeltaBytes
+			event["fileCountDelta"] = result.Comparison.FileCountDelta
+			event["baselineBuildNumber"] = result.Comparison.Baseline.BuildNumber
+		}
+		if len(result.Violations) > 0 {
+			var checks []string
+			for _, violation := range result.Violations {
+				checks = append(checks, violation.Check)
+			}
+			// Event attributes can't be arrays, NRQL matches the checks with LIKE '%fail_on_size_increase%'
+			event["violations"] = strings.Join(checks, ",")
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// SendEvents sends the BundleAnalysis events to the Event API of the account in the region (us or eu)
+func SendEvents(ctx context.Context, accountID, region, licenseKey string, results []analyze.ArtifactResult, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) error {
+	if accountID == "" {
+		return fmt.Errorf("newrelic_account_id is required to send events")
+	}
+	host, ok := eventAPIHosts[region]
+	if !ok {
+		host = eventAPIHosts["us"]
+	}
+
+	events := newEvents(results, envRepo, time.Now())
+	body, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	url := fmt.Sprintf("https://%s/v1/accounts/%s/events", host, accountID)
+	logger.Printf("Sending %d %s event(s) to %s", len(events), EventType, url)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	_, err = executor.Retry(ctx, retry, "New Relic event submission", logger, func(ctx context.Context) (string, error) {
+		return "", postEvents(ctx, client, url, licenseKey, body)
+	})
+	return err
+}
+
+// postEvents posts the encoded events to the Event API once
+func postEvents(ctx context.Context, client *http.Client, url, licenseKey string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Api-Key", licenseKey)
+
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send events: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("New Relic Event API request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package newrelic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+func TestNewEvents(t *testing.T) {
+	// The build details of the CI running the tests are cleared
+	for _, key := range []string{"BITRISE_APP_SLUG", "BITRISE_BUILD_SLUG", "BITRISE_TRIGGERED_WORKFLOW_ID", "BITRISE_GIT_COMMIT"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("BITRISE_BUILD_NUMBER", "42")
+	t.Setenv("BITRISE_GIT_BRANCH", "main")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	results := []analyze.ArtifactResult{
+		{
+			Name:          "app-release.aab",
+			App:           analyze.AppIdentity{Name: "My App", Version: "1.4.0", Variant: "release"},
+			Metrics:       analyze.BundleMetrics{SizeBytes: 5000, FileCount: 12, PotentialSavingsBytes: 300, DexMethodCount: 64000, ObfuscationMeasured: true, ObfuscationCoverage: 92.5},
+			DownloadBytes: 4000,
+			Comparison:    &analyze.BaselineComparison{Baseline: analyze.HistoryRecord{BuildNumber: "41"}, SizeDeltaBytes: 250, FileCountDelta: 1},
+			Violations: []analyze.ThresholdViolation{
+				{Check: "fail_on_size_increase", Err: errors.New("grew")},
+				{Check: "max_size_mb", Err: errors.New("too large")},
+			},
+		},
+		{Name: "App.ipa", Metrics: analyze.BundleMetrics{SizeBytes: 9000, FileCount: 30}},
+	}
+
+	events := newEvents(results, env.NewRepository(), now)
+	want := []map[string]interface{}{
+		{
+			"eventType": EventType, "timestamp": now.Unix(), "artifact": "app-release.aab",
+			"sizeBytes": int64(5000), "fileCount": int64(12), "potentialSavingsBytes": int64(300),
+			"thresholdsPassed": false, "violationCount": 2, "violations": "fail_on_size_increase,max_size_mb",
+			"buildNumber": "42", "branch": "main", "app": "My App", "version": "1.4.0", "variant": "release",
+			"downloadSizeBytes": int64(4000), "dexMethodCount": int64(64000), "obfuscationCoveragePercent": 92.5,
+			"sizeDeltaBytes": int64(250), "fileCountDelta": int64(1), "baselineBuildNumber": "41",
+		},
+		// The metrics which weren't measured are left out
+		{
+			"eventType": EventType, "timestamp": now.Unix(), "artifact": "App.ipa",
+			"sizeBytes": int64(9000), "fileCount": int64(30), "potentialSavingsBytes": int64(0),
+			"thresholdsPassed": true, "violationCount": 0, "buildNumber": "42", "branch": "main",
+		},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("newEvents() = %d events, want %d", len(events), len(want))
+	}
+	for idx := range want {
+		if !reflect.DeepEqual(events[idx], want[idx]) {
+			t.Errorf("newEvents()[%d] = %v, want %v", idx, events[idx], want[idx])
+		}
+	}
+}
+
+func TestSendEventsRequiresAccountID(t *testing.T) {
+	err := SendEvents(context.Background(), "", "eu", "license", nil, env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+	if err == nil || err.Error() != "newrelic_account_id is required to send events" {
+		t.Errorf("SendEvents() error = %v", err)
+	}
+}
+
+func TestPostEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr string
+	}{
+		{name: "accepted", status: http.StatusOK},
+		{name: "invalid license key", status: http.StatusForbidden, wantErr: "New Relic Event API request failed with status 403: invalid license key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotKey string
+			var gotEvents []map[string]interface{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotKey = r.URL.Path, r.Header.Get("Api-Key")
+				_ = json.NewDecoder(r.Body).Decode(&gotEvents)
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusOK {
+					_, _ = io.WriteString(w, "invalid license key\n")
+				}
+			}))
+			defer server.Close()
+
+			body := []byte(`[{"eventType": "BundleAnalysis", "artifact": "app-release.aab", "sizeBytes": 5000}]`)
+			err := postEvents(context.Background(), server.Client(), server.URL+"/v1/accounts/1234567/events", "NRAK-license", body)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("postEvents() error = %s", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("postEvents() error = %v, want %q", err, tt.wantErr)
+			}
+			if gotPath != "/v1/accounts/1234567/events" || gotKey != "NRAK-license" || len(gotEvents) != 1 || gotEvents[0]["eventType"] != EventType {
+				t.Errorf("request = %s %q %v", gotPath, gotKey, gotEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package outputs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// defaultTopDirectoriesCount is the number of directories exported when top_directories_count is empty or invalid
+const defaultTopDirectoriesCount = 10
+
+// DefaultOutputPrefix is the prefix of the output names, output_env_prefix replaces it
+const DefaultOutputPrefix = "BUNDLE_"
+
+// Exporter exports the step outputs with envman
+type Exporter struct {
+	cmdFactory command.Factory
+	prefix     string
+	units      config.Units
+	logger     log.Logger
+}
+
+// NewExporter returns an Exporter running envman with the command factory, the outputs are exported with prefix
+// instead of DefaultOutputPrefix and the MB outputs in the units
+func NewExporter(cmdFactory command.Factory, prefix string, units config.Units, logger log.Logger) Exporter {
+	return Exporter{cmdFactory: cmdFactory, prefix: prefix, units: units, logger: logger}
+}
+
+// OutputKey returns the name an output is exported with, keys without DefaultOutputPrefix are kept
+func (e Exporter) OutputKey(key string) string {
+	if e.prefix == "" || !strings.HasPrefix(key, DefaultOutputPrefix) {
+		return key
+	}
+	return e.prefix + strings.TrimPrefix(key, DefaultOutputPrefix)
+}
+
+// Export exports a single environment variable to the following steps of the build, outputs with
+// DefaultOutputPrefix are renamed by OutputKey
+func (e Exporter) Export(key, value string) error {
+	cmd := e.cmdFactory.Create("envman", []string{"add", "--key", e.OutputKey(key)}, &command.Opts{
+		Stdin: strings.NewReader(value),
+	})
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		if out != "" {
+			return fmt.Errorf("%s: %w", out, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ExportOutputs exports all output environment variables, certExpiry is zero when the signature wasn't verified.
+// A failed output doesn't stop the export of the others, the failures are returned joined.
+func (e Exporter) ExportOutputs(metrics analyze.BundleMetrics, comparison *analyze.BaselineComparison, paths analyze.ReportPaths, certExpiry time.Time, commentPosted bool) error {
+	outputs := map[string]string{
+		"BUNDLE_ANALYZER_REPORT_PATH":    paths.Markdown,
+		"BUNDLE_ANALYZER_HTML_PATH":      paths.HTML,
+		"BUNDLE_ANALYZER_JSON_PATH":      paths.JSON,
+		"BUNDLE_ANALYZER_CSV_PATH":       paths.CSV,
+		"BUNDLE_ANALYZER_SARIF_PATH":     paths.SARIF,
+		"BUNDLE_LICENSE_INVENTORY_PATH":  paths.Licenses,
+		"BUNDLE_SIZE_BYTES":              fmt.Sprintf("%d", metrics.SizeBytes),
+		"BUNDLE_SIZE_MB":                 fmt.Sprintf("%.2f", e.units.Megabytes(metrics.SizeBytes)),
+		"BUNDLE_SIZE_SUMMARY":            sizeSummary(metrics, comparison, e.units),
+		"BUNDLE_POTENTIAL_SAVINGS_BYTES": fmt.Sprintf("%d", metrics.PotentialSavingsBytes),
+		"BUNDLE_DEX_METHOD_COUNT":        fmt.Sprintf("%d", metrics.DexMethodCount),
+		"BUNDLE_GITHUB_COMMENT_POSTED":   fmt.Sprintf("%t", commentPosted),
+		"BUNDLE_FILE_COUNT":              fmt.Sprintf("%d", metrics.FileCount),
+		"BUNDLE_SIZE_DELTA_BYTES":        "",
+		"BUNDLE_FILE_COUNT_DELTA":        "",
+		// Only Android artifacts have DEX classes to measure
+		"BUNDLE_OBFUSCATION_COVERAGE_PERCENT": "",
+		"BUNDLE_CERT_EXPIRY_DATE":             "",
+	}
+
+	if !certExpiry.IsZero() {
+		outputs["BUNDLE_CERT_EXPIRY_DATE"] = certExpiry.Format("2006-01-02")
+	}
+
+	if metrics.ObfuscationMeasured {
+		outputs["BUNDLE_OBFUSCATION_COVERAGE_PERCENT"] = fmt.Sprintf("%.1f", metrics.ObfuscationCoverage)
+	}
+
+	if comparison != nil {
+		outputs["BUNDLE_SIZE_DELTA_BYTES"] = fmt.Sprintf("%d", comparison.SizeDeltaBytes)
+		outputs["BUNDLE_FILE_COUNT_DELTA"] = fmt.Sprintf("%d", comparison.FileCountDelta)
+	}
+
+	keys := make([]string, 0, len(outputs))
+	for key := range outputs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		if err := e.Export(key, outputs[key]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to export %s: %w", e.OutputKey(key), err))
+		} else {
+			e.logger.Printf("Exported: %s=%s", e.OutputKey(key), outputs[key])
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sizeSummary describes the bundle size and its change in one line, like "142.3 MB (+1.8 MB, +1.3% vs main)".
+// The baseline is named by its branch, or by its build number if the branch isn't recorded.
+func sizeSummary(metrics analyze.BundleMetrics, comparison *analyze.BaselineComparison, units config.Units) string {
+	summary := fmt.Sprintf("%.1f %s", units.Megabytes(metrics.SizeBytes), units.Label())
+	if comparison == nil {
+		return summary
+	}
+
+	baseline := comparison.Baseline.Branch
+	if baseline == "" {
+		baseline = "build #" + comparison.Baseline.BuildNumber
+	}
+	delta := fmt.Sprintf("%+.1f %s", units.Megabytes(comparison.SizeDeltaBytes), units.Label())
+	if comparison.Baseline.SizeBytes > 0 {
+		delta += fmt.Sprintf(", %+.1f%%", float64(comparison.SizeDeltaBytes)*100/float64(comparison.Baseline.SizeBytes))
+	}
+	return fmt.Sprintf("%s (%s vs %s)", summary, delta, baseline)
+}
+
+// ExportTimedOut exports whether the analysis was stopped by analysis_timeout
+func (e Exporter) ExportTimedOut(timedOut bool) {
+	value := fmt.Sprintf("%t", timedOut)
+	key := e.OutputKey("BUNDLE_ANALYSIS_TIMED_OUT")
+	if err := e.Export(key, value); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, value)
+	}
+}
+
+// ExportDebugBuild exports whether an analyzed artifact looks like a debug build
+func (e Exporter) ExportDebugBuild(debug bool) {
+	value := fmt.Sprintf("%t", debug)
+	key := e.OutputKey("BUNDLE_IS_DEBUG_BUILD")
+	if err := e.Export(key, value); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, value)
+	}
+}
+
+// ExportTopDirectories exports the top_directories_count largest top-level directories of the artifact as JSON,
+// like [{"path":"lib","size_bytes":5242880,"file_count":12}]
+func (e Exporter) ExportTopDirectories(inventory analyze.Inventory, countInput string) {
+	count := defaultTopDirectoriesCount
+	if countInput != "" {
+		parsed, err := strconv.Atoi(countInput)
+		if err != nil || parsed < 1 {
+			e.logger.Warnf("Invalid top_directories_count value: %s", countInput)
+		} else {
+			count = parsed
+		}
+	}
+
+	key := e.OutputKey("BUNDLE_TOP_DIRECTORIES_JSON")
+	data, err := json.Marshal(inventory.TopDirectories(count))
+	if err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+		return
+	}
+	value := string(data)
+	if err := e.Export(key, value); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, value)
+	}
+}
+
+// ExportDistributedSizeDelta exports the size change against the latest Firebase App Distribution release
+func (e Exporter) ExportDistributedSizeDelta(deltaBytes int64) {
+	value := strconv.FormatInt(deltaBytes, 10)
+	key := e.OutputKey("BUNDLE_DISTRIBUTED_SIZE_DELTA_BYTES")
+	if err := e.Export(key, value); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, value)
+	}
+}
+
+// ExportSymbolsPresent exports whether the debug symbols of the artifact were found
+func (e Exporter) ExportSymbolsPresent(present bool) {
+	value := fmt.Sprintf("%t", present)
+	key := e.OutputKey("BUNDLE_SYMBOLS_PRESENT")
+	if err := e.Export(key, value); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, value)
+	}
+}
+
+// ExportAzureReportURL exports the URL of the main report uploaded to Azure Blob Storage
+func (e Exporter) ExportAzureReportURL(url string) {
+	key := e.OutputKey("BUNDLE_REPORT_AZURE_URL")
+	if err := e.Export(key, url); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, url)
+	}
+}
+
+// ExportGCSReportURL exports the URL of the main report uploaded to Cloud Storage
+func (e Exporter) ExportGCSReportURL(url string) {
+	key := e.OutputKey("BUNDLE_REPORT_GCS_URL")
+	if err := e.Export(key, url); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, url)
+	}
+}
+
+// ExportS3ReportURL exports the URL of the main report uploaded to S3
+func (e Exporter) ExportS3ReportURL(url string) {
+	key := e.OutputKey("BUNDLE_REPORT_S3_URL")
+	if err := e.Export(key, url); err != nil {
+		e.logger.Warnf("Failed to export %s: %s", key, err)
+	} else {
+		e.logger.Printf("Exported: %s=%s", key, url)
+	}
+}
@@ -0,0 +1,191 @@
+package outputs
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// fakeEnvman answers the envman add commands, the exported values are recorded by key
+type fakeEnvman map[string]string
+
+func (f fakeEnvman) Create(name string, args []string, opts *command.Opts) command.Command {
+	return fakeEnvmanCommand{envs: f, args: args, opts: opts}
+}
+
+// failingEnvman fails the envman add commands of its keys like envman rejecting a value
+type failingEnvman struct {
+	fakeEnvman
+	keys []string
+}
+
+func (f failingEnvman) Create(name string, args []string, opts *command.Opts) command.Command {
+	cmd := fakeEnvmanCommand{envs: f.fakeEnvman, args: args, opts: opts}
+	for _, key := range f.keys {
+		cmd.fail = cmd.fail || args[2] == key
+	}
+	return cmd
+}
+
+type fakeEnvmanCommand struct {
+	envs fakeEnvman
+	args []string
+	opts *command.Opts
+	fail bool
+}
+
+func (c fakeEnvmanCommand) PrintableCommandArgs() string {
+	return "envman " + strings.Join(c.args, " ")
+}
+
+func (c fakeEnvmanCommand) Run() error {
+	_, err := c.RunAndReturnTrimmedCombinedOutput()
+	return err
+}
+
+func (c fakeEnvmanCommand) RunAndReturnExitCode() (int, error) {
+	if err := c.Run(); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func (c fakeEnvmanCommand) RunAndReturnTrimmedOutput() (string, error) {
+	return c.RunAndReturnTrimmedCombinedOutput()
+}
+
+func (c fakeEnvmanCommand) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	value, err := io.ReadAll(c.opts.Stdin)
+	if err != nil {
+		return "", err
+	}
+	if c.fail {
+		return "envman: value too large", errors.New("exit status 1")
+	}
+	c.envs[c.args[2]] = string(value)
+	return "", nil
+}
+
+func (c fakeEnvmanCommand) Start() error {
+	return c.Run()
+}
+
+func (c fakeEnvmanCommand) Wait() error {
+	return nil
+}
+
+func TestExportPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		key     string
+		wantKey string
+	}{
+		{name: "default prefix", key: "BUNDLE_SIZE_BYTES", wantKey: "BUNDLE_SIZE_BYTES"},
+		{name: "custom prefix", prefix: "APK_", key: "BUNDLE_SIZE_BYTES", wantKey: "APK_SIZE_BYTES"},
+		{name: "result path", prefix: "APK_", key: "BUNDLE_ANALYZER_RESULT_PATH", wantKey: "APK_ANALYZER_RESULT_PATH"},
+		{name: "other key is kept", prefix: "APK_", key: "BITRISE_DEPLOY_DIR", wantKey: "BITRISE_DEPLOY_DIR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envs := fakeEnvman{}
+			exporter := NewExporter(envs, tt.prefix, config.NewUnits(""), log.NewLogger())
+			if got := exporter.OutputKey(tt.key); got != tt.wantKey {
+				t.Errorf("OutputKey() = %s, want %s", got, tt.wantKey)
+			}
+			if err := exporter.Export(tt.key, "5242880"); err != nil {
+				t.Fatalf("Export() error = %s", err)
+			}
+			if envs[tt.wantKey] != "5242880" || len(envs) != 1 {
+				t.Errorf("exported %v, want %s", envs, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestExportOutputsFailures(t *testing.T) {
+	metrics := analyze.BundleMetrics{SizeBytes: 5242880}
+	envs := fakeEnvman{}
+	exporter := NewExporter(failingEnvman{fakeEnvman: envs, keys: []string{"APK_SIZE_SUMMARY", "APK_ANALYZER_HTML_PATH"}}, "APK_", config.NewUnits(""), log.NewLogger())
+	err := exporter.ExportOutputs(metrics, nil, analyze.ReportPaths{}, time.Time{}, false)
+
+	// The failures are returned together, the other outputs are still exported
+	want := "failed to export APK_ANALYZER_HTML_PATH: envman: value too large: exit status 1\n" +
+		"failed to export APK_SIZE_SUMMARY: envman: value too large: exit status 1"
+	if err == nil || err.Error() != want {
+		t.Errorf("ExportOutputs() error = %v, want %q", err, want)
+	}
+	if envs["APK_SIZE_BYTES"] != "5242880" || envs["APK_SIZE_MB"] != "5.00" {
+		t.Errorf("exported %v", envs)
+	}
+	if _, ok := envs["APK_SIZE_SUMMARY"]; ok {
+		t.Errorf("exported the failed output APK_SIZE_SUMMARY: %v", envs)
+	}
+
+	if err := NewExporter(fakeEnvman{}, "", config.NewUnits(""), log.NewLogger()).ExportOutputs(metrics, nil, analyze.ReportPaths{}, time.Time{}, false); err != nil {
+		t.Errorf("ExportOutputs() error = %s", err)
+	}
+}
+
+func TestSizeSummary(t *testing.T) {
+	metrics := analyze.BundleMetrics{SizeBytes: 150 * 1024 * 1024}
+	tests := []struct {
+		name       string
+		comparison *analyze.BaselineComparison
+		want       string
+	}{
+		{name: "no baseline", want: "150.0 MB"},
+		{name: "baseline branch", comparison: &analyze.BaselineComparison{
+			Baseline:       analyze.HistoryRecord{Branch: "main", BuildNumber: "41", SizeBytes: 148 * 1024 * 1024},
+			SizeDeltaBytes: 2 * 1024 * 1024,
+		}, want: "150.0 MB (+2.0 MB, +1.4% vs main)"},
+		{name: "baseline build", comparison: &analyze.BaselineComparison{
+			Baseline:       analyze.HistoryRecord{BuildNumber: "41", SizeBytes: 160 * 1024 * 1024},
+			SizeDeltaBytes: -10 * 1024 * 1024,
+		}, want: "150.0 MB (-10.0 MB, -6.2% vs build #41)"},
+		{name: "baseline without size", comparison: &analyze.BaselineComparison{
+			Baseline:       analyze.HistoryRecord{Branch: "main"},
+			SizeDeltaBytes: 512 * 1024,
+		}, want: "150.0 MB (+0.5 MB vs main)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sizeSummary(metrics, tt.comparison, config.NewUnits("")); got != tt.want {
+				t.Errorf("sizeSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportTopDirectories(t *testing.T) {
+	inventory := analyze.Inventory{Entries: []analyze.ArchiveEntry{
+		{Path: "lib/arm64-v8a/libapp.so", CompressedSize: 900},
+		{Path: "res/drawable/icon.png", CompressedSize: 100},
+		{Path: "assets/data.bin", CompressedSize: 500},
+	}}
+	tests := []struct {
+		name  string
+		count string
+		want  string
+	}{
+		{name: "default count", want: `[{"path":"lib","size_bytes":900,"file_count":1},{"path":"assets","size_bytes":500,"file_count":1},{"path":"res","size_bytes":100,"file_count":1}]`},
+		{name: "count", count: "1", want: `[{"path":"lib","size_bytes":900,"file_count":1}]`},
+		{name: "invalid count uses the default", count: "0", want: `[{"path":"lib","size_bytes":900,"file_count":1},{"path":"assets","size_bytes":500,"file_count":1},{"path":"res","size_bytes":100,"file_count":1}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envs := fakeEnvman{}
+			NewExporter(envs, "", config.NewUnits(""), log.NewLogger()).ExportTopDirectories(inventory, tt.count)
+			if got := envs["BUNDLE_TOP_DIRECTORIES_JSON"]; got != tt.want {
+				t.Errorf("BUNDLE_TOP_DIRECTORIES_JSON = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// AnnotationDigestMarkdown creates a short summary of every artifact for the build's annotations:
+// status, size, delta to the baseline and the three largest files
+func AnnotationDigestMarkdown(results []analyze.ArtifactResult, locale Locale) string {
+	var sections []string
+	for _, result := range results {
+		status := "✅"
+		if len(result.Violations) > 0 {
+			status = "❌"
+		}
+
+		var lines []string
+		lines = append(lines, fmt.Sprintf("**%s Bundle analysis: %s**\n", status, result.Name))
+
+		size := fmt.Sprintf("Size: **%s**", locale.MB(result.Metrics.SizeBytes))
+		if result.Comparison != nil {
+			size += fmt.Sprintf(" (%s vs build #%s)", locale.DeltaMB(result.Comparison.SizeDeltaBytes), result.Comparison.Baseline.BuildNumber)
+		}
+		lines = append(lines, "- "+size+locale.Sprintf(" · Files: %d", result.Metrics.FileCount))
+
+		if largest := result.Inventory.LargestEntries(3); len(largest) > 0 {
+			var offenders []string
+			for _, entry := range largest {
+				offenders = append(offenders, fmt.Sprintf("`%s` (%s)", entry.Path, locale.MB(entry.CompressedSize)))
+			}
+			lines = append(lines, "- Largest files: "+strings.Join(offenders, ", "))
+		}
+
+		for _, violation := range result.Violations {
+			lines = append(lines, "- ❌ "+violation.Err.Error())
+		}
+
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// AnnotationStyle returns the annotation style matching the results: error if any artifact violates a threshold
+func AnnotationStyle(results []analyze.ArtifactResult) string {
+	for _, result := range results {
+		if len(result.Violations) > 0 {
+			return "error"
+		}
+	}
+	return "success"
+}
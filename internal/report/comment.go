@@ -0,0 +1,48 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// Layouts of the PR comment, the comment_layout input
+const (
+	CommentLayoutSections   = "sections"
+	CommentLayoutTable      = "table"
+	CommentLayoutPerComment = "per_comment"
+)
+
+// CommentTableMarkdown renders the compact PR comment of the table layout: a row per artifact with its size,
+// change to the baseline and potential savings. The thresholds are checked after the comment is posted.
+func CommentTableMarkdown(results []analyze.ArtifactResult, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("## 📦 Bundle Analysis Report\n\n")
+	b.WriteString("| Artifact | Size | Change | Potential Savings | Files |\n")
+	b.WriteString("|----------|------|--------|-------------------|-------|\n")
+	for _, result := range results {
+		name := result.Name
+		if result.Title != "" {
+			name = result.Title
+		}
+
+		change := "-"
+		if result.Comparison != nil {
+			change = fmt.Sprintf("%s vs build #%s", locale.DeltaMB(result.Comparison.SizeDeltaBytes), result.Comparison.Baseline.BuildNumber)
+		}
+
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", strings.ReplaceAll(name, "|", `\|`),
+			locale.MB(result.Metrics.SizeBytes), change, locale.MB(result.Metrics.PotentialSavingsBytes), locale.Sprintf("%d", result.Metrics.FileCount))
+	}
+	return b.String()
+}
+
+// WriteCommentTable writes the table layout of the PR comment to outputPath
+func WriteCommentTable(results []analyze.ArtifactResult, locale Locale, outputPath string) error {
+	if err := os.WriteFile(outputPath, []byte(CommentTableMarkdown(results, locale)), 0644); err != nil {
+		return fmt.Errorf("failed to write PR comment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestCommentTableMarkdown(t *testing.T) {
+	results := []analyze.ArtifactResult{
+		{Name: "app-release.apk", Metrics: analyze.BundleMetrics{SizeBytes: 5 * 1024 * 1024, PotentialSavingsBytes: 512 * 1024, FileCount: 1200},
+			Comparison: &analyze.BaselineComparison{Baseline: analyze.HistoryRecord{BuildNumber: "41"}, SizeDeltaBytes: 256 * 1024}},
+		{Name: "app-release.aab", Title: "My App | Play Store", Metrics: analyze.BundleMetrics{SizeBytes: 6 * 1024 * 1024, FileCount: 900}},
+	}
+	want := "## 📦 Bundle Analysis Report\n\n" +
+		"| Artifact | Size | Change | Potential Savings | Files |\n" +
+		"|----------|------|--------|-------------------|-------|\n" +
+		"| app-release.apk | 5.00 MB | +0.25 MB vs build #41 | 0.50 MB | 1200 |\n" +
+		"| My App \\| Play Store | 6.00 MB | - | 0.00 MB | 900 |\n"
+
+	if got := CommentTableMarkdown(results, Locale{}); got != want {
+		t.Errorf("CommentTableMarkdown() = %q, want %q", got, want)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "comment.md")
+	if err := WriteCommentTable(results, Locale{}, outputPath); err != nil {
+		t.Fatalf("WriteCommentTable() error = %s", err)
+	}
+	if content, err := os.ReadFile(outputPath); err != nil || string(content) != want {
+		t.Errorf("comment = %q, %v", content, err)
+	}
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// copyBufferSize is the size of the write buffer reports are copied through, HTML reports with treemaps can be hundreds of MB
+const copyBufferSize = 256 * 1024
+
+// copyFile copies a single file, overwriting the destination
+func copyFile(srcPath, dstPath string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return streamCopy(src, dstPath)
+}
+
+// streamCopy copies the opened file to dstPath without reading it into memory,
+// the file mode and modification time of the source are preserved
+func streamCopy(src *os.File, dstPath string) error {
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriterSize(dst, copyBufferSize)
+	if _, err := io.Copy(writer, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	// OpenFile only applies the mode to new files, and the umask
+	if err := os.Chmod(dstPath, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dstPath, info.ModTime(), info.ModTime())
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// csvHeader names the columns of the CSV report
+var csvHeader = []string{"path", "compressed_bytes", "uncompressed_bytes", "crc32"}
+
+// WriteCSVReport writes the files of the artifact to a CSV report, largest compressed size first, so the contents
+// can be compared and charted in a spreadsheet. Sizes are plain byte counts regardless of size_units.
+func WriteCSVReport(result analyze.ArtifactResult, csvPath string) error {
+	entries := append([]analyze.ArchiveEntry(nil), result.Inventory.Entries...)
+	sort.SliceStable(entries, func(a, b int) bool {
+		if entries[a].CompressedSize != entries[b].CompressedSize {
+			return entries[a].CompressedSize > entries[b].CompressedSize
+		}
+		return entries[a].Path < entries[b].Path
+	})
+
+	file, err := os.Create(csvPath)
+	if err != nil {
+		return fmt.Errorf("failed to create CSV report: %w", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	writer := csv.NewWriter(buffered)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+	for _, entry := range entries {
+		record := []string{
+			entry.Path,
+			strconv.FormatInt(entry.CompressedSize, 10),
+			strconv.FormatInt(entry.UncompressedSize, 10),
+			fmt.Sprintf("%08x", entry.CRC32),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV report: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write CSV report: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,177 @@
+package report
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/logging"
+)
+
+// debugBundleName is the file name of the debug bundle in BITRISE_DEPLOY_DIR
+const debugBundleName = "bundle-analyzer-debug.zip"
+
+// sensitiveEnvNamePattern matches environment variable names which likely hold credentials
+var sensitiveEnvNamePattern = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSPHRASE|PRIVATE|CREDENTIAL|API_KEY|ACCESS_KEY|WEBHOOK)`)
+
+// StageTiming is the duration of a stage of the step
+type StageTiming struct {
+	Stage      string `json:"stage"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// DebugBundle collects intermediate data of the step for support cases.
+// A nil DebugBundle is valid and ignores everything, so callers don't need to check whether export is enabled.
+type DebugBundle struct {
+	mu      sync.Mutex
+	envRepo env.Repository
+	start   time.Time
+	timings []StageTiming
+	workDir map[string]string
+}
+
+// NewDebugBundle starts collecting the data of the debug bundle
+func NewDebugBundle(envRepo env.Repository) *DebugBundle {
+	return &DebugBundle{envRepo: envRepo, start: time.Now(), workDir: map[string]string{}}
+}
+
+// Track records the duration of a stage started at the given time
+func (b *DebugBundle) Track(stage string, start time.Time) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timings = append(b.timings, StageTiming{Stage: stage, DurationMs: time.Since(start).Milliseconds()})
+}
+
+// AddWorkDir registers the working directory of an artifact's analysis, which holds the raw reports and the plugin output
+func (b *DebugBundle) AddWorkDir(artifact, dir string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.workDir[artifact] = dir
+}
+
+// Write zips the collected data, the configuration, the environment and the results into the directory.
+// Every text file is redacted, environment variables with credential-like names are masked completely.
+func (b *DebugBundle) Write(dir string, cfg config.Config, results []analyze.ArtifactResult, redactor *logging.Redactor) (string, error) {
+	if b == nil {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	bundlePath := filepath.Join(dir, debugBundleName)
+	file, err := os.Create(bundlePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create debug bundle: %w", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	addFile := func(name string, data []byte) error {
+		writer, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write([]byte(redactor.Redact(string(data))))
+		return err
+	}
+	addJSON := func(name string, value interface{}) error {
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		return addFile(name, data)
+	}
+
+	timings := append([]StageTiming{}, b.timings...)
+	timings = append(timings, StageTiming{Stage: "total", DurationMs: time.Since(b.start).Milliseconds()})
+	if err := addJSON("timings.json", timings); err != nil {
+		return "", fmt.Errorf("failed to add timings: %w", err)
+	}
+	if err := addJSON("config.json", config.Fields(cfg)); err != nil {
+		return "", fmt.Errorf("failed to add configuration: %w", err)
+	}
+	if err := addFile("environment.txt", []byte(environmentSnapshot(b.envRepo))); err != nil {
+		return "", fmt.Errorf("failed to add environment: %w", err)
+	}
+	if err := addJSON("results.json", debugResults(results)); err != nil {
+		return "", fmt.Errorf("failed to add results: %w", err)
+	}
+
+	var artifacts []string
+	for artifact := range b.workDir {
+		artifacts = append(artifacts, artifact)
+	}
+	sort.Strings(artifacts)
+	for _, artifact := range artifacts {
+		files, _ := filepath.Glob(filepath.Join(b.workDir[artifact], "bundle-analysis-*"))
+		files = append(files, filepath.Join(b.workDir[artifact], analyze.PluginOutputName))
+		for _, path := range files {
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			if err := addFile(artifact+"/"+filepath.Base(path), data); err != nil {
+				return "", fmt.Errorf("failed to add %s: %w", path, err)
+			}
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("failed to write debug bundle: %w", err)
+	}
+	return bundlePath, nil
+}
+
+// environmentSnapshot lists the environment, masking the values of variables with credential-like names
+func environmentSnapshot(envRepo env.Repository) string {
+	environ := envRepo.List()
+	sort.Strings(environ)
+
+	var lines []string
+	for _, entry := range environ {
+		name, value, _ := strings.Cut(entry, "=")
+		if value != "" && sensitiveEnvNamePattern.MatchString(name) {
+			value = logging.RedactedPlaceholder
+		}
+		lines = append(lines, name+"="+value)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// debugResults returns the results of the analysis as written to the debug bundle
+func debugResults(results []analyze.ArtifactResult) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, result := range results {
+		var violations []string
+		for _, violation := range result.Violations {
+			violations = append(violations, fmt.Sprintf("%s: %s", violation.Check, violation.Err))
+		}
+		entries = append(entries, map[string]interface{}{
+			"artifact":   result.ArtifactPath,
+			"metrics":    result.Metrics,
+			"comparison": result.Comparison,
+			"timed_out":  result.TimedOut,
+			"violations": violations,
+		})
+	}
+	return entries
+}
@@ -0,0 +1,74 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// Deployer copies the generated reports to BITRISE_DEPLOY_DIR
+type Deployer struct {
+	fileManager fileutil.FileManager
+	logger      log.Logger
+}
+
+// NewDeployer returns a Deployer writing the reports with the file manager
+func NewDeployer(fileManager fileutil.FileManager, logger log.Logger) Deployer {
+	return Deployer{fileManager: fileManager, logger: logger}
+}
+
+// Deploy copies generated reports to the deploy directory.
+// The prefix is prepended to the file names to keep the reports of multiple artifacts apart.
+// Only the report formats accepted by deployFormat are copied, the paths of the others are returned as generated.
+func (d Deployer) Deploy(generatedFiles analyze.ReportPaths, deployDir, prefix string, deployFormat func(format string) bool) (analyze.ReportPaths, error) {
+	var paths analyze.ReportPaths
+
+	// Create deploy directory if it doesn't exist
+	if err := os.MkdirAll(deployDir, 0755); err != nil {
+		return paths, fmt.Errorf("failed to create deploy directory: %w", err)
+	}
+
+	// Helper function to deploy a single file
+	deployFile := func(srcPath, format string) string {
+		if srcPath == "" {
+			return ""
+		}
+		if format != "" && !deployFormat(format) {
+			d.logger.Printf("Not deployed: %s (%s isn't in deploy_formats)", srcPath, format)
+			return srcPath
+		}
+
+		filename := prefix + filepath.Base(srcPath)
+		dstPath := filepath.Join(deployDir, filename)
+
+		src, err := d.fileManager.Open(srcPath)
+		if err != nil {
+			d.logger.Warnf("Failed to read %s: %s", srcPath, err)
+			return ""
+		}
+		defer src.Close()
+
+		if err := streamCopy(src, dstPath); err != nil {
+			d.logger.Warnf("Failed to write %s: %s", dstPath, err)
+			return ""
+		}
+
+		d.logger.Printf("Deployed: %s", dstPath)
+		return dstPath
+	}
+
+	// Copy each report file
+	paths.Markdown = deployFile(generatedFiles.Markdown, "markdown")
+	paths.HTML = deployFile(generatedFiles.HTML, "html")
+	paths.JSON = deployFile(generatedFiles.JSON, "json")
+	paths.CSV = deployFile(generatedFiles.CSV, "csv")
+	paths.SARIF = deployFile(generatedFiles.SARIF, "sarif")
+	// The license inventory isn't a report format, it's deployed whenever it's written
+	paths.Licenses = deployFile(generatedFiles.Licenses, "")
+
+	return paths, nil
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestDeploy(t *testing.T) {
+	workDir := t.TempDir()
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	generated := analyze.ReportPaths{
+		Markdown: filepath.Join(workDir, "bundle-analysis-app.md"),
+		HTML:     filepath.Join(workDir, "bundle-analysis-app.html"),
+		JSON:     filepath.Join(workDir, "bundle-analysis-app.json"),
+	}
+	for _, path := range []string{generated.Markdown, generated.HTML, generated.JSON} {
+		if err := os.WriteFile(path, []byte("report of "+filepath.Base(path)), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deployDir := filepath.Join(t.TempDir(), "deploy")
+	// A report of an earlier step run is overwritten, including its mode
+	if err := os.MkdirAll(deployDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(deployDir, "app-bundle-analysis-app.md"), []byte("earlier report with a longer content"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	deployFormat := func(format string) bool { return format != "html" }
+	paths, err := NewDeployer(fileutil.NewFileManager(), log.NewLogger()).Deploy(generated, deployDir, "app-", deployFormat)
+	if err != nil {
+		t.Fatalf("Deploy() error = %s", err)
+	}
+
+	want := analyze.ReportPaths{
+		Markdown: filepath.Join(deployDir, "app-bundle-analysis-app.md"),
+		HTML:     generated.HTML,
+		JSON:     filepath.Join(deployDir, "app-bundle-analysis-app.json"),
+	}
+	if paths != want {
+		t.Errorf("Deploy() = %+v, want %+v", paths, want)
+	}
+	if _, err := os.Stat(filepath.Join(deployDir, "app-bundle-analysis-app.html")); err == nil {
+		t.Errorf("HTML report is deployed, it isn't in deploy_formats")
+	}
+
+	for _, path := range []string{want.Markdown, want.JSON} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if wantContent := "report of " + filepath.Base(path)[len("app-"):]; string(content) != wantContent {
+			t.Errorf("%s = %q, want %q", path, content, wantContent)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0640 {
+			t.Errorf("%s mode = %s, want -rw-r-----", path, info.Mode().Perm())
+		}
+		if !info.ModTime().Equal(modTime) {
+			t.Errorf("%s modified at %s, want %s", path, info.ModTime(), modTime)
+		}
+	}
+}
+
+func TestDeployMissingReport(t *testing.T) {
+	generated := analyze.ReportPaths{Markdown: filepath.Join(t.TempDir(), "missing.md")}
+	deployAll := func(string) bool { return true }
+	paths, err := NewDeployer(fileutil.NewFileManager(), log.NewLogger()).Deploy(generated, t.TempDir(), "", deployAll)
+	if err != nil {
+		t.Fatalf("Deploy() error = %s", err)
+	}
+	if paths.Markdown != "" {
+		t.Errorf("Deploy() returned %s for a missing report", paths.Markdown)
+	}
+}
@@ -0,0 +1,306 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// Styles of report_icons
+const (
+	ReportIconsEmoji = "emoji"
+	// ReportIconsText replaces the status markers with text like [FAIL] and drops the decorative emoji
+	ReportIconsText = "text"
+	// ReportIconsNone drops every emoji
+	ReportIconsNone = "none"
+)
+
+// statusMarker is an emoji the reports use to mark a status, report_status_icons overrides it by name
+type statusMarker struct {
+	name     string
+	emoji    string
+	text     string
+	severity string
+}
+
+// statusMarkers are the status markers of the reports and the PR comment, the emoji without the variation selector
+// are also matched, reports written by older bundle-inspector versions use them
+var statusMarkers = []statusMarker{
+	{name: "passed", emoji: "✅", text: "[OK]", severity: "passed"},
+	{name: "failed", emoji: "❌", text: "[FAIL]", severity: "failed"},
+	{name: "warning", emoji: "⚠️", text: "[WARN]", severity: "warning"},
+	{name: "new", emoji: "🆕", text: "[NEW]"},
+	{name: "increase", emoji: "⬆️", text: "[UP]", severity: "warning"},
+	{name: "decrease", emoji: "⬇️", text: "[DOWN]", severity: "passed"},
+}
+
+// defaultSeverityColors color the status markers of the HTML report once they're text, like the emoji did
+var defaultSeverityColors = map[string]string{
+	"passed":  "#1a7f37",
+	"warning": "#9a6700",
+	"failed":  "#cf222e",
+}
+
+var (
+	// emojiPattern matches the pictographs and symbols used as emoji with an optional variation selector,
+	// and the space following them so dropping "📦 Bundle" leaves "Bundle"
+	emojiPattern = regexp.MustCompile(`[\x{1F000}-\x{1FAFF}\x{2600}-\x{27BF}\x{2B00}-\x{2BFF}]\x{FE0F}? ?`)
+	colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{3,8}|[a-zA-Z]+)$`)
+	// htmlRawElementPattern matches the opening tags of the elements whose content isn't HTML text
+	htmlRawElementPattern = regexp.MustCompile(`(?i)^(script|style)[\s>]`)
+	htmlTitleTagPattern   = regexp.MustCompile(`(?i)^/?title[\s>]`)
+)
+
+// Icons rewrites the emoji of the reports and the PR comment, configured by report_icons, report_status_icons and
+// report_severity_colors. The zero value keeps the emoji.
+type Icons struct {
+	// markers maps the status emoji to their replacement
+	markers map[string]string
+	// severities maps the status emoji to the severity colored in the HTML report
+	severities map[string]string
+	colors     map[string]string
+	// keep holds the report_status_icons replacements, rewriting a rewritten report leaves them as is
+	keep  map[string]bool
+	strip bool
+}
+
+// NewIcons returns the Icons of the report_icons style, the report_status_icons overrides like "passed=PASS,
+// failed=FAIL" and the report_severity_colors like "failed=#b00020, warning=orange"
+func NewIcons(style, statusIcons, severityColors string) (Icons, error) {
+	icons := Icons{markers: map[string]string{}, severities: map[string]string{}, keep: map[string]bool{}}
+	overrides, err := parseIconAssignments(statusIcons, "report_status_icons", func(name, _ string) error {
+		for _, marker := range statusMarkers {
+			if marker.name == name {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown status %s", name)
+	})
+	if err != nil {
+		return Icons{}, err
+	}
+	colors, err := parseIconAssignments(severityColors, "report_severity_colors", func(name, value string) error {
+		if _, ok := defaultSeverityColors[name]; !ok {
+			return fmt.Errorf("unknown severity %s", name)
+		}
+		if !colorPattern.MatchString(value) {
+			return fmt.Errorf("invalid color %s", value)
+		}
+		return nil
+	})
+	if err != nil {
+		return Icons{}, err
+	}
+
+	icons.strip = style == ReportIconsText || style == ReportIconsNone
+	for _, replacement := range overrides {
+		icons.keep[replacement] = true
+	}
+	for _, marker := range statusMarkers {
+		replacement, ok := overrides[marker.name]
+		switch {
+		case ok:
+		case style == ReportIconsText:
+			replacement = marker.text
+		case style == ReportIconsNone:
+			replacement = ""
+		case len(colors) == 0:
+			continue
+		default:
+			replacement = marker.emoji
+		}
+		for _, emoji := range []string{marker.emoji, strings.TrimSuffix(marker.emoji, "\uFE0F")} {
+			icons.markers[emoji] = replacement
+			icons.severities[emoji] = marker.severity
+		}
+	}
+	if len(icons.markers) > 0 || len(colors) > 0 {
+		icons.colors = map[string]string{}
+		for severity, color := range defaultSeverityColors {
+			icons.colors[severity] = color
+		}
+		for severity, color := range colors {
+			icons.colors[severity] = color
+		}
+	}
+	return icons, nil
+}
+
+// parseIconAssignments parses a comma or newline separated list of name=value assignments of the input
+func parseIconAssignments(value, input string, validate func(name, value string) error) (map[string]string, error) {
+	assignments := map[string]string{}
+	for _, assignment := range analyze.ParsePatterns(value) {
+		name, assigned, ok := strings.Cut(assignment, "=")
+		name, assigned = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(assigned)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %q: expected name=value", input, assignment)
+		}
+		if err := validate(name, assigned); err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", input, assignment, err)
+		}
+		assignments[name] = assigned
+	}
+	return assignments, nil
+}
+
+// Enabled reports whether the icons change the reports
+func (i Icons) Enabled() bool {
+	return len(i.markers) > 0 || i.strip
+}
+
+// Apply rewrites the emoji of markdown
+func (i Icons) Apply(markdown string) string {
+	return i.replace(markdown, func(replacement, _ string) string { return replacement })
+}
+
+// applyHTML rewrites the emoji of HTML text, the status markers are colored by their severity
+func (i Icons) applyHTML(text string) string {
+	return i.replace(text, func(replacement, severity string) string {
+		color := i.colors[severity]
+		if replacement == "" || color == "" {
+			return html.EscapeString(replacement)
+		}
+		return fmt.Sprintf(`<span class="bundle-analyzer-%s" style="color:%s">%s</span>`, severity, color, html.EscapeString(replacement))
+	})
+}
+
+// replace rewrites the status markers with render and drops the other emoji when strip is set
+func (i Icons) replace(content string, render func(replacement, severity string) string) string {
+	if !i.Enabled() {
+		return content
+	}
+	return emojiPattern.ReplaceAllStringFunc(content, func(match string) string {
+		emoji := strings.TrimSuffix(match, " ")
+		if i.keep[emoji] {
+			return match
+		}
+		replacement, ok := i.markers[emoji]
+		if !ok {
+			if i.strip {
+				return ""
+			}
+			return match
+		}
+		if replacement == "" {
+			return ""
+		}
+		return render(replacement, i.severities[emoji]) + match[len(emoji):]
+	})
+}
+
+// ApplyIcons rewrites the emoji of the markdown and HTML reports. Both are streamed: the markdown report line by line,
+// the HTML report tag by tag with only its text rewritten, scripts and styles copied as is and the title getting the
+// markers without colors.
+func ApplyIcons(paths analyze.ReportPaths, icons Icons) error {
+	if !icons.Enabled() {
+		return nil
+	}
+	if paths.Markdown != "" {
+		if err := rewriteWholeReport(paths.Markdown, icons.rewriteMarkdown); err != nil {
+			return fmt.Errorf("failed to rewrite the icons of the markdown report: %w", err)
+		}
+	}
+	if paths.HTML != "" {
+		if err := rewriteWholeReport(paths.HTML, icons.rewriteHTML); err != nil {
+			return fmt.Errorf("failed to rewrite the icons of the HTML report: %w", err)
+		}
+	}
+	return nil
+}
+
+// rewriteMarkdown copies the markdown report line by line, rewriting the emoji of every line
+func (i Icons) rewriteMarkdown(src *bufio.Reader, dst *bufio.Writer) error {
+	for {
+		line, err := src.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if _, werr := dst.WriteString(i.Apply(line)); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// rewriteHTML copies the HTML report tag by tag, rewriting the text between the tags
+func (i Icons) rewriteHTML(src *bufio.Reader, dst *bufio.Writer) error {
+	raw, inTitle := "", false
+	for first := true; ; first = false {
+		chunk, err := src.ReadString('<')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		// Every chunk but the first starts with a tag, the text up to the next tag follows it
+		tag, text := "", chunk
+		if !first {
+			if end := strings.IndexByte(chunk, '>'); end >= 0 {
+				tag, text = chunk[:end+1], chunk[end+1:]
+			}
+		}
+		switch {
+		case raw != "":
+			if strings.HasPrefix(strings.ToLower(tag), "/"+raw) {
+				raw = ""
+			}
+		case htmlRawElementPattern.MatchString(tag):
+			raw = strings.ToLower(htmlRawElementPattern.FindStringSubmatch(tag)[1])
+		case htmlTitleTagPattern.MatchString(tag):
+			inTitle = !strings.HasPrefix(tag, "/")
+		}
+
+		if _, werr := dst.WriteString(tag); werr != nil {
+			return werr
+		}
+		switch {
+		case raw != "":
+		case inTitle:
+			text = i.replace(text, func(replacement, _ string) string { return html.EscapeString(replacement) })
+		default:
+			text = i.applyHTML(text)
+		}
+		if _, werr := dst.WriteString(text); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// rewriteWholeReport rewrites a report file with rewrite through a temporary file
+func rewriteWholeReport(reportPath string, rewrite func(src *bufio.Reader, dst *bufio.Writer) error) error {
+	src, err := os.Open(reportPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmpPath := reportPath + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	writer := bufio.NewWriter(dst)
+	if err := rewrite(bufio.NewReader(src), writer); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, reportPath)
+}
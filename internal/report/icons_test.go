@@ -0,0 +1,89 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestIconsApply(t *testing.T) {
+	markdown := "## 📦 Bundle Analysis\n\n| ✅ passed | ❌ failed | ⚠️ warning | ⚠ old warning | ⬆️ +1.2 MB |\n"
+	tests := []struct {
+		name        string
+		style       string
+		statusIcons string
+		want        string
+	}{
+		{name: "emoji", style: ReportIconsEmoji, want: markdown},
+		{name: "text", style: ReportIconsText, want: "## Bundle Analysis\n\n| [OK] passed | [FAIL] failed | [WARN] warning | [WARN] old warning | [UP] +1.2 MB |\n"},
+		{name: "none", style: ReportIconsNone, want: "## Bundle Analysis\n\n| passed | failed | warning | old warning | +1.2 MB |\n"},
+		{name: "status overrides", style: ReportIconsEmoji, statusIcons: "passed=🟢, failed=🔴",
+			want: "## 📦 Bundle Analysis\n\n| 🟢 passed | 🔴 failed | ⚠️ warning | ⚠ old warning | ⬆️ +1.2 MB |\n"},
+		{name: "status overrides of the text style", style: ReportIconsText, statusIcons: "failed=FAILED",
+			want: "## Bundle Analysis\n\n| [OK] passed | FAILED failed | [WARN] warning | [WARN] old warning | [UP] +1.2 MB |\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			icons, err := NewIcons(tt.style, tt.statusIcons, "")
+			if err != nil {
+				t.Fatalf("NewIcons() error = %s", err)
+			}
+			if got := icons.Apply(markdown); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+			// Rewriting a rewritten report keeps it
+			if got := icons.Apply(tt.want); got != tt.want {
+				t.Errorf("Apply() of the rewritten report = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIconsInvalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		statusIcons    string
+		severityColors string
+		wantErr        string
+	}{
+		{name: "unknown status", statusIcons: "skipped=SKIP", wantErr: `invalid report_status_icons value "skipped=SKIP": unknown status skipped`},
+		{name: "missing value", statusIcons: "passed", wantErr: `invalid report_status_icons value "passed": expected name=value`},
+		{name: "unknown severity", severityColors: "info=blue", wantErr: `invalid report_severity_colors value "info=blue": unknown severity info`},
+		{name: "invalid color", severityColors: "failed=rgb(1,2,3)", wantErr: `invalid report_severity_colors value "failed=rgb(1": invalid color rgb(1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewIcons(ReportIconsEmoji, tt.statusIcons, tt.severityColors); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewIcons() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyIconsHTML(t *testing.T) {
+	content := "<html><head><title>✅ Report</title><style>.ok::before { content: \"✅\"; }</style></head>" +
+		"<body><h1>📦 Bundle</h1><td>❌ 5 MB</td><script>const icon = \"⚠️\";</script></body></html>"
+	reportPath := filepath.Join(t.TempDir(), "report.html")
+	if err := os.WriteFile(reportPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	icons, err := NewIcons(ReportIconsText, "", "failed=#b00020")
+	if err != nil {
+		t.Fatalf("NewIcons() error = %s", err)
+	}
+	if err := ApplyIcons(analyze.ReportPaths{HTML: reportPath}, icons); err != nil {
+		t.Fatalf("ApplyIcons() error = %s", err)
+	}
+	got, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<html><head><title>[OK] Report</title><style>.ok::before { content: \"✅\"; }</style></head>" +
+		"<body><h1>Bundle</h1><td><span class=\"bundle-analyzer-failed\" style=\"color:#b00020\">[FAIL]</span> 5 MB</td><script>const icon = \"⚠️\";</script></body></html>"
+	if string(got) != want {
+		t.Errorf("HTML report = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,328 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+var (
+	// markdownListItemPattern matches a numbered or bulleted markdown list item and captures its text
+	markdownListItemPattern = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*+])\s+(.*)$`)
+	// listingExtensionPattern matches the extension of a file name, it has at least one letter unlike a size like 1.2
+	listingExtensionPattern = regexp.MustCompile(`\.[A-Za-z0-9]*[A-Za-z][A-Za-z0-9]*$`)
+	htmlTagNamePattern      = regexp.MustCompile(`^/?([a-zA-Z][a-zA-Z0-9]*)`)
+	htmlTagsPattern         = regexp.MustCompile(`<[^>]*>`)
+)
+
+// ListingLimits limits the file listings of the markdown and HTML reports, configured by report_max_files and
+// report_max_depth. The zero value lists every file, the JSON report always holds the full inventory.
+type ListingLimits struct {
+	// MaxFiles is the number of files listed per table or list
+	MaxFiles int
+	// MaxDepth is the number of directory levels of the listed paths, deeper paths are left out
+	MaxDepth int
+}
+
+// NewListingLimits returns the ListingLimits of report_max_files and report_max_depth, empty values are unlimited
+func NewListingLimits(maxFiles, maxDepth string) (ListingLimits, error) {
+	var limits ListingLimits
+	for _, input := range []struct {
+		name  string
+		value string
+		limit *int
+	}{{"report_max_files", maxFiles, &limits.MaxFiles}, {"report_max_depth", maxDepth, &limits.MaxDepth}} {
+		if input.value == "" {
+			continue
+		}
+		limit, err := strconv.Atoi(input.value)
+		if err != nil || limit < 1 {
+			return ListingLimits{}, fmt.Errorf("invalid %s value %q: expected a positive number", input.name, input.value)
+		}
+		*input.limit = limit
+	}
+	return limits, nil
+}
+
+// Enabled reports whether the limits leave files out of the listings
+func (l ListingLimits) Enabled() bool {
+	return l.MaxFiles > 0 || l.MaxDepth > 0
+}
+
+// listing counts the files of a table or list while it's written
+type listing struct {
+	listed  int
+	omitted int
+}
+
+// keep decides whether the file listing item is written: items which don't start with a path aren't file listings
+// and are always kept, files deeper than MaxDepth or beyond MaxFiles are counted as omitted
+func (l ListingLimits) keep(current *listing, text string) bool {
+	listedPath, ok := listingPath(text)
+	if !ok {
+		return true
+	}
+	if l.MaxDepth > 0 && strings.Count(strings.TrimSuffix(listedPath, "/"), "/") > l.MaxDepth {
+		current.omitted++
+		return false
+	}
+	if l.MaxFiles > 0 && current.listed >= l.MaxFiles {
+		current.omitted++
+		return false
+	}
+	current.listed++
+	return true
+}
+
+// listingPath returns the path a listing item starts with, like `Frameworks/Lib.framework` or Assets.car
+func listingPath(text string) (string, bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", false
+	}
+	candidate := strings.Trim(fields[0], "`*_")
+	if candidate == "" || strings.ContainsAny(candidate, "<>[]()|") {
+		return "", false
+	}
+	if strings.Contains(candidate, "/") || listingExtensionPattern.MatchString(candidate) {
+		return candidate, true
+	}
+	return "", false
+}
+
+// omittedNote describes the files left out of a listing
+func omittedNote(omitted int) string {
+	return fmt.Sprintf("… %d more file(s), see the JSON report", omitted)
+}
+
+// ApplyListingLimits leaves the files beyond the limits out of the tables and lists of the markdown and HTML reports
+// and notes how many were omitted. Only the listings whose items start with a path are limited.
+func ApplyListingLimits(paths analyze.ReportPaths, limits ListingLimits) error {
+	if !limits.Enabled() {
+		return nil
+	}
+	if paths.Markdown != "" {
+		if err := rewriteWholeReport(paths.Markdown, limits.rewriteMarkdown); err != nil {
+			return fmt.Errorf("failed to limit the listings of the markdown report: %w", err)
+		}
+	}
+	if paths.HTML != "" {
+		if err := rewriteWholeReport(paths.HTML, limits.rewriteHTML); err != nil {
+			return fmt.Errorf("failed to limit the listings of the HTML report: %w", err)
+		}
+	}
+	return nil
+}
+
+// rewriteMarkdown copies the markdown report line by line, a table or a list ends at the first line that isn't a row
+// or an item of it
+func (l ListingLimits) rewriteMarkdown(src *bufio.Reader, dst *bufio.Writer) error {
+	var current listing
+	kind, columns, indent, fenced := "", 0, "", false
+	finish := func() error {
+		if current.omitted > 0 {
+			note := indent + "- " + omittedNote(current.omitted) + "\n"
+			if kind == "table" {
+				note = "| " + omittedNote(current.omitted) + " |" + strings.Repeat("  |", columns-1) + "\n"
+			}
+			if _, err := dst.WriteString(note); err != nil {
+				return err
+			}
+		}
+		current, kind = listing{}, ""
+		return nil
+	}
+
+	for rowIdx := 0; ; rowIdx++ {
+		line, err := src.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		content := strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(content)
+
+		// Code blocks are copied as is
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+		}
+		lineKind, text := "", ""
+		switch match := markdownListItemPattern.FindStringSubmatch(content); {
+		case fenced || strings.HasPrefix(trimmed, "```"):
+		case strings.HasPrefix(trimmed, "|"):
+			lineKind = "table"
+			cells := strings.Split(strings.Trim(trimmed, "|"), "|")
+			text = strings.TrimSpace(cells[0])
+			if kind != "table" {
+				columns, rowIdx = len(cells), 0
+			}
+		case match != nil:
+			lineKind, text = "list", match[1]
+			if kind != "list" {
+				indent = content[:len(content)-len(strings.TrimLeft(content, " \t"))]
+			}
+		}
+
+		if lineKind != kind && kind != "" {
+			if ferr := finish(); ferr != nil {
+				return ferr
+			}
+		}
+		if lineKind != "" && line != "" {
+			kind = lineKind
+			// The header and the separator of a table are always kept
+			if (lineKind == "table" && rowIdx < 2) || l.keep(&current, text) {
+				if _, werr := dst.WriteString(line); werr != nil {
+					return werr
+				}
+			}
+		} else if _, werr := dst.WriteString(line); werr != nil {
+			return werr
+		}
+
+		if err == io.EOF {
+			return finish()
+		}
+	}
+}
+
+// htmlListing is a table or a list of the HTML report while it's written
+type htmlListing struct {
+	listing
+	tag     string
+	columns int
+}
+
+// note returns the row or list item noting the omitted files of the listing
+func (h *htmlListing) note() string {
+	if h.tag == "table" {
+		return fmt.Sprintf(`<tr class="bundle-analyzer-omitted"><td colspan="%d">%s</td></tr>`, max(h.columns, 1), html.EscapeString(omittedNote(h.omitted)))
+	}
+	return fmt.Sprintf(`<li class="bundle-analyzer-omitted">%s</li>`, html.EscapeString(omittedNote(h.omitted)))
+}
+
+// rewriteHTML copies the HTML report tag by tag. The rows of the tables and the items of the lists are buffered and
+// only written if the limits keep them, items without a closing tag end at the next item or the end of the listing.
+func (l ListingLimits) rewriteHTML(src *bufio.Reader, dst *bufio.Writer) error {
+	var stack []*htmlListing
+	var item strings.Builder
+	raw, itemTag, nested := "", "", 0
+
+	out := func(content string) error {
+		if itemTag != "" {
+			item.WriteString(content)
+			return nil
+		}
+		_, err := dst.WriteString(content)
+		return err
+	}
+	flush := func() error {
+		content := item.String()
+		itemTag = ""
+		if !l.keepHTMLItem(stack[len(stack)-1], content) {
+			return nil
+		}
+		_, err := dst.WriteString(content)
+		return err
+	}
+
+	for first := true; ; first = false {
+		chunk, err := src.ReadString('<')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if err == nil {
+			chunk = strings.TrimSuffix(chunk, "<")
+		}
+
+		// Every chunk but the first is a tag followed by the text up to the next tag
+		tag, text := "", chunk
+		if !first {
+			tag, text = "<"+chunk, ""
+			if end := strings.IndexByte(chunk, '>'); end >= 0 {
+				tag, text = "<"+chunk[:end+1], chunk[end+1:]
+			}
+		}
+		name, closing := "", strings.HasPrefix(tag, "</")
+		if match := htmlTagNamePattern.FindStringSubmatch(strings.TrimPrefix(tag, "<")); match != nil {
+			name = strings.ToLower(match[1])
+		}
+		container := name == "table" || name == "ol" || name == "ul"
+
+		switch {
+		case raw != "":
+			if closing && name == raw {
+				raw = ""
+			}
+		case !closing && (name == "script" || name == "style"):
+			raw = name
+		case itemTag != "" && container && nested > 0 && closing:
+			nested--
+		case itemTag != "" && container && !closing:
+			nested++
+		case itemTag != "" && nested == 0 && name == itemTag && closing:
+			if werr := out(tag); werr != nil {
+				return werr
+			}
+			if werr := flush(); werr != nil {
+				return werr
+			}
+			tag = ""
+		case itemTag != "" && nested == 0 && (name == itemTag || closing && container):
+			if werr := flush(); werr != nil {
+				return werr
+			}
+		}
+
+		if raw == "" && itemTag == "" && tag != "" {
+			switch {
+			case container && !closing:
+				stack = append(stack, &htmlListing{tag: name})
+			case container && closing && len(stack) > 0 && name == stack[len(stack)-1].tag:
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if top.omitted > 0 {
+					if werr := out(top.note()); werr != nil {
+						return werr
+					}
+				}
+			case !closing && len(stack) > 0 && (name == "tr" && stack[len(stack)-1].tag == "table" || name == "li" && stack[len(stack)-1].tag != "table"):
+				item.Reset()
+				itemTag, nested = name, 0
+			}
+		}
+
+		if werr := out(tag); werr != nil {
+			return werr
+		}
+		if werr := out(text); werr != nil {
+			return werr
+		}
+		if err == io.EOF {
+			if itemTag != "" {
+				_, werr := dst.WriteString(item.String())
+				return werr
+			}
+			return nil
+		}
+	}
+}
+
+// keepHTMLItem decides whether a buffered row or list item is written, header rows are always kept and set the
+// number of columns of the note
+func (l ListingLimits) keepHTMLItem(current *htmlListing, content string) bool {
+	lower := strings.ToLower(content)
+	if current.tag == "table" && strings.Contains(lower, "<th") {
+		current.columns = strings.Count(lower, "<th")
+		return true
+	}
+	if current.tag == "table" && current.columns == 0 {
+		current.columns = strings.Count(lower, "<td")
+	}
+	return l.keep(&current.listing, html.UnescapeString(htmlTagsPattern.ReplaceAllString(content, " ")))
+}
@@ -0,0 +1,129 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestNewListingLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxFiles string
+		maxDepth string
+		want     ListingLimits
+		wantErr  string
+	}{
+		{name: "unlimited"},
+		{name: "limits", maxFiles: "20", maxDepth: "3", want: ListingLimits{MaxFiles: 20, MaxDepth: 3}},
+		{name: "zero files", maxFiles: "0", wantErr: `invalid report_max_files value "0": expected a positive number`},
+		{name: "invalid depth", maxDepth: "deep", wantErr: `invalid report_max_depth value "deep": expected a positive number`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewListingLimits(tt.maxFiles, tt.maxDepth)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("NewListingLimits() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NewListingLimits() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyListingLimits(t *testing.T) {
+	markdown := "## Largest files\n\n" +
+		"| File | Size |\n" +
+		"|------|------|\n" +
+		"| `classes.dex` | 4.0 MB |\n" +
+		"| `lib/arm64-v8a/libapp.so` | 3.0 MB |\n" +
+		"| `res/raw/intro.mp4` | 2.0 MB |\n" +
+		"| `assets/www/js/vendor/x.js` | 1.0 MB |\n" +
+		"\n" +
+		"Recommendations:\n" +
+		"- Enable R8\n" +
+		"- Compress the images\n" +
+		"- Drop unused ABIs\n" +
+		"\n" +
+		"1. res/drawable/a.png\n" +
+		"2. res/drawable/b.png\n" +
+		"3. res/drawable/c.png\n"
+	html := "<table><tr><th>File</th><th>Size</th></tr>" +
+		"<tr><td>classes.dex</td><td>4.0 MB</td></tr>" +
+		"<tr><td>lib/arm64-v8a/libapp.so</td><td>3.0 MB</td></tr>" +
+		"<tr><td>res/raw/intro.mp4</td><td>2.0 MB</td></tr></table>" +
+		"<script>const rows = \"<tr><td>a.png</td></tr>\";</script>"
+
+	tests := []struct {
+		name         string
+		limits       ListingLimits
+		wantMarkdown string
+		wantHTML     string
+	}{
+		{name: "max files", limits: ListingLimits{MaxFiles: 2}, wantMarkdown: "## Largest files\n\n" +
+			"| File | Size |\n" +
+			"|------|------|\n" +
+			"| `classes.dex` | 4.0 MB |\n" +
+			"| `lib/arm64-v8a/libapp.so` | 3.0 MB |\n" +
+			"| … 2 more file(s), see the JSON report |  |\n" +
+			"\n" +
+			"Recommendations:\n" +
+			"- Enable R8\n" +
+			"- Compress the images\n" +
+			"- Drop unused ABIs\n" +
+			"\n" +
+			"1. res/drawable/a.png\n" +
+			"2. res/drawable/b.png\n" +
+			"- … 1 more file(s), see the JSON report\n",
+			wantHTML: "<table><tr><th>File</th><th>Size</th></tr>" +
+				"<tr><td>classes.dex</td><td>4.0 MB</td></tr>" +
+				"<tr><td>lib/arm64-v8a/libapp.so</td><td>3.0 MB</td></tr>" +
+				"<tr class=\"bundle-analyzer-omitted\"><td colspan=\"2\">… 1 more file(s), see the JSON report</td></tr></table>" +
+				"<script>const rows = \"<tr><td>a.png</td></tr>\";</script>"},
+		{name: "max depth", limits: ListingLimits{MaxDepth: 2}, wantMarkdown: "## Largest files\n\n" +
+			"| File | Size |\n" +
+			"|------|------|\n" +
+			"| `classes.dex` | 4.0 MB |\n" +
+			"| `lib/arm64-v8a/libapp.so` | 3.0 MB |\n" +
+			"| `res/raw/intro.mp4` | 2.0 MB |\n" +
+			"| … 1 more file(s), see the JSON report |  |\n" +
+			"\n" +
+			"Recommendations:\n" +
+			"- Enable R8\n" +
+			"- Compress the images\n" +
+			"- Drop unused ABIs\n" +
+			"\n" +
+			"1. res/drawable/a.png\n" +
+			"2. res/drawable/b.png\n" +
+			"3. res/drawable/c.png\n",
+			wantHTML: html},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths := analyze.ReportPaths{Markdown: filepath.Join(dir, "report.md"), HTML: filepath.Join(dir, "report.html")}
+			if err := os.WriteFile(paths.Markdown, []byte(markdown), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(paths.HTML, []byte(html), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := ApplyListingLimits(paths, tt.limits); err != nil {
+				t.Fatalf("ApplyListingLimits() error = %s", err)
+			}
+			if got, err := os.ReadFile(paths.Markdown); err != nil || string(got) != tt.wantMarkdown {
+				t.Errorf("markdown report = %q, want %q", got, tt.wantMarkdown)
+			}
+			if got, err := os.ReadFile(paths.HTML); err != nil || string(got) != tt.wantHTML {
+				t.Errorf("HTML report = %q, want %q", got, tt.wantHTML)
+			}
+		})
+	}
+}
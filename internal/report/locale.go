@@ -0,0 +1,107 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// dateLayouts are the date layouts of the locales by language, or language and region where they differ
+var dateLayouts = map[string]string{
+	"en":    "01/02/2006",
+	"en-GB": "02/01/2006",
+	"en-AU": "02/01/2006",
+	"en-IE": "02/01/2006",
+	"en-IN": "02/01/2006",
+	"en-NZ": "02/01/2006",
+	"fr":    "02/01/2006",
+	"es":    "02/01/2006",
+	"it":    "02/01/2006",
+	"pt":    "02/01/2006",
+	"de":    "02.01.2006",
+	"cs":    "02.01.2006",
+	"da":    "02.01.2006",
+	"fi":    "02.01.2006",
+	"nb":    "02.01.2006",
+	"pl":    "02.01.2006",
+	"ru":    "02.01.2006",
+	"tr":    "02.01.2006",
+	"uk":    "02.01.2006",
+	"nl":    "02-01-2006",
+	"sv":    "2006-01-02",
+	"hu":    "2006. 01. 02.",
+	"ko":    "2006. 01. 02.",
+	"ja":    "2006/01/02",
+	"zh":    "2006/01/02",
+}
+
+// Locale formats the numbers, sizes and dates the step writes to the reports and the PR comment. The zero value
+// keeps the default format: no thousands grouping, a decimal point, 1024-based MB and ISO 8601 dates.
+type Locale struct {
+	printer    *message.Printer
+	dateLayout string
+	location   *time.Location
+	units      config.Units
+}
+
+// NewLocale returns the Locale of a BCP 47 locale (de-DE, de_DE and de_DE.UTF-8 are accepted), an IANA timezone
+// like Europe/Berlin and the size_units units, empty values keep the default format
+func NewLocale(locale, timezone string, units config.Units) (Locale, error) {
+	l := Locale{units: units}
+	if locale != "" {
+		name, _, _ := strings.Cut(locale, ".")
+		tag, err := language.Parse(strings.ReplaceAll(name, "_", "-"))
+		if err != nil {
+			return Locale{units: units}, fmt.Errorf("invalid report_locale %s: %w", locale, err)
+		}
+		l.printer = message.NewPrinter(tag)
+
+		base, _ := tag.Base()
+		region, _ := tag.Region()
+		l.dateLayout = dateLayouts[base.String()+"-"+region.String()]
+		if l.dateLayout == "" {
+			l.dateLayout = dateLayouts[base.String()]
+		}
+	}
+	if timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return Locale{units: units}, fmt.Errorf("invalid report_timezone %s: %w", timezone, err)
+		}
+		l.location = location
+	}
+	return l, nil
+}
+
+// Sprintf formats the numbers of the arguments with the decimal and grouping separators of the locale
+func (l Locale) Sprintf(format string, args ...interface{}) string {
+	if l.printer == nil {
+		return fmt.Sprintf(format, args...)
+	}
+	return l.printer.Sprintf(format, args...)
+}
+
+// MB formats a size in bytes as MB, or MiB with size_units binary, with two decimals
+func (l Locale) MB(bytes int64) string {
+	return l.Sprintf("%.2f %s", l.units.Megabytes(bytes), l.units.Label())
+}
+
+// DeltaMB formats a size change in bytes like MB with a sign
+func (l Locale) DeltaMB(bytes int64) string {
+	return l.Sprintf("%+.2f %s", l.units.Megabytes(bytes), l.units.Label())
+}
+
+// Date formats the date of t in the timezone of the locale, ISO 8601 for locales without a known date layout
+func (l Locale) Date(t time.Time) string {
+	if l.location != nil {
+		t = t.In(l.location)
+	}
+	if l.dateLayout == "" {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(l.dateLayout)
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+func TestNewLocale(t *testing.T) {
+	date := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		locale    string
+		timezone  string
+		wantMB    string
+		wantDelta string
+		wantCount string
+		wantDate  string
+	}{
+		{name: "default", wantMB: "1536.50 MB", wantDelta: "-0.25 MB", wantCount: "12345", wantDate: "2026-03-01"},
+		{name: "en-US", locale: "en-US", wantMB: "1,536.50 MB", wantDelta: "-0.25 MB", wantCount: "12,345", wantDate: "03/01/2026"},
+		{name: "en-GB", locale: "en_GB", wantMB: "1,536.50 MB", wantDelta: "-0.25 MB", wantCount: "12,345", wantDate: "01/03/2026"},
+		{name: "de-DE with encoding", locale: "de_DE.UTF-8", wantMB: "1.536,50 MB", wantDelta: "-0,25 MB", wantCount: "12.345", wantDate: "01.03.2026"},
+		{name: "locale without date layout", locale: "el-GR", wantMB: "1.536,50 MB", wantDelta: "-0,25 MB", wantCount: "12.345", wantDate: "2026-03-01"},
+		{name: "timezone", timezone: "Europe/Berlin", wantMB: "1536.50 MB", wantDelta: "-0.25 MB", wantCount: "12345", wantDate: "2026-03-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locale, err := NewLocale(tt.locale, tt.timezone, config.NewUnits(""))
+			if err != nil {
+				t.Fatalf("NewLocale() error = %s", err)
+			}
+			if got := locale.MB(1536*1024*1024 + 512*1024); got != tt.wantMB {
+				t.Errorf("MB() = %q, want %q", got, tt.wantMB)
+			}
+			if got := locale.DeltaMB(-256 * 1024); got != tt.wantDelta {
+				t.Errorf("DeltaMB() = %q, want %q", got, tt.wantDelta)
+			}
+			if got := locale.Sprintf("%d", 12345); got != tt.wantCount {
+				t.Errorf("Sprintf() = %q, want %q", got, tt.wantCount)
+			}
+			if got := locale.Date(date); got != tt.wantDate {
+				t.Errorf("Date() = %q, want %q", got, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestNewLocaleInvalid(t *testing.T) {
+	if _, err := NewLocale("not a locale!", "", config.NewUnits("")); err == nil || !strings.Contains(err.Error(), "invalid report_locale not a locale!") {
+		t.Errorf("NewLocale() error = %v", err)
+	}
+	locale, err := NewLocale("", "Mars/Olympus", config.NewUnits(""))
+	if err == nil || !strings.Contains(err.Error(), "invalid report_timezone Mars/Olympus") {
+		t.Errorf("NewLocale() error = %v", err)
+	}
+	// The invalid input falls back to the default format
+	if got := locale.MB(1024 * 1024); got != "1.00 MB" {
+		t.Errorf("MB() = %q, want the default format", got)
+	}
+}
@@ -0,0 +1,768 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/appstoreconnect"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/firebase"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/google"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/thresholds"
+)
+
+// AppendMarkdownSections appends step generated sections to the end of the markdown report
+func AppendMarkdownSections(markdownPath string, sections []string) error {
+	if len(sections) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(markdownPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open markdown report: %w", err)
+	}
+	defer file.Close()
+
+	content := "\n\n" + strings.Join(sections, "\n\n") + "\n"
+	if _, err := file.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write markdown report: %w", err)
+	}
+	return nil
+}
+
+// ExtendMarkdownReport writes a copy of the markdown report with the sections appended to outputPath
+func ExtendMarkdownReport(markdownPath, outputPath string, sections []string) error {
+	if err := copyFile(markdownPath, outputPath); err != nil {
+		return fmt.Errorf("failed to copy markdown report: %w", err)
+	}
+	return AppendMarkdownSections(outputPath, sections)
+}
+
+// CombineMarkdownReports merges the markdown reports of multiple artifacts into a single file, one section per artifact
+// headed by its report_title or name
+func CombineMarkdownReports(results []analyze.ArtifactResult, outputPath string) error {
+	var sections []string
+	for _, result := range results {
+		if result.ReportPaths.Markdown == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(result.ReportPaths.Markdown)
+		if err != nil {
+			return fmt.Errorf("failed to read markdown report of %s: %w", result.Name, err)
+		}
+		heading := result.Name
+		if result.Title != "" {
+			heading = result.Title
+		}
+		sections = append(sections, fmt.Sprintf("# %s\n\n%s", heading, strings.TrimSpace(string(data))))
+	}
+
+	if len(sections) == 0 {
+		return fmt.Errorf("no markdown reports to combine")
+	}
+
+	if err := os.WriteFile(outputPath, []byte(strings.Join(sections, "\n\n---\n\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write combined markdown report: %w", err)
+	}
+	return nil
+}
+
+// ModuleBudgetsMarkdown renders the module budget compliance table
+func ModuleBudgetsMarkdown(results []analyze.ModuleBudgetResult, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 📐 Module Budgets\n\n")
+	b.WriteString("| Module | Path | Size | Budget | Status |\n")
+	b.WriteString("|--------|------|------|--------|--------|\n")
+	for _, result := range results {
+		status := "✅ Within budget"
+		if result.Exceeded() {
+			status = "❌ Over budget"
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s |\n",
+			result.Name, result.Prefix, locale.MB(result.SizeBytes), locale.MB(result.BudgetBytes), status)
+	}
+	return b.String()
+}
+
+// LanguageOverheadMarkdown renders the size of the bundled language runtimes and the ones a higher minimum OS or SDK
+// would drop
+func LanguageOverheadMarkdown(overhead analyze.LanguageOverhead, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🧬 Language Runtime Overhead\n\n")
+	if len(overhead.Runtimes) == 0 {
+		b.WriteString("✅ No bundled language runtime found.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "**%s** of bundled language runtimes", locale.MB(overhead.TotalBytes()))
+	if overhead.MinimumOS != "" {
+		fmt.Fprintf(&b, ", %s is `%s`", overhead.MinimumOSName(), overhead.MinimumOS)
+	}
+	b.WriteString(".\n\n")
+	b.WriteString("| Runtime | Version | Contents | Size | Guidance |\n")
+	b.WriteString("|---------|---------|----------|------|----------|\n")
+	for _, runtime := range overhead.Runtimes {
+		version := runtime.Version
+		if version == "" {
+			version = "-"
+		}
+		contents := locale.Sprintf("%d file(s)", runtime.Files)
+		if runtime.Classes > 0 {
+			contents = locale.Sprintf("%d class(es)", runtime.Classes)
+		}
+		guidance := runtime.Guidance
+		if runtime.Droppable {
+			guidance = fmt.Sprintf("💡 Raising %s to `%s` drops it: %s", overhead.MinimumOSName(), runtime.DroppedAt, runtime.Guidance)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", runtime.Name, version, contents, locale.MB(runtime.SizeBytes), guidance)
+	}
+	if overhead.Platform == "android" {
+		b.WriteString("\nThe size of Android runtimes is estimated by their share of the classes in each DEX file.\n")
+	}
+	return b.String()
+}
+
+// WhatIfMarkdown renders the projected artifact size of each simulated optimization, the savings are measured
+// separately and don't add up
+func WhatIfMarkdown(scenarios []analyze.WhatIfScenario, sizeBytes int64, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🧪 What-If Savings\n\n")
+	fmt.Fprintf(&b, "The projected size of the %s artifact with each optimization applied on its own.\n\n", locale.MB(sizeBytes))
+	b.WriteString("| Scenario | Files | Savings | Projected size |\n")
+	b.WriteString("|----------|-------|---------|----------------|\n")
+	for _, scenario := range scenarios {
+		if scenario.Skipped != "" {
+			fmt.Fprintf(&b, "| %s | - | - | ⏭️ Skipped: %s |\n", scenario.Description, scenario.Skipped)
+			continue
+		}
+		share := 0.0
+		if sizeBytes > 0 {
+			share = float64(scenario.SavingsBytes) * 100 / float64(sizeBytes)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s (%s) | %s |\n", scenario.Description, locale.Sprintf("%d", scenario.Files),
+			locale.MB(scenario.SavingsBytes), locale.Sprintf("%.1f%%", share), locale.MB(scenario.ProjectedBytes))
+	}
+	return b.String()
+}
+
+// ExcludedEntriesMarkdown renders the size left out of the totals and thresholds by include_patterns, exclude_patterns
+// and the code_only or assets_only analysis_scope
+func ExcludedEntriesMarkdown(scope analyze.EntryScope, excluded analyze.ExcludedEntries, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🚫 Excluded Files\n\n")
+	if scope.Content != "" {
+		fmt.Fprintf(&b, "The size totals and thresholds cover only the %s (`analysis_scope: %s`).\n\n", analyze.ScopeContentName(scope.Content), scope.Content)
+	}
+	if len(scope.Include) > 0 {
+		fmt.Fprintf(&b, "Only the files matching `%s` are counted in the size totals and thresholds.\n\n", strings.Join(scope.Include, "`, `"))
+	}
+	fmt.Fprintf(&b, "**%s** in %s file(s) are left out of the size totals and thresholds", locale.MB(excluded.Bytes), locale.Sprintf("%d", excluded.Files))
+	if len(scope.Exclude) > 0 {
+		fmt.Fprintf(&b, ", excluded by `%s`", strings.Join(scope.Exclude, "`, `"))
+	}
+	b.WriteString(".\n")
+	return b.String()
+}
+
+// SizeOverrideMarkdown renders the notice about an active size override
+func SizeOverrideMarkdown(override *thresholds.SizeOverride, locale Locale) string {
+	return fmt.Sprintf("### ⚠️ Size Allowance Override\n\n"+
+		"The delta allowance of this build was raised by **%s** via the `%s` directive.\n", locale.DeltaMB(override.AllowanceBytes), override.Directive)
+}
+
+// DistributedReleaseMarkdown renders the size change against the latest release distributed with Firebase App
+// Distribution
+func DistributedReleaseMarkdown(release firebase.Release, sizeBytes int64, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🔥 vs Last Distributed Build\n\n")
+	fmt.Fprintf(&b, "Compared with release **%s**, distributed with Firebase App Distribution on %s", release.Version(), locale.Date(release.CreateTime))
+	if release.ConsoleURI != "" {
+		fmt.Fprintf(&b, " ([console](%s))", release.ConsoleURI)
+	}
+	b.WriteString(".\n\n")
+
+	delta := sizeBytes - release.SizeBytes
+	change := locale.DeltaMB(delta)
+	if release.SizeBytes > 0 {
+		change += locale.Sprintf(" (%+.1f%%)", float64(delta)*100/float64(release.SizeBytes))
+	}
+	b.WriteString("| Build | Size |\n")
+	b.WriteString("|-------|------|\n")
+	fmt.Fprintf(&b, "| This build | %s |\n", locale.MB(sizeBytes))
+	fmt.Fprintf(&b, "| %s | %s |\n", release.Version(), locale.MB(release.SizeBytes))
+	fmt.Fprintf(&b, "| **Change** | **%s** |\n", change)
+	return b.String()
+}
+
+// PlayProductionMarkdown renders the estimated download of each device bucket against the sizes Google Play serves
+// for the production release
+func PlayProductionMarkdown(release google.PlayRelease, inventory analyze.Inventory, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### ▶️ vs Play Production\n\n")
+	rollout := ""
+	if release.Status == "inProgress" {
+		rollout = ", staged rollout"
+	}
+	fmt.Fprintf(&b, "Compared with the production release **%s** (version code %s%s) of `%s` on Google Play.\n\n", release.Name, release.VersionCode, rollout, release.PackageName)
+	b.WriteString("| Device | This build (estimate) | Play production | Change |\n")
+	b.WriteString("|--------|-----------------------|-----------------|--------|\n")
+	for _, bucket := range release.Buckets {
+		estimate := inventory.DeviceDownloadSize(bucket.ABI, bucket.Density)
+		delta := estimate - bucket.SizeBytes
+		change := locale.DeltaMB(delta)
+		if bucket.SizeBytes > 0 {
+			change += locale.Sprintf(" (%+.1f%%)", float64(delta)*100/float64(bucket.SizeBytes))
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", bucket.Name(), locale.MB(estimate), locale.MB(bucket.SizeBytes), change)
+	}
+	b.WriteString("\nThe estimate of this build is the compressed size of its files for the device, Google Play serves the APKs it generates.\n")
+	return b.String()
+}
+
+// maxAppStoreDeviceRows limits the device models listed in the App Store Connect section
+const maxAppStoreDeviceRows = 10
+
+// AppStoreConnectMarkdown renders the locally estimated sizes of the IPA against the universal sizes App Store Connect
+// reports for the latest build, discrepancies over discrepancyPercent are flagged, and the thinned sizes per device
+func AppStoreConnectMarkdown(sizes appstoreconnect.BuildSizes, downloadBytes, installBytes int64, discrepancyPercent float64, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🍎 vs App Store Connect\n\n")
+	fmt.Fprintf(&b, "Compared with build **%s** of `%s`, uploaded to App Store Connect on %s.\n\n", sizes.BuildNumber, sizes.BundleID, locale.Date(sizes.UploadedDate))
+
+	if universal, ok := sizes.Universal(); ok {
+		compare := func(estimate, reported int64) (string, bool) {
+			if reported == 0 || estimate == 0 {
+				return "-", false
+			}
+			discrepancy := float64(estimate-reported) * 100 / float64(reported)
+			change := locale.DeltaMB(estimate-reported) + locale.Sprintf(" (%+.1f%%)", discrepancy)
+			if discrepancy > discrepancyPercent || discrepancy < -discrepancyPercent {
+				return "⚠️ " + change, true
+			}
+			return change, false
+		}
+		downloadChange, downloadFlagged := compare(downloadBytes, universal.DownloadBytes)
+		installChange, installFlagged := compare(installBytes, universal.InstallBytes)
+
+		b.WriteString("| Size | This build (estimate) | Apple reported | Difference |\n")
+		b.WriteString("|------|-----------------------|----------------|------------|\n")
+		fmt.Fprintf(&b, "| Download | %s | %s | %s |\n", locale.MB(downloadBytes), locale.MB(universal.DownloadBytes), downloadChange)
+		if installBytes > 0 {
+			fmt.Fprintf(&b, "| Install | %s | %s | %s |\n", locale.MB(installBytes), locale.MB(universal.InstallBytes), installChange)
+		}
+		if downloadFlagged || installFlagged {
+			fmt.Fprintf(&b, "\n⚠️ The local estimate differs from the universal size Apple reported by more than %s, "+
+				"Apple's encryption and compression or a change since the build can explain it.\n", locale.Sprintf("%.1f%%", discrepancyPercent))
+		}
+		b.WriteString("\n")
+	}
+
+	var thinned []appstoreconnect.DeviceSize
+	for _, device := range sizes.Devices {
+		if device.DeviceModel != appstoreconnect.UniversalDevice {
+			thinned = append(thinned, device)
+		}
+	}
+	if len(thinned) == 0 {
+		return b.String()
+	}
+	b.WriteString("| Device | OS | Download | Install |\n")
+	b.WriteString("|--------|----|----------|---------|\n")
+	for idx, device := range thinned {
+		if idx == maxAppStoreDeviceRows {
+			fmt.Fprintf(&b, "\n... and %d more device model(s).\n", len(thinned)-idx)
+			break
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", device.DeviceModel, device.OSVersion, locale.MB(device.DownloadBytes), locale.MB(device.InstallBytes))
+	}
+	return b.String()
+}
+
+// maxSecretFindingRows limits the secret findings listed in the markdown report
+const maxSecretFindingRows = 50
+
+// SecretsMarkdown renders the masked secret findings of the artifact
+func SecretsMarkdown(findings []analyze.SecretFinding) string {
+	var b strings.Builder
+	b.WriteString("### 🔑 Secrets\n\n")
+	if len(findings) == 0 {
+		b.WriteString("✅ No secrets found in the bundled assets, plists and string resources.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "❌ Found **%d** potential secret(s), values are masked.\n\n", len(findings))
+	b.WriteString("| Detector | File | Value |\n")
+	b.WriteString("|----------|------|-------|\n")
+	for idx, finding := range findings {
+		if idx == maxSecretFindingRows {
+			fmt.Fprintf(&b, "\n... and %d more finding(s), see the step log.\n", len(findings)-idx)
+			break
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | `%s` |\n", finding.Detector, finding.Path, finding.Masked)
+	}
+	return b.String()
+}
+
+// VulnerableSDKsMarkdown renders the bundled SDK versions with known vulnerabilities
+func VulnerableSDKsMarkdown(vulnerable []analyze.VulnerableSDK) string {
+	var b strings.Builder
+	b.WriteString("### 🛡️ Vulnerable SDKs\n\n")
+	if len(vulnerable) == 0 {
+		b.WriteString("✅ No bundled SDK version with known vulnerabilities.\n")
+		return b.String()
+	}
+
+	b.WriteString("| SDK | Version | Advisory | Severity | Fixed in | Summary |\n")
+	b.WriteString("|-----|---------|----------|----------|----------|---------|\n")
+	for _, match := range vulnerable {
+		fixed := match.FixedVersion
+		if fixed == "" {
+			fixed = "-"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s | %s |\n",
+			match.SDK.Name, match.SDK.Version, match.Advisory.ID, match.Advisory.Severity, fixed, match.Advisory.Summary)
+	}
+	return b.String()
+}
+
+// LicensesMarkdown renders the licenses of the components shipped in the artifact, denied licenses are listed by component
+func LicensesMarkdown(licenses []analyze.LicenseEntry, denied []string) string {
+	var b strings.Builder
+	b.WriteString("### 📜 Licenses\n\n")
+	if len(licenses) == 0 {
+		b.WriteString("No license files or license metadata found in the artifact.\n")
+		return b.String()
+	}
+
+	counts := map[string]int64{}
+	var deniedEntries []analyze.LicenseEntry
+	for _, entry := range licenses {
+		counts[entry.License]++
+		if entry.Denied(denied) {
+			deniedEntries = append(deniedEntries, entry)
+		}
+	}
+
+	b.WriteString("| License | Components |\n")
+	b.WriteString("|---------|------------|\n")
+	for _, id := range analyze.SortedKeys(counts) {
+		fmt.Fprintf(&b, "| %s | %d |\n", id, counts[id])
+	}
+
+	if len(deniedEntries) > 0 {
+		fmt.Fprintf(&b, "\n❌ **%d** component(s) under a denied license:\n\n", len(deniedEntries))
+		for _, entry := range deniedEntries {
+			fmt.Fprintf(&b, "- `%s`: %s (`%s`)\n", entry.Component, entry.License, entry.Source)
+		}
+	}
+	return b.String()
+}
+
+// SignatureMarkdown renders the verified signature of the artifact and its signer
+func SignatureMarkdown(signature analyze.SignatureInfo, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🔏 Signature\n\n")
+	switch signature.Status {
+	case analyze.SignatureSigned:
+		b.WriteString("✅ **Signed**\n\n")
+	case analyze.SignatureDebug:
+		b.WriteString("⚠️ **Debug-signed**\n\n")
+	case analyze.SignatureUnsigned:
+		b.WriteString("❌ **Unsigned**\n\n")
+	default:
+		b.WriteString("❌ **Invalid signature**\n\n")
+	}
+
+	if len(signature.Schemes) > 0 || signature.Signer != "" {
+		b.WriteString("| | |\n")
+		b.WriteString("|---|---|\n")
+		if len(signature.Schemes) > 0 {
+			fmt.Fprintf(&b, "| Schemes | %s |\n", strings.Join(signature.Schemes, ", "))
+		}
+		if signature.Signer != "" {
+			fmt.Fprintf(&b, "| Signer | %s |\n", signature.Signer)
+			fmt.Fprintf(&b, "| SHA-256 | `%s` |\n", signature.Fingerprint)
+			fmt.Fprintf(&b, "| Expires | %s |\n", locale.Date(signature.NotAfter))
+		}
+		if signature.TeamID != "" {
+			fmt.Fprintf(&b, "| Team | %s |\n", signature.TeamID)
+		}
+	}
+
+	if len(signature.Problems) > 0 {
+		b.WriteString("\n")
+		for _, problem := range signature.Problems {
+			fmt.Fprintf(&b, "- %s\n", problem)
+		}
+	}
+	return b.String()
+}
+
+// PrivacyMarkdown renders the privacy manifests of the IPA and their issues
+func PrivacyMarkdown(privacy analyze.PrivacyReport) string {
+	var b strings.Builder
+	b.WriteString("### 🔐 Privacy Manifests\n\n")
+	fmt.Fprintf(&b, "Found **%d** privacy manifest(s).\n\n", len(privacy.Manifests))
+	if len(privacy.Findings) == 0 {
+		b.WriteString("✅ Every required reason API is declared and every listed SDK ships a privacy manifest.\n")
+		return b.String()
+	}
+
+	b.WriteString("| Bundle | Issue |\n")
+	b.WriteString("|--------|-------|\n")
+	for _, finding := range privacy.Findings {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", finding.Bundle, finding.Issue)
+	}
+	return b.String()
+}
+
+// ProvisioningProfileMarkdown renders the provisioning profile embedded in the IPA
+func ProvisioningProfileMarkdown(profile analyze.ProvisioningProfile, locale Locale) string {
+	var b strings.Builder
+	b.WriteString("### 🪪 Provisioning Profile\n\n")
+	b.WriteString("| | |\n")
+	b.WriteString("|---|---|\n")
+	fmt.Fprintf(&b, "| Name | %s |\n", profile.Name)
+	fmt.Fprintf(&b, "| Type | %s |\n", profile.Type)
+	fmt.Fprintf(&b, "| Team | %s (%s) |\n", profile.TeamName, profile.TeamID)
+	fmt.Fprintf(&b, "| App ID | `%s` |\n", profile.AppID)
+	fmt.Fprintf(&b, "| Expires | %s |\n", locale.Date(profile.ExpirationDate))
+	if profile.Type == analyze.DistributionDevelopment || profile.Type == analyze.DistributionAdHoc {
+		fmt.Fprintf(&b, "| Devices | %d |\n", profile.Devices)
+	}
+	if len(profile.Entitlements) > 0 {
+		fmt.Fprintf(&b, "| Entitlements | `%s` |\n", strings.Join(profile.Entitlements, "`, `"))
+	}
+	return b.String()
+}
+
+// PermissionsMarkdown renders the permissions of the artifact and the changes since the baseline build
+func PermissionsMarkdown(permissions []string, comparison *analyze.BaselineComparison) string {
+	var b strings.Builder
+	b.WriteString("### 🔑 Permissions\n\n")
+	fmt.Fprintf(&b, "The artifact requests **%d** permission(s).\n\n", len(permissions))
+	if comparison == nil || comparison.Permissions == nil {
+		b.WriteString("No permissions recorded for a baseline build to compare with.\n\n")
+		for _, permission := range permissions {
+			fmt.Fprintf(&b, "- `%s`\n", permission)
+		}
+		return b.String()
+	}
+
+	diff := comparison.Permissions
+	if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+		fmt.Fprintf(&b, "✅ No permission changes since build #%s.\n", comparison.Baseline.BuildNumber)
+		return b.String()
+	}
+
+	sensitive := map[string]bool{}
+	for _, permission := range diff.SensitiveAdded {
+		sensitive[permission] = true
+	}
+
+	fmt.Fprintf(&b, "Changes since build #%s:\n\n", comparison.Baseline.BuildNumber)
+	b.WriteString("| Change | Permission |\n")
+	b.WriteString("|--------|------------|\n")
+	for _, permission := range diff.Added {
+		if sensitive[permission] {
+			fmt.Fprintf(&b, "| ⚠️ Added (sensitive) | `%s` |\n", permission)
+		} else {
+			fmt.Fprintf(&b, "| Added | `%s` |\n", permission)
+		}
+	}
+	for _, permission := range diff.Removed {
+		fmt.Fprintf(&b, "| Removed | `%s` |\n", permission)
+	}
+	return b.String()
+}
+
+// LibraryChangesMarkdown renders the libraries added, upgraded and removed since the baseline build
+func LibraryChangesMarkdown(libraries []analyze.DetectedSDK, comparison *analyze.BaselineComparison) string {
+	var b strings.Builder
+	b.WriteString("### 📦 Library Changes\n\n")
+	fmt.Fprintf(&b, "The artifact ships **%d** versioned libraries.\n\n", len(libraries))
+	if comparison == nil || comparison.Libraries == nil {
+		b.WriteString("No library inventory recorded for a baseline build to compare with.\n")
+		return b.String()
+	}
+
+	diff := comparison.Libraries
+	if len(diff.Added) == 0 && len(diff.Changed) == 0 && len(diff.Removed) == 0 {
+		fmt.Fprintf(&b, "✅ No library changes since build #%s.\n", comparison.Baseline.BuildNumber)
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "Changes since build #%s:\n\n", comparison.Baseline.BuildNumber)
+	b.WriteString("| Change | Library | Version |\n")
+	b.WriteString("|--------|---------|---------|\n")
+	for _, entry := range diff.Added {
+		name, version := analyze.SplitLibraryEntry(entry)
+		fmt.Fprintf(&b, "| 🆕 Added | `%s` | %s |\n", name, version)
+	}
+	for _, change := range diff.Changed {
+		kind := "⬆️ Upgraded"
+		if change.Downgrade() {
+			kind = "⬇️ Downgraded"
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s → %s |\n", kind, change.Name, change.From, change.To)
+	}
+	for _, entry := range diff.Removed {
+		name, version := analyze.SplitLibraryEntry(entry)
+		fmt.Fprintf(&b, "| Removed | `%s` | %s |\n", name, version)
+	}
+	return b.String()
+}
+
+// AttackSurfaceMarkdown renders the exported components and deep links of the artifact,
+// entries added since the baseline build are marked new
+func AttackSurfaceMarkdown(surface analyze.AttackSurface, comparison *analyze.BaselineComparison) string {
+	var b strings.Builder
+	b.WriteString("### 🎯 Attack Surface\n\n")
+
+	added := map[string]bool{}
+	var diff *analyze.AttackSurfaceDiff
+	if comparison != nil && comparison.AttackSurface != nil {
+		diff = comparison.AttackSurface
+		for _, entry := range diff.Added {
+			added[entry] = true
+		}
+		if len(diff.Added) == 0 && len(diff.Removed) == 0 {
+			fmt.Fprintf(&b, "✅ No changes since build #%s.\n\n", comparison.Baseline.BuildNumber)
+		} else {
+			fmt.Fprintf(&b, "**%d** entry point(s) added and **%d** removed since build #%s.\n\n", len(diff.Added), len(diff.Removed), comparison.Baseline.BuildNumber)
+		}
+	}
+	newMark := func(entry string) string {
+		if added[entry] {
+			return "🆕 "
+		}
+		return ""
+	}
+
+	if len(surface.Components) == 0 && len(surface.DeepLinks) == 0 {
+		b.WriteString("No exported components or deep links found.\n")
+	}
+
+	if len(surface.Components) > 0 {
+		b.WriteString("| Component | Type | Protection | Actions |\n")
+		b.WriteString("|-----------|------|------------|---------|\n")
+		for _, component := range surface.Components {
+			protection := "⚠️ None"
+			if component.Permission != "" {
+				protection = fmt.Sprintf("`%s`", component.Permission)
+			}
+			componentType := component.Type
+			if component.Implicit {
+				componentType += " (implicitly exported)"
+			}
+			actions := "-"
+			if len(component.Actions) > 0 {
+				actions = "`" + strings.Join(component.Actions, "`, `") + "`"
+			}
+			fmt.Fprintf(&b, "| %s`%s` | %s | %s | %s |\n", newMark(component.Type+" "+component.Name), component.Name, componentType, protection, actions)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(surface.DeepLinks) > 0 {
+		b.WriteString("| Deep Link | Kind | Handler |\n")
+		b.WriteString("|-----------|------|---------|\n")
+		for _, link := range surface.DeepLinks {
+			handler := "-"
+			if link.Handler != "" {
+				handler = fmt.Sprintf("`%s`", link.Handler)
+			}
+			fmt.Fprintf(&b, "| %s`%s` | %s | %s |\n", newMark(link.Kind+" "+link.URI), link.URI, link.Kind, handler)
+		}
+		b.WriteString("\n")
+	}
+
+	if diff != nil && len(diff.Removed) > 0 {
+		b.WriteString("Removed:\n\n")
+		for _, entry := range diff.Removed {
+			fmt.Fprintf(&b, "- `%s`\n", entry)
+		}
+	}
+	return b.String()
+}
+
+// DebugBuildMarkdown renders the warning that the artifact looks like a debug build, so readers don't draw
+// conclusions from its size
+func DebugBuildMarkdown(reasons []string) string {
+	var b strings.Builder
+	b.WriteString("### ⚠️ Debug Build\n\n")
+	b.WriteString("This artifact looks like a debug build, its size doesn't reflect the release build:\n\n")
+	for _, reason := range reasons {
+		fmt.Fprintf(&b, "- %s\n", reason)
+	}
+	return b.String()
+}
+
+// DebugSymbolsMarkdown renders the symbol files missing for the artifact
+func DebugSymbolsMarkdown(symbols analyze.DebugSymbols) string {
+	var b strings.Builder
+	b.WriteString("### 🧾 Debug Symbols\n\n")
+	b.WriteString("⚠️ The crash reports of this build can't be symbolicated without its symbol files:\n\n")
+	b.WriteString("| Symbols | Status |\n")
+	b.WriteString("|---------|--------|\n")
+	for _, file := range symbols.Files {
+		if file.Present() {
+			fmt.Fprintf(&b, "| %s | ✅ %s |\n", file.Kind, file.Location)
+		} else {
+			fmt.Fprintf(&b, "| %s | ❌ Missing |\n", file.Kind)
+		}
+	}
+	return b.String()
+}
+
+// DebugArtifactsMarkdown renders the signs of a debug build found in the artifact
+func DebugArtifactsMarkdown(findings []analyze.DebugFinding) string {
+	var b strings.Builder
+	b.WriteString("### 🐞 Debug Artifacts\n\n")
+	if len(findings) == 0 {
+		b.WriteString("✅ No debug flags, debug signatures or test frameworks found.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "❌ Found **%d** sign(s) of a debug build.\n\n", len(findings))
+	b.WriteString("| Check | Finding |\n")
+	b.WriteString("|-------|---------|\n")
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "| %s | %s |\n", finding.Kind, finding.Detail)
+	}
+	return b.String()
+}
+
+// HardeningMarkdown renders the native binaries missing exploit mitigations
+func HardeningMarkdown(binaries []analyze.BinaryHardening) string {
+	var b strings.Builder
+	b.WriteString("### 🧱 Native Hardening\n\n")
+	var unhardened []analyze.BinaryHardening
+	for _, binary := range binaries {
+		if !binary.Hardened() {
+			unhardened = append(unhardened, binary)
+		}
+	}
+	if len(unhardened) == 0 {
+		fmt.Fprintf(&b, "✅ All %d native binaries are built with PIE, stack canaries, full RELRO and NX.\n", len(binaries))
+		return b.String()
+	}
+
+	mark := func(passed bool) string {
+		if passed {
+			return "✅"
+		}
+		return "❌"
+	}
+	fmt.Fprintf(&b, "⚠️ **%d** of %d native binaries aren't fully hardened.\n\n", len(unhardened), len(binaries))
+	b.WriteString("| Binary | PIE | Stack canary | Full RELRO | NX |\n")
+	b.WriteString("|--------|-----|--------------|------------|----|\n")
+	for _, binary := range unhardened {
+		relro := mark(binary.RELRO)
+		if binary.Format == analyze.BinaryFormatMachO {
+			relro = "n/a"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", binary.Path, mark(binary.PIE), mark(binary.StackCanary), relro, mark(binary.NX))
+	}
+	return b.String()
+}
+
+// ReputationMarkdown renders the native binaries flagged by the hash lists or the reputation API
+func ReputationMarkdown(binaries []analyze.BinaryReputation) string {
+	var b strings.Builder
+	b.WriteString("### 🔎 Binary Reputation\n\n")
+	var flagged []analyze.BinaryReputation
+	for _, binary := range binaries {
+		if binary.Flagged() {
+			flagged = append(flagged, binary)
+		}
+	}
+	if len(flagged) == 0 {
+		fmt.Fprintf(&b, "✅ None of the %d native binaries was flagged.\n", len(binaries))
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "❌ **%d** of %d native binaries flagged.\n\n", len(flagged), len(binaries))
+	b.WriteString("| Binary | SHA-256 | Verdict | Source |\n")
+	b.WriteString("|--------|---------|---------|--------|\n")
+	for _, binary := range flagged {
+		verdict := binary.Verdict
+		if binary.Detail != "" {
+			verdict += ": " + binary.Detail
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", binary.Path, binary.SHA256, verdict, binary.Source)
+	}
+	return b.String()
+}
+
+// NetworkSecurityMarkdown renders the cleartext traffic allowances and transport security exceptions of the artifact
+func NetworkSecurityMarkdown(findings []analyze.NetworkSecurityFinding) string {
+	var b strings.Builder
+	b.WriteString("### 🌐 Network Security\n\n")
+	if len(findings) == 0 {
+		b.WriteString("✅ No cleartext traffic allowances or transport security exceptions found.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "⚠️ **%d** transport security issue(s) found.\n\n", len(findings))
+	b.WriteString("| Source | Scope | Issue |\n")
+	b.WriteString("|--------|-------|-------|\n")
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", finding.Source, finding.Scope, finding.Issue)
+	}
+	return b.String()
+}
+
+// ReportLinksMarkdown links the deployed HTML and JSON reports from the PR comment. Bitrise doesn't know the artifact
+// URLs before the deploy step uploads them, so the links open the artifacts tab of the build, listing the files by name.
+// Reports uploaded to a storage bucket link their uploaded URL instead, uploaded maps the report paths to the URLs.
+// Reports outside of deployDir weren't deployed and aren't linked, the section is empty without a build URL or
+// deployed HTML and JSON reports and without uploaded ones.
+func ReportLinksMarkdown(results []analyze.ArtifactResult, deployDir, buildURL, buildNumber string, uploaded map[string]string) string {
+	artifactsURL := strings.TrimSuffix(buildURL, "/") + "?tab=artifacts"
+	deployed := func(reportPath string) bool {
+		return buildURL != "" && deployDir != "" && reportPath != "" && filepath.Dir(reportPath) == filepath.Clean(deployDir)
+	}
+
+	var lines []string
+	linksDeployed := false
+	for _, result := range results {
+		var links []string
+		for _, file := range []struct{ title, path string }{
+			{"Full HTML report", result.ReportPaths.HTML},
+			{"Raw JSON", result.ReportPaths.JSON},
+		} {
+			if file.path == "" {
+				continue
+			}
+			if url := uploaded[file.path]; url != "" {
+				links = append(links, fmt.Sprintf("[%s](%s)", file.title, url))
+			} else if deployed(file.path) {
+				links = append(links, fmt.Sprintf("[%s](%s) `%s`", file.title, artifactsURL, filepath.Base(file.path)))
+				linksDeployed = true
+			}
+		}
+		if len(links) == 0 {
+			continue
+		}
+		if len(results) > 1 {
+			lines = append(lines, fmt.Sprintf("- **%s**: %s", result.Name, strings.Join(links, " · ")))
+		} else {
+			lines = append(lines, "- "+strings.Join(links, "\n- "))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("### 🔗 Reports\n\n")
+	if linksDeployed {
+		fmt.Fprintf(&b, "Deployed as build artifacts of [build #%s](%s):\n\n", buildNumber, buildURL)
+	}
+	b.WriteString(strings.Join(lines, "\n") + "\n")
+	return b.String()
+}
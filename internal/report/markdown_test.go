@@ -0,0 +1,179 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/appstoreconnect"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/firebase"
+)
+
+func TestLibraryChangesMarkdown(t *testing.T) {
+	libraries := []analyze.DetectedSDK{{Name: "com.squareup.okhttp3:okhttp", Version: "4.12.0"}, {Name: "com.squareup.okio:okio", Version: "3.4.0"}}
+	baseline := analyze.HistoryRecord{BuildNumber: "41"}
+
+	tests := []struct {
+		name       string
+		comparison *analyze.BaselineComparison
+		want       []string
+	}{
+		{name: "no baseline", want: []string{"The artifact ships **2** versioned libraries.", "No library inventory recorded for a baseline build to compare with."}},
+		{name: "no changes", comparison: &analyze.BaselineComparison{Baseline: baseline, Libraries: &analyze.LibraryDiff{}},
+			want: []string{"✅ No library changes since build #41."}},
+		{name: "changes", comparison: &analyze.BaselineComparison{Baseline: baseline, Libraries: &analyze.LibraryDiff{
+			Added:   []string{"com.squareup.okhttp3:okhttp@4.12.0"},
+			Changed: []analyze.LibraryChange{{Name: "com.squareup.okio:okio", From: "3.5.0", To: "3.4.0"}, {Name: "com.google.code.gson:gson", From: "2.8.6", To: "2.10.1"}},
+			Removed: []string{"io.reactivex:rxjava@2.2.21"},
+		}}, want: []string{
+			"Changes since build #41:",
+			"| 🆕 Added | `com.squareup.okhttp3:okhttp` | 4.12.0 |",
+			"| ⬇️ Downgraded | `com.squareup.okio:okio` | 3.5.0 → 3.4.0 |",
+			"| ⬆️ Upgraded | `com.google.code.gson:gson` | 2.8.6 → 2.10.1 |",
+			"| Removed | `io.reactivex:rxjava` | 2.2.21 |",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := LibraryChangesMarkdown(libraries, tt.comparison)
+			for _, line := range tt.want {
+				if !strings.Contains(markdown, line+"\n") {
+					t.Errorf("LibraryChangesMarkdown() = %s\nwant line %q", markdown, line)
+				}
+			}
+		})
+	}
+}
+
+func TestReportLinksMarkdown(t *testing.T) {
+	deployDir := "/bitrise/deploy"
+	buildURL := "https://app.bitrise.io/build/abc123"
+	apk := analyze.ArtifactResult{Name: "app-release.apk", ReportPaths: analyze.ReportPaths{
+		HTML: deployDir + "/bundle-analysis-app-release.html",
+		JSON: deployDir + "/bundle-analysis-app-release.json",
+	}}
+	aab := analyze.ArtifactResult{Name: "app-release.aab", ReportPaths: analyze.ReportPaths{
+		HTML: "/tmp/work/bundle-analysis-app-release.html",
+		JSON: deployDir + "/bundle-analysis-app-release-aab.json",
+	}}
+
+	tests := []struct {
+		name      string
+		results   []analyze.ArtifactResult
+		deployDir string
+		buildURL  string
+		uploaded  map[string]string
+		want      string
+	}{
+		{name: "single artifact", results: []analyze.ArtifactResult{apk}, deployDir: deployDir, buildURL: buildURL, want: "### 🔗 Reports\n\n" +
+			"Deployed as build artifacts of [build #42](https://app.bitrise.io/build/abc123):\n\n" +
+			"- [Full HTML report](https://app.bitrise.io/build/abc123?tab=artifacts) `bundle-analysis-app-release.html`\n" +
+			"- [Raw JSON](https://app.bitrise.io/build/abc123?tab=artifacts) `bundle-analysis-app-release.json`\n"},
+		{name: "multiple artifacts skip reports outside the deploy dir", results: []analyze.ArtifactResult{apk, aab}, deployDir: deployDir + "/", buildURL: buildURL + "/", want: "### 🔗 Reports\n\n" +
+			"Deployed as build artifacts of [build #42](https://app.bitrise.io/build/abc123/):\n\n" +
+			"- **app-release.apk**: [Full HTML report](https://app.bitrise.io/build/abc123?tab=artifacts) `bundle-analysis-app-release.html` · [Raw JSON](https://app.bitrise.io/build/abc123?tab=artifacts) `bundle-analysis-app-release.json`\n" +
+			"- **app-release.aab**: [Raw JSON](https://app.bitrise.io/build/abc123?tab=artifacts) `bundle-analysis-app-release-aab.json`\n"},
+		{name: "uploaded reports link their URL", results: []analyze.ArtifactResult{apk}, deployDir: deployDir, buildURL: buildURL,
+			uploaded: map[string]string{apk.ReportPaths.HTML: "https://reports.s3.us-east-1.amazonaws.com/42/bundle-analysis-app-release.html"}, want: "### 🔗 Reports\n\n" +
+				"Deployed as build artifacts of [build #42](https://app.bitrise.io/build/abc123):\n\n" +
+				"- [Full HTML report](https://reports.s3.us-east-1.amazonaws.com/42/bundle-analysis-app-release.html)\n" +
+				"- [Raw JSON](https://app.bitrise.io/build/abc123?tab=artifacts) `bundle-analysis-app-release.json`\n"},
+		{name: "uploaded reports without a build URL", results: []analyze.ArtifactResult{aab},
+			uploaded: map[string]string{aab.ReportPaths.HTML: "https://reports.example.com/42/bundle-analysis-app-release.html"}, want: "### 🔗 Reports\n\n" +
+				"- [Full HTML report](https://reports.example.com/42/bundle-analysis-app-release.html)\n"},
+		{name: "no build URL", results: []analyze.ArtifactResult{apk}, deployDir: deployDir},
+		{name: "no deploy dir", results: []analyze.ArtifactResult{apk}, buildURL: buildURL},
+		{name: "no deployed reports", results: []analyze.ArtifactResult{{Name: "app.apk"}}, deployDir: deployDir, buildURL: buildURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReportLinksMarkdown(tt.results, tt.deployDir, tt.buildURL, "42", tt.uploaded); got != tt.want {
+				t.Errorf("ReportLinksMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDistributedReleaseMarkdown(t *testing.T) {
+	locale, err := NewLocale("", "", config.NewUnits(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := firebase.Release{DisplayVersion: "1.4.0", BuildVersion: "120", CreateTime: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		ConsoleURI: "https://console.firebase.google.com/releases/1", SizeBytes: 10 * 1024 * 1024}
+
+	tests := []struct {
+		name      string
+		release   firebase.Release
+		sizeBytes int64
+		want      []string
+	}{
+		{name: "grown", release: release, sizeBytes: 10*1024*1024 + 512*1024, want: []string{
+			"Compared with release **1.4.0 (120)**, distributed with Firebase App Distribution on 2026-03-01 ([console](https://console.firebase.google.com/releases/1)).",
+			"| This build | 10.50 MB |",
+			"| 1.4.0 (120) | 10.00 MB |",
+			"| **Change** | **+0.50 MB (+5.0%)** |",
+		}},
+		{name: "without console link and size", release: firebase.Release{DisplayVersion: "1.4.0", CreateTime: release.CreateTime}, sizeBytes: 1024 * 1024, want: []string{
+			"Compared with release **1.4.0**, distributed with Firebase App Distribution on 2026-03-01.",
+			"| **Change** | **+1.00 MB** |",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := DistributedReleaseMarkdown(tt.release, tt.sizeBytes, locale)
+			for _, line := range tt.want {
+				if !strings.Contains(markdown, line+"\n") {
+					t.Errorf("DistributedReleaseMarkdown() = %s\nwant line %q", markdown, line)
+				}
+			}
+		})
+	}
+}
+
+func TestAppStoreConnectMarkdown(t *testing.T) {
+	locale, err := NewLocale("", "", config.NewUnits(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sizes := appstoreconnect.BuildSizes{BundleID: "com.example.app", BuildNumber: "120", UploadedDate: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), Devices: []appstoreconnect.DeviceSize{
+		{DeviceModel: appstoreconnect.UniversalDevice, OSVersion: "Universal", DownloadBytes: 50 * 1024 * 1024, InstallBytes: 100 * 1024 * 1024},
+		{DeviceModel: "iPhone SE", OSVersion: "17.0", DownloadBytes: 30 * 1024 * 1024, InstallBytes: 60 * 1024 * 1024},
+	}}
+
+	tests := []struct {
+		name          string
+		downloadBytes int64
+		installBytes  int64
+		want          []string
+		wantFlagged   bool
+	}{
+		{name: "within the discrepancy", downloadBytes: 55 * 1024 * 1024, installBytes: 100 * 1024 * 1024, want: []string{
+			"Compared with build **120** of `com.example.app`, uploaded to App Store Connect on 2026-03-01.",
+			"| Download | 55.00 MB | 50.00 MB | +5.00 MB (+10.0%) |",
+			"| Install | 100.00 MB | 100.00 MB | +0.00 MB (+0.0%) |",
+			"| iPhone SE | 17.0 | 30.00 MB | 60.00 MB |",
+		}},
+		{name: "flagged discrepancy", downloadBytes: 35 * 1024 * 1024, want: []string{
+			"| Download | 35.00 MB | 50.00 MB | ⚠️ -15.00 MB (-30.0%) |",
+		}, wantFlagged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := AppStoreConnectMarkdown(sizes, tt.downloadBytes, tt.installBytes, 20, locale)
+			for _, line := range tt.want {
+				if !strings.Contains(markdown, line+"\n") {
+					t.Errorf("AppStoreConnectMarkdown() = %s\nwant line %q", markdown, line)
+				}
+			}
+			if flagged := strings.Contains(markdown, "differs from the universal size Apple reported by more than 20.0%"); flagged != tt.wantFlagged {
+				t.Errorf("AppStoreConnectMarkdown() = %s\nflagged %t, want %t", markdown, flagged, tt.wantFlagged)
+			}
+			if tt.installBytes == 0 && strings.Contains(markdown, "\n| Install |") {
+				t.Errorf("AppStoreConnectMarkdown() = %s\nwant no install row", markdown)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// cpuProfileName is the file name of the CPU profile in BITRISE_DEPLOY_DIR
+const cpuProfileName = "bundle-analyzer-cpu.pprof"
+
+// Profiler records the wall time of the step's phases for the profile mode.
+// A nil Profiler is valid and ignores everything, so callers don't need to check whether profiling is enabled.
+type Profiler struct {
+	mu         sync.Mutex
+	start      time.Time
+	timings    []StageTiming
+	cpuProfile *os.File
+}
+
+// NewProfiler starts timing the step
+func NewProfiler() *Profiler {
+	return &Profiler{start: time.Now()}
+}
+
+// Track records the duration of a phase started at the given time
+func (p *Profiler) Track(phase string, start time.Time) {
+	p.Record(phase, time.Since(start))
+}
+
+// Record records the duration of a phase
+func (p *Profiler) Record(phase string, duration time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timings = append(p.timings, StageTiming{Stage: phase, DurationMs: duration.Milliseconds()})
+}
+
+// StartCPUProfile writes a pprof CPU profile of the step to the directory until Finish is called
+func (p *Profiler) StartCPUProfile(dir string) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	profilePath := filepath.Join(dir, cpuProfileName)
+	file, err := os.Create(profilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(file); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	p.cpuProfile = file
+	return profilePath, nil
+}
+
+// Finish stops the CPU profile, logs the timing of every phase and returns the total duration of the step
+func (p *Profiler) Finish(logger log.Logger) time.Duration {
+	if p == nil {
+		return 0
+	}
+
+	if p.cpuProfile != nil {
+		pprof.StopCPUProfile()
+		if err := p.cpuProfile.Close(); err != nil {
+			logger.Warnf("Failed to write CPU profile: %s", err)
+		}
+		p.cpuProfile = nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total := time.Since(p.start)
+
+	logger.Println()
+	logger.Infof("Profile")
+	logger.Printf("%-40s %10s %7s", "Phase", "Duration", "Share")
+	for _, timing := range p.timings {
+		duration := time.Duration(timing.DurationMs) * time.Millisecond
+		logger.Printf("%-40s %9.2fs %6.1f%%", timing.Stage, duration.Seconds(), 100*duration.Seconds()/total.Seconds())
+	}
+	logger.Printf("%-40s %9.2fs %6.1f%%", "total", total.Seconds(), 100.0)
+	return total
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// stepRenderer writes a report format the step renders itself, see config.StepOutputFormats
+type stepRenderer struct {
+	format string
+	ext    string
+	write  func(result analyze.ArtifactResult, reportPath string) error
+	set    func(paths *analyze.ReportPaths, reportPath string)
+}
+
+var stepRenderers = []stepRenderer{
+	{format: "csv", ext: ".csv", write: WriteCSVReport, set: func(paths *analyze.ReportPaths, reportPath string) { paths.CSV = reportPath }},
+	{format: "sarif", ext: ".sarif", write: WriteSARIFReport, set: func(paths *analyze.ReportPaths, reportPath string) { paths.SARIF = reportPath }},
+}
+
+// RenderReports writes the formats rendered by the step from the analyzed result to the work directory, the formats
+// accepted by outputFormat are rendered concurrently and only once. The paths of the rendered reports are returned,
+// a failed format is left out and reported in the error.
+func RenderReports(result analyze.ArtifactResult, workDir string, outputFormat func(format string) bool) (analyze.ReportPaths, error) {
+	var renderers []stepRenderer
+	for _, renderer := range stepRenderers {
+		if outputFormat(renderer.format) {
+			renderers = append(renderers, renderer)
+		}
+	}
+
+	reportPaths := make([]string, len(renderers))
+	errs := make([]error, len(renderers))
+	analyze.RunConcurrently(len(renderers), len(renderers), func(idx int) {
+		reportPath := filepath.Join(workDir, analyze.ReportName(result.ArtifactPath, renderers[idx].ext))
+		if err := renderers[idx].write(result, reportPath); err != nil {
+			errs[idx] = fmt.Errorf("%s: %w", renderers[idx].format, err)
+			return
+		}
+		reportPaths[idx] = reportPath
+	})
+
+	var paths analyze.ReportPaths
+	for idx, renderer := range renderers {
+		if reportPaths[idx] != "" {
+			renderer.set(&paths, reportPaths[idx])
+		}
+	}
+	return paths, errors.Join(errs...)
+}
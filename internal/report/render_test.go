@@ -0,0 +1,142 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestRenderReports(t *testing.T) {
+	result := analyze.ArtifactResult{
+		ArtifactPath: "/builds/app-release.apk",
+		Name:         "app-release.apk",
+		Inventory: analyze.Inventory{Entries: []analyze.ArchiveEntry{
+			{Path: "res/raw/small.bin", CompressedSize: 10, UncompressedSize: 20, CRC32: 0xab},
+			{Path: "lib/arm64-v8a/libapp.so", CompressedSize: 300, UncompressedSize: 900, CRC32: 0x1234},
+		}},
+		Secrets:   []analyze.SecretFinding{{Detector: "aws_access_key", Path: "assets/config.json", Masked: "AKIA****"}},
+		Hardening: []analyze.BinaryHardening{{Path: "lib/arm64-v8a/libapp.so", PIE: true, RELRO: true, NX: true}},
+	}
+
+	tests := []struct {
+		name      string
+		formats   []string
+		wantCSV   bool
+		wantSARIF bool
+	}{
+		{name: "no step formats", formats: []string{"markdown", "json"}},
+		{name: "csv only", formats: []string{"csv"}, wantCSV: true},
+		{name: "csv and sarif", formats: []string{"csv", "sarif"}, wantCSV: true, wantSARIF: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workDir := t.TempDir()
+			paths, err := RenderReports(result, workDir, func(format string) bool {
+				for _, item := range tt.formats {
+					if item == format {
+						return true
+					}
+				}
+				return false
+			})
+			if err != nil {
+				t.Fatalf("RenderReports() error = %s", err)
+			}
+			if got := paths.CSV != ""; got != tt.wantCSV {
+				t.Errorf("CSV rendered = %t, want %t", got, tt.wantCSV)
+			}
+			if got := paths.SARIF != ""; got != tt.wantSARIF {
+				t.Errorf("SARIF rendered = %t, want %t", got, tt.wantSARIF)
+			}
+			if tt.wantCSV && paths.CSV != filepath.Join(workDir, "bundle-analysis-app-release.csv") {
+				t.Errorf("CSV path = %s", paths.CSV)
+			}
+		})
+	}
+}
+
+func TestWriteCSVReport(t *testing.T) {
+	result := analyze.ArtifactResult{Inventory: analyze.Inventory{Entries: []analyze.ArchiveEntry{
+		{Path: "b.txt", CompressedSize: 10, UncompressedSize: 20, CRC32: 0xab},
+		{Path: "a.so", CompressedSize: 5000000000, UncompressedSize: 6000000000, CRC32: 0x1234},
+	}}}
+	csvPath := filepath.Join(t.TempDir(), "report.csv")
+	if err := WriteCSVReport(result, csvPath); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		csvHeader,
+		{"a.so", "5000000000", "6000000000", "00001234"},
+		{"b.txt", "10", "20", "000000ab"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(records), len(want))
+	}
+	for idx := range want {
+		for col := range want[idx] {
+			if records[idx][col] != want[idx][col] {
+				t.Errorf("row %d column %d = %q, want %q", idx, col, records[idx][col], want[idx][col])
+			}
+		}
+	}
+}
+
+func TestWriteSARIFReport(t *testing.T) {
+	result := analyze.ArtifactResult{
+		Name:    "app.ipa",
+		Secrets: []analyze.SecretFinding{{Detector: "github_token", Path: "Payload/App.app/config.plist", Masked: "ghp_****"}},
+		VulnerableSDKs: []analyze.VulnerableSDK{{
+			SDK:      analyze.DetectedSDK{Name: "Alamofire", Version: "4.0.0", Path: "Payload/App.app/Frameworks/Alamofire.framework"},
+			Advisory: analyze.Advisory{ID: "GHSA-1", Severity: "low", Summary: "Header injection"},
+		}},
+		DebugFindings: []analyze.DebugFinding{{Kind: "signature", Detail: "signed with a development certificate"}},
+		Hardening: []analyze.BinaryHardening{
+			{Path: "Payload/App.app/App", PIE: true, StackCanary: true, RELRO: true, NX: true},
+			{Path: "Payload/App.app/Frameworks/Old.framework/Old", RELRO: true, NX: true},
+		},
+	}
+	sarifPath := filepath.Join(t.TempDir(), "report.sarif")
+	if err := WriteSARIFReport(result, sarifPath); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document sarifLog
+	if err := json.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ ruleID, level, uri string }{
+		{sarifRuleSecret, "error", "Payload/App.app/config.plist"},
+		{sarifRuleVulnerableSDK, "note", "Payload/App.app/Frameworks/Alamofire.framework"},
+		{sarifRuleDebugArtifact, "warning", "app.ipa"},
+		{sarifRuleUnhardened, "warning", "Payload/App.app/Frameworks/Old.framework/Old"},
+	}
+	results := document.Runs[0].Results
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %s", len(results), len(want), data)
+	}
+	for idx, item := range want {
+		got := results[idx]
+		if got.RuleID != item.ruleID || got.Level != item.level || got.Locations[0].PhysicalLocation.ArtifactLocation.URI != item.uri {
+			t.Errorf("result %d = %s %s %s, want %s %s %s", idx, got.RuleID, got.Level, got.Locations[0].PhysicalLocation.ArtifactLocation.URI, item.ruleID, item.level, item.uri)
+		}
+	}
+}
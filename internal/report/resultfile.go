@@ -0,0 +1,103 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// resultFileName is the file name of the result status file in BITRISE_DEPLOY_DIR
+const resultFileName = "bundle-analyzer-result.json"
+
+// Outcomes of the step in the result status file
+const (
+	StatusPassed = "passed"
+	// StatusWarned is set when thresholds were violated but on_violation is continue
+	StatusWarned   = "warned"
+	StatusFailed   = "failed"
+	StatusError    = "error"
+	StatusTimedOut = "timed_out"
+	StatusCanceled = "canceled"
+)
+
+// resultFile is the result status file, a small summary of the outcome for pipeline-level tooling
+type resultFile struct {
+	Status string `json:"status"`
+	// BuildSlug tells the reports of an earlier build apart on a reused VM
+	BuildSlug  string           `json:"build_slug,omitempty"`
+	DurationMs int64            `json:"duration_ms"`
+	Artifacts  []resultArtifact `json:"artifacts"`
+}
+
+// resultArtifact is the outcome of a single artifact in the result status file
+type resultArtifact struct {
+	Name                  string            `json:"name"`
+	SizeBytes             int64             `json:"size_bytes"`
+	PotentialSavingsBytes int64             `json:"potential_savings_bytes"`
+	FileCount             int64             `json:"file_count"`
+	DownloadBytes         int64             `json:"download_bytes,omitempty"`
+	DownloadCompression   string            `json:"download_compression,omitempty"`
+	SizeDeltaBytes        *int64            `json:"size_delta_bytes,omitempty"`
+	BaselineBuild         string            `json:"baseline_build,omitempty"`
+	AnalysisMs            int64             `json:"analysis_ms"`
+	DebugBuild            bool              `json:"debug_build"`
+	Violations            []resultViolation `json:"violations"`
+	Reports               map[string]string `json:"reports,omitempty"`
+}
+
+// resultViolation is a violated threshold, Check is the name of the input configuring it
+type resultViolation struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// WriteResultFile writes the status of the step, the violated thresholds, the key metrics and the timings of the
+// artifacts of the build to bundle-analyzer-result.json in the directory and returns its path
+func WriteResultFile(dir, buildSlug, status string, duration time.Duration, results []analyze.ArtifactResult) (string, error) {
+	file := resultFile{Status: status, BuildSlug: buildSlug, DurationMs: duration.Milliseconds(), Artifacts: []resultArtifact{}}
+	for _, result := range results {
+		artifact := resultArtifact{
+			Name:                  result.Name,
+			SizeBytes:             result.Metrics.SizeBytes,
+			PotentialSavingsBytes: result.Metrics.PotentialSavingsBytes,
+			FileCount:             result.Metrics.FileCount,
+			DownloadBytes:         result.DownloadBytes,
+			DownloadCompression:   result.DownloadCompression,
+			AnalysisMs:            result.PluginDuration.Milliseconds(),
+			DebugBuild:            len(result.DebugBuildReasons) > 0,
+			Violations:            []resultViolation{},
+			Reports:               map[string]string{},
+		}
+		if result.Comparison != nil {
+			delta := result.Comparison.SizeDeltaBytes
+			artifact.SizeDeltaBytes = &delta
+			artifact.BaselineBuild = result.Comparison.Baseline.BuildNumber
+		}
+		for _, violation := range result.Violations {
+			artifact.Violations = append(artifact.Violations, resultViolation{Check: violation.Check, Message: violation.Err.Error()})
+		}
+		for format, reportPath := range map[string]string{"markdown": result.ReportPaths.Markdown, "html": result.ReportPaths.HTML, "json": result.ReportPaths.JSON, "csv": result.ReportPaths.CSV, "sarif": result.ReportPaths.SARIF, "licenses": result.ReportPaths.Licenses} {
+			if reportPath != "" {
+				artifact.Reports[format] = reportPath
+			}
+		}
+		file.Artifacts = append(file.Artifacts, artifact)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result file: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	resultPath := filepath.Join(dir, resultFileName)
+	if err := os.WriteFile(resultPath, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write result file: %w", err)
+	}
+	return resultPath, nil
+}
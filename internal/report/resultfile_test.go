@@ -0,0 +1,73 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestWriteResultFile(t *testing.T) {
+	results := []analyze.ArtifactResult{
+		{
+			Name:           "app-release.apk",
+			Metrics:        analyze.BundleMetrics{SizeBytes: 5242880, PotentialSavingsBytes: 1024, FileCount: 3},
+			Comparison:     &analyze.BaselineComparison{Baseline: analyze.HistoryRecord{BuildNumber: "41"}, SizeDeltaBytes: -2048},
+			PluginDuration: 1500 * time.Millisecond,
+			Violations:     []analyze.ThresholdViolation{{Check: "max_size_mb", Err: errors.New("bundle size 5.00 MB exceeds threshold 4.00 MB")}},
+			ReportPaths:    analyze.ReportPaths{Markdown: "/deploy/report.md", JSON: "/deploy/report.json"},
+		},
+		{Name: "app-release.aab", Metrics: analyze.BundleMetrics{SizeBytes: 6291456}},
+	}
+	dir := filepath.Join(t.TempDir(), "deploy")
+
+	resultPath, err := WriteResultFile(dir, "build-slug", StatusFailed, 3*time.Second, results)
+	if err != nil {
+		t.Fatalf("WriteResultFile() error = %s", err)
+	}
+	if resultPath != filepath.Join(dir, "bundle-analyzer-result.json") {
+		t.Errorf("result path = %s", resultPath)
+	}
+	data, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got resultFile
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	delta := int64(-2048)
+	want := resultFile{Status: StatusFailed, BuildSlug: "build-slug", DurationMs: 3000, Artifacts: []resultArtifact{
+		{
+			Name: "app-release.apk", SizeBytes: 5242880, PotentialSavingsBytes: 1024, FileCount: 3,
+			SizeDeltaBytes: &delta, BaselineBuild: "41", AnalysisMs: 1500,
+			Violations: []resultViolation{{Check: "max_size_mb", Message: "bundle size 5.00 MB exceeds threshold 4.00 MB"}},
+			Reports:    map[string]string{"markdown": "/deploy/report.md", "json": "/deploy/report.json"},
+		},
+		{Name: "app-release.aab", SizeBytes: 6291456, Violations: []resultViolation{}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result file = %s", data)
+	}
+}
+
+func TestWriteResultFileWithoutArtifacts(t *testing.T) {
+	resultPath, err := WriteResultFile(t.TempDir(), "", StatusError, time.Second, nil)
+	if err != nil {
+		t.Fatalf("WriteResultFile() error = %s", err)
+	}
+	data, err := os.ReadFile(resultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pipeline tooling reads the artifacts as a list even if the analysis failed before any artifact
+	if want := "{\n  \"status\": \"error\",\n  \"duration_ms\": 1000,\n  \"artifacts\": []\n}\n"; string(data) != want {
+		t.Errorf("result file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,160 @@
+package report
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// sarifSchema and sarifVersion identify the SARIF 2.1.0 documents code scanning tools read
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// The rules of the SARIF report, one per kind of finding of the step
+const (
+	sarifRuleSecret          = "bundle-analyzer/secret"
+	sarifRuleVulnerableSDK   = "bundle-analyzer/vulnerable-sdk"
+	sarifRuleDebugArtifact   = "bundle-analyzer/debug-artifact"
+	sarifRuleUnhardened      = "bundle-analyzer/unhardened-binary"
+	sarifRuleNetworkSecurity = "bundle-analyzer/network-security"
+)
+
+// sarifRuleDescriptions are the short descriptions of the rules
+var sarifRuleDescriptions = map[string]string{
+	sarifRuleSecret:          "Potential secret bundled in the artifact",
+	sarifRuleVulnerableSDK:   "Bundled SDK version with a known vulnerability",
+	sarifRuleDebugArtifact:   "Sign of a debug build",
+	sarifRuleUnhardened:      "Native binary missing hardening flags",
+	sarifRuleNetworkSecurity: "Insecure network security setting",
+}
+
+// sarifRuleOrder lists the rules in the order of the report
+var sarifRuleOrder = []string{sarifRuleSecret, sarifRuleVulnerableSDK, sarifRuleDebugArtifact, sarifRuleUnhardened, sarifRuleNetworkSecurity}
+
+// sarifSeverityLevels map the advisory severities to the SARIF levels
+var sarifSeverityLevels = map[string]string{"low": "note", "medium": "warning", "high": "error", "critical": "error"}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// WriteSARIFReport writes the findings of the step's checks to a SARIF report for code scanning tools. The locations
+// are the paths within the artifact, findings without a file are located at the artifact itself.
+func WriteSARIFReport(result analyze.ArtifactResult, sarifPath string) error {
+	var results []sarifResult
+	add := func(ruleID, level, message, path string) {
+		if path == "" {
+			path = result.Name
+		}
+		results = append(results, sarifResult{
+			RuleID:    ruleID,
+			Level:     level,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: path}}}},
+		})
+	}
+
+	for _, finding := range result.Secrets {
+		add(sarifRuleSecret, "error", fmt.Sprintf("%s found: %s", finding.Detector, finding.Masked), finding.Path)
+	}
+	for _, vulnerable := range result.VulnerableSDKs {
+		level, ok := sarifSeverityLevels[strings.ToLower(vulnerable.Advisory.Severity)]
+		if !ok {
+			level = "warning"
+		}
+		message := fmt.Sprintf("%s %s: %s (%s)", vulnerable.SDK.Name, vulnerable.SDK.Version, vulnerable.Advisory.Summary, vulnerable.Advisory.ID)
+		if vulnerable.FixedVersion != "" {
+			message += ", fixed in " + vulnerable.FixedVersion
+		}
+		add(sarifRuleVulnerableSDK, level, message, vulnerable.SDK.Path)
+	}
+	for _, finding := range result.DebugFindings {
+		add(sarifRuleDebugArtifact, "warning", fmt.Sprintf("%s: %s", finding.Kind, finding.Detail), "")
+	}
+	for _, binary := range result.Hardening {
+		if missing := binary.Missing(); len(missing) > 0 {
+			add(sarifRuleUnhardened, "warning", "Missing "+strings.Join(missing, ", "), binary.Path)
+		}
+	}
+	for _, finding := range result.NetworkSecurity {
+		add(sarifRuleNetworkSecurity, "warning", fmt.Sprintf("%s (%s)", finding.Issue, finding.Scope), finding.Source)
+	}
+
+	driver := sarifDriver{Name: "bundle-analyzer", InformationURI: "https://github.com/bitrise-io/steps-bundle-analyzer"}
+	for _, ruleID := range sarifRuleOrder {
+		driver.Rules = append(driver.Rules, sarifRule{ID: ruleID, ShortDescription: sarifMessage{Text: sarifRuleDescriptions[ruleID]}})
+	}
+	// Code scanning tools expect an empty list when nothing was found
+	if results == nil {
+		results = []sarifResult{}
+	}
+	document := sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}}}
+
+	file, err := os.Create(sarifPath)
+	if err != nil {
+		return fmt.Errorf("failed to create SARIF report: %w", err)
+	}
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buffered)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+	return file.Close()
+}
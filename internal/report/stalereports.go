@@ -0,0 +1,128 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// Handling of the reports left in BITRISE_DEPLOY_DIR by an earlier build, stale_reports
+const (
+	StaleReportsRemove     = "remove"
+	StaleReportsQuarantine = "quarantine"
+	StaleReportsKeep       = "keep"
+)
+
+// staleReportPatterns match the files the step writes to BITRISE_DEPLOY_DIR, the reports of multiple artifacts are
+// prefixed with the artifact name
+var staleReportPatterns = []string{"bundle-analysis-*", "*-bundle-analysis-*", resultFileName, debugBundleName, cpuProfileName}
+
+// StaleReports returns the files the step wrote to the deploy directory in an earlier build, when a VM is reused.
+// The build is told by the build slug recorded in the result file: the files of an earlier invocation of the step
+// in the same build are kept, and nothing is stale without a result file. The files in keep are never stale.
+func StaleReports(deployDir, buildSlug string, keep []string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(deployDir, resultFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read result file: %w", err)
+	}
+	var previous struct {
+		BuildSlug string `json:"build_slug"`
+	}
+	if err := json.Unmarshal(data, &previous); err != nil {
+		return nil, fmt.Errorf("failed to parse result file: %w", err)
+	}
+	if previous.BuildSlug == buildSlug {
+		return nil, nil
+	}
+
+	kept := map[string]bool{}
+	for _, keepPath := range keep {
+		if absPath, err := filepath.Abs(keepPath); err == nil {
+			kept[absPath] = true
+		}
+	}
+	var stale []string
+	seen := map[string]bool{}
+	for _, pattern := range staleReportPatterns {
+		matches, err := filepath.Glob(filepath.Join(deployDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			absPath, err := filepath.Abs(match)
+			if err != nil || kept[absPath] || seen[match] {
+				continue
+			}
+			if info, err := os.Stat(match); err != nil || info.IsDir() {
+				continue
+			}
+			seen[match] = true
+			stale = append(stale, match)
+		}
+	}
+	return stale, nil
+}
+
+// CleanStaleReports removes the stale reports of the deploy directory with the stale_reports mode, or moves them to
+// a quarantine directory in the system temporary directory, so an outdated report is never exported or uploaded.
+// The files of an existing_report_path are kept.
+func CleanStaleReports(deployDir, buildSlug, mode string, keep []string, logger log.Logger) {
+	if mode == StaleReportsKeep || deployDir == "" {
+		return
+	}
+	stale, err := StaleReports(deployDir, buildSlug, keep)
+	if err != nil {
+		logger.Warnf("Failed to check for stale reports: %s", err)
+		return
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	logger.Println()
+	logger.Warnf("Found %d report(s) of an earlier build in %s", len(stale), deployDir)
+	quarantineDir := ""
+	if mode == StaleReportsQuarantine {
+		if quarantineDir, err = os.MkdirTemp("", "bundle-analyzer-stale-*"); err != nil {
+			logger.Warnf("Failed to create quarantine directory, keeping the stale reports: %s", err)
+			return
+		}
+	}
+	for _, stalePath := range stale {
+		if quarantineDir != "" {
+			err = moveFile(stalePath, filepath.Join(quarantineDir, filepath.Base(stalePath)))
+		} else {
+			err = os.Remove(stalePath)
+		}
+		if err != nil {
+			logger.Warnf("Failed to clean up %s: %s", stalePath, err)
+			continue
+		}
+		logger.Printf("Cleaned up: %s", strings.TrimPrefix(stalePath, deployDir+string(filepath.Separator)))
+	}
+	if quarantineDir != "" {
+		logger.Printf("Moved the stale reports to %s", quarantineDir)
+	}
+}
+
+// moveFile moves a file, copying it when the destination is on a different file system
+func moveFile(srcPath, dstPath string) error {
+	if err := os.Rename(srcPath, dstPath); err == nil {
+		return nil
+	}
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := streamCopy(src, dstPath); err != nil {
+		return err
+	}
+	return os.Remove(srcPath)
+}
@@ -0,0 +1,58 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestStaleReports(t *testing.T) {
+	files := []string{"bundle-analysis-app.md", "bundle-analysis-app.json", "app-bundle-analysis-free.html", "release-notes.txt", "app-release.apk"}
+	tests := []struct {
+		name       string
+		resultFile string
+		keep       []string
+		want       []string
+	}{
+		{name: "earlier build", resultFile: `{"build_slug": "earlier"}`,
+			want: []string{"app-bundle-analysis-free.html", "bundle-analysis-app.json", "bundle-analysis-app.md", "bundle-analyzer-result.json"}},
+		{name: "kept existing report", resultFile: `{"build_slug": "earlier"}`, keep: []string{"bundle-analysis-app.json"},
+			want: []string{"app-bundle-analysis-free.html", "bundle-analysis-app.md", "bundle-analyzer-result.json"}},
+		{name: "same build", resultFile: `{"build_slug": "current"}`},
+		{name: "no result file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployDir := t.TempDir()
+			for _, name := range files {
+				if err := os.WriteFile(filepath.Join(deployDir, name), []byte("content"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.resultFile != "" {
+				if err := os.WriteFile(filepath.Join(deployDir, resultFileName), []byte(tt.resultFile), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var keep []string
+			for _, name := range tt.keep {
+				keep = append(keep, filepath.Join(deployDir, name))
+			}
+
+			stale, err := StaleReports(deployDir, "current", keep)
+			if err != nil {
+				t.Fatalf("StaleReports() error = %s", err)
+			}
+			var names []string
+			for _, stalePath := range stale {
+				names = append(names, filepath.Base(stalePath))
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("StaleReports() = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// SummaryTable renders the summary printed at the end of the step log: a row per artifact with its size, the
+// estimated download size under download_compression and install size, the change to the baseline and the threshold result, followed by the paths
+// of the reports. The table is drawn with ASCII characters so it lines up in every log viewer.
+func SummaryTable(results []analyze.ArtifactResult, units config.Units) []string {
+	rows := [][]string{{"Artifact", "Size", "Download", "Install", "Change", "Thresholds"}}
+	for _, result := range results {
+		download, install := "-", "-"
+		if len(result.Inventory.Entries) > 0 {
+			if result.DownloadCompression != "" {
+				download = "~" + units.Format(result.DownloadBytes)
+			}
+			install = "~" + units.Format(result.Inventory.InstallSize())
+		}
+
+		change := "-"
+		if result.Comparison != nil {
+			change = fmt.Sprintf("%s vs #%s", units.FormatDelta(result.Comparison.SizeDeltaBytes), result.Comparison.Baseline.BuildNumber)
+		}
+
+		thresholds := "passed"
+		if len(result.Violations) > 0 {
+			thresholds = fmt.Sprintf("%d failed", len(result.Violations))
+		}
+
+		rows = append(rows, []string{result.Name, units.Format(result.Metrics.SizeBytes), download, install, change, thresholds})
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for idx, cell := range row {
+			if width := utf8.RuneCountInString(cell); width > widths[idx] {
+				widths[idx] = width
+			}
+		}
+	}
+
+	separator := "+"
+	for _, width := range widths {
+		separator += strings.Repeat("-", width+2) + "+"
+	}
+	lines := []string{separator}
+	for rowIdx, row := range rows {
+		line := "|"
+		for idx, cell := range row {
+			padding := strings.Repeat(" ", widths[idx]-utf8.RuneCountInString(cell))
+			// Sizes are right-aligned so their decimals line up
+			if rowIdx > 0 && idx > 0 && idx < 4 {
+				line += " " + padding + cell + " |"
+			} else {
+				line += " " + cell + padding + " |"
+			}
+		}
+		lines = append(lines, line)
+		if rowIdx == 0 {
+			lines = append(lines, separator)
+		}
+	}
+	lines = append(lines, separator)
+
+	for _, result := range results {
+		var paths []string
+		for _, reportPath := range []string{result.ReportPaths.Markdown, result.ReportPaths.HTML, result.ReportPaths.JSON, result.ReportPaths.Licenses} {
+			if reportPath != "" {
+				paths = append(paths, reportPath)
+			}
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		if len(results) > 1 {
+			lines = append(lines, result.Name+" reports:")
+		} else {
+			lines = append(lines, "Reports:")
+		}
+		for _, reportPath := range paths {
+			lines = append(lines, "- "+reportPath)
+		}
+	}
+	return lines
+}
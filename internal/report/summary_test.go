@@ -0,0 +1,73 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+func TestSummaryTable(t *testing.T) {
+	apk := analyze.ArtifactResult{
+		Name:        "app-release.apk",
+		Metrics:     analyze.BundleMetrics{SizeBytes: 5 * 1024 * 1024},
+		Inventory:   analyze.Inventory{Entries: []analyze.ArchiveEntry{{Path: "classes.dex", CompressedSize: 5 * 1024 * 1024, UncompressedSize: 12 * 1024 * 1024}}},
+		Comparison:  &analyze.BaselineComparison{Baseline: analyze.HistoryRecord{BuildNumber: "41"}, SizeDeltaBytes: 256 * 1024},
+		ReportPaths: analyze.ReportPaths{Markdown: "/deploy/bundle-analysis-app-release.md", JSON: "/deploy/bundle-analysis-app-release.json"},
+	}
+	aab := analyze.ArtifactResult{
+		Name:       "app-release.aab",
+		Metrics:    analyze.BundleMetrics{SizeBytes: 14 * 1024 * 1024},
+		Violations: []analyze.ThresholdViolation{{Check: "max_size_mb"}, {Check: "max_size_increase_mb"}},
+	}
+
+	tests := []struct {
+		name    string
+		results []analyze.ArtifactResult
+		want    []string
+	}{
+		{name: "single artifact", results: []analyze.ArtifactResult{apk}, want: []string{
+			"+-----------------+---------+----------+-----------+-----------------+------------+",
+			"| Artifact        | Size    | Download | Install   | Change          | Thresholds |",
+			"+-----------------+---------+----------+-----------+-----------------+------------+",
+			"| app-release.apk | 5.00 MB |        - | ~12.00 MB | +0.25 MB vs #41 | passed     |",
+			"+-----------------+---------+----------+-----------+-----------------+------------+",
+			"Reports:",
+			"- /deploy/bundle-analysis-app-release.md",
+			"- /deploy/bundle-analysis-app-release.json",
+		}},
+		{name: "multiple artifacts", results: []analyze.ArtifactResult{apk, aab}, want: []string{
+			"+-----------------+----------+----------+-----------+-----------------+------------+",
+			"| Artifact        | Size     | Download | Install   | Change          | Thresholds |",
+			"+-----------------+----------+----------+-----------+-----------------+------------+",
+			"| app-release.apk |  5.00 MB |        - | ~12.00 MB | +0.25 MB vs #41 | passed     |",
+			"| app-release.aab | 14.00 MB |        - |         - | -               | 2 failed   |",
+			"+-----------------+----------+----------+-----------+-----------------+------------+",
+			"app-release.apk reports:",
+			"- /deploy/bundle-analysis-app-release.md",
+			"- /deploy/bundle-analysis-app-release.json",
+		}},
+		{name: "download compression", results: []analyze.ArtifactResult{{
+			Name:                "app-release.apk",
+			Metrics:             analyze.BundleMetrics{SizeBytes: 5 * 1024 * 1024},
+			Inventory:           apk.Inventory,
+			DownloadBytes:       4 * 1024 * 1024,
+			DownloadCompression: "gzip",
+		}}, want: []string{
+			"+-----------------+---------+----------+-----------+--------+------------+",
+			"| Artifact        | Size    | Download | Install   | Change | Thresholds |",
+			"+-----------------+---------+----------+-----------+--------+------------+",
+			"| app-release.apk | 5.00 MB | ~4.00 MB | ~12.00 MB | -      | passed     |",
+			"+-----------------+---------+----------+-----------+--------+------------+",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SummaryTable(tt.results, config.NewUnits(""))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("SummaryTable() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// testReportDirName is the test run directory of the bundle analysis within BITRISE_TEST_RESULT_DIR
+const testReportDirName = "bundle-analysis"
+
+// junitTestSuites is the JUnit XML document the Test Reports add-on reads the test results from
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// PublishTestReport writes the analysis as a test run of the Test Reports add-on: a test-info.json,
+// a JUnit XML with a test case per artifact failing on threshold violations, and the reports as attachments.
+// The sizes are shown in the units.
+func PublishTestReport(testResultDir string, results []analyze.ArtifactResult, units config.Units) (string, error) {
+	runDir := filepath.Join(testResultDir, testReportDirName)
+	if err := os.MkdirAll(runDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create test run directory: %w", err)
+	}
+
+	testInfo, err := json.Marshal(map[string]string{"test-name": "Bundle analysis"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode test info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "test-info.json"), testInfo, 0644); err != nil {
+		return "", fmt.Errorf("failed to write test info: %w", err)
+	}
+
+	suite := junitTestSuite{Name: "Bundle analysis"}
+	for _, result := range results {
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: "bundle-analyzer",
+			SystemOut: fmt.Sprintf("Size: %s\nFiles: %d\nPotential savings: %s",
+				units.Format(result.Metrics.SizeBytes), result.Metrics.FileCount, units.Format(result.Metrics.PotentialSavingsBytes)),
+		}
+
+		if len(result.Violations) > 0 {
+			var messages []string
+			for _, violation := range result.Violations {
+				messages = append(messages, violation.Err.Error())
+			}
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d threshold violation(s)", len(result.Violations)),
+				Text:    strings.Join(messages, "\n"),
+			}
+			suite.Failures++
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+		suite.Tests++
+
+		// Attach the reports to the test run
+		name := strings.TrimSuffix(result.Name, filepath.Ext(result.Name))
+		for _, reportPath := range []string{result.GeneratedFiles.Markdown, result.GeneratedFiles.HTML, result.GeneratedFiles.JSON} {
+			if reportPath == "" {
+				continue
+			}
+			if err := copyFile(reportPath, filepath.Join(runDir, name+"-"+filepath.Base(reportPath))); err != nil {
+				return "", fmt.Errorf("failed to attach %s: %w", reportPath, err)
+			}
+		}
+	}
+
+	data, err := xml.MarshalIndent(junitTestSuites{
+		Name:     "Bundle analysis",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode test results: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "bundle-analysis.xml"), append([]byte(xml.Header), data...), 0644); err != nil {
+		return "", fmt.Errorf("failed to write test results: %w", err)
+	}
+
+	return runDir, nil
+}
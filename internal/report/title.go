@@ -0,0 +1,109 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// titleSearchSize is the size of the beginning of the reports the titles are looked up in
+const titleSearchSize = 64 * 1024
+
+var (
+	// markdownHeadingPattern matches the first heading of the markdown report, the title bundle-inspector writes
+	markdownHeadingPattern = regexp.MustCompile(`(?m)^(#{1,6})[ \t]+.*$`)
+	htmlTitlePattern       = regexp.MustCompile(`(?is)(<title[^>]*>).*?(</title>)`)
+	htmlHeadingPattern     = regexp.MustCompile(`(?is)(<h1[^>]*>).*?(</h1>)`)
+	// emptyGroupPattern matches the brackets left behind by placeholders without a value
+	emptyGroupPattern = regexp.MustCompile(`\(\s*\)|\[\s*\]`)
+	spacesPattern     = regexp.MustCompile(`\s+`)
+)
+
+// RenderTitle fills the {app_name}, {version}, {variant} and {artifact} placeholders of a report_title template.
+// Brackets of placeholders without a value are dropped, "{app_name} ({variant})" becomes "My App" without a variant.
+func RenderTitle(template string, app analyze.AppIdentity, artifactName string) string {
+	title := strings.NewReplacer(
+		"{app_name}", app.Name,
+		"{version}", app.Version,
+		"{variant}", app.Variant,
+		"{artifact}", artifactName,
+	).Replace(template)
+	title = emptyGroupPattern.ReplaceAllString(title, "")
+	return strings.TrimSpace(spacesPattern.ReplaceAllString(title, " "))
+}
+
+// ApplyTitle replaces the title of the markdown and HTML reports: the first heading of the markdown report,
+// the <title> and first <h1> of the HTML report. A markdown report without a heading gets one prepended.
+func ApplyTitle(paths analyze.ReportPaths, title string) error {
+	if paths.Markdown != "" {
+		if err := rewriteReport(paths.Markdown, func(content string) string {
+			if loc := markdownHeadingPattern.FindStringSubmatchIndex(content); loc != nil {
+				return content[:loc[0]] + content[loc[2]:loc[3]] + " " + title + content[loc[1]:]
+			}
+			return "## " + title + "\n\n" + content
+		}); err != nil {
+			return fmt.Errorf("failed to set the title of the markdown report: %w", err)
+		}
+	}
+
+	if paths.HTML != "" {
+		escaped := html.EscapeString(title)
+		if err := rewriteReport(paths.HTML, func(content string) string {
+			content = replaceFirst(htmlTitlePattern, content, escaped)
+			return replaceFirst(htmlHeadingPattern, content, escaped)
+		}); err != nil {
+			return fmt.Errorf("failed to set the title of the HTML report: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceFirst replaces the content between the opening and closing tag of the first match of pattern
+func replaceFirst(pattern *regexp.Regexp, content, text string) string {
+	loc := pattern.FindStringSubmatchIndex(content)
+	if loc == nil {
+		return content
+	}
+	return content[:loc[3]] + text + content[loc[4]:]
+}
+
+// rewriteReport rewrites the beginning of a report file with rewrite, the rest is copied as is:
+// the titles are at the top, and HTML reports with treemaps can be hundreds of MB
+func rewriteReport(reportPath string, rewrite func(string) string) error {
+	src, err := os.Open(reportPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	head := make([]byte, titleSearchSize)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	tmpPath := reportPath + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	if _, err := dst.WriteString(rewrite(string(head[:n]))); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, reportPath)
+}
@@ -0,0 +1,69 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+func TestRenderTitle(t *testing.T) {
+	app := analyze.AppIdentity{Name: "My App", Version: "2.4.0", Variant: "free-release"}
+	tests := []struct {
+		name     string
+		template string
+		app      analyze.AppIdentity
+		want     string
+	}{
+		{name: "all placeholders", template: "{app_name} {version} ({variant}) - {artifact}", app: app, want: "My App 2.4.0 (free-release) - app-free-release.apk"},
+		{name: "no placeholders", template: "Size report", app: app, want: "Size report"},
+		{name: "missing variant drops the brackets", template: "{app_name} ({variant})", app: analyze.AppIdentity{Name: "My App"}, want: "My App"},
+		{name: "missing version", template: "{app_name} [{version}] size", app: analyze.AppIdentity{Name: "My App"}, want: "My App size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderTitle(tt.template, tt.app, "app-free-release.apk"); got != tt.want {
+				t.Errorf("RenderTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyTitle(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{name: "markdown heading", file: "report.md", content: "# Bundle Analysis\n\nSize: 5 MB\n## Files\n", want: "# My App & Co\n\nSize: 5 MB\n## Files\n"},
+		{name: "markdown without heading", file: "report.md", content: "Size: 5 MB\n", want: "## My App & Co\n\nSize: 5 MB\n"},
+		{name: "HTML title and heading", file: "report.html", content: "<html><head><title>Bundle Analysis</title></head><body><h1 class=\"t\">Bundle Analysis</h1><h1>Files</h1></body></html>",
+			want: "<html><head><title>My App &amp; Co</title></head><body><h1 class=\"t\">My App &amp; Co</h1><h1>Files</h1></body></html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reportPath := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(reportPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			paths := analyze.ReportPaths{Markdown: reportPath}
+			if strings.HasSuffix(tt.file, ".html") {
+				paths = analyze.ReportPaths{HTML: reportPath}
+			}
+
+			if err := ApplyTitle(paths, "My App & Co"); err != nil {
+				t.Fatalf("ApplyTitle() error = %s", err)
+			}
+			content, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("report = %q, want %q", content, tt.want)
+			}
+		})
+	}
+}
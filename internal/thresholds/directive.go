@@ -0,0 +1,43 @@
+package thresholds
+
+import (
+	"regexp"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// sizeOverridePattern matches directives like [bundle-size: allow +5MB] or [bundle-size: allow +500KiB]
+var sizeOverridePattern = regexp.MustCompile(`(?i)\[bundle-size:\s*allow\s*\+\s*([0-9]+(?:\.[0-9]+)?\s*(?:[KMG]i?B|B))\]`)
+
+// sizeOverrideSources are the environment variables searched for a size override directive, in priority order
+var sizeOverrideSources = []string{"BITRISE_GIT_MESSAGE", "GIT_CLONE_COMMIT_MESSAGE_SUBJECT", "GIT_CLONE_COMMIT_MESSAGE_BODY"}
+
+// SizeOverride is a temporary raise of the delta allowance requested via a commit message or PR title directive
+type SizeOverride struct {
+	AllowanceBytes int64
+	Directive      string
+	Source         string
+}
+
+// FindSizeOverride looks for a size override directive in the commit message and PR title, its size is parsed in the units
+func FindSizeOverride(envRepo env.Repository, units config.Units) *SizeOverride {
+	for _, source := range sizeOverrideSources {
+		match := sizeOverridePattern.FindStringSubmatch(envRepo.Get(source))
+		if match == nil {
+			continue
+		}
+
+		allowanceBytes, err := units.ParseSize(match[1])
+		if err != nil {
+			continue
+		}
+
+		return &SizeOverride{
+			AllowanceBytes: allowanceBytes,
+			Directive:      match[0],
+			Source:         source,
+		}
+	}
+	return nil
+}
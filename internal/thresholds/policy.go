@@ -0,0 +1,169 @@
+package thresholds
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/google/cel-go/cel"
+)
+
+// Policy holds the rules of a policy file
+type Policy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// PolicyRule is a single CEL expression that marks a violation when it evaluates to true
+type PolicyRule struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Message    string `json:"message"`
+}
+
+// policyVariables are the top-level variables available to policy expressions
+var policyVariables = []string{"artifact", "metrics", "files", "native_libs", "binaries", "report", "build"}
+
+// loadPolicy reads and validates a policy file
+func loadPolicy(policyPath string) (Policy, error) {
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		return Policy{}, fmt.Errorf("failed to read policy file: %w", err)
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return Policy{}, fmt.Errorf("failed to parse policy file: %w", err)
+	}
+
+	for idx, rule := range policy.Rules {
+		if rule.Expression == "" {
+			return Policy{}, fmt.Errorf("rule #%d (%s) has no expression", idx+1, rule.Name)
+		}
+		if rule.Name == "" {
+			policy.Rules[idx].Name = fmt.Sprintf("rule #%d", idx+1)
+		}
+	}
+
+	return policy, nil
+}
+
+// policyInput builds the data model policy expressions are evaluated against
+func policyInput(artifactPath string, metrics analyze.BundleMetrics, inventory analyze.Inventory, binaries []analyze.BinaryHardening, rawReport map[string]interface{}, envRepo env.Repository) map[string]interface{} {
+	files := []interface{}{}
+	for _, entry := range inventory.Entries {
+		files = append(files, map[string]interface{}{
+			"path":              entry.Path,
+			"size":              entry.CompressedSize,
+			"uncompressed_size": entry.UncompressedSize,
+		})
+	}
+
+	nativeLibs := map[string]interface{}{}
+	for abi, size := range inventory.NativeLibSizesByABI() {
+		nativeLibs[abi] = size
+	}
+
+	hardening := []interface{}{}
+	for _, binary := range binaries {
+		hardening = append(hardening, map[string]interface{}{
+			"path":         binary.Path,
+			"format":       binary.Format,
+			"pie":          binary.PIE,
+			"stack_canary": binary.StackCanary,
+			"relro":        binary.RELRO,
+			"nx":           binary.NX,
+			"hardened":     binary.Hardened(),
+		})
+	}
+
+	if rawReport == nil {
+		rawReport = map[string]interface{}{}
+	}
+
+	return map[string]interface{}{
+		"artifact": map[string]interface{}{
+			"path":       artifactPath,
+			"size":       metrics.SizeBytes,
+			"file_count": int64(len(inventory.Entries)),
+		},
+		"metrics": map[string]interface{}{
+			"size_bytes":              metrics.SizeBytes,
+			"potential_savings_bytes": metrics.PotentialSavingsBytes,
+			"dex_method_count":        metrics.DexMethodCount,
+			"obfuscation_coverage":    metrics.ObfuscationCoverage,
+		},
+		"files":       files,
+		"native_libs": nativeLibs,
+		"binaries":    hardening,
+		"report":      rawReport,
+		"build": map[string]interface{}{
+			"branch":        envRepo.Get("BITRISE_GIT_BRANCH"),
+			"target_branch": envRepo.Get("BITRISEIO_GIT_BRANCH_DEST"),
+			"pull_request":  envRepo.Get("BITRISE_PULL_REQUEST"),
+			"commit":        envRepo.Get("BITRISE_GIT_COMMIT"),
+			"workflow":      envRepo.Get("BITRISE_TRIGGERED_WORKFLOW_ID"),
+		},
+	}
+}
+
+// evaluatePolicy evaluates every rule and returns the messages of the violated ones
+func evaluatePolicy(policy Policy, input map[string]interface{}) ([]string, error) {
+	var options []cel.EnvOption
+	for _, name := range policyVariables {
+		options = append(options, cel.Variable(name, cel.DynType))
+	}
+
+	celEnv, err := cel.NewEnv(options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create policy environment: %w", err)
+	}
+
+	var violations []string
+	for _, rule := range policy.Rules {
+		ast, issues := celEnv.Compile(rule.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("failed to compile %s: %w", rule.Name, issues.Err())
+		}
+
+		program, err := celEnv.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile %s: %w", rule.Name, err)
+		}
+
+		out, _, err := program.Eval(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate %s: %w", rule.Name, err)
+		}
+
+		violated, ok := out.Value().(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must evaluate to a bool, got %s", rule.Name, out.Type())
+		}
+
+		if violated {
+			message := rule.Message
+			if message == "" {
+				message = rule.Expression
+			}
+			violations = append(violations, fmt.Sprintf("%s: %s", rule.Name, message))
+		}
+	}
+
+	return violations, nil
+}
+
+// readRawReport reads the JSON report into a generic map
+func readRawReport(jsonPath string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(jsonPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JSON report: %w", err)
+	}
+
+	var report map[string]interface{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON report: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,91 @@
+package thresholds
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// SizeConditionKind tells how a size condition is evaluated
+type SizeConditionKind int
+
+const (
+	// SizeLimit limits the absolute bundle size
+	SizeLimit SizeConditionKind = iota
+	// IncreaseLimit limits the size increase compared to the baseline
+	IncreaseLimit
+	// IncreasePercentLimit limits the relative size increase compared to the baseline
+	IncreasePercentLimit
+)
+
+// SizeCondition is a single term of a size threshold expression
+type SizeCondition struct {
+	Kind SizeConditionKind
+	// Bytes is the size or increase limit, Percent the relative increase limit
+	Bytes   int64
+	Percent float64
+	Raw     string
+}
+
+var (
+	increasePercentPattern = regexp.MustCompile(`^\+\s*([0-9]+(?:\.[0-9]+)?)\s*%$`)
+	expressionSeparator    = regexp.MustCompile(`(?i)\s+or\s+`)
+)
+
+// parseSizeExpression parses threshold expressions like "150", "150MB or +3%" or "+500KiB":
+// a size limits the absolute size, +size and +N% limit the increase compared to the baseline.
+// Sizes are parsed in the units, a bare number is MB. The expression is violated if any of its terms is violated.
+func parseSizeExpression(expression string, units config.Units) ([]SizeCondition, error) {
+	var conditions []SizeCondition
+	for _, term := range expressionSeparator.Split(strings.TrimSpace(expression), -1) {
+		term = strings.TrimSpace(term)
+
+		if match := increasePercentPattern.FindStringSubmatch(term); match != nil {
+			percent, err := strconv.ParseFloat(match[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid term %q: %w", term, err)
+			}
+			conditions = append(conditions, SizeCondition{Kind: IncreasePercentLimit, Percent: percent, Raw: term})
+			continue
+		}
+
+		kind := SizeLimit
+		size := term
+		if strings.HasPrefix(term, "+") {
+			kind = IncreaseLimit
+			size = strings.TrimPrefix(term, "+")
+		}
+		bytes, err := units.ParseSize(size)
+		if err != nil {
+			return nil, fmt.Errorf("invalid term %q, expected a size (150MB), an increase (+5MB) or a relative increase (+3%%)", term)
+		}
+		conditions = append(conditions, SizeCondition{Kind: kind, Bytes: bytes, Raw: term})
+	}
+	return conditions, nil
+}
+
+// NeedsBaseline reports whether the condition compares against the baseline build
+func (c SizeCondition) NeedsBaseline() bool {
+	return c.Kind != SizeLimit
+}
+
+// AllowedBytes returns the maximum allowed size in bytes, relative conditions need the baseline size
+// and are extended by the override allowance.
+func (c SizeCondition) AllowedBytes(baselineBytes int64, override *SizeOverride) int64 {
+	extra := int64(0)
+	if override != nil {
+		extra = override.AllowanceBytes
+	}
+
+	switch c.Kind {
+	case IncreaseLimit:
+		return baselineBytes + c.Bytes + extra
+	case IncreasePercentLimit:
+		return baselineBytes + int64(float64(baselineBytes)*c.Percent/100) + extra
+	default:
+		return c.Bytes
+	}
+}
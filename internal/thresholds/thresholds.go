@@ -0,0 +1,804 @@
+package thresholds
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+)
+
+// Checker runs the threshold checks configured by the step inputs
+type Checker struct {
+	cfg     config.Config
+	envRepo env.Repository
+	logger  log.Logger
+}
+
+// NewChecker returns a Checker for the thresholds of the configuration
+func NewChecker(cfg config.Config, envRepo env.Repository, logger log.Logger) Checker {
+	return Checker{cfg: cfg, envRepo: envRepo, logger: logger}
+}
+
+// Check runs every configured threshold check on an analyzed artifact and returns the violations
+func (c Checker) Check(result analyze.ArtifactResult, override *SizeOverride) []analyze.ThresholdViolation {
+	cfg, logger := c.cfg, c.logger
+	var violations []analyze.ThresholdViolation
+	metrics := result.Metrics
+
+	// Check size threshold
+	if (cfg.FailOnLargeSize != "" || cfg.ArtifactThresholds != "") && metrics.SizeBytes > 0 {
+		logger.Println()
+		if err := checkSizeThreshold(cfg, result.Name, metrics.SizeBytes, result.Comparison, override, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_large_size", Err: err})
+		}
+	}
+
+	// Check per-ABI native library thresholds
+	if cfg.ABISizeThresholds != "" {
+		logger.Println()
+		if err := checkABIThresholds(cfg, result.Inventory, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "abi_size_thresholds", Err: err})
+		}
+	}
+
+	// Check uncompressed native library size threshold
+	if cfg.FailOnNativeLibs != "" {
+		logger.Println()
+		if err := checkNativeLibUncompressedThreshold(cfg, result.Inventory, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_native_lib_uncompressed_size", Err: err})
+		}
+	}
+
+	// Check DEX method count threshold
+	if cfg.FailOnDexMethods != "" && len(result.DexMethodCounts) > 0 {
+		logger.Println()
+		if err := checkDexMethodThreshold(cfg, metrics.DexMethodCount, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_dex_method_count", Err: err})
+		}
+	}
+
+	// Check file count threshold
+	if cfg.FailOnFileCount != "" && metrics.FileCount > 0 {
+		logger.Println()
+		if err := checkFileCountThreshold(cfg, metrics.FileCount, result.Comparison, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_file_count", Err: err})
+		}
+	}
+
+	if cfg.FailOnObfuscationBelow != "" && metrics.ObfuscationMeasured {
+		logger.Println()
+		if err := checkObfuscationCoverage(cfg, metrics.ObfuscationCoverage, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_obfuscation_coverage_below", Err: err})
+		}
+	}
+
+	// Check single file size threshold
+	if cfg.FailOnFileSize != "" && len(result.Inventory.Entries) > 0 {
+		logger.Println()
+		if err := checkSingleFileThreshold(cfg, result.Inventory, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_single_file_size", Err: err})
+		}
+	}
+
+	// Check module budgets
+	if len(result.ModuleResults) > 0 {
+		logger.Println()
+		if err := checkModuleBudgets(result.ModuleResults, c.cfg.Units(), logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "module_budgets", Err: err})
+		}
+	}
+
+	// Check size increase threshold
+	if cfg.FailOnIncrease != "" && metrics.SizeBytes > 0 {
+		logger.Println()
+		if err := checkSizeIncreaseThreshold(cfg, result.Comparison, override, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_size_increase", Err: err})
+		}
+	}
+
+	// Check ratcheted size budget
+	if cfg.BudgetRatchet && metrics.SizeBytes > 0 {
+		logger.Println()
+		if err := checkRatchetThreshold(cfg, metrics.SizeBytes, result.RatchetRecord, override, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "budget_ratchet", Err: err})
+		}
+	}
+
+	// Check duplicate content waste threshold, hashing every entry is skipped by the quick analysis_scope
+	if cfg.FailOnDuplicates != "" && cfg.QuickAnalysis() {
+		logger.Println()
+		logger.Warnf("fail_on_duplicate_waste skipped with analysis_scope %s", cfg.AnalysisScope)
+	} else if cfg.FailOnDuplicates != "" {
+		logger.Println()
+		if err := checkDuplicateWasteThreshold(cfg, result.ArtifactPath, result.Scope, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_duplicate_waste", Err: err})
+		}
+	}
+
+	// Check secrets found in the artifact
+	if cfg.FailOnSecrets && result.SecretsScanned {
+		logger.Println()
+		if err := checkSecrets(result.Secrets, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_secrets", Err: err})
+		}
+	}
+
+	// Check SDK versions with known vulnerabilities
+	if cfg.FailOnVulnerableSDKs != "" && result.SDKsScanned {
+		logger.Println()
+		if err := checkVulnerableSDKs(cfg, result.VulnerableSDKs, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_vulnerable_sdk_severity", Err: err})
+		}
+	}
+
+	// Check denied licenses
+	if cfg.DeniedLicenses != "" && result.LicensesRead {
+		logger.Println()
+		if err := checkDeniedLicenses(cfg, result.Licenses, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "denied_licenses", Err: err})
+		}
+	}
+
+	// Check the signature
+	if cfg.FailOnUnsigned && result.SignatureVerified {
+		logger.Println()
+		if err := checkSignature(result.Signature, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_unsigned", Err: err})
+		}
+	}
+
+	// Check the distribution type of the provisioning profile
+	if cfg.ExpectedDistribution != "" && result.ProfileRead {
+		logger.Println()
+		if err := checkDistribution(cfg.ExpectedDistribution, result.Profile, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "expected_distribution", Err: err})
+		}
+	}
+
+	// Check the privacy manifests
+	if cfg.FailOnPrivacyManifest && result.PrivacyChecked {
+		logger.Println()
+		if err := checkPrivacyManifests(result.Privacy.Findings, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_privacy_manifest_issues", Err: err})
+		}
+	}
+
+	if cfg.FailOnNewPermissions && result.PermissionsRead {
+		logger.Println()
+		if err := checkNewPermissions(cfg, result.Comparison, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_new_sensitive_permissions", Err: err})
+		}
+	}
+
+	if cfg.FailOnDebugArtifacts && result.DebugChecked {
+		logger.Println()
+		if err := checkDebugArtifacts(result.DebugFindings, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_debug_artifacts", Err: err})
+		}
+	}
+
+	if cfg.FailOnUnhardened != "" && result.HardeningChecked {
+		logger.Println()
+		if err := checkUnhardenedBinaries(cfg, result.Hardening, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_unhardened_binaries", Err: err})
+		}
+	}
+
+	if cfg.FailOnHashReputation && result.ReputationChecked {
+		logger.Println()
+		if err := checkReputation(result.Reputation, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "fail_on_hash_reputation", Err: err})
+		}
+	}
+
+	// Evaluate policy rules
+	if cfg.PolicyFile != "" {
+		logger.Println()
+		if err := checkPolicy(cfg, result.ArtifactPath, metrics, result.Inventory, result.Hardening, result.GeneratedFiles, c.envRepo, logger); err != nil {
+			violations = append(violations, analyze.ThresholdViolation{Check: "policy_file", Err: err})
+		}
+	}
+
+	return violations
+}
+
+// sizeThresholdFor returns the size threshold of an artifact and the input it comes from.
+// The first artifact_thresholds pattern matching the artifact name wins, fail_on_large_size is the fallback.
+func sizeThresholdFor(cfg config.Config, artifactName string, logger log.Logger) (string, string) {
+	if cfg.ArtifactThresholds != "" {
+		thresholds, err := config.ParseKeyValueLines(cfg.ArtifactThresholds)
+		if err != nil {
+			logger.Warnf("Invalid artifact_thresholds value: %s", err)
+		}
+
+		for _, threshold := range thresholds {
+			matched, err := filepath.Match(threshold.Key, artifactName)
+			if err != nil {
+				logger.Warnf("Invalid artifact_thresholds pattern: %s", threshold.Key)
+				continue
+			}
+			if matched {
+				logger.Printf("Using threshold of pattern %s for %s", threshold.Key, artifactName)
+				return threshold.Value, "artifact_thresholds"
+			}
+		}
+	}
+
+	return cfg.FailOnLargeSize, "fail_on_large_size"
+}
+
+// checkSizeThreshold validates the bundle size against the configured threshold expression
+func checkSizeThreshold(cfg config.Config, artifactName string, sizeBytes int64, comparison *analyze.BaselineComparison, override *SizeOverride, logger log.Logger) error {
+	threshold, input := sizeThresholdFor(cfg, artifactName, logger)
+	if threshold == "" {
+		return nil
+	}
+
+	units := cfg.Units()
+	conditions, err := parseSizeExpression(threshold, units)
+	if err != nil {
+		logger.Warnf("Invalid %s value: %s", input, err)
+		return nil
+	}
+
+	var violations []string
+	for _, condition := range conditions {
+		baselineBytes := int64(0)
+		if condition.NeedsBaseline() {
+			if comparison == nil {
+				logger.Infof("No baseline build available, skipping size threshold term %s", condition.Raw)
+				continue
+			}
+			baselineBytes = comparison.Baseline.SizeBytes
+		}
+
+		allowedBytes := condition.AllowedBytes(baselineBytes, override)
+
+		if !condition.NeedsBaseline() {
+			logger.Infof("Checking size threshold: %s / %s", units.Format(sizeBytes), units.Format(allowedBytes))
+			if sizeBytes > allowedBytes {
+				violations = append(violations, fmt.Sprintf("bundle size %s exceeds threshold %s", units.Format(sizeBytes), units.Format(allowedBytes)))
+			}
+			continue
+		}
+
+		logger.Infof("Checking size threshold %s: %s / %s (baseline build #%s %s)", condition.Raw, units.Format(sizeBytes), units.Format(allowedBytes),
+			comparison.Baseline.BuildNumber, units.Format(baselineBytes))
+		if sizeBytes > allowedBytes {
+			violations = append(violations, fmt.Sprintf("bundle size %s exceeds threshold %s (%s) compared to baseline build #%s",
+				units.Format(sizeBytes), condition.Raw, units.Format(allowedBytes), comparison.Baseline.BuildNumber))
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%s", strings.Join(violations, ", "))
+	}
+
+	logger.Donef("Bundle size is within threshold")
+	return nil
+}
+
+// checkABIThresholds validates the native library size of each configured ABI
+func checkABIThresholds(cfg config.Config, inventory analyze.Inventory, logger log.Logger) error {
+	thresholds, err := config.ParseKeyValueLines(cfg.ABISizeThresholds)
+	if err != nil {
+		logger.Warnf("Invalid abi_size_thresholds value: %s", err)
+		return nil
+	}
+
+	sizes := inventory.NativeLibSizesByABI()
+	if len(sizes) == 0 {
+		logger.Warnf("No native libraries found in the artifact, skipping per-ABI thresholds")
+		return nil
+	}
+
+	units := cfg.Units()
+	logger.Infof("Checking per-ABI native library thresholds:")
+	for _, abi := range analyze.SortedKeys(sizes) {
+		logger.Printf("- %s: %s", abi, units.Format(sizes[abi]))
+	}
+
+	var violations []string
+	for _, threshold := range thresholds {
+		thresholdBytes, err := units.ParseSize(threshold.Value)
+		if err != nil {
+			logger.Warnf("Invalid abi_size_thresholds value for %s: %s", threshold.Key, err)
+			continue
+		}
+
+		sizeBytes, ok := sizes[threshold.Key]
+		if !ok {
+			logger.Warnf("No native libraries found for ABI %s", threshold.Key)
+			continue
+		}
+
+		if sizeBytes > thresholdBytes {
+			violations = append(violations, fmt.Sprintf("%s native libraries %s exceed threshold %s", threshold.Key, units.Format(sizeBytes),
+				units.Format(thresholdBytes)))
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("per-ABI threshold exceeded:\n- %s", strings.Join(violations, "\n- "))
+	}
+
+	logger.Donef("Native libraries are within per-ABI thresholds")
+	return nil
+}
+
+// checkNativeLibUncompressedThreshold validates the extracted size of all native libraries against the configured threshold.
+// The stored size depends on android:extractNativeLibs, the uncompressed size is what ends up on the device.
+func checkNativeLibUncompressedThreshold(cfg config.Config, inventory analyze.Inventory, logger log.Logger) error {
+	units := cfg.Units()
+	thresholdBytes, err := units.ParseSize(cfg.FailOnNativeLibs)
+	if err != nil {
+		logger.Warnf("Invalid fail_on_native_lib_uncompressed_size value: %s", err)
+		return nil
+	}
+
+	sizes := inventory.NativeLibUncompressedSizesByABI()
+	if len(sizes) == 0 {
+		logger.Warnf("No native libraries found in the artifact, skipping uncompressed native library threshold")
+		return nil
+	}
+
+	var totalBytes int64
+	for _, size := range sizes {
+		totalBytes += size
+	}
+
+	logger.Infof("Checking uncompressed native library size threshold: %s / %s", units.Format(totalBytes), units.Format(thresholdBytes))
+	for _, abi := range analyze.SortedKeys(sizes) {
+		logger.Printf("- %s: %s", abi, units.Format(sizes[abi]))
+	}
+
+	if totalBytes > thresholdBytes {
+		return fmt.Errorf("uncompressed native library size %s exceeds threshold %s", units.Format(totalBytes), units.Format(thresholdBytes))
+	}
+
+	logger.Donef("Uncompressed native library size is within threshold")
+	return nil
+}
+
+// checkDexMethodThreshold validates the total DEX method reference count against the configured threshold
+func checkDexMethodThreshold(cfg config.Config, methodCount int64, logger log.Logger) error {
+	threshold, err := strconv.ParseInt(cfg.FailOnDexMethods, 10, 64)
+	if err != nil {
+		logger.Warnf("Invalid fail_on_dex_method_count value: %s", cfg.FailOnDexMethods)
+		return nil
+	}
+
+	logger.Infof("Checking DEX method count threshold: %d / %d", methodCount, threshold)
+
+	if methodCount > threshold {
+		return fmt.Errorf("DEX method count %d exceeds threshold %d", methodCount, threshold)
+	}
+
+	logger.Donef("DEX method count is within threshold")
+	return nil
+}
+
+// checkObfuscationCoverage fails if fewer DEX classes than required have obfuscated names
+func checkObfuscationCoverage(cfg config.Config, coverage float64, logger log.Logger) error {
+	threshold, err := strconv.ParseFloat(cfg.FailOnObfuscationBelow, 64)
+	if err != nil || threshold < 0 || threshold > 100 {
+		logger.Warnf("Invalid fail_on_obfuscation_coverage_below value: %s", cfg.FailOnObfuscationBelow)
+		return nil
+	}
+
+	logger.Infof("Checking obfuscation coverage: %.1f%% / %.1f%%", coverage, threshold)
+	if coverage < threshold {
+		return fmt.Errorf("obfuscation coverage %.1f%% is below threshold %.1f%%", coverage, threshold)
+	}
+
+	logger.Donef("Obfuscation coverage is within threshold")
+	return nil
+}
+
+// checkFileCountThreshold validates the number of files in the artifact against the configured threshold
+func checkFileCountThreshold(cfg config.Config, fileCount int64, comparison *analyze.BaselineComparison, logger log.Logger) error {
+	threshold, err := strconv.ParseInt(cfg.FailOnFileCount, 10, 64)
+	if err != nil {
+		logger.Warnf("Invalid fail_on_file_count value: %s", cfg.FailOnFileCount)
+		return nil
+	}
+
+	delta := ""
+	if comparison != nil {
+		delta = fmt.Sprintf(" (%+d vs baseline)", comparison.FileCountDelta)
+	}
+
+	logger.Infof("Checking file count threshold: %d%s / %d", fileCount, delta, threshold)
+
+	if fileCount > threshold {
+		return fmt.Errorf("file count %d%s exceeds threshold %d", fileCount, delta, threshold)
+	}
+
+	logger.Donef("File count is within threshold")
+	return nil
+}
+
+// checkSingleFileThreshold validates that no file in the artifact exceeds the configured size
+func checkSingleFileThreshold(cfg config.Config, inventory analyze.Inventory, logger log.Logger) error {
+	units := cfg.Units()
+	thresholdBytes, err := units.ParseSize(cfg.FailOnFileSize)
+	if err != nil {
+		logger.Warnf("Invalid fail_on_single_file_size value: %s", err)
+		return nil
+	}
+
+	logger.Infof("Checking single file size threshold: %s", units.Format(thresholdBytes))
+
+	oversized := inventory.EntriesLargerThan(thresholdBytes)
+	if len(oversized) > 0 {
+		var files []string
+		for _, entry := range oversized {
+			files = append(files, fmt.Sprintf("%s (%s)", entry.Path, units.Format(entry.UncompressedSize)))
+		}
+		return fmt.Errorf("%d file(s) exceed the single file threshold %s:\n- %s", len(oversized), units.Format(thresholdBytes), strings.Join(files, "\n- "))
+	}
+
+	logger.Donef("All files are within the single file threshold")
+	return nil
+}
+
+// checkSizeIncreaseThreshold validates the size increase compared to the baseline build against the configured threshold
+func checkSizeIncreaseThreshold(cfg config.Config, comparison *analyze.BaselineComparison, override *SizeOverride, logger log.Logger) error {
+	units := cfg.Units()
+	allowedBytes, err := units.ParseSize(cfg.FailOnIncrease)
+	if err != nil {
+		logger.Warnf("Invalid fail_on_size_increase value: %s", err)
+		return nil
+	}
+	if comparison == nil {
+		logger.Infof("No baseline build available, skipping size increase check")
+		return nil
+	}
+
+	if override != nil {
+		logger.Warnf("Raising size increase allowance by %s due to %s", units.Format(override.AllowanceBytes), override.Directive)
+		allowedBytes += override.AllowanceBytes
+	}
+
+	logger.Infof("Checking size increase threshold: %s / %s (baseline build #%s)", units.FormatDelta(comparison.SizeDeltaBytes), units.Format(allowedBytes), comparison.Baseline.BuildNumber)
+
+	if comparison.SizeDeltaBytes > allowedBytes {
+		return fmt.Errorf("bundle size increased by %s compared to baseline build #%s, exceeding allowed increase %s",
+			units.Format(comparison.SizeDeltaBytes), comparison.Baseline.BuildNumber, units.Format(allowedBytes))
+	}
+
+	logger.Donef("Bundle size increase is within threshold")
+	return nil
+}
+
+// checkRatchetThreshold validates the bundle size against the smallest size recorded on the baseline branch plus the tolerance
+func checkRatchetThreshold(cfg config.Config, sizeBytes int64, smallest *analyze.HistoryRecord, override *SizeOverride, logger log.Logger) error {
+	if cfg.HistoryFile == "" {
+		logger.Warnf("budget_ratchet requires history_file, skipping ratchet check")
+		return nil
+	}
+	if smallest == nil {
+		logger.Infof("No builds recorded for branch %s yet, skipping ratchet check", cfg.BaselineBranch)
+		return nil
+	}
+
+	units := cfg.Units()
+	toleranceBytes := int64(0)
+	if cfg.RatchetTolerance != "" {
+		var err error
+		toleranceBytes, err = units.ParseSize(cfg.RatchetTolerance)
+		if err != nil {
+			logger.Warnf("Invalid budget_ratchet_tolerance value: %s", err)
+			toleranceBytes = 0
+		}
+	}
+
+	if override != nil {
+		logger.Warnf("Raising ratchet tolerance by %s due to %s", units.Format(override.AllowanceBytes), override.Directive)
+		toleranceBytes += override.AllowanceBytes
+	}
+
+	thresholdBytes := smallest.SizeBytes + toleranceBytes
+
+	logger.Infof("Checking ratcheted size budget: %s / %s", units.Format(sizeBytes), units.Format(thresholdBytes))
+	logger.Printf("Smallest size on %s: %s (build #%s), tolerance: %s", cfg.BaselineBranch,
+		units.Format(smallest.SizeBytes), smallest.BuildNumber, units.Format(toleranceBytes))
+
+	if sizeBytes > thresholdBytes {
+		return fmt.Errorf("bundle size %s exceeds ratcheted budget %s (smallest %s build %s + %s tolerance)",
+			units.Format(sizeBytes), units.Format(thresholdBytes), cfg.BaselineBranch, units.Format(smallest.SizeBytes), units.Format(toleranceBytes))
+	}
+
+	logger.Donef("Bundle size is within ratcheted budget")
+	return nil
+}
+
+// checkDuplicateWasteThreshold validates the bytes wasted on byte-identical files against the configured threshold
+func checkDuplicateWasteThreshold(cfg config.Config, artifactPath string, scope analyze.EntryScope, logger log.Logger) error {
+	units := cfg.Units()
+	thresholdBytes, err := units.ParseSize(cfg.FailOnDuplicates)
+	if err != nil {
+		logger.Warnf("Invalid fail_on_duplicate_waste value: %s", err)
+		return nil
+	}
+
+	logger.Infof("Detecting duplicate files...")
+	sets, err := analyze.FindDuplicates(artifactPath, scope, logger)
+	if err != nil {
+		logger.Warnf("Failed to detect duplicate files: %s", err)
+		return nil
+	}
+
+	for idx, set := range sets {
+		if idx == 10 {
+			logger.Printf("... and %d more duplicate set(s)", len(sets)-idx)
+			break
+		}
+		logger.Printf("- %d copies, %s wasted: %s", len(set.Paths), units.Format(set.WastedBytes), strings.Join(set.Paths, ", "))
+	}
+
+	wastedBytes := analyze.TotalWastedBytes(sets)
+
+	logger.Infof("Checking duplicate waste threshold: %s / %s", units.Format(wastedBytes), units.Format(thresholdBytes))
+
+	if wastedBytes > thresholdBytes {
+		return fmt.Errorf("duplicate files waste %s, exceeding threshold %s", units.Format(wastedBytes), units.Format(thresholdBytes))
+	}
+
+	logger.Donef("Duplicate waste is within threshold")
+	return nil
+}
+
+// checkSecrets fails if any secret was found in the artifact
+func checkSecrets(findings []analyze.SecretFinding, logger log.Logger) error {
+	logger.Infof("Checking for secrets: %d finding(s)", len(findings))
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d potential secret(s) in the artifact", len(findings))
+	}
+
+	logger.Donef("No secrets found in the artifact")
+	return nil
+}
+
+// checkVulnerableSDKs fails if a bundled SDK version has a known vulnerability of at least the configured severity
+func checkVulnerableSDKs(cfg config.Config, vulnerable []analyze.VulnerableSDK, logger log.Logger) error {
+	if !analyze.IsValidSeverity(cfg.FailOnVulnerableSDKs) {
+		logger.Warnf("Invalid fail_on_vulnerable_sdk_severity value: %s", cfg.FailOnVulnerableSDKs)
+		return nil
+	}
+
+	var failing []string
+	for _, match := range vulnerable {
+		if analyze.SeverityAtLeast(match.Advisory.Severity, cfg.FailOnVulnerableSDKs) {
+			failing = append(failing, fmt.Sprintf("- %s %s: %s (%s)", match.SDK.Name, match.SDK.Version, match.Advisory.ID, match.Advisory.Severity))
+		}
+	}
+
+	logger.Infof("Checking vulnerable SDKs: %d finding(s) of %s or higher severity", len(failing), strings.ToLower(cfg.FailOnVulnerableSDKs))
+	if len(failing) > 0 {
+		return fmt.Errorf("%d vulnerable SDK version(s) of %s or higher severity:\n%s", len(failing), strings.ToLower(cfg.FailOnVulnerableSDKs), strings.Join(failing, "\n"))
+	}
+
+	logger.Donef("No vulnerable SDK version of %s or higher severity", strings.ToLower(cfg.FailOnVulnerableSDKs))
+	return nil
+}
+
+// checkDeniedLicenses fails if a component of the artifact is shipped under a denied license
+func checkDeniedLicenses(cfg config.Config, licenses []analyze.LicenseEntry, logger log.Logger) error {
+	denied := analyze.ParseDeniedLicenses(cfg.DeniedLicenses)
+	logger.Infof("Checking licenses against denied licenses: %s", strings.Join(denied, ", "))
+
+	var violations []string
+	for _, entry := range licenses {
+		if entry.Denied(denied) {
+			violations = append(violations, fmt.Sprintf("- %s: %s (%s)", entry.Component, entry.License, entry.Source))
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%d component(s) shipped under a denied license:\n%s", len(violations), strings.Join(violations, "\n"))
+	}
+
+	logger.Donef("No denied license found")
+	return nil
+}
+
+// checkSignature fails if the artifact is unsigned, debug-signed or its signature is invalid
+func checkSignature(signature analyze.SignatureInfo, logger log.Logger) error {
+	logger.Infof("Checking signature: %s", signature.Status)
+	if signature.Status != analyze.SignatureSigned {
+		return fmt.Errorf("%s: %s", signature.Describe(), strings.Join(signature.Problems, "; "))
+	}
+
+	logger.Donef("Artifact is signed by %s", signature.Signer)
+	return nil
+}
+
+// checkDistribution fails if the provisioning profile isn't of the expected distribution type
+func checkDistribution(expected string, profile analyze.ProvisioningProfile, logger log.Logger) error {
+	logger.Infof("Checking provisioning profile: %s (expected %s)", profile.Type, expected)
+	if profile.Type != expected {
+		return fmt.Errorf("provisioning profile %s has the distribution type %s, expected %s", profile.Name, profile.Type, expected)
+	}
+
+	logger.Donef("Provisioning profile has the expected distribution type")
+	return nil
+}
+
+// checkPrivacyManifests fails if the privacy manifests of the IPA are missing or incomplete
+func checkPrivacyManifests(findings []analyze.PrivacyFinding, logger log.Logger) error {
+	logger.Infof("Checking privacy manifests: %d issue(s)", len(findings))
+	if len(findings) > 0 {
+		var issues []string
+		for _, finding := range findings {
+			issues = append(issues, fmt.Sprintf("- %s: %s", finding.Bundle, finding.Issue))
+		}
+		return fmt.Errorf("%d privacy manifest issue(s):\n%s", len(findings), strings.Join(issues, "\n"))
+	}
+
+	logger.Donef("Privacy manifests are complete")
+	return nil
+}
+
+// checkNewPermissions fails if sensitive permissions were added since the baseline build
+func checkNewPermissions(cfg config.Config, comparison *analyze.BaselineComparison, logger log.Logger) error {
+	if cfg.HistoryFile == "" {
+		logger.Warnf("fail_on_new_sensitive_permissions requires history_file, skipping permission check")
+		return nil
+	}
+	if comparison == nil || comparison.Permissions == nil {
+		logger.Infof("No permissions recorded for the baseline build yet, skipping permission check")
+		return nil
+	}
+
+	diff, baseline := comparison.Permissions, comparison.Baseline
+	logger.Infof("Checking new sensitive permissions since build #%s: %d found", baseline.BuildNumber, len(diff.SensitiveAdded))
+	if len(diff.SensitiveAdded) > 0 {
+		return fmt.Errorf("%d sensitive permission(s) added since build #%s:\n- %s", len(diff.SensitiveAdded), baseline.BuildNumber, strings.Join(diff.SensitiveAdded, "\n- "))
+	}
+
+	logger.Donef("No sensitive permissions were added")
+	return nil
+}
+
+// checkDebugArtifacts fails if the artifact shows signs of a debug build
+func checkDebugArtifacts(findings []analyze.DebugFinding, logger log.Logger) error {
+	logger.Infof("Checking for debug artifacts: %d found", len(findings))
+	if len(findings) > 0 {
+		var details []string
+		for _, finding := range findings {
+			details = append(details, fmt.Sprintf("- %s: %s", finding.Kind, finding.Detail))
+		}
+		return fmt.Errorf("the artifact looks like a debug build:\n%s", strings.Join(details, "\n"))
+	}
+
+	logger.Donef("The artifact is a release build")
+	return nil
+}
+
+// checkUnhardenedBinaries fails if more native binaries than allowed miss an exploit mitigation
+func checkUnhardenedBinaries(cfg config.Config, binaries []analyze.BinaryHardening, logger log.Logger) error {
+	threshold, err := strconv.ParseInt(cfg.FailOnUnhardened, 10, 64)
+	if err != nil || threshold < 0 {
+		logger.Warnf("Invalid fail_on_unhardened_binaries value: %s", cfg.FailOnUnhardened)
+		return nil
+	}
+
+	var unhardened []string
+	for _, binary := range binaries {
+		if !binary.Hardened() {
+			unhardened = append(unhardened, fmt.Sprintf("%s (missing %s)", binary.Path, strings.Join(binary.Missing(), ", ")))
+		}
+	}
+
+	logger.Infof("Checking unhardened native binaries: %d / %d", len(unhardened), threshold)
+	if int64(len(unhardened)) > threshold {
+		return fmt.Errorf("%d unhardened native binaries exceed threshold %d:\n- %s", len(unhardened), threshold, strings.Join(unhardened, "\n- "))
+	}
+
+	logger.Donef("Unhardened native binaries are within threshold")
+	return nil
+}
+
+// checkReputation fails if a native binary is denied, unlisted or has a bad reputation
+func checkReputation(binaries []analyze.BinaryReputation, logger log.Logger) error {
+	var flagged []string
+	for _, binary := range binaries {
+		if binary.Flagged() {
+			flagged = append(flagged, fmt.Sprintf("- %s: %s (%s)", binary.Path, binary.Verdict, binary.Source))
+		}
+	}
+	logger.Infof("Checking native binary reputation: %d of %d flagged", len(flagged), len(binaries))
+	if len(flagged) > 0 {
+		return fmt.Errorf("%d native binaries flagged by the screening:\n%s", len(flagged), strings.Join(flagged, "\n"))
+	}
+
+	logger.Donef("No native binary flagged")
+	return nil
+}
+
+// checkModuleBudgets fails if any module is over its budget
+func checkModuleBudgets(results []analyze.ModuleBudgetResult, units config.Units, logger log.Logger) error {
+	var violations []string
+	for _, result := range results {
+		if result.Exceeded() {
+			violations = append(violations, fmt.Sprintf("%s (%s) %s exceeds budget %s", result.Name, result.Prefix,
+				units.Format(result.SizeBytes), units.Format(result.BudgetBytes)))
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("module budget exceeded:\n- %s", strings.Join(violations, "\n- "))
+	}
+
+	logger.Donef("All modules are within budget")
+	return nil
+}
+
+// checkPolicy evaluates the rules of the configured policy file against the analysis results
+func checkPolicy(cfg config.Config, artifactPath string, metrics analyze.BundleMetrics, inventory analyze.Inventory, binaries []analyze.BinaryHardening, generatedFiles analyze.ReportPaths, envRepo env.Repository, logger log.Logger) error {
+	policy, err := loadPolicy(cfg.PolicyFile)
+	if err != nil {
+		return err
+	}
+
+	var rawReport map[string]interface{}
+	if generatedFiles.JSON != "" {
+		rawReport, err = readRawReport(generatedFiles.JSON)
+		if err != nil {
+			logger.Warnf("Failed to read JSON report for policy evaluation: %s", err)
+		}
+	} else {
+		logger.Warnf("JSON report not generated, the report variable will be empty in policy rules")
+	}
+
+	logger.Infof("Evaluating %d policy rule(s) from %s", len(policy.Rules), cfg.PolicyFile)
+
+	violations, err := evaluatePolicy(policy, policyInput(artifactPath, metrics, inventory, binaries, rawReport, envRepo))
+	if err != nil {
+		return fmt.Errorf("policy evaluation failed: %w", err)
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("policy violated:\n- %s", strings.Join(violations, "\n- "))
+	}
+
+	logger.Donef("All policy rules passed")
+	return nil
+}
+
+// ApplyGracePeriod downgrades violations to warnings until they were recorded for grace_builds consecutive builds
+// of the artifact on the current branch, and returns the violations that should fail the build
+func (c Checker) ApplyGracePeriod(violations []analyze.ThresholdViolation, history *analyze.History, artifact string) []analyze.ThresholdViolation {
+	cfg, logger := c.cfg, c.logger
+	if cfg.GraceBuilds == "" || len(violations) == 0 {
+		return violations
+	}
+
+	graceBuilds, err := strconv.Atoi(cfg.GraceBuilds)
+	if err != nil || graceBuilds < 0 {
+		logger.Warnf("Invalid grace_builds value: %s", cfg.GraceBuilds)
+		return violations
+	}
+	if history == nil {
+		logger.Warnf("grace_builds requires history_file, skipping grace period")
+		return violations
+	}
+
+	branch := c.envRepo.Get("BITRISE_GIT_BRANCH")
+
+	var failing []analyze.ThresholdViolation
+	for _, violation := range violations {
+		previous := history.ConsecutiveViolations(branch, artifact, violation.Check)
+		if previous < graceBuilds {
+			logger.Warnf("%s (grace period: build %d of %d, the build will fail once the grace period is over)", violation.Err, previous+1, graceBuilds)
+			continue
+		}
+		failing = append(failing, violation)
+	}
+	return failing
+}
@@ -0,0 +1,267 @@
+package thresholds
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// fakeEnvRepository is an env.Repository backed by a map
+type fakeEnvRepository map[string]string
+
+func (r fakeEnvRepository) List() []string {
+	var envs []string
+	for key, value := range r {
+		envs = append(envs, key+"="+value)
+	}
+	return envs
+}
+
+func (r fakeEnvRepository) Unset(key string) error {
+	delete(r, key)
+	return nil
+}
+
+func (r fakeEnvRepository) Get(key string) string {
+	return r[key]
+}
+
+func (r fakeEnvRepository) Set(key, value string) error {
+	r[key] = value
+	return nil
+}
+
+const mb = 1024 * 1024
+
+// fakePluginFactory answers the bitrise commands like a bundle-inspector plugin with --machine-output writing the
+// markdown report, the analyses are counted
+type fakePluginFactory struct {
+	analyses *int
+}
+
+func (f fakePluginFactory) Create(name string, args []string, opts *command.Opts) command.Command {
+	return fakePluginCommand{factory: f, args: args, opts: opts}
+}
+
+type fakePluginCommand struct {
+	factory fakePluginFactory
+	args    []string
+	opts    *command.Opts
+}
+
+func (c fakePluginCommand) PrintableCommandArgs() string {
+	return "bitrise " + strings.Join(c.args, " ")
+}
+func (c fakePluginCommand) Run() error   { _, err := c.RunAndReturnTrimmedCombinedOutput(); return err }
+func (c fakePluginCommand) Start() error { return c.Run() }
+func (c fakePluginCommand) Wait() error  { return nil }
+func (c fakePluginCommand) RunAndReturnTrimmedOutput() (string, error) {
+	return c.RunAndReturnTrimmedCombinedOutput()
+}
+
+func (c fakePluginCommand) RunAndReturnExitCode() (int, error) {
+	if err := c.Run(); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func (c fakePluginCommand) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	switch {
+	case c.args[0] == "plugin":
+		return "⚡️ bundle-inspector (1.4.0)", nil
+	case c.args[2] == "--help":
+		return "Flags:\n  --machine-output", nil
+	}
+	*c.factory.analyses++
+	reportPath := filepath.Join(c.opts.Dir, analyze.ReportName(c.args[2], ".md"))
+	if err := os.WriteFile(reportPath, []byte("# Report"), 0644); err != nil {
+		return "", err
+	}
+	_, err := fmt.Fprintf(c.opts.Stdout, `{"schema_version": 1, "files": {"markdown": %q}, "metrics": {"size_bytes": %d, "potential_savings_bytes": 0}}`, reportPath, 50*mb)
+	return "", err
+}
+
+func TestCheckerCheck(t *testing.T) {
+	inventory := analyze.Inventory{Entries: []analyze.ArchiveEntry{
+		{Path: "lib/arm64-v8a/libapp.so", CompressedSize: 6 * mb, UncompressedSize: 12 * mb},
+		{Path: "lib/armeabi-v7a/libapp.so", CompressedSize: 2 * mb, UncompressedSize: 4 * mb},
+		{Path: "assets/video.mp4", CompressedSize: 3 * mb, UncompressedSize: 3 * mb},
+	}}
+	baseline := &analyze.BaselineComparison{Baseline: analyze.HistoryRecord{BuildNumber: "41", SizeBytes: 40 * mb}, SizeDeltaBytes: 10 * mb}
+
+	tests := []struct {
+		name       string
+		envs       fakeEnvRepository
+		result     analyze.ArtifactResult
+		override   *SizeOverride
+		wantChecks []string
+		wantErr    string
+	}{
+		{name: "no thresholds", envs: fakeEnvRepository{}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}}},
+		{name: "size within threshold", envs: fakeEnvRepository{"fail_on_large_size": "60"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}}},
+		{name: "size above threshold", envs: fakeEnvRepository{"fail_on_large_size": "40MB"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}},
+			wantChecks: []string{"fail_on_large_size"}, wantErr: "exceeds threshold"},
+		{name: "artifact pattern wins", envs: fakeEnvRepository{"fail_on_large_size": "100", "artifact_thresholds": "*.apk: 40\n*.aab: 200"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}},
+			wantChecks: []string{"fail_on_large_size"}},
+		{name: "relative size without baseline", envs: fakeEnvRepository{"fail_on_large_size": "+5%"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}}},
+		{name: "relative size above baseline", envs: fakeEnvRepository{"fail_on_large_size": "100MB or +5%"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}, Comparison: baseline},
+			wantChecks: []string{"fail_on_large_size"}, wantErr: "compared to baseline build #41"},
+		{name: "size increase", envs: fakeEnvRepository{"fail_on_size_increase": "5MB"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}, Comparison: baseline},
+			wantChecks: []string{"fail_on_size_increase"}},
+		{name: "size increase with override", envs: fakeEnvRepository{"fail_on_size_increase": "5MB"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}, Comparison: baseline},
+			override: &SizeOverride{AllowanceBytes: 10 * mb, Directive: "[size-ok]"}},
+		{name: "per-ABI threshold", envs: fakeEnvRepository{"abi_size_thresholds": "arm64-v8a: 5\narmeabi-v7a: 5"}, result: analyze.ArtifactResult{Name: "app.apk", Inventory: inventory},
+			wantChecks: []string{"abi_size_thresholds"}, wantErr: "arm64-v8a native libraries"},
+		{name: "uncompressed native libraries", envs: fakeEnvRepository{"fail_on_native_lib_uncompressed_size": "10"}, result: analyze.ArtifactResult{Name: "app.apk", Inventory: inventory},
+			wantChecks: []string{"fail_on_native_lib_uncompressed_size"}},
+		{name: "single file and file count", envs: fakeEnvRepository{"fail_on_single_file_size": "10", "fail_on_file_count": "2"}, result: analyze.ArtifactResult{Name: "app.apk", Inventory: inventory, Metrics: analyze.BundleMetrics{FileCount: 3}},
+			wantChecks: []string{"fail_on_file_count", "fail_on_single_file_size"}},
+		{name: "privacy manifest issues", envs: fakeEnvRepository{"fail_on_privacy_manifest_issues": "true"}, result: analyze.ArtifactResult{Name: "app.ipa", PrivacyChecked: true,
+			Privacy: analyze.PrivacyReport{Findings: []analyze.PrivacyFinding{{Bundle: "Test.app", Issue: "the app has no PrivacyInfo.xcprivacy"}}}},
+			wantChecks: []string{"fail_on_privacy_manifest_issues"}, wantErr: "- Test.app: the app has no PrivacyInfo.xcprivacy"},
+		{name: "complete privacy manifests", envs: fakeEnvRepository{"fail_on_privacy_manifest_issues": "true"}, result: analyze.ArtifactResult{Name: "app.ipa", PrivacyChecked: true}},
+		{name: "new sensitive permissions", envs: fakeEnvRepository{"fail_on_new_sensitive_permissions": "true", "history_file": "history.json"}, result: analyze.ArtifactResult{Name: "app.apk", PermissionsRead: true,
+			Comparison: &analyze.BaselineComparison{Baseline: baseline.Baseline, Permissions: &analyze.PermissionDiff{SensitiveAdded: []string{"android.permission.CAMERA"}}}},
+			wantChecks: []string{"fail_on_new_sensitive_permissions"}, wantErr: "1 sensitive permission(s) added since build #41:\n- android.permission.CAMERA"},
+		{name: "new sensitive permissions without history", envs: fakeEnvRepository{"fail_on_new_sensitive_permissions": "true"}, result: analyze.ArtifactResult{Name: "app.apk", PermissionsRead: true,
+			Comparison: &analyze.BaselineComparison{Baseline: baseline.Baseline, Permissions: &analyze.PermissionDiff{SensitiveAdded: []string{"android.permission.CAMERA"}}}}},
+		{name: "debug artifacts", envs: fakeEnvRepository{"fail_on_debug_artifacts": "true"}, result: analyze.ArtifactResult{Name: "app.apk", DebugChecked: true,
+			DebugFindings: []analyze.DebugFinding{{Kind: analyze.DebugFindingDebuggable, Detail: "android:debuggable is set, anyone can attach a debugger"}}},
+			wantChecks: []string{"fail_on_debug_artifacts"}, wantErr: "- debuggable: android:debuggable is set"},
+		{name: "unhardened binaries", envs: fakeEnvRepository{"fail_on_unhardened_binaries": "0"}, result: analyze.ArtifactResult{Name: "app.apk", HardeningChecked: true,
+			Hardening: []analyze.BinaryHardening{{Path: "lib/arm64-v8a/libapp.so", PIE: true, StackCanary: true, NX: true}, {Path: "lib/arm64-v8a/libok.so", PIE: true, StackCanary: true, RELRO: true, NX: true}}},
+			wantChecks: []string{"fail_on_unhardened_binaries"}, wantErr: "1 unhardened native binaries exceed threshold 0:\n- lib/arm64-v8a/libapp.so (missing full RELRO)"},
+		{name: "unhardened binaries within threshold", envs: fakeEnvRepository{"fail_on_unhardened_binaries": "1"}, result: analyze.ArtifactResult{Name: "app.apk", HardeningChecked: true,
+			Hardening: []analyze.BinaryHardening{{Path: "lib/arm64-v8a/libapp.so", PIE: true, StackCanary: true, NX: true}}}},
+		{name: "obfuscation coverage below threshold", envs: fakeEnvRepository{"fail_on_obfuscation_coverage_below": "80"}, result: analyze.ArtifactResult{Name: "app.apk",
+			Metrics: analyze.BundleMetrics{ObfuscationMeasured: true, ObfuscationCoverage: 62.5}},
+			wantChecks: []string{"fail_on_obfuscation_coverage_below"}, wantErr: "obfuscation coverage 62.5% is below threshold 80.0%"},
+		{name: "obfuscation coverage not measured", envs: fakeEnvRepository{"fail_on_obfuscation_coverage_below": "80"}, result: analyze.ArtifactResult{Name: "app.ipa"}},
+		{name: "unexpected distribution type", envs: fakeEnvRepository{"expected_distribution": "app-store"}, result: analyze.ArtifactResult{Name: "app.ipa", ProfileRead: true,
+			Profile: analyze.ProvisioningProfile{Name: "Test Profile", Type: analyze.DistributionDevelopment}},
+			wantChecks: []string{"expected_distribution"}, wantErr: "provisioning profile Test Profile has the distribution type development, expected app-store"},
+		{name: "expected distribution type", envs: fakeEnvRepository{"expected_distribution": "app-store"}, result: analyze.ArtifactResult{Name: "app.ipa", ProfileRead: true,
+			Profile: analyze.ProvisioningProfile{Name: "Test Profile", Type: analyze.DistributionAppStore}}},
+		{name: "flagged native binaries", envs: fakeEnvRepository{"fail_on_hash_reputation": "true"}, result: analyze.ArtifactResult{Name: "app.apk", ReputationChecked: true,
+			Reputation: []analyze.BinaryReputation{{Path: "lib/arm64-v8a/libapp.so", Verdict: analyze.VerdictAllowed}, {Path: "lib/arm64-v8a/libtracker.so", Verdict: analyze.VerdictMalicious, Source: "reputation API"}}},
+			wantChecks: []string{"fail_on_hash_reputation"}, wantErr: "1 native binaries flagged by the screening:\n- lib/arm64-v8a/libtracker.so: malicious (reputation API)"},
+		// 50 MiB is 52.43 MB, above the 1000-based threshold under size_units si
+		{name: "size above SI threshold", envs: fakeEnvRepository{"fail_on_large_size": "52MB", "size_units": "si"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}},
+			wantChecks: []string{"fail_on_large_size"}, wantErr: "52.43 MB exceeds threshold 52.00 MB"},
+		{name: "size within binary threshold", envs: fakeEnvRepository{"fail_on_large_size": "52MB", "size_units": "binary"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}}},
+		{name: "invalid threshold is skipped", envs: fakeEnvRepository{"fail_on_large_size": "large"}, result: analyze.ArtifactResult{Name: "app.apk", Metrics: analyze.BundleMetrics{SizeBytes: 50 * mb}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Parse(tt.envs)
+			if err != nil {
+				t.Fatalf("config.Parse() error = %s", err)
+			}
+			violations := NewChecker(cfg, tt.envs, log.NewLogger()).Check(tt.result, tt.override)
+
+			var checks []string
+			for _, violation := range violations {
+				checks = append(checks, violation.Check)
+			}
+			if strings.Join(checks, ",") != strings.Join(tt.wantChecks, ",") {
+				t.Fatalf("Check() violations = %v, want %v", checks, tt.wantChecks)
+			}
+			if tt.wantErr != "" && !strings.Contains(violations[0].Err.Error(), tt.wantErr) {
+				t.Errorf("violation = %s, want %q", violations[0].Err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckerCachedAnalysis(t *testing.T) {
+	envs := fakeEnvRepository{"output_formats": "markdown", "fail_on_large_size": "40MB", "result_cache_dir": t.TempDir()}
+	cfg, err := config.Parse(envs)
+	if err != nil {
+		t.Fatalf("config.Parse() error = %s", err)
+	}
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, []byte("artifact"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A cache hit without the JSON report is checked against the size of the cached analysis
+	analyses := 0
+	analyzer := analyze.NewAnalyzer(fakePluginFactory{analyses: &analyses}, envs, log.NewLogger())
+	for _, wantCached := range []bool{false, true} {
+		result, err := analyzer.Analyze(context.Background(), cfg, artifactPath, t.TempDir(), nil, executor.RetryOptions{})
+		if err != nil {
+			t.Fatalf("Analyze() error = %s", err)
+		}
+		if result.Cached != wantCached {
+			t.Fatalf("Cached = %t, want %t", result.Cached, wantCached)
+		}
+		violations := NewChecker(cfg, envs, log.NewLogger()).Check(result, nil)
+		if len(violations) != 1 || violations[0].Check != "fail_on_large_size" {
+			t.Errorf("Check() of the cached %t analysis = %v, want fail_on_large_size", wantCached, violations)
+		}
+	}
+	if analyses != 1 {
+		t.Errorf("bundle-inspector analyzed the artifact %d times, want 1", analyses)
+	}
+}
+
+func TestCheckerDuplicateWasteScope(t *testing.T) {
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for _, name := range []string{"assets/intro.mp4", "assets/intro-copy.mp4"} {
+		entry, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write(bytes.Repeat([]byte("video"), 1024)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	artifactPath := filepath.Join(t.TempDir(), "app.apk")
+	if err := os.WriteFile(artifactPath, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		scope      string
+		wantChecks []string
+	}{
+		{scope: "", wantChecks: []string{"fail_on_duplicate_waste"}},
+		{scope: config.AnalysisScopeFull, wantChecks: []string{"fail_on_duplicate_waste"}},
+		// Hashing the entries is skipped by the quick analysis
+		{scope: config.AnalysisScopeQuick},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			envs := fakeEnvRepository{"fail_on_duplicate_waste": "1KB", "analysis_scope": tt.scope}
+			cfg, err := config.Parse(envs)
+			if err != nil {
+				t.Fatalf("config.Parse() error = %s", err)
+			}
+			violations := NewChecker(cfg, envs, log.NewLogger()).Check(analyze.ArtifactResult{Name: "app.apk", ArtifactPath: artifactPath}, nil)
+
+			var checks []string
+			for _, violation := range violations {
+				checks = append(checks, violation.Check)
+			}
+			if strings.Join(checks, ",") != strings.Join(tt.wantChecks, ",") {
+				t.Errorf("Check() violations = %v, want %v", checks, tt.wantChecks)
+			}
+		})
+	}
+}
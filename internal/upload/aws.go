@@ -0,0 +1,205 @@
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the access key of an IAM user or the temporary credentials of an assumed role
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only set for temporary credentials
+	SessionToken string
+}
+
+// unsignedPayload is the payload hash of presigned URLs and streamed uploads, the body isn't hashed into the signature
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// awsTimeFormat is the format of the X-Amz-Date header, awsDateFormat the date of the credential scope. The V4 signatures
+// of Cloud Storage use the same formats.
+const (
+	awsTimeFormat = "20060102T150405Z"
+	awsDateFormat = "20060102"
+)
+
+// awsEscape escapes a string the way Signature Version 4 expects: everything but the unreserved characters, spaces as
+// %20. Slashes are kept for paths.
+func awsEscape(value string, keepSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(value) {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery sorts and escapes the query parameters for signing
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key, false)+"="+awsEscape(value, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// signature signs the canonical request of the service in the region with Signature Version 4, it returns the
+// credential scope and the hex signature
+func (c AWSCredentials) signature(service, region string, now time.Time, canonicalRequest string) (string, string) {
+	date := now.Format(awsDateFormat)
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format(awsTimeFormat), scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// sign adds the Signature Version 4 authorization of the service to the request, signing the payload hash: the SHA-256
+// of the body, or unsignedPayload for streamed uploads. Every header already set on the request is signed.
+func (c AWSCredentials) sign(request *http.Request, payloadHash, service, region string, now time.Time) {
+	request.Header.Set("X-Amz-Date", now.Format(awsTimeFormat))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		awsEscape(request.URL.Path, true),
+		canonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope, signature := c.signature(service, region, now, canonicalRequest)
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// presign returns the GET URL of the object signed in the query, valid for the expiry. URLs signed with temporary
+// credentials stop working when the credentials expire.
+func (c AWSCredentials) presign(objectURL *url.URL, region string, expiry time.Duration, now time.Time) string {
+	signed := *objectURL
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {c.AccessKeyID + "/" + strings.Join([]string{now.Format(awsDateFormat), region, "s3", "aws4_request"}, "/")},
+		"X-Amz-Date":          {now.Format(awsTimeFormat)},
+		"X-Amz-Expires":       {fmt.Sprintf("%d", int64(expiry.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	if c.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		awsEscape(signed.Path, true),
+		canonicalQuery(query),
+		"host:" + signed.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	_, signature := c.signature("s3", region, now, canonicalRequest)
+	signed.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + signature
+	return signed.String()
+}
+
+// assumeRoleResponse is the response of the STS AssumeRole action
+type assumeRoleResponse struct {
+	Credentials struct {
+		AccessKeyID     string `xml:"AccessKeyId"`
+		SecretAccessKey string `xml:"SecretAccessKey"`
+		SessionToken    string `xml:"SessionToken"`
+	} `xml:"AssumeRoleResult>Credentials"`
+}
+
+// assumeRole returns temporary credentials of the role, requested from STS with the credentials for an hour
+func (c AWSCredentials) assumeRole(ctx context.Context, client *http.Client, roleARN, region string) (AWSCredentials, error) {
+	form := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {roleARN},
+		"RoleSessionName": {"bundle-analyzer"},
+		"DurationSeconds": {"3600"},
+	}
+	body := []byte(form.Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://sts.%s.amazonaws.com/", region), strings.NewReader(string(body)))
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	c.sign(request, sha256Hex(body), "sts", region, time.Now().UTC())
+
+	response, err := client.Do(request)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("STS request failed: %w", err)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read STS response: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return AWSCredentials{}, fmt.Errorf("STS AssumeRole request failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var assumed assumeRoleResponse
+	if err := xml.Unmarshal(data, &assumed); err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to parse STS response: %w", err)
+	}
+	if assumed.Credentials.AccessKeyID == "" {
+		return AWSCredentials{}, fmt.Errorf("STS returned no credentials for %s", roleARN)
+	}
+	return AWSCredentials{
+		AccessKeyID:     assumed.Credentials.AccessKeyID,
+		SecretAccessKey: assumed.Credentials.SecretAccessKey,
+		SessionToken:    assumed.Credentials.SessionToken,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// azureVersion is the Blob service version of the requests and of the SAS tokens signed with the account key
+const azureVersion = "2020-12-06"
+
+// azureUploadExpiry is the validity of the SAS tokens the reports are uploaded with
+const azureUploadExpiry = time.Hour
+
+// AzureOptions configure the upload of the reports to an Azure Blob Storage container
+type AzureOptions struct {
+	Account   string
+	Container string
+	URLType   string
+	// ConnectionString is the connection string of the storage account, with its account key or a SAS token
+	ConnectionString string
+	// SASToken authorizes the uploads of the account without a connection string
+	SASToken string
+}
+
+// azureUploader uploads the reports with SAS tokens: the one of the inputs or ones signed with the account key
+type azureUploader struct {
+	account   string
+	container string
+	urlType   string
+	// endpoint is the blob service URL of the account, like https://account.blob.core.windows.net
+	endpoint   string
+	accountKey []byte
+	sasToken   string
+	client     *http.Client
+}
+
+// NewAzureUploader returns the uploader of the container, authorized with the connection string or the SAS token
+func NewAzureUploader(opts AzureOptions) (Uploader, error) {
+	if opts.Container == "" {
+		return nil, fmt.Errorf("azure_container is required to upload to Azure Blob Storage")
+	}
+	u := azureUploader{account: opts.Account, container: opts.Container, urlType: opts.URLType, sasToken: strings.TrimPrefix(opts.SASToken, "?"), client: &http.Client{Timeout: 5 * time.Minute}}
+	if opts.ConnectionString != "" {
+		if err := u.parseConnectionString(opts.ConnectionString); err != nil {
+			return nil, err
+		}
+	}
+	if u.endpoint == "" {
+		if u.account == "" {
+			return nil, fmt.Errorf("azure_storage_account is required to upload with a SAS token")
+		}
+		u.endpoint = "https://" + u.account + ".blob.core.windows.net"
+	}
+	if u.accountKey == nil && u.sasToken == "" {
+		return nil, fmt.Errorf("no Azure credentials, set azure_connection_string or azure_sas_token")
+	}
+	if u.urlType == URLTypePresigned && u.accountKey == nil {
+		return nil, fmt.Errorf("presigned URLs are signed with the account key, set a connection string with an AccountKey")
+	}
+	return u, nil
+}
+
+// parseConnectionString reads the account, the blob endpoint and the credentials of a connection string like
+// DefaultEndpointsProtocol=https;AccountName=...;AccountKey=...;EndpointSuffix=core.windows.net. Connection strings
+// of a SAS have a BlobEndpoint and a SharedAccessSignature instead.
+func (u *azureUploader) parseConnectionString(connectionString string) error {
+	fields := map[string]string{}
+	for _, part := range strings.Split(connectionString, ";") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			fields[name] = value
+		}
+	}
+	if name := fields["AccountName"]; name != "" {
+		u.account = name
+	}
+	if key := fields["AccountKey"]; key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return fmt.Errorf("invalid AccountKey in the connection string: %w", err)
+		}
+		u.accountKey = decoded
+	}
+	if sas := fields["SharedAccessSignature"]; sas != "" {
+		u.sasToken = strings.TrimPrefix(sas, "?")
+	}
+
+	if endpoint := fields["BlobEndpoint"]; endpoint != "" {
+		u.endpoint = strings.TrimSuffix(endpoint, "/")
+	} else if u.account != "" {
+		protocol, suffix := fields["DefaultEndpointsProtocol"], fields["EndpointSuffix"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		u.endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, u.account, suffix)
+	}
+	if u.endpoint == "" {
+		return fmt.Errorf("the connection string has no AccountName or BlobEndpoint")
+	}
+	return nil
+}
+
+// blobURL returns the URL of a blob in the container
+func (u azureUploader) blobURL(key string) string {
+	return u.endpoint + "/" + u.container + "/" + awsEscape(key, true)
+}
+
+// serviceSAS returns a SAS token of the blob with the permissions, signed with the account key
+func (u azureUploader) serviceSAS(key, permissions string, expiry time.Time) string {
+	expiresAt := expiry.UTC().Format(time.RFC3339)
+	stringToSign := strings.Join([]string{
+		permissions,
+		"", // signed start
+		expiresAt,
+		"/blob/" + u.account + "/" + u.container + "/" + key,
+		"", // signed identifier
+		"", // signed IP
+		"https",
+		azureVersion,
+		"b",
+		"",                 // snapshot time
+		"",                 // encryption scope
+		"", "", "", "", "", // response headers
+	}, "\n")
+	mac := hmac.New(sha256.New, u.accountKey)
+	mac.Write([]byte(stringToSign))
+	query := url.Values{
+		"sv":  {azureVersion},
+		"sr":  {"b"},
+		"sp":  {permissions},
+		"se":  {expiresAt},
+		"spr": {"https"},
+		"sig": {base64.StdEncoding.EncodeToString(mac.Sum(nil))},
+	}
+	return query.Encode()
+}
+
+// Location returns the blob URL of the object
+func (u azureUploader) Location(key string) string {
+	return u.blobURL(key)
+}
+
+// Upload uploads a block blob once
+func (u azureUploader) Upload(ctx context.Context, key string, file *os.File, size int64, mimeType string) (string, error) {
+	sas := u.sasToken
+	if u.accountKey != nil {
+		sas = u.serviceSAS(key, "cw", time.Now().Add(azureUploadExpiry))
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, u.blobURL(key)+"?"+sas, io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", mimeType)
+	request.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	request.Header.Set("X-Ms-Version", azureVersion)
+
+	response, err := u.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Azure Blob Storage request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return "", fmt.Errorf("Azure Blob Storage upload failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if u.urlType == URLTypePresigned {
+		return u.blobURL(key) + "?" + u.serviceSAS(key, "r", time.Now().Add(presignExpiry)), nil
+	}
+	return u.blobURL(key), nil
+}
@@ -0,0 +1,156 @@
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// azureAccountKey is the account key of the Azurite storage emulator
+const azureAccountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+func TestNewAzureUploader(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         AzureOptions
+		wantAccount  string
+		wantEndpoint string
+		wantKey      bool
+		wantSAS      string
+		wantErr      string
+	}{
+		{name: "account key connection string", opts: AzureOptions{Container: "reports",
+			ConnectionString: "DefaultEndpointsProtocol=https;AccountName=mobile;AccountKey=" + azureAccountKey + ";EndpointSuffix=core.chinacloudapi.cn"},
+			wantAccount: "mobile", wantEndpoint: "https://mobile.blob.core.chinacloudapi.cn", wantKey: true},
+		{name: "SAS connection string", opts: AzureOptions{Container: "reports",
+			ConnectionString: "BlobEndpoint=https://mobile.blob.core.windows.net/;SharedAccessSignature=?sv=2020-12-06&sig=abc"},
+			wantEndpoint: "https://mobile.blob.core.windows.net", wantSAS: "sv=2020-12-06&sig=abc"},
+		{name: "SAS token", opts: AzureOptions{Account: "mobile", Container: "reports", SASToken: "?sv=2020-12-06&sig=abc"},
+			wantAccount: "mobile", wantEndpoint: "https://mobile.blob.core.windows.net", wantSAS: "sv=2020-12-06&sig=abc"},
+		{name: "missing container", opts: AzureOptions{Account: "mobile", SASToken: "sig=abc"}, wantErr: "azure_container is required to upload to Azure Blob Storage"},
+		{name: "SAS token without account", opts: AzureOptions{Container: "reports", SASToken: "sig=abc"}, wantErr: "azure_storage_account is required to upload with a SAS token"},
+		{name: "missing credentials", opts: AzureOptions{Account: "mobile", Container: "reports"}, wantErr: "no Azure credentials, set azure_connection_string or azure_sas_token"},
+		{name: "invalid account key", opts: AzureOptions{Container: "reports", ConnectionString: "AccountName=mobile;AccountKey=not base64"},
+			wantErr: "invalid AccountKey in the connection string: illegal base64 data at input byte 3"},
+		{name: "connection string without account", opts: AzureOptions{Container: "reports", ConnectionString: "SharedAccessSignature=sig=abc"},
+			wantErr: "the connection string has no AccountName or BlobEndpoint"},
+		{name: "presigned URLs without account key", opts: AzureOptions{Account: "mobile", Container: "reports", URLType: URLTypePresigned, SASToken: "sig=abc"},
+			wantErr: "presigned URLs are signed with the account key, set a connection string with an AccountKey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader, err := NewAzureUploader(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("NewAzureUploader() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewAzureUploader() error = %s", err)
+			}
+			azure := uploader.(azureUploader)
+			if azure.account != tt.wantAccount || azure.endpoint != tt.wantEndpoint || (azure.accountKey != nil) != tt.wantKey || azure.sasToken != tt.wantSAS {
+				t.Errorf("NewAzureUploader() = %s %s key:%t %q", azure.account, azure.endpoint, azure.accountKey != nil, azure.sasToken)
+			}
+			if got := uploader.Location("app/42/report.html"); got != tt.wantEndpoint+"/reports/app/42/report.html" {
+				t.Errorf("Location() = %s", got)
+			}
+		})
+	}
+}
+
+func TestServiceSAS(t *testing.T) {
+	accountKey, err := base64.StdEncoding.DecodeString(azureAccountKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := azureUploader{account: "mobile", container: "reports", accountKey: accountKey}
+	query, err := url.ParseQuery(u.serviceSAS("app/42/report.html", "r", time.Date(2026, 3, 8, 12, 0, 0, 0, time.FixedZone("CET", 3600))))
+	if err != nil {
+		t.Fatalf("serviceSAS() isn't a query: %s", err)
+	}
+	if query.Get("sv") != azureVersion || query.Get("sr") != "b" || query.Get("sp") != "r" || query.Get("se") != "2026-03-08T11:00:00Z" || query.Get("spr") != "https" {
+		t.Errorf("serviceSAS() = %v", query)
+	}
+
+	// The signature covers the fields of a service SAS of the 2020-12-06 version
+	stringToSign := "r\n\n2026-03-08T11:00:00Z\n/blob/mobile/reports/app/42/report.html\n\n\nhttps\n2020-12-06\nb\n\n\n\n\n\n\n"
+	mac := hmac.New(sha256.New, accountKey)
+	mac.Write([]byte(stringToSign))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); query.Get("sig") != want {
+		t.Errorf("serviceSAS() signature = %s, want %s", query.Get("sig"), want)
+	}
+}
+
+func TestAzureUpload(t *testing.T) {
+	accountKey, err := base64.StdEncoding.DecodeString(azureAccountKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		urlType    string
+		accountKey []byte
+		status     int
+		wantSAS    string
+		wantURL    string
+		wantErr    string
+	}{
+		{name: "SAS token", status: http.StatusCreated, wantSAS: "sv=2020-12-06&sig=abc", wantURL: "https://mobile.blob.core.windows.net/reports/app/42/report.html"},
+		{name: "account key", accountKey: accountKey, status: http.StatusCreated, wantSAS: "sp=cw", wantURL: "https://mobile.blob.core.windows.net/reports/app/42/report.html"},
+		{name: "presigned", urlType: URLTypePresigned, accountKey: accountKey, status: http.StatusCreated, wantSAS: "sp=cw",
+			wantURL: "https://mobile.blob.core.windows.net/reports/app/42/report.html?se="},
+		{name: "authorization failure", status: http.StatusForbidden, wantSAS: "sig=abc",
+			wantErr: "Azure Blob Storage upload failed with status 403: <Error><Code>AuthorizationFailure</Code></Error>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod, gotHost, gotPath, gotQuery, gotBody, gotType, gotBlobType, gotVersion string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotMethod, gotHost, gotPath, gotQuery, gotBody = r.Method, r.Host, r.URL.Path, r.URL.RawQuery, string(body)
+				gotType, gotBlobType, gotVersion = r.Header.Get("Content-Type"), r.Header.Get("X-Ms-Blob-Type"), r.Header.Get("X-Ms-Version")
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusCreated {
+					_, _ = io.WriteString(w, "<Error><Code>AuthorizationFailure</Code></Error>\n")
+				}
+			}))
+			defer server.Close()
+
+			uploader := azureUploader{
+				account:    "mobile",
+				container:  "reports",
+				urlType:    tt.urlType,
+				endpoint:   "https://mobile.blob.core.windows.net",
+				accountKey: tt.accountKey,
+				sasToken:   "sv=2020-12-06&sig=abc",
+				client:     &http.Client{Transport: redirectTransport{server: server}},
+			}
+			got, err := uploader.Upload(context.Background(), "app/42/report.html", reportFile(t, "report.html", "<html>report</html>"), 19, "text/html; charset=utf-8")
+			if gotMethod != http.MethodPut || gotHost != "mobile.blob.core.windows.net" || gotPath != "/reports/app/42/report.html" || gotBody != "<html>report</html>" {
+				t.Errorf("request = %s %s%s %q", gotMethod, gotHost, gotPath, gotBody)
+			}
+			if !strings.Contains(gotQuery, tt.wantSAS) || gotType != "text/html; charset=utf-8" || gotBlobType != "BlockBlob" || gotVersion != azureVersion {
+				t.Errorf("request = %q %q %q %q", gotQuery, gotType, gotBlobType, gotVersion)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Upload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !strings.HasPrefix(got, tt.wantURL) || (tt.urlType != URLTypePresigned && got != tt.wantURL) {
+				t.Errorf("Upload() = %s, %v, want %s", got, err, tt.wantURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package upload
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/google"
+)
+
+// gcsHost is the host of the XML API, the public and signed URLs of the objects point to it
+const gcsHost = "storage.googleapis.com"
+
+// gcsReadWriteScope is the OAuth scope of the object uploads
+const gcsReadWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// GCSOptions configure the upload of the reports to a Cloud Storage bucket
+type GCSOptions struct {
+	Bucket  string
+	URLType string
+	// ServiceAccountKey is the JSON key, the path of the key file or its URL
+	ServiceAccountKey string
+}
+
+// objectURL returns the public URL of an object in the bucket
+func (o GCSOptions) objectURL(key string) *url.URL {
+	return &url.URL{Scheme: "https", Host: gcsHost, Path: "/" + o.Bucket + "/" + key}
+}
+
+// consoleURL returns the Cloud console page of an object
+func (o GCSOptions) consoleURL(key string) string {
+	return fmt.Sprintf("https://console.cloud.google.com/storage/browser/_details/%s/%s", o.Bucket, awsEscape(key, true))
+}
+
+// gcsUploader uploads the reports with an access token of the service account
+type gcsUploader struct {
+	opts   GCSOptions
+	key    google.ServiceAccountKey
+	token  string
+	client *http.Client
+}
+
+// NewGCSUploader returns the uploader of the Cloud Storage bucket, authenticated with the service account key
+func NewGCSUploader(ctx context.Context, opts GCSOptions, retry executor.RetryOptions, logger log.Logger) (Uploader, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("gcs_bucket is required to upload to GCS")
+	}
+	if opts.ServiceAccountKey == "" {
+		return nil, fmt.Errorf("no service account key, set gcs_service_account_key")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	key, err := google.LoadServiceAccountKey(ctx, client, opts.ServiceAccountKey)
+	if err != nil {
+		return nil, err
+	}
+	token, err := executor.Retry(ctx, retry, "Google access token request", logger, func(ctx context.Context) (string, error) {
+		return key.AccessToken(ctx, client, gcsReadWriteScope)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return gcsUploader{opts: opts, key: key, token: token, client: client}, nil
+}
+
+// Location returns the gs:// address of the object
+func (u gcsUploader) Location(key string) string {
+	return fmt.Sprintf("gs://%s/%s", u.opts.Bucket, key)
+}
+
+// Upload uploads an object once with the JSON API, public objects get the publicRead ACL
+func (u gcsUploader) Upload(ctx context.Context, key string, file *os.File, size int64, mimeType string) (string, error) {
+	query := url.Values{"uploadType": {"media"}, "name": {key}}
+	if u.opts.URLType == URLTypePublic {
+		query.Set("predefinedAcl", "publicRead")
+	}
+	uploadURL := fmt.Sprintf("https://%s/upload/storage/v1/b/%s/o?%s", gcsHost, url.PathEscape(u.opts.Bucket), query.Encode())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", mimeType)
+	request.Header.Set("Authorization", "Bearer "+u.token)
+
+	response, err := u.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("GCS request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return "", fmt.Errorf("GCS upload failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	switch u.opts.URLType {
+	case URLTypePublic:
+		return u.opts.objectURL(key).String(), nil
+	case URLTypePresigned:
+		signed, err := signedURL(u.key, u.opts.objectURL(key), presignExpiry, time.Now().UTC())
+		if err != nil {
+			return "", fmt.Errorf("failed to sign the URL: %w", err)
+		}
+		return signed, nil
+	}
+	return u.opts.consoleURL(key), nil
+}
+
+// signedURL returns the GET URL of the object signed with the V4 signing process of Cloud Storage and the private key
+// of the service account, valid for the expiry
+func signedURL(key google.ServiceAccountKey, objectURL *url.URL, expiry time.Duration, now time.Time) (string, error) {
+	signed := *objectURL
+	scope := strings.Join([]string{now.Format(awsDateFormat), "auto", "storage", "goog4_request"}, "/")
+	query := url.Values{
+		"X-Goog-Algorithm":     {"GOOG4-RSA-SHA256"},
+		"X-Goog-Credential":    {key.ClientEmail + "/" + scope},
+		"X-Goog-Date":          {now.Format(awsTimeFormat)},
+		"X-Goog-Expires":       {fmt.Sprintf("%d", int64(expiry.Seconds()))},
+		"X-Goog-SignedHeaders": {"host"},
+	}
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		awsEscape(signed.Path, true),
+		canonicalQuery(query),
+		"host:" + signed.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	stringToSign := strings.Join([]string{"GOOG4-RSA-SHA256", now.Format(awsTimeFormat), scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+	signature, err := key.Sign([]byte(stringToSign))
+	if err != nil {
+		return "", err
+	}
+	signed.RawQuery = canonicalQuery(query) + "&X-Goog-Signature=" + hex.EncodeToString(signature)
+	return signed.String(), nil
+}
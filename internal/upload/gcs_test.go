@@ -0,0 +1,191 @@
+package upload
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/google"
+)
+
+// serviceAccountKey returns a service account key with a new RSA key and the public key to verify its signatures
+func serviceAccountKey(t *testing.T, tokenURI string) (google.ServiceAccountKey, *rsa.PublicKey) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return google.ServiceAccountKey{
+		Type:        "service_account",
+		ClientEmail: "reports@example.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokenURI,
+	}, &privateKey.PublicKey
+}
+
+func TestGCSObjectURL(t *testing.T) {
+	opts := GCSOptions{Bucket: "reports"}
+	if got := opts.objectURL("app/42/bundle analysis.html").String(); got != "https://storage.googleapis.com/reports/app/42/bundle%20analysis.html" {
+		t.Errorf("objectURL() = %s", got)
+	}
+	if got := opts.consoleURL("app/42/bundle analysis.html"); got != "https://console.cloud.google.com/storage/browser/_details/reports/app/42/bundle%20analysis.html" {
+		t.Errorf("consoleURL() = %s", got)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	key, publicKey := serviceAccountKey(t, "")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	got, err := signedURL(key, GCSOptions{Bucket: "reports"}.objectURL("app/42/report.html"), 7*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("signedURL() error = %s", err)
+	}
+
+	query := "X-Goog-Algorithm=GOOG4-RSA-SHA256&X-Goog-Credential=reports%40example.iam.gserviceaccount.com%2F20260301%2Fauto%2Fstorage%2Fgoog4_request" +
+		"&X-Goog-Date=20260301T120000Z&X-Goog-Expires=604800&X-Goog-SignedHeaders=host"
+	wantPrefix := "https://storage.googleapis.com/reports/app/42/report.html?" + query + "&X-Goog-Signature="
+	if !strings.HasPrefix(got, wantPrefix) {
+		t.Fatalf("signedURL() = %s, want prefix %s", got, wantPrefix)
+	}
+
+	// The signature covers the canonical request of the V4 signing process of Cloud Storage
+	signature, err := hex.DecodeString(strings.TrimPrefix(got, wantPrefix))
+	if err != nil {
+		t.Fatalf("signature isn't hex encoded: %s", err)
+	}
+	canonicalRequest := "GET\n/reports/app/42/report.html\n" + query + "\nhost:storage.googleapis.com\n\nhost\nUNSIGNED-PAYLOAD"
+	stringToSign := "GOOG4-RSA-SHA256\n20260301T120000Z\n20260301/auto/storage/goog4_request\n" + sha256Hex([]byte(canonicalRequest))
+	digest := sha256.Sum256([]byte(stringToSign))
+	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("signature doesn't verify: %s", err)
+	}
+}
+
+func TestNewGCSUploader(t *testing.T) {
+	var gotScope string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := strings.Split(r.FormValue("assertion"), ".")
+		if len(claims) == 3 {
+			var assertion struct {
+				Scope string `json:"scope"`
+			}
+			payload, _ := base64.RawURLEncoding.DecodeString(claims[1])
+			_ = json.Unmarshal(payload, &assertion)
+			gotScope = assertion.Scope
+		}
+		_, _ = io.WriteString(w, `{"access_token": "ya29.token", "expires_in": 3599, "token_type": "Bearer"}`)
+	}))
+	defer server.Close()
+	key, _ := serviceAccountKey(t, server.URL+"/token")
+	keyJSON, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    GCSOptions
+		wantErr string
+	}{
+		{name: "missing bucket", opts: GCSOptions{ServiceAccountKey: string(keyJSON)}, wantErr: "gcs_bucket is required to upload to GCS"},
+		{name: "missing key", opts: GCSOptions{Bucket: "reports"}, wantErr: "no service account key, set gcs_service_account_key"},
+		{name: "authenticated", opts: GCSOptions{Bucket: "reports", ServiceAccountKey: string(keyJSON)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uploader, err := NewGCSUploader(context.Background(), tt.opts, executor.RetryOptions{}, log.NewLogger())
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("NewGCSUploader() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewGCSUploader() error = %s", err)
+			}
+			if gcs := uploader.(gcsUploader); gcs.token != "ya29.token" || gotScope != gcsReadWriteScope || uploader.Location("app/42/report.html") != "gs://reports/app/42/report.html" {
+				t.Errorf("NewGCSUploader() = %s with %q, scope %s", uploader.Location("app/42/report.html"), gcs.token, gotScope)
+			}
+		})
+	}
+}
+
+func TestGCSUpload(t *testing.T) {
+	key, _ := serviceAccountKey(t, "")
+	tests := []struct {
+		name       string
+		urlType    string
+		status     int
+		wantACL    string
+		wantPrefix string
+		wantErr    string
+	}{
+		{name: "private", urlType: URLTypePrivate, status: http.StatusOK, wantPrefix: "https://console.cloud.google.com/storage/browser/_details/reports/app/42/report.html"},
+		{name: "public", urlType: URLTypePublic, status: http.StatusOK, wantACL: "publicRead", wantPrefix: "https://storage.googleapis.com/reports/app/42/report.html"},
+		{name: "presigned", urlType: URLTypePresigned, status: http.StatusOK, wantPrefix: "https://storage.googleapis.com/reports/app/42/report.html?X-Goog-Algorithm=GOOG4-RSA-SHA256&"},
+		{name: "permission denied", urlType: URLTypePrivate, status: http.StatusForbidden,
+			wantErr: `GCS upload failed with status 403: {"error": {"code": 403, "message": "reports@example.iam.gserviceaccount.com does not have storage.objects.create access"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotMethod, gotHost, gotPath, gotBody, gotType, gotAuth string
+			var gotQuery url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				gotMethod, gotHost, gotPath, gotQuery, gotBody = r.Method, r.Host, r.URL.Path, r.URL.Query(), string(body)
+				gotType, gotAuth = r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+				w.WriteHeader(tt.status)
+				if tt.status != http.StatusOK {
+					_, _ = io.WriteString(w, `{"error": {"code": 403, "message": "reports@example.iam.gserviceaccount.com does not have storage.objects.create access"}}`+"\n")
+				}
+			}))
+			defer server.Close()
+
+			uploader := gcsUploader{
+				opts:   GCSOptions{Bucket: "reports", URLType: tt.urlType},
+				key:    key,
+				token:  "ya29.token",
+				client: &http.Client{Transport: redirectTransport{server: server}},
+			}
+			got, err := uploader.Upload(context.Background(), "app/42/report.html", reportFile(t, "report.html", "<html>report</html>"), 19, "text/html; charset=utf-8")
+			if gotMethod != http.MethodPost || gotHost != gcsHost || gotPath != "/upload/storage/v1/b/reports/o" || gotBody != "<html>report</html>" {
+				t.Errorf("request = %s %s%s %q", gotMethod, gotHost, gotPath, gotBody)
+			}
+			if gotQuery.Get("uploadType") != "media" || gotQuery.Get("name") != "app/42/report.html" || gotQuery.Get("predefinedAcl") != tt.wantACL {
+				t.Errorf("query = %v", gotQuery)
+			}
+			if gotType != "text/html; charset=utf-8" || gotAuth != "Bearer ya29.token" {
+				t.Errorf("headers = %q %q", gotType, gotAuth)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Upload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("Upload() = %s, %v, want %s", got, err, tt.wantPrefix)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Publisher uploads the reports to the storage providers configured by the upload inputs
+type Publisher struct {
+	cfg     config.Config
+	envRepo env.Repository
+	retry   executor.RetryOptions
+	logger  log.Logger
+}
+
+// NewPublisher returns a Publisher uploading with the storage inputs of the config, the credentials fall back to the
+// variables of the provider CLIs in the environment
+func NewPublisher(cfg config.Config, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) Publisher {
+	return Publisher{cfg: cfg, envRepo: envRepo, retry: retry, logger: logger}
+}
+
+// Providers returns the storage providers the reports are uploaded to: the one of upload_provider, or every provider
+// with a bucket (or container) when it's empty
+func (p Publisher) Providers() []string {
+	if p.cfg.UploadProvider != "" {
+		return []string{p.cfg.UploadProvider}
+	}
+	buckets := map[string]string{ProviderS3: p.cfg.S3Bucket, ProviderGCS: p.cfg.GCSBucket, ProviderAzure: p.cfg.AzureContainer}
+	var providers []string
+	for _, provider := range Providers {
+		if buckets[provider] != "" {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+// Publish uploads the reports of the artifacts to every provider of Providers, a failed provider is logged and
+// skipped. The uploaded reports are returned by provider, and all of them in upload order.
+func (p Publisher) Publish(ctx context.Context, results []analyze.ArtifactResult) (map[string][]Report, []Report) {
+	uploadedBy := map[string][]Report{}
+	var uploaded []Report
+	for _, provider := range p.Providers() {
+		p.logger.Println()
+		p.logger.Infof("Uploading reports to %s...", ProviderNames[provider])
+		reports, err := p.Upload(ctx, provider, results)
+		if err != nil {
+			p.logger.Warnf("Failed to upload reports to %s: %s", ProviderNames[provider], err)
+			continue
+		}
+		p.logger.Donef("Uploaded %d report(s) to %s", len(reports), ProviderNames[provider])
+		uploadedBy[provider] = reports
+		uploaded = append(uploaded, reports...)
+	}
+	return uploadedBy, uploaded
+}
+
+// Upload uploads the reports of the artifacts to the bucket of the storage provider
+func (p Publisher) Upload(ctx context.Context, provider string, results []analyze.ArtifactResult) ([]Report, error) {
+	var uploader Uploader
+	var prefix string
+	var err error
+	switch provider {
+	case ProviderS3:
+		uploader, err = NewS3Uploader(ctx, p.s3Options(), p.retry, p.logger)
+		prefix = p.cfg.S3Prefix
+	case ProviderGCS:
+		uploader, err = NewGCSUploader(ctx, GCSOptions{Bucket: p.cfg.GCSBucket, URLType: p.cfg.GCSURLType, ServiceAccountKey: string(p.cfg.GCSServiceAccountKey)}, p.retry, p.logger)
+		prefix = p.cfg.GCSPrefix
+	case ProviderAzure:
+		uploader, err = NewAzureUploader(p.azureOptions())
+		prefix = p.cfg.AzurePrefix
+	default:
+		return nil, fmt.Errorf("unknown upload provider %s", provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return UploadReports(ctx, uploader, prefix, results, p.envRepo, p.retry, p.logger)
+}
+
+// s3Options returns the options of the S3 upload, the credentials and the region fall back to the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION variables
+func (p Publisher) s3Options() S3Options {
+	credentials := AWSCredentials{AccessKeyID: p.cfg.AWSAccessKeyID, SecretAccessKey: string(p.cfg.AWSSecretAccessKey), SessionToken: string(p.cfg.AWSSessionToken)}
+	if credentials.AccessKeyID == "" {
+		credentials = AWSCredentials{AccessKeyID: p.envRepo.Get("AWS_ACCESS_KEY_ID"), SecretAccessKey: p.envRepo.Get("AWS_SECRET_ACCESS_KEY"), SessionToken: p.envRepo.Get("AWS_SESSION_TOKEN")}
+	}
+	region := p.cfg.S3Region
+	if region == "" {
+		region = p.envRepo.Get("AWS_REGION")
+	}
+	return S3Options{Bucket: p.cfg.S3Bucket, Region: region, URLType: p.cfg.S3URLType, Credentials: credentials, RoleARN: p.cfg.AWSRoleARN}
+}
+
+// azureOptions returns the options of the Azure Blob Storage upload, the account and the credentials fall back to the
+// AZURE_STORAGE_ACCOUNT, AZURE_STORAGE_CONNECTION_STRING and AZURE_STORAGE_SAS_TOKEN variables of the Azure CLI
+func (p Publisher) azureOptions() AzureOptions {
+	opts := AzureOptions{Account: p.cfg.AzureStorageAccount, Container: p.cfg.AzureContainer, URLType: p.cfg.AzureURLType, ConnectionString: string(p.cfg.AzureConnectionString), SASToken: string(p.cfg.AzureSASToken)}
+	if opts.Account == "" {
+		opts.Account = p.envRepo.Get("AZURE_STORAGE_ACCOUNT")
+	}
+	if opts.ConnectionString == "" && opts.SASToken == "" {
+		opts.ConnectionString = p.envRepo.Get("AZURE_STORAGE_CONNECTION_STRING")
+		opts.SASToken = p.envRepo.Get("AZURE_STORAGE_SAS_TOKEN")
+	}
+	return opts
+}
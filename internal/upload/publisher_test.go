@@ -0,0 +1,81 @@
+package upload
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+func TestPublisherProviders(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want []string
+	}{
+		{name: "upload provider", cfg: config.Config{UploadProvider: ProviderAzure, S3Bucket: "reports"}, want: []string{ProviderAzure}},
+		{name: "every provider with a bucket", cfg: config.Config{AzureContainer: "reports", S3Bucket: "reports"}, want: []string{ProviderS3, ProviderAzure}},
+		{name: "no buckets", cfg: config.Config{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher := NewPublisher(tt.cfg, env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+			if got := publisher.Providers(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Providers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublisherUnknownProvider(t *testing.T) {
+	publisher := NewPublisher(config.Config{}, env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+	if _, err := publisher.Upload(context.Background(), "dropbox", nil); err == nil || err.Error() != "unknown upload provider dropbox" {
+		t.Errorf("Upload() error = %v", err)
+	}
+}
+
+func TestPublisherS3Options(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAENVIRONMENT")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "environment-secret")
+	t.Setenv("AWS_SESSION_TOKEN", "environment-token")
+	t.Setenv("AWS_REGION", "eu-central-1")
+
+	// The credentials and the region of the environment are the fallback of the inputs
+	publisher := NewPublisher(config.Config{S3Bucket: "reports"}, env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+	want := S3Options{Bucket: "reports", Region: "eu-central-1",
+		Credentials: AWSCredentials{AccessKeyID: "AKIAENVIRONMENT", SecretAccessKey: "environment-secret", SessionToken: "environment-token"}}
+	if got := publisher.s3Options(); got != want {
+		t.Errorf("s3Options() = %+v, want %+v", got, want)
+	}
+
+	publisher = NewPublisher(config.Config{S3Bucket: "reports", S3Region: "us-west-2", AWSAccessKeyID: "AKIAINPUT", AWSSecretAccessKey: "input-secret"},
+		env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+	want = S3Options{Bucket: "reports", Region: "us-west-2", Credentials: AWSCredentials{AccessKeyID: "AKIAINPUT", SecretAccessKey: "input-secret"}}
+	if got := publisher.s3Options(); got != want {
+		t.Errorf("s3Options() of the inputs = %+v, want %+v", got, want)
+	}
+}
+
+func TestPublisherAzureOptions(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "environment")
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", "AccountName=environment;AccountKey="+azureAccountKey)
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "")
+
+	// The variables of the Azure CLI are the fallback of the inputs
+	publisher := NewPublisher(config.Config{AzureContainer: "reports"}, env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+	want := AzureOptions{Account: "environment", Container: "reports", ConnectionString: "AccountName=environment;AccountKey=" + azureAccountKey}
+	if got := publisher.azureOptions(); got != want {
+		t.Errorf("azureOptions() = %+v, want %+v", got, want)
+	}
+
+	// A SAS token input replaces the connection string of the environment
+	publisher = NewPublisher(config.Config{AzureContainer: "reports", AzureStorageAccount: "mobile", AzureSASToken: "sig=abc"}, env.NewRepository(), executor.RetryOptions{}, log.NewLogger())
+	want = AzureOptions{Account: "mobile", Container: "reports", SASToken: "sig=abc"}
+	if got := publisher.azureOptions(); got != want {
+		t.Errorf("azureOptions() of the inputs = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,110 @@
+package upload
+
+import (
+	"mime"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+)
+
+// Report is a report of an analyzed artifact uploaded to a storage bucket
+type Report struct {
+	Artifact string
+	// Kind is markdown, html, json, csv, sarif or licenses
+	Kind string
+	Path string
+	// Key is the object name in the bucket
+	Key string
+	// URL opens the report: the public or presigned URL, or the console page of a private object
+	URL string
+}
+
+// reportFiles lists the reports of the artifacts to upload, the deployed copies when BITRISE_DEPLOY_DIR is set
+func reportFiles(results []analyze.ArtifactResult) []Report {
+	var reports []Report
+	for _, result := range results {
+		for _, file := range []struct{ kind, path string }{
+			{"html", result.ReportPaths.HTML},
+			{"markdown", result.ReportPaths.Markdown},
+			{"json", result.ReportPaths.JSON},
+			{"csv", result.ReportPaths.CSV},
+			{"sarif", result.ReportPaths.SARIF},
+			{"licenses", result.ReportPaths.Licenses},
+		} {
+			if file.path != "" {
+				reports = append(reports, Report{Artifact: result.Name, Kind: file.kind, Path: file.path})
+			}
+		}
+	}
+	return reports
+}
+
+// buildFolder names the folder of the reports of the build: the build number, or the upload time for runs outside of
+// Bitrise
+func buildFolder(envRepo env.Repository, now time.Time) string {
+	if buildNumber := envRepo.Get("BITRISE_BUILD_NUMBER"); buildNumber != "" {
+		return buildNumber
+	}
+	return now.UTC().Format("20060102-150405")
+}
+
+// objectKey returns the name of the report in the bucket: <prefix>/<build folder>/<file name>
+func objectKey(prefix, folder, reportPath string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return path.Join(folder, filepath.Base(reportPath))
+	}
+	return path.Join(prefix, folder, filepath.Base(reportPath))
+}
+
+// contentType returns the MIME type browsers open the report with
+func contentType(reportPath string) string {
+	switch strings.ToLower(filepath.Ext(reportPath)) {
+	case ".md":
+		return "text/markdown; charset=utf-8"
+	case ".html":
+		return "text/html; charset=utf-8"
+	case ".json":
+		return "application/json"
+	case ".csv":
+		return "text/csv; charset=utf-8"
+	case ".sarif":
+		return "application/sarif+json"
+	}
+	if mimeType := mime.TypeByExtension(filepath.Ext(reportPath)); mimeType != "" {
+		return mimeType
+	}
+	return "application/octet-stream"
+}
+
+// URLs maps the local paths of the uploaded reports to their URLs, the first upload of a report wins when it was
+// uploaded to several buckets
+func URLs(reports []Report) map[string]string {
+	urls := map[string]string{}
+	for _, report := range reports {
+		if _, ok := urls[report.Path]; !ok {
+			urls[report.Path] = report.URL
+		}
+	}
+	return urls
+}
+
+// PrimaryURL returns the URL of the main report of the first artifact: the HTML report, or the markdown or JSON one
+// without HTML
+func PrimaryURL(reports []Report) string {
+	if len(reports) == 0 {
+		return ""
+	}
+	for _, kind := range []string{"html", "markdown", "json"} {
+		for _, report := range reports {
+			if report.Artifact == reports[0].Artifact && report.Kind == kind {
+				return report.URL
+			}
+		}
+	}
+	return reports[0].URL
+}
@@ -0,0 +1,116 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// DefaultS3Region is the region of the bucket when s3_region is empty
+const DefaultS3Region = "us-east-1"
+
+// S3Options configure the upload of the reports to an S3 bucket
+type S3Options struct {
+	Bucket      string
+	Region      string
+	URLType     string
+	Credentials AWSCredentials
+	// RoleARN is the role assumed with the credentials for the upload, empty to upload with the credentials
+	RoleARN string
+}
+
+// objectURL returns the URL of an object in the bucket, buckets with dots in their names are addressed by path as
+// their virtual host names don't match the certificate of S3
+func (o S3Options) objectURL(key string) *url.URL {
+	if strings.Contains(o.Bucket, ".") {
+		return &url.URL{Scheme: "https", Host: "s3." + o.Region + ".amazonaws.com", Path: "/" + o.Bucket + "/" + key}
+	}
+	return &url.URL{Scheme: "https", Host: o.Bucket + ".s3." + o.Region + ".amazonaws.com", Path: "/" + key}
+}
+
+// consoleURL returns the AWS console page of an object
+func (o S3Options) consoleURL(key string) string {
+	return fmt.Sprintf("https://s3.console.aws.amazon.com/s3/object/%s?region=%s&prefix=%s", o.Bucket, o.Region, awsEscape(key, false))
+}
+
+// s3Uploader uploads the reports with the credentials, or with the temporary credentials of the assumed role
+type s3Uploader struct {
+	opts   S3Options
+	client *http.Client
+}
+
+// NewS3Uploader returns the uploader of the S3 bucket, the role is assumed with the credentials when set
+func NewS3Uploader(ctx context.Context, opts S3Options, retry executor.RetryOptions, logger log.Logger) (Uploader, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3_bucket is required to upload to S3")
+	}
+	if opts.Region == "" {
+		opts.Region = DefaultS3Region
+	}
+	if opts.Credentials.AccessKeyID == "" || opts.Credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("no AWS credentials, set aws_access_key_id and aws_secret_access_key")
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	if opts.RoleARN != "" {
+		logger.Printf("Assuming role %s", opts.RoleARN)
+		var assumed AWSCredentials
+		_, err := executor.Retry(ctx, retry, "STS request", logger, func(ctx context.Context) (string, error) {
+			var err error
+			assumed, err = opts.Credentials.assumeRole(ctx, client, opts.RoleARN, opts.Region)
+			return "", err
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts.Credentials = assumed
+	}
+	return s3Uploader{opts: opts, client: client}, nil
+}
+
+// Location returns the s3:// address of the object
+func (u s3Uploader) Location(key string) string {
+	return fmt.Sprintf("s3://%s/%s", u.opts.Bucket, key)
+}
+
+// Upload uploads an object once, public objects get the public-read ACL. The body is streamed, so its hash isn't signed.
+func (u s3Uploader) Upload(ctx context.Context, key string, file *os.File, size int64, mimeType string) (string, error) {
+	objectURL := u.opts.objectURL(key)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, objectURL.String(), io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	request.ContentLength = size
+	request.Header.Set("Content-Type", mimeType)
+	if u.opts.URLType == URLTypePublic {
+		request.Header.Set("X-Amz-Acl", "public-read")
+	}
+	now := time.Now().UTC()
+	u.opts.Credentials.sign(request, unsignedPayload, "s3", u.opts.Region, now)
+
+	response, err := u.client.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return "", fmt.Errorf("S3 upload failed with status %d: %s", response.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	switch u.opts.URLType {
+	case URLTypePublic:
+		return objectURL.String(), nil
+	case URLTypePresigned:
+		return u.opts.Credentials.presign(objectURL, u.opts.Region, presignExpiry, now), nil
+	}
+	return u.opts.consoleURL(key), nil
+}
@@ -0,0 +1,89 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// Storage providers of upload_provider
+const (
+	ProviderS3    = "s3"
+	ProviderGCS   = "gcs"
+	ProviderAzure = "azure"
+)
+
+// Providers lists the storage providers in the order the reports are uploaded when upload_provider is empty
+var Providers = []string{ProviderS3, ProviderGCS, ProviderAzure}
+
+// ProviderNames are the names of the storage providers in the logs
+var ProviderNames = map[string]string{
+	ProviderS3:    "S3",
+	ProviderGCS:   "GCS",
+	ProviderAzure: "Azure Blob Storage",
+}
+
+// URL types of the uploaded reports
+const (
+	// URLTypePrivate links the console page of the object, only users with access to the bucket can open it
+	URLTypePrivate = "private"
+	// URLTypePublic uploads the reports readable by anyone and links the object URL
+	URLTypePublic = "public"
+	// URLTypePresigned links a URL signed with the upload credentials, it works without access to the bucket
+	URLTypePresigned = "presigned"
+)
+
+// presignExpiry is the validity of presigned URLs, the longest Signature Version 4 allows
+const presignExpiry = 7 * 24 * time.Hour
+
+// Uploader uploads the reports to a bucket of a storage provider
+type Uploader interface {
+	// Location returns the address of an object in the logs, like s3://bucket/key
+	Location(key string) string
+	// Upload uploads the size bytes of the file as an object once and returns the URL the report is linked with, as
+	// configured by the URL type. The file is read from its start on every call.
+	Upload(ctx context.Context, key string, file *os.File, size int64, mimeType string) (string, error)
+}
+
+// UploadReports uploads the reports of the artifacts to <prefix>/<build number>/ of the bucket and returns them with
+// their URLs. Failed uploads are retried with the retry options.
+func UploadReports(ctx context.Context, uploader Uploader, prefix string, results []analyze.ArtifactResult, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) ([]Report, error) {
+	reports := reportFiles(results)
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no reports to upload")
+	}
+	folder := buildFolder(envRepo, time.Now())
+	for idx, report := range reports {
+		key := objectKey(prefix, folder, report.Path)
+		reportURL, err := uploadReport(ctx, uploader, key, report.Path, retry, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", report.Path, err)
+		}
+		reports[idx].Key = key
+		reports[idx].URL = reportURL
+		logger.Printf("Uploaded %s to %s", report.Path, uploader.Location(key))
+	}
+	return reports, nil
+}
+
+// uploadReport streams the report file to the key, reports of hundreds of MB aren't read into memory
+func uploadReport(ctx context.Context, uploader Uploader, key, reportPath string, retry executor.RetryOptions, logger log.Logger) (string, error) {
+	file, err := os.Open(reportPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return executor.Retry(ctx, retry, "Report upload", logger, func(ctx context.Context) (string, error) {
+		return uploader.Upload(ctx, key, file, info.Size(), contentType(reportPath))
+	})
+}
@@ -0,0 +1,137 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+)
+
+// fakeUploader records the uploaded objects, the first failures uploads fail
+type fakeUploader struct {
+	failures int
+	objects  map[string]string
+	types    map[string]string
+}
+
+func (u *fakeUploader) Location(key string) string {
+	return "fake://bucket/" + key
+}
+
+func (u *fakeUploader) Upload(ctx context.Context, key string, file *os.File, size int64, mimeType string) (string, error) {
+	content, err := io.ReadAll(io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return "", err
+	}
+	if u.failures > 0 {
+		u.failures--
+		return "", errors.New("connection reset")
+	}
+	u.objects[key], u.types[key] = string(content), mimeType
+	return "https://bucket.example.com/" + key, nil
+}
+
+func TestUploadReports(t *testing.T) {
+	t.Setenv("BITRISE_BUILD_NUMBER", "42")
+	dir := t.TempDir()
+	writeReport := func(name, content string) string {
+		reportPath := filepath.Join(dir, name)
+		if err := os.WriteFile(reportPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return reportPath
+	}
+	results := []analyze.ArtifactResult{
+		{Name: "app-release.aab", ReportPaths: analyze.ReportPaths{
+			HTML: writeReport("bundle-analysis-app-release.html", "<html/>"),
+			JSON: writeReport("bundle-analysis-app-release.json", "{}"),
+		}},
+		{Name: "App.ipa", ReportPaths: analyze.ReportPaths{Markdown: writeReport("bundle-analysis-App.md", "# App")}},
+	}
+
+	// A failed upload is retried
+	uploader := &fakeUploader{failures: 1, objects: map[string]string{}, types: map[string]string{}}
+	reports, err := UploadReports(context.Background(), uploader, "/bundle-reports/", results, env.NewRepository(), executor.RetryOptions{Count: 1, Wait: time.Millisecond}, log.NewLogger())
+	if err != nil {
+		t.Fatalf("UploadReports() error = %s", err)
+	}
+	wantObjects := map[string]string{
+		"bundle-reports/42/bundle-analysis-app-release.html": "<html/>",
+		"bundle-reports/42/bundle-analysis-app-release.json": "{}",
+		"bundle-reports/42/bundle-analysis-App.md":           "# App",
+	}
+	if !reflect.DeepEqual(uploader.objects, wantObjects) {
+		t.Errorf("uploaded objects = %v, want %v", uploader.objects, wantObjects)
+	}
+	if uploader.types["bundle-reports/42/bundle-analysis-App.md"] != "text/markdown; charset=utf-8" || uploader.types["bundle-reports/42/bundle-analysis-app-release.json"] != "application/json" {
+		t.Errorf("content types = %v", uploader.types)
+	}
+	if len(reports) != 3 || reports[0].Kind != "html" || reports[0].Key != "bundle-reports/42/bundle-analysis-app-release.html" || reports[2].Artifact != "App.ipa" {
+		t.Errorf("UploadReports() = %+v", reports)
+	}
+	if got := PrimaryURL(reports); got != "https://bucket.example.com/bundle-reports/42/bundle-analysis-app-release.html" {
+		t.Errorf("PrimaryURL() = %s", got)
+	}
+	if got := URLs(reports)[results[1].ReportPaths.Markdown]; got != "https://bucket.example.com/bundle-reports/42/bundle-analysis-App.md" {
+		t.Errorf("URLs() = %v", URLs(reports))
+	}
+
+	if _, err := UploadReports(context.Background(), uploader, "", []analyze.ArtifactResult{{Name: "app.apk"}}, env.NewRepository(), executor.RetryOptions{}, log.NewLogger()); err == nil || err.Error() != "no reports to upload" {
+		t.Errorf("UploadReports() without reports error = %v", err)
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "", want: "42/bundle-analysis-app.html"},
+		{prefix: "/", want: "42/bundle-analysis-app.html"},
+		{prefix: "mobile/reports/", want: "mobile/reports/42/bundle-analysis-app.html"},
+	}
+	for _, tt := range tests {
+		if got := objectKey(tt.prefix, "42", "/bitrise/deploy/bundle-analysis-app.html"); got != tt.want {
+			t.Errorf("objectKey(%q) = %s, want %s", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestBuildFolder(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	t.Setenv("BITRISE_BUILD_NUMBER", "")
+	if got := buildFolder(env.NewRepository(), now); got != "20260301-113000" {
+		t.Errorf("buildFolder() outside of Bitrise = %s", got)
+	}
+	t.Setenv("BITRISE_BUILD_NUMBER", "42")
+	if got := buildFolder(env.NewRepository(), now); got != "42" {
+		t.Errorf("buildFolder() = %s", got)
+	}
+}
+
+func TestPrimaryURL(t *testing.T) {
+	reports := []Report{
+		{Artifact: "app.apk", Kind: "json", URL: "https://bucket/app.json"},
+		{Artifact: "App.ipa", Kind: "html", URL: "https://bucket/App.html"},
+		{Artifact: "app.apk", Kind: "markdown", URL: "https://bucket/app.md"},
+	}
+	// The report of the first artifact is linked, markdown takes precedence over JSON
+	if got := PrimaryURL(reports); got != "https://bucket/app.md" {
+		t.Errorf("PrimaryURL() = %s", got)
+	}
+	if got := PrimaryURL([]Report{{Artifact: "app.apk", Kind: "csv", URL: "https://bucket/app.csv"}}); got != "https://bucket/app.csv" {
+		t.Errorf("PrimaryURL() without a main report = %s", got)
+	}
+	if got := PrimaryURL(nil); got != "" {
+		t.Errorf("PrimaryURL() without reports = %s", got)
+	}
+}
@@ -1,154 +1,406 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"math"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/bitrise-io/go-steputils/stepconf"
-	"github.com/bitrise-io/go-steputils/tools"
 	"github.com/bitrise-io/go-utils/v2/command"
 	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/fileutil"
 	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/appstoreconnect"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/bitrise"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/datadog"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/detect"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/firebase"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/github"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/google"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/jira"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/logging"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/newrelic"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/outputs"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/report"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/thresholds"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/upload"
 )
 
-// Config holds the step configuration
-type Config struct {
-	ArtifactPath      string `env:"artifact_path"`
-	OutputFormats     string `env:"output_formats,required"`
-	PostGithubComment string `env:"post_github_comment"`
-	GithubToken       string `env:"github_token"`
-	FailOnLargeSize   string `env:"fail_on_large_size"`
-}
+func main() {
+	stepStart := time.Now()
 
-// BundleMetrics holds the parsed bundle analysis metrics
-type BundleMetrics struct {
-	SizeBytes              int64
-	SizeMB                 string
-	PotentialSavingsBytes  int64
-}
+	// Every log line goes through the redactor, command output may contain credentials
+	redactor := &logging.Redactor{}
+	logOutput := logging.NewRedactingWriter(redactor, os.Stdout)
 
-// ReportPaths holds the paths to generated reports
-type ReportPaths struct {
-	Markdown string
-	HTML     string
-	JSON     string
-}
+	envRepo := env.NewRepository()
+	cmdFactory := command.NewFactory(envRepo)
 
-func main() {
-	logger := log.NewLogger()
+	// The log format is needed before the configuration is parsed, so parse errors are logged in the same format
+	var logger log.Logger
+	var jsonLogger *logging.JSONLogger
+	if envRepo.Get("log_format") == "json" {
+		jsonLogger = logging.NewJSONLogger(logOutput)
+		logger = jsonLogger
+	} else {
+		logger = log.NewLogger(log.WithOutput(logOutput))
+	}
 
 	// Parse configuration
-	var cfg Config
-	if err := stepconf.Parse(&cfg); err != nil {
+	cfg, err := config.Parse(envRepo)
+	if err != nil {
 		logger.Errorf("Failed to parse configuration: %s", err)
+		logOutput.Flush()
 		os.Exit(1)
 	}
-	stepconf.Print(cfg)
+	redactor.AddFromConfig(cfg, envRepo)
+
+	// The summary is printed with every log_level, the rest of the log is filtered by the level
+	summaryLogger := logger
+	levelLogger := logging.NewLevelLogger(logger, cfg.LogLevel)
+	logger = levelLogger
+	if cfg.LogLevel == logging.LevelDebug {
+		http.DefaultTransport = logging.NewTraceTransport(http.DefaultTransport, logger)
+	}
+
+	if jsonLogger != nil {
+		jsonLogger.Log("info", "Configuration", config.Fields(cfg))
+	} else if levelLogger.Logs(logging.LevelInfo) {
+		stepconf.Print(cfg)
+	}
 
 	logger.Println()
 	logger.Infof("Bundle Analyzer Step")
 	logger.Println()
 
-	// Detect artifact path
-	artifactPath, err := detectArtifact(cfg, logger)
+	// Bitrise sends SIGTERM when the build is aborted: the context stops the plugin and the HTTP calls,
+	// then the partial results are exported. A second signal terminates the step immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Create the working directory for reports and intermediate files
+	tempDir, err := createWorkDir(cfg.WorkDir)
 	if err != nil {
-		logger.Errorf("Failed to detect artifact: %s", err)
+		logger.Errorf("Failed to create working directory: %s", err)
+		logOutput.Flush()
 		os.Exit(1)
 	}
-	logger.Infof("Analyzing artifact: %s", artifactPath)
+	logger.Infof("Using working directory: %s", tempDir)
+
+	exporter := outputs.NewExporter(cmdFactory, cfg.OutputEnvPrefix, cfg.Units(), logger)
+	deployDir := envRepo.Get("BITRISE_DEPLOY_DIR")
+	buildSlug := envRepo.Get("BITRISE_BUILD_SLUG")
+	report.CleanStaleReports(deployDir, buildSlug, cfg.StaleReports, analyze.ExistingReportFiles(cfg.ExistingReportPath), logger)
+
+	var profiler *report.Profiler
+	if cfg.Profile {
+		profiler = report.NewProfiler()
+		if cfg.ProfilePprof {
+			if deployDir == "" {
+				logger.Warnf("BITRISE_DEPLOY_DIR not set, skipping CPU profile")
+			} else if profilePath, err := profiler.StartCPUProfile(deployDir); err != nil {
+				logger.Warnf("%s", err)
+			} else {
+				logger.Infof("Writing CPU profile to: %s", profilePath)
+			}
+		}
+	}
 
-	// Validate artifact exists
-	if _, err := os.Stat(artifactPath); os.IsNotExist(err) {
-		logger.Errorf("Artifact file does not exist: %s", artifactPath)
-		os.Exit(1)
+	// os.Exit skips deferred calls, so every exit from here on goes through exit to clean up the working directory
+	exit := func(code int) {
+		finishProfile(profiler, exporter, logger)
+		removeWorkDir(tempDir, cfg.KeepWorkDir, logger)
+		logOutput.Flush()
+		os.Exit(code)
+	}
+
+	var debugBundle *report.DebugBundle
+	if cfg.ExportDebugBundle {
+		debugBundle = report.NewDebugBundle(envRepo)
+	}
+
+	retry := executor.ParseRetryOptions(cfg, logger)
+	exec := executor.New(cmdFactory, envRepo, retry, logger)
+
+	// Detect artifact paths
+	detectStart := time.Now()
+	artifactPaths, err := detect.NewDetector(envRepo, retry, logger).Artifacts(ctx, cfg, filepath.Join(tempDir, "source-build"))
+	if err != nil {
+		logger.Errorf("Failed to detect artifact: %s", err)
+		exit(1)
+	}
+	profiler.Track("detect", detectStart)
+
+	// Validate artifacts exist (the artifact of an existing report may have been built on a different stack)
+	for _, artifactPath := range artifactPaths {
+		if _, err := os.Stat(artifactPath); os.IsNotExist(err) {
+			if cfg.ExistingReportPath != "" {
+				logger.Warnf("Artifact file does not exist, content-based checks are skipped: %s", artifactPath)
+				continue
+			}
+			logger.Errorf("Artifact file does not exist: %s", artifactPath)
+			exit(1)
+		}
 	}
 
+	analyze.ApplyMemoryLimit(cfg.MemoryLimitMB, logger)
+
 	// Ensure bundle-inspector plugin is installed
-	logger.Println()
-	if err := ensureBundleInspectorInstalled(logger); err != nil {
-		logger.Errorf("Failed to ensure bundle-inspector is installed: %s", err)
-		os.Exit(1)
+	if cfg.ExistingReportPath == "" {
+		logger.Println()
+		installStart := time.Now()
+		if err := analyze.NewPluginInstaller(cmdFactory, envRepo, exporter, logger).EnsureInstalled(ctx, analyze.PluginOptions{
+			Version:      strings.TrimPrefix(cfg.PluginVersion, "v"),
+			Source:       cfg.PluginSource,
+			CacheDir:     cfg.PluginCacheDir,
+			UpdatePolicy: cfg.PluginUpdatePolicy,
+			Retry:        retry,
+		}); err != nil {
+			logger.Errorf("Failed to ensure bundle-inspector is installed: %s", err)
+			exit(1)
+		}
+		debugBundle.Track("plugin_install", installStart)
+		profiler.Track("install", installStart)
+	}
+
+	// Load the build history for baseline comparison
+	var history *analyze.History
+	if cfg.HistoryFile != "" {
+		logger.Println()
+		logger.Infof("Loading build history from: %s", cfg.HistoryFile)
+		loaded, err := analyze.LoadHistory(cfg.HistoryFile)
+		if err != nil {
+			logger.Warnf("Failed to load build history: %s", err)
+		} else {
+			logger.Printf("Found %d recorded build(s)", len(loaded.Records))
+			history = &loaded
+		}
+	}
+
+	// Fetch the latest Firebase App Distribution release for the "vs last distributed build" comparison
+	var distributed *firebase.Release
+	if cfg.FirebaseAppID != "" {
+		logger.Println()
+		distributed = firebase.NewFetcher(cfg, retry, logger).Fetch(ctx)
+	}
+
+	// Fetch the production release of Google Play for the "vs Play production" comparison
+	var playRelease *google.PlayRelease
+	if cfg.PlayServiceAccountKey != "" {
+		logger.Println()
+		playRelease = google.NewPlayFetcher(cfg, retry, logger).Fetch(ctx, artifactPaths)
+	}
+
+	// Fetch the file sizes of the latest App Store Connect build for the "vs App Store Connect" comparison
+	var appStoreSizes *appstoreconnect.BuildSizes
+	appStoreFetcher := appstoreconnect.NewFetcher(cfg, retry, logger)
+	appStoreDiscrepancy := appStoreFetcher.DiscrepancyPercent()
+	if cfg.AppStoreConnectPrivateKey != "" {
+		logger.Println()
+		appStoreSizes = appStoreFetcher.Fetch(ctx, artifactPaths)
+	}
+
+	// Look for a size allowance override directive in the commit message or PR title
+	override := thresholds.FindSizeOverride(envRepo, cfg.Units())
+	if override != nil {
+		logger.Println()
+		logger.Warnf("Size allowance override active: %s (%s found in %s)", cfg.Units().FormatDelta(override.AllowanceBytes), override.Directive, override.Source)
 	}
 
-	// Create temporary directory for reports
-	tempDir, err := os.MkdirTemp("", "bundle-analyzer-*")
+	// Numbers and dates of the step generated report sections follow report_locale and report_timezone
+	locale, err := report.NewLocale(cfg.ReportLocale, cfg.ReportTimezone, cfg.Units())
 	if err != nil {
-		logger.Errorf("Failed to create temporary directory: %s", err)
-		os.Exit(1)
+		logger.Warnf("Failed to set up the report format, using the default: %s", err)
 	}
-	defer os.RemoveAll(tempDir) // Clean up temp directory when done
-	logger.Infof("Using temporary directory: %s", tempDir)
 
-	// Run bundle-inspector
-	logger.Println()
-	logger.Infof("Running bundle-inspector analysis...")
-	if err := runBundleInspector(artifactPath, cfg.OutputFormats, tempDir, logger); err != nil {
-		logger.Errorf("Bundle analysis failed: %s", err)
-		os.Exit(1)
+	// Emoji of the reports and the PR comment follow report_icons, report_status_icons and report_severity_colors
+	icons, err := report.NewIcons(cfg.ReportIcons, cfg.ReportStatusIcons, cfg.ReportSeverityColors)
+	if err != nil {
+		logger.Warnf("Failed to set up the report icons, keeping the emoji: %s", err)
 	}
 
-	// Find generated report files
-	logger.Println()
-	logger.Infof("Locating generated report files...")
-	generatedFiles, err := findGeneratedReports(tempDir, logger)
+	// File listings of the markdown and HTML reports follow report_max_files and report_max_depth
+	listings, err := report.NewListingLimits(cfg.ReportMaxFiles, cfg.ReportMaxDepth)
 	if err != nil {
-		logger.Warnf("Failed to locate reports: %s", err)
+		logger.Warnf("Failed to set up the report listing limits, listing every file: %s", err)
 	}
 
-	// Parse JSON report to extract metrics
-	var metrics BundleMetrics
-	formats := strings.Split(cfg.OutputFormats, ",")
-	if contains(formats, "json") && generatedFiles.JSON != "" {
+	// Limit the time of the analysis
+	analysisCtx := ctx
+	analysisTimeout, err := analyze.ParseAnalysisTimeout(cfg.AnalysisTimeout)
+	if err != nil {
+		logger.Warnf("Invalid analysis_timeout value: %s", cfg.AnalysisTimeout)
+	} else if analysisTimeout > 0 {
+		var cancel context.CancelFunc
+		analysisCtx, cancel = context.WithTimeout(ctx, analysisTimeout)
+		defer cancel()
+	}
+
+	// Analyze the artifacts, multiple artifacts concurrently
+	multipleArtifacts := len(artifactPaths) > 1
+	concurrency := analyze.ParseConcurrency(cfg, logger)
+	analysis := artifactAnalysis{
+		cfg:                 cfg,
+		cmdFactory:          cmdFactory,
+		envRepo:             envRepo,
+		history:             history,
+		distributed:         distributed,
+		playRelease:         playRelease,
+		appStoreSizes:       appStoreSizes,
+		appStoreDiscrepancy: appStoreDiscrepancy,
+		override:            override,
+		locale:              locale,
+		icons:               icons,
+		listings:            listings,
+		retry:               retry,
+		debugBundle:         debugBundle,
+		profiler:            profiler,
+		tempDir:             tempDir,
+		deployDir:           deployDir,
+		count:               len(artifactPaths),
+		concurrent:          multipleArtifacts && concurrency > 1,
+		logger:              logger,
+	}
+	analysis.jsonLogger = jsonLogger
+	if analysis.concurrent {
 		logger.Println()
-		logger.Infof("Parsing JSON report for metrics...")
-		metrics, err = parseJSONReport(generatedFiles.JSON, logger)
-		if err != nil {
-			logger.Warnf("Failed to parse JSON report (will use empty metrics): %s", err)
+		logger.Infof("Analyzing %d artifacts, %d at a time", len(artifactPaths), concurrency)
+	}
+
+	artifactResults := make([]analyze.ArtifactResult, len(artifactPaths))
+	artifactErrs := make([]error, len(artifactPaths))
+	analyzed := make([]bool, len(artifactPaths))
+	analyze.RunConcurrently(concurrency, len(artifactPaths), func(idx int) {
+		// The remaining artifacts are skipped once analysis_timeout is over or the step is canceled
+		if analysisCtx.Err() != nil {
+			return
+		}
+		artifactResults[idx], artifactErrs[idx] = analysis.run(analysisCtx, idx, artifactPaths[idx])
+		analyzed[idx] = true
+	})
+
+	var results []analyze.ArtifactResult
+	var analysisErrs []error
+	timedOut := false
+	for idx := range artifactPaths {
+		if !analyzed[idx] {
+			continue
+		}
+		results = append(results, artifactResults[idx])
+		timedOut = timedOut || artifactResults[idx].TimedOut
+		if err := artifactErrs[idx]; err != nil {
+			if multipleArtifacts {
+				err = fmt.Errorf("%s: %w", artifactResults[idx].Name, err)
+			}
+			analysisErrs = append(analysisErrs, err)
+		}
+	}
+	canceled := ctx.Err() != nil
+	if skipped := len(artifactPaths) - len(results); skipped > 0 {
+		logger.Println()
+		if canceled {
+			logger.Warnf("Skipped %d artifact(s), the step was canceled", skipped)
+		} else {
+			logger.Warnf("Skipped %d artifact(s), analysis_timeout is over", skipped)
+		}
+	}
+	if len(results) == 0 {
+		if canceled {
+			logger.Errorf("Bundle analysis canceled before analyzing any artifact")
+			exit(1)
 		}
+		logger.Errorf("Bundle analysis timed out after %s before analyzing any artifact", analysisTimeout)
+		exporter.ExportTimedOut(true)
+		exit(1)
 	}
 
-	// Deploy reports to BITRISE_DEPLOY_DIR
-	deployDir := os.Getenv("BITRISE_DEPLOY_DIR")
-	var reportPaths ReportPaths
-	if deployDir != "" {
+	if cfg.ResultCacheDir != "" {
+		if err := analyze.AddCacheIncludePath(cfg.ResultCacheDir, envRepo, exporter); err != nil {
+			logger.Warnf("Failed to add result cache to BITRISE_CACHE_INCLUDE_PATHS: %s", err)
+		}
+	}
+
+	if len(analysisErrs) > 0 {
 		logger.Println()
-		logger.Infof("Deploying reports to: %s", deployDir)
-		reportPaths, err = deployReportsFromFiles(generatedFiles, deployDir, logger)
-		if err != nil {
-			logger.Warnf("Failed to deploy reports: %s", err)
+		for _, err := range analysisErrs {
+			logger.Errorf("Bundle analysis failed: %s", err)
 		}
-	} else {
-		logger.Warnf("BITRISE_DEPLOY_DIR not set, reports will remain in temporary directory: %s", tempDir)
-		// Use generated files as-is
-		reportPaths = generatedFiles
+		status := report.StatusError
+		if timedOut {
+			exporter.ExportTimedOut(true)
+			status = report.StatusTimedOut
+		}
+		writeDebugBundle(debugBundle, deployDir, cfg, results, redactor, exporter, logger)
+		writeResultFile(deployDir, buildSlug, status, stepStart, results, exporter, logger)
+		exit(1)
+	}
+
+	// A canceled step only flushes the partial results, the PR comment, the uploads and the history are skipped
+	if canceled {
+		logger.Println()
+		logger.Warnf("Step canceled, exporting the partial results...")
+		if err := exporter.ExportOutputs(results[0].Metrics, results[0].Comparison, results[0].ReportPaths, results[0].Signature.NotAfter, false); err != nil {
+			logger.Warnf("Failed to export some outputs: %s", err)
+		}
+		exporter.ExportTimedOut(timedOut)
+		exporter.ExportDebugBuild(isDebugBuild(results))
+		exporter.ExportTopDirectories(results[0].Inventory, cfg.TopDirectoriesCount)
+		if distributed != nil && distributed.Comparable(results[0].ArtifactPath) {
+			exporter.ExportDistributedSizeDelta(results[0].Metrics.SizeBytes - distributed.SizeBytes)
+		}
+		if results[0].SymbolsChecked {
+			exporter.ExportSymbolsPresent(results[0].DebugSymbols.Present())
+		}
+		writeDebugBundle(debugBundle, deployDir, cfg, results, redactor, exporter, logger)
+		writeResultFile(deployDir, buildSlug, report.StatusCanceled, stepStart, results, exporter, logger)
+		logger.Println()
+		logger.Errorf("Bundle analysis canceled, the results are partial")
+		exit(1)
 	}
 
+	// Upload the reports to the storage providers, before the PR comment links them
+	uploadedBy, uploaded := upload.NewPublisher(cfg, envRepo, retry, logger).Publish(ctx, results)
+
 	// Handle GitHub PR comments
 	commentPosted := false
-	if cfg.PostGithubComment != "no" && contains(formats, "markdown") {
-		if isPullRequest() {
+	if cfg.PostGithubComment != "no" && cfg.HasOutputFormat("markdown") {
+		if github.IsPullRequest(envRepo) {
 			logger.Println()
 			logger.Infof("Pull request detected, preparing GitHub comment...")
 
-			markdownPath := reportPaths.Markdown
-			if markdownPath == "" {
-				markdownPath = "analysis.md"
-			}
-
-			err := postGitHubComment(markdownPath, cfg.GithubToken, logger)
-			if err != nil {
-				if cfg.PostGithubComment == "yes" {
-					logger.Errorf("Failed to post GitHub comment: %s", err)
-					os.Exit(1)
-				} else {
-					logger.Warnf("Failed to post GitHub comment (non-fatal in auto mode): %s", err)
+			commentStart := time.Now()
+			commenter := github.NewCommenter(exec, envRepo, retry, logger)
+			markdownPaths := commentBodies(cfg, results, locale, deployDir, tempDir, upload.URLs(uploaded), envRepo, logger)
+			commentPosted = len(markdownPaths) > 0
+			for _, markdownPath := range markdownPaths {
+				if err := report.ApplyIcons(analyze.ReportPaths{Markdown: markdownPath}, icons); err != nil {
+					logger.Warnf("Failed to rewrite comment icons: %s", err)
 				}
-			} else {
-				commentPosted = true
+				if err := commenter.PostComment(ctx, markdownPath, string(cfg.GithubToken)); err != nil {
+					commentPosted = false
+					if cfg.PostGithubComment == "yes" {
+						logger.Errorf("Failed to post GitHub comment: %s", err)
+						exit(1)
+					} else {
+						logger.Warnf("Failed to post GitHub comment (non-fatal in auto mode): %s", err)
+					}
+				}
+			}
+			profiler.Track("comment", commentStart)
+			if commentPosted {
 				logger.Donef("GitHub PR comment posted successfully")
 			}
 		} else {
@@ -156,315 +408,576 @@ func main() {
 		}
 	}
 
-	// Export outputs
+	// Export outputs (the first artifact's metrics when multiple artifacts are analyzed)
 	logger.Println()
 	logger.Infof("Exporting outputs...")
-	if err := exportOutputs(metrics, reportPaths, commentPosted, logger); err != nil {
+	if err := exporter.ExportOutputs(results[0].Metrics, results[0].Comparison, results[0].ReportPaths, results[0].Signature.NotAfter, commentPosted); err != nil {
 		logger.Warnf("Failed to export some outputs: %s", err)
 	}
-
-	// Check size threshold
-	if cfg.FailOnLargeSize != "" && metrics.SizeBytes > 0 {
-		logger.Println()
-		if err := checkSizeThreshold(cfg, metrics.SizeBytes, logger); err != nil {
-			logger.Errorf("%s", err)
-			os.Exit(1)
-		}
+	exporter.ExportTimedOut(timedOut)
+	exporter.ExportDebugBuild(isDebugBuild(results))
+	exporter.ExportTopDirectories(results[0].Inventory, cfg.TopDirectoriesCount)
+	if distributed != nil && distributed.Comparable(results[0].ArtifactPath) {
+		exporter.ExportDistributedSizeDelta(results[0].Metrics.SizeBytes - distributed.SizeBytes)
 	}
-
-	logger.Println()
-	logger.Donef("Bundle analysis completed successfully")
-}
-
-// detectArtifact determines the artifact path from config or environment variables
-func detectArtifact(cfg Config, logger log.Logger) (string, error) {
-	// Priority 1: Explicit artifact_path
-	if cfg.ArtifactPath != "" {
-		return cfg.ArtifactPath, nil
+	if results[0].SymbolsChecked {
+		exporter.ExportSymbolsPresent(results[0].DebugSymbols.Present())
 	}
-
-	// Priority 2: BITRISE_IPA_PATH
-	if ipaPath := os.Getenv("BITRISE_IPA_PATH"); ipaPath != "" {
-		logger.Infof("Auto-detected iOS artifact from BITRISE_IPA_PATH")
-		return ipaPath, nil
+	if reports := uploadedBy[upload.ProviderS3]; len(reports) > 0 {
+		exporter.ExportS3ReportURL(upload.PrimaryURL(reports))
+	}
+	if reports := uploadedBy[upload.ProviderGCS]; len(reports) > 0 {
+		exporter.ExportGCSReportURL(upload.PrimaryURL(reports))
+	}
+	if reports := uploadedBy[upload.ProviderAzure]; len(reports) > 0 {
+		exporter.ExportAzureReportURL(upload.PrimaryURL(reports))
 	}
 
-	// Priority 3: BITRISE_AAB_PATH
-	if aabPath := os.Getenv("BITRISE_AAB_PATH"); aabPath != "" {
-		logger.Infof("Auto-detected Android App Bundle from BITRISE_AAB_PATH")
-		return aabPath, nil
+	// Upload metrics to Bitrise Insights
+	if cfg.InsightsEndpoint != "" {
+		logger.Println()
+		logger.Infof("Uploading metrics to Bitrise Insights...")
+		if err := bitrise.UploadInsightsMetrics(ctx, cfg.InsightsEndpoint, string(cfg.InsightsToken), results, envRepo, retry, logger); err != nil {
+			logger.Warnf("Failed to upload metrics to Bitrise Insights: %s", err)
+		} else {
+			logger.Donef("Metrics uploaded to Bitrise Insights")
+		}
 	}
 
-	// Priority 4: BITRISE_APK_PATH
-	if apkPath := os.Getenv("BITRISE_APK_PATH"); apkPath != "" {
-		logger.Infof("Auto-detected Android APK from BITRISE_APK_PATH")
-		return apkPath, nil
+	// Submit metrics to Datadog
+	if cfg.DatadogAPIKey != "" {
+		logger.Println()
+		logger.Infof("Submitting metrics to Datadog...")
+		if err := datadog.NewClient(cfg, envRepo, retry, logger).SubmitMetrics(ctx, results); err != nil {
+			logger.Warnf("Failed to submit metrics to Datadog: %s", err)
+		} else {
+			logger.Donef("Metrics submitted to Datadog")
+		}
 	}
 
-	return "", fmt.Errorf("no artifact found: provide artifact_path input or ensure BITRISE_IPA_PATH, BITRISE_AAB_PATH, or BITRISE_APK_PATH is set")
-}
+	// Check thresholds
+	thresholdsStart := time.Now()
+	checker := thresholds.NewChecker(cfg, envRepo, logger)
+	var violations []error
+	var records []analyze.HistoryRecord
+	for idx, result := range results {
+		if multipleArtifacts {
+			logger.Println()
+			logger.Infof("Checking thresholds for %s", result.Name)
+		}
 
-// ensureBundleInspectorInstalled checks if bundle-inspector is installed and installs it if needed
-func ensureBundleInspectorInstalled(logger log.Logger) error {
-	cmdFactory := command.NewFactory(env.NewRepository())
+		resultViolations := checker.Check(result, override)
 
-	// Check if plugin is installed
-	logger.Infof("Checking for bundle-inspector plugin...")
-	checkCmd := cmdFactory.Create("bitrise", []string{"plugin", "list"}, nil)
-	out, err := checkCmd.RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to check installed plugins: %w", err)
-	}
+		record := analyze.NewHistoryRecord(result.Name, result.Metrics, envRepo)
+		if result.PermissionsRead {
+			permissions := result.Permissions
+			record.Permissions = &permissions
+		}
+		if result.AttackSurfaceRead {
+			entries := result.AttackSurface.Entries()
+			record.AttackSurface = &entries
+		}
+		if result.LibrariesRead {
+			libraries := analyze.LibraryEntries(result.Libraries)
+			record.Libraries = &libraries
+		}
+		for _, violation := range resultViolations {
+			record.Violations = append(record.Violations, violation.Check)
+		}
+		records = append(records, record)
 
-	// Check if bundle-inspector is in the list
-	if strings.Contains(out, "bundle-inspector") {
-		logger.Donef("bundle-inspector plugin is already installed")
-		return nil
+		if len(resultViolations) > 0 && cfg.GraceBuilds != "" {
+			logger.Println()
+		}
+		results[idx].Violations = checker.ApplyGracePeriod(resultViolations, history, result.Name)
+		for _, violation := range results[idx].Violations {
+			if multipleArtifacts {
+				violations = append(violations, fmt.Errorf("%s: %w", result.Name, violation.Err))
+			} else {
+				violations = append(violations, violation.Err)
+			}
+		}
 	}
 
-	// Plugin not installed, install it
-	logger.Warnf("bundle-inspector plugin not found, installing...")
-	installCmd := cmdFactory.Create("bitrise", []string{"plugin", "install", "https://github.com/bitrise-io/bitrise-plugins-bundle-inspector.git"}, nil)
+	debugBundle.Track("thresholds", thresholdsStart)
+	profiler.Track("thresholds", thresholdsStart)
 
-	logger.Printf("$ %s", installCmd.PrintableCommandArgs())
-
-	installOut, err := installCmd.RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		if installOut != "" {
-			logger.Printf("%s", installOut)
+	// Record the current build in the history file
+	if history != nil {
+		logger.Println()
+		for _, record := range records {
+			history.Append(record)
+		}
+		if err := analyze.SaveHistory(cfg.HistoryFile, *history); err != nil {
+			logger.Warnf("Failed to update build history: %s", err)
+		} else {
+			logger.Printf("Recorded build in history file: %s", cfg.HistoryFile)
 		}
-		return fmt.Errorf("failed to install bundle-inspector plugin: %w", err)
 	}
 
-	if installOut != "" {
-		logger.Printf("%s", installOut)
+	// Summarize the results in the build's annotations
+	if cfg.PostAnnotation {
+		logger.Println()
+		logger.Infof("Adding build annotation...")
+		if err := bitrise.AddBuildAnnotation(ctx, exec, icons.Apply(report.AnnotationDigestMarkdown(results, locale)), report.AnnotationStyle(results)); err != nil {
+			logger.Warnf("Failed to add build annotation: %s", err)
+		} else {
+			logger.Donef("Build annotation added")
+		}
 	}
 
-	logger.Donef("bundle-inspector plugin installed successfully")
-	return nil
-}
+	// Publish the results to the Test Reports add-on
+	if cfg.PublishTestReport {
+		logger.Println()
+		if testResultDir := envRepo.Get("BITRISE_TEST_RESULT_DIR"); testResultDir == "" {
+			logger.Warnf("BITRISE_TEST_RESULT_DIR not set, skipping test report")
+		} else if runDir, err := report.PublishTestReport(testResultDir, results, cfg.Units()); err != nil {
+			logger.Warnf("Failed to publish test report: %s", err)
+		} else {
+			logger.Donef("Test report published: %s", runDir)
+		}
+	}
 
-// runBundleInspector executes the bundle-inspector plugin
-func runBundleInspector(artifactPath, formats, workingDir string, logger log.Logger) error {
-	cmdFactory := command.NewFactory(env.NewRepository())
+	// Send the analysis events with the threshold outcomes to New Relic
+	if cfg.NewRelicLicenseKey != "" {
+		logger.Println()
+		logger.Infof("Sending events to New Relic...")
+		if err := newrelic.NewClient(cfg, envRepo, retry, logger).SendEvents(ctx, results); err != nil {
+			logger.Warnf("Failed to send events to New Relic: %s", err)
+		} else {
+			logger.Donef("Events sent to New Relic")
+		}
+	}
 
-	// Temporarily unset BITRISE_DEPLOY_DIR to prevent bundle-inspector from auto-exporting
-	// We'll handle the deployment ourselves to have full control over output location
-	originalDeployDir := os.Getenv("BITRISE_DEPLOY_DIR")
-	os.Setenv("BITRISE_DEPLOY_DIR", "")
-	defer os.Setenv("BITRISE_DEPLOY_DIR", originalDeployDir)
+	// Comment the size summary on the Jira issue of the branch or the PR
+	if cfg.JiraBaseURL != "" {
+		logger.Println()
+		jira.NewUpdater(cfg, envRepo, retry, logger).Update(ctx, results, upload.PrimaryURL(uploaded))
+	}
 
-	args := []string{":bundle-inspector", "analyze", artifactPath, "-o", formats}
-	cmd := cmdFactory.Create("bitrise", args, &command.Opts{
-		Dir: workingDir,
-	})
+	writeDebugBundle(debugBundle, deployDir, cfg, results, redactor, exporter, logger)
 
-	logger.Printf("$ %s", cmd.PrintableCommandArgs())
-	logger.Printf("Working directory: %s", workingDir)
+	logSummary(results, violations, cfg.Units(), summaryLogger)
 
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		if out != "" {
-			logger.Printf("%s", out)
+	status := report.StatusPassed
+	if len(violations) > 0 {
+		status = report.StatusWarned
+		if !handleViolations(ctx, cfg, violations, envRepo, retry, logger) {
+			writeResultFile(deployDir, buildSlug, report.StatusFailed, stepStart, results, exporter, logger)
+			exit(1)
 		}
-		return fmt.Errorf("bundle-inspector failed: %w", err)
 	}
 
-	// Only print output in debug mode to avoid duplicate logging
-	// (we'll log the located files separately)
-	if out != "" && os.Getenv("BITRISE_STEP_DEBUG") == "true" {
-		logger.Printf("%s", out)
+	if timedOut {
+		writeResultFile(deployDir, buildSlug, report.StatusTimedOut, stepStart, results, exporter, logger)
+		logger.Println()
+		logger.Errorf("Bundle analysis timed out after %s, the results are partial", analysisTimeout)
+		exit(1)
 	}
 
-	return nil
+	writeResultFile(deployDir, buildSlug, status, stepStart, results, exporter, logger)
+	logger.Println()
+	logger.Donef("Bundle analysis completed successfully")
+	exit(0)
 }
 
-// findGeneratedReports locates the report files generated by bundle-inspector
-func findGeneratedReports(searchDir string, logger log.Logger) (ReportPaths, error) {
-	var paths ReportPaths
+// artifactAnalysis analyzes a single artifact and deploys its reports, it is shared by the concurrent analyses
+type artifactAnalysis struct {
+	cfg           config.Config
+	cmdFactory    command.Factory
+	envRepo       env.Repository
+	history       *analyze.History
+	distributed   *firebase.Release
+	playRelease   *google.PlayRelease
+	appStoreSizes *appstoreconnect.BuildSizes
+	// appStoreDiscrepancy is the difference of the local estimate from the App Store Connect size in percent that is
+	// flagged
+	appStoreDiscrepancy float64
+	override            *thresholds.SizeOverride
+	locale              report.Locale
+	icons               report.Icons
+	listings            report.ListingLimits
+	retry               executor.RetryOptions
+	debugBundle         *report.DebugBundle
+	profiler            *report.Profiler
+	tempDir             string
+	deployDir           string
+	count               int
+	concurrent          bool
+	logger              log.Logger
+	jsonLogger          *logging.JSONLogger
+}
 
-	// Bundle-inspector generates files with pattern: bundle-analysis-*.{md,html,json}
-	if matches, _ := filepath.Glob(filepath.Join(searchDir, "bundle-analysis-*.md")); len(matches) > 0 {
-		paths.Markdown = matches[0]
-		logger.Printf("Found: %s", matches[0])
+// run analyzes the artifact in its own working directory, with multiple artifacts the reports are deployed with a prefix
+func (a artifactAnalysis) run(ctx context.Context, idx int, artifactPath string) (analyze.ArtifactResult, error) {
+	name := analyze.ArtifactName(artifactPath)
+	logger := a.logger
+	if a.concurrent {
+		logger = logging.NewPrefixLogger(a.logger, fmt.Sprintf("[%s] ", name))
 	}
 
-	if matches, _ := filepath.Glob(filepath.Join(searchDir, "bundle-analysis-*.html")); len(matches) > 0 {
-		paths.HTML = matches[0]
-		logger.Printf("Found: %s", matches[0])
+	logger.Println()
+	workDir := a.tempDir
+	reportPrefix := ""
+	if a.count > 1 {
+		logger.Infof("Analyzing artifact (%d/%d): %s", idx+1, a.count, artifactPath)
+		workDir = filepath.Join(a.tempDir, strconv.Itoa(idx+1))
+		if err := os.MkdirAll(workDir, 0755); err != nil {
+			return analyze.ArtifactResult{ArtifactPath: artifactPath, Name: name}, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		reportPrefix = strings.TrimSuffix(name, filepath.Ext(name)) + "-"
+	} else {
+		logger.Infof("Analyzing artifact: %s", artifactPath)
+	}
+
+	analysisStart := time.Now()
+	result, err := analyze.NewAnalyzer(a.cmdFactory, a.envRepo, logger).Analyze(ctx, a.cfg, artifactPath, workDir, a.history, a.retry)
+	a.debugBundle.Track("analysis: "+result.Name, analysisStart)
+	a.profiler.Record("analyze: "+result.Name, result.PluginDuration)
+	a.profiler.Record("parse: "+result.Name, time.Since(analysisStart)-result.PluginDuration)
+	a.debugBundle.AddWorkDir(result.Name, workDir)
+	if a.jsonLogger != nil {
+		a.jsonLogger.Log("info", "Artifact analyzed", map[string]interface{}{
+			"artifact":    result.Name,
+			"duration_ms": time.Since(analysisStart).Milliseconds(),
+			"size_bytes":  result.Metrics.SizeBytes,
+			"file_count":  result.Metrics.FileCount,
+			"timed_out":   result.TimedOut,
+			"cached":      result.Cached,
+			"succeeded":   err == nil,
+		})
 	}
-
-	if matches, _ := filepath.Glob(filepath.Join(searchDir, "bundle-analysis-*.json")); len(matches) > 0 {
-		paths.JSON = matches[0]
-		logger.Printf("Found: %s", matches[0])
+	if err != nil {
+		return result, err
 	}
 
-	return paths, nil
-}
+	// The formats rendered by the step are written from the analyzed result while the reports of bundle-inspector
+	// are extended below
+	var rendered analyze.ReportPaths
+	var renderErr error
+	var rendering sync.WaitGroup
+	rendering.Add(1)
+	go func(result analyze.ArtifactResult) {
+		defer rendering.Done()
+		rendered, renderErr = report.RenderReports(result, workDir, a.cfg.HasOutputFormat)
+	}(result)
 
-// parseJSONReport extracts metrics from the JSON report
-func parseJSONReport(jsonPath string, logger log.Logger) (BundleMetrics, error) {
-	data, err := os.ReadFile(jsonPath)
-	if err != nil {
-		return BundleMetrics{}, fmt.Errorf("failed to read JSON report: %w", err)
+	// Limit the file listings of bundle-inspector, the step generated sections are limited on their own
+	if err := report.ApplyListingLimits(result.GeneratedFiles, a.listings); err != nil {
+		logger.Warnf("Failed to limit report listings: %s", err)
 	}
 
-	var report struct {
-		ArtifactInfo struct {
-			Size          int64  `json:"size"`
-			SizeFormatted string `json:"size_formatted"`
-		} `json:"artifact_info"`
-		PotentialSavings int64 `json:"potential_savings"`
+	// Add step generated sections to the markdown report
+	var markdownSections []string
+	if len(result.DebugBuildReasons) > 0 {
+		markdownSections = append(markdownSections, report.DebugBuildMarkdown(result.DebugBuildReasons))
+	}
+	if a.override != nil {
+		markdownSections = append(markdownSections, report.SizeOverrideMarkdown(a.override, a.locale))
+	}
+	if a.distributed != nil && a.distributed.Comparable(artifactPath) {
+		logger.Printf("Size vs distributed release %s: %s", a.distributed.Version(), a.cfg.Units().FormatDelta(result.Metrics.SizeBytes-a.distributed.SizeBytes))
+		markdownSections = append(markdownSections, report.DistributedReleaseMarkdown(*a.distributed, result.Metrics.SizeBytes, a.locale))
+	}
+	if a.playRelease != nil && analyze.IsAndroidArtifact(artifactPath) && len(result.Inventory.Entries) > 0 {
+		markdownSections = append(markdownSections, report.PlayProductionMarkdown(*a.playRelease, result.Inventory, a.locale))
+	}
+	if a.appStoreSizes != nil && strings.EqualFold(filepath.Ext(artifactPath), ".ipa") {
+		estimate := result.DownloadBytes
+		if estimate == 0 {
+			estimate = result.Metrics.SizeBytes
+		}
+		if discrepancy, ok := a.appStoreSizes.Discrepancy(estimate); ok {
+			logger.Printf("Size estimate vs App Store Connect build %s: %+.1f%%", a.appStoreSizes.BuildNumber, discrepancy)
+			if math.Abs(discrepancy) > a.appStoreDiscrepancy {
+				logger.Warnf("The local download estimate differs from the App Store Connect universal download by %+.1f%%, more than %.1f%%", discrepancy, a.appStoreDiscrepancy)
+			}
+		}
+		markdownSections = append(markdownSections, report.AppStoreConnectMarkdown(*a.appStoreSizes, estimate, result.Inventory.InstallSize(), a.appStoreDiscrepancy, a.locale))
+	}
+	if result.SymbolsChecked && !result.DebugSymbols.Present() {
+		markdownSections = append(markdownSections, report.DebugSymbolsMarkdown(result.DebugSymbols))
+	}
+	if result.Scope.Limited() {
+		markdownSections = append(markdownSections, report.ExcludedEntriesMarkdown(result.Scope, result.Excluded, a.locale))
+	}
+	if len(result.ModuleResults) > 0 {
+		markdownSections = append(markdownSections, report.ModuleBudgetsMarkdown(result.ModuleResults, a.locale))
+	}
+	if result.LanguageOverheadRead {
+		markdownSections = append(markdownSections, report.LanguageOverheadMarkdown(result.LanguageOverhead, a.locale))
+	}
+	if result.WhatIfChecked {
+		markdownSections = append(markdownSections, report.WhatIfMarkdown(result.WhatIf, result.Metrics.SizeBytes, a.locale))
+	}
+	if result.SecretsScanned {
+		markdownSections = append(markdownSections, report.SecretsMarkdown(result.Secrets))
+	}
+	if result.SDKsScanned {
+		markdownSections = append(markdownSections, report.VulnerableSDKsMarkdown(result.VulnerableSDKs))
+	}
+	if result.LicensesRead {
+		markdownSections = append(markdownSections, report.LicensesMarkdown(result.Licenses, analyze.ParseDeniedLicenses(a.cfg.DeniedLicenses)))
+	}
+	if result.SignatureVerified {
+		markdownSections = append(markdownSections, report.SignatureMarkdown(result.Signature, a.locale))
+	}
+	if result.ProfileRead {
+		markdownSections = append(markdownSections, report.ProvisioningProfileMarkdown(result.Profile, a.locale))
+	}
+	if result.PrivacyChecked {
+		markdownSections = append(markdownSections, report.PrivacyMarkdown(result.Privacy))
+	}
+	if result.PermissionsRead {
+		markdownSections = append(markdownSections, report.PermissionsMarkdown(result.Permissions, result.Comparison))
+	}
+	if result.AttackSurfaceRead {
+		markdownSections = append(markdownSections, report.AttackSurfaceMarkdown(result.AttackSurface, result.Comparison))
+	}
+	if result.LibrariesRead {
+		markdownSections = append(markdownSections, report.LibraryChangesMarkdown(result.Libraries, result.Comparison))
+	}
+	if result.DebugChecked {
+		markdownSections = append(markdownSections, report.DebugArtifactsMarkdown(result.DebugFindings))
+	}
+	if result.HardeningChecked {
+		markdownSections = append(markdownSections, report.HardeningMarkdown(result.Hardening))
+	}
+	if result.ReputationChecked {
+		markdownSections = append(markdownSections, report.ReputationMarkdown(result.Reputation))
+	}
+	if result.NetworkSecurityChecked {
+		markdownSections = append(markdownSections, report.NetworkSecurityMarkdown(result.NetworkSecurity))
+	}
+	if len(markdownSections) > 0 && result.GeneratedFiles.Markdown != "" {
+		if err := report.AppendMarkdownSections(result.GeneratedFiles.Markdown, markdownSections); err != nil {
+			logger.Warnf("Failed to extend markdown report: %s", err)
+		}
+	}
+	if a.cfg.ReportTitle != "" {
+		result.Title = report.RenderTitle(a.cfg.ReportTitle, result.App, result.Name)
+		logger.Printf("Report title: %s", result.Title)
+		if err := report.ApplyTitle(result.GeneratedFiles, result.Title); err != nil {
+			logger.Warnf("Failed to set report title: %s", err)
+		}
+	}
+	if err := report.ApplyIcons(result.GeneratedFiles, a.icons); err != nil {
+		logger.Warnf("Failed to rewrite report icons: %s", err)
 	}
 
-	if err := json.Unmarshal(data, &report); err != nil {
-		return BundleMetrics{}, fmt.Errorf("failed to parse JSON report: %w", err)
+	rendering.Wait()
+	if renderErr != nil {
+		logger.Warnf("Failed to render reports: %s", renderErr)
 	}
+	result.GeneratedFiles.CSV = rendered.CSV
+	result.GeneratedFiles.SARIF = rendered.SARIF
 
-	sizeMB := fmt.Sprintf("%.2f", float64(report.ArtifactInfo.Size)/(1024*1024))
+	// Deploy reports to BITRISE_DEPLOY_DIR
+	if a.deployDir != "" {
+		logger.Println()
+		logger.Infof("Deploying reports to: %s", a.deployDir)
+		deployStart := time.Now()
+		result.ReportPaths, err = report.NewDeployer(fileutil.NewFileManager(), logger).Deploy(result.GeneratedFiles, a.deployDir, reportPrefix, a.cfg.HasDeployFormat)
+		if err != nil {
+			logger.Warnf("Failed to deploy reports: %s", err)
+		}
+		a.debugBundle.Track("deploy: "+result.Name, deployStart)
+		a.profiler.Track("deploy: "+result.Name, deployStart)
+	} else {
+		if a.cfg.KeepWorkDir {
+			logger.Warnf("BITRISE_DEPLOY_DIR not set, reports will remain in the working directory: %s", workDir)
+		} else {
+			logger.Warnf("BITRISE_DEPLOY_DIR not set, reports are removed with the working directory unless keep_work_dir is set: %s", workDir)
+		}
+		// Use generated files as-is
+		result.ReportPaths = result.GeneratedFiles
+	}
 
-	return BundleMetrics{
-		SizeBytes:             report.ArtifactInfo.Size,
-		SizeMB:                sizeMB,
-		PotentialSavingsBytes: report.PotentialSavings,
-	}, nil
+	return result, nil
 }
 
-// deployReportsFromFiles copies generated reports to BITRISE_DEPLOY_DIR
-func deployReportsFromFiles(generatedFiles ReportPaths, deployDir string, logger log.Logger) (ReportPaths, error) {
-	var paths ReportPaths
-
-	// Create deploy directory if it doesn't exist
-	if err := os.MkdirAll(deployDir, 0755); err != nil {
-		return paths, fmt.Errorf("failed to create deploy directory: %w", err)
+// commentBodies writes the markdown files posted as PR comments according to comment_layout: sections posts the
+// reports of the artifacts in one comment, table a single table row per artifact, per_comment a comment per artifact.
+// The links to the deployed reports are only added to the comments, the deployed markdown reports are left as is.
+func commentBodies(cfg config.Config, results []analyze.ArtifactResult, locale report.Locale, deployDir, tempDir string, uploaded map[string]string, envRepo env.Repository, logger log.Logger) []string {
+	withLinks := func(markdownPath, name string, linked []analyze.ArtifactResult) string {
+		links := report.ReportLinksMarkdown(linked, deployDir, envRepo.Get("BITRISE_BUILD_URL"), envRepo.Get("BITRISE_BUILD_NUMBER"), uploaded)
+		if links == "" {
+			return markdownPath
+		}
+		commentPath := filepath.Join(tempDir, name)
+		if err := report.ExtendMarkdownReport(markdownPath, commentPath, []string{links}); err != nil {
+			logger.Warnf("Failed to add report links to the comment: %s", err)
+			return markdownPath
+		}
+		return commentPath
 	}
 
-	// Helper function to copy a single file
-	copyFile := func(srcPath string) string {
-		if srcPath == "" {
-			return ""
+	switch cfg.CommentLayout {
+	case report.CommentLayoutTable:
+		markdownPath := filepath.Join(tempDir, "bundle-analysis-table.md")
+		if err := report.WriteCommentTable(results, locale, markdownPath); err != nil {
+			logger.Warnf("Failed to write the comment table: %s", err)
 		}
-
-		filename := filepath.Base(srcPath)
-		dstPath := filepath.Join(deployDir, filename)
-
-		data, err := os.ReadFile(srcPath)
-		if err != nil {
-			logger.Warnf("Failed to read %s: %s", srcPath, err)
-			return ""
+		return []string{withLinks(markdownPath, "bundle-analysis-comment.md", results)}
+	case report.CommentLayoutPerComment:
+		var markdownPaths []string
+		for idx, result := range results {
+			// Every comment is headed by the artifact like the sections of the combined report
+			markdownPath := filepath.Join(tempDir, fmt.Sprintf("bundle-analysis-%d.md", idx+1))
+			if err := report.CombineMarkdownReports(results[idx:idx+1], markdownPath); err != nil {
+				logger.Warnf("Failed to prepare the comment of %s, skipping it: %s", result.Name, err)
+				continue
+			}
+			markdownPaths = append(markdownPaths, withLinks(markdownPath, fmt.Sprintf("bundle-analysis-comment-%d.md", idx+1), results[idx:idx+1]))
 		}
+		return markdownPaths
+	}
 
-		if err := os.WriteFile(dstPath, data, 0644); err != nil {
-			logger.Warnf("Failed to write %s: %s", dstPath, err)
-			return ""
+	markdownPath := results[0].ReportPaths.Markdown
+	if len(results) > 1 {
+		markdownPath = filepath.Join(tempDir, "bundle-analysis-combined.md")
+		if err := report.CombineMarkdownReports(results, markdownPath); err != nil {
+			logger.Warnf("Failed to combine markdown reports: %s", err)
 		}
-
-		logger.Printf("Deployed: %s", dstPath)
-		return dstPath
 	}
-
-	// Copy each report file
-	paths.Markdown = copyFile(generatedFiles.Markdown)
-	paths.HTML = copyFile(generatedFiles.HTML)
-	paths.JSON = copyFile(generatedFiles.JSON)
-
-	return paths, nil
+	if markdownPath == "" {
+		markdownPath = "analysis.md"
+	}
+	return []string{withLinks(markdownPath, "bundle-analysis-comment.md", results)}
 }
 
-// isPullRequest checks if the current build is for a pull request
-func isPullRequest() bool {
-	prNumber := os.Getenv("BITRISE_PULL_REQUEST")
-	return prNumber != "" && prNumber != "false"
+// logSummary logs the summary table of the analyzed artifacts and the number of threshold violations, it's written to
+// the unfiltered logger so quiet builds print it too
+func logSummary(results []analyze.ArtifactResult, violations []error, units config.Units, logger log.Logger) {
+	logger.Println()
+	logger.Infof("Summary")
+	for _, line := range report.SummaryTable(results, units) {
+		logger.Printf("%s", line)
+	}
+	if len(violations) > 0 {
+		logger.Errorf("Threshold violations: %d", len(violations))
+	} else {
+		logger.Donef("Threshold violations: 0")
+	}
+	for _, result := range results {
+		if len(result.DebugBuildReasons) > 0 {
+			logger.Warnf("%s looks like a debug build, its size doesn't reflect the release build", result.Name)
+		}
+	}
 }
 
-// postGitHubComment posts the markdown report as a PR comment
-// Note: Caller should verify isPullRequest() before calling this function
-func postGitHubComment(markdownPath, token string, logger log.Logger) error {
-	if token == "" {
-		return fmt.Errorf("github_token is required for posting PR comments")
+// isDebugBuild reports whether any of the analyzed artifacts looks like a debug build
+func isDebugBuild(results []analyze.ArtifactResult) bool {
+	for _, result := range results {
+		if len(result.DebugBuildReasons) > 0 {
+			return true
+		}
 	}
+	return false
+}
 
-	// Get PR number (caller already verified this is a PR build via isPullRequest())
-	prNumber := os.Getenv("BITRISE_PULL_REQUEST")
+// handleViolations reports the threshold violations according to on_violation and returns whether the step may pass:
+// fail_step fails the step, abort_build also aborts the build so the remaining steps don't run, continue only warns
+func handleViolations(ctx context.Context, cfg config.Config, violations []error, envRepo env.Repository, retry executor.RetryOptions, logger log.Logger) bool {
+	logger.Println()
 
-	// Check if markdown file exists
-	if _, err := os.Stat(markdownPath); os.IsNotExist(err) {
-		return fmt.Errorf("markdown report not found: %s", markdownPath)
+	if cfg.OnViolation == "continue" {
+		for _, violation := range violations {
+			logger.Warnf("%s", violation)
+		}
+		logger.Warnf("on_violation is continue, not failing the build")
+		return true
 	}
 
-	// Use gh CLI to post comment
-	cmdFactory := command.NewFactory(env.NewRepository())
-	cmd := cmdFactory.Create("gh", []string{"pr", "comment", prNumber, "--body-file", markdownPath}, &command.Opts{
-		Env: []string{fmt.Sprintf("GH_TOKEN=%s", token)},
-	})
-
-	logger.Printf("Posting comment to PR #%s...", prNumber)
-
-	out, err := cmd.RunAndReturnTrimmedCombinedOutput()
-	if err != nil {
-		if out != "" {
-			logger.Printf("%s", out)
-		}
-		return fmt.Errorf("gh pr comment failed: %w", err)
+	for _, violation := range violations {
+		logger.Errorf("%s", violation)
 	}
 
-	if out != "" {
-		logger.Printf("%s", out)
+	if cfg.OnViolation == "abort_build" {
+		logger.Println()
+		logger.Infof("Aborting the build...")
+		if err := bitrise.AbortCurrentBuild(ctx, string(cfg.BitriseAPIToken), fmt.Sprintf("Bundle analyzer: %d threshold violation(s)", len(violations)), envRepo, retry, logger); err != nil {
+			logger.Warnf("Failed to abort the build, failing the step instead: %s", err)
+		} else {
+			logger.Donef("Build abort requested")
+		}
 	}
 
-	return nil
+	return false
 }
 
-// checkSizeThreshold validates the bundle size against the configured threshold
-func checkSizeThreshold(cfg Config, sizeBytes int64, logger log.Logger) error {
-	thresholdMB, err := strconv.ParseFloat(cfg.FailOnLargeSize, 64)
+// createWorkDir creates the step's working directory in work_dir, or in the system temporary directory without work_dir
+func createWorkDir(parentDir string) (string, error) {
+	if parentDir != "" {
+		if err := os.MkdirAll(parentDir, 0755); err != nil {
+			return "", err
+		}
+	}
+	workDir, err := os.MkdirTemp(parentDir, "bundle-analyzer-*")
 	if err != nil {
-		logger.Warnf("Invalid fail_on_large_size value: %s", cfg.FailOnLargeSize)
-		return nil
+		return "", err
 	}
+	return filepath.Abs(workDir)
+}
 
-	thresholdBytes := int64(thresholdMB * 1024 * 1024)
-	sizeMB := float64(sizeBytes) / (1024 * 1024)
-
-	logger.Infof("Checking size threshold: %.2f MB / %.2f MB", sizeMB, thresholdMB)
-
-	if sizeBytes > thresholdBytes {
-		return fmt.Errorf("bundle size %.2f MB exceeds threshold %.2f MB", sizeMB, thresholdMB)
+// finishProfile logs the timing of the step's phases and exports the duration of the step in profile mode
+func finishProfile(profiler *report.Profiler, exporter outputs.Exporter, logger log.Logger) {
+	if profiler == nil {
+		return
+	}
+	total := profiler.Finish(logger)
+	if err := exporter.Export("BUNDLE_ANALYSIS_DURATION_SECONDS", fmt.Sprintf("%.1f", total.Seconds())); err != nil {
+		logger.Warnf("Failed to export %s: %s", exporter.OutputKey("BUNDLE_ANALYSIS_DURATION_SECONDS"), err)
 	}
-
-	logger.Donef("Bundle size is within threshold")
-	return nil
 }
 
-// exportOutputs exports all output environment variables
-func exportOutputs(metrics BundleMetrics, paths ReportPaths, commentPosted bool, logger log.Logger) error {
-	outputs := map[string]string{
-		"BUNDLE_ANALYZER_REPORT_PATH":      paths.Markdown,
-		"BUNDLE_ANALYZER_HTML_PATH":        paths.HTML,
-		"BUNDLE_ANALYZER_JSON_PATH":        paths.JSON,
-		"BUNDLE_SIZE_BYTES":                fmt.Sprintf("%d", metrics.SizeBytes),
-		"BUNDLE_SIZE_MB":                   metrics.SizeMB,
-		"BUNDLE_POTENTIAL_SAVINGS_BYTES":   fmt.Sprintf("%d", metrics.PotentialSavingsBytes),
-		"BUNDLE_GITHUB_COMMENT_POSTED":     fmt.Sprintf("%t", commentPosted),
-	}
-
-	for key, value := range outputs {
-		if err := tools.ExportEnvironmentWithEnvman(key, value); err != nil {
-			logger.Warnf("Failed to export %s: %s", key, err)
-		} else {
-			logger.Printf("Exported: %s=%s", key, value)
-		}
+// writeResultFile writes the result status file to the deploy directory and exports its path, failures only produce warnings
+func writeResultFile(deployDir, buildSlug, status string, stepStart time.Time, results []analyze.ArtifactResult, exporter outputs.Exporter, logger log.Logger) {
+	if deployDir == "" {
+		logger.Warnf("BITRISE_DEPLOY_DIR not set, skipping result file")
+		return
 	}
+	resultPath, err := report.WriteResultFile(deployDir, buildSlug, status, time.Since(stepStart), results)
+	if err != nil {
+		logger.Warnf("Failed to write result file: %s", err)
+		return
+	}
+	if err := exporter.Export("BUNDLE_ANALYZER_RESULT_PATH", resultPath); err != nil {
+		logger.Warnf("Failed to export %s: %s", exporter.OutputKey("BUNDLE_ANALYZER_RESULT_PATH"), err)
+	}
+}
 
-	return nil
+// removeWorkDir removes the step's working directory, unless keep_work_dir is set
+func removeWorkDir(workDir string, keep bool, logger log.Logger) {
+	if keep {
+		logger.Println()
+		logger.Infof("Keeping working directory: %s", workDir)
+		return
+	}
+	if err := os.RemoveAll(workDir); err != nil {
+		logger.Warnf("Failed to remove working directory: %s", err)
+	}
 }
 
-// contains checks if a slice contains a string
-func contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if strings.TrimSpace(s) == item {
-			return true
-		}
+// writeDebugBundle writes the debug bundle to the deploy directory and exports its path, failures only produce warnings
+func writeDebugBundle(debugBundle *report.DebugBundle, deployDir string, cfg config.Config, results []analyze.ArtifactResult, redactor *logging.Redactor, exporter outputs.Exporter, logger log.Logger) {
+	if debugBundle == nil {
+		return
+	}
+
+	logger.Println()
+	if deployDir == "" {
+		logger.Warnf("BITRISE_DEPLOY_DIR not set, skipping debug bundle")
+		return
+	}
+	bundlePath, err := debugBundle.Write(deployDir, cfg, results, redactor)
+	if err != nil {
+		logger.Warnf("Failed to export debug bundle: %s", err)
+		return
+	}
+	logger.Donef("Debug bundle exported: %s", bundlePath)
+	if err := exporter.Export("BUNDLE_ANALYZER_DEBUG_BUNDLE_PATH", bundlePath); err != nil {
+		logger.Warnf("Failed to export %s: %s", exporter.OutputKey("BUNDLE_ANALYZER_DEBUG_BUNDLE_PATH"), err)
 	}
-	return false
 }
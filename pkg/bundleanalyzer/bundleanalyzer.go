@@ -0,0 +1,238 @@
+// Package bundleanalyzer analyzes iOS and Android artifacts (IPA, APK and AAB files) with the bundle-inspector plugin
+// of the Bitrise CLI, the same way the Bundle Analyzer step does.
+package bundleanalyzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/analyze"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/config"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/executor"
+	"github.com/bitrise-io/steps-bundle-analyzer/internal/report"
+)
+
+// Options configures the analysis, the zero value analyzes with the installed (or latest) bundle-inspector plugin
+type Options struct {
+	// OutputFormats are the report formats generated besides json: text, markdown and html by bundle-inspector, csv
+	// and sarif from the analysis
+	OutputFormats []string
+	// WorkDir is the directory the reports are generated in, a temporary directory is created when empty
+	WorkDir string
+	// PluginVersion pins the bundle-inspector plugin version, the plugin is reinstalled when another version is installed
+	PluginVersion string
+	// PluginSource is the Git URL or local path the plugin is installed from
+	PluginSource string
+	// RetryCount is the number of retries of transient plugin installation and analysis failures
+	RetryCount int
+	// RetryWait is the wait before the first retry
+	RetryWait time.Duration
+	// RetryBackoff multiplies the wait after every retry, values below 1 keep the wait fixed
+	RetryBackoff float64
+	// CommandTimeout limits a single plugin installation attempt, 0 means no limit
+	CommandTimeout time.Duration
+	// FindDuplicates enables the detection of byte-identical files in the artifact
+	FindDuplicates bool
+	// ScanSecrets enables the scan of bundled assets, plists and string resources for API keys, private keys and tokens
+	ScanSecrets bool
+	// SecretDetectors are the built-in detector names and "name: regex" custom detectors of the scan, all built-in detectors by default
+	SecretDetectors []string
+
+	// Logger receives the progress of the analysis, defaults to the standard Bitrise logger
+	Logger log.Logger
+	// CommandFactory creates the Bitrise CLI commands, defaults to a factory using EnvRepository
+	CommandFactory command.Factory
+	// EnvRepository is the environment the Bitrise CLI runs in, defaults to the process environment
+	EnvRepository env.Repository
+}
+
+// Report is the result of analyzing a single artifact
+type Report struct {
+	ArtifactPath          string
+	Name                  string
+	SizeBytes             int64
+	PotentialSavingsBytes int64
+	FileCount             int64
+	DexMethodCount        int64
+	// DexMethodCounts holds the method reference count of every DEX file of Android artifacts
+	DexMethodCounts map[string]int64
+	Files           []File
+	Duplicates      []Duplicate
+	// Secrets holds the potential secrets found with ScanSecrets, the values are masked
+	Secrets     []Secret
+	ReportFiles ReportFiles
+	// TimedOut is set when ctx expired during the analysis, the report is based on the files generated until then
+	TimedOut bool
+	// Canceled is set when ctx was canceled during the analysis, the report is partial and has no file list
+	Canceled bool
+}
+
+// File is a single file stored in the artifact
+type File struct {
+	Path             string
+	CompressedSize   int64
+	UncompressedSize int64
+}
+
+// Duplicate is a group of byte-identical files in the artifact
+type Duplicate struct {
+	Hash        string
+	Paths       []string
+	SizeBytes   int64
+	WastedBytes int64
+}
+
+// Secret is a potential secret found in the artifact
+type Secret struct {
+	Detector string
+	Path     string
+	Masked   string
+}
+
+// ReportFiles holds the paths of the generated reports, empty for formats not generated
+type ReportFiles struct {
+	Markdown string
+	HTML     string
+	JSON     string
+	CSV      string
+	SARIF    string
+	// Licenses is the license inventory, the library doesn't write it
+	Licenses string
+}
+
+// noopExporter drops the environment variables of the plugin installation, the library doesn't run in a step
+type noopExporter struct{}
+
+func (noopExporter) Export(string, string) error {
+	return nil
+}
+
+// Analyze installs the bundle-inspector plugin if needed and analyzes the artifact.
+// The analysis stops when ctx is done, the report of an analysis stopped after generating the JSON report is still returned.
+func Analyze(ctx context.Context, path string, opts Options) (*Report, error) {
+	envRepo := opts.EnvRepository
+	if envRepo == nil {
+		envRepo = env.NewRepository()
+	}
+	cmdFactory := opts.CommandFactory
+	if cmdFactory == nil {
+		cmdFactory = command.NewFactory(envRepo)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewLogger()
+	}
+
+	// bundle-inspector runs in the work directory
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve artifact path: %w", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("artifact not found: %w", err)
+	}
+
+	workDir := opts.WorkDir
+	if workDir == "" {
+		if workDir, err = os.MkdirTemp("", "bundle-analyzer-*"); err != nil {
+			return nil, fmt.Errorf("failed to create work directory: %w", err)
+		}
+	} else if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	retry := executor.RetryOptions{Count: opts.RetryCount, Wait: opts.RetryWait, Backoff: opts.RetryBackoff, Timeout: opts.CommandTimeout}
+	if err := analyze.NewPluginInstaller(cmdFactory, envRepo, noopExporter{}, logger).EnsureInstalled(ctx, analyze.PluginOptions{
+		Version: strings.TrimPrefix(opts.PluginVersion, "v"),
+		Source:  opts.PluginSource,
+		Retry:   retry,
+	}); err != nil {
+		return nil, err
+	}
+
+	// The metrics are read from the JSON report
+	formats, err := config.NormalizeOutputFormats(strings.Join(append([]string{"json"}, opts.OutputFormats...), ","))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.Config{OutputFormats: formats}
+	if opts.ScanSecrets {
+		cfg.ScanSecrets = true
+		cfg.SecretDetectors = strings.Join(opts.SecretDetectors, "\n")
+	}
+	result, err := analyze.NewAnalyzer(cmdFactory, envRepo, logger).Analyze(ctx, cfg, path, workDir, nil, retry)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := report.RenderReports(result, workDir, cfg.HasOutputFormat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render reports: %w", err)
+	}
+	result.GeneratedFiles.CSV = rendered.CSV
+	result.GeneratedFiles.SARIF = rendered.SARIF
+
+	report := &Report{
+		ArtifactPath:          result.ArtifactPath,
+		Name:                  result.Name,
+		SizeBytes:             result.Metrics.SizeBytes,
+		PotentialSavingsBytes: result.Metrics.PotentialSavingsBytes,
+		FileCount:             result.Metrics.FileCount,
+		DexMethodCount:        result.Metrics.DexMethodCount,
+		DexMethodCounts:       result.DexMethodCounts,
+		ReportFiles:           ReportFiles(result.GeneratedFiles),
+		TimedOut:              result.TimedOut,
+		Canceled:              result.Canceled,
+	}
+	for _, entry := range result.Inventory.Entries {
+		report.Files = append(report.Files, File{
+			Path:             entry.Path,
+			CompressedSize:   entry.CompressedSize,
+			UncompressedSize: entry.UncompressedSize,
+		})
+	}
+
+	for _, finding := range result.Secrets {
+		report.Secrets = append(report.Secrets, Secret(finding))
+	}
+
+	if opts.FindDuplicates {
+		duplicates, err := analyze.FindDuplicates(path, analyze.EntryScope{}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find duplicate files: %w", err)
+		}
+		for _, set := range duplicates {
+			report.Duplicates = append(report.Duplicates, Duplicate(set))
+		}
+	}
+
+	return report, nil
+}
+
+// LoadReport reads the metrics of a JSON report generated earlier, for example the one deployed by a CI build.
+// JSON reports don't list every file of the artifact, so the report has no Files, DexMethodCounts and Duplicates.
+func LoadReport(jsonPath string) (*Report, error) {
+	metrics, err := analyze.ParseJSONReport(jsonPath, log.NewLogger())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Name:                  filepath.Base(jsonPath),
+		SizeBytes:             metrics.SizeBytes,
+		PotentialSavingsBytes: metrics.PotentialSavingsBytes,
+		ReportFiles:           ReportFiles{JSON: jsonPath},
+	}
+	if artifactPath, err := analyze.ReportArtifactPath(jsonPath); err == nil {
+		report.ArtifactPath = artifactPath
+		report.Name = analyze.ArtifactName(artifactPath)
+	}
+	return report, nil
+}
@@ -0,0 +1,260 @@
+package bundleanalyzer
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+// fakeEnvRepository is an env.Repository backed by a map
+type fakeEnvRepository map[string]string
+
+func (r fakeEnvRepository) List() []string {
+	var envs []string
+	for key, value := range r {
+		envs = append(envs, key+"="+value)
+	}
+	return envs
+}
+
+func (r fakeEnvRepository) Unset(key string) error {
+	delete(r, key)
+	return nil
+}
+
+func (r fakeEnvRepository) Get(key string) string {
+	return r[key]
+}
+
+func (r fakeEnvRepository) Set(key, value string) error {
+	r[key] = value
+	return nil
+}
+
+// fakeCommandFactory answers the Bitrise CLI commands like an installed bundle-inspector plugin writing the JSON report
+// of the artifact into the working directory, the invoked argument lists are recorded
+type fakeCommandFactory struct {
+	calls *[]string
+}
+
+func (f fakeCommandFactory) Create(name string, args []string, opts *command.Opts) command.Command {
+	return fakeCommand{factory: f, args: args, opts: opts}
+}
+
+type fakeCommand struct {
+	factory fakeCommandFactory
+	args    []string
+	opts    *command.Opts
+}
+
+func (c fakeCommand) PrintableCommandArgs() string {
+	return "bitrise " + strings.Join(c.args, " ")
+}
+
+func (c fakeCommand) Run() error {
+	_, err := c.RunAndReturnTrimmedCombinedOutput()
+	return err
+}
+
+func (c fakeCommand) RunAndReturnExitCode() (int, error) {
+	if err := c.Run(); err != nil {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func (c fakeCommand) RunAndReturnTrimmedOutput() (string, error) {
+	return c.RunAndReturnTrimmedCombinedOutput()
+}
+
+func (c fakeCommand) RunAndReturnTrimmedCombinedOutput() (string, error) {
+	*c.factory.calls = append(*c.factory.calls, strings.Join(c.args, " "))
+	out, err := c.run()
+	if c.opts != nil && c.opts.Stdout != nil {
+		_, _ = fmt.Fprint(c.opts.Stdout, out)
+		return "", err
+	}
+	return out, err
+}
+
+func (c fakeCommand) Start() error {
+	return c.Run()
+}
+
+func (c fakeCommand) Wait() error {
+	return nil
+}
+
+func (c fakeCommand) run() (string, error) {
+	switch {
+	case c.args[0] == "plugin":
+		return "⚡️ bundle-inspector (1.4.0)", nil
+	case len(c.args) > 2 && c.args[len(c.args)-1] == "--help":
+		return "Flags:\n  -o", nil
+	}
+
+	artifactPath := c.args[2]
+	name := strings.TrimSuffix(filepath.Base(artifactPath), filepath.Ext(artifactPath))
+	content, err := json.Marshal(map[string]interface{}{
+		"artifact_info":     map[string]interface{}{"path": artifactPath, "size": 5242880},
+		"potential_savings": 1024,
+	})
+	if err != nil {
+		return "", err
+	}
+	return "", os.WriteFile(filepath.Join(c.opts.Dir, "bundle-analysis-"+name+".json"), content, 0644)
+}
+
+func writeAPK(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(file)
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	artifactPath := filepath.Join(t.TempDir(), "app-release.apk")
+	writeAPK(t, artifactPath, map[string]string{
+		"AndroidManifest.xml":        "<manifest/>",
+		"res/drawable/icon.png":      strings.Repeat("icon", 256),
+		"res/drawable-hdpi/icon.png": strings.Repeat("icon", 256),
+	})
+	workDir := filepath.Join(t.TempDir(), "reports")
+
+	var calls []string
+	report, err := Analyze(context.Background(), artifactPath, Options{
+		OutputFormats:  []string{"csv"},
+		WorkDir:        workDir,
+		FindDuplicates: true,
+		Logger:         log.NewLogger(),
+		CommandFactory: fakeCommandFactory{calls: &calls},
+		EnvRepository:  fakeEnvRepository{},
+	})
+	if err != nil {
+		t.Fatalf("Analyze() error = %s", err)
+	}
+
+	// The JSON report is always generated, the metrics are read from it
+	wantCall := ":bundle-inspector analyze " + artifactPath + " -o json"
+	found := false
+	for _, call := range calls {
+		found = found || strings.HasPrefix(call, wantCall)
+	}
+	if !found {
+		t.Errorf("bundle-inspector calls = %q, want %q", calls, wantCall)
+	}
+	if report.Name != "app-release.apk" || report.SizeBytes != 5242880 || report.PotentialSavingsBytes != 1024 {
+		t.Errorf("Analyze() = %s %d %d", report.Name, report.SizeBytes, report.PotentialSavingsBytes)
+	}
+	if report.FileCount != 3 || len(report.Files) != 3 {
+		t.Errorf("FileCount = %d with %d files, want 3", report.FileCount, len(report.Files))
+	}
+	if report.ReportFiles.JSON != filepath.Join(workDir, "bundle-analysis-app-release.json") {
+		t.Errorf("JSON report = %s", report.ReportFiles.JSON)
+	}
+	if report.ReportFiles.CSV == "" || report.ReportFiles.Markdown != "" {
+		t.Errorf("ReportFiles = %+v, want the JSON and CSV reports", report.ReportFiles)
+	}
+	if len(report.Duplicates) != 1 || len(report.Duplicates[0].Paths) != 2 {
+		t.Errorf("Duplicates = %+v, want the two icons", report.Duplicates)
+	}
+}
+
+func TestAnalyzeMissingArtifact(t *testing.T) {
+	var calls []string
+	_, err := Analyze(context.Background(), filepath.Join(t.TempDir(), "missing.apk"), Options{CommandFactory: fakeCommandFactory{calls: &calls}, EnvRepository: fakeEnvRepository{}})
+	if err == nil || !strings.Contains(err.Error(), "artifact not found") {
+		t.Errorf("Analyze() error = %v, want artifact not found", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("unexpected calls: %v", calls)
+	}
+}
+
+func TestLoadReport(t *testing.T) {
+	jsonPath := filepath.Join(t.TempDir(), "bundle-analysis-app-release.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"artifact_info": {"path": "/builds/app-release.apk", "size": 3145728}, "potential_savings": 2048}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := LoadReport(jsonPath)
+	if err != nil {
+		t.Fatalf("LoadReport() error = %s", err)
+	}
+	if report.ArtifactPath != "/builds/app-release.apk" || report.Name != "app-release.apk" {
+		t.Errorf("LoadReport() artifact = %s (%s)", report.ArtifactPath, report.Name)
+	}
+	if report.SizeBytes != 3145728 || report.PotentialSavingsBytes != 2048 || report.ReportFiles.JSON != jsonPath {
+		t.Errorf("LoadReport() = %+v", report)
+	}
+	if len(report.Files) != 0 {
+		t.Errorf("LoadReport() listed %d files", len(report.Files))
+	}
+
+	if _, err := LoadReport(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("LoadReport() of a missing report succeeded")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := &Report{SizeBytes: 1000, PotentialSavingsBytes: 100, FileCount: 3, DexMethodCount: 500, Files: []File{
+		{Path: "classes.dex", CompressedSize: 400, UncompressedSize: 800},
+		{Path: "res/old.png", CompressedSize: 100, UncompressedSize: 100},
+		{Path: "lib/arm64-v8a/libapp.so", CompressedSize: 300, UncompressedSize: 600},
+	}}
+	current := &Report{SizeBytes: 1200, PotentialSavingsBytes: 50, FileCount: 4, DexMethodCount: 650, Files: []File{
+		{Path: "res/new.png", CompressedSize: 80, UncompressedSize: 80},
+		{Path: "classes.dex", CompressedSize: 520, UncompressedSize: 1000},
+		{Path: "lib/arm64-v8a/libapp.so", CompressedSize: 300, UncompressedSize: 600},
+		{Path: "assets/data.bin", CompressedSize: 200, UncompressedSize: 200},
+	}}
+
+	diff := Compare(baseline, current)
+	if diff.SizeDeltaBytes != 200 || diff.PotentialSavingsDeltaBytes != -50 || diff.FileCountDelta != 1 || diff.DexMethodCountDelta != 150 {
+		t.Errorf("Compare() deltas = %d %d %d %d", diff.SizeDeltaBytes, diff.PotentialSavingsDeltaBytes, diff.FileCountDelta, diff.DexMethodCountDelta)
+	}
+	if len(diff.Added) != 2 || diff.Added[0].Path != "assets/data.bin" || diff.Added[1].Path != "res/new.png" {
+		t.Errorf("Added = %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Path != "res/old.png" {
+		t.Errorf("Removed = %+v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Path != "classes.dex" || diff.Changed[0].CompressedDeltaBytes() != 120 {
+		t.Errorf("Changed = %+v", diff.Changed)
+	}
+
+	// Reports loaded from JSON don't list the files, only the sizes are compared
+	loaded := &Report{SizeBytes: 900, PotentialSavingsBytes: 100, FileCount: 0}
+	diff = Compare(loaded, current)
+	if !CanCompareFiles(baseline, current) || CanCompareFiles(loaded, current) {
+		t.Errorf("CanCompareFiles() is wrong")
+	}
+	if diff.SizeDeltaBytes != 300 || diff.FileCountDelta != 0 || diff.DexMethodCountDelta != 0 || diff.Added != nil || diff.Removed != nil || diff.Changed != nil {
+		t.Errorf("Compare() without files = %+v", diff)
+	}
+}
@@ -0,0 +1,77 @@
+package bundleanalyzer
+
+import "sort"
+
+// Diff holds the differences between two reports of the same app, typically the baseline build and the current build
+type Diff struct {
+	SizeDeltaBytes             int64
+	PotentialSavingsDeltaBytes int64
+	FileCountDelta             int64
+	DexMethodCountDelta        int64
+	// Added and Removed list the files only found in the current and only found in the baseline artifact
+	Added   []File
+	Removed []File
+	// Changed lists the files found in both artifacts with a different size
+	Changed []FileChange
+}
+
+// FileChange is a file of different size in the two artifacts
+type FileChange struct {
+	Path     string
+	Baseline File
+	Current  File
+}
+
+// CompressedDeltaBytes is the change of the compressed size of the file
+func (c FileChange) CompressedDeltaBytes() int64 {
+	return c.Current.CompressedSize - c.Baseline.CompressedSize
+}
+
+// Compare returns the differences of the current report from the baseline report, files are sorted by path.
+// The files, file counts and DEX method counts are only compared when both reports list the files, reports loaded with LoadReport don't.
+func Compare(baseline, current *Report) Diff {
+	diff := Diff{
+		SizeDeltaBytes:             current.SizeBytes - baseline.SizeBytes,
+		PotentialSavingsDeltaBytes: current.PotentialSavingsBytes - baseline.PotentialSavingsBytes,
+	}
+	if !CanCompareFiles(baseline, current) {
+		return diff
+	}
+	diff.FileCountDelta = current.FileCount - baseline.FileCount
+	diff.DexMethodCountDelta = current.DexMethodCount - baseline.DexMethodCount
+
+	baselineFiles := map[string]File{}
+	for _, file := range baseline.Files {
+		baselineFiles[file.Path] = file
+	}
+
+	currentFiles := map[string]bool{}
+	for _, file := range current.Files {
+		currentFiles[file.Path] = true
+
+		baselineFile, ok := baselineFiles[file.Path]
+		if !ok {
+			diff.Added = append(diff.Added, file)
+			continue
+		}
+		if baselineFile.CompressedSize != file.CompressedSize || baselineFile.UncompressedSize != file.UncompressedSize {
+			diff.Changed = append(diff.Changed, FileChange{Path: file.Path, Baseline: baselineFile, Current: file})
+		}
+	}
+
+	for _, file := range baseline.Files {
+		if !currentFiles[file.Path] {
+			diff.Removed = append(diff.Removed, file)
+		}
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Path < diff.Changed[j].Path })
+	return diff
+}
+
+// CanCompareFiles checks whether both reports list the files of their artifact
+func CanCompareFiles(baseline, current *Report) bool {
+	return len(baseline.Files) > 0 && len(current.Files) > 0
+}